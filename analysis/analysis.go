@@ -0,0 +1,92 @@
+// analysis package contains the analyzers that inspect a sequence of blocks without modifying it,
+// like the bounding box of the motion checked against the working area of the machine.
+//
+// The analyzers are built on the simulator package, so they measure the toolpath that the machine follows,
+// with the modal state tracked and the arcs interpolated.
+// Each analyzer is a function that receives the blocks and returns a report, configured with options.
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region private functions
+
+// duration returns the time of a segment at the feedrate commanded, without accelerations.
+// The moves of the extruder alone last the length extruded at the feedrate. It is zero if the feedrate is unknown.
+func duration(segment simulator.Segment) time.Duration {
+	if !(segment.Feedrate > 0) {
+		return 0
+	}
+
+	length := segment.Length()
+	if length == 0 {
+		length = math.Abs(segment.Extrusion)
+	}
+
+	return time.Duration(length / segment.Feedrate * float64(time.Minute))
+}
+
+// acceleratedDuration returns the time of a segment with the limits commanded in the state, like M204 and M205.
+//
+// The move accelerates from the speed at its start to the feedrate, limited by the maximum velocity, and decelerates to the same speed at its end,
+// like a trapezoid. The speed at the ends is the jerk of the axis, or else the square corner velocity, the speed at which the firmwares take a corner.
+// The acceleration is the one of the travel moves, the moves that print or the retractions. It is the time at the feedrate if the acceleration is unknown.
+func acceleratedDuration(segment simulator.Segment, s state.State) time.Duration {
+	if !(segment.Feedrate > 0) {
+		return 0
+	}
+
+	// the limits are in millimeters, like the firmwares use them
+	scale := 1.0
+	if s.Units == state.UnitsInches {
+		scale = gcode.MILLIMETERS_PER_INCH
+	}
+
+	limits := s.Limits
+
+	length := segment.Length() * scale
+	acceleration, corner := limits.TravelAcceleration, limits.Jerk[0]
+	switch {
+	case length == 0:
+		length = math.Abs(segment.Extrusion) * scale
+		acceleration, corner = limits.RetractAcceleration, limits.Jerk[3]
+	case segment.Extrusion > 0:
+		acceleration = limits.Acceleration
+	}
+
+	speed := segment.Feedrate * scale / 60
+	if limits.Velocity > 0 && speed > limits.Velocity {
+		speed = limits.Velocity
+	}
+
+	if !(acceleration > 0) {
+		return time.Duration(length / speed * float64(time.Second))
+	}
+
+	if corner == 0 {
+		corner = limits.SquareCornerVelocity
+	}
+	corner = math.Min(corner, speed)
+
+	// the distance to accelerate from the corner speed to the feedrate, the same as to decelerate
+	ramp := (speed*speed - corner*corner) / (2 * acceleration)
+
+	seconds := 0.0
+	if 2*ramp <= length {
+		seconds = 2*(speed-corner)/acceleration + (length-2*ramp)/speed
+	} else {
+		// the move is too short to reach the feedrate
+		peak := math.Sqrt(acceleration*length + corner*corner)
+		seconds = 2 * (peak - corner) / acceleration
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+//#endregion
@@ -0,0 +1,241 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// DEFAULT_ARC_TOLERANCE is the maximum difference in millimeters between the radius of an arc at its start and at its end, if it isn't configured.
+const DEFAULT_ARC_TOLERANCE = 0.01
+
+//#region arcs configuration
+
+// ArcsConfigurer defines the options of the validation of the arcs.
+type ArcsConfigurer interface {
+	// Set the maximum difference between the radius at the start and at the end
+	SetTolerance(tolerance float64) error
+
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// ArcsConfigurationCallbackable is the signature of the callbacks used to configure the validation of the arcs.
+type ArcsConfigurationCallbackable func(config ArcsConfigurer) error
+
+// arcsConfigurator implements ArcsConfigurer.
+type arcsConfigurator struct {
+	tolerance float64
+	initial   state.State
+}
+
+// SetTolerance defines the maximum difference in millimeters between the distances from the center to the start and to the end of an arc,
+// it is converted for the blocks in inches. Each firmware has its own tolerance, like 0.002 mm of LinuxCNC.
+// If this method isn't called, by default it is DEFAULT_ARC_TOLERANCE.
+func (ac *arcsConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it must be positive and finite: %v", tolerance)
+	}
+
+	ac.tolerance = tolerance
+
+	return nil
+}
+
+// SetInitialState defines the state of the machine before the first block, like the plane selected by a previous file.
+// If this method isn't called, by default it is the zero state, at the origin in the plane XY.
+func (ac *arcsConfigurator) SetInitialState(initial state.State) error {
+	ac.initial = initial.Clone()
+
+	return nil
+}
+
+//#endregion
+//#region arcs report
+
+// ArcViolation is an arc that is geometrically impossible.
+type ArcViolation struct {
+	// Index is the position of the block in the sequence analyzed.
+	Index int
+
+	// Block is the arc.
+	Block block.Blocker
+
+	// Plane is the plane selected when the arc is executed.
+	Plane state.Plane
+
+	// Reason describes why the arc is impossible.
+	Reason string
+}
+
+// String returns the violation formatted.
+func (v ArcViolation) String() string {
+	return fmt.Sprintf("block %d %s: %s", v.Index, v.Block, v.Reason)
+}
+
+// ArcsReport contains the result of the validation of the arcs.
+type ArcsReport struct {
+	// Arcs is the number of G2 and G3 blocks.
+	Arcs int
+
+	// FullCircles is the number of valid arcs whose end point is their start point.
+	FullCircles int
+
+	// Violations are the arcs that are geometrically impossible, in order.
+	Violations []ArcViolation
+}
+
+// Passed returns true if all arcs are possible.
+func (r *ArcsReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String returns a summary of the report.
+func (r *ArcsReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d arcs, %d full circles", r.Arcs, r.FullCircles))
+
+	for _, v := range r.Violations {
+		sb.WriteString("\n")
+		sb.WriteString(v.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region arcs
+
+// Arcs validates the geometry of the G2 and G3 blocks in the plane selected by G17, G18 or G19, reporting the ones that a firmware rejects:
+//   - the arcs without center offsets nor radius, or with both of them.
+//   - the center offsets of an axis outside of the plane, like K in the plane XY.
+//   - the arcs of radius zero.
+//   - the arcs whose center isn't at the same distance of the start and the end points, beyond the tolerance.
+//   - the arcs whose radius is shorter than half of the distance between the start and the end points.
+//   - the full circles defined by a radius, whose center is undefined.
+//
+// The full circles defined by center offsets are valid. The positions are tracked with a state.Machine.
+//
+// It returns an error if some option is invalid.
+func Arcs(blocks []block.Blocker, options ...ArcsConfigurationCallbackable) (*ArcsReport, error) {
+
+	configurator := &arcsConfigurator{
+		tolerance: DEFAULT_ARC_TOLERANCE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &ArcsReport{}
+	machine := state.New(configurator.initial)
+
+	for index, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		before := machine.Apply(b)
+
+		command := b.Command().String()
+		if command != "G2" && command != "G3" {
+			continue
+		}
+
+		report.Arcs++
+
+		tolerance := configurator.tolerance
+		if before.Units == state.UnitsInches {
+			tolerance /= gcode.MILLIMETERS_PER_INCH
+		}
+
+		full, reason := validateArc(b, before.Plane, before.Position, machine.Snapshot().Position, tolerance)
+		if reason != "" {
+			report.Violations = append(report.Violations, ArcViolation{Index: index, Block: b, Plane: before.Plane, Reason: reason})
+			continue
+		}
+
+		if full {
+			report.FullCircles++
+		}
+	}
+
+	return report, nil
+}
+
+//#endregion
+//#region private functions
+
+// validateArc returns true if the arc is a full circle, and the reason why the arc is impossible or an empty string if it is possible.
+func validateArc(b block.Blocker, plane state.Plane, from state.Position, to state.Position, tolerance float64) (bool, string) {
+	words, inPlane := planeOffsets(plane)
+
+	for _, word := range []byte{'I', 'J', 'K'} {
+		if _, ok := blockparam.Number(b, word); ok && word != words[0] && word != words[1] {
+			return false, fmt.Sprintf("the center offset %c isn't in the plane %s", word, plane)
+		}
+	}
+
+	i, hasI := blockparam.Number(b, words[0])
+	j, hasJ := blockparam.Number(b, words[1])
+	r, hasR := blockparam.Number(b, 'R')
+
+	p0, q0 := inPlane(from)
+	p1, q1 := inPlane(to)
+	chord := math.Hypot(p1-p0, q1-q0)
+	full := chord == 0
+
+	switch {
+	case (hasI || hasJ) && hasR:
+		return false, "the arc has center offsets and radius"
+	case hasI || hasJ:
+		radius := math.Hypot(i, j)
+		if radius == 0 {
+			return false, "the radius is zero"
+		}
+
+		end := math.Hypot(p1-(p0+i), q1-(q0+j))
+		if math.Abs(end-radius) > tolerance {
+			return false, fmt.Sprintf("the radius at the start %s and at the end %s differ", formatRounded(radius), formatRounded(end))
+		}
+	case hasR:
+		if r == 0 {
+			return false, "the radius is zero"
+		}
+
+		if full {
+			return false, "a full circle can't be defined by a radius"
+		}
+
+		if math.Abs(r) < chord/2-tolerance {
+			return false, fmt.Sprintf("the radius %s can't join points %s apart", numeric.Format(r), formatRounded(chord))
+		}
+	default:
+		return false, "the arc hasn't center offsets nor radius"
+	}
+
+	return full, ""
+}
+
+// planeOffsets returns the words of the center offsets of the plane, and a function that returns the coordinates of a position in the plane.
+func planeOffsets(plane state.Plane) ([2]byte, func(p state.Position) (float64, float64)) {
+	switch plane {
+	case state.PlaneZX:
+		return [2]byte{'K', 'I'}, func(p state.Position) (float64, float64) { return p.Z, p.X }
+	case state.PlaneYZ:
+		return [2]byte{'J', 'K'}, func(p state.Position) (float64, float64) { return p.Y, p.Z }
+	}
+
+	return [2]byte{'I', 'J'}, func(p state.Position) (float64, float64) { return p.X, p.Y }
+}
+
+//#endregion
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestArcs(t *testing.T) {
+	cases := map[string]struct {
+		lines []string
+		full  int
+		want  []string
+	}{
+		"empty":            {nil, 0, nil},
+		"center offsets":   {[]string{"G2 X20 Y0 I10 J0", "G3 X0 Y0 R10"}, 0, nil},
+		"full circle":      {[]string{"G1 X10 Y10", "G2 X10 Y10 I5 J0", "G2 I0 J5"}, 2, nil},
+		"inside tolerance": {[]string{"G2 X20.005 Y0 I10 J0"}, 0, nil},
+		"radius mismatch": {
+			[]string{"G2 X20 Y5 I10 J0"},
+			0, []string{"block 0 G2 X20 Y5 I10 J0: the radius at the start 10 and at the end 11.18 differ"},
+		},
+		"radius too short": {
+			[]string{"G2 X20 Y0 R5", "G2 X40 Y0 R10.005"},
+			0, []string{"block 0 G2 X20 Y0 R5: the radius 5 can't join points 20 apart"},
+		},
+		"full circle with radius": {
+			[]string{"G2 X0 Y0 R5"},
+			0, []string{"block 0 G2 X0 Y0 R5: a full circle can't be defined by a radius"},
+		},
+		"missing center": {
+			[]string{"G2 X20 Y0", "G3 X0 Y0 I-10 R10", "G2 X20 Y0 I0 J0"},
+			0, []string{
+				"block 0 G2 X20 Y0: the arc hasn't center offsets nor radius",
+				"block 1 G3 X0 Y0 I-10 R10: the arc has center offsets and radius",
+				"block 2 G2 X20 Y0 I0 J0: the radius is zero",
+			},
+		},
+		"planes": {
+			[]string{"G18", "G2 X20 Z0 I10 K0", "G2 X40 Z0 I10 J0", "G19", "G2 Y10 Z10 J5 K5"},
+			0, []string{"block 2 G2 X40 Z0 I10 J0: the center offset J isn't in the plane ZX"},
+		},
+		"inches": {
+			[]string{"G20", "G2 X2 Y0.05 I1 J0"},
+			0, []string{"block 1 G2 X2 Y0.05 I1 J0: the radius at the start 1 and at the end 1.001 differ"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Arcs(blocktest.ParseCNC(t, tc.lines...))
+			if err != nil {
+				t.Fatalf("failed to validate arcs: %v", err)
+			}
+
+			var got []string
+			for _, v := range report.Violations {
+				got = append(got, v.String())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.FullCircles != tc.full {
+				t.Errorf("got %d full circles, want %d", report.FullCircles, tc.full)
+			}
+
+			if report.Passed() != (len(tc.want) == 0) {
+				t.Errorf("got passed %v, want %v", report.Passed(), len(tc.want) == 0)
+			}
+		})
+	}
+}
+
+func TestArcs_tolerance(t *testing.T) {
+	report, err := Arcs(blocktest.Parse(t, "G2 X20.5 Y0 I10 J0"), func(config ArcsConfigurer) error {
+		return config.SetTolerance(1)
+	})
+	if err != nil {
+		t.Fatalf("failed to validate arcs: %v", err)
+	}
+
+	if got := report.String(); got != "1 arcs, 0 full circles" {
+		t.Errorf("got %q, want no violations", got)
+	}
+
+	if _, err := Arcs(nil, func(config ArcsConfigurer) error { return config.SetTolerance(0) }); err == nil {
+		t.Errorf("got nil, want error")
+	}
+}
@@ -0,0 +1,232 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region box
+
+// Point is a point of the space of the XYZ axes.
+type Point struct {
+	X, Y, Z float64
+}
+
+// Box is the box aligned to the axes between two corners.
+type Box struct {
+	// Min is the lower corner and Max the upper corner.
+	Min, Max Point
+}
+
+// Contains returns true if the point is inside the box or on its faces.
+func (b Box) Contains(p Point) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X &&
+		p.Y >= b.Min.Y && p.Y <= b.Max.Y &&
+		p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+// Size returns the length of the box along each axis.
+func (b Box) Size() Point {
+	return Point{X: b.Max.X - b.Min.X, Y: b.Max.Y - b.Min.Y, Z: b.Max.Z - b.Min.Z}
+}
+
+// String returns the corners of the box formatted like "[X0 Y0 Z0, X200 Y200 Z180]".
+func (b Box) String() string {
+	return fmt.Sprintf("[%s, %s]", b.Min, b.Max)
+}
+
+// String returns the point formatted like "X10 Y20 Z0.3".
+func (p Point) String() string {
+	return fmt.Sprintf("X%s Y%s Z%s", numeric.Format(p.X), numeric.Format(p.Y), numeric.Format(p.Z))
+}
+
+// extend returns the smallest box that contains the box and the point.
+func (b Box) extend(p Point) Box {
+	b.Min = Point{X: math.Min(b.Min.X, p.X), Y: math.Min(b.Min.Y, p.Y), Z: math.Min(b.Min.Z, p.Z)}
+	b.Max = Point{X: math.Max(b.Max.X, p.X), Y: math.Max(b.Max.Y, p.Y), Z: math.Max(b.Max.Z, p.Z)}
+
+	return b
+}
+
+//#endregion
+//#region bounds configuration
+
+// BoundsConfigurer defines the options of the analysis of the bounds.
+type BoundsConfigurer interface {
+	// Set the working area of the machine
+	SetLimits(limits Box) error
+
+	// Set if only the extrusion moves are measured
+	SetExtrusionOnly(extrusionOnly bool) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// BoundsConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the bounds.
+type BoundsConfigurationCallbackable func(config BoundsConfigurer) error
+
+// boundsConfigurator implements BoundsConfigurer.
+type boundsConfigurator struct {
+	limits        *Box
+	extrusionOnly bool
+	simulator     []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetLimits defines the working area of the machine, the motion must be inside it. The lower corner can't exceed the upper one.
+// If this method isn't called, by default the motion isn't checked.
+func (bc *boundsConfigurator) SetLimits(limits Box) error {
+	if limits.Min.X > limits.Max.X || limits.Min.Y > limits.Max.Y || limits.Min.Z > limits.Max.Z {
+		return fmt.Errorf("failed to set limits, the lower corner exceeds the upper one: %s", limits)
+	}
+
+	bc.limits = &limits
+
+	return nil
+}
+
+// SetExtrusionOnly defines if only the moves that extrude are measured, so the box is the one of the printed part.
+// The limits are always checked against all moves.
+// If this method isn't called, by default all moves are measured.
+func (bc *boundsConfigurator) SetExtrusionOnly(extrusionOnly bool) error {
+	bc.extrusionOnly = extrusionOnly
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the tolerance of the arcs or the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (bc *boundsConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	bc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region bounds report
+
+// Violation describes the first move that exceeds the working area.
+type Violation struct {
+	// Index is the position of the block in the sequence analyzed.
+	Index int
+
+	// Block is the block of the move.
+	Block block.Blocker
+
+	// Point is the first point of the move outside the working area.
+	Point Point
+}
+
+// String returns the violation formatted.
+func (v Violation) String() string {
+	return fmt.Sprintf("block %d %s reaches %s", v.Index, v.Block, v.Point)
+}
+
+// BoundsReport contains the bounding box of the motion and the result of the check against the working area.
+type BoundsReport struct {
+	// Box is the bounding box of the motion, it is only valid if Empty is false.
+	Box Box
+
+	// Empty is true if there wasn't any move measured.
+	Empty bool
+
+	// Limits is the working area checked, it is nil if it wasn't configured.
+	Limits *Box
+
+	// Violation is the first move outside the working area, it is nil if all moves are inside it or it wasn't configured.
+	Violation *Violation
+}
+
+// Passed returns true if no move exceeds the working area.
+func (r *BoundsReport) Passed() bool {
+	return r.Violation == nil
+}
+
+// String returns a summary of the report.
+func (r *BoundsReport) String() string {
+	box := "no motion"
+	if !r.Empty {
+		box = "bounding box " + r.Box.String()
+	}
+
+	if r.Limits == nil {
+		return box
+	}
+
+	if r.Violation != nil {
+		return fmt.Sprintf("%s exceeds the limits %s: %s", box, r.Limits, r.Violation)
+	}
+
+	return fmt.Sprintf("%s inside the limits %s", box, r.Limits)
+}
+
+//#endregion
+//#region bounds
+
+// Bounds computes the bounding box of the motion of the blocks and checks it against the working area of the machine,
+// so a print off the bed is detected before the job starts.
+//
+// The motion is the toolpath simulated, with the arcs interpolated. The box contains the end points of all moves,
+// the start of the first move isn't included because it is the position of the machine before the blocks.
+// The positions are in the work coordinate system of the blocks, or the one of the machine if the simulator is configured so,
+// and the positions in inches are converted to millimeters.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Bounds(blocks []block.Blocker, options ...BoundsConfigurationCallbackable) (*BoundsReport, error) {
+
+	configurator := &boundsConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	report := &BoundsReport{Empty: true, Limits: configurator.limits}
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		scale := 1.0
+		if sim.State().Units == state.UnitsInches {
+			scale = gcode.MILLIMETERS_PER_INCH
+		}
+
+		end := Point{X: segment.End.X * scale, Y: segment.End.Y * scale, Z: segment.End.Z * scale}
+
+		if report.Limits != nil && report.Violation == nil && !report.Limits.Contains(end) {
+			report.Violation = &Violation{Index: segment.Index, Block: segment.Block, Point: end}
+		}
+
+		if configurator.extrusionOnly && segment.Extrusion <= 0 {
+			continue
+		}
+
+		if report.Empty {
+			report.Box = Box{Min: end, Max: end}
+			report.Empty = false
+			continue
+		}
+
+		report.Box = report.Box.extend(end)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze bounds: %w", err)
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestBounds(t *testing.T) {
+	bed := Box{Max: Point{X: 200, Y: 200, Z: 180}}
+
+	cases := map[string]struct {
+		lines         []string
+		limits        *Box
+		extrusionOnly bool
+		want          string
+		violation     int
+	}{
+		"empty": {nil, nil, false, "no motion", -1},
+		"box": {
+			[]string{"G28", "G0 X10 Y20 Z0.5", "G1 X50 Y5 E1", "G91", "G1 Z-0.25"},
+			nil, false, "bounding box [X10 Y5 Z0.25, X50 Y20 Z0.5]", -1,
+		},
+		"arc": {
+			[]string{"G1 X5 Y0", "G1 X0 Y0", "G2 X20 Y0 I10 J0"},
+			nil, false, "bounding box [X0 Y0 Z0, X20 Y10 Z0]", -1,
+		},
+		"inches": {
+			[]string{"G20", "G1 X1 Y2", "G1 X0.5 Z0.1"},
+			nil, false, "bounding box [X12.7 Y50.8 Z0, X25.4 Y50.8 Z2.54]", -1,
+		},
+		"extrusion only": {
+			[]string{"G0 X100 Y100", "G1 X110 E1", "G1 Y120 E2", "G0 X0 Y0"},
+			nil, true, "bounding box [X110 Y100 Z0, X110 Y120 Z0]", -1,
+		},
+		"inside": {
+			[]string{"G1 X200 Y0 Z180"},
+			&bed, false, "bounding box [X200 Y0 Z180, X200 Y0 Z180] inside the limits [X0 Y0 Z0, X200 Y200 Z180]", -1,
+		},
+		"off the bed": {
+			[]string{"G1 X10 Y10", "G1 X210", "G1 Y-5"},
+			&bed, false, "bounding box [X10 Y-5 Z0, X210 Y10 Z0] exceeds the limits [X0 Y0 Z0, X200 Y200 Z180]: block 1 G1 X210 reaches X210 Y10 Z0", 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Bounds(blocktest.Parse(t, tc.lines...), func(config BoundsConfigurer) error {
+				if tc.limits != nil {
+					if err := config.SetLimits(*tc.limits); err != nil {
+						return err
+					}
+				}
+				return config.SetExtrusionOnly(tc.extrusionOnly)
+			})
+			if err != nil {
+				t.Fatalf("failed to analyze bounds: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if tc.violation < 0 && !report.Passed() {
+				t.Errorf("got violation %v, want passed", report.Violation)
+			}
+
+			if tc.violation >= 0 && (report.Violation == nil || report.Violation.Index != tc.violation) {
+				t.Errorf("got violation %v, want block %d", report.Violation, tc.violation)
+			}
+		})
+	}
+}
+
+func TestBounds_errors(t *testing.T) {
+	cases := map[string]struct {
+		lines  []string
+		option BoundsConfigurationCallbackable
+	}{
+		"inverted limits": {nil, func(config BoundsConfigurer) error {
+			return config.SetLimits(Box{Min: Point{X: 10}})
+		}},
+		"invalid arc": {[]string{"G2 X10 R1"}, nil},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []BoundsConfigurationCallbackable
+			if tc.option != nil {
+				options = append(options, tc.option)
+			}
+
+			if _, err := Bounds(blocktest.Parse(t, tc.lines...), options...); err == nil {
+				t.Errorf("got nil error, want an error")
+			}
+		})
+	}
+}
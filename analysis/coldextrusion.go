@@ -0,0 +1,224 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// DEFAULT_MIN_EXTRUSION_TEMPERATURE is the minimum target of a hotend to move its extruder in degrees Celsius, the default of Marlin, if it isn't configured.
+const DEFAULT_MIN_EXTRUSION_TEMPERATURE = 170
+
+//#region cold extrusion configuration
+
+// ColdExtrusionConfigurer defines the options of the detection of the cold extrusions.
+type ColdExtrusionConfigurer interface {
+	// Set the minimum target of a hotend to move its extruder
+	SetMinTemperature(temperature float64) error
+
+	// Set if the machine must wait for the hotend before moving its extruder
+	SetRequireWait(require bool) error
+
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// ColdExtrusionConfigurationCallbackable is the signature of the callbacks used to configure the detection of the cold extrusions.
+type ColdExtrusionConfigurationCallbackable func(config ColdExtrusionConfigurer) error
+
+// coldExtrusionConfigurator implements ColdExtrusionConfigurer.
+type coldExtrusionConfigurator struct {
+	temperature float64
+	requireWait bool
+	initial     state.State
+}
+
+// SetMinTemperature defines the minimum target of a hotend to move its extruder, like EXTRUDE_MINTEMP of Marlin.
+// If this method isn't called, by default it is DEFAULT_MIN_EXTRUSION_TEMPERATURE.
+func (cc *coldExtrusionConfigurator) SetMinTemperature(temperature float64) error {
+	if !(temperature >= 0) || math.IsInf(temperature, 0) {
+		return fmt.Errorf("failed to set minimum temperature, it can't be negative nor infinite: %v", temperature)
+	}
+
+	cc.temperature = temperature
+
+	return nil
+}
+
+// SetRequireWait defines if the machine must wait for a hotend with M109 before moving its extruder,
+// otherwise a target high enough set by M104 is accepted.
+// If this method isn't called, by default the wait is required.
+func (cc *coldExtrusionConfigurator) SetRequireWait(require bool) error {
+	cc.requireWait = require
+
+	return nil
+}
+
+// SetInitialState defines the state of the machine before the first block, like the targets of the hotends heated by a previous file.
+// The hotends of the initial state with a target high enough are considered heated.
+// If this method isn't called, by default it is the zero state, with all hotends unknown.
+func (cc *coldExtrusionConfigurator) SetInitialState(initial state.State) error {
+	cc.initial = initial.Clone()
+
+	return nil
+}
+
+//#endregion
+//#region cold extrusion report
+
+// ColdExtrusion is a sequence of consecutive blocks that move the extruder of a hotend that isn't hot enough.
+type ColdExtrusion struct {
+	// Index is the position of the first block of the sequence in the sequence analyzed.
+	Index int
+
+	// Block is the first block of the sequence.
+	Block block.Blocker
+
+	// Blocks is the number of blocks of the sequence.
+	Blocks int
+
+	// Heater is the hotend of the active tool.
+	Heater state.Heater
+
+	// Reason describes why the hotend isn't hot enough.
+	Reason string
+}
+
+// String returns the cold extrusion formatted.
+func (c ColdExtrusion) String() string {
+	return fmt.Sprintf("block %d %s: %s (%d blocks)", c.Index, c.Block, c.Reason, c.Blocks)
+}
+
+// ColdExtrusionReport contains the moves of the extruders commanded while their hotends are cold.
+type ColdExtrusionReport struct {
+	// ColdExtrusions are the sequences of blocks that move a cold extruder, in order.
+	ColdExtrusions []ColdExtrusion
+}
+
+// Passed returns true if no extruder moves while its hotend is cold.
+func (r *ColdExtrusionReport) Passed() bool {
+	return len(r.ColdExtrusions) == 0
+}
+
+// String returns a summary of the report.
+func (r *ColdExtrusionReport) String() string {
+	if r.Passed() {
+		return "no cold extrusions"
+	}
+
+	lines := make([]string, 0, len(r.ColdExtrusions))
+	for _, c := range r.ColdExtrusions {
+		lines = append(lines, c.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+//#endregion
+//#region cold extrusion
+
+// ColdExtrusions detects the moves of an extruder commanded while the target of its hotend is unknown or below the minimum temperature,
+// or before the machine waits for the hotend with M109, conditions that the firmwares reject to protect the extruder.
+// The retractions are included, like the firmwares do.
+//
+// The targets are tracked with a state.Machine across the blocks. M302 is honored like Marlin does:
+// "M302 P1" allows the cold extrusions, "M302 P0" forbids them again and "M302 S<temperature>" changes the minimum temperature.
+// Each sequence of consecutive blocks that move a cold extruder for the same reason is reported once.
+//
+// It returns an error if some option is invalid.
+func ColdExtrusions(blocks []block.Blocker, options ...ColdExtrusionConfigurationCallbackable) (*ColdExtrusionReport, error) {
+
+	configurator := &coldExtrusionConfigurator{
+		temperature: DEFAULT_MIN_EXTRUSION_TEMPERATURE,
+		requireWait: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &ColdExtrusionReport{}
+	machine := state.New(configurator.initial)
+	minimum := configurator.temperature
+	allowed := false
+
+	// the hotends that the machine waited for, or heated in the initial state
+	waited := map[int]bool{}
+	for heater, target := range configurator.initial.Heaters {
+		if heater.Kind == state.HeaterHotend && target >= minimum {
+			waited[heater.Index] = true
+		}
+	}
+
+	// the sequence in progress, it is nil if the last move of an extruder was valid
+	var current *ColdExtrusion
+
+	for index, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		before := machine.Apply(b)
+		after := machine.Snapshot()
+
+		switch b.Command().String() {
+		case "M302":
+			if p, ok := blockparam.Number(b, 'P'); ok {
+				allowed = p != 0
+			}
+			if s, ok := blockparam.Number(b, 'S'); ok {
+				minimum = s
+			}
+			continue
+		case "M104", "M109":
+			if heater, target, ok := before.HeaterTarget(b); ok {
+				waited[heater.Index] = b.Command().String() == "M109" && target >= minimum
+			}
+			continue
+		case "G0", "G1", "G2", "G3":
+		default:
+			continue
+		}
+
+		if after.Position.E == before.Position.E || allowed {
+			continue
+		}
+
+		heater := state.Heater{Kind: state.HeaterHotend, Index: after.Tool}
+		reason := ""
+
+		target, known := after.Heaters[heater]
+		switch {
+		case !known:
+			reason = fmt.Sprintf("the target of %s is unknown", heater)
+		case target < minimum:
+			reason = fmt.Sprintf("the target %s of %s is below %s", numeric.Format(target), heater, numeric.Format(minimum))
+		case configurator.requireWait && !waited[heater.Index]:
+			reason = fmt.Sprintf("the machine doesn't wait for %s with M109", heater)
+		}
+
+		if reason == "" {
+			current = nil
+			continue
+		}
+
+		if current != nil && current.Heater == heater && current.Reason == reason {
+			current.Blocks++
+			continue
+		}
+
+		report.ColdExtrusions = append(report.ColdExtrusions, ColdExtrusion{Index: index, Block: b, Blocks: 1, Heater: heater, Reason: reason})
+		current = &report.ColdExtrusions[len(report.ColdExtrusions)-1]
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestColdExtrusions(t *testing.T) {
+	cases := map[string]struct {
+		lines []string
+		wait  bool
+		want  []string
+	}{
+		"empty":      {nil, true, nil},
+		"heated":     {[]string{"M109 S200", "G1 X10 E1", "G1 E0", "M109 R180", "G1 X20 E2"}, true, nil},
+		"no motion":  {[]string{"G1 X10", "G92 E5", "G1 Y10 E5"}, true, nil},
+		"not waited": {[]string{"M104 S200", "G1 X10 E1"}, false, nil},
+		"unknown": {
+			[]string{"G1 X10 E1", "G1 X20 E2", "G1 X30 E3", "M109 S200", "G1 X40 E4"},
+			true, []string{"block 0 G1 X10 E1: the target of hotend 0 is unknown (3 blocks)"},
+		},
+		"below the minimum": {
+			[]string{"M109 S150", "G1 X10 E1", "M104 S0", "G1 E0"},
+			true, []string{
+				"block 1 G1 X10 E1: the target 150 of hotend 0 is below 170 (1 blocks)",
+				"block 3 G1 E0: the target 0 of hotend 0 is below 170 (1 blocks)",
+			},
+		},
+		"without wait": {
+			[]string{"M104 S200", "G1 X10 E1", "M109 S200", "G1 X20 E2"},
+			true, []string{"block 1 G1 X10 E1: the machine doesn't wait for hotend 0 with M109 (1 blocks)"},
+		},
+		"tool change": {
+			[]string{"M109 S200", "M104 T1 S200", "G1 X10 E1", "T1", "G1 X20 E2", "M109 T1 S200", "G1 X30 E3"},
+			true, []string{"block 4 G1 X20 E2: the machine doesn't wait for hotend 1 with M109 (1 blocks)"},
+		},
+		"allowed by M302": {
+			[]string{"M302 P1", "G1 X10 E1", "M302 P0", "M302 S100", "M109 S150", "G1 X20 E2", "G1 X30 E1"},
+			true, nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := ColdExtrusions(blocktest.Parse(t, tc.lines...), func(config ColdExtrusionConfigurer) error {
+				return config.SetRequireWait(tc.wait)
+			})
+			if err != nil {
+				t.Fatalf("failed to detect cold extrusions: %v", err)
+			}
+
+			var got []string
+			for _, c := range report.ColdExtrusions {
+				got = append(got, c.String())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.Passed() != (len(tc.want) == 0) {
+				t.Errorf("got passed %v, want %v", report.Passed(), len(tc.want) == 0)
+			}
+		})
+	}
+}
+
+func TestColdExtrusions_options(t *testing.T) {
+	blocks := blocktest.Parse(t, "G1 X10 E1")
+
+	report, err := ColdExtrusions(blocks,
+		func(config ColdExtrusionConfigurer) error { return config.SetMinTemperature(150) },
+		func(config ColdExtrusionConfigurer) error {
+			return config.SetInitialState(state.State{Heaters: map[state.Heater]float64{{Kind: state.HeaterHotend}: 160}})
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to detect cold extrusions: %v", err)
+	}
+
+	if got := report.String(); got != "no cold extrusions" {
+		t.Errorf("got %q, want no cold extrusions", got)
+	}
+
+	if _, err := ColdExtrusions(blocks, func(config ColdExtrusionConfigurer) error { return config.SetMinTemperature(-1) }); err == nil {
+		t.Errorf("got nil, want error")
+	}
+}
@@ -0,0 +1,329 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// DEFAULT_COMPARISON_TOLERANCE is the maximum distance in millimeters between two toolpaths considered the same, if it isn't configured.
+const DEFAULT_COMPARISON_TOLERANCE = 0.01
+
+//#region comparison configuration
+
+// ComparisonConfigurer defines the options of the comparison of two toolpaths.
+type ComparisonConfigurer interface {
+	// Set the maximum distance between two toolpaths considered the same
+	SetTolerance(tolerance float64) error
+
+	// Set if the travel moves are compared
+	SetTravel(travel bool) error
+
+	// Set the options of the simulation of both toolpaths
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// ComparisonConfigurationCallbackable is the signature of the callbacks used to configure the comparison of two toolpaths.
+type ComparisonConfigurationCallbackable func(config ComparisonConfigurer) error
+
+// comparisonConfigurator implements ComparisonConfigurer.
+type comparisonConfigurator struct {
+	tolerance float64
+	travel    bool
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetTolerance defines the maximum distance in millimeters between a segment and the other toolpath to consider it unchanged.
+// If this method isn't called, by default it is DEFAULT_COMPARISON_TOLERANCE.
+func (cc *comparisonConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it must be positive and finite: %v", tolerance)
+	}
+
+	cc.tolerance = tolerance
+
+	return nil
+}
+
+// SetTravel defines if the travel moves are compared too, not only the extrusion moves that shape the part.
+// If this method isn't called, by default only the extrusion moves are compared, so an optimization of the travels isn't a difference.
+func (cc *comparisonConfigurator) SetTravel(travel bool) error {
+	cc.travel = travel
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of both toolpaths, like the tolerance of the arcs.
+// If this method isn't called, by default the simulations use their defaults.
+func (cc *comparisonConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	cc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region comparison report
+
+// SegmentDifference is a segment of a toolpath that isn't in the other one.
+type SegmentDifference struct {
+	// Layer is the index of the layer of the segment.
+	Layer int
+
+	// Index is the position of the block of the segment in its sequence.
+	Index int
+
+	// Block is the block of the segment.
+	Block block.Blocker
+
+	// Start and End are the ends of the segment in millimeters.
+	Start, End Point
+
+	// Deviation is the distance in millimeters from the segment to the other toolpath in the same layer.
+	// It is infinite if the other toolpath doesn't have the layer.
+	Deviation float64
+}
+
+// String returns the difference formatted.
+func (d SegmentDifference) String() string {
+	deviation := "missing layer"
+	if !math.IsInf(d.Deviation, 1) {
+		deviation = "deviation " + formatRounded(d.Deviation)
+	}
+
+	return fmt.Sprintf("layer %d block %d %s: %s to %s, %s", d.Layer, d.Index, d.Block, d.Start, d.End, deviation)
+}
+
+// ComparisonReport contains the geometric differences between two toolpaths.
+type ComparisonReport struct {
+	// MaxDeviation is the largest distance in millimeters from a segment to the other toolpath in the same layer,
+	// for the layers that both toolpaths have.
+	MaxDeviation float64
+
+	// Added are the segments of the second toolpath that aren't in the first one, in order.
+	Added []SegmentDifference
+
+	// Removed are the segments of the first toolpath that aren't in the second one, in order.
+	Removed []SegmentDifference
+
+	// Layers are the indexes of the layers with differences, sorted.
+	Layers []int
+}
+
+// Equal returns true if the toolpaths are the same within the tolerance.
+func (r *ComparisonReport) Equal() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0
+}
+
+// String returns a summary of the report.
+func (r *ComparisonReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("maximum deviation %s, %d segments added, %d segments removed", formatRounded(r.MaxDeviation), len(r.Added), len(r.Removed)))
+
+	if len(r.Layers) > 0 {
+		layers := make([]string, 0, len(r.Layers))
+		for _, layer := range r.Layers {
+			layers = append(layers, fmt.Sprint(layer))
+		}
+		sb.WriteString(", layers " + strings.Join(layers, ", ") + " differ")
+	}
+
+	for _, d := range r.Added {
+		sb.WriteString("\n+ " + d.String())
+	}
+
+	for _, d := range r.Removed {
+		sb.WriteString("\n- " + d.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region comparison
+
+// CompareToolpaths compares the toolpaths simulated of two sequences of blocks instead of their text,
+// like the blocks before and after a transformation, to verify that it only changed what was intended.
+//
+// The segments are compared by layer. A segment is unchanged if its ends and its middle point are within the tolerance
+// of the toolpath of the other sequence in the same layer, so a path split in more segments, reversed or interpolated
+// as an arc is the same path. The segments that only move the extruder aren't compared, neither the amount extruded.
+// The positions in inches are converted to millimeters.
+//
+// It returns an error if some option is invalid or some toolpath can't be simulated.
+func CompareToolpaths(first []block.Blocker, second []block.Blocker, options ...ComparisonConfigurationCallbackable) (*ComparisonReport, error) {
+
+	configurator := &comparisonConfigurator{
+		tolerance: DEFAULT_COMPARISON_TOLERANCE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	before, err := toolpathLayers(first, configurator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate the first toolpath: %w", err)
+	}
+
+	after, err := toolpathLayers(second, configurator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate the second toolpath: %w", err)
+	}
+
+	report := &ComparisonReport{}
+
+	layers := len(before)
+	if len(after) > layers {
+		layers = len(after)
+	}
+
+	for layer := 0; layer < layers; layer++ {
+		var a, b []pathSegment
+		if layer < len(before) {
+			a = before[layer]
+		}
+		if layer < len(after) {
+			b = after[layer]
+		}
+
+		removed := report.uncovered(a, b, layer, configurator.tolerance)
+		added := report.uncovered(b, a, layer, configurator.tolerance)
+
+		report.Removed = append(report.Removed, removed...)
+		report.Added = append(report.Added, added...)
+
+		if len(removed) > 0 || len(added) > 0 {
+			report.Layers = append(report.Layers, layer)
+		}
+	}
+
+	sort.SliceStable(report.Added, func(i, j int) bool { return report.Added[i].Index < report.Added[j].Index })
+	sort.SliceStable(report.Removed, func(i, j int) bool { return report.Removed[i].Index < report.Removed[j].Index })
+
+	return report, nil
+}
+
+//#endregion
+//#region private functions
+
+// pathSegment is a segment of a toolpath in millimeters.
+type pathSegment struct {
+	index      int
+	block      block.Blocker
+	start, end Point
+}
+
+// toolpathLayers simulates the blocks and returns the segments compared of each layer.
+func toolpathLayers(blocks []block.Blocker, configurator *comparisonConfigurator) ([][]pathSegment, error) {
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	var layers [][]pathSegment
+	var tracker layerTracker
+
+	for sim.Next() {
+		segment := sim.Segment()
+		layer, _ := tracker.track(segment)
+
+		if segment.Length() == 0 || (!configurator.travel && segment.Extrusion <= 0) {
+			continue
+		}
+
+		scale := 1.0
+		if sim.State().Units == state.UnitsInches {
+			scale = gcode.MILLIMETERS_PER_INCH
+		}
+
+		for len(layers) <= layer {
+			layers = append(layers, nil)
+		}
+
+		layers[layer] = append(layers[layer], pathSegment{
+			index: segment.Index,
+			block: segment.Block,
+			start: Point{X: segment.Start.X * scale, Y: segment.Start.Y * scale, Z: segment.Start.Z * scale},
+			end:   Point{X: segment.End.X * scale, Y: segment.End.Y * scale, Z: segment.End.Z * scale},
+		})
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, err
+	}
+
+	return layers, nil
+}
+
+// uncovered returns the segments that aren't within the tolerance of the other segments,
+// and updates the maximum deviation if the other segments exist.
+func (r *ComparisonReport) uncovered(segments []pathSegment, other []pathSegment, layer int, tolerance float64) []SegmentDifference {
+	var differences []SegmentDifference
+
+	for _, s := range segments {
+		deviation := math.Inf(1)
+		if len(other) > 0 {
+			middle := Point{X: (s.start.X + s.end.X) / 2, Y: (s.start.Y + s.end.Y) / 2, Z: (s.start.Z + s.end.Z) / 2}
+
+			deviation = 0
+			for _, p := range []Point{s.start, middle, s.end} {
+				if d := distanceToPath(p, other); d > deviation {
+					deviation = d
+				}
+			}
+
+			if deviation > r.MaxDeviation {
+				r.MaxDeviation = deviation
+			}
+		}
+
+		if deviation > tolerance {
+			differences = append(differences, SegmentDifference{Layer: layer, Index: s.index, Block: s.block, Start: s.start, End: s.end, Deviation: deviation})
+		}
+	}
+
+	return differences
+}
+
+// distanceToPath returns the distance from a point to the nearest segment of a path.
+func distanceToPath(p Point, path []pathSegment) float64 {
+	nearest := math.Inf(1)
+
+	for _, s := range path {
+		if d := distanceToSegment(p, s.start, s.end); d < nearest {
+			nearest = d
+			if nearest == 0 {
+				break
+			}
+		}
+	}
+
+	return nearest
+}
+
+// distanceToSegment returns the distance from a point to the segment between a and b.
+func distanceToSegment(p Point, a Point, b Point) float64 {
+	dx, dy, dz := b.X-a.X, b.Y-a.Y, b.Z-a.Z
+	length := dx*dx + dy*dy + dz*dz
+
+	t := 0.0
+	if length > 0 {
+		t = ((p.X-a.X)*dx + (p.Y-a.Y)*dy + (p.Z-a.Z)*dz) / length
+		t = math.Max(0, math.Min(1, t))
+	}
+
+	x, y, z := a.X+t*dx-p.X, a.Y+t*dy-p.Y, a.Z+t*dz-p.Z
+
+	return math.Sqrt(x*x + y*y + z*z)
+}
+
+//#endregion
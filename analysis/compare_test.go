@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestCompareToolpaths(t *testing.T) {
+	base := []string{"M83", "G1 Z0.5 F600", "G1 X20 E2", "G1 Y20 E2", "G0 X0 Y0", "G1 Z1", "G1 X20 E2"}
+
+	cases := map[string]struct {
+		second []string
+		travel bool
+		want   string
+	}{
+		"same": {base, false, "maximum deviation 0, 0 segments added, 0 segments removed"},
+		"split and reversed": {
+			[]string{"M83", "G1 Z0.5 F600", "G0 X20 Y20", "G1 Y10 E1", "G1 Y0 E1", "G1 X0 E2", "G1 Z1", "G0 X0 Y0", "G1 X20 E2"},
+			false, "maximum deviation 0, 0 segments added, 0 segments removed",
+		},
+		"moved": {
+			[]string{"M83", "G1 Z0.5 F600", "G1 X20 E2", "G1 X20.5 Y20 E2", "G0 X0 Y0", "G1 Z1", "G1 X20 E2"},
+			false,
+			"maximum deviation 0.5, 1 segments added, 1 segments removed, layers 0 differ\n" +
+				"+ layer 0 block 3 G1 X20.5 Y20 E2: X20 Y0 Z0.5 to X20.5 Y20 Z0.5, deviation 0.5\n" +
+				"- layer 0 block 3 G1 Y20 E2: X20 Y0 Z0.5 to X20 Y20 Z0.5, deviation 0.5",
+		},
+		"extra layer": {
+			append(append([]string{}, base...), "G1 Z1.5", "G1 X0 E2"),
+			false,
+			"maximum deviation 0, 1 segments added, 0 segments removed, layers 2 differ\n" +
+				"+ layer 2 block 8 G1 X0 E2: X20 Y0 Z1.5 to X0 Y0 Z1.5, missing layer",
+		},
+		"travel ignored": {
+			[]string{"M83", "G1 Z0.5 F600", "G1 X20 E2", "G1 Y20 E2", "G0 X10 Y0", "G0 X0", "G1 Z1", "G1 X20 E2"},
+			false, "maximum deviation 0, 0 segments added, 0 segments removed",
+		},
+		"travel compared": {
+			[]string{"M83", "G1 Z0.5 F600", "G1 X20 E2", "G1 Y20 E2", "G0 X0 Y40", "G0 Y0", "G1 Z1", "G1 X20 E2"},
+			true,
+			"maximum deviation 28.284, 2 segments added, 1 segments removed, layers 0 differ\n" +
+				"+ layer 0 block 4 G0 X0 Y40: X20 Y20 Z0.5 to X0 Y40 Z0.5, deviation 28.284\n" +
+				"+ layer 0 block 5 G0 Y0: X0 Y40 Z0.5 to X0 Y0 Z0.5, deviation 28.284\n" +
+				"- layer 0 block 4 G0 X0 Y0: X20 Y20 Z0.5 to X0 Y0 Z0.5, deviation 10",
+		},
+		"inches": {
+			[]string{"G20", "M83", "G1 Z0.019685 F600", "G1 X0.787402 E2", "G1 Y0.787402 E2", "G0 X0 Y0", "G1 Z0.03937", "G1 X0.787402 E2"},
+			false, "maximum deviation 0, 0 segments added, 0 segments removed",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := CompareToolpaths(blocktest.Parse(t, base...), blocktest.Parse(t, tc.second...), func(config ComparisonConfigurer) error {
+				return config.SetTravel(tc.travel)
+			})
+			if err != nil {
+				t.Fatalf("failed to compare toolpaths: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.Equal() != (len(report.Added)+len(report.Removed) == 0) {
+				t.Errorf("got equal %v with %d differences", report.Equal(), len(report.Added)+len(report.Removed))
+			}
+		})
+	}
+}
+
+func TestCompareToolpaths_tolerance(t *testing.T) {
+	first := blocktest.Parse(t, "M83", "G1 X20 Y0.3 E2 F600")
+	second := blocktest.Parse(t, "M83", "G1 X20 Y0.4 E2 F600")
+
+	report, err := CompareToolpaths(first, second, func(config ComparisonConfigurer) error {
+		return config.SetTolerance(0.15)
+	})
+	if err != nil {
+		t.Fatalf("failed to compare toolpaths: %v", err)
+	}
+
+	if !report.Equal() {
+		t.Errorf("got %q, want equal toolpaths", report)
+	}
+
+	if _, err := CompareToolpaths(first, second, func(config ComparisonConfigurer) error { return config.SetTolerance(0) }); err == nil {
+		t.Errorf("got nil, want error")
+	}
+}
@@ -0,0 +1,306 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// DEFAULT_AMBIENT_TEMPERATURE is the temperature of the room in degrees Celsius, from which the heaters start heating, if it isn't configured.
+const DEFAULT_AMBIENT_TEMPERATURE = 25
+
+//#region power model
+
+// HeaterPower is the power model of a kind of heater.
+type HeaterPower struct {
+	// Power is the rated power of the heater in watts, drawn at full duty while it heats.
+	Power float64
+
+	// Duty is the fraction of the rated power drawn to hold a target, between 0 and 1.
+	Duty float64
+
+	// Rate is the speed at which the heater heats at full duty in degrees Celsius per second.
+	// It is used to estimate the time of the waits for the heater, zero if the waits aren't estimated.
+	Rate float64
+}
+
+// PowerModel is the power drawn by a machine, in watts. A zero value doesn't consume.
+type PowerModel struct {
+	// Standby is the steady-state draw of the electronics, the motors holding and the fans, during all the job.
+	Standby float64
+
+	// Motors is the additional draw of the motors while the machine moves.
+	Motors float64
+
+	// Hotend is the power model of each hotend.
+	Hotend HeaterPower
+
+	// Bed is the power model of the bed.
+	Bed HeaterPower
+
+	// Chamber is the power model of the chamber.
+	Chamber HeaterPower
+}
+
+// heater returns the power model of a kind of heater.
+func (m PowerModel) heater(kind state.HeaterKind) HeaterPower {
+	switch kind {
+	case state.HeaterBed:
+		return m.Bed
+	case state.HeaterChamber:
+		return m.Chamber
+	}
+
+	return m.Hotend
+}
+
+// validate returns an error if some value of the model is negative or a duty exceeds 1.
+func (m PowerModel) validate() error {
+	values := []float64{m.Standby, m.Motors}
+	for _, h := range []HeaterPower{m.Hotend, m.Bed, m.Chamber} {
+		if !(h.Duty <= 1) {
+			return fmt.Errorf("the duty of a heater can't exceed 1: %v", h.Duty)
+		}
+		values = append(values, h.Power, h.Duty, h.Rate)
+	}
+
+	for _, value := range values {
+		if !(value >= 0) || math.IsInf(value, 0) {
+			return fmt.Errorf("the values can't be negative nor infinite: %v", value)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region energy configuration
+
+// EnergyConfigurer defines the options of the estimation of the energy.
+type EnergyConfigurer interface {
+	// Set the temperature of the room
+	SetAmbientTemperature(temperature float64) error
+
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// EnergyConfigurationCallbackable is the signature of the callbacks used to configure the estimation of the energy.
+type EnergyConfigurationCallbackable func(config EnergyConfigurer) error
+
+// energyConfigurator implements EnergyConfigurer.
+type energyConfigurator struct {
+	ambient float64
+	initial state.State
+}
+
+// SetAmbientTemperature defines the temperature of the room in degrees Celsius, from which the heaters turned off start heating.
+// If this method isn't called, by default it is DEFAULT_AMBIENT_TEMPERATURE.
+func (ec *energyConfigurator) SetAmbientTemperature(temperature float64) error {
+	if math.IsNaN(temperature) || math.IsInf(temperature, 0) {
+		return fmt.Errorf("failed to set ambient temperature, it must be finite: %v", temperature)
+	}
+
+	ec.ambient = temperature
+
+	return nil
+}
+
+// SetInitialState defines the state of the machine before the first block, like the targets of the heaters heated by a previous file.
+// The heaters of the initial state are considered at their targets.
+// If this method isn't called, by default it is the zero state, with all heaters off.
+func (ec *energyConfigurator) SetInitialState(initial state.State) error {
+	ec.initial = initial.Clone()
+
+	return nil
+}
+
+//#endregion
+//#region energy report
+
+// EnergyReport contains the energy consumed by a job, in kilowatt-hours.
+type EnergyReport struct {
+	// Time is the time of the job: the moves at the feedrate commanded, without accelerations, the dwells and the waits for the heaters.
+	Time time.Duration
+
+	// Standby is the energy of the steady-state draw.
+	Standby float64
+
+	// Motors is the energy of the motors moving.
+	Motors float64
+
+	// Heaters is the energy of each heater, heating and holding its target.
+	Heaters map[state.Heater]float64
+}
+
+// Total returns the energy consumed by the job in kilowatt-hours.
+func (r *EnergyReport) Total() float64 {
+	total := r.Standby + r.Motors
+	for _, heater := range r.heaters() {
+		total += r.Heaters[heater]
+	}
+
+	return total
+}
+
+// heaters returns the heaters of the report sorted, so the sums and the summaries don't depend on the order of the map.
+func (r *EnergyReport) heaters() []state.Heater {
+	heaters := make([]state.Heater, 0, len(r.Heaters))
+	for heater := range r.Heaters {
+		heaters = append(heaters, heater)
+	}
+	sortHeaters(heaters)
+
+	return heaters
+}
+
+// String returns a summary of the report.
+func (r *EnergyReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s kWh in %s: standby %s kWh, motors %s kWh",
+		formatRounded(r.Total()), r.Time.Round(time.Second), formatRounded(r.Standby), formatRounded(r.Motors)))
+
+	for _, heater := range r.heaters() {
+		sb.WriteString(fmt.Sprintf(", %s %s kWh", heater, formatRounded(r.Heaters[heater])))
+	}
+
+	return sb.String()
+}
+
+// add adds the energy drawn at a power in watts during a time to an amount in kilowatt-hours.
+func (r *EnergyReport) add(energy *float64, power float64, elapsed time.Duration) {
+	*energy += power * elapsed.Hours() / 1000
+}
+
+//#endregion
+//#region energy
+
+// Energy estimates the energy consumed by a job, combining the time of the blocks with the power model of the machine.
+//
+// The standby draw is consumed during all the job and the motors while the machine moves.
+// A heater with a target draws its rated power at the duty of the model to hold it.
+// A wait for a heater with M109, M190 or M191 lasts the time to heat from its previous target, or the ambient temperature if it was off,
+// at the rate of the model, drawing its rated power. The dwells of G4 are included in the time.
+// The moves last their length at the feedrate commanded, so the time is a lower bound of the real one.
+//
+// It returns an error if the model or some option is invalid or the toolpath can't be simulated.
+func Energy(blocks []block.Blocker, model PowerModel, options ...EnergyConfigurationCallbackable) (*EnergyReport, error) {
+
+	if err := model.validate(); err != nil {
+		return nil, fmt.Errorf("failed to estimate energy, invalid model: %w", err)
+	}
+
+	configurator := &energyConfigurator{
+		ambient: DEFAULT_AMBIENT_TEMPERATURE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	// time of the moves of each block
+	moves := make([]time.Duration, len(blocks))
+
+	sim, err := simulator.New(blocks, func(config simulator.SimulatorConfigurer) error {
+		return config.SetInitialState(configurator.initial)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	for sim.Next() {
+		segment := sim.Segment()
+		moves[segment.Index] += duration(segment)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to estimate energy: %w", err)
+	}
+
+	report := &EnergyReport{Heaters: map[state.Heater]float64{}}
+	machine := state.New(configurator.initial)
+
+	// hold adds the energy of the standby draw and the heaters that hold their targets during a time, except the one heating
+	hold := func(s state.State, elapsed time.Duration, heating *state.Heater) {
+		report.Time += elapsed
+		report.add(&report.Standby, model.Standby, elapsed)
+
+		for heater, target := range s.Heaters {
+			if !(target > 0) || (heating != nil && heater == *heating) {
+				continue
+			}
+
+			power := model.heater(heater.Kind)
+			energy := report.Heaters[heater]
+			report.add(&energy, power.Power*power.Duty, elapsed)
+			report.Heaters[heater] = energy
+		}
+	}
+
+	for i, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		before := machine.Apply(b)
+		after := machine.Snapshot()
+
+		switch b.Command().String() {
+		case "M109", "M190", "M191":
+			heater, target, ok := before.HeaterTarget(b)
+			if !ok {
+				break
+			}
+
+			power := model.heater(heater.Kind)
+			if !(power.Rate > 0) {
+				break
+			}
+
+			from := configurator.ambient
+			if previous := before.Heaters[heater]; previous > from {
+				from = previous
+			}
+
+			if target <= from {
+				break
+			}
+
+			elapsed := time.Duration((target - from) / power.Rate * float64(time.Second))
+			hold(before, elapsed, &heater)
+
+			energy := report.Heaters[heater]
+			report.add(&energy, power.Power, elapsed)
+			report.Heaters[heater] = energy
+
+		case "G4":
+			elapsed := time.Duration(0)
+			if ms, ok := blockparam.Number(b, 'P'); ok {
+				elapsed += time.Duration(ms * float64(time.Millisecond))
+			}
+			if s, ok := blockparam.Number(b, 'S'); ok {
+				elapsed += time.Duration(s * float64(time.Second))
+			}
+			hold(after, elapsed, nil)
+
+		default:
+			if moves[i] > 0 {
+				hold(after, moves[i], nil)
+				report.add(&report.Motors, model.Motors, moves[i])
+			}
+		}
+	}
+
+	return report, nil
+}
+
+//#endregion
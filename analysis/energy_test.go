@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestEnergy(t *testing.T) {
+	model := PowerModel{
+		Standby: 10,
+		Motors:  50,
+		Hotend:  HeaterPower{Power: 40, Duty: 0.5, Rate: 2},
+		Bed:     HeaterPower{Power: 200, Duty: 0.25, Rate: 1},
+	}
+
+	cases := map[string]struct {
+		lines   []string
+		initial *state.State
+		want    string
+		total   float64
+	}{
+		"empty": {nil, nil, "0 kWh in 0s: standby 0 kWh, motors 0 kWh", 0},
+		"moves": {
+			// 1 hour moving and 30 minutes of dwell
+			[]string{"G1 X3000 F100", "G1 X0", "G4 S1800"},
+			nil, "0.065 kWh in 1h30m0s: standby 0.015 kWh, motors 0.05 kWh", 0.065,
+		},
+		"heating": {
+			// the bed heats 60 s from 25 to 85 at 200 W, then the hotend 100 s to 225 at 40 W with the bed holding at 50 W
+			[]string{"M190 S85", "M109 S225", "G4 S3600"},
+			nil, "0.086 kWh in 1h2m40s: standby 0.01 kWh, motors 0 kWh, hotend 0 0.021 kWh, bed 0.055 kWh", 0.0862777777777778,
+		},
+		"heated": {
+			// the hotend is at its target, so M109 doesn't wait
+			[]string{"M109 S200", "G4 S3600"},
+			&state.State{Heaters: map[state.Heater]float64{{Kind: state.HeaterHotend}: 200}},
+			"0.03 kWh in 1h0m0s: standby 0.01 kWh, motors 0 kWh, hotend 0 0.02 kWh", 0.03,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []EnergyConfigurationCallbackable
+			if tc.initial != nil {
+				options = append(options, func(config EnergyConfigurer) error {
+					return config.SetInitialState(*tc.initial)
+				})
+			}
+
+			report, err := Energy(blocktest.Parse(t, tc.lines...), model, options...)
+			if err != nil {
+				t.Fatalf("failed to estimate energy: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if got := report.Total(); !near(got, tc.total) {
+				t.Errorf("got total %v, want %v", got, tc.total)
+			}
+		})
+	}
+}
+
+func TestEnergy_invalid(t *testing.T) {
+	cases := map[string]struct {
+		model  PowerModel
+		option EnergyConfigurationCallbackable
+	}{
+		"negative power": {PowerModel{Standby: -1}, nil},
+		"duty above one": {PowerModel{Bed: HeaterPower{Power: 200, Duty: 1.5}}, nil},
+		"infinite ambient": {PowerModel{}, func(config EnergyConfigurer) error {
+			return config.SetAmbientTemperature(math.Inf(1))
+		}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []EnergyConfigurationCallbackable
+			if tc.option != nil {
+				options = append(options, tc.option)
+			}
+
+			if _, err := Energy(nil, tc.model, options...); err == nil {
+				t.Errorf("got nil error, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+const (
+	// DEFAULT_FILAMENT_DIAMETER is the diameter of the filament in millimeters, if it isn't configured.
+	DEFAULT_FILAMENT_DIAMETER = 1.75
+
+	// DEFAULT_FILAMENT_DENSITY is the density of the filament in grams per cubic centimeter, the one of the PLA, if it isn't configured.
+	DEFAULT_FILAMENT_DENSITY = 1.24
+)
+
+//#region material
+
+// Material describes the filament loaded in a tool.
+type Material struct {
+	// Diameter is the diameter of the filament in millimeters.
+	Diameter float64
+
+	// Density is the density of the filament in grams per cubic centimeter.
+	Density float64
+
+	// Cost is the price of a kilogram of filament. It is zero if the cost isn't estimated.
+	Cost float64
+}
+
+// validate returns an error if some property of the material isn't valid.
+func (m Material) validate() error {
+	if !(m.Diameter > 0) || math.IsInf(m.Diameter, 0) {
+		return fmt.Errorf("the diameter must be positive and finite: %v", m.Diameter)
+	}
+
+	if !(m.Density > 0) || math.IsInf(m.Density, 0) {
+		return fmt.Errorf("the density must be positive and finite: %v", m.Density)
+	}
+
+	if !(m.Cost >= 0) || math.IsInf(m.Cost, 0) {
+		return fmt.Errorf("the cost can't be negative nor infinite: %v", m.Cost)
+	}
+
+	return nil
+}
+
+// usage returns the usage of the length of filament extruded, in millimeters.
+func (m Material) usage(length float64) Usage {
+	volume := length * math.Pi * m.Diameter * m.Diameter / 4
+	weight := volume / 1000 * m.Density
+
+	return Usage{Length: length, Volume: volume, Weight: weight, Cost: weight / 1000 * m.Cost}
+}
+
+//#endregion
+//#region filament configuration
+
+// FilamentConfigurer defines the options of the analysis of the filament.
+type FilamentConfigurer interface {
+	// Set the material of all tools
+	SetDefaultMaterial(material Material) error
+
+	// Set the material of a tool
+	SetMaterial(tool int, material Material) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// FilamentConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the filament.
+type FilamentConfigurationCallbackable func(config FilamentConfigurer) error
+
+// filamentConfigurator implements FilamentConfigurer.
+type filamentConfigurator struct {
+	material  Material
+	materials map[int]Material
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetDefaultMaterial defines the material of the tools without a material configured by SetMaterial.
+// If this method isn't called, by default it is PLA of DEFAULT_FILAMENT_DIAMETER and DEFAULT_FILAMENT_DENSITY, without cost.
+func (fc *filamentConfigurator) SetDefaultMaterial(material Material) error {
+	if err := material.validate(); err != nil {
+		return fmt.Errorf("failed to set default material: %w", err)
+	}
+
+	fc.material = material
+
+	return nil
+}
+
+// SetMaterial defines the material of a tool, like a soluble support loaded in the second extruder.
+// If this method isn't called, by default the tool uses the default material.
+func (fc *filamentConfigurator) SetMaterial(tool int, material Material) error {
+	if err := material.validate(); err != nil {
+		return fmt.Errorf("failed to set material of tool %d: %w", tool, err)
+	}
+
+	fc.materials[tool] = material
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (fc *filamentConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	fc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region filament report
+
+// Usage is an amount of filament used.
+type Usage struct {
+	// Length is the length of filament in millimeters.
+	Length float64
+
+	// Volume is the volume of filament in cubic millimeters.
+	Volume float64
+
+	// Weight is the weight of filament in grams.
+	Weight float64
+
+	// Cost is the price of the filament, in the currency of the cost of the materials.
+	Cost float64
+}
+
+// String returns the usage formatted like "1234.5 mm, 2.97 g".
+func (u Usage) String() string {
+	s := fmt.Sprintf("%.1f mm, %.2f g", u.Length, u.Weight)
+	if u.Cost != 0 {
+		s += fmt.Sprintf(", cost %.2f", u.Cost)
+	}
+
+	return s
+}
+
+// add returns the sum of two usages.
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		Length: u.Length + other.Length,
+		Volume: u.Volume + other.Volume,
+		Weight: u.Weight + other.Weight,
+		Cost:   u.Cost + other.Cost,
+	}
+}
+
+// LayerUsage is the filament used by a layer.
+type LayerUsage struct {
+	// Layer is the index of the layer, starting at zero.
+	Layer int
+
+	// Z is the height of the layer.
+	Z float64
+
+	// Usage is the filament used by all tools in the layer.
+	Usage Usage
+}
+
+// FilamentReport contains the filament used by a sequence of blocks.
+type FilamentReport struct {
+	// Total is the filament used by all tools.
+	Total Usage
+
+	// Tools is the filament used by each tool that extruded.
+	Tools map[int]Usage
+
+	// Layers is the filament used by each layer, in order. The extrusion before the first layer is included in the first one,
+	// it is only included in the total and the tools if there isn't any layer.
+	Layers []LayerUsage
+}
+
+// String returns a summary of the report.
+func (r *FilamentReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("filament %s in %d layers", r.Total, len(r.Layers)))
+
+	tools := make([]int, 0, len(r.Tools))
+	for tool := range r.Tools {
+		tools = append(tools, tool)
+	}
+	sort.Ints(tools)
+
+	for _, tool := range tools {
+		sb.WriteString(fmt.Sprintf("\ntool %d: %s", tool, r.Tools[tool]))
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region filament
+
+// Filament computes the filament used by the blocks, in total, per tool and per layer,
+// and converts the length extruded to volume, weight and cost with the material of each tool.
+//
+// The length is the net movement of the extruder, so a retraction followed by the same prime doesn't use filament.
+// The absolute and relative extrusion and the resets of G92 E are handled by the simulation.
+// The extrusion in inches is converted to millimeters. The layers are detected from the heights of the extrusions.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Filament(blocks []block.Blocker, options ...FilamentConfigurationCallbackable) (*FilamentReport, error) {
+
+	configurator := &filamentConfigurator{
+		material:  Material{Diameter: DEFAULT_FILAMENT_DIAMETER, Density: DEFAULT_FILAMENT_DENSITY},
+		materials: map[int]Material{},
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	report := &FilamentReport{Tools: map[int]Usage{}}
+	var layers layerTracker
+	var pending Usage
+
+	for sim.Next() {
+		segment := sim.Segment()
+		current := sim.State()
+
+		layer, started := layers.track(segment)
+		if started {
+			// the extrusion before the first layer, like a prime, is included in it
+			report.Layers = append(report.Layers, LayerUsage{Layer: layer, Z: segment.End.Z, Usage: pending})
+			pending = Usage{}
+		}
+
+		if segment.Extrusion == 0 {
+			continue
+		}
+
+		length := segment.Extrusion
+		if current.Units == state.UnitsInches {
+			length *= gcode.MILLIMETERS_PER_INCH
+		}
+
+		material, ok := configurator.materials[current.Tool]
+		if !ok {
+			material = configurator.material
+		}
+
+		usage := material.usage(length)
+		report.Total = report.Total.add(usage)
+		report.Tools[current.Tool] = report.Tools[current.Tool].add(usage)
+
+		if len(report.Layers) == 0 {
+			pending = pending.add(usage)
+			continue
+		}
+		report.Layers[len(report.Layers)-1].Usage = report.Layers[len(report.Layers)-1].Usage.add(usage)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze filament: %w", err)
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestFilament(t *testing.T) {
+
+	// the section of a filament of 1.75 mm
+	section := math.Pi * 1.75 * 1.75 / 4
+
+	cases := map[string]struct {
+		lines   []string
+		total   float64
+		tools   map[int]float64
+		layers  []float64
+		heights []float64
+	}{
+		"empty": {nil, 0, map[int]float64{}, nil, nil},
+		"absolute with reset": {
+			[]string{"G1 Z0.2", "G1 X10 E1", "G1 X20 E3", "G92 E0", "G1 X30 E2"},
+			5, map[int]float64{0: 5}, []float64{5}, []float64{0.2},
+		},
+		"relative with retraction": {
+			[]string{"M83", "G1 E2", "G1 Z0.2", "G1 X10 E1", "G1 E-0.5", "G1 Z0.4", "G1 E0.5", "G1 X0 E1.5"},
+			4.5, map[int]float64{0: 4.5}, []float64{3, 1.5}, []float64{0.2, 0.4},
+		},
+		"z hop isn't a layer": {
+			[]string{"M83", "G1 Z0.2", "G1 X10 E1", "G0 Z1", "G0 X20", "G0 Z0.2", "G1 X30 E1"},
+			2, map[int]float64{0: 2}, []float64{2}, []float64{0.2},
+		},
+		"tools": {
+			[]string{"M83", "G1 Z0.2", "G1 X10 E1", "T1", "G1 X20 E2", "T0", "G1 Z0.4", "G1 X10 E1"},
+			4, map[int]float64{0: 2, 1: 2}, []float64{3, 1}, []float64{0.2, 0.4},
+		},
+		"inches": {
+			[]string{"G20", "M83", "G1 X1 E0.5"},
+			12.7, map[int]float64{0: 12.7}, []float64{12.7}, []float64{0},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Filament(blocktest.Parse(t, tc.lines...))
+			if err != nil {
+				t.Fatalf("failed to analyze filament: %v", err)
+			}
+
+			if !near(report.Total.Length, tc.total) || !near(report.Total.Volume, tc.total*section) {
+				t.Errorf("got total %+v, want %v mm", report.Total, tc.total)
+			}
+
+			if len(report.Tools) != len(tc.tools) {
+				t.Errorf("got tools %v, want %v", report.Tools, tc.tools)
+			}
+			for tool, length := range tc.tools {
+				if !near(report.Tools[tool].Length, length) {
+					t.Errorf("got tool %d %v mm, want %v mm", tool, report.Tools[tool].Length, length)
+				}
+			}
+
+			if len(report.Layers) != len(tc.layers) {
+				t.Fatalf("got layers %+v, want %v", report.Layers, tc.layers)
+			}
+			for i, length := range tc.layers {
+				layer := report.Layers[i]
+				if layer.Layer != i || !near(layer.Z, tc.heights[i]) || !near(layer.Usage.Length, length) {
+					t.Errorf("got layer %+v, want layer %d at Z%v with %v mm", layer, i, tc.heights[i], length)
+				}
+			}
+		})
+	}
+}
+
+func TestFilament_materials(t *testing.T) {
+	blocks := blocktest.Parse(t, "M83", "G1 X10 E100", "T1", "G1 X20 E100")
+
+	report, err := Filament(blocks, func(config FilamentConfigurer) error {
+		if err := config.SetDefaultMaterial(Material{Diameter: 1.75, Density: 1.25, Cost: 20}); err != nil {
+			return err
+		}
+		return config.SetMaterial(1, Material{Diameter: 2.85, Density: 1.0})
+	})
+	if err != nil {
+		t.Fatalf("failed to analyze filament: %v", err)
+	}
+
+	weight0 := 100 * math.Pi * 1.75 * 1.75 / 4 / 1000 * 1.25
+	weight1 := 100 * math.Pi * 2.85 * 2.85 / 4 / 1000
+
+	if got := report.Tools[0]; !near(got.Weight, weight0) || !near(got.Cost, weight0/1000*20) {
+		t.Errorf("got tool 0 %+v, want %v g", got, weight0)
+	}
+
+	if got := report.Tools[1]; !near(got.Weight, weight1) || got.Cost != 0 {
+		t.Errorf("got tool 1 %+v, want %v g without cost", got, weight1)
+	}
+
+	want := "filament 200.0 mm, 0.94 g, cost 0.01 in 1 layers\ntool 0: 100.0 mm, 0.30 g, cost 0.01\ntool 1: 100.0 mm, 0.64 g"
+	if got := report.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := Filament(blocks, func(config FilamentConfigurer) error {
+		return config.SetMaterial(0, Material{Diameter: 1.75})
+	}); err == nil {
+		t.Errorf("got nil error without density, want an error")
+	}
+}
+
+// near returns true if two values are equal except the rounding errors.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
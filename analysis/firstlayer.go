@@ -0,0 +1,238 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region first layer configuration
+
+// FirstLayerConfigurer defines the options of the analysis of the first layer.
+type FirstLayerConfigurer interface {
+	// Set the diameter of the filament
+	SetFilamentDiameter(diameter float64) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// FirstLayerConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the first layer.
+type FirstLayerConfigurationCallbackable func(config FirstLayerConfigurer) error
+
+// firstLayerConfigurator implements FirstLayerConfigurer.
+type firstLayerConfigurator struct {
+	diameter  float64
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetFilamentDiameter defines the diameter of the filament in millimeters, that converts the length extruded to the area covered.
+// If this method isn't called, by default it is DEFAULT_FILAMENT_DIAMETER.
+func (fc *firstLayerConfigurator) SetFilamentDiameter(diameter float64) error {
+	if !(diameter > 0) || math.IsInf(diameter, 0) {
+		return fmt.Errorf("failed to set filament diameter, it must be positive and finite: %v", diameter)
+	}
+
+	fc.diameter = diameter
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (fc *firstLayerConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	fc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region first layer report
+
+// FirstLayerSpeed is the length extruded at a speed in the first layer.
+type FirstLayerSpeed struct {
+	// Speed is the feedrate of the extrusions in mm/s.
+	Speed float64
+
+	// Length is the length of the extrusions at the speed in millimeters.
+	Length float64
+}
+
+// String returns the speed formatted.
+func (s FirstLayerSpeed) String() string {
+	return fmt.Sprintf("%s mm at %s mm/s", formatRounded(s.Length), formatRounded(s.Speed))
+}
+
+// FirstLayerReport contains the settings and the motion of the first layer, where most failures of a print originate.
+// All lengths are in millimeters.
+type FirstLayerReport struct {
+	// Moves is the number of extrusion moves of the first layer, the other fields are only valid if it is greater than zero.
+	Moves int
+
+	// Z is the height of the first extrusion, that defines how much the first layer is squished against the bed.
+	Z float64
+
+	// Length is the length of the extrusions.
+	Length float64
+
+	// Speeds is the length extruded at each speed, sorted by speed.
+	Speeds []FirstLayerSpeed
+
+	// Average is the length of the extrusions divided by their time in mm/s.
+	Average float64
+
+	// Area is the area covered by the extrusions in square millimeters, their volume divided by the height of the layer.
+	Area float64
+
+	// Box is the bounding box of the extrusions.
+	Box Box
+
+	// Heaters are the targets of the heaters at the first extrusion, like the temperature of the bed.
+	Heaters map[state.Heater]float64
+
+	// Fan is the highest speed of the fans during the extrusions, from 0 to 255.
+	Fan float64
+}
+
+// String returns a summary of the report.
+func (r *FirstLayerReport) String() string {
+	if r.Moves == 0 {
+		return "no first layer"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("first layer at Z%s: %d extrusion moves, %s mm at %s to %s mm/s, average %s mm/s, area %s mm2, box %s",
+		formatRounded(r.Z), r.Moves, formatRounded(r.Length), formatRounded(r.Speeds[0].Speed), formatRounded(r.Speeds[len(r.Speeds)-1].Speed),
+		formatRounded(r.Average), formatRounded(r.Area), r.Box))
+
+	heaters := make([]state.Heater, 0, len(r.Heaters))
+	for heater := range r.Heaters {
+		heaters = append(heaters, heater)
+	}
+	sortHeaters(heaters)
+
+	for _, heater := range heaters {
+		sb.WriteString(fmt.Sprintf(", %s %s", heater, numeric.Format(r.Heaters[heater])))
+	}
+
+	sb.WriteString(fmt.Sprintf(", fan %s%%", formatRounded(math.Round(r.Fan/255*100))))
+
+	return sb.String()
+}
+
+//#endregion
+//#region first layer
+
+// FirstLayer analyzes the first layer of a print: the height of its first extrusion, the distribution of the speeds of its extrusions,
+// the area that they cover, and the targets of the heaters and the speed of the fans while it is printed.
+//
+// The layers are detected like Layers does. The moves that don't extrude aren't analyzed, neither the retractions.
+// The lengths in inches are converted to millimeters.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func FirstLayer(blocks []block.Blocker, options ...FirstLayerConfigurationCallbackable) (*FirstLayerReport, error) {
+
+	configurator := &firstLayerConfigurator{
+		diameter: DEFAULT_FILAMENT_DIAMETER,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	report := &FirstLayerReport{}
+
+	var tracker layerTracker
+	var volume float64
+	var elapsed time.Duration
+	lengths := map[float64]float64{}
+
+	// the segments of an arc are the same move
+	last := -1
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		if layer, _ := tracker.track(segment); layer > 0 {
+			break
+		}
+
+		if !isExtrusion(segment) {
+			continue
+		}
+
+		s := sim.State()
+
+		scale := 1.0
+		if s.Units == state.UnitsInches {
+			scale = gcode.MILLIMETERS_PER_INCH
+		}
+
+		start := Point{X: segment.Start.X * scale, Y: segment.Start.Y * scale, Z: segment.Start.Z * scale}
+		end := Point{X: segment.End.X * scale, Y: segment.End.Y * scale, Z: segment.End.Z * scale}
+
+		if report.Moves == 0 {
+			report.Z = end.Z
+			report.Box = Box{Min: start, Max: start}
+			report.Heaters = map[state.Heater]float64{}
+			for heater, target := range s.Heaters {
+				report.Heaters[heater] = target
+			}
+		}
+
+		if segment.Index != last {
+			report.Moves++
+			last = segment.Index
+		}
+		report.Box = report.Box.extend(start).extend(end)
+
+		length := segment.Length() * scale
+		report.Length += length
+		lengths[segment.Feedrate*scale/60] += length
+		elapsed += duration(segment)
+		volume += segment.Extrusion * scale * math.Pi * configurator.diameter * configurator.diameter / 4
+
+		for _, speed := range s.Fans {
+			if speed > report.Fan {
+				report.Fan = speed
+			}
+		}
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze first layer: %w", err)
+	}
+
+	for speed, length := range lengths {
+		report.Speeds = append(report.Speeds, FirstLayerSpeed{Speed: speed, Length: length})
+	}
+	sort.Slice(report.Speeds, func(i, j int) bool { return report.Speeds[i].Speed < report.Speeds[j].Speed })
+
+	if elapsed > 0 {
+		report.Average = report.Length / elapsed.Seconds()
+	}
+
+	if report.Z > 0 {
+		report.Area = volume / report.Z
+	}
+
+	return report, nil
+}
+
+//#endregion
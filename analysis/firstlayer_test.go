@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestFirstLayer(t *testing.T) {
+	// a filament of one square millimeter, so the volume is the length extruded
+	diameter := func(config FirstLayerConfigurer) error {
+		return config.SetFilamentDiameter(2 / math.Sqrt(math.Pi))
+	}
+
+	cases := map[string]struct {
+		lines  []string
+		want   string
+		speeds []FirstLayerSpeed
+	}{
+		"empty": {nil, "no first layer", nil},
+		"first layer": {
+			[]string{
+				"M140 S60", "M104 S210", "M83", "G0 Z0.2", "G1 X100 E2 F1200", "M106 S128", "G1 Y50 E1 F2400",
+				"G1 X0 E2 F1200", "G0 Z0.4", "M106 S255", "G1 Y0 E1",
+			},
+			"first layer at Z0.2: 3 extrusion moves, 250 mm at 20 to 40 mm/s, average 22.222 mm/s, area 25 mm2, box [X0 Y0 Z0.2, X100 Y50 Z0.2], " +
+				"hotend 0 210, bed 60, fan 50%",
+			[]FirstLayerSpeed{{Speed: 20, Length: 200}, {Speed: 40, Length: 50}},
+		},
+		"inches": {
+			[]string{"G20", "M83", "G1 Z0.01", "G1 X1 E0.1 F60"},
+			"first layer at Z0.254: 1 extrusion moves, 25.4 mm at 25.4 to 25.4 mm/s, average 25.4 mm/s, area 10 mm2, box [X0 Y0 Z0.254, X25.4 Y0 Z0.254], fan 0%",
+			[]FirstLayerSpeed{{Speed: 25.4, Length: 25.4}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := FirstLayer(blocktest.Parse(t, tc.lines...), diameter)
+			if err != nil {
+				t.Fatalf("failed to analyze first layer: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if !reflect.DeepEqual(report.Speeds, tc.speeds) {
+				t.Errorf("got speeds %v, want %v", report.Speeds, tc.speeds)
+			}
+		})
+	}
+}
+
+func TestFirstLayer_invalid(t *testing.T) {
+	if _, err := FirstLayer(nil, func(config FirstLayerConfigurer) error { return config.SetFilamentDiameter(-1) }); err == nil {
+		t.Errorf("got nil error, want error for a negative diameter")
+	}
+
+	if _, err := FirstLayer(blocktest.Parse(t, "G1 X10 E1", "G2 X20 R1 E2")); err == nil {
+		t.Errorf("got nil error, want error for an invalid arc")
+	}
+}
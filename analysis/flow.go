@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region flow configuration
+
+// FlowConfigurer defines the options of the analysis of the volumetric flow.
+type FlowConfigurer interface {
+	// Set the diameter of the filament
+	SetFilamentDiameter(diameter float64) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// FlowConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the volumetric flow.
+type FlowConfigurationCallbackable func(config FlowConfigurer) error
+
+// flowConfigurator implements FlowConfigurer.
+type flowConfigurator struct {
+	diameter  float64
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetFilamentDiameter defines the diameter of the filament in millimeters, that converts the length extruded to volume.
+// If this method isn't called, by default it is DEFAULT_FILAMENT_DIAMETER.
+func (fc *flowConfigurator) SetFilamentDiameter(diameter float64) error {
+	if !(diameter > 0) || math.IsInf(diameter, 0) {
+		return fmt.Errorf("failed to set filament diameter, it must be positive and finite: %v", diameter)
+	}
+
+	fc.diameter = diameter
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (fc *flowConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	fc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region flow report
+
+// FlowMove is the volumetric flow of an extrusion move.
+type FlowMove struct {
+	// Index is the position of the block in the sequence analyzed.
+	Index int
+
+	// Block is the extrusion move.
+	Block block.Blocker
+
+	// Flow is the volume of filament extruded per second in cubic millimeters, the highest one of the segments of an arc.
+	Flow float64
+}
+
+// String returns the move formatted.
+func (m FlowMove) String() string {
+	return fmt.Sprintf("block %d %s: flow %s mm3/s", m.Index, m.Block, formatRounded(m.Flow))
+}
+
+// FlowReport contains the volumetric flow of the extrusion moves.
+type FlowReport struct {
+	// Moves are the extrusion moves, in order.
+	Moves []FlowMove
+
+	// Maximum is the move with the highest flow, it is only valid if there are moves.
+	Maximum FlowMove
+
+	// Average is the volume extruded divided by the time extruding, in cubic millimeters per second.
+	Average float64
+
+	// Limit is the maximum flow of the hotend, zero if it isn't limited.
+	Limit float64
+
+	// Violations are the moves whose flow exceeds the limit, in order.
+	Violations []FlowMove
+}
+
+// Passed returns true if no move exceeds the maximum flow of the hotend.
+func (r *FlowReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String returns a summary of the report.
+func (r *FlowReport) String() string {
+	if len(r.Moves) == 0 {
+		return "no extrusion moves"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d extrusion moves, average flow %s mm3/s, maximum %s mm3/s at block %d",
+		len(r.Moves), formatRounded(r.Average), formatRounded(r.Maximum.Flow), r.Maximum.Index))
+
+	for _, v := range r.Violations {
+		sb.WriteString(fmt.Sprintf("\n%s exceeds the maximum %s", v, numeric.Format(r.Limit)))
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region flow
+
+// Flow computes the volumetric flow of each extrusion move, the volume of filament extruded divided by the time of the move
+// at the feedrate commanded, and flags the moves whose flow exceeds the maximum of the hotend, in cubic millimeters per second.
+// A hotend can't melt the filament faster than its maximum flow, so those moves are printed under-extruded.
+//
+// The moves that only move the extruder, like the retractions and the primes, aren't extrusion moves.
+// A maximum of zero doesn't limit the flow. The extrusion in inches is converted to millimeters.
+//
+// It returns an error if the maximum is negative, some option is invalid or the toolpath can't be simulated.
+func Flow(blocks []block.Blocker, maximum float64, options ...FlowConfigurationCallbackable) (*FlowReport, error) {
+
+	if !(maximum >= 0) || math.IsInf(maximum, 0) {
+		return nil, fmt.Errorf("failed to analyze flow, the maximum can't be negative nor infinite: %v", maximum)
+	}
+
+	configurator := &flowConfigurator{
+		diameter: DEFAULT_FILAMENT_DIAMETER,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	area := math.Pi * configurator.diameter * configurator.diameter / 4
+
+	report := &FlowReport{Limit: maximum}
+	var volume, seconds float64
+
+	for sim.Next() {
+		segment := sim.Segment()
+		if !isExtrusion(segment) {
+			continue
+		}
+
+		elapsed := duration(segment).Seconds()
+		if elapsed == 0 {
+			continue
+		}
+
+		extrusion := segment.Extrusion
+		if sim.State().Units == state.UnitsInches {
+			extrusion *= gcode.MILLIMETERS_PER_INCH
+		}
+
+		flow := extrusion * area / elapsed
+		volume += extrusion * area
+		seconds += elapsed
+
+		// the segments of an arc are the same move
+		if n := len(report.Moves); n > 0 && report.Moves[n-1].Index == segment.Index {
+			if flow > report.Moves[n-1].Flow {
+				report.Moves[n-1].Flow = flow
+			}
+			continue
+		}
+
+		report.Moves = append(report.Moves, FlowMove{Index: segment.Index, Block: segment.Block, Flow: flow})
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze flow: %w", err)
+	}
+
+	if seconds > 0 {
+		report.Average = volume / seconds
+	}
+
+	for i, move := range report.Moves {
+		if i == 0 || move.Flow > report.Maximum.Flow {
+			report.Maximum = move
+		}
+
+		if maximum > 0 && move.Flow > maximum {
+			report.Violations = append(report.Violations, move)
+		}
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestFlow(t *testing.T) {
+	// a filament of one square millimeter, so the flow is the length extruded per second
+	diameter := func(config FlowConfigurer) error {
+		return config.SetFilamentDiameter(2 / math.Sqrt(math.Pi))
+	}
+
+	cases := map[string]struct {
+		lines   []string
+		maximum float64
+		want    string
+		moves   []string
+	}{
+		"empty": {nil, 15, "no extrusion moves", nil},
+		"inside": {
+			[]string{"M83", "G1 X60 E6 F1200", "G1 E-1", "G1 X0 Y0 F6000", "G1 E1"},
+			15, "1 extrusion moves, average flow 2 mm3/s, maximum 2 mm3/s at block 1",
+			[]string{"block 1 G1 X60 E6 F1200: flow 2 mm3/s"},
+		},
+		"above the maximum": {
+			[]string{"M83", "G1 X60 E6 F1200", "G1 X70 E5 F6000", "G1 X80 E1"},
+			15, "3 extrusion moves, average flow 3.75 mm3/s, maximum 50 mm3/s at block 2\n" +
+				"block 2 G1 X70 E5 F6000: flow 50 mm3/s exceeds the maximum 15",
+			[]string{"block 1 G1 X60 E6 F1200: flow 2 mm3/s", "block 2 G1 X70 E5 F6000: flow 50 mm3/s", "block 3 G1 X80 E1: flow 10 mm3/s"},
+		},
+		"unlimited": {
+			[]string{"M83", "G1 X10 E5 F6000"},
+			0, "1 extrusion moves, average flow 50 mm3/s, maximum 50 mm3/s at block 1", []string{"block 1 G1 X10 E5 F6000: flow 50 mm3/s"},
+		},
+		"arc": {
+			[]string{"M83", "G1 X0 Y0 F600", "G2 X20 Y0 I10 J0 E3.1416"},
+			0, "1 extrusion moves, average flow 1 mm3/s, maximum 1 mm3/s at block 2", []string{"block 2 G2 X20 Y0 I10 J0 E3.1416: flow 1 mm3/s"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Flow(blocktest.Parse(t, tc.lines...), tc.maximum, diameter)
+			if err != nil {
+				t.Fatalf("failed to analyze flow: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			var moves []string
+			for _, m := range report.Moves {
+				moves = append(moves, m.String())
+			}
+
+			if !reflect.DeepEqual(moves, tc.moves) {
+				t.Errorf("got moves %q, want %q", moves, tc.moves)
+			}
+		})
+	}
+}
+
+func TestFlow_invalid(t *testing.T) {
+	if _, err := Flow(nil, -1); err == nil {
+		t.Errorf("got nil, want error for a negative maximum")
+	}
+
+	if _, err := Flow(nil, 15, func(config FlowConfigurer) error { return config.SetFilamentDiameter(0) }); err == nil {
+		t.Errorf("got nil, want error for a diameter zero")
+	}
+}
@@ -0,0 +1,270 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region kinematic limits
+
+// KinematicLimits are the kinematic limits of a machine, in millimeters and seconds. A zero value doesn't limit.
+//
+// The arrays are indexed as X, Y, Z and E, like the M203 command of Marlin.
+type KinematicLimits struct {
+	// MaxFeedrates is the maximum speed of each axis in mm/s. It limits the moves and the M203 commands.
+	MaxFeedrates [4]float64
+
+	// MaxAxisAccelerations is the maximum acceleration of each axis in mm/s². It limits the M201 commands.
+	MaxAxisAccelerations [4]float64
+
+	// MaxAcceleration is the maximum acceleration of the moves that print, set by M204 P, or S.
+	MaxAcceleration float64
+
+	// MaxTravelAcceleration is the maximum acceleration of the travel moves, set by M204 T, or S.
+	MaxTravelAcceleration float64
+
+	// MaxRetractAcceleration is the maximum acceleration of the retractions, set by M204 R.
+	MaxRetractAcceleration float64
+
+	// MaxJerk is the maximum instantaneous change of speed of each axis in mm/s, set by M205 X, Y, Z and E.
+	MaxJerk [4]float64
+
+	// MaxJunctionDeviation is the maximum junction deviation in mm, set by M205 J.
+	MaxJunctionDeviation float64
+}
+
+// limitAxes are the words of the axes, in the order of the arrays of the limits.
+var limitAxes = [4]byte{'X', 'Y', 'Z', 'E'}
+
+//#endregion
+//#region kinematics configuration
+
+// KinematicsConfigurer defines the options of the validation of the kinematics.
+type KinematicsConfigurer interface {
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// KinematicsConfigurationCallbackable is the signature of the callbacks used to configure the validation of the kinematics.
+type KinematicsConfigurationCallbackable func(config KinematicsConfigurer) error
+
+// kinematicsConfigurator implements KinematicsConfigurer.
+type kinematicsConfigurator struct {
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (kc *kinematicsConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	kc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region kinematics report
+
+// KinematicViolation describes a block that exceeds a kinematic limit.
+type KinematicViolation struct {
+	// Index is the position of the block in the sequence validated.
+	Index int
+
+	// Block is the block that exceeds the limit.
+	Block block.Blocker
+
+	// Limit is the name of the limit exceeded, like "feedrate X", "acceleration" or "jerk E".
+	Limit string
+
+	// Value is the value commanded and Maximum is the limit.
+	Value, Maximum float64
+}
+
+// String returns the violation formatted.
+func (v KinematicViolation) String() string {
+	return fmt.Sprintf("block %d %s: %s %s exceeds the maximum %s", v.Index, v.Block, v.Limit, formatRounded(v.Value), formatRounded(v.Maximum))
+}
+
+// KinematicsReport contains the violations of the kinematic limits.
+type KinematicsReport struct {
+	// Violations are the violations in the order of the blocks. A block exceeds each limit once at most.
+	Violations []KinematicViolation
+}
+
+// Passed returns true if no block exceeds the limits.
+func (r *KinematicsReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String returns a summary of the report.
+func (r *KinematicsReport) String() string {
+	if r.Passed() {
+		return "kinematics inside the limits"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d kinematic violations", len(r.Violations)))
+
+	for _, v := range r.Violations {
+		sb.WriteString("\n")
+		sb.WriteString(v.String())
+	}
+
+	return sb.String()
+}
+
+// add appends a violation if the value exceeds the maximum, and the block didn't exceed the same limit yet.
+func (r *KinematicsReport) add(index int, b block.Blocker, limit string, value float64, maximum float64) {
+	if maximum <= 0 || value <= maximum {
+		return
+	}
+
+	for i := len(r.Violations) - 1; i >= 0 && r.Violations[i].Index == index; i-- {
+		if r.Violations[i].Limit == limit {
+			return
+		}
+	}
+
+	r.Violations = append(r.Violations, KinematicViolation{Index: index, Block: b, Limit: limit, Value: value, Maximum: maximum})
+}
+
+//#endregion
+//#region kinematics
+
+// Kinematics validates the feedrates of the moves, and the accelerations and jerks commanded, against the kinematic limits of a machine,
+// so a file that would stress a fragile or slow machine is detected before it is sent.
+//
+// The feedrate of each move is decomposed along its direction, so each axis is checked against its own limit.
+// The extruder is checked along the XYZ distance, like the firmwares do, or alone if the move only extrudes.
+// The moves in inches are converted to millimeters. The settings of the firmware commanded by M201, M203, M204 and M205 are checked too.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Kinematics(blocks []block.Blocker, limits KinematicLimits, options ...KinematicsConfigurationCallbackable) (*KinematicsReport, error) {
+
+	configurator := &kinematicsConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &KinematicsReport{}
+
+	for index, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		switch b.Command().String() {
+		case "M201":
+			checkAxes(report, index, b, "axis acceleration", limits.MaxAxisAccelerations)
+		case "M203":
+			checkAxes(report, index, b, "feedrate", limits.MaxFeedrates)
+		case "M204", "M205":
+			checkLimits(report, index, b, limits)
+		}
+	}
+
+	if limits.MaxFeedrates != [4]float64{} {
+		if err := checkFeedrates(report, blocks, limits, configurator.simulator); err != nil {
+			return nil, err
+		}
+	}
+
+	// the settings and the moves are checked separately, so the violations are sorted by block
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		return report.Violations[i].Index < report.Violations[j].Index
+	})
+
+	return report, nil
+}
+
+// checkAxes checks the value of each axis of a firmware setting against its limit.
+func checkAxes(report *KinematicsReport, index int, b block.Blocker, limit string, maximums [4]float64) {
+	for i, word := range limitAxes {
+		if value, ok := blockparam.Number(b, word); ok {
+			report.add(index, b, limit+" "+string(word), value, maximums[i])
+		}
+	}
+}
+
+// checkLimits checks the accelerations of M204 and the jerks of M205, read like state.State tracks them,
+// so S of M204 sets both the print and the travel accelerations.
+func checkLimits(report *KinematicsReport, index int, b block.Blocker, limits KinematicLimits) {
+	// the limits that the block doesn't command are zero, so they don't exceed any maximum
+	var commanded state.State
+	commanded.Apply(b)
+	l := commanded.Limits
+
+	report.add(index, b, "acceleration", l.Acceleration, limits.MaxAcceleration)
+	report.add(index, b, "travel acceleration", l.TravelAcceleration, limits.MaxTravelAcceleration)
+	report.add(index, b, "retract acceleration", l.RetractAcceleration, limits.MaxRetractAcceleration)
+
+	for i, word := range limitAxes {
+		report.add(index, b, "jerk "+string(word), l.Jerk[i], limits.MaxJerk[i])
+	}
+
+	report.add(index, b, "junction deviation", l.JunctionDeviation, limits.MaxJunctionDeviation)
+}
+
+// checkFeedrates checks the speed of each axis of the moves against its limit.
+func checkFeedrates(report *KinematicsReport, blocks []block.Blocker, limits KinematicLimits, options []simulator.SimulatorConfigurationCallbackable) error {
+	sim, err := simulator.New(blocks, options...)
+	if err != nil {
+		return fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	for sim.Next() {
+		segment := sim.Segment()
+		if segment.Feedrate <= 0 {
+			continue
+		}
+
+		// the feedrate is in units per minute
+		speed := segment.Feedrate / 60
+		if sim.State().Units == state.UnitsInches {
+			speed *= gcode.MILLIMETERS_PER_INCH
+		}
+
+		deltas := [4]float64{
+			segment.End.X - segment.Start.X,
+			segment.End.Y - segment.Start.Y,
+			segment.End.Z - segment.Start.Z,
+			segment.Extrusion,
+		}
+
+		length := segment.Length()
+		if length == 0 {
+			length = math.Abs(segment.Extrusion)
+		}
+
+		for i, word := range limitAxes {
+			report.add(segment.Index, segment.Block, "feedrate "+string(word), speed*math.Abs(deltas[i])/length, limits.MaxFeedrates[i])
+		}
+	}
+
+	if err := sim.Err(); err != nil {
+		return fmt.Errorf("failed to validate kinematics: %w", err)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// formatRounded returns a value rounded to three decimals, without the noise of the calculations.
+func formatRounded(value float64) string {
+	return numeric.Format(math.Round(value*1000) / 1000)
+}
+
+//#endregion
@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestKinematics(t *testing.T) {
+	limits := KinematicLimits{
+		MaxFeedrates:           [4]float64{300, 300, 10, 60},
+		MaxAxisAccelerations:   [4]float64{3000, 3000, 100, 5000},
+		MaxAcceleration:        1500,
+		MaxTravelAcceleration:  3000,
+		MaxRetractAcceleration: 1000,
+		MaxJerk:                [4]float64{10, 10, 0.4, 5},
+		MaxJunctionDeviation:   0.05,
+	}
+
+	cases := map[string]struct {
+		lines []string
+		want  []string
+	}{
+		"empty":  {nil, nil},
+		"inside": {[]string{"G1 X100 Y100 F18000", "G1 Z10 F600", "M204 P1500 T3000", "M205 X10 Y10 J0.05"}, nil},
+		"diagonal inside": {
+			// 400 mm/s along the diagonal is 282.8 mm/s per axis
+			[]string{"G1 X100 Y100 F24000"}, nil,
+		},
+		"feedrate": {
+			[]string{"G1 X100 F24000", "G1 Z5 F1200", "G1 X110"},
+			[]string{
+				"block 0 G1 X100 F24000: feedrate X 400 exceeds the maximum 300",
+				"block 1 G1 Z5 F1200: feedrate Z 20 exceeds the maximum 10",
+			},
+		},
+		"extruder": {
+			[]string{"G1 E-5 F4800", "G92 E0", "G1 X1 E5 F600"},
+			[]string{"block 0 G1 E-5 F4800: feedrate E 80 exceeds the maximum 60"},
+		},
+		"inches": {
+			[]string{"G20", "G1 X10 F720"},
+			[]string{"block 1 G1 X10 F720: feedrate X 304.8 exceeds the maximum 300"},
+		},
+		"arc once per axis": {
+			[]string{"G1 X0 Y0 F30000", "G2 X20 Y0 I10 J0"},
+			[]string{
+				"block 1 G2 X20 Y0 I10 J0: feedrate Y 499.524 exceeds the maximum 300",
+				"block 1 G2 X20 Y0 I10 J0: feedrate X 304.381 exceeds the maximum 300",
+			},
+		},
+		"settings": {
+			[]string{"M201 X5000 Z50", "M203 E120", "M204 S2000 R1200", "M205 X20 E5 J0.1", "M204 T3500"},
+			[]string{
+				"block 0 M201 X5000 Z50: axis acceleration X 5000 exceeds the maximum 3000",
+				"block 1 M203 E120: feedrate E 120 exceeds the maximum 60",
+				"block 2 M204 S2000 R1200: acceleration 2000 exceeds the maximum 1500",
+				"block 2 M204 S2000 R1200: retract acceleration 1200 exceeds the maximum 1000",
+				"block 3 M205 X20 E5 J0.1: jerk X 20 exceeds the maximum 10",
+				"block 3 M205 X20 E5 J0.1: junction deviation 0.1 exceeds the maximum 0.05",
+				"block 4 M204 T3500: travel acceleration 3500 exceeds the maximum 3000",
+			},
+		},
+		"settings and moves in order": {
+			[]string{"G1 X100 F24000", "M204 P2000"},
+			[]string{
+				"block 0 G1 X100 F24000: feedrate X 400 exceeds the maximum 300",
+				"block 1 M204 P2000: acceleration 2000 exceeds the maximum 1500",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Kinematics(blocktest.Parse(t, tc.lines...), limits)
+			if err != nil {
+				t.Fatalf("failed to validate kinematics: %v", err)
+			}
+
+			var got []string
+			for _, v := range report.Violations {
+				got = append(got, v.String())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.Passed() != (len(tc.want) == 0) {
+				t.Errorf("got passed %v, want %v", report.Passed(), len(tc.want) == 0)
+			}
+		})
+	}
+}
+
+func TestKinematics_unlimited(t *testing.T) {
+	report, err := Kinematics(blocktest.Parse(t, "G1 X100 F100000", "M204 S100000", "M205 X100"), KinematicLimits{})
+	if err != nil {
+		t.Fatalf("failed to validate kinematics: %v", err)
+	}
+
+	if got := report.String(); got != "kinematics inside the limits" {
+		t.Errorf("got %q, want no violations", got)
+	}
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"math"
+
+	"github.com/mauroalderete/gcode-core/simulator"
+)
+
+// LAYER_TOLERANCE is the minimum difference of height between two layers, smaller differences are noise of the coordinates.
+const LAYER_TOLERANCE = 1e-6
+
+//#region layer tracker
+
+// layerTracker detects the layers from the motion: a layer is the set of moves between two changes of the height
+// at which the machine extrudes. The travel moves, like the Z hops, don't change the layer.
+//
+// The moves before the first extrusion belong to the first layer, so all moves have a layer.
+type layerTracker struct {
+	// index of the current layer, starting at zero
+	layer int
+
+	// height of the current layer, it is only valid if known is true
+	z     float64
+	known bool
+}
+
+// track updates the layer with a segment and returns the index of the layer of the segment and true if it starts a new layer.
+func (lt *layerTracker) track(segment simulator.Segment) (int, bool) {
+	if !isExtrusion(segment) {
+		return lt.layer, false
+	}
+
+	z := segment.End.Z
+
+	if !lt.known {
+		lt.z, lt.known = z, true
+		return lt.layer, true
+	}
+
+	if math.Abs(z-lt.z) <= LAYER_TOLERANCE {
+		return lt.layer, false
+	}
+
+	lt.layer++
+	lt.z = z
+
+	return lt.layer, true
+}
+
+//#endregion
+//#region private functions
+
+// isExtrusion returns true if the segment extrudes while it moves in the plane XY, like the moves that print.
+// The retractions and the primes, that only move the extruder, aren't extrusions.
+func isExtrusion(segment simulator.Segment) bool {
+	return segment.Extrusion > 0 && (segment.Start.X != segment.End.X || segment.Start.Y != segment.End.Y)
+}
+
+//#endregion
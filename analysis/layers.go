@@ -0,0 +1,293 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+)
+
+// DEFAULT_HEIGHT_TOLERANCE is the maximum difference between two heights considered equal, if it isn't configured.
+const DEFAULT_HEIGHT_TOLERANCE = 0.001
+
+//#region layers configuration
+
+// LayersConfigurer defines the options of the analysis of the layers.
+type LayersConfigurer interface {
+	// Set the maximum difference between two heights considered equal
+	SetTolerance(tolerance float64) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// LayersConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the layers.
+type LayersConfigurationCallbackable func(config LayersConfigurer) error
+
+// layersConfigurator implements LayersConfigurer.
+type layersConfigurator struct {
+	tolerance float64
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetTolerance defines the maximum difference between two heights considered equal,
+// used to detect the variable layer heights and to verify the claims of the slicer. It can't be negative.
+// If this method isn't called, by default it is DEFAULT_HEIGHT_TOLERANCE.
+func (lc *layersConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance >= 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it can't be negative nor infinite: %v", tolerance)
+	}
+
+	lc.tolerance = tolerance
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (lc *layersConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	lc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region layers report
+
+// Layer is a layer detected from the motion.
+type Layer struct {
+	// Index is the index of the layer, starting at zero.
+	Index int
+
+	// Block is the position of the block of the first extrusion of the layer.
+	Block int
+
+	// Z is the height at which the layer is extruded.
+	Z float64
+
+	// Height is the thickness of the layer, the distance to the previous layer.
+	// It is the height of the layer if it is the first one or it is lower than the previous one, like the first layer of an object printed in sequence.
+	Height float64
+}
+
+// LayerClaims are the properties of the layers declared by the slicer, verified by LayersReport.Verify.
+// A zero value isn't verified.
+type LayerClaims struct {
+	// LayerHeight is the height of the layers, except the first one.
+	LayerHeight float64
+
+	// FirstLayerHeight is the height of the first layer.
+	FirstLayerHeight float64
+
+	// Count is the number of layers.
+	Count int
+}
+
+// LayersReport contains the layers detected from the motion of a sequence of blocks.
+type LayersReport struct {
+	// Layers are the layers in the order they are printed.
+	Layers []Layer
+
+	// LayerHeight is the most frequent height of the layers, except the first one. It is zero if there are less than two layers.
+	LayerHeight float64
+
+	// MinHeight and MaxHeight are the lowest and the highest heights of the layers, except the first one.
+	MinHeight, MaxHeight float64
+
+	// Variable is true if the heights of the layers, except the first one, differ more than the tolerance, like a variable layer height profile.
+	Variable bool
+
+	// tolerance of the comparison of heights
+	tolerance float64
+}
+
+// Count returns the number of layers.
+func (r *LayersReport) Count() int {
+	return len(r.Layers)
+}
+
+// FirstLayerHeight returns the height of the first layer, or zero if there aren't layers.
+func (r *LayersReport) FirstLayerHeight() float64 {
+	if len(r.Layers) == 0 {
+		return 0
+	}
+
+	return r.Layers[0].Height
+}
+
+// Heights returns the height of each layer, the profile of the heights of a variable layer height print.
+func (r *LayersReport) Heights() []float64 {
+	heights := make([]float64, len(r.Layers))
+	for i, layer := range r.Layers {
+		heights[i] = layer.Height
+	}
+
+	return heights
+}
+
+// Verify compares the layers detected with the claims of the slicer, like the header or the settings of the file,
+// and returns a message for each difference. It returns nil if all claims match.
+//
+// A variable layer height print doesn't match a layer height claimed.
+func (r *LayersReport) Verify(claims LayerClaims) []string {
+	var differences []string
+
+	if claims.Count != 0 && claims.Count != r.Count() {
+		differences = append(differences, fmt.Sprintf("found %d layers, claimed %d", r.Count(), claims.Count))
+	}
+
+	if claims.FirstLayerHeight != 0 && math.Abs(claims.FirstLayerHeight-r.FirstLayerHeight()) > r.tolerance {
+		differences = append(differences, fmt.Sprintf("found first layer height %s, claimed %s", numeric.Format(r.FirstLayerHeight()), numeric.Format(claims.FirstLayerHeight)))
+	}
+
+	if claims.LayerHeight != 0 && r.Count() > 1 &&
+		(math.Abs(claims.LayerHeight-r.MinHeight) > r.tolerance || math.Abs(claims.LayerHeight-r.MaxHeight) > r.tolerance) {
+		found := numeric.Format(r.LayerHeight)
+		if r.Variable {
+			found = fmt.Sprintf("from %s to %s", numeric.Format(r.MinHeight), numeric.Format(r.MaxHeight))
+		}
+		differences = append(differences, fmt.Sprintf("found layer height %s, claimed %s", found, numeric.Format(claims.LayerHeight)))
+	}
+
+	return differences
+}
+
+// String returns a summary of the report.
+func (r *LayersReport) String() string {
+	switch r.Count() {
+	case 0:
+		return "no layers"
+	case 1:
+		return fmt.Sprintf("1 layer of %s", numeric.Format(r.FirstLayerHeight()))
+	}
+
+	if r.Variable {
+		return fmt.Sprintf("%d layers, first layer %s, variable height from %s to %s",
+			r.Count(), numeric.Format(r.FirstLayerHeight()), numeric.Format(r.MinHeight), numeric.Format(r.MaxHeight))
+	}
+
+	return fmt.Sprintf("%d layers, first layer %s, height %s", r.Count(), numeric.Format(r.FirstLayerHeight()), numeric.Format(r.LayerHeight))
+}
+
+//#endregion
+//#region layers
+
+// Layers detects the layers of the blocks from their motion, not from the comments of the slicer,
+// so the claims of the slicer can be verified. A layer starts when the machine extrudes at a new height,
+// the travel moves, like the Z hops, don't start layers.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Layers(blocks []block.Blocker, options ...LayersConfigurationCallbackable) (*LayersReport, error) {
+
+	configurator := &layersConfigurator{
+		tolerance: DEFAULT_HEIGHT_TOLERANCE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	report := &LayersReport{tolerance: configurator.tolerance}
+	var tracker layerTracker
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		layer, started := tracker.track(segment)
+		if !started {
+			continue
+		}
+
+		height := segment.End.Z
+		if layer > 0 {
+			if previous := report.Layers[layer-1].Z; height > previous {
+				height -= previous
+			}
+		}
+
+		report.Layers = append(report.Layers, Layer{Index: layer, Block: segment.Index, Z: segment.End.Z, Height: roundHeight(height)})
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze layers: %w", err)
+	}
+
+	report.summarize()
+
+	return report, nil
+}
+
+// summarize calculates the statistics of the heights of the layers, except the first one.
+func (r *LayersReport) summarize() {
+	if len(r.Layers) < 2 {
+		return
+	}
+
+	frequencies := map[float64]int{}
+	r.MinHeight, r.MaxHeight = math.Inf(1), math.Inf(-1)
+
+	for _, layer := range r.Layers[1:] {
+		frequencies[layer.Height]++
+		r.MinHeight = math.Min(r.MinHeight, layer.Height)
+		r.MaxHeight = math.Max(r.MaxHeight, layer.Height)
+	}
+
+	heights := make([]float64, 0, len(frequencies))
+	for height := range frequencies {
+		heights = append(heights, height)
+	}
+	sort.Float64s(heights)
+
+	// the lowest of the most frequent heights, so the result doesn't depend on the order of the map
+	for _, height := range heights {
+		if frequencies[height] > frequencies[r.LayerHeight] {
+			r.LayerHeight = height
+		}
+	}
+
+	r.Variable = r.MaxHeight-r.MinHeight > r.tolerance
+}
+
+//#endregion
+//#region claims
+
+// ClaimsFromSettings returns the layer heights declared by the settings of PrusaSlicer and its forks,
+// the "layer_height" and "first_layer_height" settings. The settings missing or expressed as a percentage aren't claimed.
+func ClaimsFromSettings(settings *document.SlicerSettings) LayerClaims {
+	var claims LayerClaims
+
+	if value, err := settings.Float("layer_height"); err == nil {
+		claims.LayerHeight = value
+	}
+
+	// the first layer height can be a percentage of the nozzle diameter
+	if value, ok := settings.Get("first_layer_height"); ok && !strings.HasSuffix(value, "%") {
+		if height, err := settings.Float("first_layer_height"); err == nil {
+			claims.FirstLayerHeight = height
+		}
+	}
+
+	return claims
+}
+
+//#endregion
+//#region private functions
+
+// roundHeight rounds a height to the tolerance of the layers, so 0.4 - 0.2 is 0.2 instead of 0.20000000000000004.
+func roundHeight(height float64) float64 {
+	return math.Round(height/LAYER_TOLERANCE) / (1 / LAYER_TOLERANCE)
+}
+
+//#endregion
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestLayers(t *testing.T) {
+
+	cases := map[string]struct {
+		lines   []string
+		want    string
+		heights []float64
+		blocks  []int
+	}{
+		"empty":       {nil, "no layers", []float64{}, nil},
+		"travel only": {[]string{"G0 X10 Z5", "G0 Z10"}, "no layers", []float64{}, nil},
+		"single layer": {
+			[]string{"G1 Z0.3", "G1 X10 E1", "G1 Y10 E2"},
+			"1 layer of 0.3", []float64{0.3}, []int{1},
+		},
+		"constant": {
+			[]string{"G1 Z0.3", "G1 X10 E1", "G1 Z0.5", "G1 X0 E2", "G1 Z0.7", "G1 X10 E3", "G1 Z0.9", "G1 X0 E4"},
+			"4 layers, first layer 0.3, height 0.2", []float64{0.3, 0.2, 0.2, 0.2}, []int{1, 3, 5, 7},
+		},
+		"z hop and prime": {
+			[]string{"G1 Z0.2", "G1 X10 E1", "G1 E0.5", "G0 Z1", "G0 X0 Y5", "G0 Z0.2", "G1 E1", "G1 X10 E2", "G1 Z0.4", "G1 X0 E3"},
+			"2 layers, first layer 0.2, height 0.2", []float64{0.2, 0.2}, []int{1, 9},
+		},
+		"variable": {
+			[]string{"G1 Z0.2", "G1 X10 E1", "G1 Z0.4", "G1 X0 E2", "G1 Z0.5", "G1 X10 E3", "G1 Z0.6", "G1 X0 E4", "G1 Z0.75", "G1 X10 E5"},
+			"5 layers, first layer 0.2, variable height from 0.1 to 0.2", []float64{0.2, 0.2, 0.1, 0.1, 0.15}, []int{1, 3, 5, 7, 9},
+		},
+		"sequential objects": {
+			[]string{"G1 Z0.2", "G1 X10 E1", "G1 Z0.4", "G1 X0 E2", "G0 Z10", "G0 X50", "G0 Z0.2", "G1 X60 E3", "G1 Z0.4", "G1 X50 E4"},
+			"4 layers, first layer 0.2, height 0.2", []float64{0.2, 0.2, 0.2, 0.2}, []int{1, 3, 7, 9},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Layers(blocktest.Parse(t, tc.lines...))
+			if err != nil {
+				t.Fatalf("failed to analyze layers: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if got := report.Heights(); !reflect.DeepEqual(got, tc.heights) {
+				t.Errorf("got heights %v, want %v", got, tc.heights)
+			}
+
+			var blocks []int
+			for _, layer := range report.Layers {
+				blocks = append(blocks, layer.Block)
+			}
+			if !reflect.DeepEqual(blocks, tc.blocks) {
+				t.Errorf("got first blocks %v, want %v", blocks, tc.blocks)
+			}
+		})
+	}
+}
+
+func TestLayersReport_Verify(t *testing.T) {
+	constant := blocktest.Parse(t, "G1 Z0.3", "G1 X10 E1", "G1 Z0.5", "G1 X0 E2", "G1 Z0.7", "G1 X10 E3")
+	variable := blocktest.Parse(t, "G1 Z0.2", "G1 X10 E1", "G1 Z0.4", "G1 X0 E2", "G1 Z0.5", "G1 X10 E3")
+
+	cases := map[string]struct {
+		variable bool
+		claims   LayerClaims
+		want     []string
+	}{
+		"nothing claimed":  {false, LayerClaims{}, nil},
+		"match":            {false, LayerClaims{LayerHeight: 0.2, FirstLayerHeight: 0.3, Count: 3}, nil},
+		"within tolerance": {false, LayerClaims{LayerHeight: 0.2005}, nil},
+		"mismatch": {
+			false, LayerClaims{LayerHeight: 0.15, FirstLayerHeight: 0.2, Count: 40},
+			[]string{"found 3 layers, claimed 40", "found first layer height 0.3, claimed 0.2", "found layer height 0.2, claimed 0.15"},
+		},
+		"variable": {
+			true, LayerClaims{LayerHeight: 0.2},
+			[]string{"found layer height from 0.1 to 0.2, claimed 0.2"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			blocks := constant
+			if tc.variable {
+				blocks = variable
+			}
+
+			report, err := Layers(blocks)
+			if err != nil {
+				t.Fatalf("failed to analyze layers: %v", err)
+			}
+
+			if got := report.Verify(tc.claims); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClaimsFromSettings(t *testing.T) {
+	cases := map[string]struct {
+		settings map[string]string
+		want     LayerClaims
+	}{
+		"empty":      {nil, LayerClaims{}},
+		"heights":    {map[string]string{"layer_height": "0.15", "first_layer_height": "0.2"}, LayerClaims{LayerHeight: 0.15, FirstLayerHeight: 0.2}},
+		"percentage": {map[string]string{"layer_height": "0.2", "first_layer_height": "75%"}, LayerClaims{LayerHeight: 0.2}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			settings := document.NewSlicerSettings("prusaslicer")
+			for key, value := range tc.settings {
+				if err := settings.Set(key, value); err != nil {
+					t.Fatalf("failed to set %s: %v", key, err)
+				}
+			}
+
+			if got := ClaimsFromSettings(settings); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region motion configuration
+
+// MotionConfigurer defines the options of the statistics of the motion.
+type MotionConfigurer interface {
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+
+	// Set if the times consider the accelerations
+	SetAccelerations(enabled bool) error
+}
+
+// MotionConfigurationCallbackable is the signature of the callbacks used to configure the statistics of the motion.
+type MotionConfigurationCallbackable func(config MotionConfigurer) error
+
+// motionConfigurator implements MotionConfigurer.
+type motionConfigurator struct {
+	simulator     []simulator.SimulatorConfigurationCallbackable
+	accelerations bool
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (mc *motionConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	mc.simulator = options
+
+	return nil
+}
+
+// SetAccelerations defines if the times consider the accelerations, jerks and velocities commanded by M204, M205 and the initial state,
+// otherwise the moves last their length at the feedrate commanded.
+// If this method isn't called, by default the accelerations aren't considered.
+func (mc *motionConfigurator) SetAccelerations(enabled bool) error {
+	mc.accelerations = enabled
+
+	return nil
+}
+
+//#endregion
+//#region motion report
+
+// MotionStats are the distances and the times of the travel moves and the extrusions.
+// The distances are in millimeters and the times are at the feedrate commanded, without accelerations unless they are enabled.
+type MotionStats struct {
+	// Travel is the distance moved without extruding, the retractions while moving included.
+	Travel float64
+
+	// Extrusion is the distance moved while extruding.
+	Extrusion float64
+
+	// Retractions is the number of blocks that move the extruder backwards.
+	Retractions int
+
+	// Retraction is the length of filament retracted.
+	Retraction float64
+
+	// TravelTime is the time of the travel moves.
+	TravelTime time.Duration
+
+	// ExtrusionTime is the time of the moves that extrude.
+	ExtrusionTime time.Duration
+
+	// RetractionTime is the time of the moves of the extruder alone, like the retractions and the primes.
+	RetractionTime time.Duration
+}
+
+// TravelRatio returns the fraction of the distance moved without extruding, from 0 to 1. It is zero if the machine doesn't move.
+func (s MotionStats) TravelRatio() float64 {
+	if s.Travel+s.Extrusion == 0 {
+		return 0
+	}
+
+	return s.Travel / (s.Travel + s.Extrusion)
+}
+
+// Time returns the time of all moves.
+func (s MotionStats) Time() time.Duration {
+	return s.TravelTime + s.ExtrusionTime + s.RetractionTime
+}
+
+// String returns the statistics formatted like "travel 120.0 mm in 2s (25%), extrusion 360.0 mm in 12s, 3 retractions of 2.4 mm".
+func (s MotionStats) String() string {
+	return fmt.Sprintf("travel %.1f mm in %s (%.0f%%), extrusion %.1f mm in %s, %d retractions of %.1f mm",
+		s.Travel, s.TravelTime.Round(time.Second), s.TravelRatio()*100,
+		s.Extrusion, s.ExtrusionTime.Round(time.Second),
+		s.Retractions, s.Retraction)
+}
+
+// add returns the sum of two statistics.
+func (s MotionStats) add(other MotionStats) MotionStats {
+	return MotionStats{
+		Travel:         s.Travel + other.Travel,
+		Extrusion:      s.Extrusion + other.Extrusion,
+		Retractions:    s.Retractions + other.Retractions,
+		Retraction:     s.Retraction + other.Retraction,
+		TravelTime:     s.TravelTime + other.TravelTime,
+		ExtrusionTime:  s.ExtrusionTime + other.ExtrusionTime,
+		RetractionTime: s.RetractionTime + other.RetractionTime,
+	}
+}
+
+// LayerMotion are the statistics of the motion of a layer.
+type LayerMotion struct {
+	// Layer is the index of the layer, starting at zero.
+	Layer int
+
+	// Z is the height of the layer.
+	Z float64
+
+	MotionStats
+}
+
+// MotionReport contains the statistics of the motion of a sequence of blocks.
+type MotionReport struct {
+	// Total are the statistics of all moves.
+	Total MotionStats
+
+	// Layers are the statistics of each layer, in order. The moves before the first layer are included in the first one,
+	// they are only included in the total if there isn't any layer.
+	Layers []LayerMotion
+}
+
+// String returns a summary of the report.
+func (r *MotionReport) String() string {
+	return fmt.Sprintf("%s in %d layers", r.Total, len(r.Layers))
+}
+
+//#endregion
+//#region motion
+
+// Motion measures the distance and the time of the travel moves and the extrusions, and the retractions,
+// in total and per layer, to assess how efficient the toolpath generated by a slicer is.
+//
+// A move extrudes if the extruder advances while the machine moves, any other move is a travel.
+// A retraction is a block whose moves take the extruder backwards; an arc is a single retraction.
+// The distances in inches are converted to millimeters. The layers are detected from the heights of the extrusions.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Motion(blocks []block.Blocker, options ...MotionConfigurationCallbackable) (*MotionReport, error) {
+
+	configurator := &motionConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	report := &MotionReport{}
+	var layers layerTracker
+	var pending MotionStats
+	retracting := -1
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		layer, started := layers.track(segment)
+		if started {
+			// the moves before the first layer, like a purge line, are included in it
+			report.Layers = append(report.Layers, LayerMotion{Layer: layer, Z: segment.End.Z, MotionStats: pending})
+			pending = MotionStats{}
+		}
+
+		scale := 1.0
+		if sim.State().Units == state.UnitsInches {
+			scale = gcode.MILLIMETERS_PER_INCH
+		}
+
+		elapsed := duration(segment)
+		if configurator.accelerations {
+			elapsed = acceleratedDuration(segment, sim.State())
+		}
+
+		var stats MotionStats
+		length := segment.Length() * scale
+		switch {
+		case length == 0:
+			stats.RetractionTime = elapsed
+		case segment.Extrusion > 0:
+			stats.Extrusion = length
+			stats.ExtrusionTime = elapsed
+		default:
+			stats.Travel = length
+			stats.TravelTime = elapsed
+		}
+
+		if segment.Extrusion < 0 {
+			stats.Retraction = -segment.Extrusion * scale
+
+			// the segments of an arc are the same retraction
+			if segment.Index != retracting {
+				stats.Retractions = 1
+				retracting = segment.Index
+			}
+		}
+
+		report.Total = report.Total.add(stats)
+
+		if len(report.Layers) == 0 {
+			pending = pending.add(stats)
+			continue
+		}
+		last := &report.Layers[len(report.Layers)-1]
+		last.MotionStats = last.MotionStats.add(stats)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze motion: %w", err)
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestMotion(t *testing.T) {
+	blocks := blocktest.Parse(t,
+		"M83", "G1 Z0.2 F600", "G1 X60 E3 F1200", "G1 E-1 F1800", "G0 X60 Y60 F6000", "G1 E1 F1800",
+		"G1 Z0.4 F600", "G1 X0 E2 F1200",
+	)
+
+	report, err := Motion(blocks)
+	if err != nil {
+		t.Fatalf("failed to analyze motion: %v", err)
+	}
+
+	cases := map[string]struct {
+		got, want MotionStats
+	}{
+		"total": {report.Total, MotionStats{
+			Travel: 60.4, Extrusion: 120, Retractions: 1, Retraction: 1,
+			TravelTime: 640 * time.Millisecond, ExtrusionTime: 6 * time.Second, RetractionTime: 66666666,
+		}},
+		"first layer": {report.Layers[0].MotionStats, MotionStats{
+			Travel: 60.4, Extrusion: 60, Retractions: 1, Retraction: 1,
+			TravelTime: 640 * time.Millisecond, ExtrusionTime: 3 * time.Second, RetractionTime: 66666666,
+		}},
+		"second layer": {report.Layers[1].MotionStats, MotionStats{
+			// the Z hop belongs to the layer that it leaves
+			Extrusion: 60, ExtrusionTime: 3 * time.Second,
+		}},
+	}
+
+	if len(report.Layers) != 2 || report.Layers[1].Z != 0.4 {
+		t.Fatalf("got layers %+v, want 2 layers", report.Layers)
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if !near(tc.got.Travel, tc.want.Travel) || !near(tc.got.Extrusion, tc.want.Extrusion) ||
+				tc.got.Retractions != tc.want.Retractions || !near(tc.got.Retraction, tc.want.Retraction) ||
+				!nearDuration(tc.got.TravelTime, tc.want.TravelTime) || !nearDuration(tc.got.ExtrusionTime, tc.want.ExtrusionTime) ||
+				!nearDuration(tc.got.RetractionTime, tc.want.RetractionTime) {
+				t.Errorf("got %+v, want %+v", tc.got, tc.want)
+			}
+		})
+	}
+
+	want := "travel 60.4 mm in 1s (33%), extrusion 120.0 mm in 6s, 1 retractions of 1.0 mm in 2 layers"
+	if got := report.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMotion_arcRetraction(t *testing.T) {
+	report, err := Motion(blocktest.Parse(t, "G1 X0 Y0 F600", "G2 X20 Y0 I10 J0 E-1", "G20", "G92 X0", "G1 X1 E-1.1"))
+	if err != nil {
+		t.Fatalf("failed to analyze motion: %v", err)
+	}
+
+	if report.Total.Retractions != 2 {
+		t.Errorf("got %d retractions, want 2", report.Total.Retractions)
+	}
+
+	if math.Abs(report.Total.Retraction-3.54) > 1e-9 {
+		t.Errorf("got retraction %v, want 3.54", report.Total.Retraction)
+	}
+
+	if math.Abs(report.Total.Travel-(10*math.Pi+25.4)) > 0.01 {
+		t.Errorf("got travel %v, want %v", report.Total.Travel, 10*math.Pi+25.4)
+	}
+}
+
+func TestMotion_accelerations(t *testing.T) {
+	cases := map[string]struct {
+		lines   []string
+		initial state.State
+		want    time.Duration
+	}{
+		"unknown acceleration": {[]string{"G0 X100 F6000"}, state.State{}, time.Second},
+		"travel": {
+			// 5 mm accelerating and 5 mm decelerating at 1000 mm/s² take 0.1 s each
+			[]string{"M204 P500 T1000", "G0 X100 F6000"}, state.State{}, 1100 * time.Millisecond,
+		},
+		"short move": {
+			[]string{"M204 T1000", "G0 X4 F6000"}, state.State{}, time.Duration(2 * math.Sqrt(4000) / 1000 * float64(time.Second)),
+		},
+		"jerk": {
+			[]string{"M204 T1000", "M205 X10", "G0 X100 F6000"}, state.State{}, 1081 * time.Millisecond,
+		},
+		"extrusion": {
+			[]string{"M204 P500 T1000", "M83", "G1 X10 E1 F600"}, state.State{}, 1020 * time.Millisecond,
+		},
+		"velocity of the initial state": {
+			[]string{"G0 X100 F6000"}, state.State{Limits: state.Limits{Velocity: 50}}, 2 * time.Second,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Motion(blocktest.Parse(t, tc.lines...), func(config MotionConfigurer) error {
+				if err := config.SetAccelerations(true); err != nil {
+					return err
+				}
+				return config.SetSimulatorOptions(func(config simulator.SimulatorConfigurer) error {
+					return config.SetInitialState(tc.initial)
+				})
+			})
+			if err != nil {
+				t.Fatalf("failed to analyze motion: %v", err)
+			}
+
+			if got := report.Total.Time(); !nearDuration(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMotionStats_TravelRatio(t *testing.T) {
+	cases := map[string]struct {
+		stats MotionStats
+		want  float64
+	}{
+		"no motion":   {MotionStats{}, 0},
+		"travel only": {MotionStats{Travel: 10}, 1},
+		"quarter":     {MotionStats{Travel: 10, Extrusion: 30}, 0.25},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.stats.TravelRatio(); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// nearDuration returns true if two durations are equal up to a microsecond.
+func nearDuration(a, b time.Duration) bool {
+	return a-b < time.Microsecond && b-a < time.Microsecond
+}
@@ -0,0 +1,283 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region no-op moves configuration
+
+// NoOpMovesConfigurer defines the options of the detection of the moves that don't move.
+type NoOpMovesConfigurer interface {
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// NoOpMovesConfigurationCallbackable is the signature of the callbacks used to configure the detection of the moves that don't move.
+type NoOpMovesConfigurationCallbackable func(config NoOpMovesConfigurer) error
+
+// noOpMovesConfigurator implements NoOpMovesConfigurer.
+type noOpMovesConfigurator struct {
+	initial state.State
+	known   bool
+}
+
+// SetInitialState defines the state of the machine before the first block, like the state at the end of a previous file.
+// The position of the initial state is considered known, so an absolute move to it doesn't move.
+// If this method isn't called, by default it is the zero state, with the position unknown until the axes are positioned.
+func (nc *noOpMovesConfigurator) SetInitialState(initial state.State) error {
+	nc.initial = initial.Clone()
+	nc.known = true
+
+	return nil
+}
+
+//#endregion
+//#region no-op moves report
+
+// NoOpMove is a linear move that doesn't move any axis nor change the feedrate.
+type NoOpMove struct {
+	// Index is the position of the block in the sequence analyzed.
+	Index int
+
+	// Block is the move.
+	Block block.Blocker
+
+	// Layer is the index of the layer of the move.
+	Layer int
+
+	// Duplicate is true if the move commands absolute coordinates equal to the current position,
+	// otherwise it doesn't command any axis or its relative displacements are zero.
+	Duplicate bool
+}
+
+// String returns the move formatted.
+func (m NoOpMove) String() string {
+	reason := "move without displacement"
+	if m.Duplicate {
+		reason = "move to the current position"
+	}
+
+	return fmt.Sprintf("block %d %s: %s", m.Index, m.Block, reason)
+}
+
+// NoOpLayer is the number of moves that don't move in a layer.
+type NoOpLayer struct {
+	// Layer is the index of the layer.
+	Layer int
+
+	// Moves is the number of moves that don't move in the layer.
+	Moves int
+}
+
+// NoOpMovesReport contains the moves that don't move.
+type NoOpMovesReport struct {
+	// Moves are the moves that don't move, in order.
+	Moves []NoOpMove
+
+	// Layers are the number of moves that don't move of each layer that has some, sorted by layer.
+	Layers []NoOpLayer
+}
+
+// String returns a summary of the report.
+func (r *NoOpMovesReport) String() string {
+	if len(r.Moves) == 0 {
+		return "no no-op moves"
+	}
+
+	layers := make([]string, 0, len(r.Layers))
+	for _, l := range r.Layers {
+		layers = append(layers, fmt.Sprintf("layer %d: %d", l.Layer, l.Moves))
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d no-op moves, %s", len(r.Moves), strings.Join(layers, ", ")))
+
+	for _, m := range r.Moves {
+		sb.WriteString("\n")
+		sb.WriteString(m.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region no-op moves
+
+// NoOpMoves detects the linear moves that don't move any axis nor change the feedrate, like a G1 without axes,
+// a relative move of zero or an absolute move to the current position. They don't do anything, but they grow the file,
+// and some firmwares stop the motion planner at them. The transformer of the package transform/dedup removes them with RuleMoves.
+//
+// An absolute coordinate is only considered the current position after the axis was positioned by a move, G92 or G28,
+// or if the initial state is configured, because the position of the machine before the blocks isn't known.
+// The layers are detected like Layers does.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func NoOpMoves(blocks []block.Blocker, options ...NoOpMovesConfigurationCallbackable) (*NoOpMovesReport, error) {
+
+	configurator := &noOpMovesConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &NoOpMovesReport{}
+
+	machine := state.New(configurator.initial)
+
+	// axes whose position is known, by an absolute move, G92, G28 or the initial state
+	positioned := map[byte]bool{}
+	if configurator.known {
+		positioned['X'], positioned['Y'], positioned['Z'], positioned['E'] = true, true, true, true
+	}
+
+	for i, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		before := machine.Apply(b)
+		after := machine.Snapshot()
+
+		command := b.Command().String()
+
+		if command == "G0" || command == "G1" {
+			if move, ok := noOpMove(b, before, after, positioned); ok {
+				move.Index = i
+				report.Moves = append(report.Moves, move)
+			}
+		}
+
+		switch command {
+		case "G0", "G1", "G2", "G3":
+			position(b, positioned, before.Relative, before.RelativeExtrusion)
+		case "G92":
+			if len(b.Parameters()) == 0 {
+				positioned['X'], positioned['Y'], positioned['Z'], positioned['E'] = true, true, true, true
+			}
+			position(b, positioned, false, false)
+		case "G28":
+			if !hasAxis(b, 'X', 'Y', 'Z') {
+				positioned['X'], positioned['Y'], positioned['Z'] = true, true, true
+			}
+			position(b, positioned, false, true)
+		}
+	}
+
+	// the layer of each move is the layer of the moves that precede it
+	sim, err := simulator.New(blocks, func(config simulator.SimulatorConfigurer) error {
+		return config.SetInitialState(configurator.initial)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	var tracker layerTracker
+	next := 0
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		for ; next < len(report.Moves) && report.Moves[next].Index < segment.Index; next++ {
+			report.Moves[next].Layer = tracker.layer
+		}
+
+		tracker.track(segment)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to detect no-op moves: %w", err)
+	}
+
+	for ; next < len(report.Moves); next++ {
+		report.Moves[next].Layer = tracker.layer
+	}
+
+	for _, m := range report.Moves {
+		if n := len(report.Layers); n > 0 && report.Layers[n-1].Layer == m.Layer {
+			report.Layers[n-1].Moves++
+			continue
+		}
+
+		report.Layers = append(report.Layers, NoOpLayer{Layer: m.Layer, Moves: 1})
+	}
+
+	return report, nil
+}
+
+//#endregion
+//#region private functions
+
+// noOpMove returns the move if the linear move executed from the state before to the state after doesn't move any axis nor change the feedrate.
+func noOpMove(b block.Blocker, before state.State, after state.State, positioned map[byte]bool) (NoOpMove, bool) {
+	if before.Position != after.Position || before.Feedrate != after.Feedrate {
+		return NoOpMove{}, false
+	}
+
+	move := NoOpMove{Block: b}
+
+	for _, p := range b.Parameters() {
+		word := p.Word()
+
+		switch word {
+		case 'F':
+			continue
+		case 'X', 'Y', 'Z', 'E':
+		default:
+			// the other words, like the power of a laser, do something
+			return NoOpMove{}, false
+		}
+
+		relative := before.Relative
+		if word == 'E' {
+			relative = before.RelativeExtrusion
+		}
+
+		if relative {
+			continue
+		}
+
+		if !positioned[word] {
+			return NoOpMove{}, false
+		}
+		move.Duplicate = true
+	}
+
+	return move, true
+}
+
+// position marks the axes of the block as positioned, except the relative ones.
+func position(b block.Blocker, positioned map[byte]bool, relative bool, relativeExtrusion bool) {
+	for _, word := range []byte{'X', 'Y', 'Z', 'E'} {
+		r := relative
+		if word == 'E' {
+			r = relativeExtrusion
+		}
+
+		if !r && hasAxis(b, word) {
+			positioned[word] = true
+		}
+	}
+}
+
+// hasAxis returns true if the block has some of the words received.
+func hasAxis(b block.Blocker, words ...byte) bool {
+	for _, p := range b.Parameters() {
+		for _, word := range words {
+			if p.Word() == word {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//#endregion
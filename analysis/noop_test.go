@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestNoOpMoves(t *testing.T) {
+	cases := map[string]struct {
+		lines []string
+		known bool
+		want  string
+	}{
+		"empty": {nil, false, "no no-op moves"},
+		"moves": {
+			[]string{"G1 X10 Y10 F1200", "G1 X10", "G1 X10 F600", "G1", "G91", "G1 X0 E0", "G1 X0 S100"},
+			false, "3 no-op moves, layer 0: 3\n" +
+				"block 1 G1 X10: move to the current position\n" +
+				"block 3 G1: move without displacement\n" +
+				"block 5 G1 X0 E0: move without displacement",
+		},
+		"unknown position": {
+			[]string{"G1 X0 Y0", "G1 Z0", "G28 Z0", "G1 Z0", "G92 E0", "G1 E0"},
+			false, "2 no-op moves, layer 0: 2\n" +
+				"block 3 G1 Z0: move to the current position\n" +
+				"block 5 G1 E0: move to the current position",
+		},
+		"initial state": {
+			[]string{"G1 X0 Y0"},
+			true, "1 no-op moves, layer 0: 1\nblock 0 G1 X0 Y0: move to the current position",
+		},
+		"layers": {
+			[]string{"M83", "G1 Z0.2", "G1 X10 E1", "G1 X10", "G1 Z0.4", "G1 Z0.4", "G1 X0 E1", "G1 X0", "G1 Z0.6", "G1 Z0.6"},
+			false, "4 no-op moves, layer 0: 2, layer 1: 2\n" +
+				"block 3 G1 X10: move to the current position\n" +
+				"block 5 G1 Z0.4: move to the current position\n" +
+				"block 7 G1 X0: move to the current position\n" +
+				"block 9 G1 Z0.6: move to the current position",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []NoOpMovesConfigurationCallbackable
+			if tc.known {
+				options = append(options, func(config NoOpMovesConfigurer) error {
+					return config.SetInitialState(state.State{})
+				})
+			}
+
+			report, err := NoOpMoves(blocktest.Parse(t, tc.lines...), options...)
+			if err != nil {
+				t.Fatalf("failed to detect no-op moves: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoOpMoves_invalid(t *testing.T) {
+	if _, err := NoOpMoves(blocktest.Parse(t, "G2 X10 R1")); err == nil {
+		t.Errorf("got nil error, want an error for an invalid arc")
+	}
+}
@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region objects configuration
+
+// ObjectsConfigurer defines the options of the analysis of the objects.
+type ObjectsConfigurer interface {
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// ObjectsConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the objects.
+type ObjectsConfigurationCallbackable func(config ObjectsConfigurer) error
+
+// objectsConfigurator implements ObjectsConfigurer.
+type objectsConfigurator struct {
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (oc *objectsConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	oc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region objects report
+
+// ObjectStats are the statistics of the blocks of an object.
+type ObjectStats struct {
+	// Name is the name of the object, empty for the blocks outside of the objects.
+	Name string
+
+	// Time is the time of the moves at the feedrate commanded, without accelerations.
+	Time time.Duration
+
+	// Filament is the length of filament extruded in millimeters, the net movement of the extruder.
+	Filament float64
+
+	// Box is the bounding box of the extrusions, it is only valid if Empty is false.
+	Box Box
+
+	// Empty is true if the object doesn't extrude.
+	Empty bool
+}
+
+// String returns the statistics formatted.
+func (s ObjectStats) String() string {
+	name := "outside of objects"
+	if s.Name != "" {
+		name = "object " + s.Name
+	}
+
+	box := "without extrusion"
+	if !s.Empty {
+		box = s.Box.String()
+	}
+
+	return fmt.Sprintf("%s: %s, %.1f mm, %s", name, s.Time.Round(time.Second), s.Filament, box)
+}
+
+// ObjectsReport contains the statistics of each object of a print.
+type ObjectsReport struct {
+	// Objects are the statistics of the objects, in the order of the document.
+	Objects []ObjectStats
+
+	// Outside are the statistics of the blocks outside of the objects, like the start gcode and the travels between the objects.
+	Outside ObjectStats
+}
+
+// String returns a summary of the report.
+func (r *ObjectsReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d objects", len(r.Objects)))
+
+	for _, o := range r.Objects {
+		sb.WriteString("\n")
+		sb.WriteString(o.String())
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(r.Outside.String())
+
+	return sb.String()
+}
+
+//#endregion
+//#region objects
+
+// Objects computes the time, the filament and the bounding box of each object of a print, delimited by the markers of
+// M486 or the extended commands of Klipper, like Document.Objects detects them.
+//
+// The filament in inches is converted to millimeters, the boxes are in the units of the blocks.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Objects(d *document.Document, options ...ObjectsConfigurationCallbackable) (*ObjectsReport, error) {
+
+	configurator := &objectsConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	objects := d.Objects()
+
+	report := &ObjectsReport{Outside: ObjectStats{Empty: true}}
+
+	// object of each block, -1 for the blocks outside of the objects
+	owners := make([]int, d.Len())
+	for i := range owners {
+		owners[i] = -1
+	}
+
+	for i, o := range objects {
+		report.Objects = append(report.Objects, ObjectStats{Name: o.Name, Empty: true})
+
+		for _, r := range o.Ranges {
+			for index := r.Start; index < r.End; index++ {
+				owners[index] = i
+			}
+		}
+	}
+
+	sim, err := simulator.New(d.Blocks(), configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		stats := &report.Outside
+		if owner := owners[segment.Index]; owner >= 0 {
+			stats = &report.Objects[owner]
+		}
+
+		stats.Time += duration(segment)
+
+		extrusion := segment.Extrusion
+		if sim.State().Units == state.UnitsInches {
+			extrusion *= gcode.MILLIMETERS_PER_INCH
+		}
+		stats.Filament += extrusion
+
+		if !isExtrusion(segment) {
+			continue
+		}
+
+		start := Point{X: segment.Start.X, Y: segment.Start.Y, Z: segment.Start.Z}
+		end := Point{X: segment.End.X, Y: segment.End.Y, Z: segment.End.Z}
+
+		if stats.Empty {
+			stats.Box = Box{Min: start, Max: start}
+			stats.Empty = false
+		}
+		stats.Box = stats.Box.extend(start).extend(end)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze objects: %w", err)
+	}
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestObjects(t *testing.T) {
+	cases := map[string]struct {
+		source []string
+		want   string
+	}{
+		"without objects": {
+			[]string{"G1 X10 E1 F600"},
+			"0 objects\noutside of objects: 1s, 1.0 mm, [X0 Y0 Z0, X10 Y0 Z0]",
+		},
+		"marlin": {
+			[]string{
+				"M83", "G1 Z0.5 F600",
+				"M486 S0", "G0 X10 Y10 F6000", "G1 X20 E2 F1200", "M486 S-1",
+				"M486 S1", "G0 X50 Y50 F6000", "G1 Y60 E1 F600", "M486 S-1",
+				"G1 Z1 F600",
+				"M486 S0", "G1 X10 E2 F1200", "M486 S-1",
+			},
+			"2 objects\n" +
+				"object 0: 3s, 4.0 mm, [X10 Y10 Z0.5, X50 Y60 Z1]\n" +
+				"object 1: 2s, 1.0 mm, [X50 Y50 Z0.5, X50 Y60 Z0.5]\n" +
+				"outside of objects: 0s, 0.0 mm, without extrusion",
+		},
+		"klipper": {
+			[]string{
+				"EXCLUDE_OBJECT_DEFINE NAME=cube",
+				"EXCLUDE_OBJECT_START NAME=cube", "G1 X10 E1 F600", "EXCLUDE_OBJECT_END NAME=cube",
+				"G0 X0 F600",
+			},
+			"1 objects\nobject cube: 1s, 1.0 mm, [X0 Y0 Z0, X10 Y0 Z0]\noutside of objects: 1s, 0.0 mm, without extrusion",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(strings.Join(tc.source, "\n")), func(config document.ParseConfigurer) error {
+				return config.SetExtendedCommands(true)
+			})
+			if err != nil {
+				t.Fatalf("failed to parse document: %v", err)
+			}
+
+			report, err := Objects(d)
+			if err != nil {
+				t.Fatalf("failed to analyze objects: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region resume configuration
+
+// ResumeConfigurer defines the options of the search of a resume point.
+type ResumeConfigurer interface {
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// ResumeConfigurationCallbackable is the signature of the callbacks used to configure the search of a resume point.
+type ResumeConfigurationCallbackable func(config ResumeConfigurer) error
+
+// resumeConfigurator implements ResumeConfigurer.
+type resumeConfigurator struct {
+	initial state.State
+}
+
+// SetInitialState defines the state of the machine before the first block, like the state at the end of a previous file.
+// If this method isn't called, by default it is the zero state, at the origin with all targets unknown.
+func (rc *resumeConfigurator) SetInitialState(initial state.State) error {
+	rc.initial = initial.Clone()
+
+	return nil
+}
+
+//#endregion
+//#region resume point
+
+// ResumePoint is a block from which an interrupted print can continue safely, with the blocks that restore the state of the machine.
+type ResumePoint struct {
+	// Index is the position of the first block to execute after the preamble, in the sequence analyzed.
+	Index int
+
+	// Block is the first block to execute after the preamble.
+	Block block.Blocker
+
+	// Layer is the index of the layer that starts at the block, and Z its height.
+	Layer int
+	Z     float64
+
+	// State is the state of the machine before the block.
+	State state.State
+
+	// Preamble are the blocks that restore the state before the block, without homing the axes.
+	Preamble []block.Blocker
+}
+
+// String returns the resume point formatted.
+func (p ResumePoint) String() string {
+	return fmt.Sprintf("resume at block %d %s, layer %d at Z%s", p.Index, p.Block, p.Layer, formatRounded(p.Z))
+}
+
+//#endregion
+//#region resume
+
+// ResumeAtZ finds the start of the layer whose height is the nearest to the height required, like the height measured
+// on a failed print, and the preamble to continue the print from it. The lower layer is chosen if two layers are equally near.
+//
+// It returns an error if some option is invalid, the toolpath can't be simulated or the blocks don't have layers.
+func ResumeAtZ(blocks []block.Blocker, z float64, options ...ResumeConfigurationCallbackable) (*ResumePoint, error) {
+	configurator, starts, err := layerStarts(blocks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	nearest := 0
+	for i, start := range starts {
+		if math.Abs(start.z-z) < math.Abs(starts[nearest].z-z) {
+			nearest = i
+		}
+	}
+
+	return resumePoint(blocks, configurator.initial, starts[nearest])
+}
+
+// ResumeAtBlock finds the start of the layer that contains the block required, like the last block acknowledged by the firmware
+// before a failure, and the preamble to continue the print from it. The layer is printed again from its start,
+// so no part of the print is skipped.
+//
+// It returns an error if the block doesn't exist, some option is invalid, the toolpath can't be simulated or the blocks don't have layers.
+func ResumeAtBlock(blocks []block.Blocker, index int, options ...ResumeConfigurationCallbackable) (*ResumePoint, error) {
+	if index < 0 || index >= len(blocks) {
+		return nil, fmt.Errorf("failed to find resume point, the block %d doesn't exist, there are %d blocks", index, len(blocks))
+	}
+
+	configurator, starts, err := layerStarts(blocks, options)
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.Search(len(starts), func(i int) bool { return starts[i].index > index }) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return resumePoint(blocks, configurator.initial, starts[i])
+}
+
+//#endregion
+//#region private functions
+
+// layerStart is the first block of a layer, the one after the last extrusion of the previous layer.
+type layerStart struct {
+	layer int
+	index int
+	z     float64
+}
+
+// layerStarts applies the options and returns the starts of the layers, in order.
+func layerStarts(blocks []block.Blocker, options []ResumeConfigurationCallbackable) (*resumeConfigurator, []layerStart, error) {
+	configurator := &resumeConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, func(config simulator.SimulatorConfigurer) error {
+		return config.SetInitialState(configurator.initial)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	var starts []layerStart
+	var layers layerTracker
+	next := 0
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		layer, started := layers.track(segment)
+		if started {
+			starts = append(starts, layerStart{layer: layer, index: next, z: segment.End.Z})
+		}
+
+		if isExtrusion(segment) {
+			next = segment.Index + 1
+		}
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to find layers: %w", err)
+	}
+
+	if len(starts) == 0 {
+		return nil, nil, fmt.Errorf("failed to find resume point, the blocks don't have layers")
+	}
+
+	// the first layer starts with the blocks
+	starts[0].index = 0
+
+	return configurator, starts, nil
+}
+
+// resumePoint returns the resume point at the start of a layer, with the state before it.
+func resumePoint(blocks []block.Blocker, initial state.State, start layerStart) (*ResumePoint, error) {
+	machine := state.New(initial)
+	for _, b := range blocks[:start.index] {
+		if b != nil {
+			machine.Apply(b)
+		}
+	}
+
+	point := &ResumePoint{Index: start.index, Block: blocks[start.index], Layer: start.layer, Z: start.z, State: machine.Snapshot()}
+
+	for _, expression := range resumeExpressions(point.State) {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate preamble, %s is invalid: %w", expression, err)
+		}
+		point.Preamble = append(point.Preamble, b)
+	}
+
+	return point, nil
+}
+
+// resumeExpressions returns the blocks that restore a state without homing the axes.
+//
+// The nozzle is expected to be placed at the position of the state by hand, so G92 declares it instead of moving to it.
+// The heaters are awaited, the active hotend the last one, before the modes, the fans, the spindle and the feedrate are restored.
+func resumeExpressions(s state.State) []string {
+	expressions := []string{"G21"}
+	if s.Units == state.UnitsInches {
+		expressions[0] = "G20"
+	}
+
+	if s.Tool != 0 {
+		expressions = append(expressions, fmt.Sprintf("T%d", s.Tool))
+	}
+
+	heaters := make([]state.Heater, 0, len(s.Heaters))
+	for heater, target := range s.Heaters {
+		if target > 0 {
+			heaters = append(heaters, heater)
+		}
+	}
+	sortHeaters(heaters)
+
+	// the bed and the chamber are awaited before the hotends, that ooze while they wait
+	var hotends []string
+	var active string
+	for _, heater := range heaters {
+		target := numeric.Format(s.Heaters[heater])
+
+		switch {
+		case heater.Kind == state.HeaterBed:
+			expressions = append(expressions, "M190 S"+target)
+		case heater.Kind == state.HeaterChamber:
+			expressions = append(expressions, "M191 S"+target)
+		case heater.Index == s.Tool:
+			active = "M109 S" + target
+		default:
+			hotends = append(hotends, fmt.Sprintf("M104 T%d S%s", heater.Index, target))
+		}
+	}
+	expressions = append(expressions, hotends...)
+	if active != "" {
+		expressions = append(expressions, active)
+	}
+
+	if s.WorkOffset != state.G54 {
+		expressions = append(expressions, s.WorkOffset.String())
+	}
+
+	switch s.Plane {
+	case state.PlaneZX:
+		expressions = append(expressions, "G18")
+	case state.PlaneYZ:
+		expressions = append(expressions, "G19")
+	}
+
+	position := fmt.Sprintf("G92 X%s Y%s Z%s", formatRounded(s.Position.X), formatRounded(s.Position.Y), formatRounded(s.Position.Z))
+	if !s.RelativeExtrusion {
+		position += " E" + formatRounded(s.Position.E)
+	}
+	expressions = append(expressions, position)
+
+	// G90 and G91 also select the extrusion mode in Marlin, so M82 and M83 follow them
+	if s.Relative {
+		expressions = append(expressions, "G91")
+	} else {
+		expressions = append(expressions, "G90")
+	}
+
+	if s.RelativeExtrusion {
+		expressions = append(expressions, "M83")
+	} else {
+		expressions = append(expressions, "M82")
+	}
+
+	fans := make([]int, 0, len(s.Fans))
+	for fan, speed := range s.Fans {
+		if speed > 0 {
+			fans = append(fans, fan)
+		}
+	}
+	sort.Ints(fans)
+
+	for _, fan := range fans {
+		if fan == 0 {
+			expressions = append(expressions, "M106 S"+numeric.Format(s.Fans[fan]))
+			continue
+		}
+		expressions = append(expressions, fmt.Sprintf("M106 P%d S%s", fan, numeric.Format(s.Fans[fan])))
+	}
+
+	switch s.SpindleDirection {
+	case state.SpindleClockwise:
+		expressions = append(expressions, "M3 S"+numeric.Format(s.SpindleSpeed))
+	case state.SpindleCounterClockwise:
+		expressions = append(expressions, "M4 S"+numeric.Format(s.SpindleSpeed))
+	}
+
+	if s.Feedrate > 0 {
+		expressions = append(expressions, "G1 F"+numeric.Format(s.Feedrate))
+	}
+
+	return expressions
+}
+
+//#endregion
@@ -0,0 +1,89 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestResume(t *testing.T) {
+	blocks := blocktest.Parse(t,
+		"M140 S60", "M104 S210", "M190 S60", "M109 S210", "G21", "G90", "M82", "G28 X0 Y0",
+		"G1 Z0.2 F1200", "G1 X10 Y5 E1.5", "M106 S127", "G1 X20 Y5 E3",
+		"G0 Z0.6", "G0 X0 Y0 F6000", "G0 Z0.4", "G1 X10 E4.5 F1200",
+		"G1 Z0.6", "G1 X20 E6",
+	)
+
+	preamble := []string{"G21", "M190 S60", "M109 S210", "G92 X20 Y5 Z0.2 E3", "G90", "M82", "M106 S127", "G1 F1200"}
+
+	cases := map[string]struct {
+		find  func() (*ResumePoint, error)
+		want  string
+		valid bool
+	}{
+		"height":         {func() (*ResumePoint, error) { return ResumeAtZ(blocks, 0.45) }, "resume at block 12 G0 Z0.6, layer 1 at Z0.4", true},
+		"height equal":   {func() (*ResumePoint, error) { return ResumeAtZ(blocks, 0.3) }, "resume at block 0 M140 S60, layer 0 at Z0.2", true},
+		"block":          {func() (*ResumePoint, error) { return ResumeAtBlock(blocks, 15) }, "resume at block 12 G0 Z0.6, layer 1 at Z0.4", true},
+		"start of layer": {func() (*ResumePoint, error) { return ResumeAtBlock(blocks, 12) }, "resume at block 12 G0 Z0.6, layer 1 at Z0.4", true},
+		"first layer":    {func() (*ResumePoint, error) { return ResumeAtBlock(blocks, 3) }, "resume at block 0 M140 S60, layer 0 at Z0.2", true},
+		"last layer":     {func() (*ResumePoint, error) { return ResumeAtBlock(blocks, 17) }, "resume at block 16 G1 Z0.6, layer 2 at Z0.6", true},
+		"missing block":  {func() (*ResumePoint, error) { return ResumeAtBlock(blocks, 18) }, "", false},
+		"without layers": {func() (*ResumePoint, error) { return ResumeAtZ(blocks[:8], 0.2) }, "", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			point, err := tc.find()
+			if (err == nil) != tc.valid {
+				t.Fatalf("got error %v, want valid %v", err, tc.valid)
+			}
+
+			if !tc.valid {
+				return
+			}
+
+			if got := point.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	point, err := ResumeAtZ(blocks, 0.4)
+	if err != nil {
+		t.Fatalf("failed to find resume point: %v", err)
+	}
+
+	var got []string
+	for _, b := range point.Preamble {
+		got = append(got, b.String())
+	}
+
+	if !reflect.DeepEqual(got, preamble) {
+		t.Errorf("got preamble %q, want %q", got, preamble)
+	}
+}
+
+func TestResume_preamble(t *testing.T) {
+	blocks := blocktest.Parse(t, "G20", "T1", "M104 T0 S200", "M104 T1 S220", "M141 S40", "G55", "G18", "M83", "G91",
+		"M106 P1 S255", "M3 S1000", "G1 X1 E0.1 F120", "G1 X1 Z0.2 E0.1")
+
+	point, err := ResumeAtBlock(blocks, 12, func(config ResumeConfigurer) error {
+		return config.SetInitialState(state.State{Position: state.Position{Z: 0.01}})
+	})
+	if err != nil {
+		t.Fatalf("failed to find resume point: %v", err)
+	}
+
+	want := []string{"G20", "T1", "M191 S40", "M104 T0 S200", "M109 S220", "G55", "G18", "G92 X1 Y0 Z0.01", "G91", "M83", "M106 P1 S255", "M3 S1000", "G1 F120"}
+
+	var got []string
+	for _, b := range point.Preamble {
+		got = append(got, b.String())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got preamble %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region temperature limits
+
+// TemperatureLimits are the maximum targets allowed for each kind of heater, in degrees Celsius. A zero value doesn't limit.
+type TemperatureLimits struct {
+	// MaxHotend is the maximum target of the hotends.
+	MaxHotend float64
+
+	// MaxBed is the maximum target of the bed.
+	MaxBed float64
+
+	// MaxChamber is the maximum target of the chamber.
+	MaxChamber float64
+}
+
+// maximum returns the limit of a kind of heater.
+func (l TemperatureLimits) maximum(kind state.HeaterKind) float64 {
+	switch kind {
+	case state.HeaterBed:
+		return l.MaxBed
+	case state.HeaterChamber:
+		return l.MaxChamber
+	}
+
+	return l.MaxHotend
+}
+
+//#endregion
+//#region temperatures configuration
+
+// TemperaturesConfigurer defines the options of the analysis of the temperatures.
+type TemperaturesConfigurer interface {
+	// Set if the heaters and the fans must be turned off at the end
+	SetCheckShutdown(check bool) error
+
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+}
+
+// TemperaturesConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the temperatures.
+type TemperaturesConfigurationCallbackable func(config TemperaturesConfigurer) error
+
+// temperaturesConfigurator implements TemperaturesConfigurer.
+type temperaturesConfigurator struct {
+	checkShutdown bool
+	initial       state.State
+}
+
+// SetCheckShutdown defines if the heaters and the fans must be turned off by the end of the blocks, like the end gcode of a print does.
+// It should be disabled for the fragments of a file.
+// If this method isn't called, by default the shutdown is checked.
+func (tc *temperaturesConfigurator) SetCheckShutdown(check bool) error {
+	tc.checkShutdown = check
+
+	return nil
+}
+
+// SetInitialState defines the state of the machine before the first block, like the state at the end of a previous file.
+// If this method isn't called, by default it is the zero state, with all heaters and fans unknown.
+func (tc *temperaturesConfigurator) SetInitialState(initial state.State) error {
+	tc.initial = initial.Clone()
+
+	return nil
+}
+
+//#endregion
+//#region temperatures report
+
+// TemperatureCommand is a target commanded to a heater.
+type TemperatureCommand struct {
+	// Index is the position of the block in the sequence analyzed.
+	Index int
+
+	// Block is the block of the command.
+	Block block.Blocker
+
+	// Heater is the heater commanded.
+	Heater state.Heater
+
+	// Target is the temperature commanded, zero turns the heater off.
+	Target float64
+
+	// Wait is true if the machine waits for the target, like M109 and M190.
+	Wait bool
+}
+
+// String returns the command formatted.
+func (c TemperatureCommand) String() string {
+	return fmt.Sprintf("block %d %s: %s %s", c.Index, c.Block, c.Heater, numeric.Format(c.Target))
+}
+
+// TemperatureViolation is a target above the limit of the heater.
+type TemperatureViolation struct {
+	TemperatureCommand
+
+	// Maximum is the limit of the heater.
+	Maximum float64
+}
+
+// String returns the violation formatted.
+func (v TemperatureViolation) String() string {
+	return fmt.Sprintf("%s exceeds the maximum %s", v.TemperatureCommand, numeric.Format(v.Maximum))
+}
+
+// TemperaturesReport contains the temperatures commanded and the result of the safety checks.
+type TemperaturesReport struct {
+	// Commands are all temperature commands, in order.
+	Commands []TemperatureCommand
+
+	// Maximums is the highest target commanded to each heater.
+	Maximums map[state.Heater]float64
+
+	// Violations are the commands whose target exceeds the limit of the heater.
+	Violations []TemperatureViolation
+
+	// HeatersOn are the heaters that are on at the end, sorted by kind and index. It is empty if the shutdown isn't checked.
+	HeatersOn []state.Heater
+
+	// FansOn are the fans that are on at the end, sorted. It is empty if the shutdown isn't checked.
+	FansOn []int
+}
+
+// Passed returns true if no target exceeds the limits and all heaters and fans are off at the end.
+func (r *TemperaturesReport) Passed() bool {
+	return len(r.Violations) == 0 && len(r.HeatersOn) == 0 && len(r.FansOn) == 0
+}
+
+// String returns a summary of the report.
+func (r *TemperaturesReport) String() string {
+	var sb strings.Builder
+
+	heaters := make([]state.Heater, 0, len(r.Maximums))
+	for heater := range r.Maximums {
+		heaters = append(heaters, heater)
+	}
+	sortHeaters(heaters)
+
+	sb.WriteString(fmt.Sprintf("%d temperature commands", len(r.Commands)))
+	for i, heater := range heaters {
+		separator := ", "
+		if i == 0 {
+			separator = ", maximum "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s %s", separator, heater, numeric.Format(r.Maximums[heater])))
+	}
+
+	for _, v := range r.Violations {
+		sb.WriteString("\n")
+		sb.WriteString(v.String())
+	}
+
+	for _, heater := range r.HeatersOn {
+		sb.WriteString(fmt.Sprintf("\n%s isn't turned off at the end", heater))
+	}
+
+	for _, fan := range r.FansOn {
+		sb.WriteString(fmt.Sprintf("\nfan %d isn't turned off at the end", fan))
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region temperatures
+
+// Temperatures extracts the targets commanded to the hotends, the bed and the chamber, flags the ones above the safety limits,
+// and verifies that the heaters and the fans are turned off at the end, so a machine isn't left heating after a print.
+//
+// The heaters and the fans are tracked with a state.Machine, so the hotend of each command is resolved with the active tool.
+// The heaters and the fans that were never commanded aren't required to be turned off.
+//
+// It returns an error if some option is invalid.
+func Temperatures(blocks []block.Blocker, limits TemperatureLimits, options ...TemperaturesConfigurationCallbackable) (*TemperaturesReport, error) {
+
+	configurator := &temperaturesConfigurator{
+		checkShutdown: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &TemperaturesReport{Maximums: map[state.Heater]float64{}}
+	machine := state.New(configurator.initial)
+
+	for index, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		before := machine.Apply(b)
+
+		heater, target, ok := before.HeaterTarget(b)
+		if !ok {
+			continue
+		}
+
+		command := b.Command().String()
+		c := TemperatureCommand{Index: index, Block: b, Heater: heater, Target: target, Wait: command == "M109" || command == "M190" || command == "M191"}
+		report.Commands = append(report.Commands, c)
+
+		if maximum, ok := report.Maximums[heater]; !ok || target > maximum {
+			report.Maximums[heater] = target
+		}
+
+		if maximum := limits.maximum(heater.Kind); maximum > 0 && target > maximum {
+			report.Violations = append(report.Violations, TemperatureViolation{TemperatureCommand: c, Maximum: maximum})
+		}
+	}
+
+	if !configurator.checkShutdown {
+		return report, nil
+	}
+
+	final := machine.Snapshot()
+
+	for heater, target := range final.Heaters {
+		if target > 0 {
+			report.HeatersOn = append(report.HeatersOn, heater)
+		}
+	}
+	sortHeaters(report.HeatersOn)
+
+	for fan, speed := range final.Fans {
+		if speed > 0 {
+			report.FansOn = append(report.FansOn, fan)
+		}
+	}
+	sort.Ints(report.FansOn)
+
+	return report, nil
+}
+
+//#endregion
+//#region private functions
+
+// sortHeaters sorts the heaters by kind and index.
+func sortHeaters(heaters []state.Heater) {
+	sort.Slice(heaters, func(i, j int) bool {
+		if heaters[i].Kind != heaters[j].Kind {
+			return heaters[i].Kind < heaters[j].Kind
+		}
+		return heaters[i].Index < heaters[j].Index
+	})
+}
+
+//#endregion
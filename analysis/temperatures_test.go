@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestTemperatures(t *testing.T) {
+	limits := TemperatureLimits{MaxHotend: 260, MaxBed: 100}
+
+	cases := map[string]struct {
+		lines    []string
+		shutdown bool
+		want     string
+		passed   bool
+	}{
+		"empty": {nil, true, "0 temperature commands", true},
+		"print with end gcode": {
+			[]string{"M140 S60", "M104 S200", "M190 S60", "M109 S215", "M106 S255", "G1 X10 E1", "M104 S0", "M140 S0", "M107"},
+			true, "6 temperature commands, maximum hotend 0 215, bed 60", true,
+		},
+		"above the limits": {
+			[]string{"M104 S300", "M140 S120", "M141 S90", "M104 S0", "M140 S0", "M141 S0"},
+			true,
+			"6 temperature commands, maximum hotend 0 300, bed 120, chamber 90\n" +
+				"block 0 M104 S300: hotend 0 300 exceeds the maximum 260\n" +
+				"block 1 M140 S120: bed 120 exceeds the maximum 100",
+			false,
+		},
+		"left on": {
+			[]string{"T1", "M104 S200", "M104 T0 S190", "M106 P1 S100", "M106 S0", "M104 T0 S0"},
+			true,
+			"3 temperature commands, maximum hotend 0 190, hotend 1 200\n" +
+				"hotend 1 isn't turned off at the end\n" +
+				"fan 1 isn't turned off at the end",
+			false,
+		},
+		"fragment": {
+			[]string{"M104 S200", "M106"},
+			false, "1 temperature commands, maximum hotend 0 200", true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := Temperatures(blocktest.Parse(t, tc.lines...), limits, func(config TemperaturesConfigurer) error {
+				return config.SetCheckShutdown(tc.shutdown)
+			})
+			if err != nil {
+				t.Fatalf("failed to analyze temperatures: %v", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.Passed() != tc.passed {
+				t.Errorf("got passed %v, want %v", report.Passed(), tc.passed)
+			}
+		})
+	}
+}
+
+func TestTemperatures_commands(t *testing.T) {
+	blocks := blocktest.Parse(t, "M109 R180", "M190 S60")
+
+	report, err := Temperatures(blocks, TemperatureLimits{}, func(config TemperaturesConfigurer) error {
+		return config.SetInitialState(state.State{Tool: 1})
+	})
+	if err != nil {
+		t.Fatalf("failed to analyze temperatures: %v", err)
+	}
+
+	want := []TemperatureCommand{
+		{Index: 0, Block: blocks[0], Heater: state.Heater{Kind: state.HeaterHotend, Index: 1}, Target: 180, Wait: true},
+		{Index: 1, Block: blocks[1], Heater: state.Heater{Kind: state.HeaterBed}, Target: 60, Wait: true},
+	}
+
+	if len(report.Commands) != len(want) {
+		t.Fatalf("got %+v, want %+v", report.Commands, want)
+	}
+
+	for i := range want {
+		if report.Commands[i] != want[i] {
+			t.Errorf("got command %+v, want %+v", report.Commands[i], want[i])
+		}
+	}
+
+	if len(report.HeatersOn) != 2 {
+		t.Errorf("got heaters on %v, want the hotend and the bed", report.HeatersOn)
+	}
+}
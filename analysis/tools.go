@@ -0,0 +1,243 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region tools configuration
+
+// ToolsConfigurer defines the options of the analysis of the tools.
+type ToolsConfigurer interface {
+	// Set the tool active before the first block
+	SetInitialTool(tool int) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// ToolsConfigurationCallbackable is the signature of the callbacks used to configure the analysis of the tools.
+type ToolsConfigurationCallbackable func(config ToolsConfigurer) error
+
+// toolsConfigurator implements ToolsConfigurer.
+type toolsConfigurator struct {
+	initial   int
+	known     bool
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetInitialTool defines the tool active before the first block, like the tool loaded at the end of a previous file,
+// so the selection of another tool by the first T command is a change.
+// If this method isn't called, by default the first T command selects the tool without a change, and the moves before it belong to the tool 0.
+func (tc *toolsConfigurator) SetInitialTool(tool int) error {
+	if tool < 0 {
+		return fmt.Errorf("failed to set initial tool, it must be positive or zero: %d", tool)
+	}
+
+	tc.initial, tc.known = tool, true
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath.
+// If this method isn't called, by default the simulation uses its defaults.
+func (tc *toolsConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	tc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region tools report
+
+// ToolChange is a change of the active tool.
+type ToolChange struct {
+	// Index is the position of the block of the change in the sequence analyzed.
+	Index int
+
+	// Block is the T command of the change.
+	Block block.Blocker
+
+	// From is the tool active before the change and To the tool selected.
+	From, To int
+
+	// Layer is the index of the layer in progress, the first one before any extrusion.
+	Layer int
+}
+
+// String returns the change formatted.
+func (c ToolChange) String() string {
+	return fmt.Sprintf("block %d: T%d -> T%d at layer %d", c.Index, c.From, c.To, c.Layer)
+}
+
+// ToolUsage is the usage of a tool.
+type ToolUsage struct {
+	// Tool is the index of the tool.
+	Tool int
+
+	// Time is the time of the moves executed with the tool active, at the feedrate commanded, without accelerations.
+	Time time.Duration
+
+	// Filament is the length of filament extruded by the tool in millimeters, the net movement of its extruder.
+	Filament float64
+
+	// FirstLayer and LastLayer are the indexes of the first and the last layers in which the tool extrudes.
+	// They are -1 if the tool doesn't extrude.
+	FirstLayer, LastLayer int
+}
+
+// String returns the usage formatted.
+func (u ToolUsage) String() string {
+	layers := "without extrusion"
+	if u.FirstLayer >= 0 {
+		layers = fmt.Sprintf("layers %d-%d", u.FirstLayer, u.LastLayer)
+	}
+
+	return fmt.Sprintf("T%d: %s, %.1f mm, %s", u.Tool, u.Time.Round(time.Second), u.Filament, layers)
+}
+
+// ToolsReport contains the tool changes and the usage of each tool.
+type ToolsReport struct {
+	// Changes are the tool changes, in order. The selection of the first tool isn't a change, unless the initial tool is configured,
+	// and neither is the selection of the tool already active.
+	Changes []ToolChange
+
+	// Tools is the usage of each tool that was active while the machine moved, sorted by tool.
+	Tools []ToolUsage
+}
+
+// String returns a summary of the report.
+func (r *ToolsReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d tool changes", len(r.Changes)))
+
+	for _, usage := range r.Tools {
+		sb.WriteString("\n")
+		sb.WriteString(usage.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region tools
+
+// Tools reports the tool changes of a multi-extruder file, and the time, the filament and the layers of each tool,
+// like the inputs to tune a purge tower or to plan the changes of a multi-material unit.
+//
+// The layers are detected from the heights of the extrusions. The filament in inches is converted to millimeters.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func Tools(blocks []block.Blocker, options ...ToolsConfigurationCallbackable) (*ToolsReport, error) {
+
+	configurator := &toolsConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	selections := toolSelections(blocks, configurator.initial)
+
+	report := &ToolsReport{}
+	usages := map[int]*ToolUsage{}
+	var layers layerTracker
+	tool := configurator.initial
+	pending := 0
+
+	// selectBefore resolves the selections before a block with the layer in progress
+	selectBefore := func(index int) {
+		for ; pending < len(selections) && selections[pending].Index < index; pending++ {
+			selection := selections[pending]
+			selection.Layer = layers.layer
+
+			if (pending > 0 || configurator.known) && selection.From != selection.To {
+				report.Changes = append(report.Changes, selection)
+			}
+
+			tool = selection.To
+		}
+	}
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		selectBefore(segment.Index)
+
+		layer, _ := layers.track(segment)
+
+		usage, ok := usages[tool]
+		if !ok {
+			usage = &ToolUsage{Tool: tool, FirstLayer: -1, LastLayer: -1}
+			usages[tool] = usage
+		}
+
+		usage.Time += duration(segment)
+
+		extrusion := segment.Extrusion
+		if sim.State().Units == state.UnitsInches {
+			extrusion *= gcode.MILLIMETERS_PER_INCH
+		}
+		usage.Filament += extrusion
+
+		if isExtrusion(segment) {
+			if usage.FirstLayer < 0 {
+				usage.FirstLayer = layer
+			}
+			usage.LastLayer = layer
+		}
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to analyze tools: %w", err)
+	}
+
+	selectBefore(len(blocks))
+
+	for _, usage := range usages {
+		report.Tools = append(report.Tools, *usage)
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+
+	return report, nil
+}
+
+// toolSelections returns the T commands, with the tool active before each one.
+func toolSelections(blocks []block.Blocker, initial int) []ToolChange {
+	var selections []ToolChange
+
+	tool := initial
+	for index, b := range blocks {
+		if b == nil || b.Command().Word() != 'T' {
+			continue
+		}
+
+		address, ok := gcode.NumericAddress(b.Command())
+		if !ok {
+			continue
+		}
+
+		selections = append(selections, ToolChange{Index: index, Block: b, From: tool, To: int(address)})
+		tool = int(address)
+	}
+
+	return selections
+}
+
+//#endregion
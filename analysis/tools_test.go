@@ -0,0 +1,104 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestTools(t *testing.T) {
+	cases := map[string]struct {
+		lines   []string
+		initial int
+		known   bool
+		changes []string
+		tools   []ToolUsage
+	}{
+		"empty": {nil, 0, false, nil, nil},
+		"single tool": {
+			[]string{"T0", "G1 Z0.2 F600", "G1 X60 E3 F1200"},
+			0, false, nil,
+			[]ToolUsage{{Tool: 0, Time: 3*time.Second + 20*time.Millisecond, Filament: 3, FirstLayer: 0, LastLayer: 0}},
+		},
+		"two tools": {
+			[]string{"M83", "T0", "G1 Z0.2 F600", "G1 X60 E3 F1200", "T1", "G1 Y60 E2", "G1 Z0.4", "G1 X0 E2", "T0", "G1 Y0 E1", "T0"},
+			0, false,
+			[]string{"block 4: T0 -> T1 at layer 0", "block 8: T1 -> T0 at layer 1"},
+			[]ToolUsage{
+				{Tool: 0, Time: 6*time.Second + 20*time.Millisecond, Filament: 4, FirstLayer: 0, LastLayer: 1},
+				{Tool: 1, Time: 6*time.Second + 10*time.Millisecond, Filament: 4, FirstLayer: 0, LastLayer: 1},
+			},
+		},
+		"initial tool": {
+			[]string{"T1", "G1 X60 F1200", "T2"},
+			0, true,
+			[]string{"block 0: T0 -> T1 at layer 0", "block 2: T1 -> T2 at layer 0"},
+			[]ToolUsage{{Tool: 1, Time: 3 * time.Second, FirstLayer: -1, LastLayer: -1}},
+		},
+		"inches": {
+			[]string{"G20", "T1", "G1 X1 E1 F60"},
+			0, false, nil,
+			[]ToolUsage{{Tool: 1, Time: time.Second, Filament: 25.4, FirstLayer: 0, LastLayer: 0}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []ToolsConfigurationCallbackable
+			if tc.known {
+				options = append(options, func(config ToolsConfigurer) error {
+					return config.SetInitialTool(tc.initial)
+				})
+			}
+
+			report, err := Tools(blocktest.Parse(t, tc.lines...), options...)
+			if err != nil {
+				t.Fatalf("failed to analyze tools: %v", err)
+			}
+
+			var changes []string
+			for _, c := range report.Changes {
+				changes = append(changes, c.String())
+			}
+
+			if !reflect.DeepEqual(changes, tc.changes) {
+				t.Errorf("got changes %q, want %q", changes, tc.changes)
+			}
+
+			if len(report.Tools) != len(tc.tools) {
+				t.Fatalf("got tools %+v, want %+v", report.Tools, tc.tools)
+			}
+
+			for i, want := range tc.tools {
+				got := report.Tools[i]
+				if got.Tool != want.Tool || got.FirstLayer != want.FirstLayer || got.LastLayer != want.LastLayer ||
+					!near(got.Filament, want.Filament) || !near(got.Time.Seconds(), want.Time.Seconds()) {
+					t.Errorf("got tool %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTools_invalidInitialTool(t *testing.T) {
+	_, err := Tools(nil, func(config ToolsConfigurer) error {
+		return config.SetInitialTool(-1)
+	})
+	if err == nil {
+		t.Errorf("got nil, want error")
+	}
+}
+
+func TestToolsReport_String(t *testing.T) {
+	report, err := Tools(blocktest.Parse(t, "M83", "T0", "G1 Z0.2 F600", "G1 X60 E3 F1200", "T1", "G1 Y60 E2"))
+	if err != nil {
+		t.Fatalf("failed to analyze tools: %v", err)
+	}
+
+	want := "1 tool changes\nT0: 3s, 3.0 mm, layers 0-0\nT1: 3s, 2.0 mm, layers 0-0"
+	if got := report.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
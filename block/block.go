@@ -15,6 +15,9 @@
 //
 // - expression attached at the block with some comment. Can be empty
 //
+// Furthermore, a block can store user-defined metadata as key/value tags (e.g. layer=12, object=part_A).
+// The tags aren't part of the gcode, but they can be exported as a structured comment.
+//
 // This package allows storing the data that define a single gcode block.
 package block
 
@@ -40,6 +43,46 @@ type Blocker interface {
 	VerifyChecksum() (bool, error)
 }
 
+// The following interfaces define optional capabilities of a block.
+// They aren't part of Blocker, so the implementations of Blocker don't need to provide them,
+// and the callers that require one must check it with a type assertion, like b.(block.Editor).
+
+// Editor defines the methods to modify the elements of a block after it is created.
+type Editor interface {
+	SetChecksum(checksum gcode.AddressableGcoder[uint32])
+	SetComment(comment string)
+	SetLineNumber(lineNumber gcode.AddressableGcoder[uint32])
+}
+
+// Tagger defines the methods to handle the user-defined metadata tags attached to a block.
+type Tagger interface {
+	RemoveTag(key string)
+	SetTag(key string, value string) error
+	Tag(key string) (string, bool)
+	Tags() map[string]string
+}
+
+// Diagnoser defines the methods to know the issues found in a block while it was parsed.
+type Diagnoser interface {
+	Diagnostics() []Diagnostic
+	DuplicatedWords() []byte
+	Normalized() bool
+}
+
+// Formatter defines the methods to configure how a block exports the addresses of its gcodes.
+type Formatter interface {
+	FloatFormat() gcode.FloatFormat
+	PreserveLiterals() bool
+	SetFloatFormat(format gcode.FloatFormat) error
+	SetPreserveLiterals(preserve bool)
+}
+
+// ChecksumInputer defines the methods to configure which bytes of a block are fed to the hash to calculate the checksum.
+type ChecksumInputer interface {
+	ChecksumInput() ChecksumInput
+	SetChecksumInput(input ChecksumInput)
+}
+
 // BlockConfigurer contains the basic configurable options that define a block when is constructed.
 // Allows inyect the depencies that the block require to handle gcode elements and calculate checksum.
 type BlockConfigurer interface {
@@ -48,6 +91,21 @@ type BlockConfigurer interface {
 
 	// Set the hash instance that implement the algorith to execute checksum
 	SetHash(hash hash.Hash) error
+
+	// Set which bytes of the block are fed to the hash to calculate the checksum
+	SetChecksumInput(input ChecksumInput) error
+
+	// Set how the fractional addresses of the block are exported
+	SetFloatFormat(format gcode.FloatFormat) error
+
+	// Set if the block exports the original text of the addresses instead of format them
+	SetPreserveLiterals(preserve bool) error
+
+	// Set the words accepted by the gcodes of the block using the default gcode factory
+	SetWordRegistry(registry *gcode.WordRegistry) error
+
+	// Set a plugin in the gcode factory of the block to parse a custom address syntax
+	SetAddressPlugin(plugin gcode.AddressPlugin) error
 }
 
 // BlockConstructorConfigurer extends the basic configurable options to add other parameters that define a block when is constructed.
@@ -67,6 +125,9 @@ type BlockConstructorConfigurer interface {
 
 	// Set the comments from the block
 	SetComment(comment string) error
+
+	// Set the user-defined metadata tags of the block
+	SetTags(tags map[string]string) error
 }
 
 // BlockParserConfigurer redefine the basic configurable options that define a block when is constructed.
@@ -75,6 +136,18 @@ type BlockParserConfigurer interface {
 
 	// BlockConfigurer (wrap block.BlockConfigurer) add the basic configurable options requires to create a new Block from Parse string.
 	BlockConfigurer
+
+	// Set how the parser handles the parameters with duplicated words
+	SetDuplicatePolicy(policy DuplicatePolicy) error
+
+	// Set how the parser handles the words written in lowercase
+	SetCasePolicy(policy CasePolicy) error
+
+	// Set if the parser shares immutable instances of the frequent command gcodes between blocks
+	SetInterning(enabled bool) error
+
+	// Set if the parser must store the fractional addresses as float64 instead of float32
+	SetFloat64Promotion(promote bool) error
 }
 
 // BlockConfigurationCallbackable is the signature of the callbacks that the package function New() waiting receives to configure the new block instance.
@@ -87,6 +160,92 @@ type BlockConstructorConfigurationCallbackable func(config BlockConstructorConfi
 // Each callback provide a BlockConfigurer instance that implement a set of methods to configure the new block instance.
 type BlockParserConfigurationCallbackable func(config BlockParserConfigurer) error
 
+// Severity defines the importance of a diagnostic.
+type Severity int
+
+const (
+	// SeverityWarning is used by the diagnostics that report a suspicious block that can be used anyway.
+	SeverityWarning Severity = iota
+
+	// SeverityError is used by the diagnostics that report an invalid block.
+	SeverityError
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Diagnostic describes an issue found in a block while it was parsed.
+type Diagnostic struct {
+	// Severity is the importance of the issue.
+	Severity Severity
+
+	// Message describes the issue.
+	Message string
+}
+
+// String returns the diagnostic formatted.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+// ChecksumInput defines which bytes of the block are fed to the hash to calculate the checksum.
+//
+// The firmwares don't agree on it, so it must be configured to match the controller that will receive the lines.
+// The zero value selects the line exported with the line number and a single space between the gcodes, like "N7 G1 X2.0",
+// which is the convention of the RepRap protocol used by Marlin and RepRapFirmware.
+//
+// The checksum is written after the bytes selected, so the line sent must be exported with the same layout,
+// for example "%l%c%p%k" without spaces if Compact is used.
+type ChecksumInput struct {
+	// ExcludeLineNumber removes the line number from the bytes checksummed.
+	ExcludeLineNumber bool
+
+	// Compact removes the spaces between the gcodes, like "N7G1X2.0".
+	Compact bool
+
+	// TrailingSpace adds a space after the last gcode, for the hosts that send a space before the checksum, like "N7 G1 X2.0 *85".
+	TrailingSpace bool
+}
+
+// CasePolicy defines how the parser handles the words written in lowercase, like "g1 x10".
+//
+// The addresses enclosed in quotes are never modified.
+type CasePolicy int
+
+const (
+	// CaseReject keeps the words as they are written, so the lowercase words are rejected unless the word registry accepts them.
+	// It is the default policy.
+	CaseReject CasePolicy = iota
+
+	// CaseNormalize converts the lowercase words to uppercase before parse them. The block records that it was normalized.
+	CaseNormalize
+)
+
+// DuplicatePolicy defines how the parser handles the parameters that repeat a word, like "G1 X5 X7".
+//
+// The words G and M are never considered duplicated, because a block can contain many of them legally.
+type DuplicatePolicy int
+
+const (
+	// DuplicateWarn keeps all parameters and adds a warning diagnostic to the block. It is the default policy.
+	DuplicateWarn DuplicatePolicy = iota
+
+	// DuplicateError rejects the block with an error.
+	DuplicateError
+
+	// DuplicateAllow keeps all parameters without reporting anything.
+	DuplicateAllow
+)
+
 // BlockerFactory define the methods to create new Block instances
 type BlockerFactory interface {
 	// New return a new block instance with the configurations wishes.
@@ -104,3 +263,20 @@ type BlockerFactory interface {
 	// each option provides a config object that can be used to load the values that define the block.
 	Parse(source string, options ...BlockParserConfigurationCallbackable) (*Blocker, error)
 }
+
+// Pool recycles the blocks and their parameter slices to reduce the allocations of high-throughput parsing pipelines,
+// like senders that stream millions of lines.
+//
+// The aliasing rules are strict. A block obtained from a pool belongs to the caller until it is released.
+// After Release is called, the block and the slice returned by its Parameters method mustn't be used anymore,
+// because they will be reused to store the content of other lines.
+// The gcode instances referenced by the block aren't recycled, and the map returned by its Tags method is a copy,
+// so they can be kept after releasing the block.
+type Pool interface {
+	// Parse returns a block, possibly recycled, with the content of a single block line.
+	Parse(source string, options ...BlockParserConfigurationCallbackable) (Blocker, error)
+
+	// Release returns the block to the pool. Releasing a block that wasn't obtained from the pool,
+	// or releasing the same block twice, is ignored.
+	Release(b Blocker)
+}
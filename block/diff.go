@@ -0,0 +1,132 @@
+package block
+
+import "github.com/mauroalderete/gcode-core/gcode"
+
+//#region difference structs
+
+// Change stores the string representation of a section in each block compared.
+//
+// An empty string means that the section isn't present in that block.
+type Change struct {
+	// Left is the value of the section in the first block.
+	Left string
+
+	// Right is the value of the section in the second block.
+	Right string
+}
+
+// ParameterChange stores the difference of a single parameter, identified by its position in the parameters list.
+type ParameterChange struct {
+	// Change (via embedded block.Change struct) contains the values of the parameter in each block.
+	Change
+
+	// Index is the position of the parameter in the parameters list.
+	Index int
+}
+
+// Difference reports which sections differ between two blocks.
+//
+// Each field is nil, or empty, when the section is equal in both blocks.
+type Difference struct {
+	// LineNumber stores the difference of the line number gcodes.
+	LineNumber *Change
+
+	// Command stores the difference of the command gcodes.
+	Command *Change
+
+	// Parameters stores the difference of each parameter that isn't equal, in order.
+	Parameters []ParameterChange
+
+	// Checksum stores the difference of the checksum gcodes.
+	Checksum *Change
+
+	// Comment stores the difference of the comments.
+	Comment *Change
+}
+
+// Equal returns true if there isn't differences between the blocks compared.
+func (d Difference) Equal() bool {
+	return d.LineNumber == nil && d.Command == nil && len(d.Parameters) == 0 && d.Checksum == nil && d.Comment == nil
+}
+
+//#endregion
+//#region package functions
+
+// Diff compares two blocks section by section and reports which of them differ.
+//
+// The gcodes are compared using their Compare method, so two gcodes with the same text representation
+// but different address data type are reported as different.
+// The parameters are compared by position, when a block has more parameters than the other,
+// the extra parameters are reported with an empty value in the other side.
+func Diff(left Blocker, right Blocker) Difference {
+	var d Difference
+
+	d.LineNumber = diffGcode(addressableToGcoder(left.LineNumber()), addressableToGcoder(right.LineNumber()))
+	d.Command = diffGcode(left.Command(), right.Command())
+	d.Checksum = diffGcode(addressableToGcoder(left.Checksum()), addressableToGcoder(right.Checksum()))
+
+	if left.Comment() != right.Comment() {
+		d.Comment = &Change{Left: left.Comment(), Right: right.Comment()}
+	}
+
+	leftParameters := left.Parameters()
+	rightParameters := right.Parameters()
+
+	size := len(leftParameters)
+	if len(rightParameters) > size {
+		size = len(rightParameters)
+	}
+
+	for i := 0; i < size; i++ {
+		var l, r gcode.Gcoder
+		if i < len(leftParameters) {
+			l = leftParameters[i]
+		}
+		if i < len(rightParameters) {
+			r = rightParameters[i]
+		}
+
+		if c := diffGcode(l, r); c != nil {
+			d.Parameters = append(d.Parameters, ParameterChange{Change: *c, Index: i})
+		}
+	}
+
+	return d
+}
+
+//#endregion
+//#region private functions
+
+// diffGcode returns a change if the gcodes aren't equal, or nil if both are equal or both are nil.
+func diffGcode(left gcode.Gcoder, right gcode.Gcoder) *Change {
+	if left == nil && right == nil {
+		return nil
+	}
+
+	if left != nil && right != nil && left.Compare(right) {
+		return nil
+	}
+
+	c := &Change{}
+	if left != nil {
+		c.Left = left.String()
+	}
+	if right != nil {
+		c.Right = right.String()
+	}
+
+	return c
+}
+
+// addressableToGcoder converts an addressable gcode in a gcoder, keeping the nil value.
+//
+// It avoids to get a non-nil gcoder interface that contains a nil addressable gcode.
+func addressableToGcoder(g gcode.AddressableGcoder[uint32]) gcode.Gcoder {
+	if g == nil {
+		return nil
+	}
+
+	return g
+}
+
+//#endregion
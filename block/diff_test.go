@@ -0,0 +1,114 @@
+package block_test
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+)
+
+func TestDiff(t *testing.T) {
+	cases := map[string]struct {
+		left       string
+		right      string
+		lineNumber *block.Change
+		command    *block.Change
+		parameters []block.ParameterChange
+		checksum   *block.Change
+		comment    *block.Change
+	}{
+		"equal": {
+			left:  "N4 G92 E0*67;reset",
+			right: "N4 G92 E0*67;reset",
+		},
+		"line number": {
+			left:       "N4 G92 E0",
+			right:      "N5 G92 E0",
+			lineNumber: &block.Change{Left: "N4", Right: "N5"},
+		},
+		"line number added": {
+			left:       "G92 E0",
+			right:      "N5 G92 E0",
+			lineNumber: &block.Change{Left: "", Right: "N5"},
+		},
+		"command": {
+			left:    "G0 X1",
+			right:   "G1 X1",
+			command: &block.Change{Left: "G0", Right: "G1"},
+		},
+		"parameter value": {
+			left:       "G1 X1 Y2",
+			right:      "G1 X1 Y3",
+			parameters: []block.ParameterChange{{Index: 1, Change: block.Change{Left: "Y2", Right: "Y3"}}},
+		},
+		"parameter data type": {
+			left:       "G1 X1",
+			right:      "G1 X1.0",
+			parameters: []block.ParameterChange{{Index: 0, Change: block.Change{Left: "X1", Right: "X1.0"}}},
+		},
+		"parameter removed": {
+			left:       "G1 X1 Y2 E3",
+			right:      "G1 X1",
+			parameters: []block.ParameterChange{{Index: 1, Change: block.Change{Left: "Y2"}}, {Index: 2, Change: block.Change{Left: "E3"}}},
+		},
+		"checksum and comment": {
+			left:     "N4 G92 E0*67",
+			right:    "N4 G92 E0*10;changed",
+			checksum: &block.Change{Left: "*67", Right: "*10"},
+			comment:  &block.Change{Left: "", Right: ";changed"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			left, err := gcodeblock.Parse(tc.left)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			right, err := gcodeblock.Parse(tc.right)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			d := block.Diff(left, right)
+
+			equal := tc.lineNumber == nil && tc.command == nil && tc.parameters == nil && tc.checksum == nil && tc.comment == nil
+			if d.Equal() != equal {
+				t.Errorf("got equal %v, want equal %v", d.Equal(), equal)
+			}
+
+			compareChange(t, "line number", d.LineNumber, tc.lineNumber)
+			compareChange(t, "command", d.Command, tc.command)
+			compareChange(t, "checksum", d.Checksum, tc.checksum)
+			compareChange(t, "comment", d.Comment, tc.comment)
+
+			if len(d.Parameters) != len(tc.parameters) {
+				t.Errorf("got parameters %+v, want parameters %+v", d.Parameters, tc.parameters)
+				return
+			}
+			for i := range tc.parameters {
+				if d.Parameters[i] != tc.parameters[i] {
+					t.Errorf("got parameter %+v, want parameter %+v", d.Parameters[i], tc.parameters[i])
+				}
+			}
+		})
+	}
+}
+
+func compareChange(t *testing.T, section string, got *block.Change, want *block.Change) {
+	t.Helper()
+
+	if got == nil || want == nil {
+		if got != want {
+			t.Errorf("got %s change %+v, want %s change %+v", section, got, section, want)
+		}
+		return
+	}
+
+	if *got != *want {
+		t.Errorf("got %s change %+v, want %s change %+v", section, *got, section, *want)
+	}
+}
@@ -2,6 +2,8 @@ package gcodeblock_test
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mauroalderete/gcode-core/block"
 	"github.com/mauroalderete/gcode-core/block/gcodeblock"
@@ -275,3 +277,87 @@ func ExampleGcodeBlock_ToLine_third() {
 
 	// Output: line is:
 }
+
+func ExamplePool() {
+	pool := gcodeblock.NewPool()
+
+	for _, line := range []string{"G28", "G1 X2.0 Y2.0 F3000.0"} {
+		b, err := pool.Parse(line)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+
+		fmt.Println(b.String())
+
+		// the block mustn't be used after it is released
+		pool.Release(b)
+	}
+
+	// Output:
+	// G28
+	// G1 X2.0 Y2.0 F3000.0
+}
+
+// durationGcode is a gcode with an address expressed as a duration, like P1.5s.
+type durationGcode struct {
+	word     byte
+	duration time.Duration
+}
+
+func (g *durationGcode) String() string {
+	return fmt.Sprintf("%s%gs", string(g.word), g.duration.Seconds())
+}
+
+func (g *durationGcode) Compare(other gcode.Gcoder) bool {
+	o, ok := other.(*durationGcode)
+	return ok && o.word == g.word && o.duration == g.duration
+}
+
+func (g *durationGcode) HasAddress() bool {
+	return true
+}
+
+func (g *durationGcode) Word() byte {
+	return g.word
+}
+
+// durationPlugin recognizes the addresses that end with a time unit, like 500ms or 1.5s.
+type durationPlugin struct{}
+
+func (p durationPlugin) Name() string {
+	return "duration"
+}
+
+func (p durationPlugin) Match(word byte, address string) bool {
+	return strings.HasSuffix(address, "s")
+}
+
+func (p durationPlugin) Parse(word byte, address string) (gcode.Gcoder, error) {
+	duration, err := time.ParseDuration(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &durationGcode{word: word, duration: duration}, nil
+}
+
+func ExampleParse_addressPlugin() {
+
+	b, err := gcodeblock.Parse("G4 P1500ms", func(config block.BlockParserConfigurer) error {
+		return config.SetAddressPlugin(durationPlugin{})
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	dwell := b.Parameters()[0].(*durationGcode)
+
+	fmt.Println(b.String())
+	fmt.Println(dwell.duration)
+
+	// Output:
+	// G4 P1.5s
+	// 1.5s
+}
@@ -1,6 +1,8 @@
 // gcodeblock is an implementation of block package.
 //
 // This package define GcodeBlock struct as a implemention of block.Blocker interface.
+// It also implements all the optional capabilities of the block package: block.Editor, block.Tagger, block.Diagnoser,
+// block.Formatter and block.ChecksumInputer.
 //
 // Furemore, it defines two package functions that allows create new instances of GcodeBlock.
 // These functions can be used to a any instances that implement block.BlockerFactory
@@ -9,9 +11,12 @@ package gcodeblock
 import (
 	"fmt"
 	"hash"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mauroalderete/gcode-core/block"
 	"github.com/mauroalderete/gcode-core/block/internal/gcodefactory"
@@ -22,6 +27,28 @@ import (
 const (
 	// BLOC_SEPARATOR defines a string used to separate the sections of the block when is exported as line string format
 	BLOCK_SEPARATOR = " "
+
+	// TAGS_COMMENT_PREFIX defines the prefix of the structured comment used to export the tags of the block
+	TAGS_COMMENT_PREFIX = ";@meta"
+)
+
+var (
+	// expressions used to parse the lines, compiled once
+	commentRegex         = regexp.MustCompile(`\s*;.*$`)
+	lineNumberRegex      = regexp.MustCompile(`^N\d+`)
+	checksumRegex        = regexp.MustCompile(`\b\*\d+$`)
+	quoteRegex           = regexp.MustCompile(`(?U)""`)
+	gcodesRegex          = regexp.MustCompile(`(?U)(\w-?\d+(\.\d+)?\s)|((^\w")|(\s*\w(##)*")).*"|(\s*;.*$)|(\w-?\d+(\.\d+)?$)|(\w[^\s;"]+?(\s|$))`)
+	duplicateSpacesRegex = regexp.MustCompile(`\s{2,}`)
+	specialCharsRegex    = regexp.MustCompile(`[\n\t\r]`)
+
+	// defaultGcodeFactory is shared by the blocks without a custom gcode factory. It is never modified,
+	// SetAddressPlugin registers the plugins in a new factory instead.
+	defaultGcodeFactory = &gcodefactory.GcodeFactory{}
+
+	// hashLocks serialize the use of the hashes shared between blocks, like the one set with SetHash to many blocks,
+	// so the blocks can calculate their checksums concurrently. Each hash is guarded by the lock selected by its address.
+	hashLocks [64]sync.Mutex
 )
 
 //#region block struct
@@ -38,12 +65,21 @@ type GcodeBlock struct {
 	// special gcode that store the value of the verification of the integrity of the block
 	checksum gcode.AddressableGcoder[uint32]
 
+	// bytes of the block fed to the hash to calculate the checksum
+	checksumInput block.ChecksumInput
+
+	// true if the checksum was calculated by UpdateChecksum instead of read with the line, so it belongs to the line exported
+	calculatedChecksum bool
+
 	// first gcode expression and main significance of the block. Always is present.
 	command gcode.Gcoder
 
 	// expression attached at the block with some comment. Can be empty
 	comment string
 
+	// format used to export the fractional addresses
+	floatFormat gcode.FloatFormat
+
 	// gcode factory
 	gcodeFactory gcode.GcoderFactory
 
@@ -55,6 +91,30 @@ type GcodeBlock struct {
 
 	// list of the rest of the gcode expression that adds information to the command. Can be empty.
 	parameters []gcode.Gcoder
+
+	// if it is true the gcodes are exported using the original text of their addresses when it is known
+	preserveLiterals bool
+
+	// user-defined metadata attached to the block. Can be nil.
+	tags map[string]string
+
+	// issues found while the block was parsed. Can be empty.
+	diagnostics []block.Diagnostic
+
+	// true if the words were converted to uppercase while the block was parsed
+	normalized bool
+
+	// pool that owns the block while it is in use, nil if the block isn't pooled.
+	pool *Pool
+
+	// policies of the parser that created the block, reused by Derive
+	policies parserPolicies
+
+	// scratch memory reused to feed the hash and to read its digest
+	buffer []byte
+
+	// default hash created with the block, reused when the block is recycled by a pool
+	ownedHash *checksum.XOR
 }
 
 // String returns the block exported as single-line string format including check and comments section.
@@ -64,6 +124,42 @@ func (b *GcodeBlock) String() string {
 	return b.ToLine("%l %c %p")
 }
 
+// Normalized returns true if some word was written in lowercase and it was converted to uppercase while the block was parsed.
+func (b *GcodeBlock) Normalized() bool {
+	return b.normalized
+}
+
+// FloatFormat returns the format used to export the fractional addresses of the block.
+func (b *GcodeBlock) FloatFormat() gcode.FloatFormat {
+	return b.floatFormat
+}
+
+// SetFloatFormat changes the format used to export the fractional addresses of the block.
+//
+// It affects ToLine, String and the checksum calculated after the change. The checksum stored isn't updated.
+func (b *GcodeBlock) SetFloatFormat(format gcode.FloatFormat) error {
+	if err := format.Validate(); err != nil {
+		return fmt.Errorf("failed to set float format: %w", err)
+	}
+
+	b.floatFormat = format
+
+	return nil
+}
+
+// PreserveLiterals returns true if the block exports the original text of the addresses instead of format them.
+func (b *GcodeBlock) PreserveLiterals() bool {
+	return b.preserveLiterals
+}
+
+// SetPreserveLiterals defines if the block exports the original text of the addresses, like X0010.50, instead of format them.
+//
+// The gcodes without a literal, like the ones created by a constructor or modified after parsed, are always formatted.
+// It affects ToLine, String and the checksum calculated after the change.
+func (b *GcodeBlock) SetPreserveLiterals(preserve bool) {
+	b.preserveLiterals = preserve
+}
+
 // LineNumber returns a gcode addressable of the int32 type.
 //
 // Represent the line number of the block. It can be null. Always has an int32 type address.
@@ -71,6 +167,13 @@ func (b *GcodeBlock) LineNumber() gcode.AddressableGcoder[uint32] {
 	return b.lineNumber
 }
 
+// SetLineNumber replaces the line number gcode of the block, nil removes it.
+//
+// The checksum isn't updated, it must be recalculated with UpdateChecksum.
+func (b *GcodeBlock) SetLineNumber(lineNumber gcode.AddressableGcoder[uint32]) {
+	b.lineNumber = lineNumber
+}
+
 // Command returns a gcoder struct. Can be addressable or not.
 //
 // Represent the first gcode expression and main significance of the block. Always is present.
@@ -92,18 +195,42 @@ func (b *GcodeBlock) Checksum() gcode.AddressableGcoder[uint32] {
 	return b.checksum
 }
 
+// SetChecksum replaces the checksum gcode of the block, nil removes it.
+//
+// The value isn't verified, use UpdateChecksum to store the value calculated.
+// VerifyChecksum checks it like a checksum read with the line.
+func (b *GcodeBlock) SetChecksum(checksum gcode.AddressableGcoder[uint32]) {
+	b.checksum = checksum
+	b.calculatedChecksum = false
+}
+
+// ChecksumInput returns which bytes of the block are fed to the hash to calculate the checksum.
+func (b *GcodeBlock) ChecksumInput() block.ChecksumInput {
+	return b.checksumInput
+}
+
+// SetChecksumInput replaces which bytes of the block are fed to the hash to calculate the checksum.
+//
+// The checksum stored isn't updated, use UpdateChecksum to store the value calculated with the new input.
+func (b *GcodeBlock) SetChecksumInput(input block.ChecksumInput) {
+	b.checksumInput = input
+}
+
 // CalculateChecksum calculates a checksum from the block and returns a new GcodeAddressable[uint32] with the value computed.
+//
+// The bytes fed to the hash are selected by the checksum input of the block.
+// The digest of the hash is interpreted as a big-endian integer, so the hashes with up to 4 bytes are supported,
+// like the XOR checksum, CRC16 or CRC32. The wider hashes return an error.
 func (b *GcodeBlock) CalculateChecksum() (gcode.AddressableGcoder[uint32], error) {
 
-	b.hash.Reset()
-	_, err := b.hash.Write([]byte(b.String()))
+	value, err := b.checksumValue(b.preserveLiterals)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate hash to block %s: %w", b, err)
+		return nil, err
 	}
 
-	gc, err := b.gcodeFactory.NewAddressableGcodeUint32('*', uint32(b.hash.Sum(nil)[0]))
+	gc, err := b.gcodeFactory.NewAddressableGcodeUint32('*', value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create checksum gcode instance with hash %v: %w", uint32(b.hash.Sum(nil)[0]), err)
+		return nil, fmt.Errorf("failed to create checksum gcode instance with hash %v: %w", value, err)
 	}
 
 	return gc, nil
@@ -118,32 +245,144 @@ func (b *GcodeBlock) UpdateChecksum() error {
 	}
 
 	b.checksum = gc
+	b.calculatedChecksum = true
 
 	return nil
 }
 
 // VerifyChecksum calculates a checksum and compare him with the checksum stored in the block, it returns true if both matches.
+//
+// The firmwares compute the checksum over the bytes of the line exactly as they were sent, so it is calculated over the line
+// that the checksum belongs to, and only over it. A checksum read with the line is calculated over the original text
+// of the addresses, like X0010.50, even if the block doesn't preserve them. A checksum stored by UpdateChecksum is calculated
+// over the line exported, like CalculateChecksum does, because the original text isn't sent with it anymore.
+// The gcodes without an original text, like the ones created by a constructor or modified after parsed, are always formatted.
 func (b *GcodeBlock) VerifyChecksum() (bool, error) {
 
 	if b.checksum == nil {
 		return false, fmt.Errorf("the block '%s' hasn't check section", b)
 	}
 
-	gc, err := b.CalculateChecksum()
+	value, err := b.checksumValue(b.preserveLiterals || !b.calculatedChecksum)
 	if err != nil {
 		return false, fmt.Errorf("failed to calculate hash to the control of the checksum of the block %s: %w", b, err)
 	}
 
-	return b.checksum.Compare(gc), nil
+	return b.checksum.Address() == value, nil
 }
 
 // Comment returns the string with the comment of the block. Or nil if there isn't one.
 //
 // Is an expression attached at the block with some comment. Can be empty.
+// It keeps the whitespace of the line around the comment, like the spaces before the semicolon in "G28 ;home".
 func (b *GcodeBlock) Comment() string {
 	return b.comment
 }
 
+// SetComment replaces the comment of the block, an empty string removes it.
+//
+// The comment must include its delimiter, like ";lorem ipsum".
+func (b *GcodeBlock) SetComment(comment string) {
+	b.comment = comment
+}
+
+// Diagnostics returns the issues found while the block was parsed, like duplicated words.
+func (b *GcodeBlock) Diagnostics() []block.Diagnostic {
+	return b.diagnostics
+}
+
+// DuplicatedWords returns the words that appear in more than one parameter, in order of appearance.
+//
+// The words G and M are ignored, because a block can contain many of them legally.
+func (b *GcodeBlock) DuplicatedWords() []byte {
+	var duplicated []byte
+	seen := make(map[byte]int)
+
+	for _, p := range b.parameters {
+		word := p.Word()
+		if word == 'G' || word == 'M' {
+			continue
+		}
+
+		seen[word]++
+		if seen[word] == 2 {
+			duplicated = append(duplicated, word)
+		}
+	}
+
+	return duplicated
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+//
+// It returns the block exported as a single-line including line number, command, parameters, checksum and comment.
+// Returns an error if the block hasn't a command.
+func (b *GcodeBlock) MarshalText() ([]byte, error) {
+	if b.command == nil {
+		return nil, fmt.Errorf("failed to marshal block, it hasn't command")
+	}
+
+	return []byte(b.ToLine("%l %c %p%k %m")), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+//
+// It replaces the content of the block with the result of parse the text using the default configuration.
+// The separator that MarshalText writes before the comment isn't kept in the comment.
+// Returns an error if the text isn't a valid block line. In that case, the block isn't modified.
+func (b *GcodeBlock) UnmarshalText(text []byte) error {
+	nb, err := Parse(string(text))
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal block %q: %w", text, err)
+	}
+
+	nb.comment = strings.TrimLeft(nb.comment, " \t")
+
+	// a recycled block keeps belonging to its pool
+	nb.pool = b.pool
+	*b = *nb
+
+	return nil
+}
+
+// Tag returns the value of a metadata tag and if it exists.
+func (b *GcodeBlock) Tag(key string) (string, bool) {
+	value, ok := b.tags[key]
+	return value, ok
+}
+
+// Tags returns a copy of all metadata tags of the block. Modifying the map returned doesn't affect the block.
+func (b *GcodeBlock) Tags() map[string]string {
+	tags := make(map[string]string, len(b.tags))
+	for key, value := range b.tags {
+		tags[key] = value
+	}
+
+	return tags
+}
+
+// SetTag stores a metadata tag in the block, replacing the previous value if it exists.
+//
+// The key mustn't be empty and can't contain spaces or the '=' character.
+func (b *GcodeBlock) SetTag(key string, value string) error {
+	if err := isTagKeyValid(key); err != nil {
+		return fmt.Errorf("failed to set tag %s: %w", key, err)
+	}
+
+	if b.tags == nil {
+		b.tags = make(map[string]string)
+	}
+
+	b.tags[key] = value
+
+	return nil
+}
+
+// RemoveTag deletes a metadata tag from the block. It does nothing if the tag doesn't exist.
+func (b *GcodeBlock) RemoveTag(key string) {
+	delete(b.tags, key)
+}
+
 // ToLine export the block as a single-line string format
 //
 // format is a string that contain verbs to define the place of each element of the block.
@@ -159,6 +398,8 @@ func (b *GcodeBlock) Comment() string {
 //
 // %m: comments of the block
 //
+// %t: metadata tags of the block as a structured comment, like ";@meta layer=12 object=part_A"
+//
 // We can used the format string to determine how each element is showing. For example:
 //
 // The line generated depends on the available of elements contained in the block.
@@ -166,17 +407,17 @@ func (b *GcodeBlock) Comment() string {
 func (b *GcodeBlock) ToLine(format string) string {
 	var values []string
 
-	result := strings.ReplaceAll(format, "%c", b.Command().String())
+	result := strings.ReplaceAll(format, "%c", b.formatGcode(b.Command()))
 
 	if b.lineNumber != nil {
-		result = strings.ReplaceAll(result, "%l", b.LineNumber().String())
+		result = strings.ReplaceAll(result, "%l", b.formatGcode(b.LineNumber()))
 	} else {
 		result = strings.ReplaceAll(result, "%l", "")
 	}
 
 	if b.parameters != nil {
 		for _, g := range b.parameters {
-			values = append(values, g.String())
+			values = append(values, b.formatGcode(g))
 		}
 		if len(values) == 0 {
 			values = append(values, "")
@@ -187,16 +428,81 @@ func (b *GcodeBlock) ToLine(format string) string {
 	}
 
 	if b.checksum != nil {
-		result = strings.ReplaceAll(result, "%k", b.Checksum().String())
+		result = strings.ReplaceAll(result, "%k", b.formatGcode(b.Checksum()))
 	} else {
 		result = strings.ReplaceAll(result, "%k", "")
 	}
 
-	result = strings.ReplaceAll(result, "%m", b.comment)
+	// the format defines the separator before the comment, so the whitespace that separated it in the line isn't repeated
+	result = strings.ReplaceAll(result, "%m", strings.TrimLeft(b.comment, " \t"))
+
+	result = strings.ReplaceAll(result, "%t", b.tagsComment())
 
 	return strings.TrimSpace(result)
 }
 
+// Derive returns a new block parsed from a single block line with the same configuration of the block.
+//
+// The new block shares the gcode factory and the hash of the block, so it accepts the same words and calculates the same checksum,
+// and copies its checksum input, float format, literal policy, tags and the policies of the parser that created it.
+// It is used to replace a block by a modified version of itself, like a block with another parameter.
+// The checksum isn't recalculated, use UpdateChecksum to store the value of the new line.
+func (b *GcodeBlock) Derive(source string) (*GcodeBlock, error) {
+
+	derived := &GcodeBlock{}
+
+	err := derived.parse(source, func(config block.BlockParserConfigurer) error {
+		if configurator, ok := config.(*blockConfigurator); ok {
+			configurator.parserPolicies = b.policies
+		}
+
+		if err := config.SetGcodeFactory(b.gcodeFactory); err != nil {
+			return err
+		}
+
+		// the default hash isn't shared, the new block has its own one
+		if b.hash != hash.Hash(b.ownedHash) {
+			if err := config.SetHash(b.hash); err != nil {
+				return err
+			}
+		}
+
+		if err := config.SetChecksumInput(b.checksumInput); err != nil {
+			return err
+		}
+
+		if err := config.SetFloatFormat(b.floatFormat); err != nil {
+			return err
+		}
+
+		return config.SetPreserveLiterals(b.preserveLiterals)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive block %s: %w", b, err)
+	}
+
+	if len(b.tags) > 0 {
+		derived.tags = make(map[string]string, len(b.tags))
+		for key, value := range b.tags {
+			derived.tags[key] = value
+		}
+	}
+
+	return derived, nil
+}
+
+// NewParameter returns a new float64 gcode created with the gcode factory of the block, so it accepts the same words.
+// It is used to replace a parameter whose address can't keep a new value, like an integer address moved to a fraction.
+func (b *GcodeBlock) NewParameter(word byte, value float64) (gcode.Gcoder, error) {
+
+	g, err := b.gcodeFactory.NewAddressableGcodeFloat64(word, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parameter %c: %w", word, err)
+	}
+
+	return g, nil
+}
+
 //#endregion
 //#region constructor
 
@@ -213,10 +519,13 @@ func New(command gcode.Gcoder, options ...block.BlockConstructorConfigurationCal
 	}
 
 	// prepare a new GcodeBlock instance with some values by default
+	xor := checksum.NewXOR()
 	gcodeBlock := &GcodeBlock{
 		command:      command,
-		gcodeFactory: &gcodefactory.GcodeFactory{},
-		hash:         checksum.New(),
+		floatFormat:  gcode.DefaultFloatFormat(),
+		gcodeFactory: defaultGcodeFactory,
+		hash:         xor,
+		ownedHash:    xor,
 	}
 
 	// prepare an instance of the BlockConfigurer interface to store each configuration callback received
@@ -260,14 +569,33 @@ func New(command gcode.Gcoder, options ...block.BlockConstructorConfigurationCal
 // each option provides a config object that can be used to load the values that define the block.
 func Parse(source string, options ...block.BlockParserConfigurationCallbackable) (*GcodeBlock, error) {
 
-	gcodeFactory := &gcodefactory.GcodeFactory{}
-	hashGenerator := checksum.New()
+	gcodeBlock := &GcodeBlock{}
 
-	gcodeBlock := &GcodeBlock{
-		gcodeFactory: gcodeFactory,
-		hash:         hashGenerator,
+	err := gcodeBlock.parse(source, options...)
+	if err != nil {
+		return nil, err
 	}
 
+	return gcodeBlock, nil
+}
+
+//#endregion
+//#region private functions
+
+// parse loads the block with the elements of a single block line.
+//
+// The block must be empty, it is used by Parse with a new instance and by Pool with a recycled one.
+func (b *GcodeBlock) parse(source string, options ...block.BlockParserConfigurationCallbackable) error {
+
+	b.floatFormat = gcode.DefaultFloatFormat()
+	// the default hash is kept by the pooled blocks between their uses
+	if b.ownedHash == nil {
+		b.ownedHash = checksum.NewXOR()
+	}
+
+	b.gcodeFactory = defaultGcodeFactory
+	b.hash = b.ownedHash
+
 	// prepare an instance of the BlockConfigurer interface to store each configuration callback received
 	configurator := &blockConfigurator{}
 
@@ -275,66 +603,74 @@ func Parse(source string, options ...block.BlockParserConfigurationCallbackable)
 	for _, option := range options {
 		err := option(configurator)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load configuration: %w", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		// apply each configuration callback that modify the new gcodeBlock instance
+		// apply each configuration callback that modify the block instance
 		for _, action := range configurator.configurationCallbacks {
-			err := action(gcodeBlock)
+			err := action(b)
 			if err != nil {
-				return nil, fmt.Errorf("failed to apply configuration: %w", err)
+				return fmt.Errorf("failed to apply configuration: %w", err)
 			}
 		}
 	}
 
+	b.policies = configurator.parserPolicies
+
 	parse := prepareSourceToParse(source)
 
 	// recover comments value if is exist
-	element := take(parse, `\s*;.*$`)
+	element := take(parse, commentRegex)
 	if element.taken != "" {
-		gcodeBlock.comment = element.taken
+		b.comment = element.taken
 		parse = strings.TrimSpace(element.remainder)
 	}
 
+	// convert the lowercase words to uppercase, before they are recognized
+	if configurator.casePolicy == block.CaseNormalize {
+		parse, b.normalized = normalizeWords(parse)
+	}
+
 	// recover linenumber value if is exist
-	element = take(parse, `^N\d+`)
+	element = take(parse, lineNumberRegex)
 	if element.taken != "" {
 		address, err := strconv.ParseInt(element.taken[1:], 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("try parse Linenumber %v: %w", element.taken, err)
+			return fmt.Errorf("try parse Linenumber %v: %w", element.taken, err)
 		}
-		gcode, err := gcodeBlock.gcodeFactory.NewAddressableGcodeUint32('N', uint32(address))
+		gcode, err := b.gcodeFactory.NewAddressableGcodeUint32('N', uint32(address))
 		if err != nil {
-			return nil, fmt.Errorf("try generate Linenumber gcode: %w", err)
+			return fmt.Errorf("try generate Linenumber gcode: %w", err)
 		}
-		gcodeBlock.lineNumber = gcode
+		gcodefactory.KeepLiteral(gcode, element.taken)
+		b.lineNumber = gcode
 		parse = strings.TrimSpace(element.remainder)
 	}
 
 	// recover checksum value if is exist
-	element = take(parse, `\b\*\d+$`)
+	element = take(parse, checksumRegex)
 	if element.taken != "" {
-		address, err := strconv.ParseInt(element.taken[1:], 10, 32)
+		address, err := strconv.ParseUint(element.taken[1:], 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("try parse checksum %v: %w", element.taken, err)
+			return fmt.Errorf("try parse checksum %v: %w", element.taken, err)
 		}
-		gcode, err := gcodeBlock.gcodeFactory.NewAddressableGcodeUint32('*', uint32(address))
+		gcode, err := b.gcodeFactory.NewAddressableGcodeUint32('*', uint32(address))
 		if err != nil {
-			return nil, fmt.Errorf("try generate checksum gcode: %w", err)
+			return fmt.Errorf("try generate checksum gcode: %w", err)
 		}
-		gcodeBlock.checksum = gcode
+		gcodefactory.KeepLiteral(gcode, element.taken)
+		b.checksum = gcode
 		parse = strings.TrimSpace(element.remainder)
 	}
 
 	// apply mask to simplify quotes handle
-	quoteRegex := regexp.MustCompile(`(?U)""`)
 	parseQuotesSimplify := string(quoteRegex.ReplaceAll([]byte(parse), []byte{'#', '#'}))
 
 	// get gcodes index from parseQuotesSimplify
-	gcodesRegex := regexp.MustCompile(`(?U)(\w-?\d+(\.\d+)?\s)|((^\w")|(\s*\w(##)*")).*"|(\s*;.*$)|(\w-?\d+(\.\d+)?$)`)
+	// the last alternative takes any other address syntax, to be recognized by the address plugins
 	gcodesMatchIndex := gcodesRegex.FindAllStringIndex(parseQuotesSimplify, -1)
 	if gcodesMatchIndex == nil {
-		return nil, fmt.Errorf("failed to try get command gcode: There isn't match to (%d):%s", len(parse), parse)
+		return fmt.Errorf("failed to try get command gcode: There isn't match to (%d):%s", len(parse), parse)
 	}
 
 	// apply gcodes index getting, using parseQuotesSimplify, on parse
@@ -350,7 +686,7 @@ func Parse(source string, options ...block.BlockParserConfigurationCallbackable)
 	}
 	parseCheckEmpty = strings.TrimSpace(parseCheckEmpty)
 	if len(parseCheckEmpty) > 0 {
-		return nil, fmt.Errorf("found undefined symbols %s", parseCheckEmpty)
+		return fmt.Errorf("found undefined symbols %s", parseCheckEmpty)
 	}
 
 	// parsing gcodes
@@ -358,34 +694,213 @@ func Parse(source string, options ...block.BlockParserConfigurationCallbackable)
 		m := parse[loc[0]:loc[1]]
 		m = strings.TrimSpace(m)
 
-		gcode, err := gcodeFactory.Parse(m)
+		gcode, err := b.parseGcode(m, configurator)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if gcodeBlock.command == nil {
-			gcodeBlock.command = gcode
+		if b.command == nil {
+			b.command = gcode
 		} else {
-			gcodeBlock.parameters = append(gcodeBlock.parameters, gcode)
+			b.parameters = append(b.parameters, gcode)
 		}
 	}
 
-	return gcodeBlock, nil
+	// handle the parameters with duplicated words
+	if duplicated := b.DuplicatedWords(); len(duplicated) > 0 {
+		switch configurator.duplicatePolicy {
+		case block.DuplicateError:
+			return fmt.Errorf("found duplicated words %s in %s", string(duplicated), parse)
+		case block.DuplicateWarn:
+			b.diagnostics = append(b.diagnostics, block.Diagnostic{
+				Severity: block.SeverityWarning,
+				Message:  fmt.Sprintf("found duplicated words %s", string(duplicated)),
+			})
+		}
+	}
+
+	return nil
 }
 
-//#endregion
-//#region private functions
+// checksumValue feeds the hash with the bytes selected by the checksum input of the block and returns its digest as a big-endian integer.
+// If literals is true the gcodes are written with the original text of their addresses, when it is known.
+//
+// The line is written in a buffer reused between calls, so it doesn't allocate memory after the first call.
+// A hash that isn't the default one of the block is locked while it is used, because other blocks can share it.
+func (b *GcodeBlock) checksumValue(literals bool) (uint32, error) {
+
+	b.buffer = b.appendChecksumInput(b.buffer[:0], literals)
+
+	// the default hash belongs to the block, the others can be shared with other blocks
+	if b.hash != hash.Hash(b.ownedHash) {
+		lock := hashLock(b.hash)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	b.hash.Reset()
+	_, err := b.hash.Write(b.buffer)
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate hash to block %s: %w", b, err)
+	}
+
+	b.buffer = b.hash.Sum(b.buffer[:0])
+	if len(b.buffer) > 4 {
+		return 0, fmt.Errorf("failed to calculate hash to block %s: the digest of %d bytes exceeds the 4 bytes of the check field", b, len(b.buffer))
+	}
+
+	var value uint32
+	for _, v := range b.buffer {
+		value = value<<8 | uint32(v)
+	}
+
+	return value, nil
+}
+
+// hashLock returns the lock that guards a hash. The hashes that aren't pointers share the first lock.
+func hashLock(h hash.Hash) *sync.Mutex {
+	v := reflect.ValueOf(h)
+	if v.Kind() != reflect.Ptr {
+		return &hashLocks[0]
+	}
+
+	return &hashLocks[v.Pointer()%uintptr(len(hashLocks))]
+}
+
+// appendChecksumInput appends the bytes of the block selected by its checksum input to dst and returns the extended buffer.
+//
+// With the default input, they are the same text returned by String.
+func (b *GcodeBlock) appendChecksumInput(dst []byte, literals bool) []byte {
+	separator := BLOCK_SEPARATOR
+	if b.checksumInput.Compact {
+		separator = ""
+	}
+
+	if b.lineNumber != nil && !b.checksumInput.ExcludeLineNumber {
+		dst = b.appendGcode(dst, b.lineNumber, literals)
+		dst = append(dst, separator...)
+	}
+
+	dst = b.appendGcode(dst, b.command, literals)
+
+	for _, g := range b.parameters {
+		dst = append(dst, separator...)
+		dst = b.appendGcode(dst, g, literals)
+	}
+
+	if b.checksumInput.TrailingSpace {
+		dst = append(dst, BLOCK_SEPARATOR...)
+	}
+
+	return dst
+}
+
+// appendGcode appends the gcode to dst like formatGcode does and returns the extended buffer.
+// If literals is true the gcode is written with the original text of its address, when it is known.
+func (b *GcodeBlock) appendGcode(dst []byte, g gcode.Gcoder, literals bool) []byte {
+	if literals {
+		if lg, ok := g.(gcode.LiteralGcoder); ok && lg.Literal() != "" {
+			dst = append(dst, g.Word())
+			return append(dst, lg.Literal()...)
+		}
+	}
+
+	if ag, ok := g.(gcode.AppendableGcoder); ok {
+		return ag.AppendFormat(dst, b.floatFormat)
+	}
+
+	return append(dst, b.formatGcode(g)...)
+}
+
+// formatGcode returns the gcode exported using the original text of its address or the float format of the block, if the gcode supports it.
+func (b *GcodeBlock) formatGcode(g gcode.Gcoder) string {
+	if b.preserveLiterals {
+		if lg, ok := g.(gcode.LiteralGcoder); ok && lg.Literal() != "" {
+			return string(g.Word()) + lg.Literal()
+		}
+	}
+
+	if fg, ok := g.(gcode.FormattableGcoder); ok {
+		return fg.Format(b.floatFormat)
+	}
+
+	return g.String()
+}
+
+// parseGcode converts a gcode expression using the gcode factory of the block.
+//
+// The configurator defines if the fractional addresses are parsed as float64 instead of float32
+// and if the command gcodes are interned.
+func (b *GcodeBlock) parseGcode(source string, configurator *blockConfigurator) (gcode.Gcoder, error) {
+
+	if configurator.float64Promotion && len(source) > 1 && source[0] != 'N' && source[0] != '*' && !strings.Contains(source, "\"") && strings.Contains(source, ".") {
+		// if it isn't a number, the gcode factory and its address plugins must recognize it
+		if value, err := strconv.ParseFloat(source[1:], 64); err == nil {
+			gc, err := b.gcodeFactory.NewAddressableGcodeFloat64(source[0], value)
+			if err != nil {
+				return nil, err
+			}
+
+			gcodefactory.KeepLiteral(gc, source)
+
+			return gc, nil
+		}
+	}
+
+	if factory, ok := b.gcodeFactory.(*gcodefactory.GcodeFactory); ok && configurator.interning {
+		return factory.ParseInterned(source)
+	}
+
+	return b.gcodeFactory.Parse(source)
+}
+
+// tagsComment returns the tags of the block formatted as a structured comment, sorted by key.
+//
+// Values that contain spaces, quotes or the '=' character are quoted. If the block hasn't tags it returns an empty string.
+func (b *GcodeBlock) tagsComment() string {
+	if len(b.tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(b.tags))
+	for key := range b.tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{TAGS_COMMENT_PREFIX}
+	for _, key := range keys {
+		value := b.tags[key]
+		if value == "" || strings.ContainsAny(value, " \t\"=;") {
+			value = strconv.Quote(value)
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// isTagKeyValid returns an error if the key can't be used as a metadata tag.
+func isTagKeyValid(key string) error {
+	if key == "" {
+		return fmt.Errorf("tag key mustn't be empty")
+	}
+
+	if strings.ContainsAny(key, " \t\r\n=;\"") {
+		return fmt.Errorf("tag key contains invalid chars: %q", key)
+	}
+
+	return nil
+}
 
 // removeDuplicateSpaces remove all space char consecutive two or more times
 func removeDuplicateSpaces(s string) string {
-	rx := regexp.MustCompile(`\s{2,}`)
-	return rx.ReplaceAllString(s, " ")
+	return duplicateSpacesRegex.ReplaceAllString(s, " ")
 }
 
 // removeSpecialChars remove all escape characters
 func removeSpecialChars(s string) string {
-	rx := regexp.MustCompile(`[\n\t\r]`)
-	return rx.ReplaceAllString(s, " ")
+	return specialCharsRegex.ReplaceAllString(s, " ")
 }
 
 // prepareSourceToParse modify a string to can be parsed for the Parse function
@@ -399,14 +914,33 @@ func prepareSourceToParse(s string) string {
 	return s
 }
 
+// normalizeWords converts to uppercase all letters that aren't enclosed in quotes, it returns true if some letter was converted.
+func normalizeWords(s string) (string, bool) {
+	out := []byte(s)
+	quoted := false
+	normalized := false
+
+	for i, c := range out {
+		if c == '"' {
+			quoted = !quoted
+			continue
+		}
+
+		if !quoted && c >= 'a' && c <= 'z' {
+			out[i] = c - 'a' + 'A'
+			normalized = true
+		}
+	}
+
+	return string(out), normalized
+}
+
 type elementTaken struct {
 	taken     string
 	remainder string
 }
 
-func take(source string, regex string) elementTaken {
-
-	r := regexp.MustCompile(regex)
+func take(source string, r *regexp.Regexp) elementTaken {
 
 	match := r.FindIndex([]byte(source))
 	if match == nil {
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"hash"
 
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/internal/gcodefactory"
 	"github.com/mauroalderete/gcode-core/gcode"
 )
 
@@ -19,6 +21,26 @@ type optionalBlockPropertyCallbackable func(*GcodeBlock) error
 // It defines a slice of callbacks that will recive a new block reference that must be configured.
 type blockConfigurator struct {
 	configurationCallbacks []optionalBlockPropertyCallbackable
+
+	// parserPolicies are only used by Parse
+	parserPolicies
+}
+
+// parserPolicies stores the options that only define how Parse reads a line.
+//
+// The block keeps them to parse the blocks derived from it in the same way.
+type parserPolicies struct {
+	// duplicatePolicy defines how to handle the parameters with duplicated words
+	duplicatePolicy block.DuplicatePolicy
+
+	// casePolicy defines how to handle the words written in lowercase
+	casePolicy block.CasePolicy
+
+	// interning defines if the command gcodes are shared between blocks
+	interning bool
+
+	// float64Promotion defines if the fractional addresses are stored as float64
+	float64Promotion bool
 }
 
 // SetGcodeFactory loads the gcodeFactory of the block with the input instance. Doesn't accept nil.
@@ -37,6 +59,87 @@ func (bc *blockConfigurator) SetGcodeFactory(gcodeFactory gcode.GcoderFactory) e
 	return nil
 }
 
+// SetChecksumInput defines which bytes of the block are fed to the hash to calculate the checksum.
+// If this method isn't called when a new block is created, by default is the zero value of block.ChecksumInput.
+func (bc *blockConfigurator) SetChecksumInput(input block.ChecksumInput) error {
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		gb.checksumInput = input
+		return nil
+	})
+
+	return nil
+}
+
+// SetFloatFormat loads the format used to export the fractional addresses of the block. Doesn't accept an invalid format.
+// If this method isn't called when a new block is created, by default will use gcode.DefaultFloatFormat.
+func (bc *blockConfigurator) SetFloatFormat(format gcode.FloatFormat) error {
+
+	if err := format.Validate(); err != nil {
+		return fmt.Errorf("failed set float format: %w", err)
+	}
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		gb.floatFormat = format
+		return nil
+	})
+
+	return nil
+}
+
+// SetPreserveLiterals defines if the block exports the original text of the addresses, like X0010.50, instead of format them.
+// If this method isn't called when a new block is created, by default the addresses are formatted.
+func (bc *blockConfigurator) SetPreserveLiterals(preserve bool) error {
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		gb.preserveLiterals = preserve
+		return nil
+	})
+
+	return nil
+}
+
+// SetWordRegistry loads the default gcode factory configured to accept only the words of the registry. Doesn't accept nil.
+// It allows vendor-specific or lowercase words, or restricts the words to the ones supported by a machine.
+//
+// It replaces the gcode factory of the block, so it mustn't be combined with SetGcodeFactory.
+// If this method isn't called when a new block is created, the words are validated with gcode.IsValidWord.
+func (bc *blockConfigurator) SetWordRegistry(registry *gcode.WordRegistry) error {
+
+	if registry == nil {
+		return fmt.Errorf("failed set word registry, it mustn't be nil")
+	}
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		gb.gcodeFactory = gcodefactory.New(registry)
+		return nil
+	})
+
+	return nil
+}
+
+// SetAddressPlugin registers a plugin in the gcode factory of the block to parse a custom address syntax. Doesn't accept nil.
+// It must be called after SetGcodeFactory or SetWordRegistry, because they replace the gcode factory.
+//
+// The plugin is registered by name, so setting it many times has the same effect that setting it once.
+func (bc *blockConfigurator) SetAddressPlugin(plugin gcode.AddressPlugin) error {
+
+	if plugin == nil {
+		return fmt.Errorf("failed set address plugin, it mustn't be nil")
+	}
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		// the default gcode factory is shared by the blocks, so the plugin is registered in a new one
+		if gb.gcodeFactory == gcode.GcoderFactory(defaultGcodeFactory) {
+			gb.gcodeFactory = &gcodefactory.GcodeFactory{}
+		}
+
+		return gb.gcodeFactory.RegisterAddressPlugin(plugin)
+	})
+
+	return nil
+}
+
 // SetLineNumber loads the linenumber gcode of the block with the input instance. Doesn't accept nil.
 // If this method isn't called when a new block is created, by default will to be nil.
 func (bc *blockConfigurator) SetLineNumber(lineNumber gcode.AddressableGcoder[uint32]) error {
@@ -88,7 +191,8 @@ func (bc *blockConfigurator) SetChecksum(checksum gcode.AddressableGcoder[uint32
 
 // SetHash loads the an hash algoritgh instance of the block with the input instanced. Doesn't accept nil.
 // It require that the gcodeFactory is loaded in the block previously.
-// If this method isn't called when a new block is created, by default will store a instance of Hash from the checksum package.
+// The same instance can be set to many blocks, they never use it concurrently to calculate their checksums.
+// If this method isn't called when a new block is created, by default will store a checksum.XOR instance, the RepRap protocol checksum.
 func (bc *blockConfigurator) SetHash(hash hash.Hash) error {
 
 	if hash == nil {
@@ -117,3 +221,80 @@ func (bc *blockConfigurator) SetComment(comment string) error {
 
 	return nil
 }
+
+// SetTags stores the user-defined metadata tags of the block. Doesn't accept nil, but it accepts an empty map.
+// Each key mustn't be empty and can't contain spaces or the '=' character.
+// If this method isn't called when a new block is created, by default the block hasn't tags.
+func (bc *blockConfigurator) SetTags(tags map[string]string) error {
+
+	if tags == nil {
+		return fmt.Errorf("failed set tags at block, it mustn't be nil")
+	}
+
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		if err := isTagKeyValid(key); err != nil {
+			return fmt.Errorf("failed set tags at block: %w", err)
+		}
+		copied[key] = value
+	}
+
+	bc.configurationCallbacks = append(bc.configurationCallbacks, func(gb *GcodeBlock) error {
+		gb.tags = copied
+		return nil
+	})
+
+	return nil
+}
+
+// SetDuplicatePolicy defines how Parse handles the parameters that repeat a word.
+// If this method isn't called when a new block is parsed, by default is block.DuplicateWarn.
+func (bc *blockConfigurator) SetDuplicatePolicy(policy block.DuplicatePolicy) error {
+
+	switch policy {
+	case block.DuplicateWarn, block.DuplicateError, block.DuplicateAllow:
+	default:
+		return fmt.Errorf("failed set duplicate policy, unknown value %d", policy)
+	}
+
+	bc.duplicatePolicy = policy
+
+	return nil
+}
+
+// SetFloat64Promotion defines if Parse stores the fractional addresses as float64 instead of float32.
+// It avoids losing precision with large coordinates or micron resolution, and works with any gcode factory.
+// If this method isn't called when a new block is parsed, by default the fractional addresses are float32.
+func (bc *blockConfigurator) SetFloat64Promotion(promote bool) error {
+
+	bc.float64Promotion = promote
+
+	return nil
+}
+
+// SetCasePolicy defines how Parse handles the words written in lowercase, like "g1 x10".
+// If this method isn't called when a new block is parsed, by default the policy is block.CaseReject.
+func (bc *blockConfigurator) SetCasePolicy(policy block.CasePolicy) error {
+
+	switch policy {
+	case block.CaseReject, block.CaseNormalize:
+	default:
+		return fmt.Errorf("failed set case policy, unknown policy %d", policy)
+	}
+
+	bc.casePolicy = policy
+
+	return nil
+}
+
+// SetInterning defines if Parse shares immutable instances of the command gcodes, like G1 or M104, between all blocks.
+// It cuts the allocations when large sources are parsed, but the commands can't be modified in place, they must be replaced.
+//
+// It only works with the default gcode factory, a custom factory set with SetGcodeFactory is used as is.
+// If this method isn't called when a new block is parsed, by default each block has its own command instance.
+func (bc *blockConfigurator) SetInterning(enabled bool) error {
+
+	bc.interning = enabled
+
+	return nil
+}
@@ -0,0 +1,72 @@
+// This file defines a Pool struct as an implementation of block.Pool interface
+// that recycles GcodeBlock instances using a sync.Pool.
+package gcodeblock
+
+import (
+	"sync"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+// Pool recycles GcodeBlock instances and their parameter slices. It is safe for concurrent use.
+//
+// Read the aliasing rules described in block.Pool before using it.
+type Pool struct {
+	pool sync.Pool
+}
+
+// Parse returns a GcodeBlock, possibly recycled, with the content of a single block line.
+//
+// It accepts the same options than the Parse function. If the line is invalid the block is recycled and an error is returned.
+func (p *Pool) Parse(source string, options ...block.BlockParserConfigurationCallbackable) (block.Blocker, error) {
+
+	b, ok := p.pool.Get().(*GcodeBlock)
+	if !ok {
+		b = &GcodeBlock{}
+	}
+	b.pool = p
+
+	if err := b.parse(source, options...); err != nil {
+		p.Release(b)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Release clears the block and returns it to the pool.
+//
+// Blocks that weren't obtained from this pool, or that were already released, are ignored.
+func (p *Pool) Release(b block.Blocker) {
+
+	gb, ok := b.(*GcodeBlock)
+	if !ok || gb == nil || gb.pool != p {
+		return
+	}
+
+	gb.reset()
+	p.pool.Put(gb)
+}
+
+// NewPool returns a new empty Pool.
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// reset clears all elements of the block, keeping the capacity of the parameters slice and of the scratch buffer, and the default hash.
+func (b *GcodeBlock) reset() {
+	for i := range b.parameters {
+		b.parameters[i] = nil
+	}
+
+	for key := range b.tags {
+		delete(b.tags, key)
+	}
+
+	*b = GcodeBlock{
+		parameters: b.parameters[:0],
+		tags:       b.tags,
+		buffer:     b.buffer[:0],
+		ownedHash:  b.ownedHash,
+	}
+}
@@ -0,0 +1,142 @@
+package gcodeblock
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+func TestPool(t *testing.T) {
+
+	t.Run("parse and release", func(t *testing.T) {
+		p := NewPool()
+
+		b, err := p.Parse("N4 G1 X1.0 Y2.0 E3.0*67;comment")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if b.ToLine("%l %c %p%k %m") != "N4 G1 X1.0 Y2.0 E3.0*67 ;comment" {
+			t.Errorf("got %s, want N4 G1 X1.0 Y2.0 E3.0*67 ;comment", b.ToLine("%l %c %p%k %m"))
+		}
+
+		gb := b.(*GcodeBlock)
+
+		if err := gb.SetTag("layer", "1"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+
+		capacity := cap(gb.parameters)
+
+		p.Release(b)
+
+		if gb.command != nil || gb.lineNumber != nil || gb.checksum != nil || gb.comment != "" || len(gb.tags) != 0 || gb.pool != nil {
+			t.Errorf("got block not cleared after release")
+		}
+
+		if len(gb.parameters) != 0 || cap(gb.parameters) != capacity {
+			t.Errorf("got parameters len %d cap %d, want len 0 cap %d", len(gb.parameters), cap(gb.parameters), capacity)
+		}
+
+		// a recycled block must contain only the new line
+		if err := gb.parse("G28"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if gb.ToLine("%l %c %p%k %m %t") != "G28" {
+			t.Errorf("got %s, want G28", gb.ToLine("%l %c %p%k %m %t"))
+		}
+	})
+
+	t.Run("reuse the default hash", func(t *testing.T) {
+		p := NewPool()
+
+		b, err := p.Parse("G1 X1", func(config block.BlockParserConfigurer) error {
+			return config.SetAddressPlugin(bracketPlugin{})
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		gb := b.(*GcodeBlock)
+		xor := gb.hash
+		p.Release(b)
+
+		if err := gb.parse("N1 G28*18"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if gb.hash != xor {
+			t.Errorf("got a new hash, want the default hash reused")
+		}
+
+		if ok, err := gb.VerifyChecksum(); err != nil || !ok {
+			t.Errorf("got error %v verified %v, want error nil verified true", err, ok)
+		}
+
+		// the plugin was registered in a factory of the block, not in the default one
+		gb.reset()
+		if err := gb.parse("G1 X[#1+2]"); err == nil {
+			t.Errorf("got error nil, want the plugin not registered in the default factory")
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		p := NewPool()
+
+		b, err := p.Parse("G 1")
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+
+		if b != nil {
+			t.Errorf("got block %v, want nil", b)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		p := NewPool()
+
+		_, err := p.Parse("G1 X1 X2", func(config block.BlockParserConfigurer) error {
+			return config.SetDuplicatePolicy(block.DuplicateError)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("release foreign or released blocks", func(t *testing.T) {
+		p := NewPool()
+		other := NewPool()
+
+		foreign, err := Parse("G1 X1")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		p.Release(foreign)
+		if foreign.Command() == nil {
+			t.Errorf("got foreign block cleared, want block untouched")
+		}
+
+		b, err := other.Parse("G1 X1")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		p.Release(b)
+		if b.Command() == nil {
+			t.Errorf("got block of other pool cleared, want block untouched")
+		}
+
+		other.Release(b)
+		other.Release(b)
+		p.Release(nil)
+	})
+}
@@ -1,8 +1,11 @@
 package gcodeblock
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"hash"
+	"strings"
 	"testing"
 
 	"github.com/mauroalderete/gcode-core/block"
@@ -713,6 +716,141 @@ func TestGcodeblock_Verify(t *testing.T) {
 	}
 }
 
+func TestGcodeblock_VerifyFirmwareLines(t *testing.T) {
+
+	// lines with the checksum computed by the hosts over the bytes sent to the firmware
+	cases := map[string]struct {
+		line  string
+		valid bool
+	}{
+		"reset line number":   {"N0 M110 N0*125", true},
+		"report temperature":  {"N1 M105*38", true},
+		"move":                {"N8 G1 X3.0 Y3.0 Z3.0 F3000.0*13", true},
+		"trailing zeros":      {"N10 G1 X10.500 Y5.00 F1500*123", true},
+		"leading zeros":       {"N11 G1 X0010.50*74", true},
+		"padded command":      {"N12 G01 X1.20*78", true},
+		"corrupted":           {"N12 G01 X1.21*78", false},
+		"formatted corrupted": {"N10 G1 X10.5 Y5.0 F1500*123", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.line)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			ok, err := b.VerifyChecksum()
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if ok != tc.valid {
+				t.Errorf("got %v, want %v", ok, tc.valid)
+			}
+
+			if b.PreserveLiterals() {
+				t.Errorf("got preserve literals true, want the block unchanged")
+			}
+		})
+	}
+
+	t.Run("checksum updated", func(t *testing.T) {
+		b, err := Parse("N11 G1 X0010.50*74")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		b.SetLineNumber(nil)
+		if err := b.UpdateChecksum(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		// the line exported is G1 X10.5 with the new checksum, the original text of the address isn't sent anymore
+		if ok, err := b.VerifyChecksum(); err != nil || !ok {
+			t.Errorf("got %v, %v, want true, nil", ok, err)
+		}
+
+		b.SetChecksum(b.Checksum())
+		if ok, err := b.VerifyChecksum(); err != nil || ok {
+			t.Errorf("got %v, %v with the checksum set, want false, nil", ok, err)
+		}
+	})
+}
+
+func TestGcodeblock_WideChecksum(t *testing.T) {
+
+	cases := map[string]struct {
+		line  string
+		hash  func() hash.Hash
+		valid bool
+	}{
+		"crc16":            {"N7 G1 X2.0 Y2.0 F3000.0*17307", func() hash.Hash { return checksum.NewCRC16() }, true},
+		"crc16 corrupted":  {"N7 G1 X2.0 Y2.0 F3000.0*17308", func() hash.Hash { return checksum.NewCRC16() }, false},
+		"crc32":            {"N7 G1 X2.0 Y2.0 F3000.0*798157868", func() hash.Hash { return checksum.NewCRC32() }, true},
+		"crc32 over int32": {"N8 G1 X3.0*2238163462", func() hash.Hash { return checksum.NewCRC32() }, true},
+		"crc32 corrupted":  {"N8 G1 X3.0*2238163461", func() hash.Hash { return checksum.NewCRC32() }, false},
+		"mod256":           {"N7 G1 X2.0 Y2.0 F3000.0*181", func() hash.Hash { return checksum.NewMod256() }, true},
+		"fletcher16":       {"N7 G1 X2.0 Y2.0 F3000.0*42425", func() hash.Hash { return checksum.NewFletcher16() }, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.line, func(config block.BlockParserConfigurer) error {
+				return config.SetHash(tc.hash())
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			ok, err := b.VerifyChecksum()
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if ok != tc.valid {
+				t.Errorf("got %v, want %v", ok, tc.valid)
+			}
+
+			if err := b.UpdateChecksum(); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			exported := b.ToLine("%l %c %p%k")
+			if tc.valid && exported != tc.line {
+				t.Errorf("got %s, want %s", exported, tc.line)
+			}
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		if _, err := Parse("N2 M115*4294967296"); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("digest too wide", func(t *testing.T) {
+		b, err := Parse("G28", func(config block.BlockParserConfigurer) error {
+			return config.SetHash(sha256.New())
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, err := b.CalculateChecksum(); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
 func TestGcodeblock_ToLineError(t *testing.T) {
 
 	mockCommand, err := addressablegcode.New[int32]('G', 92)
@@ -742,3 +880,863 @@ func TestGcodeblock_ToLineError(t *testing.T) {
 		}
 	})
 }
+
+func TestGcodeblock_Tags(t *testing.T) {
+
+	mockCommand, err := addressablegcode.New[int32]('G', 1)
+	if err != nil {
+		t.Errorf("got error not nil, want error nil: %v", err)
+	}
+
+	t.Run("configured tags", func(t *testing.T) {
+		tags := map[string]string{"layer": "12", "object": "part A"}
+
+		b, err := New(mockCommand, func(config block.BlockConstructorConfigurer) error {
+			return config.SetTags(tags)
+		})
+		if err != nil {
+			t.Errorf("got %v, want nil error", err)
+			return
+		}
+
+		// the block must keep its own copy
+		tags["layer"] = "13"
+
+		if value, ok := b.Tag("layer"); !ok || value != "12" {
+			t.Errorf("got tag layer (%v)%s, want tag layer (true)12", ok, value)
+		}
+
+		if line := b.ToLine("%c %t"); line != "G1 ;@meta layer=12 object=\"part A\"" {
+			t.Errorf("got %s, want G1 ;@meta layer=12 object=\"part A\"", line)
+		}
+	})
+
+	t.Run("invalid configured tags", func(t *testing.T) {
+		_, err := New(mockCommand, func(config block.BlockConstructorConfigurer) error {
+			return config.SetTags(map[string]string{"bad key": "1"})
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+
+		_, err = New(mockCommand, func(config block.BlockConstructorConfigurer) error {
+			return config.SetTags(nil)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("set and remove", func(t *testing.T) {
+		b, err := Parse("G1 X1.0;travel")
+		if err != nil {
+			t.Errorf("got %v, want nil error", err)
+			return
+		}
+
+		if line := b.ToLine("%c %p%k %m %t"); line != "G1 X1.0 ;travel" {
+			t.Errorf("got %s, want G1 X1.0 ;travel", line)
+		}
+
+		for _, key := range []string{"", "a=b", "a b", "a;b"} {
+			if err := b.SetTag(key, "1"); err == nil {
+				t.Errorf("got error nil with key %q, want error not nil", key)
+			}
+		}
+
+		if err := b.SetTag("generated-by", "retraction-fixer"); err != nil {
+			t.Errorf("got %v, want nil error", err)
+		}
+
+		tags := b.Tags()
+		tags["generated-by"] = "other"
+		if value, _ := b.Tag("generated-by"); value != "retraction-fixer" {
+			t.Errorf("got tag %s, want tag retraction-fixer", value)
+		}
+
+		if line := b.ToLine("%c %p%k %m %t"); line != "G1 X1.0 ;travel ;@meta generated-by=retraction-fixer" {
+			t.Errorf("got %s, want G1 X1.0 ;travel ;@meta generated-by=retraction-fixer", line)
+		}
+
+		b.RemoveTag("generated-by")
+		if _, ok := b.Tag("generated-by"); ok {
+			t.Errorf("got tag generated-by, want tag removed")
+		}
+	})
+}
+
+func TestParse_DuplicatedWords(t *testing.T) {
+
+	cases := map[string]struct {
+		source      string
+		policy      block.DuplicatePolicy
+		valid       bool
+		duplicated  string
+		diagnostics int
+	}{
+		"no duplicated":            {"G1 X5 Y7", block.DuplicateWarn, true, "", 0},
+		"duplicated warn":          {"G1 X5 X7", block.DuplicateWarn, true, "X", 1},
+		"duplicated many warn":     {"G1 X5 Y1 X7 Y2 X8", block.DuplicateWarn, true, "XY", 1},
+		"duplicated error":         {"G1 X5 X7", block.DuplicateError, false, "", 0},
+		"duplicated allow":         {"G1 X5 X7", block.DuplicateAllow, true, "X", 0},
+		"multiple g words allowed": {"G90 G21 G1 X5", block.DuplicateError, true, "", 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				return config.SetDuplicatePolicy(tc.policy)
+			})
+
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if string(b.DuplicatedWords()) != tc.duplicated {
+				t.Errorf("got duplicated words %s, want %s", string(b.DuplicatedWords()), tc.duplicated)
+			}
+
+			if len(b.Diagnostics()) != tc.diagnostics {
+				t.Errorf("got %d diagnostics, want %d", len(b.Diagnostics()), tc.diagnostics)
+				return
+			}
+
+			if tc.diagnostics > 0 && b.Diagnostics()[0].Severity != block.SeverityWarning {
+				t.Errorf("got diagnostic %s, want a warning", b.Diagnostics()[0])
+			}
+
+			if b.ToLine("%c %p") != tc.source {
+				t.Errorf("got %s, want all parameters kept %s", b.ToLine("%c %p"), tc.source)
+			}
+		})
+	}
+
+	t.Run("unknown policy", func(t *testing.T) {
+		_, err := Parse("G1 X5", func(config block.BlockParserConfigurer) error {
+			return config.SetDuplicatePolicy(block.DuplicatePolicy(99))
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestParse_Float64Promotion(t *testing.T) {
+
+	cases := map[string]struct {
+		source  string
+		promote bool
+		want    string
+	}{
+		"float32 by default": {"G1 X123456.789012 Y0.001", false, "G1 X123456.79 Y0.001"},
+		"float64 promoted":   {"G1 X123456.789012 Y0.001", true, "G1 X123456.789012 Y0.001"},
+		"integers kept":      {"G1 X10 F1500", true, "G1 X10 F1500"},
+		"strings kept":       {"M23 P\"file.1.gcode\"", true, "M23 P\"file.1.gcode\""},
+		"line number kept":   {"N10 G1 X1.25", true, "N10 G1 X1.25"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				return config.SetFloat64Promotion(tc.promote)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := b.ToLine("%l %c %p"); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGcodeblock_FloatFormat(t *testing.T) {
+
+	const source = "N7 G1 X2.0 Y0.125 E1.5 F3000.0"
+
+	cases := map[string]struct {
+		format gcode.FloatFormat
+		valid  bool
+		want   string
+	}{
+		"default":      {gcode.DefaultFloatFormat(), true, "N7 G1 X2.0 Y0.125 E1.5 F3000.0"},
+		"shortest":     {gcode.FloatFormat{Precision: -1}, true, "N7 G1 X2 Y0.125 E1.5 F3000"},
+		"fixed":        {gcode.FloatFormat{Precision: 3}, true, "N7 G1 X2.000 Y0.125 E1.500 F3000.000"},
+		"rounded":      {gcode.FloatFormat{Precision: 1, TrimZeros: true}, true, "N7 G1 X2 Y0.1 E1.5 F3000"},
+		"out of range": {gcode.FloatFormat{Precision: gcode.MAX_FLOAT_PRECISION + 1}, false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(source, func(config block.BlockParserConfigurer) error {
+				return config.SetFloatFormat(tc.format)
+			})
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := b.String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("change the format after parse", func(t *testing.T) {
+		b, err := Parse("N7 G1 X2.0 Y0.125")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		before, err := b.CalculateChecksum()
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := b.SetFloatFormat(gcode.FloatFormat{Precision: -1}); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if b.String() != "N7 G1 X2 Y0.125" {
+			t.Errorf("got %s, want N7 G1 X2 Y0.125", b)
+		}
+
+		after, err := b.CalculateChecksum()
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if before.Compare(after) {
+			t.Errorf("got the same checksum %s, want it calculated over the line exported", after)
+		}
+
+		if err := b.SetFloatFormat(gcode.FloatFormat{Precision: gcode.MAX_FLOAT_PRECISION + 1}); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestGcodeblock_PreserveLiterals(t *testing.T) {
+
+	cases := map[string]struct {
+		source   string
+		promote  bool
+		preserve bool
+		want     string
+	}{
+		"formatted":         {"N007 G01 X0010.50 Y-0.0 F1500*12 ;keep", false, false, "N7 G1 X10.5 Y-0.0 F1500*12 ;keep"},
+		"preserved":         {"N007 G01 X0010.50 Y-0.0 F1500*12 ;keep", false, true, "N007 G01 X0010.50 Y-0.0 F1500*12 ;keep"},
+		"preserved float64": {"G1 X123456.789012000", true, true, "G1 X123456.789012000"},
+		"string preserved":  {"M23 P\"file.gcode\"", false, true, "M23 P\"file.gcode\""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				if err := config.SetFloat64Promotion(tc.promote); err != nil {
+					return err
+				}
+				return config.SetPreserveLiterals(tc.preserve)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := b.ToLine("%l %c %p%k %m"); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("checksum over the original text", func(t *testing.T) {
+		const source = "N007 G01 X0010.50"
+
+		b, err := Parse(source)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		b.SetPreserveLiterals(true)
+
+		checksum, err := b.CalculateChecksum()
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		var want byte
+		for _, c := range []byte(source) {
+			want ^= c
+		}
+
+		if checksum.Address() != uint32(want) {
+			t.Errorf("got checksum %d, want %d", checksum.Address(), want)
+		}
+	})
+
+	t.Run("modified parameter is formatted", func(t *testing.T) {
+		b, err := Parse("G1 X0010.50 Y02.0", func(config block.BlockParserConfigurer) error {
+			return config.SetPreserveLiterals(true)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		x, ok := b.Parameters()[0].(gcode.AddressableGcoder[float32])
+		if !ok {
+			t.Errorf("got parameter %T, want float32 addressable gcode", b.Parameters()[0])
+			return
+		}
+
+		if err := x.SetAddress(11); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if b.String() != "G1 X11.0 Y02.0" {
+			t.Errorf("got %s, want G1 X11.0 Y02.0", b)
+		}
+	})
+}
+
+func TestParse_WordRegistry(t *testing.T) {
+
+	lowercase := gcode.DefaultWordRegistry()
+	if err := lowercase.Allow('g', 'x', 'y'); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	machine, err := gcode.NewWordRegistry('G', 'M', 'X', 'Y', 'F', 'N', '*')
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	cases := map[string]struct {
+		source   string
+		registry *gcode.WordRegistry
+		valid    bool
+	}{
+		"lowercase default":   {"g1 x10 y5", nil, false},
+		"lowercase allowed":   {"g1 x10 y5", lowercase, true},
+		"mixed allowed":       {"N3 g1 X10 y5*45", lowercase, true},
+		"machine supported":   {"N3 G1 X10 Y5 F1500", machine, true},
+		"machine unsupported": {"G1 X10 Z5", machine, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				if tc.registry == nil {
+					return nil
+				}
+				return config.SetWordRegistry(tc.registry)
+			})
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if b.ToLine("%l %c %p%k") != tc.source {
+				t.Errorf("got %s, want %s", b.ToLine("%l %c %p%k"), tc.source)
+			}
+		})
+	}
+
+	t.Run("nil registry", func(t *testing.T) {
+		_, err := Parse("G1", func(config block.BlockParserConfigurer) error {
+			return config.SetWordRegistry(nil)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestParse_CasePolicy(t *testing.T) {
+
+	cases := map[string]struct {
+		source     string
+		policy     block.CasePolicy
+		valid      bool
+		want       string
+		normalized bool
+	}{
+		"uppercase rejected policy": {"G1 X10", block.CaseReject, true, "G1 X10", false},
+		"lowercase rejected":        {"g1 x10", block.CaseReject, false, "", false},
+		"lowercase normalized":      {"g1 x10 y2.5", block.CaseNormalize, true, "G1 X10 Y2.5", true},
+		"uppercase not normalized":  {"G1 X10", block.CaseNormalize, true, "G1 X10", false},
+		"line number and checksum":  {"n3 g1 x10*12", block.CaseNormalize, true, "N3 G1 X10*12", true},
+		"string kept":               {"m23 p\"File.gcode\"", block.CaseNormalize, true, "M23 P\"File.gcode\"", true},
+		"comment kept":              {"G1 X1 ;Lower case", block.CaseNormalize, true, "G1 X1 ;Lower case", false},
+		"unknown policy":            {"G1 X10", block.CasePolicy(9), false, "", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				return config.SetCasePolicy(tc.policy)
+			})
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := b.ToLine("%l %c %p%k %m"); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+
+			if b.Normalized() != tc.normalized {
+				t.Errorf("got normalized %v, want %v", b.Normalized(), tc.normalized)
+			}
+		})
+	}
+}
+
+func TestParse_Interning(t *testing.T) {
+
+	parse := func(source string, interning bool) (*GcodeBlock, error) {
+		return Parse(source, func(config block.BlockParserConfigurer) error {
+			return config.SetInterning(interning)
+		})
+	}
+
+	cases := map[string]struct {
+		interning bool
+		shared    bool
+	}{
+		"interning enabled":  {true, true},
+		"interning disabled": {false, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			first, err := parse("G1 X10 Y5", tc.interning)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			second, err := parse("N2 G1 X20", tc.interning)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if (first.Command() == second.Command()) != tc.shared {
+				t.Errorf("got shared command %v, want %v", first.Command() == second.Command(), tc.shared)
+			}
+
+			if first.String() != "G1 X10 Y5" || second.String() != "N2 G1 X20" {
+				t.Errorf("got %s and %s, want G1 X10 Y5 and N2 G1 X20", first, second)
+			}
+		})
+	}
+}
+
+// bracketPlugin parses the bracket expressions, like X[#1+2], as string addresses.
+type bracketPlugin struct{}
+
+func (p bracketPlugin) Name() string { return "bracket" }
+
+func (p bracketPlugin) Match(word byte, address string) bool {
+	return strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]")
+}
+
+func (p bracketPlugin) Parse(word byte, address string) (gcode.Gcoder, error) {
+	return addressablegcode.New(word, fmt.Sprintf("%q", address))
+}
+
+func TestParse_AddressPlugin(t *testing.T) {
+
+	cases := map[string]struct {
+		source  string
+		plugin  bool
+		promote bool
+		valid   bool
+		want    string
+	}{
+		"without plugin":    {"G1 X[#1+2] Y2", false, false, false, ""},
+		"with plugin":       {"G1 X[#1+2] Y2 ;move", true, false, true, "G1 X\"[#1+2]\" Y2 ;move"},
+		"promoted floats":   {"G1 X[#1.5] Y2.5", true, true, true, "G1 X\"[#1.5]\" Y2.5"},
+		"unknown syntax":    {"G1 X{1}", true, false, false, ""},
+		"invalid number":    {"G1 X1.2.3", true, false, false, ""},
+		"plugin not needed": {"N1 G1 X1 Y2*3", true, false, true, "N1 G1 X1 Y2*3"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.source, func(config block.BlockParserConfigurer) error {
+				if err := config.SetFloat64Promotion(tc.promote); err != nil {
+					return err
+				}
+				if !tc.plugin {
+					return nil
+				}
+				return config.SetAddressPlugin(bracketPlugin{})
+			})
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := b.ToLine("%l %c %p%k %m"); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGcodeblock_TextMarshaling(t *testing.T) {
+
+	type job struct {
+		Name  string
+		Block *GcodeBlock
+	}
+
+	t.Run("json round trip", func(t *testing.T) {
+		b, err := Parse("N4 G92 E0*67;reset")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		data, err := json.Marshal(job{Name: "a", Block: b})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if string(data) != `{"Name":"a","Block":"N4 G92 E0*67 ;reset"}` {
+			t.Errorf("got %s, want {\"Name\":\"a\",\"Block\":\"N4 G92 E0*67 ;reset\"}", data)
+		}
+
+		var decoded job
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if d := block.Diff(b, decoded.Block); !d.Equal() {
+			t.Errorf("got difference %+v, want equal blocks", d)
+		}
+	})
+
+	t.Run("comment whitespace", func(t *testing.T) {
+		b, err := Parse("G1 X10 ;move")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if b.Comment() != " ;move" {
+			t.Errorf("got comment %q, want \" ;move\"", b.Comment())
+		}
+
+		data, err := b.MarshalText()
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if string(data) != "G1 X10 ;move" {
+			t.Errorf("got %s, want G1 X10 ;move", data)
+		}
+
+		var decoded GcodeBlock
+		if err := decoded.UnmarshalText(data); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if decoded.Comment() != ";move" {
+			t.Errorf("got comment %q, want \";move\"", decoded.Comment())
+		}
+	})
+
+	t.Run("invalid text", func(t *testing.T) {
+		var decoded job
+		if err := json.Unmarshal([]byte(`{"Block":"G 1"}`), &decoded); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("empty block", func(t *testing.T) {
+		if _, err := (&GcodeBlock{}).MarshalText(); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestGcodeblock_StreamingChecksum(t *testing.T) {
+
+	lines := []string{
+		"G28",
+		"N1 T0",
+		"N7 G1 X2.0 Y2.0 F3000.0",
+		"N12 G1 X0010.50 Y-3.25 E0.01234",
+		"M32 P\"file.gcode\"",
+		"G1 Z.5 F1200 ;lift",
+	}
+
+	hashes := map[string]func() hash.Hash{
+		"xor":   func() hash.Hash { return checksum.NewXOR() },
+		"crc16": func() hash.Hash { return checksum.NewCRC16() },
+		"crc32": func() hash.Hash { return checksum.NewCRC32() },
+	}
+
+	for name, newHash := range hashes {
+		for _, line := range lines {
+			for _, preserve := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s %s preserve %v", name, line, preserve), func(t *testing.T) {
+					b, err := Parse(line, func(config block.BlockParserConfigurer) error {
+						return config.SetHash(newHash())
+					})
+					if err != nil {
+						t.Errorf("got error %v, want error nil", err)
+						return
+					}
+					b.SetPreserveLiterals(preserve)
+
+					// the value computed over the exported string
+					h := newHash()
+					h.Write([]byte(b.String()))
+					var want uint32
+					for _, v := range h.Sum(nil) {
+						want = want<<8 | uint32(v)
+					}
+
+					gc, err := b.CalculateChecksum()
+					if err != nil {
+						t.Errorf("got error %v, want error nil", err)
+						return
+					}
+
+					if gc.Address() != want {
+						t.Errorf("got checksum %d, want checksum %d", gc.Address(), want)
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("verify without allocations", func(t *testing.T) {
+		b, err := Parse("N12 G1 X10.5 Y-3.25 E0.01234 F1200*29")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		ok, err := b.VerifyChecksum()
+		if err != nil || !ok {
+			t.Errorf("got error %v verified %v, want error nil verified true", err, ok)
+			return
+		}
+
+		allocs := testing.AllocsPerRun(100, func() {
+			b.VerifyChecksum()
+		})
+		if allocs != 0 {
+			t.Errorf("got %v allocations, want 0 allocations", allocs)
+		}
+	})
+}
+
+func TestGcodeblock_ChecksumInput(t *testing.T) {
+
+	cases := map[string]struct {
+		input block.ChecksumInput
+		want  uint32
+	}{
+		"default":             {block.ChecksumInput{}, 46},
+		"without line number": {block.ChecksumInput{ExcludeLineNumber: true}, 119},
+		"compact":             {block.ChecksumInput{Compact: true}, 14},
+		"trailing space":      {block.ChecksumInput{TrailingSpace: true}, 14},
+		"all":                 {block.ChecksumInput{ExcludeLineNumber: true, Compact: true, TrailingSpace: true}, 87},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse("N7 G1 X2.0 Y2.0", func(config block.BlockParserConfigurer) error {
+				return config.SetChecksumInput(tc.input)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if b.ChecksumInput() != tc.input {
+				t.Errorf("got checksum input %+v, want checksum input %+v", b.ChecksumInput(), tc.input)
+			}
+
+			gc, err := b.CalculateChecksum()
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if gc.Address() != tc.want {
+				t.Errorf("got checksum value %d, want checksum value %d", gc.Address(), tc.want)
+			}
+
+			if err := b.UpdateChecksum(); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			ok, err := b.VerifyChecksum()
+			if err != nil || !ok {
+				t.Errorf("got error %v verified %v, want error nil verified true", err, ok)
+			}
+
+			// the default input must reject the checksum calculated with other inputs
+			b.SetChecksumInput(block.ChecksumInput{})
+			ok, err = b.VerifyChecksum()
+			if err != nil || ok != (tc.want == 46) {
+				t.Errorf("got error %v verified %v, want error nil verified %v", err, ok, tc.want == 46)
+			}
+		})
+	}
+
+	t.Run("checksum parsed", func(t *testing.T) {
+		b, err := Parse("N7 G1 X2.0 Y2.0*14", func(config block.BlockParserConfigurer) error {
+			return config.SetChecksumInput(block.ChecksumInput{TrailingSpace: true})
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		ok, err := b.VerifyChecksum()
+		if err != nil || !ok {
+			t.Errorf("got error %v verified %v, want error nil verified true", err, ok)
+		}
+	})
+}
+
+func TestGcodeblock_Derive(t *testing.T) {
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K'); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	b, err := Parse("N10 G2 X10.0 I5 K2 ;arc", func(config block.BlockParserConfigurer) error {
+		if err := config.SetWordRegistry(registry); err != nil {
+			return err
+		}
+		if err := config.SetHash(checksum.NewCRC16()); err != nil {
+			return err
+		}
+		if err := config.SetChecksumInput(block.ChecksumInput{Compact: true}); err != nil {
+			return err
+		}
+		if err := config.SetPreserveLiterals(true); err != nil {
+			return err
+		}
+		return config.SetFloat64Promotion(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	if err := b.SetTag("layer", "3"); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	derived, err := b.Derive("N10 G2 X10.0 I5 K2 F1200.125000001 ;arc")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if err := derived.UpdateChecksum(); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	// the checksum calculated with the hash and the checksum input of the original block, over the literals
+	crc := checksum.NewCRC16()
+	crc.Write([]byte("N10G2X10.0I5K2F1200.125000001"))
+
+	if derived.Checksum().Address() != uint32(crc.Sum16()) {
+		t.Errorf("got checksum %d, want checksum %d", derived.Checksum().Address(), crc.Sum16())
+	}
+
+	if line := derived.ToLine("%l %c %p %m %t"); line != "N10 G2 X10.0 I5 K2 F1200.125000001 ;arc ;@meta layer=3" {
+		t.Errorf("got %s, want %s", line, "N10 G2 X10.0 I5 K2 F1200.125000001 ;arc ;@meta layer=3")
+	}
+
+	if derived.ChecksumInput() != b.ChecksumInput() || !derived.PreserveLiterals() {
+		t.Errorf("got checksum input %+v preserve literals %v, want %+v true", derived.ChecksumInput(), derived.PreserveLiterals(), b.ChecksumInput())
+	}
+
+	if _, err := b.Derive("G1 L2"); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+func TestGcodeblock_Capabilities(t *testing.T) {
+	gb, err := Parse("G1 X1")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	var b block.Blocker = gb
+
+	capabilities := map[string]bool{}
+	_, capabilities["editor"] = b.(block.Editor)
+	_, capabilities["tagger"] = b.(block.Tagger)
+	_, capabilities["diagnoser"] = b.(block.Diagnoser)
+	_, capabilities["formatter"] = b.(block.Formatter)
+	_, capabilities["checksum inputer"] = b.(block.ChecksumInputer)
+
+	for name, ok := range capabilities {
+		if !ok {
+			t.Errorf("got block without the capability %s, want it implemented", name)
+		}
+	}
+}
@@ -13,21 +13,52 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mauroalderete/gcode-core/gcode"
 	"github.com/mauroalderete/gcode-core/gcode/addressablegcode"
 	"github.com/mauroalderete/gcode-core/gcode/unaddressablegcode"
 )
 
-type GcodeFactory struct{}
+// MAX_INTERNED_GCODES is the maximum number of gcodes stored in the interning cache.
+//
+// When the cache is full the new gcodes aren't interned, so the memory used is bounded for any source.
+const MAX_INTERNED_GCODES = 4096
+
+// interned is the cache of shared command gcodes, indexed by their source expression. It is shared by all factories.
+var interned = struct {
+	sync.RWMutex
+	gcodes map[string]gcode.Gcoder
+}{
+	gcodes: make(map[string]gcode.Gcoder),
+}
+
+// GcodeFactory implements gcode.GcoderFactory using the addressablegcode and unaddressablegcode packages.
+//
+// The zero value validates the words with gcode.IsValidWord.
+type GcodeFactory struct {
+	// registry defines the valid words, it can be nil
+	registry *gcode.WordRegistry
+
+	// plugins parse the custom address syntax, in order of registration
+	plugins []gcode.AddressPlugin
+}
+
+// New returns a GcodeFactory that validates the words with the registry.
+// If the registry is nil the words are validated with gcode.IsValidWord.
+func New(registry *gcode.WordRegistry) *GcodeFactory {
+	return &GcodeFactory{
+		registry: registry,
+	}
+}
 
 // NewUnaddressableGcode is the constructor to instance a unaddressablegcode.Gcode struct.
 //
 // word represents the letter of the gcode command.
 //
-// If the word is an unknown symbol it returns nil with an error description.
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
 func (g *GcodeFactory) NewUnaddressableGcode(word byte) (gcode.Gcoder, error) {
-	ng, err := unaddressablegcode.New(word)
+	ng, err := unaddressablegcode.NewWithRegistry(word, g.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +71,10 @@ func (g *GcodeFactory) NewUnaddressableGcode(word byte) (gcode.Gcoder, error) {
 // word represents the letter of the gcode command.
 // address is the value of the gcode command.
 //
-// If the word is an unknown symbol it returns nil with an error description.
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
 func (g *GcodeFactory) NewAddressableGcodeUint32(word byte, address uint32) (gcode.AddressableGcoder[uint32], error) {
 
-	ng, err := addressablegcode.New(word, address)
+	ng, err := addressablegcode.NewWithRegistry(word, address, g.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -56,10 +87,10 @@ func (g *GcodeFactory) NewAddressableGcodeUint32(word byte, address uint32) (gco
 // word represents the letter of the gcode command.
 // address is the value of the gcode command.
 //
-// If the word is an unknown symbol it returns nil with an error description.
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
 func (g *GcodeFactory) NewAddressableGcodeInt32(word byte, address int32) (gcode.AddressableGcoder[int32], error) {
 
-	ng, err := addressablegcode.New(word, address)
+	ng, err := addressablegcode.NewWithRegistry(word, address, g.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -72,10 +103,26 @@ func (g *GcodeFactory) NewAddressableGcodeInt32(word byte, address int32) (gcode
 // word represents the letter of the gcode command.
 // address is the value of the gcode command.
 //
-// If the word is an unknown symbol it returns nil with an error description.
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
 func (g *GcodeFactory) NewAddressableGcodeFloat32(word byte, address float32) (gcode.AddressableGcoder[float32], error) {
 
-	ng, err := addressablegcode.New(word, address)
+	ng, err := addressablegcode.NewWithRegistry(word, address, g.registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return ng, nil
+}
+
+// NewAddressableGcodeFloat64 is the constructor to instance a addressablegcode.Gcode[float64] struct.
+//
+// word represents the letter of the gcode command.
+// address is the value of the gcode command.
+//
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
+func (g *GcodeFactory) NewAddressableGcodeFloat64(word byte, address float64) (gcode.AddressableGcoder[float64], error) {
+
+	ng, err := addressablegcode.NewWithRegistry(word, address, g.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -88,10 +135,10 @@ func (g *GcodeFactory) NewAddressableGcodeFloat32(word byte, address float32) (g
 // word represents the letter of the gcode command.
 // address is the value of the gcode command.
 //
-// If the word is an unknown symbol it returns nil with an error description.
+// If the word isn't accepted by the registry of the factory it returns nil with an error description.
 func (g *GcodeFactory) NewAddressableGcodeString(word byte, address string) (gcode.AddressableGcoder[string], error) {
 
-	ng, err := addressablegcode.New(word, address)
+	ng, err := addressablegcode.NewWithRegistry(word, address, g.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -99,12 +146,140 @@ func (g *GcodeFactory) NewAddressableGcodeString(word byte, address string) (gco
 	return ng, nil
 }
 
+// RegisterAddressPlugin stores a plugin to parse a custom address syntax. Doesn't accept nil.
+//
+// The plugins are consulted in order of registration. If other plugin with the same name exists it is replaced, keeping its order.
+func (g *GcodeFactory) RegisterAddressPlugin(plugin gcode.AddressPlugin) error {
+
+	if plugin == nil {
+		return fmt.Errorf("failed to register address plugin, it mustn't be nil")
+	}
+
+	for i, p := range g.plugins {
+		if p.Name() == plugin.Name() {
+			g.plugins[i] = plugin
+			return nil
+		}
+	}
+
+	g.plugins = append(g.plugins, plugin)
+
+	return nil
+}
+
 // Parse recives a string expression and tries convert a gcode.Gcoder object.
 // source is a string expression of a gcode valid.
 // if the expression is not recognited then returns an error.
 // The orden to evaluate is N or checksum gcode first, string gcode second, nexto the float gcode and int gcode to end.
+// Before that, the address is offered to the plugins registered, the first one that matches it parses the gcode.
+//
+// The original text of the address is stored in the gcode, so it can be re-emitted exactly.
 func (g *GcodeFactory) Parse(source string) (gcode.Gcoder, error) {
 
+	gc, err := g.parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	KeepLiteral(gc, source)
+
+	return gc, nil
+}
+
+// ParseInterned works like Parse, but the command gcodes (G, M and T words with integer address) are shared.
+//
+// The first time that a command is parsed it is frozen and stored in a cache shared by all factories,
+// the following times the same instance is returned, saving its allocation.
+// The gcodes returned are immutable, so to modify them they must be replaced by a new instance.
+// The words are always validated with the registry of the factory. If the factory has address plugins nothing is interned.
+func (g *GcodeFactory) ParseInterned(source string) (gcode.Gcoder, error) {
+
+	// the plugins could parse the commands in a custom way
+	if len(g.plugins) > 0 || !isInternable(source) {
+		return g.Parse(source)
+	}
+
+	interned.RLock()
+	gc, ok := interned.gcodes[source]
+	interned.RUnlock()
+
+	if ok {
+		if err := g.isValidWord(source[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+		}
+
+		return gc, nil
+	}
+
+	gc, err := g.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fg, ok := gc.(*addressablegcode.Gcode[int32])
+	if !ok {
+		return gc, nil
+	}
+
+	fg.Freeze()
+
+	interned.Lock()
+	defer interned.Unlock()
+
+	// other goroutine could store the same gcode meanwhile
+	if shared, ok := interned.gcodes[source]; ok {
+		return shared, nil
+	}
+
+	if len(interned.gcodes) < MAX_INTERNED_GCODES {
+		interned.gcodes[source] = fg
+	}
+
+	return fg, nil
+}
+
+// isValidWord validates the word with the registry of the factory, or with gcode.IsValidWord if it hasn't.
+func (g *GcodeFactory) isValidWord(word byte) error {
+	if g.registry == nil {
+		return gcode.IsValidWord(word)
+	}
+
+	return g.registry.IsValidWord(word)
+}
+
+// isInternable returns true if the source is a command expression, like G1 or M104, that can be shared.
+func isInternable(source string) bool {
+	if len(source) < 2 {
+		return false
+	}
+
+	switch source[0] {
+	case 'G', 'M', 'T':
+	default:
+		return false
+	}
+
+	for _, c := range source[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// KeepLiteral stores the address of the source expression as the literal of the gcode, if the gcode supports it.
+//
+// The literal is informative, it is discarded if the gcode doesn't accept it.
+func KeepLiteral(gc gcode.Gcoder, source string) {
+	if lg, ok := gc.(gcode.LiteralGcoder); ok && gc.HasAddress() && len(source) > 1 {
+		_ = lg.SetLiteral(source[1:])
+	}
+}
+
+// parse converts a string expression in a gcode.Gcoder object without store the literal.
+func (g *GcodeFactory) parse(source string) (gcode.Gcoder, error) {
+
 	if source == "" {
 		return nil, fmt.Errorf("it is not possible to parse an empty string")
 	}
@@ -123,18 +298,32 @@ func (g *GcodeFactory) Parse(source string) (gcode.Gcoder, error) {
 		return gcode, nil
 	}
 
+	// contains a custom address
+	for _, plugin := range g.plugins {
+		if !plugin.Match(source[0], source[1:]) {
+			continue
+		}
+
+		if err := g.isValidWord(source[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+		}
+
+		gcode, err = plugin.Parse(source[0], source[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s with the address plugin %s: %w", source, plugin.Name(), err)
+		}
+
+		return gcode, nil
+	}
+
 	// contains a linenumber or checksum gcode
 	if source[0] == 'N' || source[0] == '*' {
 
-		val, err := strconv.ParseInt(source[1:], 10, 32)
+		val, err := strconv.ParseUint(source[1:], 10, 32)
 		if err != nil {
 			return nil, fmt.Errorf("failed to try parse uint32 value from %s gcode: %w", source, err)
 		}
 
-		if val < 0 {
-			return nil, fmt.Errorf("failed to try parse %d to uint32 value, it must be positive", val)
-		}
-
 		gcode, err = g.NewAddressableGcodeUint32(source[0], uint32(val))
 		if err != nil {
 			return nil, fmt.Errorf("try generate uint32 gcode from %s: %w", source, err)
@@ -2,7 +2,10 @@ package gcodefactory
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/mauroalderete/gcode-core/gcode"
 )
 
 func TestGcodeFactoryNewGcode(t *testing.T) {
@@ -197,6 +200,278 @@ func TestGcodeAddressableFactoryNewGcodeAddressable(t *testing.T) {
 	})
 }
 
+func TestGcodeAddressableFactoryNewGcodeAddressableFloat64(t *testing.T) {
+	gcodeFactory := &GcodeFactory{}
+
+	cases := map[string]struct {
+		word    byte
+		address float64
+		valid   bool
+		want    string
+	}{
+		"eval_X1":   {'X', 1, true, "X1.0"},
+		"eval_Y2":   {'Y', 123456.789012, true, "Y123456.789012"},
+		"eval_+3":   {'+', 3, false, ""},
+		"eval_\\t4": {'\t', 4, false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := gcodeFactory.NewAddressableGcodeFloat64(tc.word, tc.address)
+			if tc.valid {
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+				if gc.String() != tc.want {
+					t.Errorf("got gcode %s, want gcode %s", gc, tc.want)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestGcodeFactoryWithRegistry(t *testing.T) {
+
+	registry, err := gcode.NewWordRegistry('g', 'x', 'Y')
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	gcodeFactory := New(registry)
+
+	cases := map[string]struct {
+		source string
+		valid  bool
+	}{
+		"lowercase command":   {"g1", true},
+		"lowercase parameter": {"x10.5", true},
+		"uppercase allowed":   {"Y2", true},
+		"uppercase denied":    {"G1", false},
+		"unaddressable":       {"x", true},
+		"string":              {"g\"a\"", true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := gcodeFactory.Parse(tc.source)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if gc.String() != tc.source {
+				t.Errorf("got gcode %s, want gcode %s", gc, tc.source)
+			}
+		})
+	}
+}
+
+func TestGcodeFactoryParseInterned(t *testing.T) {
+	gcodeFactory := &GcodeFactory{}
+
+	t.Run("commands are shared", func(t *testing.T) {
+		cases := map[string]struct {
+			source string
+			shared bool
+		}{
+			"G1":         {"G1", true},
+			"M104":       {"M104", true},
+			"T0":         {"T0", true},
+			"G01":        {"G01", true},
+			"parameter":  {"X10", false},
+			"float":      {"G1.5", false},
+			"negative":   {"G-1", false},
+			"linenumber": {"N10", false},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				first, err := gcodeFactory.ParseInterned(tc.source)
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+
+				second, err := gcodeFactory.ParseInterned(tc.source)
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+
+				if (first == second) != tc.shared {
+					t.Errorf("got shared %v, want %v", first == second, tc.shared)
+				}
+
+				if first.String() != second.String() {
+					t.Errorf("got %s and %s, want the same gcode", first, second)
+				}
+			})
+		}
+	})
+
+	t.Run("shared commands are frozen", func(t *testing.T) {
+		gc, err := gcodeFactory.ParseInterned("G28")
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		agc, ok := gc.(gcode.AddressableGcoder[int32])
+		if !ok {
+			t.Errorf("got %T, want int32 addressable gcode", gc)
+			return
+		}
+
+		if err := agc.SetAddress(0); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("registry is applied to shared commands", func(t *testing.T) {
+		if _, err := gcodeFactory.ParseInterned("M105"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		registry, err := gcode.NewWordRegistry('G')
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, err := New(registry).ParseInterned("M105"); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("allocations", func(t *testing.T) {
+		if _, err := gcodeFactory.ParseInterned("G0"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = gcodeFactory.ParseInterned("G0")
+		})
+
+		if allocs != 0 {
+			t.Errorf("got %v allocations, want 0", allocs)
+		}
+	})
+}
+
+// expressionGcode is a gcode with an address expressed as a bracket expression, like X[#1+2].
+type expressionGcode struct {
+	word       byte
+	expression string
+}
+
+func (g *expressionGcode) String() string                  { return string(g.word) + g.expression }
+func (g *expressionGcode) Compare(other gcode.Gcoder) bool { return other.String() == g.String() }
+func (g *expressionGcode) HasAddress() bool                { return true }
+func (g *expressionGcode) Word() byte                      { return g.word }
+
+// expressionPlugin parses the bracket expressions.
+type expressionPlugin struct {
+	name string
+}
+
+func (p expressionPlugin) Name() string { return p.name }
+
+func (p expressionPlugin) Match(word byte, address string) bool {
+	return strings.HasPrefix(address, "[")
+}
+
+func (p expressionPlugin) Parse(word byte, address string) (gcode.Gcoder, error) {
+	if !strings.HasSuffix(address, "]") {
+		return nil, fmt.Errorf("unclosed expression %s", address)
+	}
+
+	return &expressionGcode{word: word, expression: address}, nil
+}
+
+func TestGcodeFactoryAddressPlugins(t *testing.T) {
+
+	t.Run("register", func(t *testing.T) {
+		gcodeFactory := &GcodeFactory{}
+
+		if err := gcodeFactory.RegisterAddressPlugin(nil); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+
+		for i := 0; i < 3; i++ {
+			if err := gcodeFactory.RegisterAddressPlugin(expressionPlugin{"expression"}); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+		}
+
+		if len(gcodeFactory.plugins) != 1 {
+			t.Errorf("got %d plugins, want 1", len(gcodeFactory.plugins))
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		gcodeFactory := &GcodeFactory{}
+		if err := gcodeFactory.RegisterAddressPlugin(expressionPlugin{"expression"}); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		cases := map[string]struct {
+			source string
+			valid  bool
+			custom bool
+		}{
+			"expression":         {"X[#1+2]", true, true},
+			"built-in float":     {"X1.5", true, false},
+			"built-in int":       {"G1", true, false},
+			"invalid expression": {"X[#1+2", false, false},
+			"invalid word":       {"K[#1]", false, false},
+			"unknown syntax":     {"X{1}", false, false},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				gc, err := gcodeFactory.Parse(tc.source)
+				if !tc.valid {
+					if err == nil {
+						t.Errorf("got error nil, want error not nil")
+					}
+					return
+				}
+
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+
+				if _, ok := gc.(*expressionGcode); ok != tc.custom {
+					t.Errorf("got gcode %T, want custom %v", gc, tc.custom)
+				}
+
+				if gc.String() != tc.source {
+					t.Errorf("got %s, want %s", gc, tc.source)
+				}
+			})
+		}
+	})
+}
+
 func TestParse(t *testing.T) {
 
 	// output:           "N4 G92 E0*67 ;comentario",
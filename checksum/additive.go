@@ -0,0 +1,113 @@
+package checksum
+
+//#region mod256
+
+// Mod256 implements the additive checksum, the sum of all bytes of the line modulo 256.
+//
+// It is used by some DNC links and hobby controllers.
+// The zero value is ready to use.
+type Mod256 struct {
+	sum uint8
+}
+
+// Sum8 returns the current checksum.
+func (d *Mod256) Sum8() uint8 {
+	return d.sum
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+// It does not change the underlying hash state.
+func (d *Mod256) Sum(in []byte) []byte {
+	return append(in, d.sum)
+}
+
+// Reset resets the Hash to its initial state.
+func (d *Mod256) Reset() {
+	d.sum = 0
+}
+
+// Size returns the number of bytes Sum will return.
+func (d *Mod256) Size() int {
+	return 1
+}
+
+// BlockSize returns the hash's underlying block size.
+func (d *Mod256) BlockSize() int {
+	return 1
+}
+
+// Write (via the embedded io.Writer interface) adds more data to the running hash.
+// It never returns an error.
+func (d *Mod256) Write(p []byte) (n int, err error) {
+	for _, v := range p {
+		d.sum += v
+	}
+
+	return len(p), nil
+}
+
+//#endregion
+//#region fletcher16
+
+// Fletcher16 implements the Fletcher-16 checksum, two running sums modulo 255 that detect swapped bytes,
+// unlike the XOR and the additive checksums.
+//
+// The digest is the second sum followed by the first one, like 0xC8F0 for "abcde".
+// The zero value is ready to use.
+type Fletcher16 struct {
+	sum1 uint16
+	sum2 uint16
+}
+
+// Sum16 returns the current checksum.
+func (d *Fletcher16) Sum16() uint16 {
+	return d.sum2<<8 | d.sum1
+}
+
+// Sum appends the current hash to b, in big-endian order, and returns the resulting slice.
+// It does not change the underlying hash state.
+func (d *Fletcher16) Sum(in []byte) []byte {
+	return append(in, byte(d.sum2), byte(d.sum1))
+}
+
+// Reset resets the Hash to its initial state.
+func (d *Fletcher16) Reset() {
+	d.sum1 = 0
+	d.sum2 = 0
+}
+
+// Size returns the number of bytes Sum will return.
+func (d *Fletcher16) Size() int {
+	return 2
+}
+
+// BlockSize returns the hash's underlying block size.
+func (d *Fletcher16) BlockSize() int {
+	return 1
+}
+
+// Write (via the embedded io.Writer interface) adds more data to the running hash.
+// It never returns an error.
+func (d *Fletcher16) Write(p []byte) (n int, err error) {
+	for _, v := range p {
+		d.sum1 = (d.sum1 + uint16(v)) % 255
+		d.sum2 = (d.sum2 + d.sum1) % 255
+	}
+
+	return len(p), nil
+}
+
+//#endregion
+//#region constructors
+
+// NewMod256 creates a new additive checksum modulo 256.
+func NewMod256() *Mod256 {
+	return &Mod256{}
+}
+
+// NewFletcher16 creates a new Fletcher-16 checksum.
+func NewFletcher16() *Fletcher16 {
+	return &Fletcher16{}
+}
+
+//#endregion
@@ -5,6 +5,11 @@
 //
 // It is compatible with [hash.Hash] interface
 //
+// Furthermore, it includes CRC16 and CRC32 algorithms for the controllers that check each line with a wider value.
+// Their digests are exported as multi-digit check fields, like *17307.
+//
+// The simple additive (modulo 256) and Fletcher-16 checksums are available too, for the DNC links and hobby controllers that use them.
+//
 // [Checksum algorithm]: https://reprap.org/wiki/G-code#.2A:_Checksum
 // [hash.Hash]: https://pkg.go.dev/hash@go1.18.3
 package checksum
@@ -13,24 +18,32 @@ import "hash"
 
 //region hash implementation
 
-// digest represents the partial evaluation of a checksum
-type digest struct {
+// XOR implements the checksum of the RepRap protocol, a XOR of all bytes of the line before the '*' character.
+//
+// It is the algorithm used by Marlin, RepRapFirmware, Repetier and Prusa firmwares.
+// The zero value is ready to use.
+type XOR struct {
 	checksum uint8
 }
 
+// Sum8 returns the current checksum.
+func (d *XOR) Sum8() uint8 {
+	return d.checksum
+}
+
 // Sum appends the current hash to b and returns the resulting slice.
 // It does not change the underlying hash state.
-func (d *digest) Sum(in []byte) []byte {
+func (d *XOR) Sum(in []byte) []byte {
 	return append(in, byte(d.checksum))
 }
 
 // Reset resets the Hash to its initial state.
-func (d *digest) Reset() {
+func (d *XOR) Reset() {
 	d.checksum = 0
 }
 
 // Size returns the number of bytes Sum will return.
-func (d *digest) Size() int {
+func (d *XOR) Size() int {
 	return 1
 }
 
@@ -38,13 +51,13 @@ func (d *digest) Size() int {
 // The Write method must be able to accept any amount
 // of data, but it may operate more efficiently if all writes
 // are a multiple of the block size.
-func (d *digest) BlockSize() int {
+func (d *XOR) BlockSize() int {
 	return 1
 }
 
 // Write (via the embedded io.Writer interface) adds more data to the running hash.
 // It never returns an error.
-func (d *digest) Write(p []byte) (n int, err error) {
+func (d *XOR) Write(p []byte) (n int, err error) {
 	d.checksum = simpleUpdate(d.checksum, p)
 	return len(p), nil
 }
@@ -53,8 +66,23 @@ func (d *digest) Write(p []byte) (n int, err error) {
 //#region constructors
 
 // New creates a new hash.Hash computing checksum using the Marlin and RepRap algorithm.
+//
+// It is the same that NewXOR.
 func New() hash.Hash {
-	return &digest{}
+	return NewXOR()
+}
+
+// NewXOR creates a new XOR checksum.
+func NewXOR() *XOR {
+	return &XOR{}
+}
+
+//#endregion
+//#region package functions
+
+// Checksum returns the XOR checksum of a line, it must not include the '*' character and the checksum value.
+func Checksum(line []byte) uint8 {
+	return simpleUpdate(0, line)
 }
 
 //#endregion
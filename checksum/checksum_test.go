@@ -1,7 +1,9 @@
 package checksum
 
 import (
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"testing"
 )
 
@@ -35,6 +37,40 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestXOR(t *testing.T) {
+	// lines sent by Printrun and OctoPrint to Marlin firmware, with the checksum expected by the firmware
+	cases := map[string]struct {
+		line     string
+		checksum uint8
+	}{
+		"reset line number":  {"N0 M110 N0", 125},
+		"report temperature": {"N1 M105", 38},
+		"firmware info":      {"N2 M115", 36},
+		"move":               {"N8 G1 X3.0 Y3.0 Z3.0 F3000.0", 13},
+		"trailing zeros":     {"N10 G1 X10.500 Y5.00 F1500", 123},
+		"empty":              {"", 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var h XOR
+
+			if _, err := h.Write([]byte(tc.line)); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if h.Sum8() != tc.checksum {
+				t.Errorf("got %d, want %d", h.Sum8(), tc.checksum)
+			}
+
+			if Checksum([]byte(tc.line)) != tc.checksum {
+				t.Errorf("got Checksum %d, want %d", Checksum([]byte(tc.line)), tc.checksum)
+			}
+		})
+	}
+}
+
 func TestConfiguration(t *testing.T) {
 	t.Run("size", func(t *testing.T) {
 		if New().Size() != 1 {
@@ -48,3 +84,97 @@ func TestConfiguration(t *testing.T) {
 		}
 	})
 }
+
+func TestCRC(t *testing.T) {
+	cases := map[string]struct {
+		hash  hash.Hash
+		input string
+		want  string
+		size  int
+	}{
+		"crc16 check value": {NewCRC16(), "123456789", "29b1", 2},
+		"crc16 empty":       {NewCRC16(), "", "ffff", 2},
+		"crc16 line":        {NewCRC16(), "N7 G1 X2.0 Y2.0 F3000.0", "439b", 2},
+		"crc32 check value": {NewCRC32(), "123456789", "cbf43926", 4},
+		"crc32 empty":       {NewCRC32(), "", "00000000", 4},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// write the input byte by byte to check the partial evaluation
+			for _, b := range []byte(tc.input) {
+				if _, err := tc.hash.Write([]byte{b}); err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+			}
+
+			if got := hex.EncodeToString(tc.hash.Sum(nil)); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+
+			if tc.hash.Size() != tc.size {
+				t.Errorf("got size %d, want %d", tc.hash.Size(), tc.size)
+			}
+
+			tc.hash.Reset()
+			if _, err := tc.hash.Write([]byte(tc.input)); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := hex.EncodeToString(tc.hash.Sum(nil)); got != tc.want {
+				t.Errorf("got %s after reset, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAdditive(t *testing.T) {
+	cases := map[string]struct {
+		hash  hash.Hash
+		input string
+		want  string
+		size  int
+	}{
+		"mod256 check value":    {NewMod256(), "123456789", "dd", 1},
+		"mod256 empty":          {NewMod256(), "", "00", 1},
+		"mod256 overflow":       {NewMod256(), "\xff\x02", "01", 1},
+		"fletcher16 abcde":      {NewFletcher16(), "abcde", "c8f0", 2},
+		"fletcher16 abcdef":     {NewFletcher16(), "abcdef", "2057", 2},
+		"fletcher16 abcdefgh":   {NewFletcher16(), "abcdefgh", "0627", 2},
+		"fletcher16 empty":      {NewFletcher16(), "", "0000", 2},
+		"fletcher16 zero value": {&Fletcher16{}, "abcde", "c8f0", 2},
+		"fletcher16 swapped ab": {NewFletcher16(), "bacde", "c9f0", 2},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// write the input byte by byte to check the partial evaluation
+			for _, b := range []byte(tc.input) {
+				if _, err := tc.hash.Write([]byte{b}); err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+			}
+
+			if got := hex.EncodeToString(tc.hash.Sum(nil)); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+
+			if tc.hash.Size() != tc.size {
+				t.Errorf("got size %d, want %d", tc.hash.Size(), tc.size)
+			}
+
+			tc.hash.Reset()
+			if _, err := tc.hash.Write([]byte(tc.input)); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := hex.EncodeToString(tc.hash.Sum(nil)); got != tc.want {
+				t.Errorf("got %s after reset, want %s", got, tc.want)
+			}
+		})
+	}
+}
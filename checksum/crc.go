@@ -0,0 +1,95 @@
+package checksum
+
+import (
+	"hash"
+	"hash/crc32"
+)
+
+//#region crc16
+
+// CRC16 implements the CRC-16/CCITT-FALSE algorithm (polynomial 0x1021, initial value 0xFFFF) used by some controllers
+// and DNC links to check each line with a wider value than the XOR checksum.
+//
+// Use NewCRC16 to create it, the zero value isn't initialized.
+type CRC16 struct {
+	crc uint16
+}
+
+// crc16Table is the lookup table of the CRC-16/CCITT-FALSE polynomial.
+var crc16Table = makeCRC16Table(0x1021)
+
+// Sum16 returns the current checksum.
+func (d *CRC16) Sum16() uint16 {
+	return d.crc
+}
+
+// Sum appends the current hash to b, in big-endian order, and returns the resulting slice.
+// It does not change the underlying hash state.
+func (d *CRC16) Sum(in []byte) []byte {
+	return append(in, byte(d.crc>>8), byte(d.crc))
+}
+
+// Reset resets the Hash to its initial state.
+func (d *CRC16) Reset() {
+	d.crc = 0xFFFF
+}
+
+// Size returns the number of bytes Sum will return.
+func (d *CRC16) Size() int {
+	return 2
+}
+
+// BlockSize returns the hash's underlying block size.
+func (d *CRC16) BlockSize() int {
+	return 1
+}
+
+// Write (via the embedded io.Writer interface) adds more data to the running hash.
+// It never returns an error.
+func (d *CRC16) Write(p []byte) (n int, err error) {
+	for _, v := range p {
+		d.crc = d.crc<<8 ^ crc16Table[byte(d.crc>>8)^v]
+	}
+
+	return len(p), nil
+}
+
+//#endregion
+//#region constructors
+
+// NewCRC16 creates a new CRC-16/CCITT-FALSE checksum.
+func NewCRC16() *CRC16 {
+	d := &CRC16{}
+	d.Reset()
+
+	return d
+}
+
+// NewCRC32 creates a new CRC-32 (IEEE) checksum, the same used by zip and ethernet.
+func NewCRC32() hash.Hash32 {
+	return crc32.NewIEEE()
+}
+
+//#endregion
+//#region private functions
+
+// makeCRC16Table computes the lookup table of a CRC-16 polynomial without reflection.
+func makeCRC16Table(poly uint16) [256]uint16 {
+	var table [256]uint16
+
+	for i := range table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return table
+}
+
+//#endregion
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region move builder
+
+// MoveBuilder builds a linear move parameter by parameter, like Move().X(10).Y(5).Feedrate(3000).Block().
+// The parameters that aren't set aren't written, and each one is written once with the last value set.
+type MoveBuilder struct {
+	move LinearMove
+}
+
+// Move returns a builder of a linear move, G1.
+func Move() *MoveBuilder {
+	return &MoveBuilder{}
+}
+
+// Rapid returns a builder of a rapid move, G0.
+func Rapid() *MoveBuilder {
+	return &MoveBuilder{move: LinearMove{Rapid: true}}
+}
+
+// X sets the position of the X axis.
+func (mb *MoveBuilder) X(x float64) *MoveBuilder {
+	mb.move.X = Float(x)
+	return mb
+}
+
+// Y sets the position of the Y axis.
+func (mb *MoveBuilder) Y(y float64) *MoveBuilder {
+	mb.move.Y = Float(y)
+	return mb
+}
+
+// Z sets the position of the Z axis.
+func (mb *MoveBuilder) Z(z float64) *MoveBuilder {
+	mb.move.Z = Float(z)
+	return mb
+}
+
+// E sets the position of the extruder.
+func (mb *MoveBuilder) E(e float64) *MoveBuilder {
+	mb.move.E = Float(e)
+	return mb
+}
+
+// Feedrate sets the feedrate in units per minute, the F word.
+func (mb *MoveBuilder) Feedrate(feedrate float64) *MoveBuilder {
+	mb.move.F = Float(feedrate)
+	return mb
+}
+
+// Command returns a copy of the move built, so the builder can continue without modifying it.
+func (mb *MoveBuilder) Command() *LinearMove {
+	move := mb.move
+	return &move
+}
+
+// Block returns a new block with the move built.
+// It returns an error if some value isn't finite.
+func (mb *MoveBuilder) Block() (block.Blocker, error) {
+	return mb.move.Block()
+}
+
+//#endregion
+//#region arc builder
+
+// ArcBuilder builds an arc move parameter by parameter, like Arc(true).X(10).Y(0).Center(5, 0).Block().
+// The parameters that aren't set aren't written, and each one is written once with the last value set.
+type ArcBuilder struct {
+	arc ArcMove
+}
+
+// Arc returns a builder of an arc move, G2 if it is clockwise or G3 otherwise.
+func Arc(clockwise bool) *ArcBuilder {
+	return &ArcBuilder{arc: ArcMove{Clockwise: clockwise}}
+}
+
+// X sets the position of the X axis at the end of the arc.
+func (ab *ArcBuilder) X(x float64) *ArcBuilder {
+	ab.arc.X = Float(x)
+	return ab
+}
+
+// Y sets the position of the Y axis at the end of the arc.
+func (ab *ArcBuilder) Y(y float64) *ArcBuilder {
+	ab.arc.Y = Float(y)
+	return ab
+}
+
+// Z sets the position of the Z axis at the end of the arc, for a helical arc.
+func (ab *ArcBuilder) Z(z float64) *ArcBuilder {
+	ab.arc.Z = Float(z)
+	return ab
+}
+
+// E sets the position of the extruder at the end of the arc.
+func (ab *ArcBuilder) E(e float64) *ArcBuilder {
+	ab.arc.E = Float(e)
+	return ab
+}
+
+// Center sets the offsets of the center from the start along X and Y, the I and J words, and removes the radius.
+func (ab *ArcBuilder) Center(i, j float64) *ArcBuilder {
+	ab.arc.I, ab.arc.J, ab.arc.R = Float(i), Float(j), nil
+	return ab
+}
+
+// Radius sets the radius, the R word, and removes the offsets of the center.
+func (ab *ArcBuilder) Radius(r float64) *ArcBuilder {
+	ab.arc.I, ab.arc.J, ab.arc.K, ab.arc.R = nil, nil, nil, Float(r)
+	return ab
+}
+
+// Feedrate sets the feedrate in units per minute, the F word.
+func (ab *ArcBuilder) Feedrate(feedrate float64) *ArcBuilder {
+	ab.arc.F = Float(feedrate)
+	return ab
+}
+
+// Command returns a copy of the arc built, so the builder can continue without modifying it.
+func (ab *ArcBuilder) Command() *ArcMove {
+	arc := ab.arc
+	return &arc
+}
+
+// Block returns a new block with the arc built.
+// It returns an error if some value isn't finite.
+func (ab *ArcBuilder) Block() (block.Blocker, error) {
+	return ab.arc.Block()
+}
+
+//#endregion
+//#region shortcuts
+
+// RapidTo returns a new block with a rapid move to a position, like "G0 X10 Y5 Z0.2".
+// It returns an error if some value isn't finite.
+func RapidTo(x, y, z float64) (block.Blocker, error) {
+	return Rapid().X(x).Y(y).Z(z).Block()
+}
+
+// MoveTo returns a new block with a linear move to a position at a feedrate in units per minute, like "G1 X10 Y5 Z0.2 F3000".
+// It returns an error if some value isn't finite.
+func MoveTo(x, y, z, feedrate float64) (block.Blocker, error) {
+	return Move().X(x).Y(y).Z(z).Feedrate(feedrate).Block()
+}
+
+//#endregion
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+func TestBuilder(t *testing.T) {
+	cases := map[string]struct {
+		build func() (block.Blocker, error)
+		want  string
+	}{
+		"move":         {func() (block.Blocker, error) { return Move().X(10).Y(5).Feedrate(3000).Block() }, "G1 X10 Y5 F3000"},
+		"last value":   {func() (block.Blocker, error) { return Move().E(1).X(1).X(2.5).Block() }, "G1 X2.5 E1"},
+		"rapid":        {func() (block.Blocker, error) { return Rapid().Z(0.2).Block() }, "G0 Z0.2"},
+		"rapid to":     {func() (block.Blocker, error) { return RapidTo(10, 5, 0.2) }, "G0 X10 Y5 Z0.2"},
+		"move to":      {func() (block.Blocker, error) { return MoveTo(10, 5, 0.2, 3000) }, "G1 X10 Y5 Z0.2 F3000"},
+		"arc center":   {func() (block.Blocker, error) { return Arc(true).X(10).Y(0).Center(5, 0).E(1).Block() }, "G2 X10 Y0 I5 J0 E1"},
+		"arc radius":   {func() (block.Blocker, error) { return Arc(false).Center(5, 0).X(10).Radius(5).Feedrate(600).Block() }, "G3 X10 R5 F600"},
+		"empty":        {func() (block.Blocker, error) { return Move().Block() }, "G1"},
+		"helical move": {func() (block.Blocker, error) { return Arc(false).X(0).Z(1).Center(-5, 0).Block() }, "G3 X0 Z1 I-5 J0"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := tc.build()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if b.String() != tc.want {
+				t.Errorf("got %q, want %q", b.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("not finite", func(t *testing.T) {
+		if _, err := Move().X(math.NaN()).Block(); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("command copied", func(t *testing.T) {
+		builder := Move().X(1)
+		move := builder.Command()
+		builder.X(2)
+
+		if *move.X != 1 {
+			t.Errorf("got X%v, want X1", *move.X)
+		}
+	})
+}
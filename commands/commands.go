@@ -0,0 +1,255 @@
+// commands package interprets the blocks as typed commands, like LinearMove or SetHotendTemp, and converts them back to blocks.
+//
+// The analyzers written against the typed commands don't inspect the words and the addresses of the blocks:
+//
+//	c, err := commands.Interpret(b)
+//	if move, ok := c.(*commands.LinearMove); ok && move.X != nil {
+//		fmt.Println(*move.X)
+//	}
+//
+// The values are the ones written in the blocks, in their units and their distance mode, without conversions.
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region commander interface
+
+// Commander is a block interpreted as a typed command.
+type Commander interface {
+	// Block returns a new block with the command
+	Block() (block.Blocker, error)
+}
+
+// Raw is a block that isn't interpreted as a typed command, like M82 or a block without command.
+type Raw struct {
+	// Original is the block received.
+	Original block.Blocker
+}
+
+// Block returns the block received, it isn't a copy.
+func (r *Raw) Block() (block.Blocker, error) {
+	return r.Original, nil
+}
+
+//#endregion
+//#region tool change and dwell
+
+// ToolChange is the selection of a tool, like T1.
+type ToolChange struct {
+	// Tool is the index of the tool.
+	Tool int
+}
+
+// Block returns a new block with the tool change.
+func (c *ToolChange) Block() (block.Blocker, error) {
+	if c.Tool < 0 {
+		return nil, fmt.Errorf("failed to create tool change, the tool can't be negative: %d", c.Tool)
+	}
+
+	return build("T" + strconv.Itoa(c.Tool))
+}
+
+// Dwell is a pause, G4 with the time in milliseconds in P or in seconds in S.
+type Dwell struct {
+	// Duration is the time of the pause.
+	Duration time.Duration
+}
+
+// Block returns a new block with the pause, with its time in milliseconds in P.
+func (c *Dwell) Block() (block.Blocker, error) {
+	if c.Duration < 0 {
+		return nil, fmt.Errorf("failed to create dwell, the duration can't be negative: %v", c.Duration)
+	}
+
+	return build("G4", word{'P', Float(float64(c.Duration) / float64(time.Millisecond))})
+}
+
+//#endregion
+//#region interpret
+
+// Interpret returns the typed command of a block.
+// The blocks that don't have a typed command are returned as Raw, so Interpret can be applied to any block,
+// and the temperature commands implement TemperatureCommander.
+//
+// It returns an error if some parameter of a typed command has an invalid value, like a tool that isn't an integer.
+func Interpret(b block.Blocker) (Commander, error) {
+	command := b.Command()
+	if command == nil {
+		return &Raw{Original: b}, nil
+	}
+
+	if command.Word() == 'T' {
+		tool, err := integer(command)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpret block %s: %w", b, err)
+		}
+		return &ToolChange{Tool: tool}, nil
+	}
+
+	var c Commander
+	var err error
+
+	switch command.String() {
+	case "G0", "G1":
+		c = interpretLinearMove(b)
+	case "G2", "G3":
+		c = interpretArcMove(b)
+	case "G4":
+		c, err = interpretDwell(b)
+	case "M104", "M109":
+		c, err = interpretSetHotendTemp(b)
+	case "M140", "M190":
+		c = interpretSetBedTemp(b)
+	case "M141", "M191":
+		c = interpretSetChamberTemp(b)
+	case "M106", "M107":
+		c, err = interpretSetFanSpeed(b)
+	case "M42":
+		c, err = interpretSetPin(b)
+	case "M150":
+		c, err = interpretSetLED(b)
+	case "M20", "M21", "M22", "M23", "M24", "M25", "M26", "M27", "M28", "M29", "M30", "M31", "M32", "M33":
+		number, _ := strconv.Atoi(command.String()[1:])
+
+		var sd *SDCommand
+		if sd, err = interpretSDCommand(b, SDOperation(number-20)); err == nil && sd == nil {
+			return &Raw{Original: b}, nil
+		}
+		c = sd
+	default:
+		return &Raw{Original: b}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpret block %s: %w", b, err)
+	}
+
+	return c, nil
+}
+
+// Float returns a pointer to a value, to set the optional fields of the typed commands, like LinearMove{X: commands.Float(10)}.
+func Float(value float64) *float64 {
+	return &value
+}
+
+// Int returns a pointer to a value, to set the optional fields of the typed commands, like SetHotendTemp{Tool: commands.Int(1)}.
+func Int(value int) *int {
+	return &value
+}
+
+//#endregion
+//#region private functions
+
+// interpretDwell interprets G4, the time in seconds of S is used if P isn't written.
+func interpretDwell(b block.Blocker) (*Dwell, error) {
+	if ms, ok := transform.Parameter(b, 'P'); ok {
+		if ms < 0 {
+			return nil, fmt.Errorf("the time of the dwell can't be negative: %v", ms)
+		}
+		return &Dwell{Duration: time.Duration(ms * float64(time.Millisecond))}, nil
+	}
+
+	seconds, _ := transform.Parameter(b, 'S')
+	if seconds < 0 {
+		return nil, fmt.Errorf("the time of the dwell can't be negative: %v", seconds)
+	}
+
+	return &Dwell{Duration: time.Duration(seconds * float64(time.Second))}, nil
+}
+
+// optional returns a pointer to the value of the parameter of the block, or nil if it hasn't the parameter.
+func optional(b block.Blocker, letter byte) *float64 {
+	if value, ok := transform.Parameter(b, letter); ok {
+		return &value
+	}
+
+	return nil
+}
+
+// optionalInteger returns a pointer to the integer value of the parameter of the block, or nil if it hasn't the parameter.
+// It returns an error if the value isn't an integer.
+func optionalInteger(b block.Blocker, letter byte) (*int, error) {
+	for _, p := range b.Parameters() {
+		if p.Word() != letter {
+			continue
+		}
+
+		value, err := integer(p)
+		if err != nil {
+			return nil, err
+		}
+		return &value, nil
+	}
+
+	return nil, nil
+}
+
+// integer returns the address of a gcode as an integer.
+// It returns an error if the address isn't numeric or it isn't an integer.
+func integer(g gcode.Gcoder) (int, error) {
+	value, ok := gcode.NumericAddress(g)
+	if !ok || value != math.Trunc(value) {
+		return 0, fmt.Errorf("the address of %s must be an integer", g)
+	}
+
+	return int(value), nil
+}
+
+// word is a parameter of a block that is built, it is omitted if its value is nil.
+type word struct {
+	letter byte
+	value  *float64
+}
+
+// build parses a new block with the command and the parameters that have value, accepting the K word of the arcs and the B word of the LEDs.
+func build(command string, words ...word) (block.Blocker, error) {
+	parts := []string{command}
+
+	for _, w := range words {
+		if w.value == nil {
+			continue
+		}
+
+		if math.IsNaN(*w.value) || math.IsInf(*w.value, 0) {
+			return nil, fmt.Errorf("failed to create %s, the parameter %c must be finite: %v", command, w.letter, *w.value)
+		}
+
+		parts = append(parts, string(w.letter)+strconv.FormatFloat(*w.value, 'f', -1, 64))
+	}
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K', 'B'); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", command, err)
+	}
+
+	b, err := gcodeblock.Parse(strings.Join(parts, " "), func(config block.BlockParserConfigurer) error {
+		return config.SetWordRegistry(registry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", command, err)
+	}
+
+	return b, nil
+}
+
+// integerValue returns a pointer to the value as float64, or nil if the value is nil.
+func integerValue(value *int) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	return Float(float64(*value))
+}
+
+//#endregion
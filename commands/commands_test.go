@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+// parseBlock parses a line as a block, accepting the K word of the arcs and the B word of the LEDs. It fails the test if the line is invalid.
+func parseBlock(t *testing.T, line string) block.Blocker {
+	t.Helper()
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K', 'B'); err != nil {
+		t.Fatalf("failed to allow K and B: %v", err)
+	}
+
+	b, err := gcodeblock.Parse(line, func(config block.BlockParserConfigurer) error {
+		return config.SetWordRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", line, err)
+	}
+
+	return b
+}
+
+func TestInterpret(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		want   Commander
+		block  string
+	}{
+		"linear move": {"G1 X10 Y20.5 F1200", &LinearMove{X: Float(10), Y: Float(20.5), F: Float(1200)}, "G1 X10 Y20.5 F1200"},
+		"rapid move":  {"G0 Z0.2", &LinearMove{Rapid: true, Z: Float(0.2)}, "G0 Z0.2"},
+		"arc":         {"G2 X10 Y0 I5 J0 E1.5", &ArcMove{Clockwise: true, X: Float(10), Y: Float(0), I: Float(5), J: Float(0), E: Float(1.5)}, "G2 X10 Y0 I5 J0 E1.5"},
+		"helical arc": {"G3 X10 Z1 K0.5 R5", &ArcMove{X: Float(10), Z: Float(1), K: Float(0.5), R: Float(5)}, "G3 X10 Z1 K0.5 R5"},
+		"hotend":      {"M104 S200 T1", &SetHotendTemp{Temperature: 200, Tool: Int(1)}, "M104 S200 T1"},
+		"wait hotend": {"M109 R180", &SetHotendTemp{Temperature: 180, Wait: true, Cooling: true}, "M109 R180"},
+		"bed":         {"M190 S60", &SetBedTemp{Temperature: 60, Wait: true}, "M190 S60"},
+		"bed off":     {"M140", &SetBedTemp{}, "M140 S0"},
+		"tool change": {"T2", &ToolChange{Tool: 2}, "T2"},
+		"dwell":       {"G4 P500", &Dwell{Duration: 500 * time.Millisecond}, "G4 P500"},
+		"dwell in s":  {"G4 S2", &Dwell{Duration: 2 * time.Second}, "G4 P2000"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Interpret(parseBlock(t, tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+
+			b, err := got.Block()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if b.String() != tc.block {
+				t.Errorf("got block %q, want %q", b.String(), tc.block)
+			}
+		})
+	}
+}
+
+func TestInterpret_raw(t *testing.T) {
+	for _, source := range []string{"M82", "G28 X0", "G92 E0"} {
+		b := parseBlock(t, source)
+
+		got, err := Interpret(b)
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		raw, ok := got.(*Raw)
+		if !ok || raw.Original != b {
+			t.Errorf("got %#v, want the block %s as raw", got, source)
+		}
+	}
+}
+
+func TestInterpret_invalid(t *testing.T) {
+	for _, source := range []string{"M104 S200 T1.5", "G4 P-1", "T0.5"} {
+		if _, err := Interpret(parseBlock(t, source)); err == nil {
+			t.Errorf("got error nil with %s, want error not nil", source)
+		}
+	}
+}
+
+func TestCommander_Block_invalid(t *testing.T) {
+	cases := map[string]Commander{
+		"negative tool":     &ToolChange{Tool: -1},
+		"negative dwell":    &Dwell{Duration: -time.Second},
+		"cooling M104":      &SetHotendTemp{Temperature: 200, Cooling: true},
+		"cooling M140":      &SetBedTemp{Temperature: 60, Cooling: true},
+		"position infinite": &LinearMove{X: Float(math.Inf(1))},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.Block(); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
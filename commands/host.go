@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HOST_ACTION_PREFIX is the prefix of the lines that a firmware sends to ask an action to the host, like "//action:pause".
+const HOST_ACTION_PREFIX = "//action:"
+
+const (
+	// ACTION_PAUSE asks the host to pause the print that it streams.
+	ACTION_PAUSE = "pause"
+
+	// ACTION_PAUSED notifies the host that the firmware paused the print.
+	ACTION_PAUSED = "paused"
+
+	// ACTION_RESUME asks the host to resume the print that it streams.
+	ACTION_RESUME = "resume"
+
+	// ACTION_RESUMED notifies the host that the firmware resumed the print.
+	ACTION_RESUMED = "resumed"
+
+	// ACTION_CANCEL asks the host to cancel the print that it streams.
+	ACTION_CANCEL = "cancel"
+
+	// ACTION_START asks the host to start a print.
+	ACTION_START = "start"
+
+	// ACTION_NOTIFICATION shows its arguments as a message in the host.
+	ACTION_NOTIFICATION = "notification"
+)
+
+//#region host action
+
+// HostAction is an action that a firmware asks to the host, like "//action:pause" or "//action:notification Heating".
+type HostAction struct {
+	// Name is the name of the action, like ACTION_PAUSE.
+	Name string
+
+	// Arguments are the text after the name, empty if the action hasn't arguments.
+	Arguments string
+}
+
+// String returns the line that the firmware sends, like "//action:notification Heating".
+func (a HostAction) String() string {
+	if a.Arguments == "" {
+		return HOST_ACTION_PREFIX + a.Name
+	}
+
+	return HOST_ACTION_PREFIX + a.Name + " " + a.Arguments
+}
+
+// Command returns the text of the gcode that makes Marlin send the action to the host, like "M118 A1 action:pause",
+// so a print can ask actions to the host that streams it.
+//
+// It returns an error if the name is empty or contains spaces, or the arguments contain semicolons or line breaks.
+func (a HostAction) Command() (string, error) {
+	if a.Name == "" || strings.ContainsAny(a.Name, " \t;\r\n") {
+		return "", fmt.Errorf("failed to create host action, the name can't be empty nor contain spaces or semicolons: %q", a.Name)
+	}
+
+	if strings.ContainsAny(a.Arguments, ";\r\n") {
+		return "", fmt.Errorf("failed to create host action %s, the arguments can't contain semicolons or line breaks: %q", a.Name, a.Arguments)
+	}
+
+	return "M118 A1 " + strings.TrimPrefix(a.String(), "//"), nil
+}
+
+// ParseHostAction returns the action of a line sent by a firmware, like "//action:pause".
+// It returns false if the line isn't an action.
+func ParseHostAction(line string) (HostAction, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, HOST_ACTION_PREFIX) {
+		return HostAction{}, false
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(line, HOST_ACTION_PREFIX), " ", 2)
+	if fields[0] == "" {
+		return HostAction{}, false
+	}
+
+	action := HostAction{Name: fields[0]}
+	if len(fields) > 1 {
+		action.Arguments = strings.TrimSpace(fields[1])
+	}
+
+	return action, true
+}
+
+//#endregion
+//#region sd progress
+
+// SDProgress is the progress of the print of the SD card, reported by M27.
+type SDProgress struct {
+	// Printing is false if the firmware isn't printing from the SD card.
+	Printing bool
+
+	// Printed is the position in bytes of the print in the file.
+	Printed int64
+
+	// Size is the size in bytes of the file.
+	Size int64
+}
+
+// Fraction returns the fraction of the file printed, from 0 to 1. It is zero if the firmware isn't printing.
+func (p SDProgress) Fraction() float64 {
+	if !p.Printing || p.Size <= 0 {
+		return 0
+	}
+
+	return float64(p.Printed) / float64(p.Size)
+}
+
+// ParseSDProgress returns the progress of a line that reports it in response to M27, like "SD printing byte 1234/5678"
+// or "Not SD printing".
+// It returns false if the line isn't a report of the progress.
+func ParseSDProgress(line string) (SDProgress, bool) {
+	line = strings.TrimSpace(line)
+
+	if strings.EqualFold(line, "Not SD printing") {
+		return SDProgress{}, true
+	}
+
+	const prefix = "SD printing byte "
+	if !strings.HasPrefix(line, prefix) {
+		return SDProgress{}, false
+	}
+
+	bytes := strings.SplitN(strings.TrimPrefix(line, prefix), "/", 2)
+	if len(bytes) != 2 {
+		return SDProgress{}, false
+	}
+
+	printed, err := strconv.ParseInt(bytes[0], 10, 64)
+	if err != nil {
+		return SDProgress{}, false
+	}
+
+	size, err := strconv.ParseInt(bytes[1], 10, 64)
+	if err != nil || printed < 0 || size < printed {
+		return SDProgress{}, false
+	}
+
+	return SDProgress{Printing: true, Printed: printed, Size: size}, true
+}
+
+//#endregion
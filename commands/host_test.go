@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestParseHostAction(t *testing.T) {
+	cases := map[string]struct {
+		line  string
+		want  HostAction
+		found bool
+	}{
+		"pause":        {"//action:pause", HostAction{Name: ACTION_PAUSE}, true},
+		"notification": {"//action:notification Heating hotend  ", HostAction{Name: ACTION_NOTIFICATION, Arguments: "Heating hotend"}, true},
+		"not action":   {"ok T:200.0 /200.0", HostAction{}, false},
+		"without name": {"//action:", HostAction{}, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, ok := ParseHostAction(tc.line)
+			if ok != tc.found {
+				t.Fatalf("got found %v, want %v", ok, tc.found)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHostAction_Command(t *testing.T) {
+	cases := map[string]struct {
+		action HostAction
+		want   string
+	}{
+		"pause":        {HostAction{Name: ACTION_PAUSE}, "M118 A1 action:pause"},
+		"notification": {HostAction{Name: ACTION_NOTIFICATION, Arguments: "Change filament"}, "M118 A1 action:notification Change filament"},
+		"empty":        {HostAction{}, ""},
+		"semicolon":    {HostAction{Name: ACTION_NOTIFICATION, Arguments: "a;b"}, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.action.Command()
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("got %q, want error not nil", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSDProgress(t *testing.T) {
+	cases := map[string]struct {
+		line     string
+		want     SDProgress
+		found    bool
+		fraction float64
+	}{
+		"printing":     {"SD printing byte 1234/4936", SDProgress{Printing: true, Printed: 1234, Size: 4936}, true, 0.25},
+		"not printing": {"Not SD printing", SDProgress{}, true, 0},
+		"invalid size": {"SD printing byte 10/5", SDProgress{}, false, 0},
+		"other":        {"ok", SDProgress{}, false, 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, ok := ParseSDProgress(tc.line)
+			if ok != tc.found {
+				t.Fatalf("got found %v, want %v", ok, tc.found)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+
+			if got.Fraction() != tc.fraction {
+				t.Errorf("got fraction %v, want %v", got.Fraction(), tc.fraction)
+			}
+		})
+	}
+}
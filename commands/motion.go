@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region linear move
+
+// LinearMove is a move in a straight line, G1, or a rapid move, G0.
+//
+// The fields are nil if the block doesn't write them.
+type LinearMove struct {
+	// Rapid is true for G0.
+	Rapid bool
+
+	// X, Y and Z are the positions of the axes.
+	X, Y, Z *float64
+
+	// E is the position of the extruder.
+	E *float64
+
+	// F is the feedrate in units per minute.
+	F *float64
+}
+
+// Block returns a new block with the move, G0 if it is rapid or G1 otherwise.
+func (c *LinearMove) Block() (block.Blocker, error) {
+	command := "G1"
+	if c.Rapid {
+		command = "G0"
+	}
+
+	return build(command, word{'X', c.X}, word{'Y', c.Y}, word{'Z', c.Z}, word{'E', c.E}, word{'F', c.F})
+}
+
+// interpretLinearMove interprets G0 and G1.
+func interpretLinearMove(b block.Blocker) *LinearMove {
+	return &LinearMove{
+		Rapid: b.Command().String() == "G0",
+		X:     optional(b, 'X'),
+		Y:     optional(b, 'Y'),
+		Z:     optional(b, 'Z'),
+		E:     optional(b, 'E'),
+		F:     optional(b, 'F'),
+	}
+}
+
+//#endregion
+//#region arc move
+
+// ArcMove is a move along an arc, clockwise with G2 or counterclockwise with G3.
+// The center is given by the offsets I, J and K from the start, or by the radius R.
+//
+// The fields are nil if the block doesn't write them.
+type ArcMove struct {
+	// Clockwise is true for G2.
+	Clockwise bool
+
+	// X, Y and Z are the positions of the axes at the end of the arc.
+	X, Y, Z *float64
+
+	// I, J and K are the offsets of the center from the start along X, Y and Z.
+	I, J, K *float64
+
+	// R is the radius.
+	R *float64
+
+	// E is the position of the extruder at the end of the arc.
+	E *float64
+
+	// F is the feedrate in units per minute.
+	F *float64
+}
+
+// Block returns a new block with the arc, G2 if it is clockwise or G3 otherwise.
+func (c *ArcMove) Block() (block.Blocker, error) {
+	command := "G3"
+	if c.Clockwise {
+		command = "G2"
+	}
+
+	return build(command, word{'X', c.X}, word{'Y', c.Y}, word{'Z', c.Z},
+		word{'I', c.I}, word{'J', c.J}, word{'K', c.K}, word{'R', c.R}, word{'E', c.E}, word{'F', c.F})
+}
+
+// interpretArcMove interprets G2 and G3.
+func interpretArcMove(b block.Blocker) *ArcMove {
+	return &ArcMove{
+		Clockwise: b.Command().String() == "G2",
+		X:         optional(b, 'X'),
+		Y:         optional(b, 'Y'),
+		Z:         optional(b, 'Z'),
+		I:         optional(b, 'I'),
+		J:         optional(b, 'J'),
+		K:         optional(b, 'K'),
+		R:         optional(b, 'R'),
+		E:         optional(b, 'E'),
+		F:         optional(b, 'F'),
+	}
+}
+
+//#endregion
@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+// PWM_MAXIMUM is the value of S at full duty cycle in the blocks interpreted and in the blocks returned by Block, like Marlin does.
+const PWM_MAXIMUM = 255
+
+//#region dialect commander
+
+// DialectCommander is a command whose text differs between the firmwares, implemented by SetFanSpeed, SetPin and SetLED.
+// Their Block method returns the text of Marlin.
+type DialectCommander interface {
+	Commander
+
+	// Line returns the text of the command in a dialect
+	Line(d *dialect.Dialect) (string, error)
+}
+
+//#endregion
+//#region fan
+
+// SetFanSpeed is the speed of a fan, M106, or the fan stopped, M107.
+type SetFanSpeed struct {
+	// Fan is the index of the fan, nil for the part cooling fan.
+	Fan *int
+
+	// Speed is the fraction of the full speed, from 0 to 1.
+	Speed float64
+}
+
+// Fan returns the speed of a fan as a fraction of its full speed, from 0 to 1.
+func Fan(index int, speed float64) *SetFanSpeed {
+	return &SetFanSpeed{Fan: Int(index), Speed: speed}
+}
+
+// FanPWM returns the speed of a fan as a duty cycle from 0 to PWM_MAXIMUM.
+func FanPWM(index int, pwm float64) *SetFanSpeed {
+	return &SetFanSpeed{Fan: Int(index), Speed: pwm / PWM_MAXIMUM}
+}
+
+// PWM returns the speed as a duty cycle from 0 to PWM_MAXIMUM.
+func (c *SetFanSpeed) PWM() float64 {
+	return c.Speed * PWM_MAXIMUM
+}
+
+// Block returns a new block with the speed, M107 if the fan is stopped or M106 otherwise, with S from 0 to PWM_MAXIMUM.
+func (c *SetFanSpeed) Block() (block.Blocker, error) {
+	return c.block(PWM_MAXIMUM, true)
+}
+
+// Line returns the text of the speed in a dialect, with S scaled to the PWM of the dialect, like "M106 P1 S0.5" in RepRapFirmware.
+// The index of the fan is omitted if the dialect doesn't select the fans with P.
+//
+// It returns an error if the dialect doesn't support M106 and M107, or it can't select a fan other than the first one.
+func (c *SetFanSpeed) Line(d *dialect.Dialect) (string, error) {
+	if !d.Supports("M106") || !d.Supports("M107") {
+		return "", fmt.Errorf("failed to create fan speed, the dialect %s doesn't support M106 and M107", d.Name)
+	}
+
+	if c.Fan != nil && *c.Fan != 0 && !d.FanIndex {
+		return "", fmt.Errorf("failed to create fan speed, the dialect %s doesn't select the fan %d", d.Name, *c.Fan)
+	}
+
+	b, err := c.block(d.PWMMaximum(), d.FanIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// block returns a new block with the speed scaled to a maximum and the index of the fan if it is required.
+func (c *SetFanSpeed) block(maximum float64, index bool) (block.Blocker, error) {
+	if err := validateFraction("speed", c.Speed); err != nil {
+		return nil, fmt.Errorf("failed to create fan speed: %w", err)
+	}
+
+	var fan *float64
+	if index {
+		fan = integerValue(c.Fan)
+	}
+
+	if c.Speed == 0 {
+		return build("M107", word{'P', fan})
+	}
+
+	return build("M106", word{'P', fan}, word{'S', Float(scale(c.Speed, maximum))})
+}
+
+// interpretSetFanSpeed interprets M106 and M107, M106 without S is the full speed.
+func interpretSetFanSpeed(b block.Blocker) (*SetFanSpeed, error) {
+	fan, err := optionalInteger(b, 'P')
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SetFanSpeed{Fan: fan}
+	if b.Command().String() == "M106" {
+		c.Speed = 1
+		if pwm, ok := transform.Parameter(b, 'S'); ok {
+			c.Speed = pwm / PWM_MAXIMUM
+		}
+	}
+
+	return c, validateFraction("speed", c.Speed)
+}
+
+//#endregion
+//#region pin
+
+// SetPin is the value of a digital or PWM pin, M42.
+type SetPin struct {
+	// Pin is the number of the pin.
+	Pin int
+
+	// Value is the fraction of the full duty cycle, from 0 to 1, a digital pin is high with 1.
+	Value float64
+}
+
+// Block returns a new block with the value, with S from 0 to PWM_MAXIMUM.
+func (c *SetPin) Block() (block.Blocker, error) {
+	return c.block(PWM_MAXIMUM)
+}
+
+// Line returns the text of the value in a dialect, with S scaled to the PWM of the dialect.
+//
+// It returns an error if the dialect doesn't support M42.
+func (c *SetPin) Line(d *dialect.Dialect) (string, error) {
+	if !d.Supports("M42") {
+		return "", fmt.Errorf("failed to create pin value, the dialect %s doesn't support M42", d.Name)
+	}
+
+	b, err := c.block(d.PWMMaximum())
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// block returns a new block with the value scaled to a maximum.
+func (c *SetPin) block(maximum float64) (block.Blocker, error) {
+	if c.Pin < 0 {
+		return nil, fmt.Errorf("failed to create pin value, the pin can't be negative: %d", c.Pin)
+	}
+
+	if err := validateFraction("value", c.Value); err != nil {
+		return nil, fmt.Errorf("failed to create pin value: %w", err)
+	}
+
+	return build("M42", word{'P', Float(float64(c.Pin))}, word{'S', Float(scale(c.Value, maximum))})
+}
+
+// interpretSetPin interprets M42.
+func interpretSetPin(b block.Blocker) (*SetPin, error) {
+	pin, err := optionalInteger(b, 'P')
+	if err != nil {
+		return nil, err
+	}
+	if pin == nil {
+		return nil, fmt.Errorf("the pin isn't written")
+	}
+
+	c := &SetPin{Pin: *pin}
+	if pwm, ok := transform.Parameter(b, 'S'); ok {
+		c.Value = pwm / PWM_MAXIMUM
+	}
+
+	return c, validateFraction("value", c.Value)
+}
+
+//#endregion
+//#region led
+
+// SetLED is the color of a LED, M150 in Marlin and RepRapFirmware or SET_LED in Klipper.
+//
+// The components of the color are fractions of their full intensity, from 0 to 1.
+type SetLED struct {
+	// Red, Green, Blue and White are the components of the color.
+	Red, Green, Blue, White float64
+
+	// Brightness is the fraction of the full brightness, nil to keep the brightness of the firmware.
+	Brightness *float64
+
+	// Index is the index of the pixel of a strip, from zero, nil for all of them.
+	Index *int
+
+	// Name is the name of the LED in the configuration of Klipper, like "my_neopixel". The dialects with M150 don't use it.
+	Name string
+}
+
+// Block returns a new block with the color, M150 with the components from 0 to PWM_MAXIMUM.
+func (c *SetLED) Block() (block.Blocker, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	var brightness *float64
+	if c.Brightness != nil {
+		brightness = Float(scale(*c.Brightness, PWM_MAXIMUM))
+	}
+
+	return build("M150",
+		word{'R', Float(scale(c.Red, PWM_MAXIMUM))},
+		word{'U', Float(scale(c.Green, PWM_MAXIMUM))},
+		word{'B', Float(scale(c.Blue, PWM_MAXIMUM))},
+		word{'W', Float(scale(c.White, PWM_MAXIMUM))},
+		word{'P', brightness},
+		word{'I', integerValue(c.Index)})
+}
+
+// Line returns the text of the color in a dialect: M150 if the dialect supports it, or SET_LED with the components
+// multiplied by the brightness if the dialect accepts the extended commands of Klipper, like "SET_LED LED=my_neopixel RED=1 GREEN=0.5 BLUE=0 WHITE=0".
+//
+// It returns an error if the dialect doesn't support LEDs, or it requires the name of the LED and it is empty.
+func (c *SetLED) Line(d *dialect.Dialect) (string, error) {
+	if d.Supports("M150") {
+		b, err := c.Block()
+		if err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	if !d.ExtendedCommands {
+		return "", fmt.Errorf("failed to create LED color, the dialect %s doesn't support LEDs", d.Name)
+	}
+
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	if c.Name == "" || strings.ContainsAny(c.Name, " \t") {
+		return "", fmt.Errorf("failed to create LED color, the dialect %s requires the name of the LED without spaces: %q", d.Name, c.Name)
+	}
+
+	brightness := 1.0
+	if c.Brightness != nil {
+		brightness = *c.Brightness
+	}
+
+	line := fmt.Sprintf("SET_LED LED=%s RED=%s GREEN=%s BLUE=%s WHITE=%s", c.Name,
+		formatFraction(c.Red*brightness), formatFraction(c.Green*brightness), formatFraction(c.Blue*brightness), formatFraction(c.White*brightness))
+
+	// the pixels of Klipper are indexed from one
+	if c.Index != nil {
+		line += " INDEX=" + strconv.Itoa(*c.Index+1)
+	}
+
+	return line, nil
+}
+
+// validate returns an error if some component, the brightness or the index is out of range.
+func (c *SetLED) validate() error {
+	components := map[string]float64{"red": c.Red, "green": c.Green, "blue": c.Blue, "white": c.White}
+	if c.Brightness != nil {
+		components["brightness"] = *c.Brightness
+	}
+
+	for _, name := range []string{"red", "green", "blue", "white", "brightness"} {
+		value, ok := components[name]
+		if !ok {
+			continue
+		}
+
+		if err := validateFraction(name, value); err != nil {
+			return fmt.Errorf("failed to create LED color: %w", err)
+		}
+	}
+
+	if c.Index != nil && *c.Index < 0 {
+		return fmt.Errorf("failed to create LED color, the index can't be negative: %d", *c.Index)
+	}
+
+	return nil
+}
+
+// interpretSetLED interprets M150, the components that aren't written are zero.
+func interpretSetLED(b block.Blocker) (*SetLED, error) {
+	index, err := optionalInteger(b, 'I')
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SetLED{Index: index}
+
+	components := []struct {
+		letter byte
+		value  *float64
+	}{{'R', &c.Red}, {'U', &c.Green}, {'B', &c.Blue}, {'W', &c.White}}
+
+	for _, component := range components {
+		if pwm, ok := transform.Parameter(b, component.letter); ok {
+			*component.value = pwm / PWM_MAXIMUM
+		}
+	}
+
+	if pwm, ok := transform.Parameter(b, 'P'); ok {
+		c.Brightness = Float(pwm / PWM_MAXIMUM)
+	}
+
+	return c, c.validate()
+}
+
+//#endregion
+//#region private functions
+
+// validateFraction returns an error if the value isn't between 0 and 1.
+func validateFraction(name string, value float64) error {
+	if !(value >= 0 && value <= 1) {
+		return fmt.Errorf("the %s must be between 0 and 1: %v", name, value)
+	}
+
+	return nil
+}
+
+// scale converts a fraction to a maximum, rounded to an integer if the maximum is greater than one.
+func scale(fraction float64, maximum float64) float64 {
+	if maximum > 1 {
+		return math.Round(fraction * maximum)
+	}
+
+	return roundFraction(fraction * maximum)
+}
+
+// formatFraction returns a fraction with three decimals at most, like "0.502".
+func formatFraction(value float64) string {
+	return strconv.FormatFloat(roundFraction(value), 'f', -1, 64)
+}
+
+// roundFraction rounds a value to three decimals.
+func roundFraction(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+//#endregion
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/dialect"
+)
+
+func TestDialectCommander(t *testing.T) {
+	dialects := map[string]*dialect.Dialect{}
+	for _, name := range dialect.Names() {
+		d, err := dialect.Get(name)
+		if err != nil {
+			t.Fatalf("failed to get dialect %s: %v", name, err)
+		}
+		dialects[name] = d
+	}
+
+	cases := map[string]struct {
+		command DialectCommander
+		block   string
+		lines   map[string]string
+	}{
+		"fan": {
+			Fan(1, 0.5), "M106 P1 S128",
+			map[string]string{"marlin": "M106 P1 S128", "reprapfirmware": "M106 P1 S0.5", "klipper": "", "grbl": ""},
+		},
+		"part cooling fan": {
+			FanPWM(0, 255), "M106 P0 S255",
+			map[string]string{"marlin": "M106 P0 S255", "reprapfirmware": "M106 P0 S1", "klipper": "M106 S255", "grbl": ""},
+		},
+		"fan off": {
+			&SetFanSpeed{}, "M107",
+			map[string]string{"marlin": "M107", "reprapfirmware": "M107", "klipper": "M107"},
+		},
+		"pin": {
+			&SetPin{Pin: 13, Value: 1}, "M42 P13 S255",
+			map[string]string{"marlin": "M42 P13 S255", "reprapfirmware": "M42 P13 S1", "klipper": ""},
+		},
+		"led": {
+			&SetLED{Red: 1, Green: 0.5, Name: "strip"}, "M150 R255 U128 B0 W0",
+			map[string]string{"marlin": "M150 R255 U128 B0 W0", "klipper": "SET_LED LED=strip RED=1 GREEN=0.5 BLUE=0 WHITE=0", "grbl": ""},
+		},
+		"led pixel": {
+			&SetLED{Blue: 1, Brightness: Float(0.5), Index: Int(0), Name: "strip"}, "M150 R0 U0 B255 W0 P128 I0",
+			map[string]string{"reprapfirmware": "M150 R0 U0 B255 W0 P128 I0", "klipper": "SET_LED LED=strip RED=0 GREEN=0 BLUE=0.5 WHITE=0 INDEX=1"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := tc.command.Block()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if b.String() != tc.block {
+				t.Errorf("got block %q, want %q", b.String(), tc.block)
+			}
+
+			for d, want := range tc.lines {
+				got, err := tc.command.Line(dialects[d])
+				if want == "" {
+					if err == nil {
+						t.Errorf("got %q in %s, want error not nil", got, d)
+					}
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("got error %v in %s, want error nil", err, d)
+					continue
+				}
+
+				if got != want {
+					t.Errorf("got %q in %s, want %q", got, d, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpret_peripherals(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		want   Commander
+	}{
+		"fan":            {"M106 P1 S51", &SetFanSpeed{Fan: Int(1), Speed: 0.2}},
+		"fan full speed": {"M106", &SetFanSpeed{Speed: 1}},
+		"fan off":        {"M107 P2", &SetFanSpeed{Fan: Int(2)}},
+		"pin":            {"M42 P13 S255", &SetPin{Pin: 13, Value: 1}},
+		"led":            {"M150 R255 B51 I3", &SetLED{Red: 1, Blue: 0.2, Index: Int(3)}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Interpret(parseBlock(t, tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+
+	for _, source := range []string{"M106 S300", "M42 S255", "M150 R-1"} {
+		if _, err := Interpret(parseBlock(t, source)); err == nil {
+			t.Errorf("got error nil with %s, want error not nil", source)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+//#region sd operation
+
+// SDOperation identifies an operation of the SD card, from M20 to M33.
+type SDOperation int
+
+const (
+	// SDList lists the files of the SD card, M20.
+	SDList SDOperation = iota
+
+	// SDInit initializes the SD card, M21.
+	SDInit
+
+	// SDRelease releases the SD card so it can be removed, M22.
+	SDRelease
+
+	// SDSelect selects a file to print, M23.
+	SDSelect
+
+	// SDStart starts or resumes the print of the file selected, M24.
+	SDStart
+
+	// SDPause pauses the print of the file selected, M25.
+	SDPause
+
+	// SDSetPosition sets the position in bytes of the print in the file selected, M26.
+	SDSetPosition
+
+	// SDReportStatus reports the progress of the print, or reports it every interval of seconds, M27.
+	SDReportStatus
+
+	// SDBeginWrite starts to write the following lines to a file, M28.
+	SDBeginWrite
+
+	// SDEndWrite stops to write lines to the file, M29.
+	SDEndWrite
+
+	// SDDelete deletes a file, M30 with the name of the file.
+	SDDelete
+
+	// SDReportPrintTime reports the time of the last print, M31.
+	SDReportPrintTime
+
+	// SDSelectAndStart selects a file and starts to print it, M32.
+	SDSelectAndStart
+
+	// SDLongName reports the long name of a file from its short name, M33.
+	SDLongName
+)
+
+// Command returns the command of the operation, like "M23".
+func (o SDOperation) Command() string {
+	return "M" + strconv.Itoa(20+int(o))
+}
+
+// HasName returns true if the operation requires the name of a file.
+func (o SDOperation) HasName() bool {
+	switch o {
+	case SDSelect, SDBeginWrite, SDDelete, SDSelectAndStart, SDLongName:
+		return true
+	}
+
+	return false
+}
+
+// HasValue returns true if the operation accepts a value in S, the position of M24 and M26 or the interval of M27.
+func (o SDOperation) HasValue() bool {
+	return o == SDStart || o == SDSetPosition || o == SDReportStatus
+}
+
+//#endregion
+//#region sd command
+
+// SDCommand is an operation of the SD card, from M20 to M33.
+//
+// The firmwares write the name of the file after the command, like "M23 /model.gco", which isn't a block,
+// so Line returns that text and Block returns the name as a string address, like M23 P"/model.gco".
+type SDCommand struct {
+	// Operation is the operation of the SD card.
+	Operation SDOperation
+
+	// Name is the name of the file of the operations that require it, like "/model.gco".
+	Name string
+
+	// Value is the S of the operations that accept it, nil if it isn't written.
+	Value *int
+}
+
+// PauseSD returns the pause of the print of the SD card, M25.
+func PauseSD() *SDCommand {
+	return &SDCommand{Operation: SDPause}
+}
+
+// ResumeSD returns the start or the resume of the print of the SD card, M24.
+func ResumeSD() *SDCommand {
+	return &SDCommand{Operation: SDStart}
+}
+
+// PrintFile returns the selection of a file and the start of its print, M32.
+func PrintFile(name string) *SDCommand {
+	return &SDCommand{Operation: SDSelectAndStart, Name: name}
+}
+
+// Block returns a new block with the operation, with the name of the file as the string address of P.
+func (c *SDCommand) Block() (block.Blocker, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	command := c.Operation.Command()
+	if c.Operation.HasName() {
+		command += " P\"" + c.Name + "\""
+	}
+
+	return build(command, word{'S', integerValue(c.Value)})
+}
+
+// Line returns the text of the operation in a dialect, with the name of the file after the command, like "M23 /model.gco".
+//
+// It returns an error if the dialect doesn't support the command of the operation.
+func (c *SDCommand) Line(d *dialect.Dialect) (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	command := c.Operation.Command()
+	if !d.Supports(command) {
+		return "", fmt.Errorf("failed to create SD command, the dialect %s doesn't support %s", d.Name, command)
+	}
+
+	if c.Operation.HasName() {
+		return command + " " + c.Name, nil
+	}
+
+	if c.Value != nil {
+		return command + " S" + strconv.Itoa(*c.Value), nil
+	}
+
+	return command, nil
+}
+
+// validate returns an error if the operation is unknown, or the name or the value doesn't match the operation.
+func (c *SDCommand) validate() error {
+	if c.Operation < SDList || c.Operation > SDLongName {
+		return fmt.Errorf("failed to create SD command, unknown operation %d", c.Operation)
+	}
+
+	command := c.Operation.Command()
+
+	if c.Operation.HasName() {
+		if c.Name == "" || strings.ContainsAny(c.Name, "\";\r\n") {
+			return fmt.Errorf("failed to create %s, the name of the file can't be empty nor contain quotes, semicolons or line breaks: %q", command, c.Name)
+		}
+	} else if c.Name != "" {
+		return fmt.Errorf("failed to create %s, it doesn't accept the name of a file", command)
+	}
+
+	if c.Value != nil && (!c.Operation.HasValue() || *c.Value < 0) {
+		return fmt.Errorf("failed to create %s, it doesn't accept the value %d", command, *c.Value)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region interpret line
+
+// InterpretLine returns the typed command of a line as the firmwares receive it, so the operations of the SD card
+// with the name of the file after the command, like "M23 /model.gco", are interpreted too.
+// The rest of the lines are parsed as blocks and interpreted with Interpret.
+//
+// It returns an error if the line isn't a valid block or some parameter has an invalid value.
+func InterpretLine(line string) (Commander, error) {
+	line = strings.TrimSpace(line)
+
+	fields := strings.SplitN(line, " ", 2)
+	for o := SDList; o <= SDLongName; o++ {
+		if !o.HasName() || !strings.EqualFold(fields[0], o.Command()) || len(fields) < 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(fields[1])
+		if strings.HasPrefix(name, "P\"") {
+			break
+		}
+
+		c := &SDCommand{Operation: o, Name: name}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("failed to interpret line %s: %w", line, err)
+		}
+		return c, nil
+	}
+
+	b, err := gcodeblock.Parse(line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpret line %s: %w", line, err)
+	}
+
+	return Interpret(b)
+}
+
+//#endregion
+//#region private functions
+
+// interpretSDCommand interprets the operations of the SD card, the name of the file is the string address of P.
+// It returns nil if the block isn't an operation of the SD card, like M30 without the name of a file, that ends a program.
+func interpretSDCommand(b block.Blocker, o SDOperation) (*SDCommand, error) {
+	c := &SDCommand{Operation: o}
+
+	for _, p := range b.Parameters() {
+		if p.Word() != 'P' {
+			continue
+		}
+
+		if name, ok := p.(gcode.AddressableGcoder[string]); ok {
+			c.Name = strings.Trim(name.Address(), "\"")
+		}
+	}
+
+	if o == SDDelete && c.Name == "" {
+		return nil, nil
+	}
+
+	if o.HasValue() {
+		value, err := optionalInteger(b, 'S')
+		if err != nil {
+			return nil, err
+		}
+		c.Value = value
+	}
+
+	return c, c.validate()
+}
+
+//#endregion
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/dialect"
+)
+
+func TestSDCommand(t *testing.T) {
+	marlin, err := dialect.Get("marlin")
+	if err != nil {
+		t.Fatalf("failed to get dialect marlin: %v", err)
+	}
+
+	cases := map[string]struct {
+		command *SDCommand
+		block   string
+		line    string
+	}{
+		"list":     {&SDCommand{Operation: SDList}, "M20", "M20"},
+		"select":   {&SDCommand{Operation: SDSelect, Name: "/model.gco"}, `M23 P"/model.gco"`, "M23 /model.gco"},
+		"resume":   {ResumeSD(), "M24", "M24"},
+		"pause":    {PauseSD(), "M25", "M25"},
+		"position": {&SDCommand{Operation: SDSetPosition, Value: Int(1024)}, "M26 S1024", "M26 S1024"},
+		"report":   {&SDCommand{Operation: SDReportStatus, Value: Int(5)}, "M27 S5", "M27 S5"},
+		"delete":   {&SDCommand{Operation: SDDelete, Name: "old.gco"}, `M30 P"old.gco"`, "M30 old.gco"},
+		"print":    {PrintFile("my model.gco"), `M32 P"my model.gco"`, "M32 my model.gco"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := tc.command.Block()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if b.String() != tc.block {
+				t.Errorf("got block %q, want %q", b.String(), tc.block)
+			}
+
+			line, err := tc.command.Line(marlin)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if line != tc.line {
+				t.Errorf("got line %q, want %q", line, tc.line)
+			}
+
+			// the block and the line are interpreted as the same command
+			for _, source := range []string{tc.block, tc.line} {
+				got, err := InterpretLine(source)
+				if err != nil {
+					t.Fatalf("got error %v interpreting %s, want error nil", err, source)
+				}
+
+				if !reflect.DeepEqual(got, tc.command) {
+					t.Errorf("got %#v interpreting %s, want %#v", got, source, tc.command)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := map[string]*SDCommand{
+			"unknown operation": {Operation: SDLongName + 1},
+			"without name":      {Operation: SDSelect},
+			"name with quotes":  {Operation: SDSelect, Name: `a"b`},
+			"unexpected name":   {Operation: SDPause, Name: "model.gco"},
+			"unexpected value":  {Operation: SDList, Value: Int(1)},
+			"negative value":    {Operation: SDSetPosition, Value: Int(-1)},
+		}
+
+		for name, c := range invalid {
+			if _, err := c.Block(); err == nil {
+				t.Errorf("got error nil with %s, want error not nil", name)
+			}
+		}
+	})
+
+	t.Run("end of program", func(t *testing.T) {
+		got, err := InterpretLine("M30")
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		if _, ok := got.(*Raw); !ok {
+			t.Errorf("got %#v, want M30 without name as raw", got)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		grbl, err := dialect.Get("grbl")
+		if err != nil {
+			t.Fatalf("failed to get dialect grbl: %v", err)
+		}
+
+		if _, err := PauseSD().Line(grbl); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/state"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region temperature commander
+
+// WaitMode defines if a temperature command waits for the heater to reach its target.
+type WaitMode int
+
+const (
+	// WaitNone doesn't wait, like M104, M140 and M141.
+	WaitNone WaitMode = iota
+
+	// WaitHeating waits for the heater to heat up to its target but not to cool down, like M109 with S.
+	WaitHeating
+
+	// WaitHeatingOrCooling waits for the heater to reach its target heating up or cooling down, like M109 with R.
+	WaitHeatingOrCooling
+)
+
+// TemperatureCommander is a temperature command, implemented by SetHotendTemp, SetBedTemp and SetChamberTemp,
+// so the callers recognize any of them asserting the result of Interpret.
+type TemperatureCommander interface {
+	Commander
+
+	// Kind returns the kind of the heater commanded
+	Kind() state.HeaterKind
+
+	// Target returns the target in degrees Celsius
+	Target() float64
+
+	// Mode returns how the command waits for the heater
+	Mode() WaitMode
+}
+
+// Heat returns the temperature command that sets the target of a heater and waits like the mode requires,
+// so the callers don't choose between M104, M109, M140, M190, M141 and M191 nor between S and R.
+// The tool of a hotend is always written, like "M109 S200 T1".
+//
+// It returns an error if the heater or the mode is unknown or the index of the hotend is negative.
+func Heat(heater state.Heater, temperature float64, mode WaitMode) (TemperatureCommander, error) {
+	if mode < WaitNone || mode > WaitHeatingOrCooling {
+		return nil, fmt.Errorf("failed to heat %s, unknown wait mode %d", heater, mode)
+	}
+
+	wait, cooling := mode != WaitNone, mode == WaitHeatingOrCooling
+
+	switch heater.Kind {
+	case state.HeaterHotend:
+		if heater.Index < 0 {
+			return nil, fmt.Errorf("failed to heat %s, the tool can't be negative", heater)
+		}
+		return &SetHotendTemp{Temperature: temperature, Tool: Int(heater.Index), Wait: wait, Cooling: cooling}, nil
+	case state.HeaterBed:
+		return &SetBedTemp{Temperature: temperature, Wait: wait, Cooling: cooling}, nil
+	case state.HeaterChamber:
+		return &SetChamberTemp{Temperature: temperature, Wait: wait, Cooling: cooling}, nil
+	}
+
+	return nil, fmt.Errorf("failed to heat %s, unknown heater", heater)
+}
+
+//#endregion
+//#region hotend temperature
+
+// SetHotendTemp is the target of a hotend, M104, or the target and the wait until the hotend reaches it, M109.
+type SetHotendTemp struct {
+	// Temperature is the target in degrees Celsius.
+	Temperature float64
+
+	// Tool is the index of the hotend, nil for the active one.
+	Tool *int
+
+	// Wait is true for M109.
+	Wait bool
+
+	// Cooling is true if M109 waits for the hotend to cool down too, written with R instead of S.
+	Cooling bool
+}
+
+// Block returns a new block with the target, M109 if it waits or M104 otherwise.
+func (c *SetHotendTemp) Block() (block.Blocker, error) {
+	return temperatureBlock("M104", "M109", c.Temperature, c.Mode(), c.Cooling, word{'T', integerValue(c.Tool)})
+}
+
+// Kind returns state.HeaterHotend.
+func (c *SetHotendTemp) Kind() state.HeaterKind {
+	return state.HeaterHotend
+}
+
+// Target returns the target in degrees Celsius.
+func (c *SetHotendTemp) Target() float64 {
+	return c.Temperature
+}
+
+// Mode returns how the command waits for the hotend.
+func (c *SetHotendTemp) Mode() WaitMode {
+	return waitMode(c.Wait, c.Cooling)
+}
+
+// interpretSetHotendTemp interprets M104 and M109.
+func interpretSetHotendTemp(b block.Blocker) (*SetHotendTemp, error) {
+	tool, err := optionalInteger(b, 'T')
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SetHotendTemp{Tool: tool, Wait: b.Command().String() == "M109"}
+	c.Temperature, c.Cooling = target(b, c.Wait)
+
+	return c, nil
+}
+
+//#endregion
+//#region bed temperature
+
+// SetBedTemp is the target of the bed, M140, or the target and the wait until the bed reaches it, M190.
+type SetBedTemp struct {
+	// Temperature is the target in degrees Celsius.
+	Temperature float64
+
+	// Wait is true for M190.
+	Wait bool
+
+	// Cooling is true if M190 waits for the bed to cool down too, written with R instead of S.
+	Cooling bool
+}
+
+// Block returns a new block with the target, M190 if it waits or M140 otherwise.
+func (c *SetBedTemp) Block() (block.Blocker, error) {
+	return temperatureBlock("M140", "M190", c.Temperature, c.Mode(), c.Cooling)
+}
+
+// Kind returns state.HeaterBed.
+func (c *SetBedTemp) Kind() state.HeaterKind {
+	return state.HeaterBed
+}
+
+// Target returns the target in degrees Celsius.
+func (c *SetBedTemp) Target() float64 {
+	return c.Temperature
+}
+
+// Mode returns how the command waits for the bed.
+func (c *SetBedTemp) Mode() WaitMode {
+	return waitMode(c.Wait, c.Cooling)
+}
+
+// interpretSetBedTemp interprets M140 and M190.
+func interpretSetBedTemp(b block.Blocker) *SetBedTemp {
+	c := &SetBedTemp{Wait: b.Command().String() == "M190"}
+	c.Temperature, c.Cooling = target(b, c.Wait)
+
+	return c
+}
+
+//#endregion
+//#region chamber temperature
+
+// SetChamberTemp is the target of the chamber, M141, or the target and the wait until the chamber reaches it, M191.
+type SetChamberTemp struct {
+	// Temperature is the target in degrees Celsius.
+	Temperature float64
+
+	// Wait is true for M191.
+	Wait bool
+
+	// Cooling is true if M191 waits for the chamber to cool down too, written with R instead of S.
+	Cooling bool
+}
+
+// Block returns a new block with the target, M191 if it waits or M141 otherwise.
+func (c *SetChamberTemp) Block() (block.Blocker, error) {
+	return temperatureBlock("M141", "M191", c.Temperature, c.Mode(), c.Cooling)
+}
+
+// Kind returns state.HeaterChamber.
+func (c *SetChamberTemp) Kind() state.HeaterKind {
+	return state.HeaterChamber
+}
+
+// Target returns the target in degrees Celsius.
+func (c *SetChamberTemp) Target() float64 {
+	return c.Temperature
+}
+
+// Mode returns how the command waits for the chamber.
+func (c *SetChamberTemp) Mode() WaitMode {
+	return waitMode(c.Wait, c.Cooling)
+}
+
+// interpretSetChamberTemp interprets M141 and M191.
+func interpretSetChamberTemp(b block.Blocker) *SetChamberTemp {
+	c := &SetChamberTemp{Wait: b.Command().String() == "M191"}
+	c.Temperature, c.Cooling = target(b, c.Wait)
+
+	return c
+}
+
+//#endregion
+//#region private functions
+
+// temperatureBlock returns a new block with the command that sets the target, or the one that waits too,
+// with the target in S, or in R if the command waits heating or cooling.
+// It returns an error if the command cools down without waiting, because only the commands that wait accept R.
+func temperatureBlock(set string, wait string, temperature float64, mode WaitMode, cooling bool, words ...word) (block.Blocker, error) {
+	if cooling && mode == WaitNone {
+		return nil, fmt.Errorf("failed to create %s, only %s waits for the heater to cool down", set, wait)
+	}
+
+	command, letter := set, byte('S')
+	if mode != WaitNone {
+		command = wait
+	}
+	if mode == WaitHeatingOrCooling {
+		letter = 'R'
+	}
+
+	return build(command, append([]word{{letter, Float(temperature)}}, words...)...)
+}
+
+// waitMode returns the wait mode of the fields of a temperature command.
+func waitMode(wait bool, cooling bool) WaitMode {
+	switch {
+	case !wait:
+		return WaitNone
+	case cooling:
+		return WaitHeatingOrCooling
+	}
+
+	return WaitHeating
+}
+
+// target returns the temperature of S, or of R if the command waits and S isn't written, and true if it is R.
+// A block without temperature turns the heater off.
+func target(b block.Blocker, wait bool) (float64, bool) {
+	if value, ok := transform.Parameter(b, 'S'); ok {
+		return value, false
+	}
+
+	if wait {
+		if value, ok := transform.Parameter(b, 'R'); ok {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+//#endregion
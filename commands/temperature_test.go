@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestHeat(t *testing.T) {
+	cases := map[string]struct {
+		heater      state.Heater
+		temperature float64
+		mode        WaitMode
+		want        string
+	}{
+		"hotend":          {state.Heater{Kind: state.HeaterHotend}, 200, WaitNone, "M104 S200 T0"},
+		"wait hotend":     {state.Heater{Kind: state.HeaterHotend, Index: 1}, 210, WaitHeating, "M109 S210 T1"},
+		"cool hotend":     {state.Heater{Kind: state.HeaterHotend, Index: 1}, 50, WaitHeatingOrCooling, "M109 R50 T1"},
+		"bed":             {state.Heater{Kind: state.HeaterBed}, 60, WaitNone, "M140 S60"},
+		"wait bed":        {state.Heater{Kind: state.HeaterBed}, 60, WaitHeating, "M190 S60"},
+		"chamber":         {state.Heater{Kind: state.HeaterChamber}, 40, WaitNone, "M141 S40"},
+		"wait chamber":    {state.Heater{Kind: state.HeaterChamber}, 40, WaitHeatingOrCooling, "M191 R40"},
+		"chamber heating": {state.Heater{Kind: state.HeaterChamber}, 45.5, WaitHeating, "M191 S45.5"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := Heat(tc.heater, tc.temperature, tc.mode)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if c.Kind() != tc.heater.Kind || c.Target() != tc.temperature || c.Mode() != tc.mode {
+				t.Errorf("got kind %v target %v mode %v, want kind %v target %v mode %v",
+					c.Kind(), c.Target(), c.Mode(), tc.heater.Kind, tc.temperature, tc.mode)
+			}
+
+			b, err := c.Block()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if b.String() != tc.want {
+				t.Errorf("got %q, want %q", b.String(), tc.want)
+			}
+
+			// the block is recognized as the same command
+			interpreted, err := Interpret(b)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			temperature, ok := interpreted.(TemperatureCommander)
+			if !ok {
+				t.Fatalf("got %#v, want a temperature command", interpreted)
+			}
+
+			if temperature.Kind() != tc.heater.Kind || temperature.Target() != tc.temperature || temperature.Mode() != tc.mode {
+				t.Errorf("got kind %v target %v mode %v interpreted, want kind %v target %v mode %v",
+					temperature.Kind(), temperature.Target(), temperature.Mode(), tc.heater.Kind, tc.temperature, tc.mode)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := map[string]struct {
+			heater state.Heater
+			mode   WaitMode
+		}{
+			"negative tool":  {state.Heater{Kind: state.HeaterHotend, Index: -1}, WaitNone},
+			"unknown heater": {state.Heater{Kind: state.HeaterChamber + 1}, WaitNone},
+			"unknown mode":   {state.Heater{Kind: state.HeaterBed}, WaitHeatingOrCooling + 1},
+		}
+
+		for name, tc := range invalid {
+			if _, err := Heat(tc.heater, 200, tc.mode); err == nil {
+				t.Errorf("got error nil with %s, want error not nil", name)
+			}
+		}
+	})
+}
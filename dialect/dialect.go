@@ -0,0 +1,298 @@
+// dialect package describes the gcode accepted by each firmware, as data-driven profiles.
+//
+// A dialect lists the commands and the words that a firmware supports, its styles of comments, its policy of checksums
+// and the limits of the machine. The dialects of the main firmwares are embedded in the package and retrieved by name with Get,
+// and the dialects of the users are loaded from JSON documents with Load.
+//
+// A dialect configures the rest of the library: the parser with ParseOptions, the export of the documents with WriterOptions,
+// and the validation of the preflight package, whose profiles are created from a dialect with preflight.NewProfile.
+package dialect
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+const (
+	// COMMENT_SEMICOLON is the style of the comments that start with a semicolon and end with the line, like "; comment".
+	COMMENT_SEMICOLON = "semicolon"
+
+	// COMMENT_PARENTHESES is the style of the comments enclosed in parentheses, like "(comment)".
+	COMMENT_PARENTHESES = "parentheses"
+
+	// CHECKSUM_NONE is the policy of the firmwares that don't accept line numbers nor checksums.
+	CHECKSUM_NONE = "none"
+
+	// CHECKSUM_OPTIONAL is the policy of the firmwares that verify the checksums if the blocks have them.
+	CHECKSUM_OPTIONAL = "optional"
+
+	// CHECKSUM_REQUIRED is the policy of the firmwares that require a line number and a checksum in every block.
+	CHECKSUM_REQUIRED = "required"
+)
+
+// profiles are the dialects embedded, one JSON document per dialect named as the dialect.
+//
+//go:embed profiles/*.json
+var profiles embed.FS
+
+//#region dialect struct
+
+// Dialect describes the gcode accepted by a firmware and the limits of the machine that runs it.
+//
+// The zero value of each limit doesn't limit.
+type Dialect struct {
+	// Name identifies the dialect, like "marlin".
+	Name string `json:"name"`
+
+	// Description describes the firmware.
+	Description string `json:"description"`
+
+	// Commands are the commands supported, like "G1" or "M104". If it is empty, any command is supported.
+	Commands []string `json:"commands"`
+
+	// Words are the letters of the commands, the axes and the parameters accepted, like "GMXYZEF". The '*' of the checksums is included.
+	// If it is empty, the words are gcode.DEFAULT_WORDS.
+	Words string `json:"words"`
+
+	// Comments are the styles of the comments supported, COMMENT_SEMICOLON or COMMENT_PARENTHESES.
+	// If it is empty, the comments start with a semicolon.
+	Comments []string `json:"comments"`
+
+	// Checksum is the policy of line numbers and checksums, CHECKSUM_NONE, CHECKSUM_OPTIONAL or CHECKSUM_REQUIRED.
+	// If it is empty, the checksums are optional.
+	Checksum string `json:"checksum"`
+
+	// ExtendedCommands is true if the firmware accepts the extended commands of Klipper, like "EXCLUDE_OBJECT_START NAME=part".
+	ExtendedCommands bool `json:"extended_commands"`
+
+	// MacroStatements is true if the firmware accepts the custom macros B of Fanuc, like "IF [#1 GT 0] GOTO 10" or "G1 X#24".
+	MacroStatements bool `json:"macro_statements"`
+
+	// PWMScale is the value of S at full duty cycle in M106 and M42, like 255 or 1. If it is zero, it is 255.
+	PWMScale float64 `json:"pwm_scale"`
+
+	// FanIndex is true if M106 and M107 select the fan with P. Otherwise they only control the part cooling fan.
+	FanIndex bool `json:"fan_index"`
+
+	// MaxHotendTemperature is the maximum target of the hotends.
+	MaxHotendTemperature float64 `json:"max_hotend_temperature"`
+
+	// MaxBedTemperature is the maximum target of the bed.
+	MaxBedTemperature float64 `json:"max_bed_temperature"`
+
+	// Min is the lower corner of the working area, indexed as X, Y and Z.
+	Min [3]float64 `json:"min"`
+
+	// Max is the upper corner of the working area, indexed as X, Y and Z. If all its values are zero, the area isn't limited.
+	Max [3]float64 `json:"max"`
+
+	// MaxFeedrates is the maximum speed of each axis in units per second, indexed as X, Y, Z and E.
+	MaxFeedrates [4]float64 `json:"max_feedrates"`
+
+	// StartCommands are the commands that must be executed before the first extrusion, like "G28".
+	StartCommands []string `json:"start_commands"`
+}
+
+// Supports returns true if the command is supported by the dialect, like "G1".
+func (d *Dialect) Supports(command string) bool {
+	if len(d.Commands) == 0 {
+		return true
+	}
+
+	for _, c := range d.Commands {
+		if c == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PWMMaximum returns the value of S at full duty cycle in M106 and M42, PWMScale or 255 if it is zero.
+func (d *Dialect) PWMMaximum() float64 {
+	if d.PWMScale == 0 {
+		return 255
+	}
+
+	return d.PWMScale
+}
+
+// SupportsComments returns true if the dialect supports the style of comments, COMMENT_SEMICOLON or COMMENT_PARENTHESES.
+func (d *Dialect) SupportsComments(style string) bool {
+	if len(d.Comments) == 0 {
+		return style == COMMENT_SEMICOLON
+	}
+
+	for _, c := range d.Comments {
+		if c == style {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WordRegistry returns a new registry that accepts the words of the dialect.
+func (d *Dialect) WordRegistry() (*gcode.WordRegistry, error) {
+	if d.Words == "" {
+		return gcode.DefaultWordRegistry(), nil
+	}
+
+	registry, err := gcode.NewWordRegistry([]byte(d.Words)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create word registry of dialect %s: %w", d.Name, err)
+	}
+
+	return registry, nil
+}
+
+// ParseOptions returns the options that parse a document in the dialect: the words of the blocks are restricted to the ones accepted,
+// and the extended commands and the macro statements are kept if the dialect accepts them.
+func (d *Dialect) ParseOptions() ([]document.ParseConfigurationCallbackable, error) {
+	registry, err := d.WordRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	return []document.ParseConfigurationCallbackable{
+		func(config document.ParseConfigurer) error {
+			return config.SetBlockOptions(func(config block.BlockParserConfigurer) error {
+				return config.SetWordRegistry(registry)
+			})
+		},
+		func(config document.ParseConfigurer) error {
+			return config.SetExtendedCommands(d.ExtendedCommands)
+		},
+		func(config document.ParseConfigurer) error {
+			return config.SetMacroStatements(d.MacroStatements)
+		},
+	}, nil
+}
+
+// WriterOptions returns the options that export a document for the dialect: the checksums are omitted if the firmware doesn't accept them,
+// and the comments if it doesn't support the comments with semicolon, the only style exported.
+//
+// The policy CHECKSUM_REQUIRED requires a line number and a checksum in every block, so the document must be renumbered before,
+// with Document.Renumber.
+func (d *Dialect) WriterOptions() []document.WriterConfigurationCallbackable {
+	return []document.WriterConfigurationCallbackable{
+		func(config document.WriterConfigurer) error {
+			return config.SetChecksums(d.Checksum != CHECKSUM_NONE)
+		},
+		func(config document.WriterConfigurer) error {
+			return config.SetComments(d.SupportsComments(COMMENT_SEMICOLON))
+		},
+	}
+}
+
+// validate returns an error if some value of the dialect is invalid.
+func (d *Dialect) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("the name can't be empty")
+	}
+
+	for _, c := range d.Commands {
+		if len(c) < 2 || !strings.ContainsAny(c[:1], "GMT") {
+			return fmt.Errorf("the command %q isn't a G, M or T command", c)
+		}
+	}
+
+	if _, err := d.WordRegistry(); err != nil {
+		return err
+	}
+
+	for _, c := range d.Comments {
+		if c != COMMENT_SEMICOLON && c != COMMENT_PARENTHESES {
+			return fmt.Errorf("unknown style of comments %q", c)
+		}
+	}
+
+	switch d.Checksum {
+	case "", CHECKSUM_NONE, CHECKSUM_OPTIONAL, CHECKSUM_REQUIRED:
+	default:
+		return fmt.Errorf("unknown policy of checksums %q", d.Checksum)
+	}
+
+	if d.MaxHotendTemperature < 0 || d.MaxBedTemperature < 0 {
+		return fmt.Errorf("the maximum temperatures can't be negative")
+	}
+
+	if d.PWMScale < 0 || math.IsInf(d.PWMScale, 0) {
+		return fmt.Errorf("the scale of the PWM can't be negative nor infinite")
+	}
+
+	for i := range d.Min {
+		if d.Min[i] > d.Max[i] && d.Max != [3]float64{} {
+			return fmt.Errorf("the lower corner of the working area exceeds the upper corner")
+		}
+	}
+
+	for _, f := range d.MaxFeedrates {
+		if f < 0 {
+			return fmt.Errorf("the maximum feedrates can't be negative")
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region package functions
+
+// Load reads a dialect from a JSON document, with the fields named like the tags of Dialect, like "max_bed_temperature".
+//
+// It returns an error if the document isn't valid JSON, has unknown fields, or some value is invalid.
+func Load(source io.Reader) (*Dialect, error) {
+	decoder := json.NewDecoder(source)
+	decoder.DisallowUnknownFields()
+
+	d := &Dialect{}
+	if err := decoder.Decode(d); err != nil {
+		return nil, fmt.Errorf("failed to load dialect: %w", err)
+	}
+
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load dialect %s: %w", d.Name, err)
+	}
+
+	return d, nil
+}
+
+// Get returns a new instance of an embedded dialect, so it can be modified without affecting the rest.
+// The names are the ones returned by Names, like "marlin".
+//
+// It returns an error if there isn't an embedded dialect with the name.
+func Get(name string) (*Dialect, error) {
+	file, err := profiles.Open(path.Join("profiles", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dialect, unknown name %q", name)
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Names returns the names of the embedded dialects, sorted.
+func Names() []string {
+	entries, _ := profiles.ReadDir("profiles")
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//#endregion
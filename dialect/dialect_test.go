@@ -0,0 +1,177 @@
+package dialect
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestGet(t *testing.T) {
+	names := Names()
+	if want := []string{"fanuc", "grbl", "klipper", "marlin", "reprapfirmware"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got names %v, want %v", names, want)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			d, err := Get(name)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if d.Name != name {
+				t.Errorf("got name %s, want %s", d.Name, name)
+			}
+
+			if !d.Supports("G1") || d.Supports("M9999") {
+				t.Errorf("got G1 unsupported or M9999 supported by %s", name)
+			}
+		})
+	}
+
+	t.Run("independent instances", func(t *testing.T) {
+		first, _ := Get("marlin")
+		first.Commands[0] = "M9999"
+
+		second, _ := Get("marlin")
+		if second.Commands[0] == "M9999" {
+			t.Errorf("got the instances shared, want independent ones")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		for _, name := range []string{"haas", "", "../dialect"} {
+			if _, err := Get(name); err == nil {
+				t.Errorf("got error nil for %q, want error not nil", name)
+			}
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		valid  bool
+	}{
+		"minimal":          {`{"name": "custom"}`, true},
+		"complete":         {`{"name": "custom", "commands": ["G0", "G1", "T0"], "words": "GTXYF", "comments": ["parentheses"], "checksum": "required", "max_bed_temperature": 100, "max": [200, 200, 180], "start_commands": ["G28"]}`, true},
+		"invalid json":     {`{"name": `, false},
+		"unknown field":    {`{"name": "custom", "firmware": "marlin"}`, false},
+		"without name":     {`{"commands": ["G1"]}`, false},
+		"invalid command":  {`{"name": "custom", "commands": ["X1"]}`, false},
+		"invalid word":     {`{"name": "custom", "words": "G1"}`, false},
+		"unknown comments": {`{"name": "custom", "comments": ["hash"]}`, false},
+		"unknown checksum": {`{"name": "custom", "checksum": "always"}`, false},
+		"negative maximum": {`{"name": "custom", "max_hotend_temperature": -1}`, false},
+		"inverted area":    {`{"name": "custom", "min": [10, 0, 0], "max": [5, 5, 5]}`, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tc.source))
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestDialect_ParseOptions(t *testing.T) {
+
+	cases := map[string]struct {
+		dialect string
+		source  string
+		valid   bool
+		lines   int
+	}{
+		"marlin":                   {"marlin", "G1 X10 E1\nM104 S200\n", true, 2},
+		"word rejected":            {"grbl", "G1 X10 E1\n", false, 0},
+		"extended command":         {"klipper", "EXCLUDE_OBJECT_START NAME=part\nG1 X10 E1\n", true, 2},
+		"extended command invalid": {"marlin", "EXCLUDE_OBJECT_START NAME=part\n", false, 0},
+		"macro statements":         {"fanuc", "O1000\n#1 = 10\nG1 X#1 F100\nM30\n", true, 4},
+		"macro statement invalid":  {"marlin", "#1 = 10\n", false, 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Get(tc.dialect)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			options, err := d.ParseOptions()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			doc, err := document.Parse(strings.NewReader(tc.source), options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got := len(doc.Lines()); got != tc.lines {
+				t.Errorf("got %d lines, want %d", got, tc.lines)
+			}
+		})
+	}
+}
+
+func TestDialect_WriterOptions(t *testing.T) {
+
+	cases := map[string]struct {
+		dialect string
+		want    string
+	}{
+		"marlin":           {"marlin", "N1 G28*18 ;home\n"},
+		"grbl":             {"grbl", "N1 G28 ;home\n"},
+		"only parentheses": {"custom", "N1 G28*18\n"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &Dialect{Name: "custom", Comments: []string{COMMENT_PARENTHESES}}
+			if tc.dialect != "custom" {
+				var err error
+				if d, err = Get(tc.dialect); err != nil {
+					t.Fatalf("got error %v, want error nil", err)
+				}
+			}
+
+			doc, err := document.Parse(strings.NewReader("N1 G28*18 ;home\n"))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			var buffer bytes.Buffer
+			w, err := document.NewWriter(&buffer, d.WriterOptions()...)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if err := w.WriteDocument(doc); err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got := buffer.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
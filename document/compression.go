@@ -0,0 +1,72 @@
+package document
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+//#region compression
+
+// Compression defines the compression of a gcode file.
+type Compression int
+
+const (
+	// CompressionAuto detects the compression of a file parsed by its first bytes. It is only accepted by Parse.
+	CompressionAuto Compression = iota
+
+	// CompressionNone reads or writes plain text.
+	CompressionNone
+
+	// CompressionGzip reads or writes gzip compressed text, like the .gcode.gz files stored by many hosts.
+	CompressionGzip
+)
+
+// String returns the name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case CompressionAuto:
+		return "auto"
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	}
+
+	return fmt.Sprintf("compression(%d)", int(c))
+}
+
+//#endregion
+//#region private functions
+
+// gzipMagic are the first bytes of any gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader that decompresses the source if it is required.
+//
+// The reader returned must be closed when the source is consumed.
+func decompress(source io.Reader, compression Compression) (io.ReadCloser, error) {
+	if compression == CompressionAuto {
+		buffered := bufio.NewReader(source)
+		source = buffered
+
+		compression = CompressionNone
+		if magic, _ := buffered.Peek(len(gzipMagic)); string(magic) == string(gzipMagic) {
+			compression = CompressionGzip
+		}
+	}
+
+	if compression == CompressionGzip {
+		reader, err := gzip.NewReader(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress source: %w", err)
+		}
+
+		return reader, nil
+	}
+
+	return io.NopCloser(source), nil
+}
+
+//#endregion
@@ -0,0 +1,111 @@
+package document
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+
+	source := "G28\n; comment\nN1 G1 X10 Y10*105\n"
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(source))
+	gz.Close()
+
+	cases := map[string]struct {
+		data        []byte
+		compression Compression
+		valid       bool
+	}{
+		"plain auto":        {[]byte(source), CompressionAuto, true},
+		"plain none":        {[]byte(source), CompressionNone, true},
+		"gzip auto":         {compressed.Bytes(), CompressionAuto, true},
+		"gzip forced":       {compressed.Bytes(), CompressionGzip, true},
+		"plain as gzip":     {[]byte(source), CompressionGzip, false},
+		"truncated gzip":    {compressed.Bytes()[:compressed.Len()-4], CompressionAuto, false},
+		"unknown":           {[]byte(source), Compression(9), false},
+		"empty auto source": {[]byte{}, CompressionAuto, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(bytes.NewReader(tc.data), func(config ParseConfigurer) error {
+				return config.SetCompression(tc.compression)
+			})
+
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if len(tc.data) > 0 && d.String() != source {
+				t.Errorf("got %q, want %q", d.String(), source)
+			}
+		})
+	}
+
+	t.Run("write gzip", func(t *testing.T) {
+		d, err := Parse(strings.NewReader(source))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		var output bytes.Buffer
+		w, err := NewWriter(&output, func(config WriterConfigurer) error {
+			return config.SetCompression(CompressionGzip)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := w.WriteDocument(d); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := w.Close(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if !bytes.HasPrefix(output.Bytes(), gzipMagic) {
+			t.Errorf("got %q, want a gzip stream", output.Bytes())
+		}
+
+		if w.Bytes() != int64(len(source)) {
+			t.Errorf("got %d bytes, want %d", w.Bytes(), len(source))
+		}
+
+		parsed, err := Parse(&output)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if parsed.String() != source {
+			t.Errorf("got %q, want %q", parsed.String(), source)
+		}
+	})
+
+	t.Run("invalid writer compression", func(t *testing.T) {
+		_, err := NewWriter(&bytes.Buffer{}, func(config WriterConfigurer) error {
+			return config.SetCompression(CompressionAuto)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
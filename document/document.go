@@ -0,0 +1,588 @@
+// document package models a whole gcode file as an ordered collection of blocks.
+//
+// While the block package handles a single line, the document package allows to execute
+// operations that involve all blocks of a file, like verify the integrity of each one of them.
+//
+// A document can be parsed from a reader. The lines without gcode, like the comments and the blank lines,
+// are preserved in their position, so the file can be exported again without losing them.
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+)
+
+const (
+	// LINE_FORMAT is the format used to export each block of a document, it includes the checksum and the comments.
+	LINE_FORMAT = "%l %c %p%k %m"
+
+	// LINE_ENDING is the character written at the end of each line exported.
+	LINE_ENDING = "\n"
+)
+
+//#region line struct
+
+// Line is a single line of a document, it contains a block or a text without gcode, like a comment or a blank line.
+type Line struct {
+	// Block is the block of the line, it is nil if the line doesn't contain gcode.
+	Block block.Blocker
+
+	// Text is the content of a line without gcode, like ";LAYER:2" or an empty string. It is ignored if Block isn't nil.
+	Text string
+
+	// original bytes of the line, including its line ending. It is only recorded by Parse in round-trip mode.
+	raw string
+
+	// line exported when it was parsed, used to detect if it was modified
+	snapshot string
+
+	// position of the first byte of the line in the source, it is only valid if located is true
+	offset  int64
+	located bool
+}
+
+// IsBlock returns true if the line contains a block.
+func (l Line) IsBlock() bool {
+	return l.Block != nil
+}
+
+// String returns the line exported, using LINE_FORMAT if it contains a block.
+//
+// The separators of the elements that the block hasn't are omitted, like "N1 G28*18" instead of "N1 G28 *18".
+func (l Line) String() string {
+	if l.Block != nil {
+		return formatBlock(l.Block, LINE_FORMAT)
+	}
+
+	return l.Text
+}
+
+// Original returns the bytes of the line exactly as they were read, including its line ending.
+// It returns false if the line wasn't parsed in round-trip mode.
+func (l Line) Original() (string, bool) {
+	return l.raw, l.raw != ""
+}
+
+// Modified returns true if the line is exported in a different way than when it was parsed.
+// The lines that weren't parsed in round-trip mode are always considered modified.
+func (l Line) Modified() bool {
+	return l.raw == "" || l.String() != l.snapshot
+}
+
+// Offset returns the position of the first byte of the line in the source parsed, after decompressing it.
+// It returns false if the line wasn't parsed, like the lines inserted by an edition.
+func (l Line) Offset() (int64, bool) {
+	return l.offset, l.located
+}
+
+// ending returns the line ending of the original bytes of the line, it returns false if they weren't recorded.
+func (l Line) ending() (string, bool) {
+	if l.raw == "" {
+		return "", false
+	}
+
+	if strings.HasSuffix(l.raw, "\r\n") {
+		return "\r\n", true
+	}
+
+	if strings.HasSuffix(l.raw, "\n") {
+		return "\n", true
+	}
+
+	return "", true
+}
+
+//#endregion
+//#region document struct
+
+// Document stores the lines of a gcode file in order.
+type Document struct {
+	// lines of the document, including the ones without gcode
+	lines []Line
+
+	// blocks of the document, in the same order that they must be executed
+	blocks []block.Blocker
+
+	// positions of the blocks indexed by their line number, only the first block of each line number is stored
+	byLineNumber map[uint32]int
+
+	// positions of the blocks indexed by their command, like "G1" or "M104"
+	byCommand map[string][]int
+
+	// positions of the blocks indexed by the word of their command
+	byWord map[byte][]int
+
+	// true if the document is renumbered after each edition, with the options stored
+	renumberOnEdit  bool
+	renumberOptions []RenumberConfigurationCallbackable
+}
+
+// Blocks returns the blocks of the document in order.
+//
+// The slice returned is a copy, but the blocks are shared with the document.
+func (d *Document) Blocks() []block.Blocker {
+	blocks := make([]block.Blocker, len(d.blocks))
+	copy(blocks, d.blocks)
+
+	return blocks
+}
+
+// Len returns the number of blocks of the document.
+func (d *Document) Len() int {
+	return len(d.blocks)
+}
+
+// Block returns the block in the position required, starting at zero. It returns false if the index is out of range.
+func (d *Document) Block(index int) (block.Blocker, bool) {
+	if index < 0 || index >= len(d.blocks) {
+		return nil, false
+	}
+
+	return d.blocks[index], true
+}
+
+// Lines returns all lines of the document in order, including the ones without gcode.
+//
+// The slice returned is a copy, but the blocks are shared with the document.
+func (d *Document) Lines() []Line {
+	lines := make([]Line, len(d.lines))
+	copy(lines, d.lines)
+
+	return lines
+}
+
+// LineCount returns the number of lines of the document, including the ones without gcode.
+func (d *Document) LineCount() int {
+	return len(d.lines)
+}
+
+// Line returns the line in the position required, starting at zero. It returns false if the index is out of range.
+func (d *Document) Line(index int) (Line, bool) {
+	if index < 0 || index >= len(d.lines) {
+		return Line{}, false
+	}
+
+	return d.lines[index], true
+}
+
+// Walk calls visit for each line of the document in order.
+//
+// The iteration stops when visit returns an error, and the error is returned.
+func (d *Document) Walk(visit func(index int, line Line) error) error {
+	for i, l := range d.lines {
+		if err := visit(i, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTo exports all lines of the document to w, each one followed by LINE_ENDING.
+//
+// It implements the io.WriterTo interface and returns the number of bytes written.
+// Use a Writer to export the document with other options.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	writer, err := NewWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writer.WriteDocument(d); err != nil {
+		return writer.Bytes(), err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return writer.Bytes(), err
+	}
+
+	return writer.Bytes(), nil
+}
+
+// String returns all lines of the document rendered with LINE_FORMAT, each one followed by LINE_ENDING.
+//
+// Unlike WriteTo, the original bytes of the lines parsed in round-trip mode are never used.
+func (d *Document) String() string {
+	var sb strings.Builder
+	for _, l := range d.lines {
+		sb.WriteString(l.String())
+		sb.WriteString(LINE_ENDING)
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region parse configuration
+
+// ParseConfigurer defines the options of the parsing of a document.
+type ParseConfigurer interface {
+	// Set the options used to parse each block
+	SetBlockOptions(options ...block.BlockParserConfigurationCallbackable) error
+
+	// Set if the original bytes of each line are recorded to export again the lines that aren't modified
+	SetRoundTrip(enabled bool) error
+
+	// Set the compression of the source
+	SetCompression(compression Compression) error
+
+	// Set the callback that receives the progress of the parsing
+	SetProgress(progress ProgressCallbackable) error
+
+	// Set if the extended commands of Klipper are kept as lines without gcode
+	SetExtendedCommands(enabled bool) error
+
+	// Set if the meta commands of RepRapFirmware and the lines with expressions are kept as lines without gcode
+	SetMetaCommands(enabled bool) error
+
+	// Set if the statements of the custom macros B of Fanuc are kept as lines without gcode
+	SetMacroStatements(enabled bool) error
+}
+
+// ParseConfigurationCallbackable is the signature of the callbacks used to configure the parsing.
+type ParseConfigurationCallbackable func(config ParseConfigurer) error
+
+// parseConfigurator implements ParseConfigurer.
+type parseConfigurator struct {
+	blockOptions []block.BlockParserConfigurationCallbackable
+	roundTrip    bool
+	compression  Compression
+	progress     ProgressCallbackable
+	extended     bool
+	meta         bool
+	macro        bool
+}
+
+// SetBlockOptions defines the options used to parse each block, like the hash or the case policy. Doesn't accept nil options.
+// If this method isn't called, by default the blocks are parsed with the default options of gcodeblock.Parse.
+func (pc *parseConfigurator) SetBlockOptions(options ...block.BlockParserConfigurationCallbackable) error {
+	for i, option := range options {
+		if option == nil {
+			return fmt.Errorf("failed to set block options, the option %d mustn't be nil", i)
+		}
+	}
+
+	pc.blockOptions = append(pc.blockOptions, options...)
+
+	return nil
+}
+
+// SetRoundTrip defines if the original bytes of each line are recorded, including whitespaces, case, address literals and line endings.
+// The writers with the default export options write the original bytes of the lines that weren't modified,
+// so only the lines modified are rendered again and the diffs of a post-processed file are minimal.
+// If this method isn't called, by default the lines are always rendered again.
+func (pc *parseConfigurator) SetRoundTrip(enabled bool) error {
+	pc.roundTrip = enabled
+
+	return nil
+}
+
+// SetCompression defines the compression of the source, so the compressed files can be parsed without wrapping the reader.
+// If this method isn't called, by default it is CompressionAuto, the gzip streams are detected by their first bytes.
+func (pc *parseConfigurator) SetCompression(compression Compression) error {
+	switch compression {
+	case CompressionAuto, CompressionNone, CompressionGzip:
+	default:
+		return fmt.Errorf("failed to set compression, unknown value %d", compression)
+	}
+
+	pc.compression = compression
+
+	return nil
+}
+
+// SetProgress defines the callback that receives the progress of the parsing. Doesn't accept nil.
+// The lines are counted as they are parsed, and the bytes as they are read from the source, before decompressing them.
+// The size of the source is only known for readers with a Len method, like strings.Reader, and regular files.
+// If this method isn't called, by default the progress isn't reported.
+func (pc *parseConfigurator) SetProgress(progress ProgressCallbackable) error {
+	if progress == nil {
+		return fmt.Errorf("failed to set progress, the callback mustn't be nil")
+	}
+
+	pc.progress = progress
+
+	return nil
+}
+
+// SetExtendedCommands defines if the extended commands of Klipper, like "EXCLUDE_OBJECT_START NAME=part_1" or "PRINT_START",
+// are kept as lines without gcode, with the command in their text, instead of failing the parsing.
+// An extended command starts with a name of two or more letters, digits or underscores, being the first two of them letters or underscores.
+// If this method isn't called, by default the extended commands are rejected as invalid gcode.
+func (pc *parseConfigurator) SetExtendedCommands(enabled bool) error {
+	pc.extended = enabled
+
+	return nil
+}
+
+// SetMetaCommands defines if the meta commands of RepRapFirmware, like "if", "while", "var" or "echo", and the lines with
+// expressions of the object model between braces, like "G1 X{var.x}", are kept as lines without gcode, with their indentation,
+// instead of failing the parsing. The meta package evaluates them.
+// If this method isn't called, by default the meta commands are rejected as invalid gcode.
+func (pc *parseConfigurator) SetMetaCommands(enabled bool) error {
+	pc.meta = enabled
+
+	return nil
+}
+
+// SetMacroStatements defines if the lines of the custom macros B of Fanuc that can't be parsed as blocks, like "IF [#1 GT 0] GOTO 10",
+// "#100 = 1", "G1 X#24" or "O1000", are kept as lines without gcode instead of failing the parsing. The macrob package interprets them.
+// If this method isn't called, by default the statements are rejected as invalid gcode.
+func (pc *parseConfigurator) SetMacroStatements(enabled bool) error {
+	pc.macro = enabled
+
+	return nil
+}
+
+//#endregion
+//#region parse error
+
+// ParseError is the error returned by Parse and ParseBytes when a line isn't a valid block.
+type ParseError struct {
+	// Line is the number of the line, starting at one.
+	Line int
+
+	// Err is the error of the parser of blocks.
+	Err error
+}
+
+// Error returns the error formatted with the line number.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error of the parser of blocks.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new document that contains the blocks in the order received.
+//
+// The nil blocks are ignored.
+func New(blocks ...block.Blocker) *Document {
+	d := &Document{
+		lines: make([]Line, 0, len(blocks)),
+	}
+
+	for _, b := range blocks {
+		if b != nil {
+			d.lines = append(d.lines, Line{Block: b})
+		}
+	}
+
+	d.index()
+
+	return d
+}
+
+// NewFromLines returns a new document that contains the lines in the order received, like the lines of another document.
+//
+// The lines parsed in round-trip mode keep their original bytes.
+func NewFromLines(lines ...Line) *Document {
+	d := &Document{
+		lines: make([]Line, len(lines)),
+	}
+	copy(d.lines, lines)
+
+	d.index()
+
+	return d
+}
+
+// Parse reads a whole gcode file and returns a document with all its lines.
+//
+// The blank lines and the lines that only contain a comment are preserved as text.
+// The gzip compressed sources are decompressed transparently.
+// If some line can't be parsed it returns a *ParseError with its line number, starting at one.
+func Parse(source io.Reader, options ...ParseConfigurationCallbackable) (*Document, error) {
+
+	configurator := &parseConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	counter := &countingReader{r: source}
+	total := int64(0)
+	if configurator.progress != nil {
+		total = sourceSize(source)
+	}
+
+	reader, err := decompress(counter, configurator.compression)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	p := &lineParser{
+		configurator: configurator,
+		document:     &Document{},
+		read:         func() int64 { return counter.bytes },
+		total:        total,
+	}
+
+	// the lines are read without a limit of length, like ParseBytes does
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadString('\n')
+		if line != "" {
+			if err := p.parse(line); err != nil {
+				return nil, err
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source: %w", err)
+		}
+	}
+
+	return p.finish(), nil
+}
+
+//#endregion
+//#region private functions
+
+// lineParser parses the lines of a source one by one and appends them to a document.
+type lineParser struct {
+	// options of the parsing
+	configurator *parseConfigurator
+
+	// document that receives the lines
+	document *Document
+
+	// read returns the number of bytes read from the source, and total is the size of the source, zero if it is unknown
+	read  func() int64
+	total int64
+
+	// number of lines parsed
+	number int
+
+	// position of the next line in the source
+	offset int64
+}
+
+// parse appends a line to the document, raw is the line including its line ending.
+func (p *lineParser) parse(raw string) error {
+	p.number++
+	text := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+
+	l := Line{offset: p.offset, located: true}
+	p.offset += int64(len(raw))
+
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, ";") || (p.configurator.extended && IsExtendedCommand(trimmed)) ||
+		(p.configurator.meta && (IsMetaCommand(trimmed) || HasExpression(trimmed))) || (p.configurator.macro && IsMacroStatement(trimmed)) {
+		l.Text = text
+	} else {
+		b, err := gcodeblock.Parse(trimmed, p.configurator.blockOptions...)
+		if err != nil {
+			return &ParseError{Line: p.number, Err: err}
+		}
+		l.Block = b
+	}
+
+	if p.configurator.roundTrip {
+		l.raw = raw
+		l.snapshot = l.String()
+	}
+
+	p.document.lines = append(p.document.lines, l)
+
+	if p.configurator.progress != nil && p.number%PROGRESS_INTERVAL == 0 {
+		p.configurator.progress(Progress{Lines: p.number, Bytes: p.read(), TotalBytes: p.total})
+	}
+
+	return nil
+}
+
+// finish reports the end of the parsing and returns the document indexed.
+func (p *lineParser) finish() *Document {
+	if p.configurator.progress != nil {
+		p.configurator.progress(Progress{Lines: p.number, TotalLines: p.number, Bytes: p.read(), TotalBytes: p.total})
+	}
+
+	p.document.index()
+
+	return p.document
+}
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines, but the lines returned keep their line ending.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// formatBlock returns the block exported with a ToLine format, omitting the separator that precedes each element that the block hasn't.
+//
+// The checksum is calculated over the line without the spaces that the missing elements would leave,
+// so a line like "N1 G28*18" is accepted by the firmwares while "N1 G28 *18" isn't.
+func formatBlock(b block.Blocker, format string) string {
+	missing := []struct {
+		verb    string
+		missing bool
+	}{
+		{"%l", b.LineNumber() == nil},
+		{"%p", len(b.Parameters()) == 0},
+		{"%k", b.Checksum() == nil},
+		{"%m", b.Comment() == ""},
+		{"%t", !tagged(b)},
+	}
+
+	for _, element := range missing {
+		if element.missing {
+			format = strings.ReplaceAll(format, " "+element.verb, "")
+			format = strings.ReplaceAll(format, element.verb, "")
+		}
+	}
+
+	return b.ToLine(format)
+}
+
+// tagged returns true if the block has some user-defined metadata tag.
+func tagged(b block.Blocker) bool {
+	t, ok := b.(block.Tagger)
+	return ok && len(t.Tags()) > 0
+}
+
+// editors returns the blocks as block.Editor, so they can be modified.
+// It returns an error if some block doesn't implement it, in that case none can be modified.
+func editors(blocks []block.Blocker) ([]block.Editor, error) {
+	editors := make([]block.Editor, len(blocks))
+
+	for i, b := range blocks {
+		e, ok := b.(block.Editor)
+		if !ok {
+			return nil, fmt.Errorf("failed to edit the block %d, it doesn't implement block.Editor", i)
+		}
+		editors[i] = e
+	}
+
+	return editors, nil
+}
+
+//#endregion
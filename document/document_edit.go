@@ -0,0 +1,135 @@
+package document
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region edition
+
+// SetRenumberOnEdit defines if the document is renumbered after each edition made by InsertAfter, ReplaceRange or DeleteRange,
+// so the sequence of line numbers and the checksums stay valid. The options are the same that Renumber receives.
+//
+// By default the editions don't renumber the document. It returns an error if some option is invalid.
+func (d *Document) SetRenumberOnEdit(enabled bool, options ...RenumberConfigurationCallbackable) error {
+
+	configurator := &renumberConfigurator{}
+
+	for _, option := range options {
+		if option == nil {
+			return fmt.Errorf("failed to load configuration, the option mustn't be nil")
+		}
+
+		if err := option(configurator); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	d.renumberOnEdit = enabled
+	d.renumberOptions = options
+
+	return nil
+}
+
+// InsertAfter inserts the blocks after the block in the position required, starting at zero.
+// The position -1 inserts them at the start of the document.
+//
+// The blocks are inserted just after the line of the block, so the comment lines that follow it keep after the new blocks.
+// It returns an error if the position is out of range or some block is nil.
+func (d *Document) InsertAfter(index int, blocks ...block.Blocker) error {
+	if index < -1 || index >= len(d.blocks) {
+		return fmt.Errorf("failed to insert blocks, the position %d is out of range [-1, %d)", index, len(d.blocks))
+	}
+
+	position := 0
+	if index >= 0 {
+		position = d.blockLine(index) + 1
+	}
+
+	return d.splice(position, position, blocks)
+}
+
+// ReplaceRange replaces the blocks from start to end-1 by the blocks received, that can be more or less.
+//
+// All lines from the line of the block start to the line of the block end-1 are replaced, including the comment lines between them.
+// If start is equal to end, the blocks are inserted before the block start, or at the end of the document if there isn't one.
+// It returns an error if the range is invalid or some block is nil.
+func (d *Document) ReplaceRange(start int, end int, blocks ...block.Blocker) error {
+	if start < 0 || start > end || end > len(d.blocks) {
+		return fmt.Errorf("failed to replace blocks, the range [%d, %d) is invalid, the document has %d blocks", start, end, len(d.blocks))
+	}
+
+	first := len(d.lines)
+	if start < len(d.blocks) {
+		first = d.blockLine(start)
+	}
+
+	last := first
+	if end > start {
+		last = d.blockLine(end-1) + 1
+	}
+
+	return d.splice(first, last, blocks)
+}
+
+// DeleteRange removes the blocks from start to end-1, and the comment lines between them.
+//
+// It returns an error if the range is invalid.
+func (d *Document) DeleteRange(start int, end int) error {
+	if err := d.ReplaceRange(start, end); err != nil {
+		return fmt.Errorf("failed to delete blocks: %w", err)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// blockLine returns the position of the line that contains the block required. The block must exist.
+func (d *Document) blockLine(index int) int {
+	blocks := 0
+	for i, l := range d.lines {
+		if l.Block == nil {
+			continue
+		}
+
+		if blocks == index {
+			return i
+		}
+		blocks++
+	}
+
+	return len(d.lines)
+}
+
+// splice replaces the lines from start to end-1 by the blocks received, then it rebuilds the indexes
+// and renumbers the document if it is required.
+func (d *Document) splice(start int, end int, blocks []block.Blocker) error {
+	for i, b := range blocks {
+		if b == nil {
+			return fmt.Errorf("failed to edit document, the block %d mustn't be nil", i)
+		}
+	}
+
+	lines := make([]Line, 0, len(d.lines)-(end-start)+len(blocks))
+	lines = append(lines, d.lines[:start]...)
+	for _, b := range blocks {
+		lines = append(lines, Line{Block: b})
+	}
+	lines = append(lines, d.lines[end:]...)
+
+	d.lines = lines
+	d.index()
+
+	if d.renumberOnEdit {
+		if err := d.Renumber(d.renumberOptions...); err != nil {
+			return fmt.Errorf("failed to renumber edited document: %w", err)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
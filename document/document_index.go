@@ -0,0 +1,85 @@
+package document
+
+import (
+	"fmt"
+)
+
+//#region queries
+
+// FindByLineNumber returns the position of the first block whose line number is n.
+// It returns false if no block has that line number.
+func (d *Document) FindByLineNumber(n uint32) (int, bool) {
+	index, ok := d.byLineNumber[n]
+
+	return index, ok
+}
+
+// FindCommand returns the positions of all blocks whose command is the word and the integer address required,
+// like FindCommand('M', 104) to find the changes of the hotend temperature. The positions are ordered.
+func (d *Document) FindCommand(word byte, address int32) []int {
+	return d.FindCommandString(fmt.Sprintf("%c%d", word, address))
+}
+
+// FindCommandString returns the positions of all blocks whose command is exported like the expression required,
+// like "G1", "M104" or "G92.1". The positions are ordered.
+func (d *Document) FindCommandString(command string) []int {
+	return copyIndexes(d.byCommand[command])
+}
+
+// FindWord returns the positions of all blocks whose command has the word required,
+// like FindWord('T') to find the tool changes. The positions are ordered.
+func (d *Document) FindWord(word byte) []int {
+	return copyIndexes(d.byWord[word])
+}
+
+// Reindex rebuilds the indexes of the document.
+//
+// The document keeps them updated when it is modified by its own methods,
+// but it must be called if the line number or the command of some block is modified directly.
+func (d *Document) Reindex() {
+	d.index()
+}
+
+//#endregion
+//#region private functions
+
+// index rebuilds the list of blocks from the lines of the document, and the indexes used by the queries.
+func (d *Document) index() {
+	d.blocks = d.blocks[:0]
+	d.byLineNumber = make(map[uint32]int)
+	d.byCommand = make(map[string][]int)
+	d.byWord = make(map[byte][]int)
+
+	for _, l := range d.lines {
+		if l.Block == nil {
+			continue
+		}
+
+		i := len(d.blocks)
+		d.blocks = append(d.blocks, l.Block)
+
+		if ln := l.Block.LineNumber(); ln != nil {
+			if _, ok := d.byLineNumber[ln.Address()]; !ok {
+				d.byLineNumber[ln.Address()] = i
+			}
+		}
+
+		command := l.Block.Command()
+		d.byCommand[command.String()] = append(d.byCommand[command.String()], i)
+		d.byWord[command.Word()] = append(d.byWord[command.Word()], i)
+	}
+}
+
+// copyIndexes returns a copy of a list of positions, so the indexes of the document can't be modified.
+func copyIndexes(indexes []int) []int {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	copied := make([]int, len(indexes))
+	copy(copied, indexes)
+
+	return copied
+}
+
+//#endregion
@@ -0,0 +1,286 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+)
+
+const (
+	// LAYER_MARKER is the comment inserted by Cura and other slicers at each layer change, followed by the layer number.
+	LAYER_MARKER = ";LAYER:"
+
+	// LAYER_CHANGE_MARKER is the comment inserted by PrusaSlicer and SuperSlicer at each layer change.
+	LAYER_CHANGE_MARKER = ";LAYER_CHANGE"
+)
+
+//#region layer struct
+
+// Layer is a range of consecutive blocks printed at the same height.
+type Layer struct {
+	// Index is the position of the layer in the document, starting at zero.
+	Index int
+
+	// Z is the height of the layer.
+	Z float64
+
+	// Start is the position of the first block of the layer.
+	Start int
+
+	// End is the position after the last block of the layer, so the layer contains the blocks from Start to End-1.
+	End int
+
+	// StartLine is the position of the first line of the layer, it is the line of the marker if the layer was detected by a marker.
+	StartLine int
+
+	// EndLine is the position after the last line of the layer.
+	EndLine int
+}
+
+// Len returns the number of blocks of the layer.
+func (l Layer) Len() int {
+	return l.End - l.Start
+}
+
+// String returns the layer formatted.
+func (l Layer) String() string {
+	return fmt.Sprintf("layer %d at Z%s: blocks [%d, %d)", l.Index, strconv.FormatFloat(l.Z, 'f', -1, 64), l.Start, l.End)
+}
+
+//#endregion
+//#region layers configuration
+
+// LayerDetection defines how the layers of a document are detected.
+type LayerDetection int
+
+const (
+	// LayerAuto uses the layer markers if the document has some, else the changes of height. It is the default detection.
+	LayerAuto LayerDetection = iota
+
+	// LayerMarkers only uses the layer markers inserted by the slicers, like ";LAYER:2" or ";LAYER_CHANGE".
+	LayerMarkers
+
+	// LayerHeights starts a layer each time the extrusion moves are executed at a new height.
+	// The height changes without extrusion, like a z-hop, are ignored.
+	LayerHeights
+)
+
+// LayersConfigurer defines the options of the detection of layers.
+type LayersConfigurer interface {
+	// Set how the layers are detected
+	SetDetection(detection LayerDetection) error
+}
+
+// LayersConfigurationCallbackable is the signature of the callbacks used to configure the detection of layers.
+type LayersConfigurationCallbackable func(config LayersConfigurer) error
+
+// layersConfigurator implements LayersConfigurer.
+type layersConfigurator struct {
+	detection LayerDetection
+}
+
+// SetDetection defines how the layers are detected.
+// If this method isn't called, by default the detection is LayerAuto.
+func (lc *layersConfigurator) SetDetection(detection LayerDetection) error {
+	switch detection {
+	case LayerAuto, LayerMarkers, LayerHeights:
+	default:
+		return fmt.Errorf("failed to set layer detection, unknown value %d", detection)
+	}
+
+	lc.detection = detection
+
+	return nil
+}
+
+//#endregion
+//#region layers
+
+// Layers returns the layers of the document in order.
+//
+// The blocks before the first layer, like the start gcode, don't belong to any layer.
+// The height of each layer is the first Z commanded by a G0 or G1 block inside it, or the current height if there isn't one.
+// It returns an error only if some option is invalid.
+func (d *Document) Layers(options ...LayersConfigurationCallbackable) ([]Layer, error) {
+
+	configurator := &layersConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	switch configurator.detection {
+	case LayerMarkers:
+		return d.layersByMarkers(), nil
+	case LayerHeights:
+		return d.layersByHeights(), nil
+	}
+
+	if layers := d.layersByMarkers(); len(layers) > 0 {
+		return layers, nil
+	}
+
+	return d.layersByHeights(), nil
+}
+
+// layersByMarkers detects the layers using the comments inserted by the slicers.
+func (d *Document) layersByMarkers() []Layer {
+	var layers []Layer
+
+	tracker := heightTracker{}
+	zFound := false
+	blocks := 0
+
+	for i, l := range d.lines {
+		if l.Block == nil {
+			if !isLayerMarker(l.Text) {
+				continue
+			}
+
+			if len(layers) > 0 {
+				closeLayer(&layers[len(layers)-1], blocks, i)
+			}
+
+			layers = append(layers, Layer{Index: len(layers), Z: tracker.z, Start: blocks, StartLine: i})
+			zFound = false
+			continue
+		}
+
+		blocks++
+		if !tracker.update(l.Block) {
+			continue
+		}
+
+		if len(layers) > 0 && !zFound {
+			layers[len(layers)-1].Z = tracker.z
+			zFound = true
+		}
+	}
+
+	if len(layers) > 0 {
+		closeLayer(&layers[len(layers)-1], blocks, len(d.lines))
+	}
+
+	return layers
+}
+
+// layersByHeights detects the layers at the extrusion moves executed at a new height.
+//
+// The layer starts at the block that commanded the new height, so the travel to the layer belongs to it.
+func (d *Document) layersByHeights() []Layer {
+	var layers []Layer
+
+	tracker := heightTracker{}
+
+	// position of the block and the line that commanded the current height
+	changeBlock, changeLine := 0, 0
+	blocks := 0
+
+	for i, l := range d.lines {
+		if l.Block == nil {
+			continue
+		}
+
+		if tracker.update(l.Block) {
+			changeBlock, changeLine = blocks, i
+		}
+		blocks++
+
+		if !isExtrusionMove(l.Block) {
+			continue
+		}
+
+		if len(layers) > 0 && layers[len(layers)-1].Z == tracker.z {
+			continue
+		}
+
+		if len(layers) > 0 {
+			closeLayer(&layers[len(layers)-1], changeBlock, changeLine)
+		}
+
+		layers = append(layers, Layer{Index: len(layers), Z: tracker.z, Start: changeBlock, StartLine: changeLine})
+	}
+
+	if len(layers) > 0 {
+		closeLayer(&layers[len(layers)-1], blocks, len(d.lines))
+	}
+
+	return layers
+}
+
+//#endregion
+//#region private functions
+
+// heightTracker follows the height of the nozzle along the blocks, considering the absolute and relative positioning.
+type heightTracker struct {
+	z        float64
+	relative bool
+}
+
+// update applies a block to the height, it returns true if the block commanded a height.
+func (h *heightTracker) update(b block.Blocker) bool {
+	switch b.Command().String() {
+	case "G90":
+		h.relative = false
+	case "G91":
+		h.relative = true
+	case "G92":
+		if z, ok := blockparam.Number(b, 'Z'); ok {
+			h.z = z
+		}
+	case "G0", "G1":
+		z, ok := blockparam.Number(b, 'Z')
+		if !ok {
+			return false
+		}
+
+		if h.relative {
+			h.z += z
+		} else {
+			h.z = z
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// closeLayer sets the end of a layer.
+func closeLayer(layer *Layer, end int, endLine int) {
+	layer.End = end
+	layer.EndLine = endLine
+}
+
+// isLayerMarker returns true if the text is a comment that marks a layer change.
+func isLayerMarker(text string) bool {
+	text = strings.TrimSpace(text)
+
+	return strings.HasPrefix(text, LAYER_MARKER) || strings.HasPrefix(text, LAYER_CHANGE_MARKER)
+}
+
+// isExtrusionMove returns true if the block moves in the XY plane while it extrudes.
+func isExtrusionMove(b block.Blocker) bool {
+	switch b.Command().String() {
+	case "G1", "G2", "G3":
+	default:
+		return false
+	}
+
+	e, ok := blockparam.Number(b, 'E')
+	if !ok || e <= 0 {
+		return false
+	}
+
+	_, x := blockparam.Number(b, 'X')
+	_, y := blockparam.Number(b, 'Y')
+
+	return x || y
+}
+
+//#endregion
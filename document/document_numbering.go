@@ -0,0 +1,218 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//#region line number issue
+
+// LineNumberIssueKind identifies a break of the sequence of line numbers.
+type LineNumberIssueKind int
+
+const (
+	// LineNumberGap is a line number that isn't the expected by the step, neither a duplicate nor a reset, like N5 after N3 with step 1.
+	LineNumberGap LineNumberIssueKind = iota
+
+	// LineNumberDuplicate is a line number equal to the previous one.
+	LineNumberDuplicate
+
+	// LineNumberReset is a line number lower than the previous one, without M110 that sets it.
+	LineNumberReset
+
+	// LineNumberMissing is a block without line number in a document whose other blocks are numbered.
+	LineNumberMissing
+)
+
+// String returns the name of the kind.
+func (k LineNumberIssueKind) String() string {
+	switch k {
+	case LineNumberGap:
+		return "gap"
+	case LineNumberDuplicate:
+		return "duplicate"
+	case LineNumberReset:
+		return "reset"
+	case LineNumberMissing:
+		return "missing"
+	}
+
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// LineNumberIssue describes a block that breaks the sequence of line numbers.
+type LineNumberIssue struct {
+	// Index is the position of the block in the document, starting at zero.
+	Index int
+
+	// Kind is the kind of break.
+	Kind LineNumberIssueKind
+
+	// LineNumber is the line number of the block, it isn't valid if the kind is LineNumberMissing.
+	LineNumber uint32
+
+	// Expected is the line number expected for the block.
+	// It is only valid if a previous block is numbered or the first line number is configured.
+	Expected uint32
+}
+
+// String returns the issue formatted.
+func (i LineNumberIssue) String() string {
+	if i.Kind == LineNumberMissing {
+		return fmt.Sprintf("block %d: missing line number", i.Index)
+	}
+
+	return fmt.Sprintf("block %d (N%d): %s, expected N%d", i.Index, i.LineNumber, i.Kind, i.Expected)
+}
+
+// LineNumberReport contains the result of the verification of the sequence of line numbers of a document.
+type LineNumberReport struct {
+	// Numbered is the number of blocks with line number.
+	Numbered int
+
+	// Unnumbered is the number of blocks without line number.
+	Unnumbered int
+
+	// Issues lists the blocks that break the sequence, ordered by their position in the document.
+	Issues []LineNumberIssue
+}
+
+// Passed returns true if the sequence of line numbers isn't broken.
+func (r *LineNumberReport) Passed() bool {
+	return len(r.Issues) == 0
+}
+
+// String returns a summary of the report.
+func (r *LineNumberReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d blocks numbered, %d without line number, %d issues", r.Numbered, r.Unnumbered, len(r.Issues)))
+	for _, i := range r.Issues {
+		sb.WriteString("\n")
+		sb.WriteString(i.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region line numbers configuration
+
+// LineNumbersConfigurer defines the options of the verification of the line numbers.
+type LineNumbersConfigurer interface {
+	// Set the line number expected of the first block
+	SetBase(base uint32) error
+
+	// Set the increment expected between consecutive line numbers
+	SetStep(step uint32) error
+}
+
+// LineNumbersConfigurationCallbackable is the signature of the callbacks used to configure the verification of the line numbers.
+type LineNumbersConfigurationCallbackable func(config LineNumbersConfigurer) error
+
+// lineNumbersConfigurator implements LineNumbersConfigurer.
+type lineNumbersConfigurator struct {
+	base    uint32
+	baseSet bool
+	step    uint32
+}
+
+// SetBase defines the line number expected of the first block, like N1 expected by Marlin after a reset.
+// If this method isn't called, by default the first line number isn't verified.
+func (lc *lineNumbersConfigurator) SetBase(base uint32) error {
+	lc.base = base
+	lc.baseSet = true
+
+	return nil
+}
+
+// SetStep defines the increment expected between consecutive line numbers. It must be positive.
+// If this method isn't called, by default the step is 1, required to stream to the firmwares.
+func (lc *lineNumbersConfigurator) SetStep(step uint32) error {
+	if step == 0 {
+		return fmt.Errorf("failed to set step, it must be positive")
+	}
+
+	lc.step = step
+
+	return nil
+}
+
+//#endregion
+//#region line numbers verification
+
+// VerifyLineNumbers verifies that the line numbers of the blocks increase strictly with the step, like the firmwares require
+// to stream a document with line numbers and checksums over serial, where a break in the sequence is a resend request.
+//
+// It reports the gaps, the duplicates and the resets of the sequence, and the blocks without line number if others have one.
+// After a break, the sequence continues from the line number of the block that broke it.
+// M110 sets the line number, like the firmwares do, so its line number can be any one.
+// It returns an error only if some option is invalid.
+func (d *Document) VerifyLineNumbers(options ...LineNumbersConfigurationCallbackable) (*LineNumberReport, error) {
+
+	configurator := &lineNumbersConfigurator{
+		step: 1,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &LineNumberReport{}
+
+	// the expected line number of the next block, it is only valid if known is true
+	expected := uint64(configurator.base)
+	known := configurator.baseSet
+
+	var previous uint64
+	numbered := false
+
+	var missing []int
+
+	for i, b := range d.blocks {
+		ln := b.LineNumber()
+		if ln == nil {
+			report.Unnumbered++
+			missing = append(missing, i)
+			continue
+		}
+
+		report.Numbered++
+		current := uint64(ln.Address())
+
+		if known && current != expected && b.Command().String() != "M110" {
+			issue := LineNumberIssue{Index: i, LineNumber: uint32(current), Expected: uint32(expected)}
+
+			switch {
+			case numbered && current == previous:
+				issue.Kind = LineNumberDuplicate
+			case numbered && current < previous:
+				issue.Kind = LineNumberReset
+			default:
+				issue.Kind = LineNumberGap
+			}
+
+			report.Issues = append(report.Issues, issue)
+		}
+
+		previous, numbered = current, true
+		expected, known = current+uint64(configurator.step), true
+	}
+
+	if report.Numbered > 0 {
+		for _, i := range missing {
+			report.Issues = append(report.Issues, LineNumberIssue{Index: i, Kind: LineNumberMissing})
+		}
+	}
+
+	sort.Slice(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Index < report.Issues[j].Index
+	})
+
+	return report, nil
+}
+
+//#endregion
@@ -0,0 +1,74 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestDocument_VerifyLineNumbers(t *testing.T) {
+
+	cases := map[string]struct {
+		lines   []string
+		options []LineNumbersConfigurationCallbackable
+		want    string
+	}{
+		"empty": {nil, nil, "0 blocks numbered, 0 without line number, 0 issues"},
+		"without line numbers": {
+			[]string{"G28", "G1 X10"},
+			nil, "0 blocks numbered, 2 without line number, 0 issues",
+		},
+		"sequence": {
+			[]string{"N7 G28", "N8 G1 X10", "N9 G1 X20"},
+			nil, "3 blocks numbered, 0 without line number, 0 issues",
+		},
+		"breaks": {
+			[]string{"N1 G28", "N2 G1 X10", "N4 G1 X20", "N4 G1 X30", "N2 G1 X40", "N3 G1 X50", "G1 X60"},
+			nil, "6 blocks numbered, 1 without line number, 4 issues\n" +
+				"block 2 (N4): gap, expected N3\n" +
+				"block 3 (N4): duplicate, expected N5\n" +
+				"block 4 (N2): reset, expected N5\n" +
+				"block 6: missing line number",
+		},
+		"M110": {
+			[]string{"N5 G28", "N0 M110", "N1 G1 X10", "N100 M110", "N101 G1 X20"},
+			nil, "5 blocks numbered, 0 without line number, 0 issues",
+		},
+		"base and step": {
+			[]string{"N0 G28", "N10 G1 X10", "N15 G1 X20", "N25 G1 X30"},
+			[]LineNumbersConfigurationCallbackable{
+				func(config LineNumbersConfigurer) error { return config.SetBase(10) },
+				func(config LineNumbersConfigurer) error { return config.SetStep(10) },
+			},
+			"4 blocks numbered, 0 without line number, 2 issues\n" +
+				"block 0 (N0): gap, expected N10\n" +
+				"block 2 (N15): gap, expected N20",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := New(blocktest.Parse(t, tc.lines...)...)
+
+			report, err := d.VerifyLineNumbers(tc.options...)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got := report.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if report.Passed() != (len(report.Issues) == 0) {
+				t.Errorf("got passed %v with %d issues", report.Passed(), len(report.Issues))
+			}
+		})
+	}
+
+	t.Run("zero step", func(t *testing.T) {
+		_, err := New().VerifyLineNumbers(func(config LineNumbersConfigurer) error { return config.SetStep(0) })
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+const (
+	// EXCLUDE_OBJECT_DEFINE is the extended command of Klipper that declares an object, in the header of the file.
+	EXCLUDE_OBJECT_DEFINE = "EXCLUDE_OBJECT_DEFINE"
+
+	// EXCLUDE_OBJECT_START is the extended command of Klipper that starts the blocks of an object.
+	EXCLUDE_OBJECT_START = "EXCLUDE_OBJECT_START"
+
+	// EXCLUDE_OBJECT_END is the extended command of Klipper that ends the blocks of an object.
+	EXCLUDE_OBJECT_END = "EXCLUDE_OBJECT_END"
+)
+
+//#region object struct
+
+// Object is an object of a print, delimited by the markers inserted by the slicers to cancel objects:
+// "M486 S<id>" and "M486 S-1" of Marlin and RepRapFirmware, or EXCLUDE_OBJECT_START and EXCLUDE_OBJECT_END of Klipper.
+type Object struct {
+	// Name is the NAME of the markers of Klipper, or the identifier of M486 S formatted, like "0".
+	Name string
+
+	// Ranges are the ranges of lines of the object in order, each one from its start marker to its end marker, both included.
+	// An object is printed in a range by layer.
+	Ranges []Range
+
+	// Definitions are the positions of the lines of EXCLUDE_OBJECT_DEFINE of the object.
+	Definitions []int
+}
+
+// Len returns the number of blocks of the object.
+func (o Object) Len() int {
+	blocks := 0
+	for _, r := range o.Ranges {
+		blocks += r.Len()
+	}
+
+	return blocks
+}
+
+// String returns the object formatted.
+func (o Object) String() string {
+	return fmt.Sprintf("object %s: %d ranges, %d blocks", o.Name, len(o.Ranges), o.Len())
+}
+
+//#endregion
+//#region objects
+
+// Objects returns the objects of the document, in the order of their first block.
+//
+// A start marker ends the object in progress, if any. The last object ends with the document if it isn't ended by a marker.
+// The markers of Klipper are only detected if the document was parsed with the extended commands kept.
+func (d *Document) Objects() []Object {
+	var objects []Object
+	byName := map[string]int{}
+
+	object := func(name string) *Object {
+		i, ok := byName[name]
+		if !ok {
+			i = len(objects)
+			byName[name] = i
+			objects = append(objects, Object{Name: name})
+		}
+		return &objects[i]
+	}
+
+	current := ""
+	startLine := 0
+
+	end := func(endLine int) {
+		if current == "" {
+			return
+		}
+
+		o := object(current)
+		o.Ranges = append(o.Ranges, d.lineRange(startLine, endLine))
+		current = ""
+	}
+
+	for i, l := range d.lines {
+		marker, name, ok := objectMarker(l)
+		if !ok {
+			continue
+		}
+
+		switch marker {
+		case EXCLUDE_OBJECT_DEFINE:
+			o := object(name)
+			o.Definitions = append(o.Definitions, i)
+		case EXCLUDE_OBJECT_START:
+			end(i)
+			object(name)
+			current, startLine = name, i
+		case EXCLUDE_OBJECT_END:
+			end(i + 1)
+		}
+	}
+	end(len(d.lines))
+
+	return objects
+}
+
+// RemoveObject removes all lines of an object, with its markers and definitions, like a part that failed on a plate of several parts.
+//
+// The state of the machine at the end of each range removed is restored, so the blocks that follow it behave like before:
+// the position of the extruder is set with G92 if the extrusion is absolute, and the height and the feedrate are restored if they changed.
+// It returns an error if the document doesn't have the object.
+func (d *Document) RemoveObject(name string) error {
+	var removed *Object
+	for _, o := range d.Objects() {
+		if o.Name == name {
+			removed = &o
+			break
+		}
+	}
+
+	if removed == nil {
+		return fmt.Errorf("failed to remove object, the document doesn't have the object %s", name)
+	}
+
+	// the states before and after each range, the blocks are indexed before the edition
+	before := map[int]ModalState{}
+	after := map[int]ModalState{}
+	for _, r := range removed.Ranges {
+		before[r.Start] = ModalState{}
+		after[r.End-1] = ModalState{}
+	}
+
+	it := d.Iterate()
+	for it.Next() {
+		if _, ok := before[it.Index()]; ok {
+			before[it.Index()] = it.Before()
+		}
+		if _, ok := after[it.Index()]; ok {
+			after[it.Index()] = it.State()
+		}
+	}
+
+	type edition struct {
+		start, end int
+		blocks     []block.Blocker
+	}
+
+	var editions []edition
+	for _, r := range removed.Ranges {
+		e := edition{start: r.StartLine, end: r.EndLine}
+
+		if r.Len() > 0 {
+			blocks, err := restoreState(before[r.Start], after[r.End-1])
+			if err != nil {
+				return fmt.Errorf("failed to remove object %s: %w", name, err)
+			}
+			e.blocks = blocks
+		}
+
+		editions = append(editions, e)
+	}
+
+	for _, line := range removed.Definitions {
+		editions = append(editions, edition{start: line, end: line + 1})
+	}
+
+	// the editions are applied from the end, so the positions of the lines before them don't change
+	sort.Slice(editions, func(i, j int) bool {
+		return editions[i].start > editions[j].start
+	})
+
+	for _, e := range editions {
+		if err := d.splice(e.start, e.end, e.blocks); err != nil {
+			return fmt.Errorf("failed to remove object %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// objectMarker returns the marker of objects of the line, one of the extended commands of Klipper, and the name of its object.
+// M486 S<id> is returned as EXCLUDE_OBJECT_START and M486 S-1 as EXCLUDE_OBJECT_END.
+// It returns false if the line isn't a marker.
+func objectMarker(l Line) (string, string, bool) {
+	if l.Block != nil {
+		if l.Block.Command().String() != "M486" {
+			return "", "", false
+		}
+
+		id, ok := blockparam.Number(l.Block, 'S')
+		if !ok {
+			return "", "", false
+		}
+
+		if id < 0 {
+			return EXCLUDE_OBJECT_END, "", true
+		}
+
+		return EXCLUDE_OBJECT_START, strconv.Itoa(int(id)), true
+	}
+
+	command, arguments, ok := ParseExtendedCommand(l.Text)
+	if !ok {
+		return "", "", false
+	}
+
+	switch command {
+	case EXCLUDE_OBJECT_DEFINE, EXCLUDE_OBJECT_START, EXCLUDE_OBJECT_END:
+		return command, arguments["NAME"], true
+	}
+
+	return "", "", false
+}
+
+// restoreState returns the blocks that take the machine from the state before a range of blocks removed to the state after it,
+// for the extruder, the height and the feedrate.
+func restoreState(before ModalState, after ModalState) ([]block.Blocker, error) {
+	var expressions []string
+
+	if !after.RelativeExtrusion && after.Position.E != before.Position.E {
+		expressions = append(expressions, "G92 E"+numeric.Format(after.Position.E))
+	}
+
+	if !after.Relative && after.Position.Z != before.Position.Z {
+		expressions = append(expressions, "G0 Z"+numeric.Format(after.Position.Z))
+	}
+
+	if after.Feedrate != before.Feedrate && after.Feedrate > 0 {
+		expressions = append(expressions, "G1 F"+numeric.Format(after.Feedrate))
+	}
+
+	blocks := make([]block.Blocker, 0, len(expressions))
+	for _, expression := range expressions {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", expression, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+//#endregion
@@ -0,0 +1,120 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_Objects(t *testing.T) {
+
+	cases := map[string]struct {
+		source []string
+		want   []string
+	}{
+		"without objects": {[]string{"G28", "G1 X10 E1"}, nil},
+		"marlin": {
+			[]string{"M486 T2", "M486 S0", "G1 X10 E1", "M486 S1", "G1 X20 E2", "M486 S-1", "M486 S0", "G1 X30 E3", "M486 S-1", "M104 S0"},
+			[]string{"object 0: 2 ranges, 5 blocks", "object 1: 1 ranges, 3 blocks"},
+		},
+		"klipper": {
+			[]string{
+				"EXCLUDE_OBJECT_DEFINE NAME=cube CENTER=10,10", "EXCLUDE_OBJECT_DEFINE NAME=\"cone\"",
+				"EXCLUDE_OBJECT_START NAME=cube", "G1 X10 E1", "EXCLUDE_OBJECT_END NAME=cube",
+				"exclude_object_start name=cone", "G1 X20 E2", "; comment", "G1 X30 E3",
+			},
+			[]string{"object cube: 1 ranges, 1 blocks", "object cone: 1 ranges, 2 blocks"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(strings.Join(tc.source, "\n")), func(config ParseConfigurer) error {
+				return config.SetExtendedCommands(true)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var got []string
+			for _, o := range d.Objects() {
+				got = append(got, o.String())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDocument_RemoveObject(t *testing.T) {
+
+	source := strings.Join([]string{
+		"EXCLUDE_OBJECT_DEFINE NAME=a",
+		"EXCLUDE_OBJECT_DEFINE NAME=b",
+		"G90",
+		"M82",
+		"G1 Z0.2 F600",
+		"EXCLUDE_OBJECT_START NAME=a",
+		"G1 X10 E1 F1200",
+		"EXCLUDE_OBJECT_END NAME=a",
+		"EXCLUDE_OBJECT_START NAME=b",
+		"G1 X20 E2",
+		"EXCLUDE_OBJECT_END NAME=b",
+		"G1 Z0.4",
+		"EXCLUDE_OBJECT_START NAME=a",
+		"G1 X10 E3",
+		"G1 Z0.6 E3",
+		"EXCLUDE_OBJECT_END NAME=a",
+		"EXCLUDE_OBJECT_START NAME=b",
+		"G1 X20 E4",
+		"EXCLUDE_OBJECT_END NAME=b",
+	}, "\n")
+
+	cases := map[string]struct {
+		object string
+		valid  bool
+		want   string
+	}{
+		"first": {"a", true, strings.Join([]string{
+			"EXCLUDE_OBJECT_DEFINE NAME=b", "G90", "M82", "G1 Z0.2 F600",
+			"G92 E1", "G1 F1200",
+			"EXCLUDE_OBJECT_START NAME=b", "G1 X20 E2", "EXCLUDE_OBJECT_END NAME=b",
+			"G1 Z0.4",
+			"G92 E3", "G0 Z0.6",
+			"EXCLUDE_OBJECT_START NAME=b", "G1 X20 E4", "EXCLUDE_OBJECT_END NAME=b", "",
+		}, "\n")},
+		"last": {"b", true, strings.Join([]string{
+			"EXCLUDE_OBJECT_DEFINE NAME=a", "G90", "M82", "G1 Z0.2 F600",
+			"EXCLUDE_OBJECT_START NAME=a", "G1 X10 E1 F1200", "EXCLUDE_OBJECT_END NAME=a",
+			"G92 E2",
+			"G1 Z0.4",
+			"EXCLUDE_OBJECT_START NAME=a", "G1 X10 E3", "G1 Z0.6 E3", "EXCLUDE_OBJECT_END NAME=a",
+			"G92 E4", "",
+		}, "\n")},
+		"missing": {"c", false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+				return config.SetExtendedCommands(true)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			err = d.RemoveObject(tc.object)
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, want valid %v", err, tc.valid)
+				return
+			}
+
+			if tc.valid && d.String() != tc.want {
+				t.Errorf("got %q, want %q", d.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package document
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/gcode/addressablegcode"
+)
+
+//#region renumber configuration
+
+// RenumberConfigurer defines the options of the renumbering of a document.
+type RenumberConfigurer interface {
+	// Set the line number of the first block
+	SetBase(base uint32) error
+
+	// Set the increment between consecutive line numbers
+	SetStep(step uint32) error
+
+	// Set if the checksum of each block is recalculated or removed
+	SetChecksum(enabled bool) error
+}
+
+// RenumberConfigurationCallbackable is the signature of the callbacks used to configure the renumbering.
+type RenumberConfigurationCallbackable func(config RenumberConfigurer) error
+
+// renumberConfigurator implements RenumberConfigurer.
+type renumberConfigurator struct {
+	base     uint32
+	step     uint32
+	checksum bool
+}
+
+// SetBase defines the line number of the first block.
+// If this method isn't called, by default the first block is N1, the line expected by Marlin after a reset.
+func (rc *renumberConfigurator) SetBase(base uint32) error {
+	rc.base = base
+
+	return nil
+}
+
+// SetStep defines the increment between consecutive line numbers. It must be positive.
+// If this method isn't called, by default the step is 1, required to stream to the firmwares.
+func (rc *renumberConfigurator) SetStep(step uint32) error {
+	if step == 0 {
+		return fmt.Errorf("failed to set step, it must be positive")
+	}
+
+	rc.step = step
+
+	return nil
+}
+
+// SetChecksum defines if the checksum of each block is recalculated, or removed if it is false.
+// If this method isn't called, by default the checksums are recalculated.
+func (rc *renumberConfigurator) SetChecksum(enabled bool) error {
+	rc.checksum = enabled
+
+	return nil
+}
+
+//#endregion
+//#region renumber
+
+// Renumber rewrites the line number of each block with a sequence and recomputes their checksums.
+//
+// It is the standard preprocessing before stream a document to Marlin over serial.
+// By default the sequence starts at N1 with step 1, and a checksum is added to each block.
+//
+// It returns an error if some option is invalid, if some block doesn't implement block.Editor,
+// if the sequence overflows or if a checksum can't be calculated. In the last two cases the blocks already processed keep their new values.
+func (d *Document) Renumber(options ...RenumberConfigurationCallbackable) error {
+
+	configurator := &renumberConfigurator{
+		base:     1,
+		step:     1,
+		checksum: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	blocks, err := editors(d.blocks)
+	if err != nil {
+		return fmt.Errorf("failed to renumber document: %w", err)
+	}
+
+	// the line numbers change, even if some block fails
+	defer d.index()
+
+	if len(d.blocks) > 0 {
+		last := uint64(configurator.base) + uint64(len(d.blocks)-1)*uint64(configurator.step)
+		if last > math.MaxUint32 {
+			return fmt.Errorf("failed to renumber document, the line number %d of the last block overflows", last)
+		}
+	}
+
+	number := configurator.base
+	for i, b := range blocks {
+		lineNumber, err := addressablegcode.New('N', number)
+		if err != nil {
+			return fmt.Errorf("failed to create line number %d of the block %d: %w", number, i, err)
+		}
+
+		b.SetLineNumber(lineNumber)
+
+		if configurator.checksum {
+			if err := d.blocks[i].UpdateChecksum(); err != nil {
+				return fmt.Errorf("failed to update checksum of the block %d: %w", i, err)
+			}
+		} else {
+			b.SetChecksum(nil)
+		}
+
+		number += configurator.step
+	}
+
+	return nil
+}
+
+//#endregion
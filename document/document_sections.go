@@ -0,0 +1,160 @@
+package document
+
+import (
+	"fmt"
+)
+
+//#region section
+
+// Section identifies a part of a printing document.
+type Section int
+
+const (
+	// SectionHeader is the start gcode, the blocks executed before the first layer, like homing and heating.
+	SectionHeader Section = iota
+
+	// SectionBody is the print itself, from the first layer to the last extrusion.
+	SectionBody
+
+	// SectionFooter is the end gcode, the blocks executed after the last extrusion, like parking and cooling.
+	SectionFooter
+)
+
+// String returns the name of the section.
+func (s Section) String() string {
+	switch s {
+	case SectionHeader:
+		return "header"
+	case SectionBody:
+		return "body"
+	case SectionFooter:
+		return "footer"
+	}
+
+	return fmt.Sprintf("section(%d)", int(s))
+}
+
+// Range is a range of consecutive lines of a document.
+type Range struct {
+	// Start is the position of the first block of the range.
+	Start int
+
+	// End is the position after the last block of the range.
+	End int
+
+	// StartLine is the position of the first line of the range.
+	StartLine int
+
+	// EndLine is the position after the last line of the range.
+	EndLine int
+}
+
+// Len returns the number of blocks of the range.
+func (r Range) Len() int {
+	return r.End - r.Start
+}
+
+// Sections contains the ranges of the header, body and footer of a document. They are consecutive and cover the whole document.
+type Sections struct {
+	Header Range
+	Body   Range
+	Footer Range
+}
+
+// Get returns the range of the section required.
+func (s Sections) Get(section Section) (Range, error) {
+	switch section {
+	case SectionHeader:
+		return s.Header, nil
+	case SectionBody:
+		return s.Body, nil
+	case SectionFooter:
+		return s.Footer, nil
+	}
+
+	return Range{}, fmt.Errorf("unknown section %d", section)
+}
+
+//#endregion
+//#region sections
+
+// Sections detects the header, body and footer of the document.
+//
+// The body starts at the first layer, detected like Layers does with LayerAuto,
+// or at the first extrusion move if there aren't layers. It ends after the last extrusion move.
+// If the document hasn't extrusion moves, all lines belong to the header.
+func (d *Document) Sections() Sections {
+
+	bodyStart, footerStart := len(d.lines), len(d.lines)
+
+	first, last := -1, -1
+	for i, l := range d.lines {
+		if l.Block != nil && isExtrusionMove(l.Block) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first >= 0 {
+		bodyStart = first
+		footerStart = last + 1
+
+		// the default options are always valid
+		layers, _ := d.Layers()
+		if len(layers) > 0 && layers[0].StartLine < bodyStart {
+			bodyStart = layers[0].StartLine
+		}
+	}
+
+	return Sections{
+		Header: d.lineRange(0, bodyStart),
+		Body:   d.lineRange(bodyStart, footerStart),
+		Footer: d.lineRange(footerStart, len(d.lines)),
+	}
+}
+
+// ReplaceSection replaces all lines of a section by the lines received, like a new start gcode.
+//
+// The sections are detected before the replacement, so the lines received can be of any kind.
+func (d *Document) ReplaceSection(section Section, lines ...Line) error {
+
+	r, err := d.Sections().Get(section)
+	if err != nil {
+		return fmt.Errorf("failed to replace section: %w", err)
+	}
+
+	replaced := make([]Line, 0, len(d.lines)-(r.EndLine-r.StartLine)+len(lines))
+	replaced = append(replaced, d.lines[:r.StartLine]...)
+	replaced = append(replaced, lines...)
+	replaced = append(replaced, d.lines[r.EndLine:]...)
+
+	d.lines = replaced
+	d.index()
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// lineRange returns the range that contains the lines from start to end-1, including the positions of its blocks.
+func (d *Document) lineRange(start int, end int) Range {
+	r := Range{StartLine: start, EndLine: end}
+
+	for i, l := range d.lines[:end] {
+		if l.Block == nil {
+			continue
+		}
+
+		if i < start {
+			r.Start++
+		}
+		r.End++
+	}
+
+	return r
+}
+
+//#endregion
@@ -0,0 +1,151 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//#region stats struct
+
+// Stats summarizes the content of a document.
+type Stats struct {
+	// Lines is the number of lines, including the blank and comment lines.
+	Lines int
+
+	// Blocks is the number of blocks.
+	Blocks int
+
+	// BlankLines is the number of lines that only contain whitespaces.
+	BlankLines int
+
+	// CommentLines is the number of lines that only contain a comment.
+	CommentLines int
+
+	// CommentedBlocks is the number of blocks with a comment at the end.
+	CommentedBlocks int
+
+	// Bytes is the size of the document exported by a writer with the default options.
+	Bytes int64
+
+	// Commands relates each command, like "G1", with the number of blocks that execute it.
+	Commands map[string]int
+
+	// Numbered is the number of blocks with line number.
+	Numbered int
+
+	// MinLineNumber and MaxLineNumber are the lowest and highest line numbers, they are only valid if Numbered is greater than zero.
+	MinLineNumber uint32
+	MaxLineNumber uint32
+
+	// Checksummed is the number of blocks with checksum.
+	Checksummed int
+}
+
+// CommentRatio returns the fraction of the lines that contain a comment, including the blocks with a comment at the end.
+// It returns zero if the document is empty.
+func (s Stats) CommentRatio() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+
+	return float64(s.CommentLines+s.CommentedBlocks) / float64(s.Lines)
+}
+
+// ChecksumCoverage returns the fraction of the blocks with checksum. It returns zero if the document hasn't blocks.
+func (s Stats) ChecksumCoverage() float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+
+	return float64(s.Checksummed) / float64(s.Blocks)
+}
+
+// String returns a summary of the stats in a few lines, with the commands ordered from the most to the least used.
+func (s Stats) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "lines: %d (%d blank, %d comments)\n", s.Lines, s.BlankLines, s.CommentLines)
+	fmt.Fprintf(&sb, "blocks: %d (%d numbered, %d with checksum, %d commented)\n", s.Blocks, s.Numbered, s.Checksummed, s.CommentedBlocks)
+	fmt.Fprintf(&sb, "bytes: %d\n", s.Bytes)
+
+	if s.Numbered > 0 {
+		fmt.Fprintf(&sb, "line numbers: N%d to N%d\n", s.MinLineNumber, s.MaxLineNumber)
+	}
+
+	fmt.Fprintf(&sb, "comment ratio: %.2f\n", s.CommentRatio())
+	fmt.Fprintf(&sb, "checksum coverage: %.2f\n", s.ChecksumCoverage())
+
+	commands := make([]string, 0, len(s.Commands))
+	for command := range s.Commands {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		if s.Commands[commands[i]] != s.Commands[commands[j]] {
+			return s.Commands[commands[i]] > s.Commands[commands[j]]
+		}
+		return commands[i] < commands[j]
+	})
+
+	sb.WriteString("commands:")
+	for _, command := range commands {
+		fmt.Fprintf(&sb, " %s=%d", command, s.Commands[command])
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region stats
+
+// Stats counts the lines, blocks, commands, line numbers and checksums of the document.
+//
+// It is a quick health report of a document, and a simple way to check the result of a processing pipeline.
+func (d *Document) Stats() Stats {
+	s := Stats{
+		Lines:    len(d.lines),
+		Commands: map[string]int{},
+	}
+
+	for _, l := range d.lines {
+		if l.Block == nil {
+			if strings.TrimSpace(l.Text) == "" {
+				s.BlankLines++
+			} else {
+				s.CommentLines++
+			}
+			continue
+		}
+
+		s.Blocks++
+		s.Commands[l.Block.Command().String()]++
+
+		if l.Block.Comment() != "" {
+			s.CommentedBlocks++
+		}
+
+		if l.Block.Checksum() != nil {
+			s.Checksummed++
+		}
+
+		if n := l.Block.LineNumber(); n != nil {
+			if s.Numbered == 0 || n.Address() < s.MinLineNumber {
+				s.MinLineNumber = n.Address()
+			}
+			if s.Numbered == 0 || n.Address() > s.MaxLineNumber {
+				s.MaxLineNumber = n.Address()
+			}
+			s.Numbered++
+		}
+	}
+
+	// io.Discard never fails, so the writer neither
+	w, _ := NewWriter(io.Discard)
+	_ = w.WriteDocument(d)
+	s.Bytes = w.Bytes()
+
+	return s
+}
+
+//#endregion
@@ -0,0 +1,80 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+//#region strip configuration
+
+// StripConfigurer defines the options of the stripping of a document.
+type StripConfigurer interface {
+	// Set if the comments of the blocks and the comment lines are removed too
+	SetComments(strip bool) error
+}
+
+// StripConfigurationCallbackable is the signature of the callbacks used to configure the stripping.
+type StripConfigurationCallbackable func(config StripConfigurer) error
+
+// stripConfigurator implements StripConfigurer.
+type stripConfigurator struct {
+	comments bool
+}
+
+// SetComments defines if the comments of the blocks and the lines that only contain a comment are removed too.
+// If this method isn't called, by default the comments are kept.
+func (sc *stripConfigurator) SetComments(strip bool) error {
+	sc.comments = strip
+
+	return nil
+}
+
+//#endregion
+//#region strip
+
+// Strip removes the line number and the checksum of every block, producing clean gcode that can be edited by hand.
+//
+// It is the inverse operation of Renumber, useful after capture a stream or to archive a document.
+// It returns an error only if some option is invalid or some block doesn't implement block.Editor,
+// in that case the document isn't modified.
+func (d *Document) Strip(options ...StripConfigurationCallbackable) error {
+
+	configurator := &stripConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	blocks, err := editors(d.blocks)
+	if err != nil {
+		return fmt.Errorf("failed to strip document: %w", err)
+	}
+
+	defer d.index()
+
+	for _, b := range blocks {
+		b.SetLineNumber(nil)
+		b.SetChecksum(nil)
+
+		if configurator.comments {
+			b.SetComment("")
+		}
+	}
+
+	if configurator.comments {
+		lines := d.lines[:0]
+		for _, l := range d.lines {
+			if l.Block == nil && strings.HasPrefix(strings.TrimSpace(l.Text), ";") {
+				continue
+			}
+			lines = append(lines, l)
+		}
+		d.lines = lines
+	}
+
+	return nil
+}
+
+//#endregion
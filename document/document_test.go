@@ -0,0 +1,1106 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/checksum"
+	"github.com/mauroalderete/gcode-core/gcode/addressablegcode"
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestNew(t *testing.T) {
+	blocks := blocktest.Parse(t, "G28", "G1 X10")
+
+	d := New(blocks[0], nil, blocks[1])
+
+	if d.Len() != 2 {
+		t.Errorf("got %d blocks, want 2", d.Len())
+	}
+
+	got := d.Blocks()
+	got[0] = nil
+	if d.Blocks()[0] == nil {
+		t.Errorf("got document modified, want Blocks returning a copy")
+	}
+}
+
+func TestDocument_VerifyChecksums(t *testing.T) {
+
+	cases := map[string]struct {
+		lines    []string
+		workers  int
+		valid    bool
+		verified int
+		skipped  int
+		failures []ChecksumFailure
+	}{
+		"empty": {nil, 0, true, 0, 0, nil},
+		"all valid": {
+			[]string{"N3 T0*57", "N4 G92 E0*67", "N5 G28*22", "M105"},
+			0, true, 3, 1, nil,
+		},
+		"some corrupted": {
+			[]string{"N3 T0*57", "N4 G92 E0*68", "N5 G28*22", "G1 X2.0 Y2.0*10"},
+			0, true, 4, 0,
+			[]ChecksumFailure{
+				{Index: 1, LineNumber: 4, Numbered: true, Expected: 67, Actual: 68},
+				{Index: 3, Expected: 119, Actual: 10},
+			},
+		},
+		"single worker": {
+			[]string{"N3 T0*57", "N4 G92 E0*68"},
+			1, true, 2, 0,
+			[]ChecksumFailure{
+				{Index: 1, LineNumber: 4, Numbered: true, Expected: 67, Actual: 68},
+			},
+		},
+		"invalid workers": {[]string{"N3 T0*57"}, -1, false, 0, 0, nil},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := New(blocktest.Parse(t, tc.lines...)...)
+
+			var options []VerifyConfigurationCallbackable
+			if tc.workers != 0 {
+				options = append(options, func(config VerifyConfigurer) error {
+					return config.SetWorkers(tc.workers)
+				})
+			}
+
+			report, err := d.VerifyChecksums(options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if report.Verified != tc.verified || report.Skipped != tc.skipped {
+				t.Errorf("got verified %d and skipped %d, want %d and %d", report.Verified, report.Skipped, tc.verified, tc.skipped)
+			}
+
+			if report.Passed() != (len(tc.failures) == 0) {
+				t.Errorf("got passed %v, want %v", report.Passed(), len(tc.failures) == 0)
+			}
+
+			if len(report.Failures) != len(tc.failures) {
+				t.Errorf("got %d failures, want %d: %s", len(report.Failures), len(tc.failures), report)
+				return
+			}
+
+			for i, f := range report.Failures {
+				if f != tc.failures[i] {
+					t.Errorf("got failure %+v, want %+v", f, tc.failures[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDocument_VerifyChecksums_Repair(t *testing.T) {
+	d := New(blocktest.Parse(t, "N3 T0*57", "N4 G92 E0*68", "N5 G28*22", "G1 X2.0 Y2.0*10")...)
+
+	report, err := d.VerifyChecksums(func(config VerifyConfigurer) error {
+		return config.SetRepair(true)
+	})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	want := []ChecksumFailure{
+		{Index: 1, LineNumber: 4, Numbered: true, Expected: 67, Actual: 68, Repaired: true},
+		{Index: 3, Expected: 119, Actual: 10, Repaired: true},
+	}
+
+	repaired := report.Repaired()
+	if report.Passed() || len(repaired) != len(want) {
+		t.Errorf("got passed %v with %d repaired, want not passed with %d repaired: %s", report.Passed(), len(repaired), len(want), report)
+		return
+	}
+
+	for i, f := range repaired {
+		if f != want[i] {
+			t.Errorf("got failure %+v, want %+v", f, want[i])
+		}
+	}
+
+	// the document must be valid after the repair
+	report, err = d.VerifyChecksums()
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if !report.Passed() || report.Verified != 4 {
+		t.Errorf("got report %s, want 4 checksums verified without failures", report)
+	}
+
+	if got := d.Blocks()[1].ToLine("%l %c %p%k"); got != "N4 G92 E0*67" {
+		t.Errorf("got %s, want N4 G92 E0*67", got)
+	}
+}
+
+// TestDocument_VerifyChecksums_SharedHash verifies concurrently blocks that share the hash, it must be run with -race.
+func TestDocument_VerifyChecksums_SharedHash(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sb, "G1 X%d Y%d\n", i, i*2)
+	}
+
+	crc := checksum.NewCRC16()
+
+	d, err := Parse(strings.NewReader(sb.String()), func(config ParseConfigurer) error {
+		return config.SetBlockOptions(func(config block.BlockParserConfigurer) error {
+			return config.SetHash(crc)
+		})
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if err := d.Renumber(); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	report, err := d.VerifyChecksums(func(config VerifyConfigurer) error {
+		return config.SetWorkers(8)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if !report.Passed() || report.Verified != 500 {
+		t.Errorf("got report %s, want 500 checksums verified without failures", report)
+	}
+}
+
+func TestDocument_VerifyChecksums_Order(t *testing.T) {
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, "N4 G92 E0*68")
+	}
+
+	report, err := New(blocktest.Parse(t, lines...)...).VerifyChecksums()
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if len(report.Failures) != len(lines) {
+		t.Errorf("got %d failures, want %d", len(report.Failures), len(lines))
+		return
+	}
+
+	for i, f := range report.Failures {
+		if f.Index != i {
+			t.Errorf("got failure of the block %d at position %d, want them ordered", f.Index, i)
+			return
+		}
+	}
+}
+
+func TestDocument_Renumber(t *testing.T) {
+
+	cases := map[string]struct {
+		lines   []string
+		options []RenumberConfigurationCallbackable
+		valid   bool
+		want    []string
+	}{
+		"default": {
+			[]string{"T0", "N40 G92 E0*1", "G28"},
+			nil, true,
+			[]string{"N1 T0*59", "N2 G92 E0*69", "N3 G28*16"},
+		},
+		"base and step": {
+			[]string{"T0", "G28"},
+			[]RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetBase(10) },
+				func(config RenumberConfigurer) error { return config.SetStep(10) },
+			}, true,
+			[]string{"N10 T0*11", "N20 G28*33"},
+		},
+		"without checksum": {
+			[]string{"N7 T0*1", "G28"},
+			[]RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetChecksum(false) },
+			}, true,
+			[]string{"N1 T0", "N2 G28"},
+		},
+		"zero step": {
+			[]string{"G28"},
+			[]RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetStep(0) },
+			}, false, nil,
+		},
+		"overflow": {
+			[]string{"G28", "G28"},
+			[]RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetBase(4294967295) },
+			}, false, nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := New(blocktest.Parse(t, tc.lines...)...)
+
+			err := d.Renumber(tc.options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			for i, b := range d.Blocks() {
+				if got := formatBlock(b, "%l %c %p%k"); got != tc.want[i] {
+					t.Errorf("got %s, want %s", got, tc.want[i])
+				}
+			}
+
+			report, err := d.VerifyChecksums()
+			if err != nil || !report.Passed() {
+				t.Errorf("got report %v with error %v, want all checksums valid", report, err)
+			}
+		})
+	}
+}
+
+func TestDocument_Strip(t *testing.T) {
+
+	lines := []string{"N1 T0*59 ;tool", "N2 G92 E0*69", "G28 ;home", "N4 M105"}
+
+	cases := map[string]struct {
+		comments bool
+		want     []string
+	}{
+		"keep comments":  {false, []string{"T0 ;tool", "G92 E0", "G28 ;home", "M105"}},
+		"strip comments": {true, []string{"T0", "G92 E0", "G28", "M105"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := New(blocktest.Parse(t, lines...)...)
+
+			err := d.Strip(func(config StripConfigurer) error {
+				return config.SetComments(tc.comments)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			for i, b := range d.Blocks() {
+				if got := formatBlock(b, "%l %c %p%k %m"); got != tc.want[i] {
+					t.Errorf("got %s, want %s", got, tc.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("inverse of renumber", func(t *testing.T) {
+		d := New(blocktest.Parse(t, "T0", "G92 E0")...)
+
+		if err := d.Renumber(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := d.Strip(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		for i, want := range []string{"T0", "G92 E0"} {
+			if got := d.Blocks()[i].ToLine("%l %c %p%k"); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		valid  bool
+		blocks int
+		lines  int
+		want   string
+	}{
+		"empty":          {"", true, 0, 0, ""},
+		"blocks":         {"G28\nG1 X10", true, 2, 2, "G28\nG1 X10\n"},
+		"comments":       {";start\nG28 ;home\n\n  ; indented\nM105", true, 2, 5, ";start\nG28 ;home\n\n  ; indented\nM105\n"},
+		"crlf":           {"G28\r\n;end\r\n", true, 1, 2, "G28\n;end\n"},
+		"checksum":       {"N3 T0*57\nN4 G92 E0*67", true, 2, 2, "N3 T0*57\nN4 G92 E0*67\n"},
+		"invalid line":   {"G28\n;ok\nG 1", false, 0, 0, ""},
+		"invalid option": {"G28", false, 0, 0, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []ParseConfigurationCallbackable
+			if name == "invalid option" {
+				options = append(options, func(config ParseConfigurer) error {
+					return config.SetBlockOptions(nil)
+				})
+			}
+
+			d, err := Parse(strings.NewReader(tc.source), options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if d.Len() != tc.blocks || d.LineCount() != tc.lines {
+				t.Errorf("got %d blocks and %d lines, want %d blocks and %d lines", d.Len(), d.LineCount(), tc.blocks, tc.lines)
+			}
+
+			var sb strings.Builder
+			n, err := d.WriteTo(&sb)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if sb.String() != tc.want || int(n) != len(tc.want) || d.String() != tc.want {
+				t.Errorf("got %q (%d bytes), want %q", sb.String(), n, tc.want)
+			}
+		})
+	}
+
+	t.Run("line number in error", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("G28\n;ok\nG 1"))
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("got error %v, want error at line 3", err)
+		}
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 3 {
+			t.Errorf("got error %v, want a *ParseError at line 3", err)
+		}
+	})
+
+	t.Run("block options", func(t *testing.T) {
+		d, err := Parse(strings.NewReader("g28"), func(config ParseConfigurer) error {
+			return config.SetBlockOptions(func(config block.BlockParserConfigurer) error {
+				return config.SetCasePolicy(block.CaseNormalize)
+			})
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if d.String() != "G28\n" {
+			t.Errorf("got %q, want \"G28\\n\"", d.String())
+		}
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		source := "G28\n;" + strings.Repeat("x", 70000) + "\nM105\n"
+
+		d, err := Parse(strings.NewReader(source))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if d.String() != source {
+			t.Errorf("got %d bytes, want %d bytes", len(d.String()), len(source))
+		}
+	})
+}
+
+func TestDocument_Access(t *testing.T) {
+	d, err := Parse(strings.NewReader(";start\nG28\n\nG1 X10"))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if b, ok := d.Block(1); !ok || b.String() != "G1 X10" {
+		t.Errorf("got block %v %v, want G1 X10", b, ok)
+	}
+
+	if _, ok := d.Block(2); ok {
+		t.Errorf("got block out of range, want false")
+	}
+
+	if l, ok := d.Line(0); !ok || l.IsBlock() || l.Text != ";start" {
+		t.Errorf("got line %+v %v, want the comment line", l, ok)
+	}
+
+	if _, ok := d.Line(-1); ok {
+		t.Errorf("got line out of range, want false")
+	}
+
+	var visited []string
+	err = d.Walk(func(index int, line Line) error {
+		visited = append(visited, line.String())
+		return nil
+	})
+	if err != nil || strings.Join(visited, "|") != ";start|G28||G1 X10" {
+		t.Errorf("got %v visited with error %v, want all lines", visited, err)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = d.Walk(func(index int, line Line) error {
+		count++
+		if line.IsBlock() {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 2 {
+		t.Errorf("got error %v after %d lines, want stop after 2 lines", err, count)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestDocument_WriteToError(t *testing.T) {
+	d := New(blocktest.Parse(t, "G28")...)
+
+	if _, err := d.WriteTo(failingWriter{}); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+// readOnlyBlock hides the optional capabilities of a block, it only implements block.Blocker.
+type readOnlyBlock struct {
+	block.Blocker
+}
+
+func TestDocument_notEditable(t *testing.T) {
+	blocks := blocktest.Parse(t, "N1 G28*18", "N2 M105*37")
+
+	d := New(blocks[0], readOnlyBlock{blocks[1]})
+
+	if err := d.Strip(); err == nil {
+		t.Errorf("got error nil stripping, want error not nil")
+	}
+
+	if err := d.Renumber(); err == nil {
+		t.Errorf("got error nil renumbering, want error not nil")
+	}
+
+	if got := formatBlock(blocks[0], "%l %c%k"); got != "N1 G28*18" {
+		t.Errorf("got %s, want the first block unmodified", got)
+	}
+}
+
+func TestDocument_StripCommentLines(t *testing.T) {
+	d, err := Parse(strings.NewReader(";start\nN1 G28*18 ;home\n\nN2 M105*37"))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if err := d.Strip(); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if d.String() != ";start\nG28 ;home\n\nM105\n" {
+		t.Errorf("got %q, want the comment lines kept", d.String())
+	}
+
+	err = d.Strip(func(config StripConfigurer) error {
+		return config.SetComments(true)
+	})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if d.String() != "G28\n\nM105\n" {
+		t.Errorf("got %q, want the comment lines removed", d.String())
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+
+	source := "  g1 x0010.50 y2 ;first\r\nN1 G28*18\r\n;comment  \r\n\r\nG1   X1.000 F1200"
+
+	parse := func(t *testing.T) *Document {
+		d, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+			if err := config.SetRoundTrip(true); err != nil {
+				return err
+			}
+			return config.SetBlockOptions(func(config block.BlockParserConfigurer) error {
+				return config.SetCasePolicy(block.CaseNormalize)
+			})
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		return d
+	}
+
+	t.Run("unmodified", func(t *testing.T) {
+		d := parse(t)
+
+		var sb strings.Builder
+		n, err := d.WriteTo(&sb)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if sb.String() != source || int(n) != len(source) {
+			t.Errorf("got %q (%d bytes), want %q", sb.String(), n, source)
+		}
+
+		for i, l := range d.Lines() {
+			if l.Modified() {
+				t.Errorf("got line %d modified, want unmodified", i)
+			}
+		}
+	})
+
+	t.Run("modified block", func(t *testing.T) {
+		d := parse(t)
+
+		b, _ := d.Block(0)
+		b.(block.Editor).SetComment(";changed")
+
+		b, _ = d.Block(2)
+		b.(block.Editor).SetComment(";last")
+
+		var sb strings.Builder
+		if _, err := d.WriteTo(&sb); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want := "G1 X10.5 Y2 ;changed\r\nN1 G28*18\r\n;comment  \r\n\r\nG1 X1.0 F1200 ;last"
+		if sb.String() != want {
+			t.Errorf("got %q, want %q", sb.String(), want)
+		}
+	})
+
+	t.Run("writer with other options", func(t *testing.T) {
+		d := parse(t)
+
+		var sb strings.Builder
+		w, err := NewWriter(&sb, func(config WriterConfigurer) error {
+			return config.SetLineEnding("\n")
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := w.WriteDocument(d); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want := "G1 X10.5 Y2 ;first\nN1 G28*18\n;comment  \n\nG1 X1.0 F1200\n"
+		if sb.String() != want {
+			t.Errorf("got %q, want %q", sb.String(), want)
+		}
+	})
+
+	t.Run("original", func(t *testing.T) {
+		d := parse(t)
+
+		l, _ := d.Line(0)
+		if raw, ok := l.Original(); !ok || raw != "  g1 x0010.50 y2 ;first\r\n" {
+			t.Errorf("got original %q %v, want the first line", raw, ok)
+		}
+
+		d = New(blocktest.Parse(t, "G28")...)
+		l, _ = d.Line(0)
+		if _, ok := l.Original(); ok || !l.Modified() {
+			t.Errorf("got original recorded, want a line without original bytes")
+		}
+	})
+}
+
+func TestDocument_Find(t *testing.T) {
+	source := ";start\nN1 M104 S200*79\nN2 T0*56\nN3 G1 X10*99\nN4 M104 S210*78\nN5 T1*58\nN6 G92.1*35\nM110 N1\nN1 G28*18"
+
+	d, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	cases := map[string]struct {
+		got  []int
+		want []int
+	}{
+		"command":         {d.FindCommand('M', 104), []int{0, 3}},
+		"command string":  {d.FindCommandString("G92.1"), []int{5}},
+		"missing command": {d.FindCommand('M', 109), nil},
+		"word":            {d.FindWord('T'), []int{1, 4}},
+		"missing word":    {d.FindWord('S'), nil},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if fmt.Sprint(tc.got) != fmt.Sprint(tc.want) {
+				t.Errorf("got %v, want %v", tc.got, tc.want)
+			}
+		})
+	}
+
+	t.Run("line number", func(t *testing.T) {
+		if i, ok := d.FindByLineNumber(3); !ok || i != 2 {
+			t.Errorf("got %d %v, want 2 true", i, ok)
+		}
+
+		// the first block is returned when the line numbers are reset
+		if i, ok := d.FindByLineNumber(1); !ok || i != 0 {
+			t.Errorf("got %d %v, want 0 true", i, ok)
+		}
+
+		if _, ok := d.FindByLineNumber(99); ok {
+			t.Errorf("got line number 99 found, want not found")
+		}
+	})
+
+	t.Run("updated by renumber", func(t *testing.T) {
+		err := d.Renumber(func(config RenumberConfigurer) error {
+			return config.SetBase(100)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if i, ok := d.FindByLineNumber(107); !ok || i != 7 {
+			t.Errorf("got %d %v, want 7 true", i, ok)
+		}
+
+		if err := d.Strip(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, ok := d.FindByLineNumber(107); ok {
+			t.Errorf("got line number found after strip, want not found")
+		}
+	})
+
+	t.Run("reindex", func(t *testing.T) {
+		b, _ := d.Block(0)
+		ln, err := addressablegcode.New[uint32]('N', 500)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+		b.(block.Editor).SetLineNumber(ln)
+
+		if _, ok := d.FindByLineNumber(500); ok {
+			t.Errorf("got line number found before reindex, want not found")
+		}
+
+		d.Reindex()
+		if i, ok := d.FindByLineNumber(500); !ok || i != 0 {
+			t.Errorf("got %d %v, want 0 true", i, ok)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		got := d.FindWord('T')
+		got[0] = 99
+		if d.FindWord('T')[0] != 1 {
+			t.Errorf("got index modified, want a copy")
+		}
+	})
+}
+
+func TestDocument_Layers(t *testing.T) {
+
+	markers := strings.Join([]string{
+		"G28",
+		"G1 Z5 F3000",
+		";LAYER:0",
+		"G0 X10 Y10 Z0.2",
+		"G1 X20 Y10 E1.5",
+		";LAYER:1",
+		"G0 Z0.4",
+		"G1 X10 Y10 E3",
+		";LAYER:2",
+		"G1 X20 Y20 E4.5",
+		"M84",
+	}, "\n")
+
+	heights := strings.Join([]string{
+		"G28",
+		"G1 Z5 F3000",
+		"G0 X10 Y10 Z0.2",
+		"G1 X20 Y10 E1.5",
+		"G1 E0.5",
+		"G0 Z0.8",
+		"G0 X0 Y0",
+		"G0 Z0.2",
+		"G1 E1.5",
+		"G1 X10 Y0 E2",
+		"G91",
+		"G0 Z0.2",
+		"G90",
+		"G1 X10 Y10 E3",
+		"M84",
+	}, "\n")
+
+	cases := map[string]struct {
+		source    string
+		detection LayerDetection
+		valid     bool
+		want      []Layer
+	}{
+		"markers": {markers, LayerMarkers, true, []Layer{
+			{Index: 0, Z: 0.2, Start: 2, End: 4, StartLine: 2, EndLine: 5},
+			{Index: 1, Z: 0.4, Start: 4, End: 6, StartLine: 5, EndLine: 8},
+			{Index: 2, Z: 0.4, Start: 6, End: 8, StartLine: 8, EndLine: 11},
+		}},
+		"auto with markers": {markers, LayerAuto, true, []Layer{
+			{Index: 0, Z: 0.2, Start: 2, End: 4, StartLine: 2, EndLine: 5},
+			{Index: 1, Z: 0.4, Start: 4, End: 6, StartLine: 5, EndLine: 8},
+			{Index: 2, Z: 0.4, Start: 6, End: 8, StartLine: 8, EndLine: 11},
+		}},
+		"heights": {heights, LayerHeights, true, []Layer{
+			{Index: 0, Z: 0.2, Start: 2, End: 11, StartLine: 2, EndLine: 11},
+			{Index: 1, Z: 0.4, Start: 11, End: 15, StartLine: 11, EndLine: 15},
+		}},
+		"auto without markers": {heights, LayerAuto, true, []Layer{
+			{Index: 0, Z: 0.2, Start: 2, End: 11, StartLine: 2, EndLine: 11},
+			{Index: 1, Z: 0.4, Start: 11, End: 15, StartLine: 11, EndLine: 15},
+		}},
+		"markers without markers": {heights, LayerMarkers, true, nil},
+		"empty":                   {"", LayerAuto, true, nil},
+		"invalid detection":       {markers, LayerDetection(9), false, nil},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			layers, err := d.Layers(func(config LayersConfigurer) error {
+				return config.SetDetection(tc.detection)
+			})
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if len(layers) != len(tc.want) {
+				t.Errorf("got %d layers %v, want %d layers %v", len(layers), layers, len(tc.want), tc.want)
+				return
+			}
+
+			for i, l := range layers {
+				if l != tc.want[i] {
+					t.Errorf("got %+v, want %+v", l, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDocument_Sections(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		want   Sections
+	}{
+		"markers": {
+			"M104 S200\nG28\n;LAYER:0\nG0 X10 Y10 Z0.2\nG1 X20 Y10 E1.5\nG0 X0 Y0\nM104 S0\nM84",
+			Sections{
+				Header: Range{Start: 0, End: 2, StartLine: 0, EndLine: 2},
+				Body:   Range{Start: 2, End: 4, StartLine: 2, EndLine: 5},
+				Footer: Range{Start: 4, End: 7, StartLine: 5, EndLine: 8},
+			},
+		},
+		"heights": {
+			"G28\nG0 X10 Y10 Z0.2\nG1 X20 Y10 E1.5\nM84",
+			Sections{
+				Header: Range{Start: 0, End: 1, StartLine: 0, EndLine: 1},
+				Body:   Range{Start: 1, End: 3, StartLine: 1, EndLine: 3},
+				Footer: Range{Start: 3, End: 4, StartLine: 3, EndLine: 4},
+			},
+		},
+		"without extrusion": {
+			"G28\nG0 X10",
+			Sections{
+				Header: Range{Start: 0, End: 2, StartLine: 0, EndLine: 2},
+				Body:   Range{Start: 2, End: 2, StartLine: 2, EndLine: 2},
+				Footer: Range{Start: 2, End: 2, StartLine: 2, EndLine: 2},
+			},
+		},
+		"empty": {"", Sections{}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := d.Sections(); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("get", func(t *testing.T) {
+		s := Sections{Footer: Range{StartLine: 3}}
+
+		if r, err := s.Get(SectionFooter); err != nil || r.StartLine != 3 {
+			t.Errorf("got %+v with error %v, want the footer", r, err)
+		}
+
+		if _, err := s.Get(Section(7)); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("replace header", func(t *testing.T) {
+		d, err := Parse(strings.NewReader("M104 S200\nG28\n;LAYER:0\nG0 X10 Y10 Z0.2\nG1 X20 Y10 E1.5\nM84"))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		header := blocktest.Parse(t, "G28", "M109 S210")
+		err = d.ReplaceSection(SectionHeader, Line{Text: ";new header"}, Line{Block: header[0]}, Line{Block: header[1]})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want := ";new header\nG28\nM109 S210\n;LAYER:0\nG0 X10 Y10 Z0.2\nG1 X20 Y10 E1.5\nM84\n"
+		if d.String() != want {
+			t.Errorf("got %q, want %q", d.String(), want)
+		}
+
+		if d.Len() != 5 || len(d.FindCommand('M', 109)) != 1 {
+			t.Errorf("got %d blocks, want 5 blocks indexed", d.Len())
+		}
+
+		if err := d.ReplaceSection(Section(7)); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestDocument_Stats(t *testing.T) {
+
+	source := "; start\n\nN10 G28*17\nN11 G1 X1 Y1 ;move\nN12 G1 X2 Y2\nG1 X3 Y3\nM84\n"
+
+	d, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	s := d.Stats()
+
+	if s.Lines != 7 || s.Blocks != 5 || s.BlankLines != 1 || s.CommentLines != 1 || s.CommentedBlocks != 1 {
+		t.Errorf("got %d lines, %d blocks, %d blank, %d comments and %d commented, want 7, 5, 1, 1 and 1", s.Lines, s.Blocks, s.BlankLines, s.CommentLines, s.CommentedBlocks)
+	}
+
+	if s.Numbered != 3 || s.MinLineNumber != 10 || s.MaxLineNumber != 12 || s.Checksummed != 1 {
+		t.Errorf("got %d numbered from N%d to N%d and %d checksums, want 3 from N10 to N12 and 1", s.Numbered, s.MinLineNumber, s.MaxLineNumber, s.Checksummed)
+	}
+
+	if s.Commands["G1"] != 3 || s.Commands["G28"] != 1 || s.Commands["M84"] != 1 || len(s.Commands) != 3 {
+		t.Errorf("got commands %v, want G1=3 G28=1 M84=1", s.Commands)
+	}
+
+	if s.Bytes != int64(len(d.String())) {
+		t.Errorf("got %d bytes, want %d", s.Bytes, len(d.String()))
+	}
+
+	if s.CommentRatio() != 2.0/7 || s.ChecksumCoverage() != 0.2 {
+		t.Errorf("got comment ratio %v and checksum coverage %v, want %v and 0.2", s.CommentRatio(), s.ChecksumCoverage(), 2.0/7)
+	}
+
+	if !strings.Contains(s.String(), "commands: G1=3 G28=1 M84=1") || !strings.Contains(s.String(), "line numbers: N10 to N12") {
+		t.Errorf("got summary %q, want the commands and line numbers", s.String())
+	}
+
+	empty := New().Stats()
+	if empty.CommentRatio() != 0 || empty.ChecksumCoverage() != 0 || empty.Bytes != 0 {
+		t.Errorf("got stats %+v of an empty document, want zeros", empty)
+	}
+}
+
+func TestDocument_Edit(t *testing.T) {
+
+	source := "G28\n; travel\nG1 X1\n;LAYER:0\nG1 X2 E1\nG1 X3 E2\nM84\n"
+
+	cases := map[string]struct {
+		edit  func(d *Document) error
+		want  string
+		valid bool
+	}{
+		"insert after": {
+			edit:  func(d *Document) error { return d.InsertAfter(0, blocktest.Parse(t, "G92 E0")...) },
+			want:  "G28\nG92 E0\n; travel\nG1 X1\n;LAYER:0\nG1 X2 E1\nG1 X3 E2\nM84\n",
+			valid: true,
+		},
+		"insert at start": {
+			edit:  func(d *Document) error { return d.InsertAfter(-1, blocktest.Parse(t, "M140 S60", "G21")...) },
+			want:  "M140 S60\nG21\nG28\n; travel\nG1 X1\n;LAYER:0\nG1 X2 E1\nG1 X3 E2\nM84\n",
+			valid: true,
+		},
+		"insert at end": {
+			edit:  func(d *Document) error { return d.InsertAfter(4, blocktest.Parse(t, "M107")...) },
+			want:  source + "M107\n",
+			valid: true,
+		},
+		"replace range": {
+			edit:  func(d *Document) error { return d.ReplaceRange(1, 3, blocktest.Parse(t, "G0 X9")...) },
+			want:  "G28\n; travel\nG0 X9\nG1 X3 E2\nM84\n",
+			valid: true,
+		},
+		"replace empty range": {
+			edit:  func(d *Document) error { return d.ReplaceRange(4, 4, blocktest.Parse(t, "M400")...) },
+			want:  "G28\n; travel\nG1 X1\n;LAYER:0\nG1 X2 E1\nG1 X3 E2\nM400\nM84\n",
+			valid: true,
+		},
+		"replace at end": {
+			edit:  func(d *Document) error { return d.ReplaceRange(5, 5, blocktest.Parse(t, "M400")...) },
+			want:  source + "M400\n",
+			valid: true,
+		},
+		"delete range": {
+			edit:  func(d *Document) error { return d.DeleteRange(3, 5) },
+			want:  "G28\n; travel\nG1 X1\n;LAYER:0\nG1 X2 E1\n",
+			valid: true,
+		},
+		"delete nothing": {
+			edit:  func(d *Document) error { return d.DeleteRange(2, 2) },
+			want:  source,
+			valid: true,
+		},
+		"insert out of range": {
+			edit: func(d *Document) error { return d.InsertAfter(5, blocktest.Parse(t, "M400")...) },
+		},
+		"insert nil": {
+			edit: func(d *Document) error { return d.InsertAfter(0, nil) },
+		},
+		"invalid range": {
+			edit: func(d *Document) error { return d.ReplaceRange(3, 2) },
+		},
+		"delete out of range": {
+			edit: func(d *Document) error { return d.DeleteRange(0, 6) },
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			err = tc.edit(d)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				if d.String() != source {
+					t.Errorf("got document modified %q, want %q", d.String(), source)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if d.String() != tc.want {
+				t.Errorf("got %q, want %q", d.String(), tc.want)
+			}
+
+			if len(d.FindCommandString("G28")) != 1 || d.Len() != len(d.Blocks()) {
+				t.Errorf("got indexes outdated after the edition")
+			}
+		})
+	}
+
+	t.Run("renumber on edit", func(t *testing.T) {
+		d := New(blocktest.Parse(t, "G28", "G1 X1", "M84")...)
+
+		if err := d.SetRenumberOnEdit(true, func(config RenumberConfigurer) error {
+			return config.SetBase(10)
+		}); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := d.InsertAfter(0, blocktest.Parse(t, "G92 E0")...); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if i, ok := d.FindByLineNumber(13); !ok || i != 3 {
+			t.Errorf("got N13 at %d %v, want the block 3", i, ok)
+		}
+
+		report, err := d.VerifyChecksums()
+		if err != nil || !report.Passed() || report.Verified != 4 {
+			t.Errorf("got report %v with error %v, want 4 checksums verified", report, err)
+		}
+
+		if err := d.SetRenumberOnEdit(true, func(config RenumberConfigurer) error {
+			return config.SetStep(0)
+		}); err == nil {
+			t.Errorf("got error nil with an invalid option, want error not nil")
+		}
+	})
+}
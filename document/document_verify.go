@@ -0,0 +1,270 @@
+package document
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region checksum report
+
+// ChecksumFailure describes a block whose checksum couldn't be verified.
+type ChecksumFailure struct {
+	// Index is the position of the block in the document, starting at zero.
+	Index int
+
+	// LineNumber is the address of the N word of the block, it is only valid if Numbered is true.
+	LineNumber uint32
+
+	// Numbered is true if the block has a line number.
+	Numbered bool
+
+	// Expected is the checksum calculated from the content of the block.
+	Expected uint32
+
+	// Actual is the checksum stored in the block.
+	Actual uint32
+
+	// Err is the error returned by the verification, if it couldn't be executed. In that case, Expected is zero.
+	Err error
+
+	// Repaired is true if the checksum stored in the block was replaced by the expected one.
+	Repaired bool
+}
+
+// String returns the failure formatted.
+func (f ChecksumFailure) String() string {
+	location := fmt.Sprintf("block %d", f.Index)
+	if f.Numbered {
+		location = fmt.Sprintf("%s (N%d)", location, f.LineNumber)
+	}
+
+	if f.Err != nil {
+		return fmt.Sprintf("%s: %v", location, f.Err)
+	}
+
+	if f.Repaired {
+		return fmt.Sprintf("%s: checksum %d, expected %d, repaired", location, f.Actual, f.Expected)
+	}
+
+	return fmt.Sprintf("%s: checksum %d, expected %d", location, f.Actual, f.Expected)
+}
+
+// ChecksumReport contains the result of the verification of all checksums of a document.
+type ChecksumReport struct {
+	// Verified is the number of blocks with checksum that were verified, including the failed ones.
+	Verified int
+
+	// Skipped is the number of blocks without checksum.
+	Skipped int
+
+	// Failures lists the blocks whose checksum doesn't match, ordered by their position in the document.
+	Failures []ChecksumFailure
+}
+
+// Passed returns true if all checksums verified match.
+//
+// The failures repaired are still failures, because the document didn't match when it was verified.
+func (r *ChecksumReport) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Repaired returns the failures whose checksum was replaced by the expected one, ordered by their position in the document.
+func (r *ChecksumReport) Repaired() []ChecksumFailure {
+	var repaired []ChecksumFailure
+	for _, f := range r.Failures {
+		if f.Repaired {
+			repaired = append(repaired, f)
+		}
+	}
+
+	return repaired
+}
+
+// String returns a summary of the report.
+func (r *ChecksumReport) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%d checksums verified, %d failed, %d blocks without checksum", r.Verified, len(r.Failures), r.Skipped))
+	if repaired := len(r.Repaired()); repaired > 0 {
+		sb.WriteString(fmt.Sprintf(", %d repaired", repaired))
+	}
+	for _, f := range r.Failures {
+		sb.WriteString("\n")
+		sb.WriteString(f.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region verification configuration
+
+// VerifyConfigurer defines the options of the checksum verification.
+type VerifyConfigurer interface {
+	// Set the number of blocks verified concurrently
+	SetWorkers(workers int) error
+
+	// Set if the checksums that don't match are replaced by the expected ones
+	SetRepair(repair bool) error
+
+	// Set the callback that receives the progress of the verification
+	SetProgress(progress ProgressCallbackable) error
+}
+
+// VerifyConfigurationCallbackable is the signature of the callbacks used to configure the verification.
+type VerifyConfigurationCallbackable func(config VerifyConfigurer) error
+
+// verifyConfigurator implements VerifyConfigurer.
+type verifyConfigurator struct {
+	workers  int
+	repair   bool
+	progress ProgressCallbackable
+}
+
+// SetWorkers defines the number of blocks verified concurrently. It must be positive.
+// If this method isn't called, by default the number of workers is runtime.GOMAXPROCS.
+func (vc *verifyConfigurator) SetWorkers(workers int) error {
+	if workers <= 0 {
+		return fmt.Errorf("failed to set workers, it must be positive: %d", workers)
+	}
+
+	vc.workers = workers
+
+	return nil
+}
+
+// SetRepair defines if the checksum of the blocks that fail is replaced by the expected one, to fix a corrupted document.
+// The blocks whose verification returns an error and the blocks that don't implement block.Editor aren't repaired.
+// If this method isn't called, by default the blocks aren't modified.
+func (vc *verifyConfigurator) SetRepair(repair bool) error {
+	vc.repair = repair
+
+	return nil
+}
+
+// SetProgress defines the callback that receives the progress of the verification. Doesn't accept nil.
+// The lines reported are the blocks sent to the workers, including the ones without checksum, over the number of blocks of the document.
+// If this method isn't called, by default the progress isn't reported.
+func (vc *verifyConfigurator) SetProgress(progress ProgressCallbackable) error {
+	if progress == nil {
+		return fmt.Errorf("failed to set progress, the callback mustn't be nil")
+	}
+
+	vc.progress = progress
+
+	return nil
+}
+
+//#endregion
+//#region verification
+
+// VerifyChecksums verifies concurrently the checksum of every block of the document that has one.
+//
+// It returns a report with the blocks that fail, including the expected and actual values.
+// If the repair mode is enabled, the failures are repaired and flagged in the report.
+// It returns an error only if some option is invalid.
+func (d *Document) VerifyChecksums(options ...VerifyConfigurationCallbackable) (*ChecksumReport, error) {
+
+	configurator := &verifyConfigurator{
+		workers: runtime.GOMAXPROCS(0),
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	report := &ChecksumReport{}
+
+	// each worker stores the result in the position of the block, so the report keeps the order of the document
+	results := make([]*ChecksumFailure, len(d.blocks))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < configurator.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = d.verifyBlock(i, configurator.repair)
+			}
+		}()
+	}
+
+	for i, b := range d.blocks {
+		if configurator.progress != nil && i > 0 && i%PROGRESS_INTERVAL == 0 {
+			configurator.progress(Progress{Lines: i, TotalLines: len(d.blocks)})
+		}
+
+		if b.Checksum() == nil {
+			report.Skipped++
+			continue
+		}
+
+		report.Verified++
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if configurator.progress != nil {
+		configurator.progress(Progress{Lines: len(d.blocks), TotalLines: len(d.blocks)})
+	}
+
+	for _, failure := range results {
+		if failure != nil {
+			report.Failures = append(report.Failures, *failure)
+		}
+	}
+
+	return report, nil
+}
+
+// verifyBlock verifies the checksum of the block in the position required, it returns nil if it matches.
+//
+// If repair is true and the checksum doesn't match, it is replaced by the expected one.
+func (d *Document) verifyBlock(index int, repair bool) *ChecksumFailure {
+	b := d.blocks[index]
+
+	failure := &ChecksumFailure{
+		Index:  index,
+		Actual: b.Checksum().Address(),
+	}
+
+	if ln := b.LineNumber(); ln != nil {
+		failure.LineNumber = ln.Address()
+		failure.Numbered = true
+	}
+
+	ok, err := b.VerifyChecksum()
+	if err != nil {
+		failure.Err = err
+		return failure
+	}
+
+	if ok {
+		return nil
+	}
+
+	expected, err := b.CalculateChecksum()
+	if err != nil {
+		failure.Err = err
+		return failure
+	}
+
+	failure.Expected = expected.Address()
+
+	if e, ok := b.(block.Editor); ok && repair {
+		e.SetChecksum(expected)
+		failure.Repaired = true
+	}
+
+	return failure
+}
+
+//#endregion
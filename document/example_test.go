@@ -0,0 +1,178 @@
+package document_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func ExampleParse() {
+	source := ";start\nG28 ;home\n\nG1 X10.5 Y2"
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("%d blocks in %d lines\n", d.Len(), d.LineCount())
+
+	if err := d.Renumber(); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if _, err := d.WriteTo(os.Stdout); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	// Output:
+	// 2 blocks in 4 lines
+	// ;start
+	// N1 G28*18 ;home
+	//
+	// N2 G1 X10.5 Y2*3
+}
+
+func ExampleDocument_VerifyChecksums() {
+	var blocks []block.Blocker
+
+	for _, line := range []string{"N3 T0*57", "N4 G92 E0*68", "N5 G28*22"} {
+		b, err := gcodeblock.Parse(line)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		blocks = append(blocks, b)
+	}
+
+	report, err := document.New(blocks...).VerifyChecksums()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(report)
+
+	// Output:
+	// 3 checksums verified, 1 failed, 0 blocks without checksum
+	// block 1 (N4): checksum 68, expected 67
+}
+
+func ExampleDocument_VerifyChecksums_repair() {
+	var blocks []block.Blocker
+
+	for _, line := range []string{"N3 T0*57", "N4 G92 E0*68", "N5 G28*22"} {
+		b, err := gcodeblock.Parse(line)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		blocks = append(blocks, b)
+	}
+
+	d := document.New(blocks...)
+	report, err := d.VerifyChecksums(func(config document.VerifyConfigurer) error {
+		return config.SetRepair(true)
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(report)
+	fmt.Println(d.Blocks()[1].ToLine("%l %c %p%k"))
+
+	// Output:
+	// 3 checksums verified, 1 failed, 0 blocks without checksum, 1 repaired
+	// block 1 (N4): checksum 68, expected 67, repaired
+	// N4 G92 E0*67
+}
+
+func ExampleDocument_Renumber() {
+	var blocks []block.Blocker
+
+	for _, line := range []string{"T0", "G92 E0", "G28"} {
+		b, err := gcodeblock.Parse(line)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		blocks = append(blocks, b)
+	}
+
+	d := document.New(blocks...)
+	if err := d.Renumber(); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print(d)
+
+	// Output:
+	// N1 T0*59
+	// N2 G92 E0*69
+	// N3 G28*16
+}
+
+func ExampleNewWriter() {
+	d, err := document.Parse(strings.NewReader(";start\nN1 G28*18 ;home\nN2 M105*37"))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	w, err := document.NewWriter(os.Stdout, func(config document.WriterConfigurer) error {
+		return config.SetComments(false)
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if err := w.WriteDocument(d); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("%d lines, %d bytes\n", w.Lines(), w.Bytes())
+
+	// Output:
+	// N1 G28*18
+	// N2 M105*37
+	// 2 lines, 21 bytes
+}
+
+func ExampleDocument_Layers() {
+	source := "G28\n;LAYER:0\nG0 X10 Y10 Z0.2\nG1 X20 Y10 E1.5\n;LAYER:1\nG0 Z0.4\nG1 X10 Y10 E3"
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	layers, err := d.Layers()
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	for _, l := range layers {
+		fmt.Println(l)
+	}
+
+	// Output:
+	// layer 0 at Z0.2: blocks [1, 3)
+	// layer 1 at Z0.4: blocks [3, 5)
+}
@@ -0,0 +1,86 @@
+package document
+
+import (
+	"strings"
+)
+
+//#region extended commands
+
+// IsExtendedCommand returns true if the text is an extended command of Klipper, like "EXCLUDE_OBJECT_START NAME=part_1".
+// Its name has two or more letters, digits or underscores, being the first two of them letters or underscores,
+// so it can't be confused with a gcode word like G28.
+func IsExtendedCommand(text string) bool {
+	name, _, _ := strings.Cut(strings.TrimSpace(text), " ")
+	if len(name) < 2 {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		letter := c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+
+		if !letter && (i < 2 || c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseExtendedCommand returns the name of an extended command of Klipper in upper case and its arguments,
+// indexed by their names in upper case, like "EXCLUDE_OBJECT_START" and {"NAME": "part_1"}.
+// The comment after a semicolon is ignored and the quotes around the values are removed, the quoted values can contain spaces.
+// It returns false if the text isn't an extended command.
+func ParseExtendedCommand(text string) (string, map[string]string, bool) {
+	text, _, _ = strings.Cut(text, ";")
+	if !IsExtendedCommand(text) {
+		return "", nil, false
+	}
+
+	fields := splitArguments(text)
+	arguments := map[string]string{}
+
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		arguments[strings.ToUpper(key)] = strings.Trim(value, "\"'")
+	}
+
+	return strings.ToUpper(fields[0]), arguments, true
+}
+
+//#endregion
+//#region private functions
+
+// splitArguments splits a text by the spaces outside of quotes.
+func splitArguments(text string) []string {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+
+	for _, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t':
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+
+		field.WriteRune(c)
+	}
+
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+//#endregion
@@ -0,0 +1,69 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsExtendedCommand(t *testing.T) {
+	cases := map[string]bool{
+		"EXCLUDE_OBJECT_START NAME=part": true,
+		"PRINT_START":                    true,
+		"print_end":                      true,
+		"M84":                            false,
+		"G28 X0":                         false,
+		"T0":                             false,
+		"X":                              false,
+		"SET_FAN_SPEED FAN=aux SPEED=1":  true,
+		"BED-MESH":                       false,
+		"":                               false,
+	}
+
+	for text, want := range cases {
+		t.Run(text, func(t *testing.T) {
+			if got := IsExtendedCommand(text); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseExtendedCommand(t *testing.T) {
+	command, arguments, ok := ParseExtendedCommand(`exclude_object_define name="part 1" center=10,10 ; defined by the slicer`)
+	if !ok {
+		t.Fatalf("got false, want the command parsed")
+	}
+
+	if command != "EXCLUDE_OBJECT_DEFINE" {
+		t.Errorf("got command %s, want EXCLUDE_OBJECT_DEFINE", command)
+	}
+
+	want := map[string]string{"NAME": "part 1", "CENTER": "10,10"}
+	if !reflect.DeepEqual(arguments, want) {
+		t.Errorf("got arguments %v, want %v", arguments, want)
+	}
+
+	if _, _, ok := ParseExtendedCommand("G1 X10"); ok {
+		t.Errorf("got true, want false for gcode")
+	}
+}
+
+func TestParse_extendedCommands(t *testing.T) {
+	source := "PRINT_START BED=60\nG28\n"
+
+	if _, err := Parse(strings.NewReader(source)); err == nil {
+		t.Errorf("got error nil, want the extended command rejected by default")
+	}
+
+	d, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+		return config.SetExtendedCommands(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if d.Len() != 1 || d.LineCount() != 2 || d.String() != source {
+		t.Errorf("got %d blocks in %q, want the extended command kept as a line", d.Len(), d.String())
+	}
+}
@@ -0,0 +1,56 @@
+package document
+
+import (
+	"strings"
+)
+
+// macroKeywords are the keywords that start the statements of the custom macros B of Fanuc.
+var macroKeywords = []string{"IF", "GOTO", "WHILE", "END"}
+
+//#region macro statements
+
+// IsMacroStatement returns true if the text is a line of the custom macros B of Fanuc that can't be parsed as a block:
+// a statement, like "IF [#1 GT 10] GOTO 20", "WHILE [#1 LT 5] DO1", "END1" or "#100 = #100 + 1", a block with variables
+// or expressions, like "G1 X#24 Y[#25 + 2]", or a line of the structure of the program, like "%", "O1000" or "(comment)".
+// The statements can start with a sequence number, like "N10 GOTO 20", and the keywords are case-insensitive.
+func IsMacroStatement(text string) bool {
+	text = strings.ToUpper(strings.TrimSpace(text))
+
+	if text == "%" || strings.HasPrefix(text, "(") {
+		return true
+	}
+
+	if len(text) > 1 && text[0] == 'O' && text[1] >= '0' && text[1] <= '9' {
+		return true
+	}
+
+	// the variables and the brackets before the comment
+	code, _, _ := strings.Cut(text, "(")
+	if strings.ContainsAny(code, "#[") {
+		return true
+	}
+
+	// the sequence number
+	if strings.HasPrefix(code, "N") {
+		i := 1
+		for i < len(code) && code[i] >= '0' && code[i] <= '9' {
+			i++
+		}
+		code = strings.TrimSpace(code[i:])
+	}
+
+	for _, keyword := range macroKeywords {
+		if !strings.HasPrefix(code, keyword) {
+			continue
+		}
+
+		rest := code[len(keyword):]
+		if rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '[' || (rest[0] >= '0' && rest[0] <= '9') {
+			return true
+		}
+	}
+
+	return false
+}
+
+//#endregion
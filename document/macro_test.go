@@ -0,0 +1,49 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMacroStatement(t *testing.T) {
+	cases := map[string]bool{
+		"IF [#1 GT 10] GOTO 20":  true,
+		"N10 goto 20":            true,
+		"WHILE [#1 LT 5] DO1":    true,
+		"END1":                   true,
+		"#100 = #100 + 1":        true,
+		"G1 X#24 Y[#25 + 2]":     true,
+		"O1000":                  true,
+		"%":                      true,
+		"(ROUGHING)":             true,
+		"G1 X10 (#1 is ignored)": false,
+		"G1 X10":                 false,
+		"ENDING":                 false,
+		"":                       false,
+	}
+
+	for text, want := range cases {
+		if got := IsMacroStatement(text); got != want {
+			t.Errorf("got %v for %q, want %v", got, text, want)
+		}
+	}
+}
+
+func TestParse_macroStatements(t *testing.T) {
+	source := "%\nO1000\n#1 = 0\nWHILE [#1 LT 3] DO1\nG1 X[#1 * 10]\n#1 = #1 + 1\nEND1\nG28\nM30\n%\n"
+
+	if _, err := Parse(strings.NewReader(source)); err == nil {
+		t.Errorf("got error nil, want the macro statements rejected by default")
+	}
+
+	d, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+		return config.SetMacroStatements(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if d.Len() != 2 || d.LineCount() != 10 || d.String() != source {
+		t.Errorf("got %d blocks in %q, want the macro statements kept as lines", d.Len(), d.String())
+	}
+}
@@ -0,0 +1,152 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+//#region mapped file
+
+// MappedFile is a gcode file opened in read-only mode and mapped in memory, where the operating system supports it.
+//
+// The parsing of a mapped file reads the lines directly from the memory of the file, without copying it to intermediate buffers,
+// and the regions addressed by an OffsetIndex can be parsed again without reading the file.
+// In the systems without support, the whole file is read in memory.
+type MappedFile struct {
+	// content of the file
+	data []byte
+
+	// true if data is mapped, so it must be unmapped when the file is closed
+	mapped bool
+}
+
+// Bytes returns the content of the file. It mustn't be modified, nor used after closing the file.
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// Len returns the size of the file.
+func (m *MappedFile) Len() int {
+	return len(m.data)
+}
+
+// Close releases the memory of the file. The documents parsed from it are still valid.
+func (m *MappedFile) Close() error {
+	if !m.mapped {
+		m.data = nil
+		return nil
+	}
+
+	if err := unmap(m.data); err != nil {
+		return fmt.Errorf("failed to close mapped file: %w", err)
+	}
+
+	m.data = nil
+	m.mapped = false
+
+	return nil
+}
+
+// Parse parses the whole file, like Parse does with a reader. The byte offsets of the lines are relative to the start of the file.
+func (m *MappedFile) Parse(options ...ParseConfigurationCallbackable) (*Document, error) {
+	return ParseBytes(m.data, options...)
+}
+
+// ParseRange parses the bytes from start to end-1 of the file, like a layer located by an OffsetIndex built from the same file.
+// The byte offsets of the lines are relative to start.
+//
+// It returns an error if the range is invalid.
+func (m *MappedFile) ParseRange(start int64, end int64, options ...ParseConfigurationCallbackable) (*Document, error) {
+	if start < 0 || start > end || end > int64(len(m.data)) {
+		return nil, fmt.Errorf("failed to parse range [%d, %d), the file has %d bytes", start, end, len(m.data))
+	}
+
+	return ParseBytes(m.data[start:end], options...)
+}
+
+// ParseLayer parses the bytes of a layer located by an OffsetIndex built from the same file.
+//
+// It returns an error if the layer doesn't exist.
+func (m *MappedFile) ParseLayer(x *OffsetIndex, layer int, options ...ParseConfigurationCallbackable) (*Document, error) {
+	start, end, ok := x.LayerRange(layer)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse layer %d, the file has %d layers", layer, x.Layers())
+	}
+
+	return m.ParseRange(start, end, options...)
+}
+
+// OpenMapped opens a file and maps it in memory, it must be closed when it isn't used anymore.
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapped file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapped file: %w", err)
+	}
+
+	// an empty file can't be mapped
+	if info.Size() == 0 {
+		return &MappedFile{}, nil
+	}
+
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("failed to open mapped file, it is too large: %d bytes", info.Size())
+	}
+
+	data, mapped, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to map file: %w", err)
+	}
+
+	return &MappedFile{data: data, mapped: mapped}, nil
+}
+
+//#endregion
+//#region parse bytes
+
+// ParseBytes parses a gcode file stored in memory, like Parse does with a reader.
+//
+// The lines are read directly from data, without copying it to intermediate buffers, so data mustn't be modified while it is parsed.
+// The gzip compressed data is decompressed like Parse does, in that case the data is buffered.
+func ParseBytes(data []byte, options ...ParseConfigurationCallbackable) (*Document, error) {
+
+	configurator := &parseConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	compressed := configurator.compression == CompressionGzip ||
+		(configurator.compression == CompressionAuto && bytes.HasPrefix(data, gzipMagic))
+	if compressed {
+		return Parse(bytes.NewReader(data), options...)
+	}
+
+	p := &lineParser{
+		configurator: configurator,
+		document:     &Document{},
+		total:        int64(len(data)),
+	}
+	p.read = func() int64 { return p.offset }
+
+	for len(data) > 0 {
+		// scanLines never fails and always advances at the end of the data
+		advance, line, _ := scanLines(data, true)
+		if err := p.parse(string(line)); err != nil {
+			return nil, err
+		}
+		data = data[advance:]
+	}
+
+	return p.finish(), nil
+}
+
+//#endregion
@@ -0,0 +1,27 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package document
+
+import (
+	"io"
+	"os"
+)
+
+//#region private functions
+
+// mapFile reads the first size bytes of a file in memory, because the system doesn't support the memory mapped files.
+func mapFile(f *os.File, size int) ([]byte, bool, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, false, err
+	}
+
+	return data, false, nil
+}
+
+// unmap is never called, because the files are never mapped.
+func unmap(data []byte) error {
+	return nil
+}
+
+//#endregion
@@ -0,0 +1,164 @@
+package document
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMappedFile(t *testing.T) {
+
+	source := "G28\r\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\n;LAYER:1\nG1 X20 Y10 Z0.4 E2\nM84"
+
+	path := filepath.Join(t.TempDir(), "print.gcode")
+	if err := os.WriteFile(path, []byte(source), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	m, err := OpenMapped(path)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+	defer m.Close()
+
+	if m.Len() != len(source) || string(m.Bytes()) != source {
+		t.Errorf("got content %q, want %q", m.Bytes(), source)
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		got, err := m.Parse(func(config ParseConfigurer) error {
+			return config.SetRoundTrip(true)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+			return config.SetRoundTrip(true)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		var gotOutput, wantOutput bytes.Buffer
+		got.WriteTo(&gotOutput)
+		want.WriteTo(&wantOutput)
+		if gotOutput.String() != wantOutput.String() || gotOutput.String() != source {
+			t.Errorf("got %q, want %q", gotOutput.String(), wantOutput.String())
+		}
+
+		for i := 0; i < want.Len(); i++ {
+			gotOffset, _ := got.BlockOffset(i)
+			wantOffset, _ := want.BlockOffset(i)
+			if gotOffset != wantOffset {
+				t.Errorf("got offset %d of block %d, want %d", gotOffset, i, wantOffset)
+			}
+		}
+	})
+
+	t.Run("parse layer", func(t *testing.T) {
+		x, err := BuildOffsetIndex(bytes.NewReader(m.Bytes()))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		d, err := m.ParseLayer(x, 1)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if d.String() != ";LAYER:1\nG1 X20 Y10 Z0.4 E2\nM84\n" {
+			t.Errorf("got %q, want the layer 1", d.String())
+		}
+
+		if _, err := m.ParseLayer(x, 2); err == nil {
+			t.Errorf("got error nil parsing a missing layer, want error not nil")
+		}
+
+		if _, err := m.ParseRange(3, 2); err == nil {
+			t.Errorf("got error nil parsing an invalid range, want error not nil")
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.gcode")
+		if err := os.WriteFile(empty, nil, 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		e, err := OpenMapped(empty)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		d, err := e.Parse()
+		if err != nil || d.LineCount() != 0 {
+			t.Errorf("got %d lines with error %v, want 0 lines", d.LineCount(), err)
+		}
+
+		if err := e.Close(); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := OpenMapped(filepath.Join(t.TempDir(), "missing.gcode")); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestParseBytes(t *testing.T) {
+
+	source := "G28\n; comment\nG1 X10 Y10\n"
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(source))
+	gz.Close()
+
+	cases := map[string]struct {
+		data  []byte
+		valid bool
+	}{
+		"plain":      {[]byte(source), true},
+		"compressed": {compressed.Bytes(), true},
+		"invalid":    {[]byte("G28\nG1 X\n"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var calls int
+			d, err := ParseBytes(tc.data, func(config ParseConfigurer) error {
+				return config.SetProgress(func(progress Progress) {
+					calls++
+				})
+			})
+
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if d.String() != source || calls != 1 {
+				t.Errorf("got %q with %d progress calls, want %q with 1", d.String(), calls, source)
+			}
+		})
+	}
+}
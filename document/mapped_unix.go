@@ -0,0 +1,27 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package document
+
+import (
+	"os"
+	"syscall"
+)
+
+//#region private functions
+
+// mapFile maps the first size bytes of a file in memory, in read-only mode.
+func mapFile(f *os.File, size int) ([]byte, bool, error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// unmap releases the memory mapped by mapFile.
+func unmap(data []byte) error {
+	return syscall.Munmap(data)
+}
+
+//#endregion
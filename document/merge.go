@@ -0,0 +1,159 @@
+package document
+
+import (
+	"fmt"
+)
+
+//#region merge configuration
+
+// MergeConfigurer defines the options of the merge of documents.
+type MergeConfigurer interface {
+	// Set if the merged document is renumbered
+	SetRenumber(enabled bool) error
+
+	// Set if the redundant modal commands of the headers are removed
+	SetDeduplicate(enabled bool) error
+
+	// Set the function that generates the lines inserted between two documents
+	SetSeparator(separator func(index int) []Line) error
+}
+
+// MergeConfigurationCallbackable is the signature of the callbacks used to configure the merge.
+type MergeConfigurationCallbackable func(config MergeConfigurer) error
+
+// mergeConfigurator implements MergeConfigurer.
+type mergeConfigurator struct {
+	renumber    bool
+	deduplicate bool
+	separator   func(index int) []Line
+}
+
+// SetRenumber defines if the merged document is renumbered from 1, with checksums, when some of its blocks has line number.
+// It keeps the sequence of line numbers continuous. The documents without line numbers are never numbered.
+// If this method isn't called, by default it is enabled.
+func (mc *mergeConfigurator) SetRenumber(enabled bool) error {
+	mc.renumber = enabled
+
+	return nil
+}
+
+// SetDeduplicate defines if the modal commands of the headers that don't change the state of the machine are removed.
+// Only the commands of units (G20, G21), positioning (G90, G91), extrusion mode (M82, M83) and plane (G17, G18, G19) are considered.
+// If this method isn't called, by default it is enabled.
+func (mc *mergeConfigurator) SetDeduplicate(enabled bool) error {
+	mc.deduplicate = enabled
+
+	return nil
+}
+
+// SetSeparator defines the function that generates the lines inserted between two documents. Doesn't accept nil.
+// index is the position of the document that follows the separator, starting at one.
+// The function must return new blocks each time it is called, because they are renumbered independently.
+// If this method isn't called, by default the documents are concatenated without separator.
+func (mc *mergeConfigurator) SetSeparator(separator func(index int) []Line) error {
+	if separator == nil {
+		return fmt.Errorf("failed to set separator, it mustn't be nil")
+	}
+
+	mc.separator = separator
+
+	return nil
+}
+
+//#endregion
+//#region merge
+
+// Merge concatenates documents in order, like a queue of sequential print jobs.
+//
+// The merged document shares the blocks with the documents received, so they mustn't be used after the merge.
+// It returns an error if some option is invalid or some document is nil.
+func Merge(docs []*Document, options ...MergeConfigurationCallbackable) (*Document, error) {
+
+	configurator := &mergeConfigurator{
+		renumber:    true,
+		deduplicate: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	merged := &Document{}
+	modes := modalState{}
+	numbered := false
+
+	for i, d := range docs {
+		if d == nil {
+			return nil, fmt.Errorf("failed to merge documents, the document %d is nil", i)
+		}
+
+		if i > 0 && configurator.separator != nil {
+			merged.lines = append(merged.lines, configurator.separator(i)...)
+		}
+
+		header := d.Sections().Header
+		for j, l := range d.lines {
+			if l.Block == nil {
+				merged.lines = append(merged.lines, l)
+				continue
+			}
+
+			if l.Block.LineNumber() != nil {
+				numbered = true
+			}
+
+			redundant := modes.apply(l.Block.ToLine("%c %p"))
+			if i > 0 && configurator.deduplicate && redundant && j < header.EndLine {
+				continue
+			}
+
+			merged.lines = append(merged.lines, l)
+		}
+	}
+
+	merged.index()
+
+	if configurator.renumber && numbered {
+		if err := merged.Renumber(); err != nil {
+			return nil, fmt.Errorf("failed to renumber merged document: %w", err)
+		}
+	}
+
+	return merged, nil
+}
+
+//#endregion
+//#region private functions
+
+// modalGroups relates each modal command considered by the deduplication with its group.
+var modalGroups = map[string]string{
+	"G20": "units",
+	"G21": "units",
+	"G90": "positioning",
+	"G91": "positioning",
+	"M82": "extrusion",
+	"M83": "extrusion",
+	"G17": "plane",
+	"G18": "plane",
+	"G19": "plane",
+}
+
+// modalState stores the last command executed of each modal group.
+type modalState map[string]string
+
+// apply updates the state with the expression of a block, it returns true if the block is a modal command that doesn't change the state.
+func (m modalState) apply(expression string) bool {
+	group, ok := modalGroups[expression]
+	if !ok {
+		return false
+	}
+
+	redundant := m[group] == expression
+	m[group] = expression
+
+	return redundant
+}
+
+//#endregion
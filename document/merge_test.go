@@ -0,0 +1,92 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+
+	first := "G21\nG90\nM82\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nM84"
+	second := "G21\nG91\nM82\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nG90\nM84"
+
+	separator := func(config MergeConfigurer) error {
+		return config.SetSeparator(func(index int) []Line {
+			return []Line{{Text: fmt.Sprintf("; job %d", index+1)}}
+		})
+	}
+
+	cases := map[string]struct {
+		sources []string
+		options []MergeConfigurationCallbackable
+		valid   bool
+		want    string
+	}{
+		"deduplicated": {
+			[]string{first, second}, nil, true,
+			"G21\nG90\nM82\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nM84\nG91\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nG90\nM84\n",
+		},
+		"without deduplication": {
+			[]string{first, second},
+			[]MergeConfigurationCallbackable{func(config MergeConfigurer) error { return config.SetDeduplicate(false) }},
+			true,
+			first + "\n" + second + "\n",
+		},
+		"separator": {
+			[]string{"G28", "G28", "G28"},
+			[]MergeConfigurationCallbackable{separator},
+			true,
+			"G28\n; job 2\nG28\n; job 3\nG28\n",
+		},
+		"renumbered": {
+			[]string{"N1 T0*59\nN2 G28*17", "G92 E0"}, nil, true,
+			"N1 T0*59\nN2 G28*17\nN3 G92 E0*68\n",
+		},
+		"without renumber": {
+			[]string{"N1 T0*59", "N1 T0*59"},
+			[]MergeConfigurationCallbackable{func(config MergeConfigurer) error { return config.SetRenumber(false) }},
+			true,
+			"N1 T0*59\nN1 T0*59\n",
+		},
+		"empty":             {nil, nil, true, ""},
+		"invalid separator": {[]string{"G28"}, []MergeConfigurationCallbackable{func(config MergeConfigurer) error { return config.SetSeparator(nil) }}, false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var docs []*Document
+			for _, source := range tc.sources {
+				d, err := Parse(strings.NewReader(source))
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+				docs = append(docs, d)
+			}
+
+			merged, err := Merge(docs, tc.options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if merged.String() != tc.want {
+				t.Errorf("got %q, want %q", merged.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("nil document", func(t *testing.T) {
+		if _, err := Merge([]*Document{New(), nil}); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
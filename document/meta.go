@@ -0,0 +1,40 @@
+package document
+
+import (
+	"strings"
+)
+
+// metaKeywords are the keywords that start the meta commands of RepRapFirmware.
+var metaKeywords = []string{"if", "elif", "else", "while", "break", "continue", "var", "set", "global", "echo", "abort"}
+
+//#region meta commands
+
+// IsMetaCommand returns true if the text is a meta command of RepRapFirmware, like "if move.axes[0].homed" or "echo var.x".
+// It starts with a keyword in lower case, "if", "elif", "else", "while", "break", "continue", "var", "set", "global", "echo" or "abort",
+// followed by a space, a tab, an opening brace, a comment or the end of the text.
+func IsMetaCommand(text string) bool {
+	text = strings.TrimSpace(text)
+
+	for _, keyword := range metaKeywords {
+		if !strings.HasPrefix(text, keyword) {
+			continue
+		}
+
+		rest := text[len(keyword):]
+		if rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '{' || rest[0] == ';' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasExpression returns true if the text contains an expression of the object model of RepRapFirmware between braces
+// before its comment, like "G1 X{move.axes[0].max - 10}", so it can't be parsed as a block until the expression is evaluated.
+func HasExpression(text string) bool {
+	text, _, _ = strings.Cut(text, ";")
+
+	return strings.Contains(text, "{")
+}
+
+//#endregion
@@ -0,0 +1,59 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMetaCommand(t *testing.T) {
+	cases := map[string]bool{
+		"if move.axes[0].homed":  true,
+		"  elif var.x > 1":       true,
+		"else":                   true,
+		"echo{var.x}":            true,
+		"break ; leave the loop": true,
+		"G1 X10":                 false,
+		"variable":               false,
+		"IF x":                   false,
+		"":                       false,
+	}
+
+	for text, want := range cases {
+		if got := IsMetaCommand(text); got != want {
+			t.Errorf("got %v for %q, want %v", got, text, want)
+		}
+	}
+}
+
+func TestHasExpression(t *testing.T) {
+	cases := map[string]bool{
+		"G1 X{var.x}":        true,
+		"G1 X10 ; {comment}": false,
+		"G1 X10":             false,
+	}
+
+	for text, want := range cases {
+		if got := HasExpression(text); got != want {
+			t.Errorf("got %v for %q, want %v", got, text, want)
+		}
+	}
+}
+
+func TestParse_metaCommands(t *testing.T) {
+	source := "var x = 10\nif var.x > 5\n  G1 X{var.x}\nelse\n  echo \"small\"\nG28\n"
+
+	if _, err := Parse(strings.NewReader(source)); err == nil {
+		t.Errorf("got error nil, want the meta commands rejected by default")
+	}
+
+	d, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+		return config.SetMetaCommands(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if d.Len() != 1 || d.LineCount() != 6 || d.String() != source {
+		t.Errorf("got %d blocks in %q, want the meta commands kept as lines", d.Len(), d.String())
+	}
+}
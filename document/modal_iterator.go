@@ -0,0 +1,117 @@
+package document
+
+import (
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region modal state
+
+// Units identifies the units of the lengths commanded, it is the type of the state package.
+type Units = state.Units
+
+const (
+	// UnitsMillimeters is selected by G21, it is the default of the firmwares.
+	UnitsMillimeters = state.UnitsMillimeters
+
+	// UnitsInches is selected by G20.
+	UnitsInches = state.UnitsInches
+)
+
+// Position is the position of the axes of the machine, it is the type of the state package.
+type Position = state.Position
+
+// ModalState is the state of the machine that the blocks modify and the following blocks inherit.
+//
+// It is the state.State of the state package, the only model of the machine of the library, so the documents and the analyzers
+// interpret the blocks the same way. The maps of the targets are shared by the copies, use Clone to keep an independent one.
+type ModalState = state.State
+
+//#endregion
+//#region modal iterator
+
+// ModalIterator walks the blocks of a document together with the modal state of the machine, computed incrementally.
+//
+// It is created by Document.Iterate and used like a bufio.Scanner:
+//
+//	it := d.Iterate()
+//	for it.Next() {
+//		fmt.Println(it.Block(), it.Before().Position, it.State().Position)
+//	}
+type ModalIterator struct {
+	// document iterated
+	document *Document
+
+	// position of the current block, it is -1 before the first call to Next
+	index int
+
+	// state before and after the current block
+	before ModalState
+	after  ModalState
+}
+
+// Next advances to the next block, it returns false when there aren't more blocks.
+func (it *ModalIterator) Next() bool {
+	if it.index+1 >= len(it.document.blocks) {
+		it.index = len(it.document.blocks)
+		return false
+	}
+
+	it.index++
+	it.before = it.after.Clone()
+	it.after.Apply(it.document.blocks[it.index])
+
+	return true
+}
+
+// Index returns the position of the current block in the document.
+func (it *ModalIterator) Index() int {
+	return it.index
+}
+
+// Block returns the current block.
+func (it *ModalIterator) Block() block.Blocker {
+	if it.index < 0 || it.index >= len(it.document.blocks) {
+		return nil
+	}
+
+	return it.document.blocks[it.index]
+}
+
+// Before returns the modal state in which the current block is executed, so a move goes from Before().Position to State().Position.
+// It is a copy that the iterator doesn't modify.
+func (it *ModalIterator) Before() ModalState {
+	return it.before.Clone()
+}
+
+// State returns the modal state after executing the current block. It is a copy that the iterator doesn't modify.
+func (it *ModalIterator) State() ModalState {
+	return it.after.Clone()
+}
+
+// Iterate returns an iterator over the blocks of the document that carries the modal state of the machine.
+//
+// The machine starts in millimeters, absolute positioning, tool zero and all axes at zero.
+// The document mustn't be modified while it is iterated.
+func (d *Document) Iterate() *ModalIterator {
+	return &ModalIterator{
+		document: d,
+		index:    -1,
+	}
+}
+
+//#endregion
+//#region private functions
+
+// hasParameter returns true if the block has a parameter with the word required, with or without address.
+func hasParameter(b block.Blocker, word byte) bool {
+	for _, p := range b.Parameters() {
+		if p.Word() == word {
+			return true
+		}
+	}
+
+	return false
+}
+
+//#endregion
@@ -0,0 +1,88 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+func TestDocument_Iterate(t *testing.T) {
+
+	source := strings.Join([]string{
+		"G21",
+		"G90",
+		"M83",
+		"G28",
+		"G1 Z0.2 F1200",
+		"G1 X10 Y5 E0.5",
+		"; comment",
+		"T1",
+		"G91",
+		"G1 X2 E1 F600",
+		"G92 X0 E0",
+		"G20",
+		"G90",
+		"G28 X0",
+	}, "\n")
+
+	d, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	want := []ModalState{
+		{Units: UnitsMillimeters},
+		{},
+		{RelativeExtrusion: true},
+		{RelativeExtrusion: true},
+		{RelativeExtrusion: true, Feedrate: 1200, Position: Position{Z: 0.2}},
+		{RelativeExtrusion: true, Feedrate: 1200, Position: Position{X: 10, Y: 5, Z: 0.2, E: 0.5}},
+		{RelativeExtrusion: true, Tool: 1, Feedrate: 1200, Position: Position{X: 10, Y: 5, Z: 0.2, E: 0.5}},
+		{Relative: true, RelativeExtrusion: true, Tool: 1, Feedrate: 1200, Position: Position{X: 10, Y: 5, Z: 0.2, E: 0.5}},
+		{Relative: true, RelativeExtrusion: true, Tool: 1, Feedrate: 600, Position: Position{X: 12, Y: 5, Z: 0.2, E: 1.5}},
+		{Relative: true, RelativeExtrusion: true, Tool: 1, Feedrate: 600, Position: Position{X: 0, Y: 5, Z: 0.2, E: 0}, Shift: state.Offset{X: 12}},
+		{Units: UnitsInches, Relative: true, RelativeExtrusion: true, Tool: 1, Feedrate: 600, Position: Position{Y: 5, Z: 0.2}, Shift: state.Offset{X: 12}},
+		{Units: UnitsInches, Tool: 1, Feedrate: 600, Position: Position{Y: 5, Z: 0.2}, Shift: state.Offset{X: 12}},
+		{Units: UnitsInches, Tool: 1, Feedrate: 600, Position: Position{Y: 5, Z: 0.2}},
+	}
+
+	it := d.Iterate()
+	if it.Block() != nil {
+		t.Errorf("got block %v before the first call to Next, want nil", it.Block())
+	}
+
+	var previous ModalState
+	count := 0
+	for it.Next() {
+		if count >= len(want) {
+			t.Errorf("got more blocks than %d", len(want))
+			break
+		}
+
+		if it.Index() != count {
+			t.Errorf("got index %d, want %d", it.Index(), count)
+		}
+
+		if !reflect.DeepEqual(it.Before(), previous) {
+			t.Errorf("block %d %v: got state before %+v, want %+v", count, it.Block(), it.Before(), previous)
+		}
+
+		if !reflect.DeepEqual(it.State(), want[count]) {
+			t.Errorf("block %d %v: got state %+v, want %+v", count, it.Block(), it.State(), want[count])
+		}
+
+		previous = it.State()
+		count++
+	}
+
+	if count != len(want) {
+		t.Errorf("got %d blocks iterated, want %d", count, len(want))
+	}
+
+	if it.Next() || it.Block() != nil {
+		t.Errorf("got iterator not finished, want it finished")
+	}
+}
@@ -0,0 +1,341 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// DEFAULT_INDEX_STRIDE is the number of lines between two offsets stored by an offset index, if it isn't configured.
+	DEFAULT_INDEX_STRIDE = 1024
+
+	// offsetIndexMagic identifies the binary encoding of an offset index.
+	offsetIndexMagic = "GCIX"
+
+	// offsetIndexVersion is the version of the binary encoding of an offset index.
+	offsetIndexVersion = 1
+)
+
+//#region offset index configuration
+
+// OffsetIndexConfigurer defines the options of the construction of an offset index.
+type OffsetIndexConfigurer interface {
+	// Set the number of lines between two offsets stored
+	SetStride(stride int) error
+}
+
+// OffsetIndexConfigurationCallbackable is the signature of the callbacks used to configure an offset index.
+type OffsetIndexConfigurationCallbackable func(config OffsetIndexConfigurer) error
+
+// offsetIndexConfigurator implements OffsetIndexConfigurer.
+type offsetIndexConfigurator struct {
+	stride int
+}
+
+// SetStride defines the number of lines between two offsets stored. It must be positive.
+// A stride of one stores the offset of every line, the larger strides use less memory but a seek must skip more lines.
+// If this method isn't called, by default it is DEFAULT_INDEX_STRIDE.
+func (oc *offsetIndexConfigurator) SetStride(stride int) error {
+	if stride <= 0 {
+		return fmt.Errorf("failed to set stride, it must be positive: %d", stride)
+	}
+
+	oc.stride = stride
+
+	return nil
+}
+
+//#endregion
+//#region offset index struct
+
+// OffsetIndex stores the byte offsets of the lines and the layers of a gcode file, to read any part of it without parsing the whole file.
+//
+// It is built in a single pass over the file and can be saved next to it, so huge files can be opened at any layer or line.
+// The layers are detected by their markers, like ";LAYER:2" or ";LAYER_CHANGE".
+type OffsetIndex struct {
+	// number of lines between two offsets stored
+	stride int
+
+	// number of lines of the file
+	lines int
+
+	// number of bytes of the file
+	size int64
+
+	// offset of every line whose position is a multiple of the stride
+	offsets []int64
+
+	// position and offset of the marker line of each layer
+	layerLines   []int
+	layerOffsets []int64
+}
+
+// Lines returns the number of lines of the file indexed.
+func (x *OffsetIndex) Lines() int {
+	return x.lines
+}
+
+// Layers returns the number of layers of the file indexed.
+func (x *OffsetIndex) Layers() int {
+	return len(x.layerLines)
+}
+
+// Size returns the number of bytes of the file indexed.
+func (x *OffsetIndex) Size() int64 {
+	return x.size
+}
+
+// LayerLine returns the position of the marker line of a layer. It returns false if the layer doesn't exist.
+func (x *OffsetIndex) LayerLine(layer int) (int, bool) {
+	if layer < 0 || layer >= len(x.layerLines) {
+		return 0, false
+	}
+
+	return x.layerLines[layer], true
+}
+
+// LayerRange returns the byte offsets of the start and the end of a layer, so the layer contains the bytes from start to end-1.
+// It returns false if the layer doesn't exist.
+func (x *OffsetIndex) LayerRange(layer int) (int64, int64, bool) {
+	if layer < 0 || layer >= len(x.layerOffsets) {
+		return 0, 0, false
+	}
+
+	end := x.size
+	if layer+1 < len(x.layerOffsets) {
+		end = x.layerOffsets[layer+1]
+	}
+
+	return x.layerOffsets[layer], end, true
+}
+
+// SeekLine moves the file to the start of the line required, starting at zero, and returns a reader that continues from it.
+//
+// The file must be the same that was indexed.
+func (x *OffsetIndex) SeekLine(source io.ReadSeeker, line int) (io.Reader, error) {
+	if line < 0 || line >= x.lines {
+		return nil, fmt.Errorf("failed to seek line %d, the file has %d lines", line, x.lines)
+	}
+
+	checkpoint := line / x.stride
+	if _, err := source.Seek(x.offsets[checkpoint], io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek line %d: %w", line, err)
+	}
+
+	reader := bufio.NewReader(source)
+	for skip := line - checkpoint*x.stride; skip > 0; skip-- {
+		if err := skipLine(reader); err != nil {
+			return nil, fmt.Errorf("failed to seek line %d: %w", line, err)
+		}
+	}
+
+	return reader, nil
+}
+
+// SeekLayer moves the file to the marker line of the layer required, starting at zero,
+// and returns a reader limited to the bytes of the layer, so it can be parsed as a document.
+//
+// The file must be the same that was indexed.
+func (x *OffsetIndex) SeekLayer(source io.ReadSeeker, layer int) (io.Reader, error) {
+	start, end, ok := x.LayerRange(layer)
+	if !ok {
+		return nil, fmt.Errorf("failed to seek layer %d, the file has %d layers", layer, len(x.layerOffsets))
+	}
+
+	if _, err := source.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek layer %d: %w", layer, err)
+	}
+
+	return io.LimitReader(source, end-start), nil
+}
+
+// WriteTo saves the index in a compact binary encoding, so it can be loaded with ReadOffsetIndex.
+//
+// It implements the io.WriterTo interface and returns the number of bytes written.
+func (x *OffsetIndex) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var written int64
+
+	n, err := bw.WriteString(offsetIndexMagic)
+	written += int64(n)
+	if err != nil {
+		return written, fmt.Errorf("failed to write offset index: %w", err)
+	}
+
+	values := []uint64{offsetIndexVersion, uint64(x.stride), uint64(x.lines), uint64(x.size), uint64(len(x.offsets))}
+
+	// the offsets are stored as differences to keep them small
+	var previous int64
+	for _, offset := range x.offsets {
+		values = append(values, uint64(offset-previous))
+		previous = offset
+	}
+
+	values = append(values, uint64(len(x.layerLines)))
+	for i := range x.layerLines {
+		values = append(values, uint64(x.layerLines[i]), uint64(x.layerOffsets[i]))
+	}
+
+	var buffer [binary.MaxVarintLen64]byte
+	for _, v := range values {
+		n, err := bw.Write(buffer[:binary.PutUvarint(buffer[:], v)])
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write offset index: %w", err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return written, fmt.Errorf("failed to write offset index: %w", err)
+	}
+
+	return written, nil
+}
+
+//#endregion
+//#region constructors
+
+// BuildOffsetIndex reads a whole gcode file once and returns its offset index.
+//
+// The blocks aren't parsed, so it is much faster than Parse and the memory used only depends on the stride and the number of layers.
+func BuildOffsetIndex(source io.Reader, options ...OffsetIndexConfigurationCallbackable) (*OffsetIndex, error) {
+
+	configurator := &offsetIndexConfigurator{
+		stride: DEFAULT_INDEX_STRIDE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	x := &OffsetIndex{stride: configurator.stride}
+
+	reader := bufio.NewReader(source)
+
+	// true while the bytes read belong to a line that didn't end yet
+	inLine := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 {
+			if !inLine {
+				if x.lines%x.stride == 0 {
+					x.offsets = append(x.offsets, x.size)
+				}
+
+				if isLayerMarkerBytes(chunk) {
+					x.layerLines = append(x.layerLines, x.lines)
+					x.layerOffsets = append(x.layerOffsets, x.size)
+				}
+			}
+
+			x.size += int64(len(chunk))
+			inLine = chunk[len(chunk)-1] != '\n'
+			if !inLine {
+				x.lines++
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read line %d: %w", x.lines+1, err)
+		}
+	}
+
+	// the last line hasn't line ending
+	if inLine {
+		x.lines++
+	}
+
+	return x, nil
+}
+
+// ReadOffsetIndex loads an offset index saved with WriteTo.
+func ReadOffsetIndex(source io.Reader) (*OffsetIndex, error) {
+
+	reader := bufio.NewReader(source)
+
+	magic := make([]byte, len(offsetIndexMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, fmt.Errorf("failed to read offset index: %w", err)
+	}
+	if string(magic) != offsetIndexMagic {
+		return nil, fmt.Errorf("failed to read offset index, the source isn't an offset index")
+	}
+
+	var err error
+	next := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(reader)
+		return v
+	}
+
+	if version := next(); err == nil && version != offsetIndexVersion {
+		return nil, fmt.Errorf("failed to read offset index, unknown version %d", version)
+	}
+
+	x := &OffsetIndex{
+		stride: int(next()),
+		lines:  int(next()),
+		size:   int64(next()),
+	}
+
+	count := next()
+	if err == nil && (x.stride <= 0 || count != uint64((x.lines+x.stride-1)/x.stride)) {
+		return nil, fmt.Errorf("failed to read offset index, it is corrupted")
+	}
+
+	var offset int64
+	for i := uint64(0); i < count && err == nil; i++ {
+		offset += int64(next())
+		x.offsets = append(x.offsets, offset)
+	}
+
+	layers := next()
+	for i := uint64(0); i < layers && err == nil; i++ {
+		x.layerLines = append(x.layerLines, int(next()))
+		x.layerOffsets = append(x.layerOffsets, int64(next()))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offset index: %w", err)
+	}
+
+	return x, nil
+}
+
+//#endregion
+//#region private functions
+
+// isLayerMarkerBytes is like isLayerMarker, but it doesn't allocate a string.
+func isLayerMarkerBytes(line []byte) bool {
+	line = bytes.TrimSpace(line)
+
+	return bytes.HasPrefix(line, []byte(LAYER_MARKER)) || bytes.HasPrefix(line, []byte(LAYER_CHANGE_MARKER))
+}
+
+// skipLine discards the bytes of the reader until the end of the current line.
+func skipLine(reader *bufio.Reader) error {
+	for {
+		_, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		return err
+	}
+}
+
+//#endregion
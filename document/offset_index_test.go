@@ -0,0 +1,152 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestOffsetIndex(t *testing.T) {
+
+	lines := []string{
+		"G28",
+		";LAYER:0",
+		"G1 X10 Y10 Z0.2 E1",
+		";" + strings.Repeat("x", 5000),
+		";LAYER:1",
+		"G1 X20 Y10 Z0.4 E2",
+		"  ;LAYER_CHANGE",
+		"G1 X20 Y20 Z0.6 E3",
+		"M84",
+	}
+	source := strings.Join(lines, "\r\n")
+
+	for _, stride := range []int{1, 2, 4, 100} {
+		x, err := BuildOffsetIndex(strings.NewReader(source), func(config OffsetIndexConfigurer) error {
+			return config.SetStride(stride)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if x.Lines() != len(lines) || x.Layers() != 3 || x.Size() != int64(len(source)) {
+			t.Errorf("stride %d: got %d lines, %d layers and %d bytes, want %d lines, 3 layers and %d bytes", stride, x.Lines(), x.Layers(), x.Size(), len(lines), len(source))
+		}
+
+		for i, want := range lines {
+			r, err := x.SeekLine(strings.NewReader(source), i)
+			if err != nil {
+				t.Errorf("stride %d: got error %v seeking line %d, want error nil", stride, err, i)
+				continue
+			}
+
+			got, _ := bufio.NewReader(r).ReadString('\n')
+			if strings.TrimRight(got, "\r\n") != want {
+				t.Errorf("stride %d: got line %d %q, want %q", stride, i, got, want)
+			}
+		}
+	}
+
+	x, err := BuildOffsetIndex(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	t.Run("seek layer", func(t *testing.T) {
+		r, err := x.SeekLayer(strings.NewReader(source), 1)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		d, err := Parse(r)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if d.String() != ";LAYER:1\nG1 X20 Y10 Z0.4 E2\n" {
+			t.Errorf("got %q, want the layer 1", d.String())
+		}
+
+		r, err = x.SeekLayer(strings.NewReader(source), 2)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		data, _ := io.ReadAll(r)
+		if string(data) != "  ;LAYER_CHANGE\r\nG1 X20 Y20 Z0.6 E3\r\nM84" {
+			t.Errorf("got %q, want the last layer", data)
+		}
+
+		if line, ok := x.LayerLine(2); !ok || line != 6 {
+			t.Errorf("got %d %v, want 6 true", line, ok)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		if _, err := x.SeekLine(strings.NewReader(source), len(lines)); err == nil {
+			t.Errorf("got error nil seeking a missing line, want error not nil")
+		}
+
+		if _, err := x.SeekLayer(strings.NewReader(source), 3); err == nil {
+			t.Errorf("got error nil seeking a missing layer, want error not nil")
+		}
+
+		if _, ok := x.LayerLine(-1); ok {
+			t.Errorf("got layer line of a missing layer, want false")
+		}
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		var buffer bytes.Buffer
+		n, err := x.WriteTo(&buffer)
+		if err != nil || int(n) != buffer.Len() {
+			t.Errorf("got %d bytes with error %v, want %d bytes", n, err, buffer.Len())
+			return
+		}
+
+		loaded, err := ReadOffsetIndex(&buffer)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if loaded.Lines() != x.Lines() || loaded.Layers() != x.Layers() || loaded.Size() != x.Size() {
+			t.Errorf("got index %+v, want %+v", loaded, x)
+		}
+
+		r, err := loaded.SeekLine(strings.NewReader(source), 5)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		got, _ := bufio.NewReader(r).ReadString('\n')
+		if got != "G1 X20 Y10 Z0.4 E2\r\n" {
+			t.Errorf("got %q, want the line 5", got)
+		}
+	})
+
+	t.Run("invalid encoding", func(t *testing.T) {
+		for _, data := range []string{"", "GCI", "XXXX\x01", "GCIX\x02", "GCIX\x01\x00\x01\x01\x01", "GCIX\x01\x01\x02\x05\x02\x00"} {
+			if _, err := ReadOffsetIndex(strings.NewReader(data)); err == nil {
+				t.Errorf("got error nil reading %q, want error not nil", data)
+			}
+		}
+	})
+
+	t.Run("invalid stride", func(t *testing.T) {
+		_, err := BuildOffsetIndex(strings.NewReader(source), func(config OffsetIndexConfigurer) error {
+			return config.SetStride(0)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package document
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+const (
+	// DEFAULT_PAUSE_RETRACTION is the length retracted before parking the nozzle, if it isn't configured.
+	DEFAULT_PAUSE_RETRACTION = 2
+
+	// PAUSE_TRAVEL_FEEDRATE is the feedrate of the park moves in the XY plane.
+	PAUSE_TRAVEL_FEEDRATE = 6000
+
+	// PAUSE_Z_FEEDRATE is the feedrate of the park moves in Z.
+	PAUSE_Z_FEEDRATE = 600
+
+	// PAUSE_RETRACTION_FEEDRATE is the feedrate of the retraction and the prime around a pause.
+	PAUSE_RETRACTION_FEEDRATE = 2100
+)
+
+//#region pause configuration
+
+// PauseConfigurer defines the options of the pauses inserted.
+type PauseConfigurer interface {
+	// Set the commands that pause the print
+	SetScript(expressions ...string) error
+
+	// Set the position where the nozzle is parked during the pause
+	SetPark(x float64, y float64, lift float64) error
+
+	// Set the length retracted before parking the nozzle
+	SetRetraction(length float64) error
+}
+
+// PauseConfigurationCallbackable is the signature of the callbacks used to configure the pauses.
+type PauseConfigurationCallbackable func(config PauseConfigurer) error
+
+// pauseConfigurator implements PauseConfigurer.
+type pauseConfigurator struct {
+	script     []block.Blocker
+	park       bool
+	x, y, lift float64
+	retraction float64
+}
+
+// SetScript defines the commands that pause the print, like "M0" or "M25", or a macro of the firmware. It requires at least one command.
+// If this method isn't called, by default the script is "M600", the filament change of Marlin, that parks and restores the nozzle by itself.
+func (pc *pauseConfigurator) SetScript(expressions ...string) error {
+	if len(expressions) == 0 {
+		return fmt.Errorf("failed to set script, it requires at least one command")
+	}
+
+	pc.script = pc.script[:0]
+	for _, expression := range expressions {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return fmt.Errorf("failed to set script, invalid command %s: %w", expression, err)
+		}
+		pc.script = append(pc.script, b)
+	}
+
+	return nil
+}
+
+// SetPark defines the position in the XY plane where the nozzle is parked during the pause, and the height it is lifted before.
+// The coordinates must be finite and the lift can't be negative.
+// The park moves are generated around the script: the filament is retracted, the nozzle is lifted and parked,
+// and after the script it returns to the position of the print, lowers and primes the filament. Then the modes of the print are restored.
+// If this method isn't called, by default the nozzle isn't parked, because M600 does it.
+func (pc *pauseConfigurator) SetPark(x float64, y float64, lift float64) error {
+	for _, v := range []float64{x, y, lift} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("failed to set park, the values must be finite: %v", v)
+		}
+	}
+
+	if lift < 0 {
+		return fmt.Errorf("failed to set park, the lift can't be negative: %v", lift)
+	}
+
+	pc.park = true
+	pc.x, pc.y, pc.lift = x, y, lift
+
+	return nil
+}
+
+// SetRetraction defines the length of filament retracted before parking the nozzle and primed after the pause. It can't be negative.
+// It is only used if the nozzle is parked.
+// If this method isn't called, by default it is DEFAULT_PAUSE_RETRACTION.
+func (pc *pauseConfigurator) SetRetraction(length float64) error {
+	if !(length >= 0) || math.IsInf(length, 0) {
+		return fmt.Errorf("failed to set retraction, it must be positive and finite: %v", length)
+	}
+
+	pc.retraction = length
+
+	return nil
+}
+
+//#endregion
+//#region pause
+
+// InsertPauses inserts a pause, like a filament change, before each layer selected by the criteria.
+// The criteria select the layers like Split does.
+//
+// Each pause is inserted after the last block of the previous layer, so it is executed when that layer is finished.
+// If the nozzle is parked, the position, the modes and the feedrate in effect before the layer are restored after the pause.
+// The blocks of the script are copied at each pause.
+//
+// It returns an error if some option is invalid, some layer doesn't exist or there isn't a layer at some height.
+func (d *Document) InsertPauses(criteria SplitCriteria, options ...PauseConfigurationCallbackable) error {
+
+	configurator := &pauseConfigurator{
+		retraction: DEFAULT_PAUSE_RETRACTION,
+	}
+
+	if err := configurator.SetScript("M600"); err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	selected, err := d.selectLayers(criteria)
+	if err != nil {
+		return fmt.Errorf("failed to insert pauses: %w", err)
+	}
+
+	// the state of the machine before the first block of each layer selected
+	states := make([]ModalState, len(selected))
+	it := d.Iterate()
+	for i, l := range selected {
+		for it.Index() < l.Start {
+			if !it.Next() {
+				break
+			}
+		}
+		states[i] = it.Before()
+	}
+
+	// the pauses are inserted from the end, so the positions of the previous layers don't change
+	for i := len(selected) - 1; i >= 0; i-- {
+		blocks, err := configurator.pause(states[i])
+		if err != nil {
+			return fmt.Errorf("failed to insert pause at layer %d: %w", selected[i].Index, err)
+		}
+
+		if err := d.InsertAfter(selected[i].Start-1, blocks...); err != nil {
+			return fmt.Errorf("failed to insert pause at layer %d: %w", selected[i].Index, err)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// pause returns the blocks of a pause executed with the machine in the state received.
+func (pc *pauseConfigurator) pause(state ModalState) ([]block.Blocker, error) {
+	var before, after []string
+
+	if pc.park {
+		before = []string{
+			"G91",
+			"G1 E-" + numeric.Format(pc.retraction) + " F" + numeric.Format(PAUSE_RETRACTION_FEEDRATE),
+			"G1 Z" + numeric.Format(pc.lift) + " F" + numeric.Format(PAUSE_Z_FEEDRATE),
+			"G90",
+			"G1 X" + numeric.Format(pc.x) + " Y" + numeric.Format(pc.y) + " F" + numeric.Format(PAUSE_TRAVEL_FEEDRATE),
+		}
+
+		after = []string{
+			"G1 X" + numeric.Format(state.Position.X) + " Y" + numeric.Format(state.Position.Y) + " F" + numeric.Format(PAUSE_TRAVEL_FEEDRATE),
+			"G91",
+			"G1 Z-" + numeric.Format(pc.lift) + " F" + numeric.Format(PAUSE_Z_FEEDRATE),
+			"G1 E" + numeric.Format(pc.retraction) + " F" + numeric.Format(PAUSE_RETRACTION_FEEDRATE),
+		}
+
+		// G91 selects the relative extrusion too, so both modes are restored
+		if !state.Relative {
+			after = append(after, "G90")
+		}
+		if state.RelativeExtrusion {
+			after = append(after, "M83")
+		} else {
+			after = append(after, "M82")
+		}
+
+		if state.Feedrate > 0 {
+			after = append(after, "G1 F"+numeric.Format(state.Feedrate))
+		}
+	}
+
+	var blocks []block.Blocker
+
+	for _, expression := range before {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", expression, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	for _, b := range pc.script {
+		copied, err := gcodeblock.Parse(b.ToLine("%c %p %m"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", b, err)
+		}
+		blocks = append(blocks, copied)
+	}
+
+	for _, expression := range after {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", expression, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+//#endregion
@@ -0,0 +1,83 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_InsertPauses(t *testing.T) {
+
+	source := "G28\nM83\n;LAYER:0\nG1 X10 Y10 Z0.2 F1200\nG1 X20 E1\n;LAYER:1\nG1 Z0.4\nG1 X10 E1\n;LAYER:2\nG1 Z0.6\n"
+
+	cases := map[string]struct {
+		criteria SplitCriteria
+		options  []PauseConfigurationCallbackable
+		valid    bool
+		want     string
+	}{
+		"filament change": {
+			criteria: SplitCriteria{Layers: []int{1, 2}},
+			valid:    true,
+			want:     "G28\nM83\n;LAYER:0\nG1 X10 Y10 Z0.2 F1200\nG1 X20 E1\nM600\n;LAYER:1\nG1 Z0.4\nG1 X10 E1\nM600\n;LAYER:2\nG1 Z0.6\n",
+		},
+		"first layer": {
+			criteria: SplitCriteria{Layers: []int{0}},
+			valid:    true,
+			want:     "G28\nM83\nM600\n;LAYER:0\nG1 X10 Y10 Z0.2 F1200\nG1 X20 E1\n;LAYER:1\nG1 Z0.4\nG1 X10 E1\n;LAYER:2\nG1 Z0.6\n",
+		},
+		"park": {
+			criteria: SplitCriteria{Heights: []float64{0.3}},
+			options: []PauseConfigurationCallbackable{
+				func(config PauseConfigurer) error { return config.SetScript("M300 S440 P200", "M0") },
+				func(config PauseConfigurer) error { return config.SetPark(0, 200, 10) },
+				func(config PauseConfigurer) error { return config.SetRetraction(5) },
+			},
+			valid: true,
+			want: "G28\nM83\n;LAYER:0\nG1 X10 Y10 Z0.2 F1200\nG1 X20 E1\n" +
+				"G91\nG1 E-5 F2100\nG1 Z10 F600\nG90\nG1 X0 Y200 F6000\nM300 S440 P200\nM0\nG1 X20 Y10 F6000\nG91\nG1 Z-10 F600\nG1 E5 F2100\nG90\nM83\nG1 F1200\n" +
+				";LAYER:1\nG1 Z0.4\nG1 X10 E1\n;LAYER:2\nG1 Z0.6\n",
+		},
+		"missing layer": {
+			criteria: SplitCriteria{Layers: []int{3}},
+		},
+		"invalid script": {
+			criteria: SplitCriteria{Layers: []int{1}},
+			options: []PauseConfigurationCallbackable{
+				func(config PauseConfigurer) error { return config.SetScript() },
+			},
+		},
+		"invalid park": {
+			criteria: SplitCriteria{Layers: []int{1}},
+			options: []PauseConfigurationCallbackable{
+				func(config PauseConfigurer) error { return config.SetPark(0, 0, -1) },
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			err = d.InsertPauses(tc.criteria, tc.options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if d.String() != tc.want {
+				t.Errorf("got %q, want %q", d.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package document
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	// PROGRESS_INTERVAL is the number of lines processed between two calls to a progress callback.
+	PROGRESS_INTERVAL = 1000
+)
+
+//#region progress
+
+// Progress describes the advance of a long operation, like the parsing or the verification of a huge document.
+type Progress struct {
+	// Lines is the number of lines or blocks processed.
+	Lines int
+
+	// TotalLines is the number of lines or blocks to process, it is zero if it is unknown, like while a document is parsed.
+	TotalLines int
+
+	// Bytes is the number of bytes read from the source, it is zero if the operation doesn't read a source.
+	Bytes int64
+
+	// TotalBytes is the size of the source, it is zero if it is unknown or the operation doesn't read a source.
+	TotalBytes int64
+}
+
+// Percent returns the percentage of the operation completed, between 0 and 100.
+// It uses the bytes if the size of the source is known, else the lines. It returns -1 if both totals are unknown.
+func (p Progress) Percent() float64 {
+	switch {
+	case p.TotalBytes > 0:
+		return 100 * float64(p.Bytes) / float64(p.TotalBytes)
+	case p.TotalLines > 0:
+		return 100 * float64(p.Lines) / float64(p.TotalLines)
+	}
+
+	return -1
+}
+
+// ProgressCallbackable is the signature of the callbacks that receive the progress of an operation.
+//
+// They are called every PROGRESS_INTERVAL lines and once when the operation finishes, always from the goroutine that started it.
+type ProgressCallbackable func(progress Progress)
+
+//#endregion
+//#region private functions
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r     io.Reader
+	bytes int64
+}
+
+// Read implements io.Reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.bytes += int64(n)
+
+	return n, err
+}
+
+// sourceSize returns the number of bytes that remain to read from a source, it returns zero if it is unknown.
+func sourceSize(source io.Reader) int64 {
+	switch s := source.(type) {
+	case interface{ Len() int }:
+		return int64(s.Len())
+	case *os.File:
+		info, err := s.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0
+		}
+
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0
+		}
+
+		return info.Size() - offset
+	}
+
+	return 0
+}
+
+//#endregion
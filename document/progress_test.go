@@ -0,0 +1,119 @@
+package document
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+
+	var sb strings.Builder
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&sb, "G1 X%d\n", i)
+	}
+	source := sb.String()
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(source))
+	gz.Close()
+
+	cases := map[string]struct {
+		source    func() io.Reader
+		total     int64
+		wantCalls int
+	}{
+		"known size":   {func() io.Reader { return strings.NewReader(source) }, int64(len(source)), 3},
+		"unknown size": {func() io.Reader { return io.MultiReader(strings.NewReader(source)) }, 0, 3},
+		// the compressed source is so small that it is read at once
+		"compressed": {func() io.Reader { return bytes.NewReader(compressed.Bytes()) }, int64(compressed.Len()), 3},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []Progress
+			_, err := Parse(tc.source(), func(config ParseConfigurer) error {
+				return config.SetProgress(func(progress Progress) {
+					got = append(got, progress)
+				})
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if len(got) != tc.wantCalls {
+				t.Errorf("got %d calls, want %d", len(got), tc.wantCalls)
+				return
+			}
+
+			if got[0].Lines != PROGRESS_INTERVAL || got[0].TotalBytes != tc.total {
+				t.Errorf("got first progress %+v, want %d lines of %d bytes", got[0], PROGRESS_INTERVAL, tc.total)
+			}
+
+			last := got[len(got)-1]
+			if last.Lines != 2500 || last.Percent() != 100 {
+				t.Errorf("got last progress %+v at %v%%, want 2500 lines at 100%%", last, last.Percent())
+			}
+
+			if tc.total > 0 && (got[0].Percent() <= 0 || got[0].Percent() > 100) {
+				t.Errorf("got first progress at %v%%, want it in (0, 100]", got[0].Percent())
+			}
+
+			if tc.total == 0 && got[0].Percent() != -1 {
+				t.Errorf("got first progress at %v%%, want -1", got[0].Percent())
+			}
+		})
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		d, err := Parse(strings.NewReader(source))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		var got []Progress
+		_, err = d.VerifyChecksums(func(config VerifyConfigurer) error {
+			return config.SetProgress(func(progress Progress) {
+				got = append(got, progress)
+			})
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want := []float64{40, 80, 100}
+		if len(got) != len(want) {
+			t.Errorf("got %d calls, want %d", len(got), len(want))
+			return
+		}
+
+		for i, p := range got {
+			if p.Percent() != want[i] {
+				t.Errorf("got call %d at %v%%, want %v%%", i, p.Percent(), want[i])
+			}
+		}
+	})
+
+	t.Run("nil callback", func(t *testing.T) {
+		_, err := Parse(strings.NewReader(source), func(config ParseConfigurer) error {
+			return config.SetProgress(nil)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+
+		_, err = New().VerifyChecksums(func(config VerifyConfigurer) error {
+			return config.SetProgress(nil)
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package document
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region offsets
+
+// BlockOffset returns the position of the first byte of the block required in the source parsed, after decompressing it.
+// It returns false if the index is out of range or the block wasn't parsed.
+func (d *Document) BlockOffset(index int) (int64, bool) {
+	if index < 0 || index >= len(d.blocks) {
+		return 0, false
+	}
+
+	return d.lines[d.blockLine(index)].Offset()
+}
+
+// FindByOffset returns the position of the first block parsed whose line starts at the byte offset required or after it,
+// like the position stored by a firmware when a print from the SD card is interrupted.
+// It returns false if there isn't such a block.
+func (d *Document) FindByOffset(offset int64) (int, bool) {
+	blocks := 0
+	for _, l := range d.lines {
+		if l.Block == nil {
+			continue
+		}
+
+		if o, ok := l.Offset(); ok && o >= offset {
+			return blocks, true
+		}
+		blocks++
+	}
+
+	return 0, false
+}
+
+//#endregion
+//#region resume
+
+// ResumeFromBlock returns a new document that continues the print from the block required,
+// to recover an interrupted print.
+//
+// The new document starts with a generated preamble that restores the state of the machine before the block:
+// the active tool, the temperatures, which are awaited, the units, the position and the modes, the extruder position, the fan speed and the feedrate.
+// The nozzle is assumed to stay at the height of the interruption, so only X and Y are homed before moving to the last position.
+// The lines from the block to the end of the document follow the preamble, sharing the blocks with the document.
+//
+// It returns an error if the block doesn't exist.
+func (d *Document) ResumeFromBlock(index int) (*Document, error) {
+	if index < 0 || index >= len(d.blocks) {
+		return nil, fmt.Errorf("failed to resume document, the block %d doesn't exist, it has %d blocks", index, len(d.blocks))
+	}
+
+	machine := newMachineState()
+	it := d.Iterate()
+	for it.Next() && it.Index() < index {
+		machine.apply(it.Block())
+	}
+
+	preamble, err := generateLines("; preamble generated to resume the print", resumeExpressions(machine, it.Before()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate preamble: %w", err)
+	}
+
+	resumed := &Document{}
+	resumed.lines = append(resumed.lines, preamble...)
+	resumed.lines = append(resumed.lines, d.lines[d.blockLine(index):]...)
+	resumed.index()
+
+	return resumed, nil
+}
+
+// ResumeFromLineNumber is like ResumeFromBlock, but it resumes from the first block with the line number required,
+// like the last line acknowledged by the firmware while the document was streamed.
+func (d *Document) ResumeFromLineNumber(n uint32) (*Document, error) {
+	index, ok := d.FindByLineNumber(n)
+	if !ok {
+		return nil, fmt.Errorf("failed to resume document, there isn't a block with line number %d", n)
+	}
+
+	return d.ResumeFromBlock(index)
+}
+
+// ResumeFromOffset is like ResumeFromBlock, but it resumes from the block found by FindByOffset,
+// like the position stored by the power-loss recovery of a firmware.
+func (d *Document) ResumeFromOffset(offset int64) (*Document, error) {
+	index, ok := d.FindByOffset(offset)
+	if !ok {
+		return nil, fmt.Errorf("failed to resume document, there isn't a block at offset %d or after it", offset)
+	}
+
+	return d.ResumeFromBlock(index)
+}
+
+//#endregion
+//#region private functions
+
+// resumeExpressions returns the blocks that restore the state of an interrupted print.
+//
+// The absolute positioning is selected to move to the last position, then the modes of the print are restored.
+func resumeExpressions(machine *machineState, modal ModalState) []string {
+	var expressions []string
+
+	if modal.Tool != 0 {
+		expressions = append(expressions, fmt.Sprintf("T%d", modal.Tool))
+	}
+
+	if machine.bedSet {
+		expressions = append(expressions, "M190 S"+numeric.Format(machine.bed))
+	}
+
+	if machine.hotendSet {
+		expressions = append(expressions, "M109 S"+numeric.Format(machine.hotend))
+	}
+
+	if units, ok := machine.modes["units"]; ok {
+		expressions = append(expressions, units)
+	}
+
+	expressions = append(expressions,
+		"G92 Z"+numeric.Format(modal.Position.Z),
+		"G28 X0 Y0",
+		"G90",
+		"G0 X"+numeric.Format(modal.Position.X)+" Y"+numeric.Format(modal.Position.Y),
+	)
+
+	for _, group := range []string{"positioning", "extrusion", "plane"} {
+		if command, ok := machine.modes[group]; ok && command != "G90" {
+			expressions = append(expressions, command)
+		}
+	}
+
+	if !machine.relativeExtrusion {
+		expressions = append(expressions, "G92 E"+numeric.Format(machine.e))
+	}
+
+	if machine.fanSet {
+		expressions = append(expressions, "M106 S"+numeric.Format(machine.fan))
+	}
+
+	if modal.Feedrate > 0 {
+		expressions = append(expressions, "G1 F"+numeric.Format(modal.Feedrate))
+	}
+
+	return expressions
+}
+
+//#endregion
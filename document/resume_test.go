@@ -0,0 +1,100 @@
+package document
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestDocument_Resume(t *testing.T) {
+
+	source := strings.Join([]string{
+		"N1 M140 S60*91",
+		"N2 M104 S210*98",
+		"; start",
+		"N3 G21*50",
+		"N4 G90*32",
+		"N5 M82*46",
+		"N6 G28*22",
+		"N7 G1 Z0.2 F1200*29",
+		"N8 G1 X10 Y5 E1.5*31",
+		"N9 M106 S127*82",
+		"N10 G1 X20 Y5 E3*60",
+		"N11 G1 X20 Y15 E4.5*12",
+		"",
+	}, "\n")
+
+	d, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	preamble := strings.Join([]string{
+		"; preamble generated to resume the print",
+		"M190 S60",
+		"M109 S210",
+		"G21",
+		"G92 Z0.2",
+		"G28 X0 Y0",
+		"G90",
+		"G0 X20 Y5",
+		"M82",
+		"G92 E3",
+		"M106 S127",
+		"G1 F1200",
+		"",
+	}, "\n")
+
+	offset := int64(strings.Index(source, "N11"))
+
+	cases := map[string]struct {
+		resume func() (*Document, error)
+		valid  bool
+	}{
+		"block":             {func() (*Document, error) { return d.ResumeFromBlock(10) }, true},
+		"line number":       {func() (*Document, error) { return d.ResumeFromLineNumber(11) }, true},
+		"offset":            {func() (*Document, error) { return d.ResumeFromOffset(offset) }, true},
+		"offset inside":     {func() (*Document, error) { return d.ResumeFromOffset(offset - 3) }, true},
+		"missing block":     {func() (*Document, error) { return d.ResumeFromBlock(11) }, false},
+		"missing line":      {func() (*Document, error) { return d.ResumeFromLineNumber(12) }, false},
+		"offset out of end": {func() (*Document, error) { return d.ResumeFromOffset(int64(len(source))) }, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resumed, err := tc.resume()
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			want := preamble + "N11 G1 X20 Y15 E4.5*12\n"
+			if resumed.String() != want {
+				t.Errorf("got %q, want %q", resumed.String(), want)
+			}
+		})
+	}
+
+	t.Run("offsets", func(t *testing.T) {
+		if o, ok := d.BlockOffset(10); !ok || o != offset {
+			t.Errorf("got offset %d %v, want %d true", o, ok, offset)
+		}
+
+		if _, ok := d.BlockOffset(11); ok {
+			t.Errorf("got offset of a missing block, want false")
+		}
+
+		if _, ok := New(blocktest.Parse(t, "G28")...).BlockOffset(0); ok {
+			t.Errorf("got offset of a block that wasn't parsed, want false")
+		}
+	})
+}
@@ -0,0 +1,316 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SLICER_CONFIG_BEGIN is the suffix of the comment that opens the settings block of PrusaSlicer and its forks, like "; prusaslicer_config = begin".
+	SLICER_CONFIG_BEGIN = "_config = begin"
+
+	// SLICER_CONFIG_END is the suffix of the comment that closes the settings block.
+	SLICER_CONFIG_END = "_config = end"
+)
+
+//#region slicer settings struct
+
+// SlicerSettings are the settings of the slicer appended by PrusaSlicer and its forks at the end of a file,
+// as comments like "; layer_height = 0.2". The order of the settings is preserved.
+type SlicerSettings struct {
+	// Slicer is the prefix of the markers of the block, like "prusaslicer". It is empty if the block hasn't markers.
+	Slicer string
+
+	// keys of the settings in order
+	keys []string
+
+	// values of the settings, as they are written
+	values map[string]string
+}
+
+// NewSlicerSettings returns an empty settings block whose markers use the slicer name required, like "prusaslicer".
+// If the name is empty the block is written without markers.
+func NewSlicerSettings(slicer string) *SlicerSettings {
+	return &SlicerSettings{
+		Slicer: slicer,
+		values: map[string]string{},
+	}
+}
+
+// Keys returns the keys of the settings in order.
+func (s *SlicerSettings) Keys() []string {
+	keys := make([]string, len(s.keys))
+	copy(keys, s.keys)
+
+	return keys
+}
+
+// Len returns the number of settings.
+func (s *SlicerSettings) Len() int {
+	return len(s.keys)
+}
+
+// Get returns the value of a setting as it is written. It returns false if the setting doesn't exist.
+func (s *SlicerSettings) Get(key string) (string, bool) {
+	value, ok := s.values[key]
+
+	return value, ok
+}
+
+// Float returns the value of a setting as a number. It returns an error if it doesn't exist or it isn't a number.
+//
+// The percentages, like "15%", are returned as written, without the percent sign.
+func (s *SlicerSettings) Float(key string) (float64, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return 0, fmt.Errorf("failed to get setting %s, it doesn't exist", key)
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get setting %s as number: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// Int returns the value of a setting as an integer. It returns an error if it doesn't exist or it isn't an integer.
+func (s *SlicerSettings) Int(key string) (int, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return 0, fmt.Errorf("failed to get setting %s, it doesn't exist", key)
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get setting %s as integer: %w", key, err)
+	}
+
+	return i, nil
+}
+
+// Bool returns the value of a setting as a boolean, the slicers write them as 0 or 1.
+// It returns an error if it doesn't exist or it isn't a boolean.
+func (s *SlicerSettings) Bool(key string) (bool, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return false, fmt.Errorf("failed to get setting %s, it doesn't exist", key)
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to get setting %s as boolean: %w", key, err)
+	}
+
+	return b, nil
+}
+
+// Floats returns the value of a setting with a number per extruder, like "nozzle_diameter = 0.4,0.6".
+// It returns an error if it doesn't exist or some item isn't a number.
+func (s *SlicerSettings) Floats(key string) ([]float64, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return nil, fmt.Errorf("failed to get setting %s, it doesn't exist", key)
+	}
+
+	var floats []float64
+	for i, item := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(item), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get item %d of setting %s as number: %w", i, key, err)
+		}
+		floats = append(floats, f)
+	}
+
+	return floats, nil
+}
+
+// Set defines the value of a setting, a new setting is added at the end. The key mustn't be empty or contain " = " or line breaks.
+func (s *SlicerSettings) Set(key string, value string) error {
+	if key == "" || strings.Contains(key, " = ") || strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("failed to set setting, the key is invalid: %q", key)
+	}
+
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("failed to set setting %s, the value mustn't contain line breaks", key)
+	}
+
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+
+	return nil
+}
+
+// Delete removes a setting, it does nothing if the setting doesn't exist.
+func (s *SlicerSettings) Delete(key string) {
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+
+	delete(s.values, key)
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Lines returns the comment lines of the settings block, including the markers if the slicer is defined.
+func (s *SlicerSettings) Lines() []Line {
+	lines := make([]Line, 0, len(s.keys)+2)
+
+	if s.Slicer != "" {
+		lines = append(lines, Line{Text: "; " + s.Slicer + SLICER_CONFIG_BEGIN})
+	}
+
+	for _, key := range s.keys {
+		lines = append(lines, Line{Text: "; " + key + " = " + s.values[key]})
+	}
+
+	if s.Slicer != "" {
+		lines = append(lines, Line{Text: "; " + s.Slicer + SLICER_CONFIG_END})
+	}
+
+	return lines
+}
+
+//#endregion
+//#region slicer settings
+
+// SlicerSettings locates and parses the settings block of the slicer.
+//
+// It looks for the block delimited by markers like "; prusaslicer_config = begin" and "; prusaslicer_config = end".
+// If there aren't markers, like in the files of the older versions, it uses the comments like "; key = value" after the last block.
+// It returns false if the document hasn't a settings block.
+func (d *Document) SlicerSettings() (*SlicerSettings, bool) {
+	start, end, slicer, ok := d.findSlicerSettings()
+	if !ok {
+		return nil, false
+	}
+
+	// the markers aren't settings
+	if slicer != "" {
+		start, end = start+1, end-1
+	}
+
+	settings := NewSlicerSettings(slicer)
+	for _, l := range d.lines[start:end] {
+		key, value, ok := parseSetting(l.Text)
+		if !ok {
+			continue
+		}
+
+		if _, exists := settings.values[key]; !exists {
+			settings.keys = append(settings.keys, key)
+		}
+		settings.values[key] = value
+	}
+
+	return settings, true
+}
+
+// SetSlicerSettings writes the settings block, replacing the block of the document if it has one, else appending it at the end.
+// It returns an error if the settings are nil.
+func (d *Document) SetSlicerSettings(settings *SlicerSettings) error {
+	if settings == nil {
+		return fmt.Errorf("failed to set slicer settings, they mustn't be nil")
+	}
+
+	start, end, _, ok := d.findSlicerSettings()
+	if !ok {
+		start, end = len(d.lines), len(d.lines)
+	}
+
+	block := settings.Lines()
+
+	lines := make([]Line, 0, len(d.lines)-(end-start)+len(block))
+	lines = append(lines, d.lines[:start]...)
+	lines = append(lines, block...)
+	lines = append(lines, d.lines[end:]...)
+
+	d.lines = lines
+	d.index()
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// findSlicerSettings returns the range of lines of the settings block, including its markers, and the name of the slicer of its markers.
+func (d *Document) findSlicerSettings() (int, int, string, bool) {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		text := strings.TrimSpace(d.lines[i].Text)
+		if d.lines[i].Block != nil || !strings.HasPrefix(text, ";") || !strings.HasSuffix(text, SLICER_CONFIG_END) {
+			continue
+		}
+
+		slicer := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, ";"), SLICER_CONFIG_END))
+		begin := "; " + slicer + SLICER_CONFIG_BEGIN
+		for j := i - 1; j >= 0; j-- {
+			if d.lines[j].Block == nil && strings.TrimSpace(d.lines[j].Text) == begin {
+				return j, i + 1, slicer, true
+			}
+		}
+	}
+
+	// the block without markers is made of the setting lines after the last block
+	start := len(d.lines)
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		l := d.lines[i]
+		if l.Block != nil {
+			break
+		}
+
+		if _, _, ok := parseSetting(l.Text); ok {
+			start = i
+		} else if strings.TrimSpace(l.Text) != "" {
+			// a comment that isn't a setting splits the block
+			break
+		}
+	}
+
+	if start == len(d.lines) {
+		return 0, 0, "", false
+	}
+
+	end := start
+	for i := start; i < len(d.lines); i++ {
+		if _, _, ok := parseSetting(d.lines[i].Text); ok {
+			end = i + 1
+		}
+	}
+
+	return start, end, "", true
+}
+
+// parseSetting returns the key and the value of a comment like "; key = value".
+func parseSetting(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, ";") {
+		return "", "", false
+	}
+
+	// the empty values are written like "; key = ", so the trailing space was trimmed
+	if strings.HasSuffix(text, " =") {
+		text += " "
+	}
+
+	i := strings.Index(text, " = ")
+	if i < 0 {
+		return "", "", false
+	}
+
+	key := strings.TrimSpace(text[1:i])
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+
+	return key, text[i+len(" = "):], true
+}
+
+//#endregion
@@ -0,0 +1,141 @@
+package document
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestDocument_SlicerSettings(t *testing.T) {
+
+	body := "G28\nG1 X10 Y10 E1\n; filament used [mm] = 120.5\n\n"
+
+	cases := map[string]struct {
+		source string
+		slicer string
+		keys   []string
+		found  bool
+	}{
+		"markers": {
+			source: body + "; prusaslicer_config = begin\n; layer_height = 0.2\n; nozzle_diameter = 0.4,0.6\n; infill = 15%\n; end_gcode = \n; prusaslicer_config = end\n",
+			slicer: "prusaslicer",
+			keys:   []string{"layer_height", "nozzle_diameter", "infill", "end_gcode"},
+			found:  true,
+		},
+		"without markers": {
+			source: body + "; layer_height = 0.2\n\n; nozzle_diameter = 0.4,0.6\n; infill = 15%\n",
+			keys:   []string{"layer_height", "nozzle_diameter", "infill"},
+			found:  true,
+		},
+		"missing": {
+			source: "G28\n; layer_height = 0.2\nG1 X10 Y10 E1\n; end\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			s, ok := d.SlicerSettings()
+			if ok != tc.found {
+				t.Errorf("got found %v, want %v", ok, tc.found)
+				return
+			}
+
+			if !ok {
+				return
+			}
+
+			if s.Slicer != tc.slicer || strings.Join(s.Keys(), ",") != strings.Join(tc.keys, ",") {
+				t.Errorf("got slicer %q with keys %v, want %q with %v", s.Slicer, s.Keys(), tc.slicer, tc.keys)
+			}
+
+			if f, err := s.Float("layer_height"); err != nil || f != 0.2 {
+				t.Errorf("got layer height %v with error %v, want 0.2", f, err)
+			}
+
+			if f, err := s.Float("infill"); err != nil || f != 15 {
+				t.Errorf("got infill %v with error %v, want 15", f, err)
+			}
+
+			if floats, err := s.Floats("nozzle_diameter"); err != nil || len(floats) != 2 || floats[1] != 0.6 {
+				t.Errorf("got nozzle diameters %v with error %v, want [0.4 0.6]", floats, err)
+			}
+
+			if _, err := s.Float("missing"); err == nil {
+				t.Errorf("got error nil reading a missing setting, want error not nil")
+			}
+
+			if err := s.Set("layer_height", "0.3"); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if err := s.Set("first_layer_height", "0.25"); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			s.Delete("infill")
+
+			if err := d.SetSlicerSettings(s); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			updated, ok := d.SlicerSettings()
+			if !ok {
+				t.Errorf("got settings lost after writing them, want them")
+				return
+			}
+
+			if v, _ := updated.Get("layer_height"); v != "0.3" || updated.Len() != len(tc.keys) {
+				t.Errorf("got layer height %q and %d settings, want 0.3 and %d", v, updated.Len(), len(tc.keys))
+			}
+
+			if !strings.HasPrefix(d.String(), body) {
+				t.Errorf("got body modified %q, want %q", d.String(), body)
+			}
+		})
+	}
+
+	t.Run("append", func(t *testing.T) {
+		d := New(blocktest.Parse(t, "G28")...)
+
+		s := NewSlicerSettings("prusaslicer")
+		if err := s.Set("bed_temperature", "60"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+		if err := s.Set("wipe", "1"); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+
+		if err := d.SetSlicerSettings(s); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+
+		want := "G28\n; prusaslicer_config = begin\n; bed_temperature = 60\n; wipe = 1\n; prusaslicer_config = end\n"
+		if d.String() != want {
+			t.Errorf("got %q, want %q", d.String(), want)
+		}
+
+		if i, err := s.Int("bed_temperature"); err != nil || i != 60 {
+			t.Errorf("got bed temperature %d with error %v, want 60", i, err)
+		}
+
+		if b, err := s.Bool("wipe"); err != nil || !b {
+			t.Errorf("got wipe %v with error %v, want true", b, err)
+		}
+
+		for _, key := range []string{"", "a = b", "a\nb"} {
+			if err := s.Set(key, "1"); err == nil {
+				t.Errorf("got error nil setting key %q, want error not nil", key)
+			}
+		}
+
+		if err := d.SetSlicerSettings(nil); err == nil {
+			t.Errorf("got error nil setting nil settings, want error not nil")
+		}
+	})
+}
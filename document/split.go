@@ -0,0 +1,228 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region split criteria
+
+// SplitCriteria defines the points where a document is split. Both lists can be combined.
+type SplitCriteria struct {
+	// Layers are the indexes of the layers that start a new document.
+	Layers []int
+
+	// Heights start a new document at the first layer whose height is greater than or equal to each one of them.
+	Heights []float64
+}
+
+//#endregion
+//#region split
+
+// Split divides the document at layer boundaries and returns the parts in order.
+//
+// Each part, except the first one, starts with a generated preamble that restores the state of the machine at the split point:
+// units, positioning, extrusion mode, extruder position, temperatures and fan speed. The temperatures are awaited.
+// The layers are detected like Layers does with LayerAuto. The parts share the blocks with the document.
+//
+// It returns an error if some layer doesn't exist or there isn't a layer at some height.
+func (d *Document) Split(criteria SplitCriteria) ([]*Document, error) {
+
+	selected, err := d.selectLayers(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split document: %w", err)
+	}
+
+	var starts []int
+	for _, l := range selected {
+		if l.StartLine > 0 && l.StartLine < len(d.lines) {
+			starts = append(starts, l.StartLine)
+		}
+	}
+	starts = append(starts, len(d.lines))
+
+	var parts []*Document
+	state := newMachineState()
+	start := 0
+
+	for _, end := range starts {
+		part := &Document{}
+
+		if start > 0 {
+			preamble, err := state.preamble()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate preamble of the part %d: %w", len(parts), err)
+			}
+			part.lines = append(part.lines, preamble...)
+		}
+
+		for _, l := range d.lines[start:end] {
+			if l.Block != nil {
+				state.apply(l.Block)
+			}
+			part.lines = append(part.lines, l)
+		}
+
+		part.index()
+		parts = append(parts, part)
+		start = end
+	}
+
+	return parts, nil
+}
+
+//#endregion
+//#region private functions
+
+// selectLayers returns the layers selected by the criteria, without repetitions and in order.
+// The layers are detected like Layers does with LayerAuto.
+func (d *Document) selectLayers(criteria SplitCriteria) ([]Layer, error) {
+
+	// the default options are always valid
+	layers, _ := d.Layers()
+
+	selected := map[int]bool{}
+
+	for _, index := range criteria.Layers {
+		if index < 0 || index >= len(layers) {
+			return nil, fmt.Errorf("the layer %d doesn't exist, it has %d layers", index, len(layers))
+		}
+		selected[index] = true
+	}
+
+	for _, height := range criteria.Heights {
+		found := false
+		for _, l := range layers {
+			if l.Z >= height {
+				selected[l.Index] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("there isn't a layer at Z%s or above", strconv.FormatFloat(height, 'f', -1, 64))
+		}
+	}
+
+	var indexes []int
+	for index := range selected {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	result := make([]Layer, 0, len(indexes))
+	for _, index := range indexes {
+		result = append(result, layers[index])
+	}
+
+	return result, nil
+}
+
+// machineState follows the modal state of the machine along the blocks.
+type machineState struct {
+	// last command executed of each modal group, like "G21" for the units
+	modes modalState
+
+	// true if the extrusion mode is relative
+	relativeExtrusion bool
+
+	// position of the extruder, only tracked in absolute extrusion mode
+	e float64
+
+	// targets of the heaters and the fan, they are only restored if they were set
+	hotend, bed, fan          float64
+	hotendSet, bedSet, fanSet bool
+}
+
+// newMachineState returns the state of a machine before executing any block.
+func newMachineState() *machineState {
+	return &machineState{modes: modalState{}}
+}
+
+// apply updates the state with a block.
+func (s *machineState) apply(b block.Blocker) {
+	command := b.Command().String()
+	s.modes.apply(command)
+
+	switch command {
+	case "M82":
+		s.relativeExtrusion = false
+	case "M83":
+		s.relativeExtrusion = true
+	case "G92":
+		if e, ok := blockparam.Number(b, 'E'); ok {
+			s.e = e
+		}
+	case "G0", "G1", "G2", "G3":
+		if e, ok := blockparam.Number(b, 'E'); ok && !s.relativeExtrusion {
+			s.e = e
+		}
+	case "M104", "M109":
+		if t, ok := blockparam.Number(b, 'S'); ok {
+			s.hotend, s.hotendSet = t, true
+		}
+	case "M140", "M190":
+		if t, ok := blockparam.Number(b, 'S'); ok {
+			s.bed, s.bedSet = t, true
+		}
+	case "M106":
+		s.fan, s.fanSet = 255, true
+		if speed, ok := blockparam.Number(b, 'S'); ok {
+			s.fan = speed
+		}
+	case "M107":
+		s.fan, s.fanSet = 0, true
+	}
+}
+
+// preamble returns the lines that restore the state in a machine that just started.
+func (s *machineState) preamble() ([]Line, error) {
+	var expressions []string
+
+	for _, group := range []string{"units", "positioning", "extrusion", "plane"} {
+		if command, ok := s.modes[group]; ok {
+			expressions = append(expressions, command)
+		}
+	}
+
+	if !s.relativeExtrusion {
+		expressions = append(expressions, "G92 E"+numeric.Format(s.e))
+	}
+
+	if s.bedSet {
+		expressions = append(expressions, "M190 S"+numeric.Format(s.bed))
+	}
+
+	if s.hotendSet {
+		expressions = append(expressions, "M109 S"+numeric.Format(s.hotend))
+	}
+
+	if s.fanSet {
+		expressions = append(expressions, "M106 S"+numeric.Format(s.fan))
+	}
+
+	return generateLines("; preamble generated to restore the state of the machine", expressions)
+}
+
+// generateLines returns a comment line followed by a block for each expression.
+func generateLines(comment string, expressions []string) ([]Line, error) {
+	lines := []Line{{Text: comment}}
+	for _, expression := range expressions {
+		b, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", expression, err)
+		}
+		lines = append(lines, Line{Block: b})
+	}
+
+	return lines, nil
+}
+
+//#endregion
@@ -0,0 +1,85 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_Split(t *testing.T) {
+
+	source := strings.Join([]string{
+		"G21",
+		"G90",
+		"M82",
+		"M140 S60",
+		"M104 S200",
+		"G28",
+		";LAYER:0",
+		"G1 X10 Y10 Z0.2 E1",
+		"M106 S127",
+		";LAYER:1",
+		"G1 X20 Y10 Z0.4 E2",
+		";LAYER:2",
+		"G1 X20 Y20 Z0.6 E3",
+		"M104 S0",
+	}, "\n")
+
+	preamble := "; preamble generated to restore the state of the machine\nG21\nG90\nM82\n"
+
+	cases := map[string]struct {
+		criteria SplitCriteria
+		valid    bool
+		want     []string
+	}{
+		"layer": {SplitCriteria{Layers: []int{1}}, true, []string{
+			"G21\nG90\nM82\nM140 S60\nM104 S200\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nM106 S127\n",
+			preamble + "G92 E1\nM190 S60\nM109 S200\nM106 S127\n;LAYER:1\nG1 X20 Y10 Z0.4 E2\n;LAYER:2\nG1 X20 Y20 Z0.6 E3\nM104 S0\n",
+		}},
+		"height": {SplitCriteria{Heights: []float64{0.5}}, true, []string{
+			"G21\nG90\nM82\nM140 S60\nM104 S200\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nM106 S127\n;LAYER:1\nG1 X20 Y10 Z0.4 E2\n",
+			preamble + "G92 E2\nM190 S60\nM109 S200\nM106 S127\n;LAYER:2\nG1 X20 Y20 Z0.6 E3\nM104 S0\n",
+		}},
+		"combined and duplicated": {SplitCriteria{Layers: []int{1, 2}, Heights: []float64{0.4}}, true, []string{
+			"G21\nG90\nM82\nM140 S60\nM104 S200\nG28\n;LAYER:0\nG1 X10 Y10 Z0.2 E1\nM106 S127\n",
+			preamble + "G92 E1\nM190 S60\nM109 S200\nM106 S127\n;LAYER:1\nG1 X20 Y10 Z0.4 E2\n",
+			preamble + "G92 E2\nM190 S60\nM109 S200\nM106 S127\n;LAYER:2\nG1 X20 Y20 Z0.6 E3\nM104 S0\n",
+		}},
+		"nothing":        {SplitCriteria{}, true, []string{source + "\n"}},
+		"missing layer":  {SplitCriteria{Layers: []int{3}}, false, nil},
+		"missing height": {SplitCriteria{Heights: []float64{1}}, false, nil},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			parts, err := d.Split(tc.criteria)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if len(parts) != len(tc.want) {
+				t.Errorf("got %d parts, want %d parts", len(parts), len(tc.want))
+				return
+			}
+
+			for i, part := range parts {
+				if part.String() != tc.want[i] {
+					t.Errorf("got part %d %q, want %q", i, part.String(), tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,601 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+const (
+	// TRAVEL_EXTRUSION_TOLERANCE is the maximum extrusion left by the travel of a sequence, like a retraction that isn't primed,
+	// to consider that the sequence can be moved.
+	TRAVEL_EXTRUSION_TOLERANCE = 1e-6
+
+	// travelWords are the words accepted in the moves that can be reordered.
+	travelWords = "XYZEFIJR"
+
+	// travelImprovement is the minimum reduction of the travel accepted by the 2-opt algorithm, to avoid cycles by rounding errors.
+	travelImprovement = 1e-9
+)
+
+//#region travel algorithm
+
+// TravelAlgorithm defines how the order of the sequences of a layer is optimized.
+type TravelAlgorithm int
+
+const (
+	// TravelNearestNeighbor prints next the sequence that starts nearest to the current position. It is the default algorithm.
+	TravelNearestNeighbor TravelAlgorithm = iota
+
+	// TravelTwoOpt improves the nearest neighbor order reversing subsequences while the travel decreases.
+	// It is slower, but usually finds shorter travels.
+	TravelTwoOpt
+)
+
+// String returns the name of the algorithm.
+func (a TravelAlgorithm) String() string {
+	switch a {
+	case TravelNearestNeighbor:
+		return "nearest neighbor"
+	case TravelTwoOpt:
+		return "2-opt"
+	}
+
+	return fmt.Sprintf("travel algorithm(%d)", int(a))
+}
+
+//#endregion
+//#region travel stats
+
+// TravelStats summarizes the travel optimization of a document.
+type TravelStats struct {
+	// Sequences is the number of sequences that could be reordered.
+	Sequences int
+
+	// Before is the travel distance between the sequences in the original order.
+	Before float64
+
+	// After is the travel distance between the sequences in the optimized order.
+	After float64
+}
+
+// Saved returns the travel distance removed.
+func (s TravelStats) Saved() float64 {
+	return s.Before - s.After
+}
+
+//#endregion
+//#region travel configuration
+
+// TravelConfigurer defines the options of the travel optimization.
+type TravelConfigurer interface {
+	// Set the algorithm that optimizes the order of the sequences
+	SetAlgorithm(algorithm TravelAlgorithm) error
+}
+
+// TravelConfigurationCallbackable is the signature of the callbacks used to configure the travel optimization.
+type TravelConfigurationCallbackable func(config TravelConfigurer) error
+
+// travelConfigurator implements TravelConfigurer.
+type travelConfigurator struct {
+	algorithm TravelAlgorithm
+}
+
+// SetAlgorithm defines the algorithm that optimizes the order of the sequences.
+// If this method isn't called, by default it is TravelNearestNeighbor.
+func (tc *travelConfigurator) SetAlgorithm(algorithm TravelAlgorithm) error {
+	switch algorithm {
+	case TravelNearestNeighbor, TravelTwoOpt:
+	default:
+		return fmt.Errorf("failed to set travel algorithm, unknown value %d", algorithm)
+	}
+
+	tc.algorithm = algorithm
+
+	return nil
+}
+
+//#endregion
+//#region optimize travel
+
+// OptimizeTravel reorders the independent sequences of each layer to reduce the travel distance between them.
+//
+// A sequence is a travel followed by the moves that work, the extrusion moves of a print or the G1 moves of a plotter or a laser,
+// if the layer doesn't extrude. The sequences are independent if their travel positions X and Y, the extrusion of the travel is zero,
+// like a retraction and its prime, and they work at the same height, so they can be executed in any order.
+// Only the moves in absolute positioning and relative extrusion are reordered. Any other block, like a fan or a tool change,
+// and the layer markers, split the sequences that can be reordered, so they keep their place.
+// The last sequence before each split keeps its place too, so the position and the modal state after it don't change.
+// A feedrate is inserted before the sequences that inherited a different one, if it was known.
+//
+// The layers are detected like Layers does with LayerAuto, the whole document is a single layer if it hasn't layers.
+// It returns the travel distances before and after the optimization, and an error only if some option is invalid.
+func (d *Document) OptimizeTravel(options ...TravelConfigurationCallbackable) (TravelStats, error) {
+
+	configurator := &travelConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return TravelStats{}, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	// the default options are always valid
+	layers, _ := d.Layers()
+
+	ranges := make([]Range, 0, len(layers))
+	for _, l := range layers {
+		ranges = append(ranges, Range{StartLine: l.StartLine, EndLine: l.EndLine})
+	}
+	if len(ranges) == 0 {
+		ranges = append(ranges, Range{StartLine: 0, EndLine: len(d.lines)})
+	}
+
+	var stats TravelStats
+	lines := make([]Line, 0, len(d.lines))
+	state := ModalState{}
+	next := 0
+
+	for _, r := range ranges {
+		for _, l := range d.lines[next:r.StartLine] {
+			if l.Block != nil {
+				state.Apply(l.Block)
+			}
+			lines = append(lines, l)
+		}
+
+		o := &travelOptimizer{
+			algorithm: configurator.algorithm,
+			extrusion: hasExtrusion(d.lines[r.StartLine:r.EndLine]),
+			state:     state,
+		}
+
+		for _, l := range d.lines[r.StartLine:r.EndLine] {
+			if err := o.add(l); err != nil {
+				return TravelStats{}, fmt.Errorf("failed to optimize travel: %w", err)
+			}
+		}
+
+		if err := o.split(); err != nil {
+			return TravelStats{}, fmt.Errorf("failed to optimize travel: %w", err)
+		}
+
+		lines = append(lines, o.lines...)
+		state = o.state
+		stats.Sequences += o.stats.Sequences
+		stats.Before += o.stats.Before
+		stats.After += o.stats.After
+		next = r.EndLine
+	}
+
+	lines = append(lines, d.lines[next:]...)
+
+	d.lines = lines
+	d.index()
+
+	return stats, nil
+}
+
+//#endregion
+//#region private functions
+
+// travelKind classifies the blocks for the travel optimization.
+type travelKind int
+
+const (
+	// travelMove positions the machine without working.
+	travelMove travelKind = iota
+
+	// travelWork is a move that works, like an extrusion.
+	travelWork
+
+	// travelSplit can't be reordered, so it splits the sequences.
+	travelSplit
+)
+
+// travelSequence is a travel followed by the moves that work.
+type travelSequence struct {
+	lines []Line
+
+	// true if the travel positions each axis
+	x, y bool
+
+	// extrusion of the travel
+	e float64
+
+	// true after the first move that works
+	working bool
+
+	// position before the sequence, after the travel and after the last move
+	origin, start, end Position
+
+	// feedrate before and after the sequence
+	entryFeedrate, exitFeedrate float64
+
+	// true if some move inherits the feedrate before the sequence
+	inherits bool
+
+	// true after the first feedrate commanded by the sequence
+	feedrate bool
+}
+
+// movable returns true if the sequence can be executed in any order.
+func (s *travelSequence) movable() bool {
+	return s.working && s.x && s.y && math.Abs(s.e) <= TRAVEL_EXTRUSION_TOLERANCE
+}
+
+// travelOptimizer reorders the sequences of a layer, line by line.
+type travelOptimizer struct {
+	algorithm TravelAlgorithm
+
+	// true if the layer extrudes, else the G1 moves are the ones that work
+	extrusion bool
+
+	// state after the last line added, in the original order
+	state ModalState
+
+	// lines processed
+	lines []Line
+
+	// consecutive sequences that can be reordered
+	movable []*travelSequence
+
+	// sequence that is receiving lines
+	current *travelSequence
+
+	// lines without gcode that aren't assigned to a sequence yet
+	pending []Line
+
+	stats TravelStats
+}
+
+// add processes the next line of the layer.
+func (o *travelOptimizer) add(l Line) error {
+	if l.Block == nil {
+		if isLayerMarker(l.Text) {
+			if err := o.split(); err != nil {
+				return err
+			}
+			o.lines = append(o.lines, l)
+			return nil
+		}
+
+		o.pending = append(o.pending, l)
+		return nil
+	}
+
+	before := o.state.Clone()
+	kind := o.classify(l.Block, before)
+	o.state.Apply(l.Block)
+
+	switch kind {
+	case travelSplit:
+		if err := o.split(); err != nil {
+			return err
+		}
+		o.lines = append(o.lines, l)
+		return nil
+
+	case travelMove:
+		// a feedrate alone doesn't start a new travel
+		onlyFeedrate := !hasParameter(l.Block, 'X') && !hasParameter(l.Block, 'Y') && !hasParameter(l.Block, 'Z') && !hasParameter(l.Block, 'E')
+
+		if o.current != nil && o.current.working && !onlyFeedrate {
+			if err := o.close(); err != nil {
+				return err
+			}
+		}
+
+		if o.current == nil {
+			o.open(before)
+		}
+
+		o.current.x = o.current.x || hasParameter(l.Block, 'X')
+		o.current.y = o.current.y || hasParameter(l.Block, 'Y')
+		if e, ok := blockparam.Number(l.Block, 'E'); ok {
+			o.current.e += e
+		}
+
+	case travelWork:
+		if o.current == nil {
+			o.open(before)
+		}
+
+		if !o.current.working {
+			o.current.working = true
+			o.current.start = before.Position
+		}
+	}
+
+	if !o.current.feedrate {
+		if hasParameter(l.Block, 'F') {
+			o.current.feedrate = true
+		} else {
+			o.current.inherits = true
+		}
+	}
+
+	o.current.lines = append(o.current.lines, o.pending...)
+	o.current.lines = append(o.current.lines, l)
+	o.pending = o.pending[:0]
+	o.current.end = o.state.Position
+	o.current.exitFeedrate = o.state.Feedrate
+
+	return nil
+}
+
+// classify returns how the block received is handled, considering the state in which it is executed.
+func (o *travelOptimizer) classify(b block.Blocker, before ModalState) travelKind {
+	command := b.Command().String()
+	switch command {
+	case "G0", "G1", "G2", "G3":
+	default:
+		return travelSplit
+	}
+
+	if before.Relative {
+		return travelSplit
+	}
+
+	for _, p := range b.Parameters() {
+		if strings.IndexByte(travelWords, p.Word()) < 0 {
+			return travelSplit
+		}
+	}
+
+	e, extrudes := blockparam.Number(b, 'E')
+	if extrudes && !before.RelativeExtrusion {
+		return travelSplit
+	}
+
+	arc := command == "G2" || command == "G3"
+
+	work := command != "G0"
+	if o.extrusion {
+		work = extrudes && e > 0 && (hasParameter(b, 'X') || hasParameter(b, 'Y'))
+	}
+
+	if !work {
+		if arc {
+			return travelSplit
+		}
+		return travelMove
+	}
+
+	if z, ok := blockparam.Number(b, 'Z'); ok && z != before.Position.Z {
+		return travelSplit
+	}
+
+	return travelWork
+}
+
+// open starts a new sequence in the state received.
+func (o *travelOptimizer) open(before ModalState) {
+	o.current = &travelSequence{origin: before.Position, entryFeedrate: before.Feedrate}
+}
+
+// close finishes the current sequence, it is added to the movable ones if it can be reordered with them.
+func (o *travelOptimizer) close() error {
+	s := o.current
+	o.current = nil
+
+	if s == nil {
+		return nil
+	}
+
+	if !s.movable() {
+		if err := o.flush(); err != nil {
+			return err
+		}
+		o.lines = append(o.lines, s.lines...)
+		return nil
+	}
+
+	// the sequences must work at the same height, so the travels that don't position Z are valid in any order
+	if len(o.movable) > 0 && o.movable[0].start.Z != s.start.Z {
+		if err := o.flush(); err != nil {
+			return err
+		}
+	}
+
+	o.movable = append(o.movable, s)
+
+	return nil
+}
+
+// split closes the current sequence and writes the movable ones, reordered, followed by the lines pending.
+func (o *travelOptimizer) split() error {
+	if err := o.close(); err != nil {
+		return err
+	}
+
+	if err := o.flush(); err != nil {
+		return err
+	}
+
+	o.lines = append(o.lines, o.pending...)
+	o.pending = o.pending[:0]
+
+	return nil
+}
+
+// flush writes the movable sequences in the optimized order. The last one keeps its place.
+func (o *travelOptimizer) flush() error {
+	sequences := o.movable
+	o.movable = nil
+
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	origin := sequences[0].origin
+
+	order := make([]int, len(sequences))
+	for i := range order {
+		order[i] = i
+	}
+
+	before := travelDistance(sequences, order, origin)
+	after := before
+
+	// the first sequence keeps its place if it moves to the height of the others, like after a layer change,
+	// because the travels of the others could not position Z
+	fixed, start := 0, origin
+	if sequences[0].origin.Z != sequences[0].start.Z {
+		fixed, start = 1, sequences[0].end
+	}
+
+	if len(sequences)-fixed > 2 {
+		optimized := nearestNeighbor(sequences[fixed:], start)
+		if o.algorithm == TravelTwoOpt {
+			twoOpt(sequences[fixed:], optimized, start)
+		}
+
+		candidate := order[:fixed:fixed]
+		for _, i := range optimized {
+			candidate = append(candidate, i+fixed)
+		}
+
+		if distance := travelDistance(sequences, candidate, origin); distance < before {
+			order, after = candidate, distance
+		}
+	}
+
+	o.stats.Sequences += len(sequences)
+	o.stats.Before += before
+	o.stats.After += after
+
+	feedrate := sequences[0].entryFeedrate
+	for _, i := range order {
+		s := sequences[i]
+
+		// the feedrate isn't known before the first move that commands one
+		if s.inherits && s.entryFeedrate != feedrate && s.entryFeedrate > 0 {
+			b, err := gcodeblock.Parse("G1 F" + numeric.Format(s.entryFeedrate))
+			if err != nil {
+				return fmt.Errorf("failed to restore feedrate %v: %w", s.entryFeedrate, err)
+			}
+			o.lines = append(o.lines, Line{Block: b})
+		}
+
+		o.lines = append(o.lines, s.lines...)
+		feedrate = s.exitFeedrate
+	}
+
+	return nil
+}
+
+// hasExtrusion returns true if some block of the lines commands the extruder.
+func hasExtrusion(lines []Line) bool {
+	for _, l := range lines {
+		if l.Block != nil && hasParameter(l.Block, 'E') {
+			return true
+		}
+	}
+
+	return false
+}
+
+// travelDistance returns the distance in the XY plane between the sequences executed in the order received, starting at the origin.
+func travelDistance(sequences []*travelSequence, order []int, origin Position) float64 {
+	distance := 0.0
+	position := origin
+
+	for _, i := range order {
+		distance += planeDistance(position, sequences[i].start)
+		position = sequences[i].end
+	}
+
+	return distance
+}
+
+// nearestNeighbor returns the order that always executes next the sequence nearest to the current position.
+// The last sequence keeps its place.
+func nearestNeighbor(sequences []*travelSequence, origin Position) []int {
+	last := len(sequences) - 1
+	used := make([]bool, last)
+	order := make([]int, 0, len(sequences))
+	position := origin
+
+	for len(order) < last {
+		nearest, distance := -1, math.Inf(1)
+		for i := 0; i < last; i++ {
+			if used[i] {
+				continue
+			}
+
+			if d := planeDistance(position, sequences[i].start); d < distance {
+				nearest, distance = i, d
+			}
+		}
+
+		used[nearest] = true
+		order = append(order, nearest)
+		position = sequences[nearest].end
+	}
+
+	return append(order, last)
+}
+
+// twoOpt reverses the subsequences of the order that reduce the travel, until none does. The last sequence keeps its place.
+//
+// The travels are directed, from the end of a sequence to the start of the next one,
+// so reversing a subsequence changes the travels inside it too.
+func twoOpt(sequences []*travelSequence, order []int, origin Position) {
+	// travel between the position k and k+1 of the order, forward and reversed
+	link := func(from int, to int) float64 {
+		if from < 0 {
+			return planeDistance(origin, sequences[order[to]].start)
+		}
+		return planeDistance(sequences[order[from]].end, sequences[order[to]].start)
+	}
+
+	movable := len(order) - 1
+
+	for improved := true; improved; {
+		improved = false
+
+		// prefix sums of the travels inside the order, forward and reversed
+		forward := make([]float64, len(order))
+		backward := make([]float64, len(order))
+		for k := 1; k < len(order); k++ {
+			forward[k] = forward[k-1] + link(k-1, k)
+			backward[k] = backward[k-1] + planeDistance(sequences[order[k]].end, sequences[order[k-1]].start)
+		}
+
+		for i := 0; i < movable-1 && !improved; i++ {
+			for j := i + 1; j < movable; j++ {
+				current := link(i-1, i) + (forward[j] - forward[i]) + link(j, j+1)
+
+				// the subsequence from i to j reversed: the travel enters at j and leaves from i
+				var enter float64
+				if i == 0 {
+					enter = planeDistance(origin, sequences[order[j]].start)
+				} else {
+					enter = planeDistance(sequences[order[i-1]].end, sequences[order[j]].start)
+				}
+				reversed := enter + (backward[j] - backward[i]) + planeDistance(sequences[order[i]].end, sequences[order[j+1]].start)
+
+				if reversed < current-travelImprovement {
+					for a, b := i, j; a < b; a, b = a+1, b-1 {
+						order[a], order[b] = order[b], order[a]
+					}
+					improved = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// planeDistance returns the distance between two positions in the XY plane.
+func planeDistance(a Position, b Position) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+//#endregion
@@ -0,0 +1,109 @@
+package document
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDocument_OptimizeTravel(t *testing.T) {
+
+	printer := "M83\n;LAYER:0\nG1 Z0.2 F600\nG0 X50 Y0 F6000\nG1 X60 Y0 E1 F1200\n" +
+		"G1 E-1 F2100\nG0 X0 Y0 F6000\nG1 E1 F2100\nG1 X10 Y0 E1 F1200\n" +
+		"G1 E-1 F2100\nG0 X65 Y0 F6000\nG1 E1 F2100\nG1 X68 Y0 E1 F1200\n" +
+		"G1 E-1 F2100\nG0 X12 Y0 F6000\nG1 E1 F2100\nG1 X20 Y0 E1 F1200\n" +
+		"M106 S255\n;LAYER:1\nG1 Z0.4 F600\nG1 X30 Y0 E1 F1200\n"
+
+	plotter := "G21\nG90\nG0 X0 Y0\nG0 X100 Y0\nG1 X110 Y0 F1000\nG0 X10 Y0\nG1 X20 Y0\n; part\nG0 X50 Y0\nG1 X60 Y0\nG0 X0 Y50\nG1 X0 Y60\nM5\n"
+
+	cases := map[string]struct {
+		source    string
+		algorithm TravelAlgorithm
+		want      string
+		stats     TravelStats
+	}{
+		"printer": {
+			source: printer,
+			want: "M83\n;LAYER:0\nG1 Z0.2 F600\nG0 X50 Y0 F6000\nG1 X60 Y0 E1 F1200\n" +
+				"G1 E-1 F2100\nG0 X65 Y0 F6000\nG1 E1 F2100\nG1 X68 Y0 E1 F1200\n" +
+				"G1 E-1 F2100\nG0 X0 Y0 F6000\nG1 E1 F2100\nG1 X10 Y0 E1 F1200\n" +
+				"G1 E-1 F2100\nG0 X12 Y0 F6000\nG1 E1 F2100\nG1 X20 Y0 E1 F1200\n" +
+				"M106 S255\n;LAYER:1\nG1 Z0.4 F600\nG1 X30 Y0 E1 F1200\n",
+			stats: TravelStats{Sequences: 4, Before: 221, After: 125},
+		},
+		"plotter": {
+			source: plotter,
+			want:   "G21\nG90\nG1 F1000\nG0 X10 Y0\nG1 X20 Y0\n; part\nG0 X50 Y0\nG1 X60 Y0\nG0 X0 Y0\nG0 X100 Y0\nG1 X110 Y0 F1000\nG0 X0 Y50\nG1 X0 Y60\nM5\n",
+			stats:  TravelStats{Sequences: 4, Before: 230 + math.Hypot(60, 50), After: 80 + math.Hypot(110, 50)},
+		},
+		"plotter with 2-opt": {
+			source:    plotter,
+			algorithm: TravelTwoOpt,
+			want:      "G21\nG90\nG1 F1000\nG0 X10 Y0\nG1 X20 Y0\n; part\nG0 X50 Y0\nG1 X60 Y0\nG0 X0 Y0\nG0 X100 Y0\nG1 X110 Y0 F1000\nG0 X0 Y50\nG1 X0 Y60\nM5\n",
+			stats:     TravelStats{Sequences: 4, Before: 230 + math.Hypot(60, 50), After: 80 + math.Hypot(110, 50)},
+		},
+		"absolute extrusion": {
+			source: "M82\nG0 X0 Y0\nG1 X10 Y0 E1\nG0 X50 Y0\nG1 X60 Y0 E2\nG0 X20 Y0\nG1 X30 Y0 E3\n",
+			want:   "M82\nG0 X0 Y0\nG1 X10 Y0 E1\nG0 X50 Y0\nG1 X60 Y0 E2\nG0 X20 Y0\nG1 X30 Y0 E3\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			stats, err := d.OptimizeTravel(func(config TravelConfigurer) error {
+				return config.SetAlgorithm(tc.algorithm)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if d.String() != tc.want {
+				t.Errorf("got %q, want %q", d.String(), tc.want)
+			}
+
+			if math.Abs(stats.Before-tc.stats.Before) > 1e-9 || math.Abs(stats.After-tc.stats.After) > 1e-9 || stats.Sequences != tc.stats.Sequences {
+				t.Errorf("got stats %+v, want %+v", stats, tc.stats)
+			}
+		})
+	}
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		d := NewFromLines()
+
+		_, err := d.OptimizeTravel(func(config TravelConfigurer) error {
+			return config.SetAlgorithm(TravelAlgorithm(9))
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestTwoOpt(t *testing.T) {
+	// points where the sequences start and end, the last one keeps its place
+	var sequences []*travelSequence
+	for _, x := range []float64{1, -2, 4, 5} {
+		sequences = append(sequences, &travelSequence{start: Position{X: x}, end: Position{X: x}})
+	}
+
+	order := nearestNeighbor(sequences, Position{})
+	if got := travelDistance(sequences, order, Position{}); got != 11 {
+		t.Errorf("got nearest neighbor distance %v, want 11", got)
+	}
+
+	twoOpt(sequences, order, Position{})
+	if got := travelDistance(sequences, order, Position{}); got != 9 {
+		t.Errorf("got 2-opt distance %v with order %v, want 9", got, order)
+	}
+
+	if order[len(order)-1] != 3 {
+		t.Errorf("got last sequence %d, want 3", order[len(order)-1])
+	}
+}
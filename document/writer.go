@@ -0,0 +1,315 @@
+package document
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region writer configuration
+
+// WriterConfigurer defines the options of the export of a writer.
+type WriterConfigurer interface {
+	// Set if the checksum of the blocks is exported
+	SetChecksums(export bool) error
+
+	// Set if the comments of the blocks and the comment lines are exported
+	SetComments(export bool) error
+
+	// Set the characters written at the end of each line
+	SetLineEnding(ending string) error
+
+	// Set the size of the buffer used to group the writes
+	SetBufferSize(size int) error
+
+	// Set the compression of the output
+	SetCompression(compression Compression) error
+}
+
+// WriterConfigurationCallbackable is the signature of the callbacks used to configure a writer.
+type WriterConfigurationCallbackable func(config WriterConfigurer) error
+
+// writerConfigurator implements WriterConfigurer.
+type writerConfigurator struct {
+	checksums   bool
+	comments    bool
+	lineEnding  string
+	bufferSize  int
+	compression Compression
+}
+
+// SetChecksums defines if the checksum of the blocks is exported.
+// If this method isn't called, by default the checksums are exported.
+func (wc *writerConfigurator) SetChecksums(export bool) error {
+	wc.checksums = export
+
+	return nil
+}
+
+// SetComments defines if the comments of the blocks and the lines that only contain a comment are exported.
+// If this method isn't called, by default the comments are exported.
+func (wc *writerConfigurator) SetComments(export bool) error {
+	wc.comments = export
+
+	return nil
+}
+
+// SetLineEnding defines the characters written at the end of each line, only "\n" and "\r\n" are accepted.
+// If this method isn't called, by default it is LINE_ENDING, but the lines parsed in round-trip mode keep their original line ending.
+func (wc *writerConfigurator) SetLineEnding(ending string) error {
+	if ending != "\n" && ending != "\r\n" {
+		return fmt.Errorf("failed to set line ending, it must be \\n or \\r\\n: %q", ending)
+	}
+
+	wc.lineEnding = ending
+
+	return nil
+}
+
+// SetBufferSize defines the size in bytes of the buffer used to group the writes. It must be positive.
+// If this method isn't called, by default it is the size used by the bufio package.
+func (wc *writerConfigurator) SetBufferSize(size int) error {
+	if size <= 0 {
+		return fmt.Errorf("failed to set buffer size, it must be positive: %d", size)
+	}
+
+	wc.bufferSize = size
+
+	return nil
+}
+
+// SetCompression defines the compression of the output, only CompressionNone and CompressionGzip are accepted.
+// The compressed output is only complete when the writer is closed.
+// If this method isn't called, by default it is CompressionNone.
+func (wc *writerConfigurator) SetCompression(compression Compression) error {
+	if compression != CompressionNone && compression != CompressionGzip {
+		return fmt.Errorf("failed to set compression, it must be none or gzip: %s", compression)
+	}
+
+	wc.compression = compression
+
+	return nil
+}
+
+//#endregion
+//#region writer struct
+
+// Writer exports blocks and lines to an io.Writer, one per line.
+//
+// The writes are buffered, so Flush must be called when all lines were written,
+// or Close if the output is compressed.
+type Writer struct {
+	// buffered destination of the lines
+	w *bufio.Writer
+
+	// compressor of the output, it is nil if the output isn't compressed
+	compressor *gzip.Writer
+
+	// format used to export each block
+	format string
+
+	// true if the comment lines are exported
+	comments bool
+
+	// characters written at the end of each line
+	lineEnding string
+
+	// true if the lines parsed in round-trip mode that weren't modified are written with their original bytes
+	exact bool
+
+	// number of bytes written, including the ones that are still in the buffer
+	bytes int64
+
+	// number of lines written, including the ones that are still in the buffer
+	lines int
+}
+
+// WriteBlock exports a block as a single line.
+func (w *Writer) WriteBlock(b block.Blocker) error {
+	if b == nil {
+		return fmt.Errorf("failed to write line %d, the block mustn't be nil", w.lines+1)
+	}
+
+	return w.write(formatBlock(b, w.format))
+}
+
+// WriteLine exports a line of a document. The comment lines are omitted if the writer doesn't export comments.
+//
+// If the writer uses the default export options, the lines parsed in round-trip mode are written with their original bytes
+// while they aren't modified, and the lines modified keep their original line ending.
+func (w *Writer) WriteLine(l Line) error {
+	if w.exact {
+		if raw, ok := l.Original(); ok && !l.Modified() {
+			return w.writeRaw(raw)
+		}
+
+		if ending, ok := l.ending(); ok {
+			return w.writeEnding(l.String(), ending)
+		}
+	}
+
+	if l.Block != nil {
+		return w.WriteBlock(l.Block)
+	}
+
+	if !w.comments && strings.HasPrefix(strings.TrimSpace(l.Text), ";") {
+		return nil
+	}
+
+	return w.write(l.Text)
+}
+
+// WriteDocument exports all lines of a document in order.
+func (w *Writer) WriteDocument(d *Document) error {
+	for _, l := range d.lines {
+		if err := w.WriteLine(l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the lines buffered to the underlying io.Writer.
+//
+// If the output is compressed, the data written can be decompressed up to the last line, but the stream isn't finished.
+func (w *Writer) Flush() error {
+	if err := w.w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush writer: %w", err)
+	}
+
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close writes the lines buffered and finishes the compressed stream, if the output is compressed.
+// It doesn't close the underlying io.Writer, and the writer mustn't be used after closing it.
+func (w *Writer) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			return fmt.Errorf("failed to close writer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Bytes returns the number of bytes written, including the ones that weren't flushed yet.
+// If the output is compressed, they are the bytes before the compression.
+func (w *Writer) Bytes() int64 {
+	return w.bytes
+}
+
+// Lines returns the number of lines written, including the ones that weren't flushed yet.
+func (w *Writer) Lines() int {
+	return w.lines
+}
+
+// write exports a single line followed by the line ending of the writer.
+func (w *Writer) write(text string) error {
+	return w.writeEnding(text, w.lineEnding)
+}
+
+// writeEnding exports a single line followed by the line ending required.
+func (w *Writer) writeEnding(text string, ending string) error {
+	n, err := w.w.WriteString(text)
+	w.bytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write line %d: %w", w.lines+1, err)
+	}
+
+	n, err = w.w.WriteString(ending)
+	w.bytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write line %d: %w", w.lines+1, err)
+	}
+
+	w.lines++
+
+	return nil
+}
+
+// writeRaw exports the original bytes of a line, they already include the line ending.
+func (w *Writer) writeRaw(raw string) error {
+	n, err := w.w.WriteString(raw)
+	w.bytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write line %d: %w", w.lines+1, err)
+	}
+
+	w.lines++
+
+	return nil
+}
+
+//#endregion
+//#region constructor
+
+// NewWriter returns a new writer that exports the lines to w.
+//
+// By default, the blocks are exported with LINE_FORMAT, including checksums and comments, and each line ends with LINE_ENDING.
+func NewWriter(w io.Writer, options ...WriterConfigurationCallbackable) (*Writer, error) {
+
+	if w == nil {
+		return nil, fmt.Errorf("failed to create writer, the destination mustn't be nil")
+	}
+
+	configurator := &writerConfigurator{
+		checksums:   true,
+		comments:    true,
+		compression: CompressionNone,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	format := "%l %c %p"
+	if configurator.checksums {
+		format += "%k"
+	}
+	if configurator.comments {
+		format += " %m"
+	}
+
+	writer := &Writer{
+		format:     format,
+		comments:   configurator.comments,
+		lineEnding: configurator.lineEnding,
+		exact:      configurator.checksums && configurator.comments && configurator.lineEnding == "",
+	}
+
+	if writer.lineEnding == "" {
+		writer.lineEnding = LINE_ENDING
+	}
+
+	if configurator.compression == CompressionGzip {
+		writer.compressor = gzip.NewWriter(w)
+		w = writer.compressor
+	}
+
+	if configurator.bufferSize > 0 {
+		writer.w = bufio.NewWriterSize(w, configurator.bufferSize)
+	} else {
+		writer.w = bufio.NewWriter(w)
+	}
+
+	return writer, nil
+}
+
+//#endregion
@@ -0,0 +1,115 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+
+	source := ";start\nN1 G28*18 ;home\n\nN2 M105*37"
+
+	cases := map[string]struct {
+		options []WriterConfigurationCallbackable
+		valid   bool
+		want    string
+		lines   int
+	}{
+		"default": {nil, true, ";start\nN1 G28*18 ;home\n\nN2 M105*37\n", 4},
+		"without checksums": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetChecksums(false) }},
+			true, ";start\nN1 G28 ;home\n\nN2 M105\n", 4,
+		},
+		"without comments": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetComments(false) }},
+			true, "N1 G28*18\n\nN2 M105*37\n", 3,
+		},
+		"crlf": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetLineEnding("\r\n") }},
+			true, ";start\r\nN1 G28*18 ;home\r\n\r\nN2 M105*37\r\n", 4,
+		},
+		"small buffer": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetBufferSize(16) }},
+			true, ";start\nN1 G28*18 ;home\n\nN2 M105*37\n", 4,
+		},
+		"invalid line ending": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetLineEnding("\r") }},
+			false, "", 0,
+		},
+		"invalid buffer size": {
+			[]WriterConfigurationCallbackable{func(config WriterConfigurer) error { return config.SetBufferSize(0) }},
+			false, "", 0,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var sb strings.Builder
+			w, err := NewWriter(&sb, tc.options...)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if err := w.WriteDocument(d); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if err := w.Flush(); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if sb.String() != tc.want {
+				t.Errorf("got %q, want %q", sb.String(), tc.want)
+			}
+
+			if w.Lines() != tc.lines || w.Bytes() != int64(len(tc.want)) {
+				t.Errorf("got %d lines and %d bytes, want %d lines and %d bytes", w.Lines(), w.Bytes(), tc.lines, len(tc.want))
+			}
+		})
+	}
+}
+
+func TestWriter_Errors(t *testing.T) {
+
+	if _, err := NewWriter(nil); err == nil {
+		t.Errorf("got error nil, want error not nil with nil destination")
+	}
+
+	w, err := NewWriter(&strings.Builder{})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if err := w.WriteBlock(nil); err == nil {
+		t.Errorf("got error nil, want error not nil with nil block")
+	}
+
+	w, err = NewWriter(failingWriter{}, func(config WriterConfigurer) error {
+		return config.SetBufferSize(1)
+	})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if err := w.WriteLine(Line{Text: ";long comment"}); err == nil {
+		t.Errorf("got error nil, want error not nil writing to a failing destination")
+	}
+}
@@ -11,6 +11,7 @@ package addressablegcode
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -31,6 +32,12 @@ type Gcode[T gcode.AddressType] struct {
 
 	// address stores the address value of the gcode that we are modeling
 	address T
+
+	// literal stores the original text of the address when the gcode was parsed, it can be empty
+	literal string
+
+	// frozen is true if the gcode is shared and it can't be modified
+	frozen bool
 }
 
 // Address return the value of the address
@@ -61,33 +68,112 @@ func (g *Gcode[T]) HasAddress() bool {
 
 // SetAddress allow to store a new value
 //
+// It allows the transforms to adjust the address in place, instead of create a new gcode through a factory.
+//
 // If the address data type is string then the new value is verified.
 // If it doesn't satisfy the a string format then SetAddress returns an error.
+// If the address data type is float32 or float64 then the new value must be a finite number.
+// When it returns an error the address isn't modified.
 func (g *Gcode[T]) SetAddress(address T) error {
 
-	if ok, err := isGenericValueAnStringAddressValid(address); ok {
-		if err != nil {
-			return fmt.Errorf("failed set the value %v at the %T address: %w", address, address, err)
-		}
+	if g.frozen {
+		return fmt.Errorf("failed set the value %v at the gcode %s, it is frozen", address, g)
+	}
+
+	if err := isAddressValid(address); err != nil {
+		return fmt.Errorf("failed set the value %v at the %T address: %w", address, address, err)
 	}
 
 	g.address = address
+	// the original text doesn't represent the new value anymore
+	g.literal = ""
+
+	return nil
+}
+
+// Literal returns the original text of the address, like "0010.50" in X0010.50.
+//
+// It is empty if the gcode wasn't parsed from a text or if the address was modified after that.
+func (g *Gcode[T]) Literal() string {
+	return g.literal
+}
+
+// SetLiteral stores the original text of the address, to re-emit it exactly.
+//
+// Return an error if the literal can't be parsed as an address of the data type T or if its value is different to the current address.
+// An empty literal removes the text stored.
+func (g *Gcode[T]) SetLiteral(literal string) error {
+
+	if g.frozen {
+		return fmt.Errorf("failed to set literal %s at the gcode %s, it is frozen", literal, g)
+	}
+
+	if literal == "" {
+		g.literal = ""
+		return nil
+	}
+
+	var address T
+	if err := parseAddress(literal, &address); err != nil {
+		return fmt.Errorf("failed to set literal %s: %w", literal, err)
+	}
+
+	if address != g.address {
+		return fmt.Errorf("failed to set literal %s, it doesn't represent the address %v", literal, g.address)
+	}
+
+	g.literal = literal
 
 	return nil
 }
 
 // String return gcode formatted
+//
+// The fractional addresses are exported using gcode.DefaultFloatFormat.
 func (g *Gcode[T]) String() string {
+	return g.Format(gcode.DefaultFloatFormat())
+}
 
-	if float32Value, ok := any(g.address).(float32); ok {
-		sv := strconv.FormatFloat(float64(float32Value), 'f', -1, 32)
-		if !strings.Contains(sv, ".") {
-			sv += ".0"
-		}
-		return fmt.Sprintf("%s%s", string(g.word), sv)
+// Format return gcode formatted using a custom float format.
+//
+// It is only applied to float32 and float64 addresses, the rest of the data types are exported like String.
+func (g *Gcode[T]) Format(format gcode.FloatFormat) string {
+	return string(g.AppendFormat(nil, format))
+}
+
+// AppendFormat appends the gcode formatted like Format to dst and returns the extended buffer.
+//
+// It doesn't allocate memory if dst has enough capacity.
+func (g *Gcode[T]) AppendFormat(dst []byte, format gcode.FloatFormat) []byte {
+
+	dst = append(dst, g.word)
+
+	switch value := any(g.address).(type) {
+	case float32:
+		return format.AppendFloat(dst, float64(value), 32)
+	case float64:
+		return format.AppendFloat(dst, value, 64)
+	case int32:
+		return strconv.AppendInt(dst, int64(value), 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(value), 10)
+	case string:
+		return append(dst, value...)
 	}
 
-	return fmt.Sprintf("%s%v", string(g.word), g.address)
+	return append(dst, fmt.Sprint(g.address)...)
+}
+
+// Freeze makes the gcode immutable, so it can be shared safely between many blocks.
+//
+// After it is called, SetAddress and SetLiteral always return an error. It can't be undone.
+func (g *Gcode[T]) Freeze() {
+	g.frozen = true
+}
+
+// Frozen returns true if the gcode is immutable.
+func (g *Gcode[T]) Frozen() bool {
+	return g.frozen
 }
 
 // Word return a copy of the word struct in the gcode
@@ -95,24 +181,66 @@ func (g *Gcode[T]) Word() byte {
 	return g.word
 }
 
+// MarshalText implements encoding.TextMarshaler interface, it returns the gcode formatted like String.
+func (g *Gcode[T]) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface, it loads the gcode from an expression like "X12.5".
+//
+// The address must be expressed in the data type T of the gcode. Returns an error if the word or the address are invalid.
+// In that case, the gcode isn't modified. A frozen gcode is never modified.
+func (g *Gcode[T]) UnmarshalText(text []byte) error {
+	if g.frozen {
+		return fmt.Errorf("failed to unmarshal gcode %q: the gcode %s is frozen", text, g)
+	}
+
+	if len(text) < 2 {
+		return fmt.Errorf("failed to unmarshal gcode %q: it must contain a word and an address", text)
+	}
+
+	var address T
+	if err := parseAddress(string(text[1:]), &address); err != nil {
+		return fmt.Errorf("failed to unmarshal gcode %q: %w", text, err)
+	}
+
+	ng, err := New(text[0], address)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal gcode %q: %w", text, err)
+	}
+	ng.literal = string(text[1:])
+
+	*g = *ng
+
+	return nil
+}
+
 //#endregion
 //#region package constructor
 
 // New return a new Gcode[T] instance or error if some inputs are invalids
 // word is the letter that compose the gcode
 // address is the value of the gcode
+//
+// The word is validated with gcode.IsValidWord.
 func New[T gcode.AddressType](word byte, address T) (*Gcode[T], error) {
+	return NewWithRegistry(word, address, nil)
+}
+
+// NewWithRegistry return a new Gcode[T] instance or error if some inputs are invalids
+// word is the letter that compose the gcode, it must be accepted by the registry
+// address is the value of the gcode
+// registry defines the valid words, if it is nil then the word is validated with gcode.IsValidWord
+func NewWithRegistry[T gcode.AddressType](word byte, address T, registry *gcode.WordRegistry) (*Gcode[T], error) {
 	// Try instace Word struct
-	err := gcode.IsValidWord(word)
+	err := isWordValid(word, registry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an addressable gcode instance of type %T when trying to use %v word: %w", address, word, err)
 	}
 
 	// Try instace Address struct
-	if ok, err := isGenericValueAnStringAddressValid(address); ok {
-		if err != nil {
-			return nil, fmt.Errorf("failed to create an string address instance using the expression %v: %w", address, err)
-		}
+	if err := isAddressValid(address); err != nil {
+		return nil, fmt.Errorf("failed to create an address instance using the expression %v: %w", address, err)
 	}
 
 	return &Gcode[T]{
@@ -124,6 +252,76 @@ func New[T gcode.AddressType](word byte, address T) (*Gcode[T], error) {
 //#endregion
 //#region private functions
 
+// parseAddress converts a string expression in an address value of the data type T.
+func parseAddress[T gcode.AddressType](source string, address *T) error {
+	switch a := any(address).(type) {
+	case *string:
+		*a = source
+	case *int32:
+		value, err := strconv.ParseInt(source, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid int32 address %s: %w", source, err)
+		}
+		*a = int32(value)
+	case *uint32:
+		value, err := strconv.ParseUint(source, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid uint32 address %s: %w", source, err)
+		}
+		*a = uint32(value)
+	case *float32:
+		value, err := strconv.ParseFloat(source, 32)
+		if err != nil {
+			return fmt.Errorf("invalid float32 address %s: %w", source, err)
+		}
+		*a = float32(value)
+	case *float64:
+		value, err := strconv.ParseFloat(source, 64)
+		if err != nil {
+			return fmt.Errorf("invalid float64 address %s: %w", source, err)
+		}
+		*a = value
+	default:
+		return fmt.Errorf("unsupported address data type %T", *address)
+	}
+
+	return nil
+}
+
+// isWordValid validates the word with the registry, or with gcode.IsValidWord if the registry is nil.
+func isWordValid(word byte, registry *gcode.WordRegistry) error {
+	if registry == nil {
+		return gcode.IsValidWord(word)
+	}
+
+	return registry.IsValidWord(word)
+}
+
+// isAddressValid returns an error if the value can't be stored as address.
+//
+// The string values must satisfy the string address format and the fractional values must be finite numbers.
+func isAddressValid[T gcode.AddressType](value T) error {
+	if ok, err := isGenericValueAnStringAddressValid(value); ok {
+		return err
+	}
+
+	var number float64
+	switch v := any(value).(type) {
+	case float32:
+		number = float64(v)
+	case float64:
+		number = v
+	default:
+		return nil
+	}
+
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Errorf("gcode address must be a finite number: %v", number)
+	}
+
+	return nil
+}
+
 // isAddressStringValid allow knowing if a string input can be an address value of string data type valid.
 //
 // Return an error if s string is invalid.
@@ -2,6 +2,7 @@ package addressablegcode
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/mauroalderete/gcode-core/gcode"
@@ -284,6 +285,85 @@ func TestAddresableGcodeSetAddress(t *testing.T) {
 			t.Errorf("got %s, want error: not nil", err)
 		}
 	})
+
+	t.Run("address float", func(t *testing.T) {
+		cases := map[string]struct {
+			set   func() (string, error)
+			valid bool
+			want  string
+		}{
+			"float32 valid":        {setAddress[float32]('X', 1, 12.5), true, "X12.5"},
+			"float32 nan":          {setAddress('X', float32(1), float32(math.NaN())), false, "X1.0"},
+			"float32 inf":          {setAddress('X', float32(1), float32(math.Inf(1))), false, "X1.0"},
+			"float64 valid":        {setAddress[float64]('Y', 1, -0.001), true, "Y-0.001"},
+			"float64 nan":          {setAddress('Y', 1, math.NaN()), false, "Y1.0"},
+			"float64 negative inf": {setAddress('Y', 1, math.Inf(-1)), false, "Y1.0"},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				got, err := tc.set()
+				if tc.valid && err != nil {
+					t.Errorf("got error %v, want error nil", err)
+				}
+				if !tc.valid && err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				if got != tc.want {
+					t.Errorf("got %s, want %s", got, tc.want)
+				}
+			})
+		}
+	})
+
+	t.Run("new rejects nan", func(t *testing.T) {
+		if _, err := New('X', math.NaN()); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestAddressableGcodeFreeze(t *testing.T) {
+
+	gc, err := New[int32]('G', 1)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	gc.Freeze()
+
+	if !gc.Frozen() {
+		t.Errorf("got frozen false, want true")
+	}
+
+	if err := gc.SetAddress(0); err == nil {
+		t.Errorf("got SetAddress error nil, want error not nil")
+	}
+
+	if err := gc.SetLiteral("01"); err == nil {
+		t.Errorf("got SetLiteral error nil, want error not nil")
+	}
+
+	if err := gc.UnmarshalText([]byte("G0")); err == nil {
+		t.Errorf("got UnmarshalText error nil, want error not nil")
+	}
+
+	if gc.String() != "G1" {
+		t.Errorf("got %s, want G1", gc)
+	}
+}
+
+func setAddress[T gcode.AddressType](word byte, address T, value T) func() (string, error) {
+	return func() (string, error) {
+		gc, err := New(word, address)
+		if err != nil {
+			return "", err
+		}
+
+		err = gc.SetAddress(value)
+		return gc.String(), err
+	}
 }
 
 func TestAddressableGcodeWord(t *testing.T) {
@@ -301,3 +381,290 @@ func TestAddressableGcodeWord(t *testing.T) {
 }
 
 //#endregion
+
+func TestNewGcodeAddressableWithRegistry(t *testing.T) {
+
+	registry, err := gcode.NewWordRegistry('g', 'x', 'N')
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	cases := map[string]struct {
+		word     byte
+		registry *gcode.WordRegistry
+		valid    bool
+	}{
+		"lowercase allowed":  {'x', registry, true},
+		"uppercase allowed":  {'N', registry, true},
+		"default denied":     {'X', registry, false},
+		"nil registry":       {'X', nil, true},
+		"nil registry lower": {'x', nil, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := NewWithRegistry[float32](tc.word, 1.5, tc.registry)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if gc.String() != string(tc.word)+"1.5" {
+				t.Errorf("got gcode %s, want %s1.5", gc, string(tc.word))
+			}
+		})
+	}
+}
+
+func TestNewGcodeAddressable_Float64(t *testing.T) {
+
+	cases := map[string]struct {
+		word    byte
+		address float64
+		valid   bool
+		want    string
+	}{
+		"integer":   {'X', 2, true, "X2.0"},
+		"negative":  {'Y', -0.5, true, "Y-0.5"},
+		"precision": {'X', 123456.789012, true, "X123456.789012"},
+		"micron":    {'Z', 0.001, true, "Z0.001"},
+		"invalid":   {'+', 1, false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := New(tc.word, tc.address)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if gc.String() != tc.want {
+				t.Errorf("got gcode %s, want gcode %s", gc, tc.want)
+			}
+
+			if gc.Address() != tc.address {
+				t.Errorf("got address %v, want address %v", gc.Address(), tc.address)
+			}
+		})
+	}
+}
+
+func TestAddressableGcodeFormat(t *testing.T) {
+
+	cases := map[string]struct {
+		gcode  func() (gcode.FormattableGcoder, error)
+		format gcode.FloatFormat
+		want   string
+	}{
+		"float32 default":  {newFormattable[float32]('X', 2), gcode.DefaultFloatFormat(), "X2.0"},
+		"float32 shortest": {newFormattable[float32]('X', 2), gcode.FloatFormat{Precision: -1}, "X2"},
+		"float32 fixed":    {newFormattable[float32]('X', 2), gcode.FloatFormat{Precision: 3}, "X2.000"},
+		"float32 rounded":  {newFormattable[float32]('X', 1.23456), gcode.FloatFormat{Precision: 2}, "X1.23"},
+		"float32 trimmed":  {newFormattable[float32]('X', 1.5), gcode.FloatFormat{Precision: 3, TrimZeros: true}, "X1.5"},
+		"float64 trimmed":  {newFormattable[float64]('Y', 10), gcode.FloatFormat{Precision: 4, TrimZeros: true}, "Y10"},
+		"float64 point":    {newFormattable[float64]('Y', 10), gcode.FloatFormat{Precision: 4, TrimZeros: true, DecimalPoint: true}, "Y10.0"},
+		"int32 ignored":    {newFormattable[int32]('G', 1), gcode.FloatFormat{Precision: 3}, "G1"},
+		"string ignored":   {newFormattable[string]('M', "\"a.gcode\""), gcode.FloatFormat{Precision: 3}, "M\"a.gcode\""},
+		"negative rounded": {newFormattable[float64]('Z', -0.0004), gcode.FloatFormat{Precision: 3, TrimZeros: true}, "Z-0"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := tc.gcode()
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := gc.Format(tc.format); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+
+			ag, ok := gc.(gcode.AppendableGcoder)
+			if !ok {
+				t.Errorf("got %T, want gcode.AppendableGcoder", gc)
+				return
+			}
+
+			if got := string(ag.AppendFormat([]byte("N1 "), tc.format)); got != "N1 "+tc.want {
+				t.Errorf("got %s, want N1 %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func newFormattable[T gcode.AddressType](word byte, address T) func() (gcode.FormattableGcoder, error) {
+	return func() (gcode.FormattableGcoder, error) {
+		return New(word, address)
+	}
+}
+
+func TestAddressableGcodeLiteral(t *testing.T) {
+
+	t.Run("set literal", func(t *testing.T) {
+		cases := map[string]struct {
+			set   func() (string, error)
+			valid bool
+		}{
+			"float32 padded":    {setLiteral[float32]('X', 10.5, "0010.50"), true},
+			"float64 padded":    {setLiteral[float64]('X', 10.5, "10.500"), true},
+			"int32 padded":      {setLiteral[int32]('G', 1, "01"), true},
+			"uint32 padded":     {setLiteral[uint32]('N', 7, "007"), true},
+			"empty":             {setLiteral[float32]('X', 10.5, ""), true},
+			"other value":       {setLiteral[float32]('X', 10.5, "10.6"), false},
+			"invalid data type": {setLiteral[int32]('G', 1, "1.0"), false},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				_, err := tc.set()
+				if tc.valid && err != nil {
+					t.Errorf("got error %v, want error nil", err)
+				}
+				if !tc.valid && err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+			})
+		}
+	})
+
+	t.Run("unmarshal keeps literal", func(t *testing.T) {
+		var gc Gcode[float32]
+		if err := gc.UnmarshalText([]byte("X0010.50")); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if gc.Literal() != "0010.50" || gc.String() != "X10.5" {
+			t.Errorf("got literal %s and gcode %s, want literal 0010.50 and gcode X10.5", gc.Literal(), gc.String())
+		}
+	})
+
+	t.Run("set address removes literal", func(t *testing.T) {
+		var gc Gcode[float32]
+		if err := gc.UnmarshalText([]byte("X0010.50")); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := gc.SetAddress(11); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if gc.Literal() != "" {
+			t.Errorf("got literal %s, want empty", gc.Literal())
+		}
+	})
+}
+
+func setLiteral[T gcode.AddressType](word byte, address T, literal string) func() (string, error) {
+	return func() (string, error) {
+		gc, err := New(word, address)
+		if err != nil {
+			return "", err
+		}
+
+		err = gc.SetLiteral(literal)
+		return gc.Literal(), err
+	}
+}
+
+func TestGcode_TextMarshaling(t *testing.T) {
+
+	t.Run("marshal", func(t *testing.T) {
+		gc, err := New[float32]('X', 2)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		text, err := gc.MarshalText()
+		if err != nil || string(text) != "X2.0" {
+			t.Errorf("got (%v)%s, want (nil)X2.0", err, text)
+		}
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		cases := map[string]struct {
+			text      string
+			valid     bool
+			unmarshal func(text []byte) (string, error)
+		}{
+			"uint32":          {"N12", true, unmarshalAs[uint32]},
+			"uint32 negative": {"N-12", false, unmarshalAs[uint32]},
+			"int32":           {"G-1", true, unmarshalAs[int32]},
+			"int32 float":     {"G1.5", false, unmarshalAs[int32]},
+			"float32":         {"X12.5", true, unmarshalAs[float32]},
+			"float32 invalid": {"Xa", false, unmarshalAs[float32]},
+			"float64":         {"X123456.789012", true, unmarshalAs[float64]},
+			"float64 invalid": {"X1.2.3", false, unmarshalAs[float64]},
+			"string":          {"M\"file.gcode\"", true, unmarshalAs[string]},
+			"string unquoted": {"Mfile", false, unmarshalAs[string]},
+			"invalid word":    {"K12.5", false, unmarshalAs[float32]},
+			"without address": {"X", false, unmarshalAs[float32]},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				got, err := tc.unmarshal([]byte(tc.text))
+				if !tc.valid {
+					if err == nil {
+						t.Errorf("got error nil, want error not nil")
+					}
+					return
+				}
+
+				if err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+
+				if got != tc.text {
+					t.Errorf("got %s, want %s", got, tc.text)
+				}
+			})
+		}
+	})
+
+	t.Run("unmarshal error keeps value", func(t *testing.T) {
+		gc, err := New[int32]('G', 1)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if err := gc.UnmarshalText([]byte("K2")); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+
+		if gc.String() != "G1" {
+			t.Errorf("got %s, want G1", gc)
+		}
+	})
+}
+
+func unmarshalAs[T gcode.AddressType](text []byte) (string, error) {
+	var gc Gcode[T]
+	if err := gc.UnmarshalText(text); err != nil {
+		return "", err
+	}
+
+	return gc.String(), nil
+}
@@ -0,0 +1,48 @@
+package addressablegcode
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+//#region unit conversion
+
+// Scale returns a new gcode with the same word and the numeric address multiplied by a factor.
+//
+// The float64 addresses are kept as float64, the rest of numeric addresses are converted to float32 because the result can be fractional.
+// It returns an error if the gcode hasn't a numeric address. The input gcode isn't modified.
+func Scale(g gcode.Gcoder, factor float64) (gcode.Gcoder, error) {
+	value, ok := gcode.NumericAddress(g)
+	if !ok {
+		return nil, fmt.Errorf("failed to scale gcode %s, it hasn't a numeric address", g)
+	}
+
+	if _, ok := g.(gcode.AddressableGcoder[float64]); ok {
+		return New(g.Word(), value*factor)
+	}
+
+	return New(g.Word(), float32(value*factor))
+}
+
+// MillimetersToInches returns a new gcode with the length address converted from millimeters to inches.
+func MillimetersToInches(g gcode.Gcoder) (gcode.Gcoder, error) {
+	return Scale(g, 1/gcode.MILLIMETERS_PER_INCH)
+}
+
+// InchesToMillimeters returns a new gcode with the length address converted from inches to millimeters.
+func InchesToMillimeters(g gcode.Gcoder) (gcode.Gcoder, error) {
+	return Scale(g, gcode.MILLIMETERS_PER_INCH)
+}
+
+// PerMinuteToPerSecond returns a new gcode with the feedrate address converted from mm/min to mm/s.
+func PerMinuteToPerSecond(g gcode.Gcoder) (gcode.Gcoder, error) {
+	return Scale(g, 1.0/gcode.SECONDS_PER_MINUTE)
+}
+
+// PerSecondToPerMinute returns a new gcode with the feedrate address converted from mm/s to mm/min.
+func PerSecondToPerMinute(g gcode.Gcoder) (gcode.Gcoder, error) {
+	return Scale(g, gcode.SECONDS_PER_MINUTE)
+}
+
+//#endregion
@@ -0,0 +1,70 @@
+package addressablegcode
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/gcode/unaddressablegcode"
+)
+
+func TestUnitConversion(t *testing.T) {
+
+	x, _ := New[float32]('X', 25.4)
+	y, _ := New[int32]('Y', 254)
+	z, _ := New[float64]('Z', 1)
+	f, _ := New[uint32]('F', 1500)
+	s, _ := New('M', "\"file.gcode\"")
+	u, _ := unaddressablegcode.New('X')
+
+	cases := map[string]struct {
+		gcode   gcode.Gcoder
+		convert func(gcode.Gcoder) (gcode.Gcoder, error)
+		valid   bool
+		want    string
+	}{
+		"float32 to inches":      {x, MillimetersToInches, true, "X1.0"},
+		"int32 to inches":        {y, MillimetersToInches, true, "Y10.0"},
+		"float64 to millimeters": {z, InchesToMillimeters, true, "Z25.4"},
+		"feedrate to seconds":    {f, PerMinuteToPerSecond, true, "F25.0"},
+		"feedrate to minutes":    {x, PerSecondToPerMinute, true, "X1524.0"},
+		"string address":         {s, MillimetersToInches, false, ""},
+		"without address":        {u, MillimetersToInches, false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.convert(tc.gcode)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("float64 kept", func(t *testing.T) {
+		got, err := Scale(z, 0.5)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, ok := got.(gcode.AddressableGcoder[float64]); !ok {
+			t.Errorf("got %T, want float64 addressable gcode", got)
+		}
+
+		if z.Address() != 1 {
+			t.Errorf("got input modified %s, want Z1.0", z)
+		}
+	})
+}
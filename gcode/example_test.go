@@ -35,3 +35,58 @@ func ExampleIsValidWord_second() {
 
 	// Output: word ; is invalid: gcode's word has invalid value: 59
 }
+
+func ExampleFloatFormat_FormatFloat() {
+
+	formats := []gcode.FloatFormat{
+		gcode.DefaultFloatFormat(),
+		{Precision: -1},
+		{Precision: 3},
+		{Precision: 3, TrimZeros: true, DecimalPoint: true},
+	}
+
+	for _, format := range formats {
+		fmt.Printf("%s %s\n", format.FormatFloat(2, 32), format.FormatFloat(0.125, 32))
+	}
+
+	// Output:
+	// 2.0 0.125
+	// 2 0.125
+	// 2.000 0.125
+	// 2.0 0.125
+}
+
+func ExampleWordRegistry() {
+
+	// accept the lowercase words used by some firmwares
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('g', 'x'); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// restrict the words to the ones supported by the machine
+	registry.Deny('U', 'V', 'W')
+
+	fmt.Println(registry.IsValidWord('x'))
+	fmt.Println(registry.IsValidWord('U'))
+	fmt.Println(registry.Allow(';'))
+
+	// Output:
+	// <nil>
+	// gcode's word has invalid value: 85
+	// failed to allow word ';', it must be an ASCII letter or '*'
+}
+
+func ExampleNewWordRegistry() {
+
+	registry, err := gcode.NewWordRegistry('G', 'X', 'Y', 'N', '*')
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(string(registry.Words()))
+
+	// Output: *GNXY
+}
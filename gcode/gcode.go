@@ -14,7 +14,7 @@
 //
 // The address model allows store and management the representation of the part assigned to the value of a gcode.
 //
-// A gcode can have or doesn't have an address. When it has, the address must be of either int32, uin32, float32 or string data type.
+// A gcode can have or doesn't have an address. When it has, the address must be of either int32, uin32, float32, float64 or string data type.
 // This package contains a constructor that returns an address of some of these data types defined by the AddressType interface.
 //
 // gcode package define a series of interfaces to implement mainly two kind gcode structs.
@@ -24,7 +24,7 @@
 //
 // AddressableGcoder interfaces, join to AddressType interface, allows constructing a typical gcode object but, also,
 // includes an address struct of the data type defined by the AddressType interface in the address package.
-// This we allow to handle a gcode with a string address, address int32, address uint32 or address float32.
+// This we allow to handle a gcode with a string address, address int32, address uint32, address float32 or address float64.
 //
 // AddressableGcoder interface wrap Gcoder interface.
 // This means, that all the AddressableGcoder objects are as well Gcoder objects.
@@ -44,7 +44,14 @@
 // gcode package provides two packages that implement all interfaces ready to use.
 package gcode
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// MAX_FLOAT_PRECISION is the maximum number of decimals supported by FloatFormat.
+const MAX_FLOAT_PRECISION = 16
 
 //#region interfaces
 
@@ -67,7 +74,7 @@ type Gcoder interface {
 // The address can be numeric or some cases strings.
 // The numeric values supported changes depending on the word value.
 type AddressType interface {
-	string | int32 | float32 | uint32
+	string | int32 | float32 | uint32 | float64
 }
 
 // AddressableGcoder compose the Gcoder interface and add methods to handle the address value.
@@ -78,11 +85,43 @@ type AddressableGcoder[T AddressType] interface {
 	// Address return address value of the data type T.
 	Address() T
 
-	// SetAddress stores address value of the data type T.
-	// Return an error if the format the value is not valid.
+	// SetAddress stores address value of the data type T, it allows modify the gcode in place.
+	// Return an error if the format the value is not valid, like a NaN float.
 	SetAddress(value T) error
 }
 
+// FormattableGcoder is implemented by the gcodes that can export their fractional address with a custom float format.
+type FormattableGcoder interface {
+	// Gcoder (via the embedded gcode.Gcoder interface) allow converts FormattableGcoder in a Gcoder element.
+	Gcoder
+
+	// Format returns the gcode formatted using the float format required.
+	// The gcodes without a fractional address ignore the format and return the same value as String.
+	Format(format FloatFormat) string
+}
+
+// AppendableGcoder is implemented by the gcodes that can be exported without allocate a new string.
+type AppendableGcoder interface {
+	// Gcoder (via the embedded gcode.Gcoder interface) allow converts AppendableGcoder in a Gcoder element.
+	Gcoder
+
+	// AppendFormat appends the gcode formatted with the float format to dst and returns the extended buffer.
+	AppendFormat(dst []byte, format FloatFormat) []byte
+}
+
+// LiteralGcoder is implemented by the gcodes that can remember the original text of their address, like "0010.50" in X0010.50.
+type LiteralGcoder interface {
+	// Gcoder (via the embedded gcode.Gcoder interface) allow converts LiteralGcoder in a Gcoder element.
+	Gcoder
+
+	// Literal returns the original text of the address, or an empty string if it isn't known.
+	Literal() string
+
+	// SetLiteral stores the original text of the address.
+	// Return an error if the text doesn't represent the current address value.
+	SetLiteral(literal string) error
+}
+
 // GcoderFactory defines constructors to instance each kind of gcode object.
 type GcoderFactory interface {
 	// Create a Gcoder instance that not use address element.
@@ -92,10 +131,99 @@ type GcoderFactory interface {
 	NewAddressableGcodeUint32(word byte, address uint32) (AddressableGcoder[uint32], error)
 	NewAddressableGcodeInt32(word byte, address int32) (AddressableGcoder[int32], error)
 	NewAddressableGcodeFloat32(word byte, address float32) (AddressableGcoder[float32], error)
+	NewAddressableGcodeFloat64(word byte, address float64) (AddressableGcoder[float64], error)
 	NewAddressableGcodeString(word byte, address string) (AddressableGcoder[string], error)
 
 	// Create a Gcoder instance from a string input.
 	Parse(source string) (Gcoder, error)
+
+	// Register a plugin to parse a custom address syntax, it replaces the plugin registered with the same name.
+	RegisterAddressPlugin(plugin AddressPlugin) error
+}
+
+// AddressPlugin is implemented by the extensions that parse a custom address syntax, like expressions, durations or enumerated values.
+//
+// The factory delegates to the plugins the addresses that they match, before apply the built-in rules.
+type AddressPlugin interface {
+	// Name identifies the plugin in the factory.
+	Name() string
+
+	// Match returns true if the plugin recognizes the address, the text after the word, like "[#1+2]" in X[#1+2].
+	Match(word byte, address string) bool
+
+	// Parse returns a new gcode with the word and the address recognized. The word is already validated by the factory.
+	Parse(word byte, address string) (Gcoder, error)
+}
+
+//#endregion
+//#region float format
+
+// FloatFormat defines how the fractional addresses are exported, like X2.0, X2 or X2.000.
+//
+// The zero value isn't the default format, use DefaultFloatFormat to get it.
+type FloatFormat struct {
+	// Precision is the number of decimals exported.
+	// A negative value exports the minimum number of decimals needed to represent the value exactly.
+	Precision int
+
+	// TrimZeros removes the trailing zeros of the decimals, only is useful with a positive precision.
+	TrimZeros bool
+
+	// DecimalPoint appends ".0" to the integral values, so they are always recognized as fractional.
+	DecimalPoint bool
+}
+
+// DefaultFloatFormat returns the format used by String, the minimum number of decimals and always a decimal point.
+func DefaultFloatFormat() FloatFormat {
+	return FloatFormat{
+		Precision:    -1,
+		DecimalPoint: true,
+	}
+}
+
+// Validate returns an error if the precision is greater than the maximum supported.
+func (f FloatFormat) Validate() error {
+	if f.Precision > MAX_FLOAT_PRECISION {
+		return fmt.Errorf("float precision %d exceeds the maximum %d", f.Precision, MAX_FLOAT_PRECISION)
+	}
+
+	return nil
+}
+
+// FormatFloat returns the value formatted.
+//
+// bitSize is 32 for float32 values or 64 for float64 values, it is used to find the minimum number of decimals.
+func (f FloatFormat) FormatFloat(value float64, bitSize int) string {
+	return string(f.AppendFloat(nil, value, bitSize))
+}
+
+// AppendFloat appends the value formatted to dst and returns the extended buffer.
+//
+// It is like FormatFloat, but it doesn't allocate memory if dst has enough capacity.
+func (f FloatFormat) AppendFloat(dst []byte, value float64, bitSize int) []byte {
+	precision := f.Precision
+	if precision < 0 {
+		precision = -1
+	}
+
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, value, 'f', precision, bitSize)
+
+	hasPoint := bytes.IndexByte(dst[start:], '.') >= 0
+
+	if f.TrimZeros && hasPoint {
+		dst = bytes.TrimRight(dst, "0")
+		if dst[len(dst)-1] == '.' {
+			dst = dst[:len(dst)-1]
+			hasPoint = false
+		}
+	}
+
+	if f.DecimalPoint && !hasPoint {
+		dst = append(dst, '.', '0')
+	}
+
+	return dst
 }
 
 //#endregion
@@ -104,16 +232,109 @@ type GcoderFactory interface {
 // IsValid allow knowledge if a potential word value contains a value valid according to a specification gcode.
 //
 // The set valid values are hard coding and they correspond to a [ReRap documentation].
+// To accept other words use a WordRegistry.
 //
 // [ReRap documentation]: https://reprap.org/wiki/G-code
 func IsValidWord(word byte) error {
+	return defaultWordRegistry.IsValidWord(word)
+}
+
+//#endregion
+//#region word registry
+
+// DEFAULT_WORDS are the words accepted by IsValidWord, they correspond to the RepRap documentation.
+// The programs of the CNC controllers, with words like K of the arcs or L of G10, must be parsed with a WordRegistry
+// that accepts them, like the one of a dialect profile.
+const DEFAULT_WORDS = "GMTSPXYZUVWIJDHFRQEN*"
+
+// defaultWordRegistry is used by IsValidWord, it is never modified.
+var defaultWordRegistry = DefaultWordRegistry()
+
+// WordRegistry stores the set of words accepted by the gcode constructors.
+//
+// It allows accepting vendor-specific or lowercase words, or restricting the words to the ones supported by a machine.
+// The words can be ASCII letters or the '*' checksum symbol, any other character is rejected because it would break the parsing.
+//
+// The zero value doesn't accept any word.
+type WordRegistry struct {
+	words [256]bool
+}
+
+// NewWordRegistry returns a registry that accepts only the words required.
+//
+// Remember to include N and '*' if the line numbers and checksums are used.
+func NewWordRegistry(words ...byte) (*WordRegistry, error) {
+	r := &WordRegistry{}
+
+	if err := r.Allow(words...); err != nil {
+		return nil, fmt.Errorf("failed to create word registry: %w", err)
+	}
+
+	return r, nil
+}
+
+// DefaultWordRegistry returns a new registry that accepts the DEFAULT_WORDS.
+//
+// Each call returns a new instance, so it can be modified without affect the rest.
+func DefaultWordRegistry() *WordRegistry {
+	r := &WordRegistry{}
 
-	switch word {
-	case 'G', 'M', 'T', 'S', 'P', 'X', 'Y', 'Z', 'U', 'V', 'W', 'I', 'J', 'D', 'H', 'F', 'R', 'Q', 'E', 'N', '*':
-		return nil
+	for _, word := range []byte(DEFAULT_WORDS) {
+		r.words[word] = true
 	}
 
-	return fmt.Errorf("gcode's word has invalid value: %v", word)
+	return r
+}
+
+// Allow adds words to the registry.
+//
+// Return an error if some word isn't an ASCII letter or '*'. In that case, the registry isn't modified.
+func (r *WordRegistry) Allow(words ...byte) error {
+	for _, word := range words {
+		if !isWordAllowable(word) {
+			return fmt.Errorf("failed to allow word %q, it must be an ASCII letter or '*'", word)
+		}
+	}
+
+	for _, word := range words {
+		r.words[word] = true
+	}
+
+	return nil
+}
+
+// Deny removes words from the registry. The words that aren't in the registry are ignored.
+func (r *WordRegistry) Deny(words ...byte) {
+	for _, word := range words {
+		r.words[word] = false
+	}
+}
+
+// Words returns the words accepted by the registry in ascending order.
+func (r *WordRegistry) Words() []byte {
+	var words []byte
+
+	for word, ok := range r.words {
+		if ok {
+			words = append(words, byte(word))
+		}
+	}
+
+	return words
+}
+
+// IsValidWord returns an error if the word isn't accepted by the registry.
+func (r *WordRegistry) IsValidWord(word byte) error {
+	if !r.words[word] {
+		return fmt.Errorf("gcode's word has invalid value: %v", word)
+	}
+
+	return nil
+}
+
+// isWordAllowable returns true if the word can be stored in a registry.
+func isWordAllowable(word byte) bool {
+	return (word >= 'A' && word <= 'Z') || (word >= 'a' && word <= 'z') || word == '*'
 }
 
 //#endregion
@@ -51,11 +51,39 @@ func (g *Gcode) String() string {
 	return string(g.word)
 }
 
+// AppendFormat appends the word to dst and returns the extended buffer. The format is ignored because the gcode hasn't address.
+func (g *Gcode) AppendFormat(dst []byte, format gcode.FloatFormat) []byte {
+	return append(dst, g.word)
+}
+
 // Word return a copy of the word struct in the gcode
 func (g *Gcode) Word() byte {
 	return g.word
 }
 
+// MarshalText implements encoding.TextMarshaler interface, it returns the gcode formatted like String.
+func (g *Gcode) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface, it loads the gcode from a single word like "G".
+//
+// Returns an error if the text isn't a single valid word. In that case, the gcode isn't modified.
+func (g *Gcode) UnmarshalText(text []byte) error {
+	if len(text) != 1 {
+		return fmt.Errorf("failed to unmarshal gcode %q: it must contain a single word", text)
+	}
+
+	ng, err := New(text[0])
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal gcode %q: %w", text, err)
+	}
+
+	*g = *ng
+
+	return nil
+}
+
 //#endregion
 //#region constructor
 
@@ -64,8 +92,25 @@ func (g *Gcode) Word() byte {
 // Receive a word that represents the letter of the command.
 //
 // Return nil with an error description of something is bad.
+//
+// The word is validated with gcode.IsValidWord.
 func New(word byte) (*Gcode, error) {
-	err := gcode.IsValidWord(word)
+	return NewWithRegistry(word, nil)
+}
+
+// NewWithRegistry is the constructor to instance a Gcode struct that does not include an address.
+//
+// Receive a word that represents the letter of the command, it must be accepted by the registry.
+// If the registry is nil then the word is validated with gcode.IsValidWord.
+//
+// Return nil with an error description of something is bad.
+func NewWithRegistry(word byte, registry *gcode.WordRegistry) (*Gcode, error) {
+	var err error
+	if registry == nil {
+		err = gcode.IsValidWord(word)
+	} else {
+		err = registry.IsValidWord(word)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an gcode instance when trying to use %v word: %w", word, err)
 	}
@@ -92,6 +92,48 @@ func TestNewGcode(t *testing.T) {
 	}
 }
 
+func TestNewGcodeWithRegistry(t *testing.T) {
+
+	registry, err := gcode.NewWordRegistry('g', 'X')
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	cases := map[string]struct {
+		word     byte
+		registry *gcode.WordRegistry
+		valid    bool
+	}{
+		"lowercase allowed":  {'g', registry, true},
+		"uppercase allowed":  {'X', registry, true},
+		"default denied":     {'G', registry, false},
+		"nil registry":       {'G', nil, true},
+		"nil registry lower": {'g', nil, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gc, err := NewWithRegistry(tc.word, tc.registry)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if gc.Word() != tc.word {
+				t.Errorf("got word %s, want %s", string(gc.Word()), string(tc.word))
+			}
+		})
+	}
+}
+
 func TestGcodeCompare(t *testing.T) {
 
 	gcodeA, err := New('M')
@@ -174,6 +216,30 @@ func TestGcodeString(t *testing.T) {
 	}
 }
 
+func TestGcodeAppendFormat(t *testing.T) {
+
+	gc, err := New('X')
+	if err != nil {
+		t.Errorf("got %v, want nil error", err)
+	}
+
+	cases := map[string]struct {
+		dst  []byte
+		want string
+	}{
+		"nil":    {nil, "X"},
+		"prefix": {[]byte("G28 "), "G28 X"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := string(gc.AppendFormat(tc.dst, gcode.DefaultFloatFormat())); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestGcodeWord(t *testing.T) {
 
 	gc, err := New('M')
@@ -196,3 +262,38 @@ func TestGcodeWord(t *testing.T) {
 		})
 	}
 }
+
+func TestGcode_TextMarshaling(t *testing.T) {
+	cases := map[string]struct {
+		text  string
+		valid bool
+	}{
+		"valid":        {"G", true},
+		"invalid word": {"K", false},
+		"with address": {"G1", false},
+		"empty":        {"", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gc Gcode
+			err := gc.UnmarshalText([]byte(tc.text))
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			text, err := gc.MarshalText()
+			if err != nil || string(text) != tc.text {
+				t.Errorf("got (%v)%s, want (nil)%s", err, text, tc.text)
+			}
+		})
+	}
+}
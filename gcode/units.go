@@ -0,0 +1,31 @@
+package gcode
+
+//#region units
+
+const (
+	// MILLIMETERS_PER_INCH is the factor to convert a length from inches to millimeters.
+	MILLIMETERS_PER_INCH = 25.4
+
+	// SECONDS_PER_MINUTE is the factor to convert a feedrate from mm/s to mm/min.
+	SECONDS_PER_MINUTE = 60
+)
+
+// NumericAddress returns the address of a gcode as float64, if the gcode has a numeric address.
+//
+// It returns false if the gcode hasn't address or if it is a string.
+func NumericAddress(g Gcoder) (float64, bool) {
+	switch v := g.(type) {
+	case AddressableGcoder[float32]:
+		return float64(v.Address()), true
+	case AddressableGcoder[float64]:
+		return v.Address(), true
+	case AddressableGcoder[int32]:
+		return float64(v.Address()), true
+	case AddressableGcoder[uint32]:
+		return float64(v.Address()), true
+	}
+
+	return 0, false
+}
+
+//#endregion
@@ -0,0 +1,174 @@
+package grbl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+// AXES are the axes that a jog can move, in the order that they are written.
+const AXES = "XYZABC"
+
+// JOG_PREFIX is the prefix of the jogs.
+const JOG_PREFIX = "$J="
+
+//#region jog
+
+// Jog is a jog of GRBL, a motion that doesn't change the modal state of the parser, like "$J=G91 G21 X10 F500".
+type Jog struct {
+	// Relative is true if the axes are distances from the current position, G91, instead of positions, G90.
+	Relative bool
+
+	// Inches is true if the axes and the feedrate are in inches, G20, instead of millimeters, G21.
+	Inches bool
+
+	// Machine is true if the positions are in the coordinates of the machine, G53.
+	Machine bool
+
+	// Axes are the positions or distances of the axes moved, indexed by their letter, like 'X'.
+	Axes map[byte]float64
+
+	// Feedrate is the feedrate of the jog, in units per minute.
+	Feedrate float64
+}
+
+// String returns the line of the jog, like "$J=G91 G21 X10 F500". It always writes the distance mode and the units,
+// so the jog doesn't depend on the modal state of the parser.
+func (j Jog) String() string {
+	parts := make([]string, 0, len(j.Axes)+4)
+
+	if j.Machine {
+		parts = append(parts, "G53")
+	}
+
+	if j.Relative {
+		parts = append(parts, "G91")
+	} else {
+		parts = append(parts, "G90")
+	}
+
+	if j.Inches {
+		parts = append(parts, "G20")
+	} else {
+		parts = append(parts, "G21")
+	}
+
+	for i := 0; i < len(AXES); i++ {
+		if value, ok := j.Axes[AXES[i]]; ok {
+			parts = append(parts, string(AXES[i])+strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+
+	parts = append(parts, "F"+strconv.FormatFloat(j.Feedrate, 'f', -1, 64))
+
+	return JOG_PREFIX + strings.Join(parts, " ")
+}
+
+// Validate verifies that the jog can be executed.
+//
+// It returns an error if it doesn't move any axis, some axis is unknown, G53 is relative or the feedrate isn't positive.
+func (j Jog) Validate() error {
+	if len(j.Axes) == 0 {
+		return fmt.Errorf("invalid jog, it must move some axis")
+	}
+
+	for axis := range j.Axes {
+		if !isAxis(axis) {
+			return fmt.Errorf("invalid jog, unknown axis %q", axis)
+		}
+	}
+
+	if j.Machine && j.Relative {
+		return fmt.Errorf("invalid jog, the coordinates of the machine can't be relative")
+	}
+
+	if j.Feedrate <= 0 {
+		return fmt.Errorf("invalid jog, the feedrate must be positive: %v", j.Feedrate)
+	}
+
+	return nil
+}
+
+// ParseJog parses a jog, like "$J=G91 G21 X10 F500" or "$J=G91X10F500". The words are case-insensitive and the spaces
+// are optional, like GRBL. The distance mode and the units are absolute and millimeters if they aren't written.
+//
+// It returns an error if the line isn't a jog, it has words that a jog doesn't accept, or the jog isn't valid.
+func ParseJog(line string) (Jog, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(strings.ToUpper(line), JOG_PREFIX) {
+		return Jog{}, fmt.Errorf("failed to parse jog, it must start with %s: %q", JOG_PREFIX, line)
+	}
+
+	j := Jog{Axes: map[byte]float64{}}
+	feedrate := false
+
+	text := strings.ToUpper(strings.Join(strings.Fields(line[len(JOG_PREFIX):]), ""))
+
+	for i := 0; i < len(text); {
+		letter := text[i]
+		i++
+
+		start := i
+		for i < len(text) && (numeric.IsDigit(text[i]) || text[i] == '.' || text[i] == '-' || text[i] == '+') {
+			i++
+		}
+
+		value, err := strconv.ParseFloat(text[start:i], 64)
+		if err != nil {
+			return Jog{}, fmt.Errorf("failed to parse jog, invalid value of %c: %q", letter, line)
+		}
+
+		switch {
+		case letter == 'G':
+			switch text[start:i] {
+			case "90":
+				j.Relative = false
+			case "91":
+				j.Relative = true
+			case "20":
+				j.Inches = true
+			case "21":
+				j.Inches = false
+			case "53":
+				j.Machine = true
+			default:
+				return Jog{}, fmt.Errorf("failed to parse jog, it doesn't accept G%s: %q", text[start:i], line)
+			}
+		case letter == 'F':
+			j.Feedrate = value
+			feedrate = true
+		case isAxis(letter):
+			if _, ok := j.Axes[letter]; ok {
+				return Jog{}, fmt.Errorf("failed to parse jog, repeated axis %c: %q", letter, line)
+			}
+			j.Axes[letter] = value
+		default:
+			return Jog{}, fmt.Errorf("failed to parse jog, it doesn't accept the word %c: %q", letter, line)
+		}
+	}
+
+	if !feedrate {
+		return Jog{}, fmt.Errorf("failed to parse jog, it requires a feedrate: %q", line)
+	}
+
+	if err := j.Validate(); err != nil {
+		return Jog{}, fmt.Errorf("failed to parse jog: %w", err)
+	}
+
+	return j, nil
+}
+
+// Command returns the system command of the jog.
+//
+// It returns an error if the jog isn't valid.
+func (j Jog) Command() (SystemCommand, error) {
+	if err := j.Validate(); err != nil {
+		return SystemCommand{}, fmt.Errorf("failed to create jog: %w", err)
+	}
+
+	return SystemCommand{Kind: SystemJog, Value: strings.TrimPrefix(j.String(), JOG_PREFIX)}, nil
+}
+
+//#endregion
@@ -0,0 +1,84 @@
+package grbl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJog(t *testing.T) {
+	cases := map[string]struct {
+		line   string
+		want   Jog
+		output string
+	}{
+		"relative": {
+			"$J=G91 G21 X10 F500",
+			Jog{Relative: true, Axes: map[byte]float64{'X': 10}, Feedrate: 500},
+			"$J=G91 G21 X10 F500",
+		},
+		"without spaces": {
+			"$j=g91g20y-0.5z1f20",
+			Jog{Relative: true, Inches: true, Axes: map[byte]float64{'Y': -0.5, 'Z': 1}, Feedrate: 20},
+			"$J=G91 G20 Y-0.5 Z1 F20",
+		},
+		"machine": {
+			"$J=G53 Z-5 X0 F1000",
+			Jog{Machine: true, Axes: map[byte]float64{'X': 0, 'Z': -5}, Feedrate: 1000},
+			"$J=G53 G90 G21 X0 Z-5 F1000",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseJog(tc.line)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+
+			if got.String() != tc.output {
+				t.Errorf("got %q, want %q", got.String(), tc.output)
+			}
+		})
+	}
+}
+
+func TestParseJog_errors(t *testing.T) {
+	cases := map[string]string{
+		"not jog":          "G91 X10 F500",
+		"without feedrate": "$J=X10",
+		"zero feedrate":    "$J=X10 F0",
+		"without axes":     "$J=G91 F100",
+		"modal":            "$J=G0 X10 F100",
+		"word":             "$J=X10 S100 F100",
+		"repeated axis":    "$J=X10 X20 F100",
+		"value":            "$J=X F100",
+		"relative machine": "$J=G53 G91 X10 F100",
+	}
+
+	for name, line := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got, err := ParseJog(line); err == nil {
+				t.Errorf("got %#v, want error not nil", got)
+			}
+		})
+	}
+}
+
+func TestJog_Command(t *testing.T) {
+	c, err := Jog{Relative: true, Axes: map[byte]float64{'X': 1.5}, Feedrate: 300}.Command()
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if want := (SystemCommand{Kind: SystemJog, Value: "G91 G21 X1.5 F300"}); c != want {
+		t.Errorf("got %#v, want %#v", c, want)
+	}
+
+	if _, err := (Jog{Axes: map[byte]float64{'W': 1}, Feedrate: 300}).Command(); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
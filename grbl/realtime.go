@@ -0,0 +1,141 @@
+// grbl package recognizes and generates the commands of GRBL that aren't gcode: the system commands that start with "$",
+// like "$H" or "$110=5000", the jogs, like "$J=G91 X10 F500", and the realtime commands of a single byte, like "?" or 0x18,
+// that GRBL executes as soon as it receives them, even inside the lines streamed.
+package grbl
+
+import (
+	"fmt"
+)
+
+//#region realtime
+
+// Realtime is a realtime command of GRBL, a single byte that GRBL executes as soon as it receives it.
+type Realtime byte
+
+const (
+	// REALTIME_RESET is the soft reset, ctrl-x.
+	REALTIME_RESET Realtime = 0x18
+
+	// REALTIME_STATUS asks a report of the status, like "<Idle|MPos:0.000,0.000,0.000|FS:0,0>".
+	REALTIME_STATUS Realtime = '?'
+
+	// REALTIME_CYCLE_START starts or resumes the cycle after a feed hold.
+	REALTIME_CYCLE_START Realtime = '~'
+
+	// REALTIME_FEED_HOLD stops the motion with a controlled deceleration.
+	REALTIME_FEED_HOLD Realtime = '!'
+
+	// REALTIME_SAFETY_DOOR behaves like the safety door was opened.
+	REALTIME_SAFETY_DOOR Realtime = 0x84
+
+	// REALTIME_JOG_CANCEL cancels the jog in progress and discards the jogs queued.
+	REALTIME_JOG_CANCEL Realtime = 0x85
+
+	// REALTIME_FEED_RESET restores the override of the feedrate to 100%.
+	REALTIME_FEED_RESET Realtime = 0x90
+
+	// REALTIME_FEED_PLUS_10 increases the override of the feedrate by 10%.
+	REALTIME_FEED_PLUS_10 Realtime = 0x91
+
+	// REALTIME_FEED_MINUS_10 decreases the override of the feedrate by 10%.
+	REALTIME_FEED_MINUS_10 Realtime = 0x92
+
+	// REALTIME_FEED_PLUS_1 increases the override of the feedrate by 1%.
+	REALTIME_FEED_PLUS_1 Realtime = 0x93
+
+	// REALTIME_FEED_MINUS_1 decreases the override of the feedrate by 1%.
+	REALTIME_FEED_MINUS_1 Realtime = 0x94
+
+	// REALTIME_RAPID_100 sets the override of the rapids to 100%.
+	REALTIME_RAPID_100 Realtime = 0x95
+
+	// REALTIME_RAPID_50 sets the override of the rapids to 50%.
+	REALTIME_RAPID_50 Realtime = 0x96
+
+	// REALTIME_RAPID_25 sets the override of the rapids to 25%.
+	REALTIME_RAPID_25 Realtime = 0x97
+
+	// REALTIME_SPINDLE_RESET restores the override of the spindle speed to 100%.
+	REALTIME_SPINDLE_RESET Realtime = 0x99
+
+	// REALTIME_SPINDLE_PLUS_10 increases the override of the spindle speed by 10%.
+	REALTIME_SPINDLE_PLUS_10 Realtime = 0x9A
+
+	// REALTIME_SPINDLE_MINUS_10 decreases the override of the spindle speed by 10%.
+	REALTIME_SPINDLE_MINUS_10 Realtime = 0x9B
+
+	// REALTIME_SPINDLE_PLUS_1 increases the override of the spindle speed by 1%.
+	REALTIME_SPINDLE_PLUS_1 Realtime = 0x9C
+
+	// REALTIME_SPINDLE_MINUS_1 decreases the override of the spindle speed by 1%.
+	REALTIME_SPINDLE_MINUS_1 Realtime = 0x9D
+
+	// REALTIME_SPINDLE_STOP toggles the stop of the spindle during a feed hold.
+	REALTIME_SPINDLE_STOP Realtime = 0x9E
+
+	// REALTIME_FLOOD toggles the flood coolant.
+	REALTIME_FLOOD Realtime = 0xA0
+
+	// REALTIME_MIST toggles the mist coolant.
+	REALTIME_MIST Realtime = 0xA1
+)
+
+// realtimeNames are the names of the realtime commands.
+var realtimeNames = map[Realtime]string{
+	REALTIME_RESET:            "reset",
+	REALTIME_STATUS:           "status",
+	REALTIME_CYCLE_START:      "cycle start",
+	REALTIME_FEED_HOLD:        "feed hold",
+	REALTIME_SAFETY_DOOR:      "safety door",
+	REALTIME_JOG_CANCEL:       "jog cancel",
+	REALTIME_FEED_RESET:       "feed 100%",
+	REALTIME_FEED_PLUS_10:     "feed +10%",
+	REALTIME_FEED_MINUS_10:    "feed -10%",
+	REALTIME_FEED_PLUS_1:      "feed +1%",
+	REALTIME_FEED_MINUS_1:     "feed -1%",
+	REALTIME_RAPID_100:        "rapid 100%",
+	REALTIME_RAPID_50:         "rapid 50%",
+	REALTIME_RAPID_25:         "rapid 25%",
+	REALTIME_SPINDLE_RESET:    "spindle 100%",
+	REALTIME_SPINDLE_PLUS_10:  "spindle +10%",
+	REALTIME_SPINDLE_MINUS_10: "spindle -10%",
+	REALTIME_SPINDLE_PLUS_1:   "spindle +1%",
+	REALTIME_SPINDLE_MINUS_1:  "spindle -1%",
+	REALTIME_SPINDLE_STOP:     "spindle stop",
+	REALTIME_FLOOD:            "flood",
+	REALTIME_MIST:             "mist",
+}
+
+// String returns the name of the realtime command, like "feed hold".
+func (r Realtime) String() string {
+	if name, ok := realtimeNames[r]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Realtime(0x%02X)", byte(r))
+}
+
+// IsRealtime returns true if the byte is a realtime command.
+func IsRealtime(b byte) bool {
+	_, ok := realtimeNames[Realtime(b)]
+	return ok
+}
+
+// SplitRealtime separates the realtime commands of the data received by GRBL from the rest, like GRBL does, so the lines
+// streamed can be recovered. Both keep their order.
+func SplitRealtime(data []byte) ([]Realtime, []byte) {
+	var commands []Realtime
+	rest := make([]byte, 0, len(data))
+
+	for _, b := range data {
+		if IsRealtime(b) {
+			commands = append(commands, Realtime(b))
+			continue
+		}
+		rest = append(rest, b)
+	}
+
+	return commands, rest
+}
+
+//#endregion
@@ -0,0 +1,60 @@
+package grbl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsRealtime(t *testing.T) {
+	cases := map[string]struct {
+		b    byte
+		want bool
+	}{
+		"reset":       {0x18, true},
+		"status":      {'?', true},
+		"cycle start": {'~', true},
+		"feed hold":   {'!', true},
+		"jog cancel":  {0x85, true},
+		"letter":      {'G', false},
+		"unassigned":  {0x98, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsRealtime(tc.b); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRealtime_String(t *testing.T) {
+	cases := map[string]struct {
+		r    Realtime
+		want string
+	}{
+		"feed hold": {REALTIME_FEED_HOLD, "feed hold"},
+		"reset":     {REALTIME_RESET, "reset"},
+		"unknown":   {Realtime(0x98), "Realtime(0x98)"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.r.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitRealtime(t *testing.T) {
+	commands, rest := SplitRealtime([]byte("G1 X1?\n!G1\x18 Y2\n~"))
+
+	if want := []Realtime{REALTIME_STATUS, REALTIME_FEED_HOLD, REALTIME_RESET, REALTIME_CYCLE_START}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("got %v, want %v", commands, want)
+	}
+
+	if want := "G1 X1\nG1 Y2\n"; string(rest) != want {
+		t.Errorf("got %q, want %q", rest, want)
+	}
+}
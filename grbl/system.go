@@ -0,0 +1,338 @@
+package grbl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region system kind
+
+// SystemKind identifies the kind of a system command of GRBL.
+type SystemKind int
+
+const (
+	// SystemHelp lists the system commands, "$".
+	SystemHelp SystemKind = iota
+
+	// SystemSettings reports the settings, "$$".
+	SystemSettings
+
+	// SystemSetting writes a setting, like "$110=5000". GRBL reports each setting in the same format.
+	SystemSetting
+
+	// SystemParameters reports the offsets of the coordinate systems and the last probe, "$#".
+	SystemParameters
+
+	// SystemParserState reports the modal state of the parser of gcode, "$G".
+	SystemParserState
+
+	// SystemBuildInfo reports the version and the build options, "$I", or writes the build info, like "$I=router".
+	SystemBuildInfo
+
+	// SystemStartupBlocks reports the lines executed after each reset, "$N".
+	SystemStartupBlocks
+
+	// SystemStartupBlock writes a line executed after each reset, like "$N0=G21 G54".
+	SystemStartupBlock
+
+	// SystemCheckMode toggles the check mode of gcode, that parses the lines without moving, "$C".
+	SystemCheckMode
+
+	// SystemUnlock kills the alarm lock, "$X".
+	SystemUnlock
+
+	// SystemHome runs the homing cycle of all axes, "$H", or of some axes, like "$HX".
+	SystemHome
+
+	// SystemJog moves the machine without changing the modal state, like "$J=G91 X10 F500".
+	SystemJog
+
+	// SystemRestore restores the settings "$RST=$", the offsets "$RST=#" or both and the startup lines "$RST=*".
+	SystemRestore
+
+	// SystemSleep enters the sleep mode, "$SLP".
+	SystemSleep
+)
+
+// String returns the command of the kind without its arguments, like "$H".
+func (k SystemKind) String() string {
+	switch k {
+	case SystemHelp:
+		return "$"
+	case SystemSettings:
+		return "$$"
+	case SystemSetting:
+		return "$x="
+	case SystemParameters:
+		return "$#"
+	case SystemParserState:
+		return "$G"
+	case SystemBuildInfo:
+		return "$I"
+	case SystemStartupBlocks:
+		return "$N"
+	case SystemStartupBlock:
+		return "$Nx="
+	case SystemCheckMode:
+		return "$C"
+	case SystemUnlock:
+		return "$X"
+	case SystemHome:
+		return "$H"
+	case SystemJog:
+		return "$J="
+	case SystemRestore:
+		return "$RST="
+	case SystemSleep:
+		return "$SLP"
+	}
+
+	return fmt.Sprintf("SystemKind(%d)", int(k))
+}
+
+//#endregion
+//#region system command
+
+// STARTUP_BLOCKS is the number of lines that GRBL executes after each reset.
+const STARTUP_BLOCKS = 2
+
+const (
+	// RESTORE_SETTINGS restores the settings to their defaults.
+	RESTORE_SETTINGS = "$"
+
+	// RESTORE_PARAMETERS clears the offsets of the coordinate systems.
+	RESTORE_PARAMETERS = "#"
+
+	// RESTORE_ALL restores the settings, clears the offsets and the startup lines.
+	RESTORE_ALL = "*"
+)
+
+// SystemCommand is a system command of GRBL, a line that starts with "$".
+type SystemCommand struct {
+	// Kind is the kind of the command.
+	Kind SystemKind
+
+	// Index is the number of the setting of SystemSetting and of the startup line of SystemStartupBlock.
+	Index int
+
+	// Value is the value of SystemSetting, the line of SystemStartupBlock, the build info of SystemBuildInfo,
+	// the axes of SystemHome, the line of gcode of SystemJog and the target of SystemRestore, like RESTORE_ALL.
+	// It is empty for the rest.
+	Value string
+}
+
+// String returns the line of the command, like "$110=5000" or "$HX".
+func (c SystemCommand) String() string {
+	switch c.Kind {
+	case SystemSetting:
+		return "$" + strconv.Itoa(c.Index) + "=" + c.Value
+	case SystemStartupBlock:
+		return "$N" + strconv.Itoa(c.Index) + "=" + c.Value
+	case SystemBuildInfo:
+		if c.Value != "" {
+			return "$I=" + c.Value
+		}
+	case SystemHome, SystemJog, SystemRestore:
+		return c.Kind.String() + c.Value
+	}
+
+	return c.Kind.String()
+}
+
+// Setting returns the command that writes the value of a setting, like "$110=5000".
+//
+// It returns an error if the number of the setting is negative.
+func Setting(number int, value float64) (SystemCommand, error) {
+	if number < 0 {
+		return SystemCommand{}, fmt.Errorf("failed to create setting, the number must be positive or zero: %d", number)
+	}
+
+	return SystemCommand{Kind: SystemSetting, Index: number, Value: strconv.FormatFloat(value, 'f', -1, 64)}, nil
+}
+
+// StartupBlock returns the command that writes a line executed after each reset, like "$N0=G21 G54".
+// An empty line clears it.
+//
+// It returns an error if the index isn't lower than STARTUP_BLOCKS, or the line contains line breaks or starts with "$".
+func StartupBlock(index int, line string) (SystemCommand, error) {
+	if index < 0 || index >= STARTUP_BLOCKS {
+		return SystemCommand{}, fmt.Errorf("failed to create startup block, the index must be between 0 and %d: %d", STARTUP_BLOCKS-1, index)
+	}
+
+	if strings.ContainsAny(line, "\r\n") || strings.HasPrefix(line, "$") {
+		return SystemCommand{}, fmt.Errorf("failed to create startup block, the line must be a single line of gcode: %q", line)
+	}
+
+	return SystemCommand{Kind: SystemStartupBlock, Index: index, Value: line}, nil
+}
+
+// Home returns the command that runs the homing cycle of the axes received, like "$HX", or of all axes without axes.
+//
+// It returns an error if some axis isn't X, Y, Z, A, B or C.
+func Home(axes ...byte) (SystemCommand, error) {
+	for _, axis := range axes {
+		if !isAxis(axis) {
+			return SystemCommand{}, fmt.Errorf("failed to create homing, invalid axis %q", axis)
+		}
+	}
+
+	return SystemCommand{Kind: SystemHome, Value: string(axes)}, nil
+}
+
+// Restore returns the command that restores a group of values, RESTORE_SETTINGS, RESTORE_PARAMETERS or RESTORE_ALL.
+//
+// It returns an error if the target is unknown.
+func Restore(target string) (SystemCommand, error) {
+	switch target {
+	case RESTORE_SETTINGS, RESTORE_PARAMETERS, RESTORE_ALL:
+		return SystemCommand{Kind: SystemRestore, Value: target}, nil
+	}
+
+	return SystemCommand{}, fmt.Errorf("failed to create restore, invalid target %q", target)
+}
+
+// IsSystemCommand returns true if the line is a system command of GRBL, it starts with "$".
+func IsSystemCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "$")
+}
+
+// ParseSystemCommand parses a system command of GRBL, like "$H" or "$110=5000.000". It also parses the lines that GRBL reports
+// for "$$" and "$N", since they use the format of the commands that write them. The names are case-insensitive, like GRBL.
+//
+// It returns an error if the line isn't a system command or it is invalid.
+func ParseSystemCommand(line string) (SystemCommand, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return SystemCommand{}, fmt.Errorf("failed to parse system command, it must start with $: %q", line)
+	}
+
+	body := line[1:]
+	name, value, assigned := strings.Cut(body, "=")
+	name = strings.ToUpper(name)
+
+	switch {
+	case body == "":
+		return SystemCommand{Kind: SystemHelp}, nil
+	case !assigned && len(name) > 0 && numeric.IsDigit(name[0]):
+		return SystemCommand{}, fmt.Errorf("failed to parse system command, the setting requires a value: %q", line)
+	case assigned && len(name) > 0 && numeric.IsDigit(name[0]):
+		number, err := strconv.Atoi(name)
+		if err != nil {
+			return SystemCommand{}, fmt.Errorf("failed to parse system command, invalid setting: %q", line)
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return SystemCommand{}, fmt.Errorf("failed to parse system command, the value of the setting must be a number: %q", line)
+		}
+		return SystemCommand{Kind: SystemSetting, Index: number, Value: value}, nil
+	case assigned && strings.HasPrefix(name, "N") && len(name) > 1:
+		index, err := strconv.Atoi(name[1:])
+		if err != nil || index < 0 || index >= STARTUP_BLOCKS {
+			return SystemCommand{}, fmt.Errorf("failed to parse system command, invalid startup block: %q", line)
+		}
+		return SystemCommand{Kind: SystemStartupBlock, Index: index, Value: value}, nil
+	case assigned && name == "I":
+		return SystemCommand{Kind: SystemBuildInfo, Value: value}, nil
+	case assigned && name == "J":
+		if _, err := ParseJog(line); err != nil {
+			return SystemCommand{}, fmt.Errorf("failed to parse system command: %w", err)
+		}
+		return SystemCommand{Kind: SystemJog, Value: value}, nil
+	case assigned && name == "RST":
+		return Restore(value)
+	case assigned:
+		return SystemCommand{}, fmt.Errorf("failed to parse system command, unknown command: %q", line)
+	case strings.HasPrefix(name, "H"):
+		return Home([]byte(name[1:])...)
+	}
+
+	kinds := map[string]SystemKind{
+		"$":   SystemSettings,
+		"#":   SystemParameters,
+		"G":   SystemParserState,
+		"I":   SystemBuildInfo,
+		"N":   SystemStartupBlocks,
+		"C":   SystemCheckMode,
+		"X":   SystemUnlock,
+		"SLP": SystemSleep,
+	}
+
+	kind, ok := kinds[name]
+	if !ok {
+		return SystemCommand{}, fmt.Errorf("failed to parse system command, unknown command: %q", line)
+	}
+
+	return SystemCommand{Kind: kind}, nil
+}
+
+//#endregion
+//#region settings
+
+// settingDescriptions are the descriptions of the settings of GRBL 1.1 with their units.
+var settingDescriptions = map[int]string{
+	0:   "step pulse time, microseconds",
+	1:   "step idle delay, milliseconds",
+	2:   "step pulse invert, mask",
+	3:   "step direction invert, mask",
+	4:   "invert step enable pin, boolean",
+	5:   "invert limit pins, boolean",
+	6:   "invert probe pin, boolean",
+	10:  "status report options, mask",
+	11:  "junction deviation, millimeters",
+	12:  "arc tolerance, millimeters",
+	13:  "report in inches, boolean",
+	20:  "soft limits enable, boolean",
+	21:  "hard limits enable, boolean",
+	22:  "homing cycle enable, boolean",
+	23:  "homing direction invert, mask",
+	24:  "homing locate feed rate, mm/min",
+	25:  "homing search seek rate, mm/min",
+	26:  "homing switch debounce delay, milliseconds",
+	27:  "homing switch pull-off distance, millimeters",
+	30:  "maximum spindle speed, RPM",
+	31:  "minimum spindle speed, RPM",
+	32:  "laser mode enable, boolean",
+	100: "X-axis travel resolution, step/mm",
+	101: "Y-axis travel resolution, step/mm",
+	102: "Z-axis travel resolution, step/mm",
+	110: "X-axis maximum rate, mm/min",
+	111: "Y-axis maximum rate, mm/min",
+	112: "Z-axis maximum rate, mm/min",
+	120: "X-axis acceleration, mm/sec^2",
+	121: "Y-axis acceleration, mm/sec^2",
+	122: "Z-axis acceleration, mm/sec^2",
+	130: "X-axis maximum travel, millimeters",
+	131: "Y-axis maximum travel, millimeters",
+	132: "Z-axis maximum travel, millimeters",
+}
+
+// SettingDescription returns the description of a setting of GRBL 1.1 with its unit, like "X-axis maximum rate, mm/min" for 110.
+// It returns false if the setting is unknown.
+func SettingDescription(number int) (string, bool) {
+	description, ok := settingDescriptions[number]
+	return description, ok
+}
+
+// Settings returns the numbers of the settings of GRBL 1.1 in ascending order.
+func Settings() []int {
+	numbers := make([]int, 0, len(settingDescriptions))
+	for number := range settingDescriptions {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	return numbers
+}
+
+//#endregion
+//#region private functions
+
+// isAxis returns true if the character is an axis of GRBL.
+func isAxis(c byte) bool {
+	return strings.IndexByte(AXES, c) >= 0
+}
+
+//#endregion
@@ -0,0 +1,135 @@
+package grbl
+
+import (
+	"testing"
+)
+
+func TestParseSystemCommand(t *testing.T) {
+	cases := map[string]struct {
+		line   string
+		want   SystemCommand
+		output string
+	}{
+		"help":          {"$", SystemCommand{Kind: SystemHelp}, "$"},
+		"settings":      {"$$", SystemCommand{Kind: SystemSettings}, "$$"},
+		"setting":       {"$110=5000.000", SystemCommand{Kind: SystemSetting, Index: 110, Value: "5000.000"}, "$110=5000.000"},
+		"parameters":    {"$#", SystemCommand{Kind: SystemParameters}, "$#"},
+		"parser state":  {"$g", SystemCommand{Kind: SystemParserState}, "$G"},
+		"build info":    {"$I", SystemCommand{Kind: SystemBuildInfo}, "$I"},
+		"write info":    {"$I=router", SystemCommand{Kind: SystemBuildInfo, Value: "router"}, "$I=router"},
+		"startup lines": {"$N", SystemCommand{Kind: SystemStartupBlocks}, "$N"},
+		"startup line":  {"$N1=G21 G54", SystemCommand{Kind: SystemStartupBlock, Index: 1, Value: "G21 G54"}, "$N1=G21 G54"},
+		"check mode":    {"$C", SystemCommand{Kind: SystemCheckMode}, "$C"},
+		"unlock":        {" $X\n", SystemCommand{Kind: SystemUnlock}, "$X"},
+		"home":          {"$H", SystemCommand{Kind: SystemHome}, "$H"},
+		"home axis":     {"$HZ", SystemCommand{Kind: SystemHome, Value: "Z"}, "$HZ"},
+		"jog":           {"$J=G91X10F500", SystemCommand{Kind: SystemJog, Value: "G91X10F500"}, "$J=G91X10F500"},
+		"restore":       {"$RST=*", SystemCommand{Kind: SystemRestore, Value: RESTORE_ALL}, "$RST=*"},
+		"sleep":         {"$SLP", SystemCommand{Kind: SystemSleep}, "$SLP"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseSystemCommand(tc.line)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+
+			if got.String() != tc.output {
+				t.Errorf("got %q, want %q", got.String(), tc.output)
+			}
+		})
+	}
+}
+
+func TestParseSystemCommand_errors(t *testing.T) {
+	cases := map[string]string{
+		"not system":      "G28",
+		"setting value":   "$110",
+		"setting number":  "$110=fast",
+		"startup index":   "$N2=G21",
+		"restore target":  "$RST=X",
+		"unknown":         "$Q",
+		"unknown assign":  "$Q=1",
+		"home axis":       "$HW",
+		"jog without F":   "$J=G91 X10",
+		"jog with G1":     "$J=G1 X10 F100",
+		"jog without axe": "$J=G91 F100",
+	}
+
+	for name, line := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got, err := ParseSystemCommand(line); err == nil {
+				t.Errorf("got %#v, want error not nil", got)
+			}
+		})
+	}
+}
+
+func TestSetting(t *testing.T) {
+	c, err := Setting(110, 5000)
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if got, want := c.String(), "$110=5000"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := Setting(-1, 0); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+func TestStartupBlock(t *testing.T) {
+	cases := map[string]struct {
+		index int
+		line  string
+		want  string
+	}{
+		"line":       {0, "G21 G54", "$N0=G21 G54"},
+		"clear":      {1, "", "$N1="},
+		"index":      {2, "G21", ""},
+		"line break": {0, "G21\nG54", ""},
+		"system":     {0, "$H", ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := StartupBlock(tc.index, tc.line)
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("got %q, want error not nil", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestSettingDescription(t *testing.T) {
+	if got, ok := SettingDescription(110); !ok || got != "X-axis maximum rate, mm/min" {
+		t.Errorf("got %q %v, want the description of 110", got, ok)
+	}
+
+	if _, ok := SettingDescription(7); ok {
+		t.Errorf("got found, want not found")
+	}
+
+	numbers := Settings()
+	if len(numbers) == 0 || numbers[0] != 0 || numbers[len(numbers)-1] != 132 {
+		t.Errorf("got %v, want the settings from 0 to 132", numbers)
+	}
+}
@@ -0,0 +1,34 @@
+// blockparam package reads the numeric parameters of the blocks, shared by the packages that interpret them.
+//
+// The packages that can't import transform, like document, state and simulator, use it to convert the addresses the same way.
+//
+// This package is only to internal use.
+package blockparam
+
+import (
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+// Number returns the numeric address of the first parameter of the block with the word required.
+// It returns false if the block hasn't that parameter or its address isn't numeric.
+//
+// The float32 addresses are converted through their shortest representation, so Z0.2 is 0.2 instead of 0.20000000298.
+func Number(b block.Blocker, word byte) (float64, bool) {
+	for _, p := range b.Parameters() {
+		if p.Word() != word {
+			continue
+		}
+
+		if v, ok := p.(gcode.AddressableGcoder[float32]); ok {
+			value, err := strconv.ParseFloat(strconv.FormatFloat(float64(v.Address()), 'f', -1, 32), 64)
+			return value, err == nil
+		}
+
+		return gcode.NumericAddress(p)
+	}
+
+	return 0, false
+}
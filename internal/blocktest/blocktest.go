@@ -0,0 +1,54 @@
+// blocktest package contains the fixtures shared by the tests of the packages that consume blocks.
+//
+// This package is only to internal use.
+package blocktest
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+// CNC_WORDS are the words of the CNC controllers that gcode.DEFAULT_WORDS doesn't include:
+// A, B and C of the rotary axes, K of the arcs, L of G10 and O of the programs.
+const CNC_WORDS = "ABCKLO"
+
+// Parse parses each line as a block with the default configuration. It fails the test if some line is invalid.
+func Parse(t testing.TB, lines ...string) []block.Blocker {
+	t.Helper()
+
+	return parse(t, lines)
+}
+
+// ParseCNC parses each line as a block with a word registry that accepts the CNC_WORDS too,
+// like the programs of the CNC controllers with G10 L2 or arcs in the planes ZX and YZ. It fails the test if some line is invalid.
+func ParseCNC(t testing.TB, lines ...string) []block.Blocker {
+	t.Helper()
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow([]byte(CNC_WORDS)...); err != nil {
+		t.Fatalf("failed to allow the words %s: %v", CNC_WORDS, err)
+	}
+
+	return parse(t, lines, func(config block.BlockParserConfigurer) error {
+		return config.SetWordRegistry(registry)
+	})
+}
+
+// parse parses each line as a block with the options. It fails the test if some line is invalid.
+func parse(t testing.TB, lines []string, options ...block.BlockParserConfigurationCallbackable) []block.Blocker {
+	t.Helper()
+
+	var blocks []block.Blocker
+	for _, line := range lines {
+		b, err := gcodeblock.Parse(line, options...)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", line, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
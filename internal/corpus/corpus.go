@@ -0,0 +1,42 @@
+// corpus package provides a set of sample gcode files shared by the tests of all packages.
+//
+// The files are embedded in the binary, so the tests don't depend on the working directory.
+// They cover 3D-printing files with layers, CNC files with arcs and streams with line numbers and checksums.
+//
+// This package is only to internal use.
+package corpus
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+)
+
+//go:embed files/*.gcode
+var files embed.FS
+
+// Names returns the names of all files of the corpus sorted in ascending order.
+func Names() []string {
+	entries, err := fs.ReadDir(files, "files")
+	if err != nil {
+		panic(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Read returns the content of a file of the corpus. It panics if the file doesn't exist.
+func Read(name string) string {
+	content, err := files.ReadFile("files/" + name)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(content)
+}
@@ -0,0 +1,101 @@
+package corpus_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/corpus"
+	"github.com/mauroalderete/gcode-core/oplog"
+	"github.com/mauroalderete/gcode-core/preflight"
+)
+
+// render executes all the exporters over a file of the corpus and returns the output concatenated.
+func render(t *testing.T, name string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	source := corpus.Read(name)
+
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		b, err := gcodeblock.Parse(line)
+		if err != nil {
+			t.Fatalf("got error %v parsing %s, want error nil", err, line)
+		}
+
+		if err := b.SetTag("source", name); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		if err := b.SetTag("length", fmt.Sprint(len(line))); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		checksum, err := b.CalculateChecksum()
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		fmt.Fprintf(&buffer, "%s|%s\n", b.ToLine("%l %c %p%k %m %t"), checksum)
+	}
+
+	for _, format := range []oplog.Format{oplog.Text, oplog.JSON, oplog.Markdown} {
+		if err := oplog.Export(&buffer, strings.NewReader(source), format); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+	}
+
+	report, err := preflight.PreflightSource(strings.NewReader(source), preflight.Profile{
+		MaxHotendTemperature:  260,
+		MaxBedTemperature:     100,
+		Max:                   [3]float64{200, 200, 200},
+		RequiredStartCommands: []string{"G28"},
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	buffer.WriteString(report.String())
+
+	return buffer.Bytes()
+}
+
+// TestDeterminism asserts that the output of the exporters is byte-identical across runs and platforms.
+//
+// The golden hashes must only be updated when the output format changes on purpose.
+func TestDeterminism(t *testing.T) {
+	golden := map[string]string{
+		"cnc.gcode":    "c1ea1da47145ca099b955736c4b8e998c4b44cd9d94a95b14f259ac7ad1e7638",
+		"print.gcode":  "b30e1e95379d76d1211077e3d9473611ee82e126183796c0fdd2befd7f8a61b0",
+		"stream.gcode": "0346ea0132fb798c0f8b930b778da9f894d9bac3d22b361bc7d1534feb361baf",
+	}
+
+	names := corpus.Names()
+	if len(names) != len(golden) {
+		t.Errorf("got %d files in the corpus, want %d", len(names), len(golden))
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			first := render(t, name)
+
+			for i := 0; i < 5; i++ {
+				if !bytes.Equal(first, render(t, name)) {
+					t.Fatalf("got different outputs in the run %d, want identical outputs", i+1)
+				}
+			}
+
+			sum := sha256.Sum256(first)
+			if got := hex.EncodeToString(sum[:]); got != golden[name] {
+				t.Errorf("got hash %s, want hash %s", got, golden[name])
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+; simple pocket milled with a 3mm end mill
+G21
+G90
+G17
+M3 S12000
+G0 Z5
+G0 X0 Y0
+G1 Z-1 F300
+G1 X20 F800
+G2 X30 Y10 I0 J10
+G1 Y30
+G3 X20 Y40 I-10 J0
+G1 X0
+G1 Y0
+G0 Z5
+M5
+M30
@@ -0,0 +1,36 @@
+;FLAVOR:Marlin
+;LAYER_COUNT:3
+M140 S60
+M104 S200
+M190 S60
+M109 S200
+G28
+G90
+M82
+G92 E0
+G1 Z0.2 F3000
+;LAYER:0
+G1 X10 Y10 F1500
+G1 X50.5 Y10 E2.12345
+G1 X50.5 Y50.25 E4.2
+G1 X10 Y50.25 E6.3
+G1 X10 Y10 E8.4
+G0 X12 Y12 F6000
+;LAYER:1
+G1 Z0.4 F3000
+M106 S255
+G1 X48.5 Y12 E10.1 F1500
+G1 X48.5 Y48.25 E11.9
+G1 X12 Y48.25 E13.7
+G1 X12 Y12 E15.5
+;LAYER:2
+G1 Z0.6
+G1 X46.5 Y14 E17.2
+G1 X46.5 Y46.25 E18.9
+G1 E17.9 F2400
+G0 Z10.6 F6000
+M107
+M104 S0
+M140 S0
+G28 X0 Y0
+M84
@@ -0,0 +1,9 @@
+; stream captured from a host
+N0 M110*35
+N1 G28*18
+N2 G92 E0*69
+N3 M104 S210*101
+N4 G1 X2.0 Y2.0 F3000.0*86
+N5 G1 X3.0 Y3.0 E0.5*98
+N6 G1 X-1.5 Y0.25 E1.0*123
+N7 M107*34
@@ -0,0 +1,43 @@
+// numeric package formats and rounds the numbers written in the messages and in the blocks created by the library,
+// and recognizes the digits of the expressions parsed, shared by the packages that use them.
+//
+// This package is only to internal use.
+package numeric
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format returns the shortest representation of a value, without trailing zeros.
+func Format(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// FormatDecimals returns a value rounded to the number of decimals received at most, without trailing zeros.
+// The values rounded to zero are written without sign.
+func FormatDecimals(value float64, decimals int) string {
+	s := strconv.FormatFloat(value, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+
+	if s == "-0" {
+		return "0"
+	}
+
+	return s
+}
+
+// Round rounds a value to the number of decimals received, to remove the errors of the arithmetic, like the subtractions.
+func Round(value float64, decimals int) float64 {
+	factor := math.Pow10(decimals)
+
+	return math.Round(value*factor) / factor
+}
+
+// IsDigit returns true if the character is a decimal digit.
+func IsDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
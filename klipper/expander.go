@@ -0,0 +1,308 @@
+package klipper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+//#region expander configuration
+
+// ExpanderConfigurer defines the options of an expander.
+type ExpanderConfigurer interface {
+	// Set the macros expanded
+	SetMacros(macros ...Macro) error
+
+	// Set the status of the printer read by the templates
+	SetPrinter(status map[string]interface{}) error
+
+	// Set the options used to parse the gcode rendered
+	SetParseOptions(options ...document.ParseConfigurationCallbackable) error
+
+	// Set if the calls are kept as comments before their gcode
+	SetKeepCalls(enabled bool) error
+}
+
+// ExpanderConfigurationCallbackable is the signature of the callbacks used to configure an expander.
+type ExpanderConfigurationCallbackable func(config ExpanderConfigurer) error
+
+// expanderConfigurator implements ExpanderConfigurer.
+type expanderConfigurator struct {
+	macros       map[string]*compiledMacro
+	printer      map[string]interface{}
+	parseOptions []document.ParseConfigurationCallbackable
+	keepCalls    bool
+}
+
+// SetMacros defines the macros expanded, they are added to the ones defined before and replace the ones with the same name.
+// The names are converted to upper case and can't be empty or contain spaces.
+// If this method isn't called, by default there aren't macros and the documents are expanded without changes.
+func (ec *expanderConfigurator) SetMacros(macros ...Macro) error {
+	for _, m := range macros {
+		name := strings.ToUpper(strings.TrimSpace(m.Name))
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("failed to set macros, invalid name %q", m.Name)
+		}
+
+		template, err := ParseTemplate(m.Gcode)
+		if err != nil {
+			return fmt.Errorf("failed to set macro %s: %w", name, err)
+		}
+
+		defaults := map[string]interface{}{}
+		for parameter, value := range m.Parameters {
+			defaults[strings.ToUpper(parameter)] = value
+		}
+
+		ec.macros[name] = &compiledMacro{name: name, defaults: defaults, variables: m.Variables, template: template}
+	}
+
+	return nil
+}
+
+// SetPrinter defines the status of the printer, read by the templates as "printer", like "printer.toolhead.position".
+// The values are ints, floats, strings, booleans, nil, and slices and maps of them.
+// If this method isn't called, by default the status is unknown and "printer" is undefined.
+func (ec *expanderConfigurator) SetPrinter(status map[string]interface{}) error {
+	ec.printer = status
+
+	return nil
+}
+
+// SetParseOptions defines the options used to parse the gcode rendered by the macros, like the block options.
+// The extended commands of Klipper are always enabled. Doesn't accept nil options.
+// If this method isn't called, by default the gcode is parsed with the default options of document.Parse.
+func (ec *expanderConfigurator) SetParseOptions(options ...document.ParseConfigurationCallbackable) error {
+	for _, option := range options {
+		if option == nil {
+			return fmt.Errorf("failed to set parse options, they mustn't be nil")
+		}
+	}
+
+	ec.parseOptions = options
+
+	return nil
+}
+
+// SetKeepCalls defines if the calls of the macros are kept as comments before their gcode, like "; START_PRINT BED=60",
+// so the gcode expanded can be traced to its call.
+// If this method isn't called, by default the calls are removed.
+func (ec *expanderConfigurator) SetKeepCalls(enabled bool) error {
+	ec.keepCalls = enabled
+
+	return nil
+}
+
+//#endregion
+//#region expander
+
+// compiledMacro is a macro with its template parsed.
+type compiledMacro struct {
+	name      string
+	defaults  map[string]interface{}
+	variables map[string]interface{}
+	template  *Template
+}
+
+// Expander replaces the calls of the macros in the documents by their gcode.
+type Expander struct {
+	expanderConfigurator
+}
+
+// NewExpander returns a new expander, configured with the options received.
+//
+// It returns an error if some option fails, like a macro with an invalid template.
+func NewExpander(options ...ExpanderConfigurationCallbackable) (*Expander, error) {
+	e := &Expander{
+		expanderConfigurator: expanderConfigurator{
+			macros: map[string]*compiledMacro{},
+		},
+	}
+
+	for _, option := range options {
+		if err := option(&e.expanderConfigurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// Expand returns a new document with the calls of the macros replaced by the gcode that they render, recursively, so a macro
+// can call other macros. The source document isn't modified.
+//
+// The calls are the extended commands with the name of a macro, like "START_PRINT BED=60", whose arguments are read as
+// "params.BED", or the blocks whose command is the name of a macro, like "M600 X10", whose parameters are read as "params.X".
+// The text of the arguments is read as "rawparams". The arguments are strings, like in Klipper, so the arithmetic requires
+// the filters float or int. The lines rendered are trimmed and the empty ones are removed.
+//
+// It returns an error if some template can't be rendered, its gcode can't be parsed, or a macro calls itself.
+func (e *Expander) Expand(d *document.Document) (*document.Document, error) {
+	lines, err := e.expand(d, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return document.NewFromLines(lines...), nil
+}
+
+// Render returns the gcode of a call of a macro, like "START_PRINT BED=60", without expanding the macros that it calls.
+//
+// It returns an error if the text isn't a call of a macro or its template can't be rendered.
+func (e *Expander) Render(call string) (string, error) {
+	d, err := document.Parse(strings.NewReader(call), e.options()...)
+	if err != nil || d.LineCount() != 1 {
+		return "", fmt.Errorf("failed to render %q, it isn't a call", call)
+	}
+
+	line, _ := d.Line(0)
+	m, params, raw, ok := e.call(line)
+	if !ok {
+		return "", fmt.Errorf("failed to render %q, it isn't a call of a macro", call)
+	}
+
+	return e.render(m, params, raw)
+}
+
+// expand returns the lines of a document with the calls expanded, the stack contains the names of the macros being expanded.
+func (e *Expander) expand(d *document.Document, stack []string) ([]document.Line, error) {
+	lines := make([]document.Line, 0, d.LineCount())
+
+	for index, line := range d.Lines() {
+		m, params, raw, ok := e.call(line)
+		if !ok {
+			lines = append(lines, line)
+			continue
+		}
+
+		for _, name := range stack {
+			if name == m.name {
+				return nil, fmt.Errorf("failed to expand line %d (%s), macro %s called recursively", index, line, m.name)
+			}
+		}
+
+		text, err := e.render(m, params, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand line %d (%s): %w", index, line, err)
+		}
+
+		rendered, err := document.Parse(strings.NewReader(text), e.options()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand line %d (%s), invalid gcode rendered: %w", index, line, err)
+		}
+
+		expanded, err := e.expand(rendered, append(stack[:len(stack):len(stack)], m.name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand line %d (%s): %w", index, line, err)
+		}
+
+		if e.keepCalls {
+			lines = append(lines, document.Line{Text: "; " + line.String()})
+		}
+		lines = append(lines, expanded...)
+	}
+
+	return lines, nil
+}
+
+// call returns the macro called by a line with its parameters and the text of its arguments, or false if the line isn't a call.
+func (e *Expander) call(line document.Line) (*compiledMacro, map[string]interface{}, string, bool) {
+	params := map[string]interface{}{}
+
+	if line.IsBlock() {
+		command := line.Block.Command()
+		if command == nil {
+			return nil, nil, "", false
+		}
+
+		m, ok := e.macros[strings.ToUpper(command.String())]
+		if !ok {
+			return nil, nil, "", false
+		}
+
+		raw := make([]string, 0, len(line.Block.Parameters()))
+		for _, p := range line.Block.Parameters() {
+			params[string(p.Word())] = strings.TrimPrefix(p.String(), string(p.Word()))
+			raw = append(raw, p.String())
+		}
+
+		return m, params, strings.Join(raw, " "), true
+	}
+
+	name, arguments, ok := document.ParseExtendedCommand(line.Text)
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	m, ok := e.macros[name]
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	for key, value := range arguments {
+		params[key] = value
+	}
+
+	text, _, _ := strings.Cut(line.Text, ";")
+	fields := strings.SplitN(strings.TrimSpace(text), " ", 2)
+
+	raw := ""
+	if len(fields) > 1 {
+		raw = strings.TrimSpace(fields[1])
+	}
+
+	return m, params, raw, true
+}
+
+// render returns the gcode of a macro with the parameters of a call, the lines trimmed and without the empty ones.
+func (e *Expander) render(m *compiledMacro, params map[string]interface{}, raw string) (string, error) {
+	variables := map[string]interface{}{}
+	for name, value := range m.variables {
+		variables[name] = value
+	}
+
+	merged := map[string]interface{}{}
+	for name, value := range m.defaults {
+		merged[name] = value
+	}
+	for name, value := range params {
+		merged[name] = value
+	}
+
+	variables["params"] = merged
+	variables["rawparams"] = raw
+	if e.printer != nil {
+		variables["printer"] = e.printer
+	}
+
+	text, err := m.template.Render(variables)
+	if err != nil {
+		return "", fmt.Errorf("failed to render macro %s: %w", m.name, err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// options returns the options used to parse the gcode rendered, with the extended commands enabled.
+func (e *Expander) options() []document.ParseConfigurationCallbackable {
+	options := make([]document.ParseConfigurationCallbackable, 0, len(e.parseOptions)+1)
+	options = append(options, e.parseOptions...)
+
+	return append(options, func(config document.ParseConfigurer) error {
+		return config.SetExtendedCommands(true)
+	})
+}
+
+//#endregion
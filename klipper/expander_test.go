@@ -0,0 +1,169 @@
+package klipper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestExpander_Expand(t *testing.T) {
+
+	macros := []Macro{
+		{
+			Name:       "START_PRINT",
+			Parameters: map[string]string{"BED": "60", "EXTRUDER": "200"},
+			Gcode:      "M190 S{params.BED}\nM109 S{params.EXTRUDER}\nPURGE_LINE",
+		},
+		{
+			Name:      "PURGE_LINE",
+			Variables: map[string]interface{}{"length": 100},
+			Gcode:     "G1 X{length} E{length / 10} F1500",
+		},
+		{
+			Name:  "M600",
+			Gcode: "G1 X{params.X|default(0)} Y{params.Y|default(0)}\nM117 {rawparams}",
+		},
+		{
+			Name:  "PARK",
+			Gcode: "G1 Z{printer.toolhead.position[2] + 10}",
+		},
+	}
+
+	cases := map[string]struct {
+		source string
+		want   string
+	}{
+		"without calls": {"G28\n;comment\nG1 X10\n", "G28\n;comment\nG1 X10\n"},
+		"defaults":      {"START_PRINT\nG28\n", "M190 S60\nM109 S200\nG1 X100 E10.0 F1500\nG28\n"},
+		"arguments":     {"START_PRINT BED=70 EXTRUDER=215\n", "M190 S70\nM109 S215\nG1 X100 E10.0 F1500\n"},
+		"lower case":    {"start_print bed=70\n", "M190 S70\nM109 S200\nG1 X100 E10.0 F1500\n"},
+		"block":         {"M600 X10\n", "G1 X10 Y0\nM117 X10\n"},
+		"printer":       {"PARK\n", "G1 Z15.5\n"},
+		"unknown":       {"BED_MESH_CALIBRATE\n", "BED_MESH_CALIBRATE\n"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			expander, err := NewExpander(func(config ExpanderConfigurer) error {
+				if err := config.SetMacros(macros...); err != nil {
+					return err
+				}
+				return config.SetPrinter(map[string]interface{}{
+					"toolhead": map[string]interface{}{"position": []float64{10, 20, 5.5, 0}},
+				})
+			})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got, err := expander.Expand(parse(t, tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestExpander_options(t *testing.T) {
+
+	t.Run("keep calls", func(t *testing.T) {
+		expander, err := NewExpander(func(config ExpanderConfigurer) error {
+			if err := config.SetMacros(Macro{Name: "HOME", Gcode: "G28"}); err != nil {
+				return err
+			}
+			return config.SetKeepCalls(true)
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		got, err := expander.Expand(parse(t, "HOME\n"))
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		if want := "; HOME\nG28\n"; got.String() != want {
+			t.Errorf("got %q, want %q", got.String(), want)
+		}
+	})
+
+	t.Run("invalid macros", func(t *testing.T) {
+		for _, m := range []Macro{{Name: "", Gcode: "G28"}, {Name: "MY HOME", Gcode: "G28"}, {Name: "HOME", Gcode: "{% if True %}"}} {
+			_, err := NewExpander(func(config ExpanderConfigurer) error {
+				return config.SetMacros(m)
+			})
+			if err == nil {
+				t.Errorf("got error nil for %q, want error not nil", m.Name)
+			}
+		}
+	})
+}
+
+func TestExpander_Expand_errors(t *testing.T) {
+
+	cases := map[string]struct {
+		macros []Macro
+		source string
+	}{
+		"recursive":       {[]Macro{{Name: "LOOP_A", Gcode: "LOOP_B"}, {Name: "LOOP_B", Gcode: "LOOP_A"}}, "LOOP_A\n"},
+		"render failed":   {[]Macro{{Name: "HEAT", Gcode: "M104 S{params.TEMP + 10}"}}, "HEAT\n"},
+		"invalid gcode":   {[]Macro{{Name: "BAD", Gcode: "G1 X{'a'}"}}, "BAD\n"},
+		"printer unknown": {[]Macro{{Name: "PARK", Gcode: "G1 Z{printer.toolhead.position[2]}"}}, "PARK\n"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			expander, err := NewExpander(func(config ExpanderConfigurer) error {
+				return config.SetMacros(tc.macros...)
+			})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if _, err := expander.Expand(parse(t, tc.source)); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestExpander_Render(t *testing.T) {
+	expander, err := NewExpander(func(config ExpanderConfigurer) error {
+		return config.SetMacros(Macro{Name: "HEAT", Parameters: map[string]string{"TEMP": "200"}, Gcode: "M104 S{params.TEMP}\n\n  M109 S{params.TEMP}  \nHOME"})
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	got, err := expander.Render("HEAT TEMP=215")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if want := "M104 S215\nM109 S215\nHOME\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := expander.Render("G28"); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+// parse parses a source with the extended commands enabled.
+func parse(t *testing.T, source string) *document.Document {
+	t.Helper()
+
+	d, err := document.Parse(strings.NewReader(source), func(config document.ParseConfigurer) error {
+		return config.SetExtendedCommands(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	return d
+}
@@ -0,0 +1,1310 @@
+package klipper
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+// MAX_RANGE is the maximum number of values of range, so a template can't exhaust the memory.
+const MAX_RANGE = 10000
+
+//#region values
+
+// undefined is the value of a name or a key that doesn't exist. It is rendered as an empty string, it is false,
+// and it can be replaced with the filter default, but it can't be operated.
+type undefined struct {
+	name string
+}
+
+// normalize converts the numbers to int or float64 and the maps and slices to their generic types, recursively.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint:
+		return int(v)
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case uint32:
+		return int(v)
+	case float32:
+		number, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'f', -1, 32), 64)
+		return number
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalize(item)
+		}
+		return normalized
+	case map[string]string:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = item
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	case []float64:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = item
+		}
+		return normalized
+	}
+
+	return value
+}
+
+// FormatValue returns a value as it is rendered by a template, like Python does: the floats always have decimals, like "60.0",
+// the booleans are "True" and "False", nil is "None" and the undefined values are empty.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case undefined:
+		return ""
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = represent(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		return "{...}"
+	}
+
+	return fmt.Sprint(value)
+}
+
+// represent returns a value as it is written inside a list, the strings between quotes.
+func represent(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return "'" + s + "'"
+	}
+
+	return FormatValue(value)
+}
+
+// formatFloat returns the shortest representation of a float with a decimal point.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	}
+
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(text, ".") {
+		text += ".0"
+	}
+
+	return text
+}
+
+// truthy returns if a value is true in a condition.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case undefined, nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+
+	return true
+}
+
+// number returns the value of an int or a float as a float.
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// describe returns the type of a value for the messages of the errors.
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case undefined:
+		return fmt.Sprintf("%s undefined", v.name)
+	case nil:
+		return "None"
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+//#endregion
+//#region tokens
+
+// tokenKind identifies the kind of a token of an expression.
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenName
+	tokenOperator
+)
+
+// token is a token of an expression.
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+// operators are the operators of the expressions, the longest ones first.
+var operators = []string{"==", "!=", "<=", ">=", "//", "**", "<", ">", "+", "-", "*", "/", "%", "~", "|", "(", ")", "[", "]", ".", ",", "="}
+
+// tokenize splits the text of an expression in tokens.
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case numeric.IsDigit(c) || (c == '.' && i+1 < len(text) && numeric.IsDigit(text[i+1])):
+			start := i
+			float := false
+			for i < len(text) && (numeric.IsDigit(text[i]) || text[i] == '.' || text[i] == '_') {
+				float = float || text[i] == '.'
+				i++
+			}
+			if i < len(text) && (text[i] == 'e' || text[i] == 'E') {
+				float = true
+				i++
+				if i < len(text) && (text[i] == '+' || text[i] == '-') {
+					i++
+				}
+				for i < len(text) && numeric.IsDigit(text[i]) {
+					i++
+				}
+			}
+
+			literal := strings.ReplaceAll(text[start:i], "_", "")
+			if float {
+				value, err := strconv.ParseFloat(literal, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number %q", text[start:i])
+				}
+				tokens = append(tokens, token{kind: tokenNumber, text: text[start:i], value: value})
+				continue
+			}
+
+			value, err := strconv.Atoi(literal)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", text[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text[start:i], value: value})
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(text[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string %s", text[i:])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text[i : i+end+2], value: text[i+1 : i+1+end]})
+			i += end + 2
+		case isLetter(c):
+			start := i
+			for i < len(text) && (isLetter(text[i]) || numeric.IsDigit(text[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: text[start:i]})
+		default:
+			found := false
+			for _, operator := range operators {
+				if strings.HasPrefix(text[i:], operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator})
+					i += len(operator)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// isLetter returns true if the character can start a name.
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+//#endregion
+//#region parser
+
+// parser is a recursive descent parser of the expressions.
+type parser struct {
+	tokens   []token
+	position int
+}
+
+// parseExpression parses a whole expression.
+func parseExpression(text string) (node, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.expression()
+	if err == nil && p.position < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.position].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	return root, nil
+}
+
+// peek returns the next token if it is one of the operators or keywords received.
+func (p *parser) peek(texts ...string) (string, bool) {
+	if p.position >= len(p.tokens) {
+		return "", false
+	}
+
+	t := p.tokens[p.position]
+	if t.kind != tokenOperator && t.kind != tokenName {
+		return "", false
+	}
+
+	for _, text := range texts {
+		if t.text == text {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+// expect consumes the operator required or returns an error.
+func (p *parser) expect(operator string) error {
+	if _, ok := p.peek(operator); !ok {
+		if p.position >= len(p.tokens) {
+			return fmt.Errorf("expected %q at the end", operator)
+		}
+		return fmt.Errorf("expected %q instead of %q", operator, p.tokens[p.position].text)
+	}
+
+	p.position++
+
+	return nil
+}
+
+// name consumes a name or returns an error.
+func (p *parser) name() (string, error) {
+	if p.position >= len(p.tokens) || p.tokens[p.position].kind != tokenName {
+		return "", fmt.Errorf("expected a name")
+	}
+
+	p.position++
+
+	return p.tokens[p.position-1].text, nil
+}
+
+// expression parses "or", the lowest precedence.
+func (p *parser) expression() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("or"); !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalNode{operator: "or", left: left, right: right}
+	}
+}
+
+// and parses "and".
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("and"); !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalNode{operator: "and", left: left, right: right}
+	}
+}
+
+// not parses "not".
+func (p *parser) not() (node, error) {
+	if _, ok := p.peek("not"); ok {
+		p.position++
+
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{operand: operand}, nil
+	}
+
+	return p.comparison()
+}
+
+// comparison parses "==", "!=", "<", "<=", ">" and ">=".
+func (p *parser) comparison() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("==", "!=", "<", "<=", ">", ">=")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// additive parses "+" and "-".
+func (p *parser) additive() (node, error) {
+	left, err := p.concatenation()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("+", "-")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.concatenation()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// concatenation parses "~", that joins the values as strings.
+func (p *parser) concatenation() (node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("~"); !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: "~", left: left, right: right}
+	}
+}
+
+// multiplicative parses "*", "/", "//" and "%".
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.power()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("*", "/", "//", "%")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.power()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// power parses "**".
+func (p *parser) power() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("**"); !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: "**", left: left, right: right}
+	}
+}
+
+// unary parses the filters, that are applied after the signs, like "-1|abs".
+func (p *parser) unary() (node, error) {
+	operand, err := p.signed()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peek("|"); !ok {
+			return operand, nil
+		}
+		p.position++
+
+		if operand, err = p.filter(operand); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// signed parses the signs.
+func (p *parser) signed() (node, error) {
+	if operator, ok := p.peek("-", "+"); ok {
+		p.position++
+
+		operand, err := p.signed()
+		if err != nil {
+			return nil, err
+		}
+
+		if operator == "-" {
+			return &negateNode{operand: operand}, nil
+		}
+
+		return operand, nil
+	}
+
+	return p.postfix()
+}
+
+// filter parses the name of a filter and its arguments.
+func (p *parser) filter(operand node) (node, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, fmt.Errorf("expected the name of a filter")
+	}
+
+	if _, ok := filters[name]; !ok {
+		return nil, fmt.Errorf("unknown filter %s", name)
+	}
+
+	arguments, err := p.arguments()
+	if err != nil {
+		return nil, err
+	}
+
+	return &filterNode{name: name, operand: operand, arguments: arguments}, nil
+}
+
+// arguments parses the arguments between parentheses of a filter or a function, if they are written.
+func (p *parser) arguments() ([]node, error) {
+	if _, ok := p.peek("("); !ok {
+		return nil, nil
+	}
+	p.position++
+
+	var arguments []node
+	for {
+		if _, ok := p.peek(")"); ok {
+			p.position++
+			return arguments, nil
+		}
+
+		if len(arguments) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		argument, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+}
+
+// postfix parses the attributes and the subscripts of a primary, like "params.BED" or "printer['heater_bed']".
+func (p *parser) postfix() (node, error) {
+	operand, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek(".", "[")
+		if !ok {
+			return operand, nil
+		}
+		p.position++
+
+		if operator == "." {
+			name, err := p.name()
+			if err != nil {
+				return nil, fmt.Errorf("expected the name of an attribute")
+			}
+			operand = &itemNode{operand: operand, key: &literalNode{value: name}}
+			continue
+		}
+
+		key, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		operand = &itemNode{operand: operand, key: key}
+	}
+}
+
+// primary parses the literals, the names, the functions, the lists and the expressions between parentheses.
+func (p *parser) primary() (node, error) {
+	if p.position >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+
+	t := p.tokens[p.position]
+	p.position++
+
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenName:
+		switch t.text {
+		case "True", "true":
+			return &literalNode{value: true}, nil
+		case "False", "false":
+			return &literalNode{value: false}, nil
+		case "None", "none":
+			return &literalNode{value: nil}, nil
+		case "and", "or", "not":
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+
+		if _, ok := p.peek("("); ok {
+			if _, ok := functions[t.text]; !ok {
+				return nil, fmt.Errorf("unknown function %s", t.text)
+			}
+
+			arguments, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: t.text, arguments: arguments}, nil
+		}
+
+		return &nameNode{name: t.text}, nil
+	}
+
+	switch t.text {
+	case "(":
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case "[":
+		var items []node
+		for {
+			if _, ok := p.peek("]"); ok {
+				p.position++
+				return &listNode{items: items}, nil
+			}
+
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+
+			item, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+//#endregion
+//#region nodes
+
+// node is a node of the tree of an expression.
+type node interface {
+	// evaluate returns the value of the node, reading the names from the scope
+	evaluate(s *scope) (interface{}, error)
+}
+
+// literalNode is a number, a string, a boolean or None.
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) evaluate(s *scope) (interface{}, error) {
+	return n.value, nil
+}
+
+// nameNode is a variable, like "params".
+type nameNode struct {
+	name string
+}
+
+func (n *nameNode) evaluate(s *scope) (interface{}, error) {
+	if value, ok := s.lookup(n.name); ok {
+		return value, nil
+	}
+
+	return undefined{name: n.name}, nil
+}
+
+// listNode is a list of values, like "[1, 2]".
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) evaluate(s *scope) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.evaluate(s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// itemNode is an attribute or a subscript of a value, like "params.BED" or "position[0]".
+type itemNode struct {
+	operand node
+	key     node
+}
+
+func (n *itemNode) evaluate(s *scope) (interface{}, error) {
+	operand, err := n.operand.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := n.key.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := operand.(type) {
+	case undefined:
+		return nil, fmt.Errorf("%s is undefined", v.name)
+	case map[string]interface{}:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid key %s of a dict", FormatValue(key))
+		}
+		if value, ok := v[name]; ok {
+			return value, nil
+		}
+		return undefined{name: name}, nil
+	case []interface{}:
+		index, ok := key.(int)
+		if !ok {
+			return nil, fmt.Errorf("invalid index %s of a list", FormatValue(key))
+		}
+		if index < 0 {
+			index += len(v)
+		}
+		if index < 0 || index >= len(v) {
+			return nil, fmt.Errorf("index %s out of range", FormatValue(key))
+		}
+		return v[index], nil
+	}
+
+	return nil, fmt.Errorf("%s hasn't the item %s", describe(operand), FormatValue(key))
+}
+
+// negateNode is the negative of a number.
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) evaluate(s *scope) (interface{}, error) {
+	operand, err := n.operand.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := operand.(type) {
+	case int:
+		return -v, nil
+	case float64:
+		return -v, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operand of -: %s", describe(operand))
+}
+
+// notNode is the negation of a condition.
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) evaluate(s *scope) (interface{}, error) {
+	operand, err := n.operand.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return !truthy(operand), nil
+}
+
+// logicalNode is "and" or "or", that return one of their operands like Python and only evaluate the right one if it is required.
+type logicalNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *logicalNode) evaluate(s *scope) (interface{}, error) {
+	left, err := n.left.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(left) == (n.operator == "or") {
+		return left, nil
+	}
+
+	return n.right.evaluate(s)
+}
+
+// binaryNode is an arithmetic operation, a comparison or a concatenation.
+type binaryNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *binaryNode) evaluate(s *scope) (interface{}, error) {
+	left, err := n.left.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "~":
+		return FormatValue(left) + FormatValue(right), nil
+	case "==", "!=":
+		return equal(left, right) == (n.operator == "=="), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.operator, left, right)
+	}
+
+	return arithmetic(n.operator, left, right)
+}
+
+// equal returns if two values are equal, the ints and the floats are compared by their values.
+func equal(left interface{}, right interface{}) bool {
+	if l, ok := number(left); ok {
+		if r, ok := number(right); ok {
+			return l == r
+		}
+		return false
+	}
+
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case nil:
+		return right == nil
+	case undefined:
+		_, ok := right.(undefined)
+		return ok
+	}
+
+	return false
+}
+
+// compare compares two numbers or two strings.
+func compare(operator string, left interface{}, right interface{}) (bool, error) {
+	var order int
+
+	l, leftNumber := number(left)
+	r, rightNumber := number(right)
+	ls, leftString := left.(string)
+	rs, rightString := right.(string)
+
+	switch {
+	case leftNumber && rightNumber:
+		if l < r {
+			order = -1
+		} else if l > r {
+			order = 1
+		}
+	case leftString && rightString:
+		order = strings.Compare(ls, rs)
+	default:
+		return false, fmt.Errorf("unsupported operands of %s: %s and %s", operator, describe(left), describe(right))
+	}
+
+	switch operator {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	}
+
+	return order >= 0, nil
+}
+
+// arithmetic applies an arithmetic operator, the result is an int if both operands are ints, except in the division.
+// The strings can be added and the lists can be added and multiplied, like Python.
+func arithmetic(operator string, left interface{}, right interface{}) (interface{}, error) {
+	if operator == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+		if l, ok := left.([]interface{}); ok {
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	}
+
+	l, leftNumber := number(left)
+	r, rightNumber := number(right)
+	if !leftNumber || !rightNumber {
+		return nil, fmt.Errorf("unsupported operands of %s: %s and %s", operator, describe(left), describe(right))
+	}
+
+	li, leftInt := left.(int)
+	ri, rightInt := right.(int)
+	integers := leftInt && rightInt
+
+	if (operator == "/" || operator == "//" || operator == "%") && r == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	switch operator {
+	case "+":
+		if integers {
+			return li + ri, nil
+		}
+		return l + r, nil
+	case "-":
+		if integers {
+			return li - ri, nil
+		}
+		return l - r, nil
+	case "*":
+		if integers {
+			return li * ri, nil
+		}
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "//":
+		if integers {
+			return int(math.Floor(l / r)), nil
+		}
+		return math.Floor(l / r), nil
+	case "%":
+		remainder := l - r*math.Floor(l/r)
+		if integers {
+			return int(remainder), nil
+		}
+		return remainder, nil
+	case "**":
+		if integers && ri >= 0 {
+			return int(math.Pow(l, r)), nil
+		}
+		return math.Pow(l, r), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", operator)
+}
+
+// filterNode is a filter applied to a value, like "params.BED|default(60)|float".
+type filterNode struct {
+	name      string
+	operand   node
+	arguments []node
+}
+
+func (n *filterNode) evaluate(s *scope) (interface{}, error) {
+	operand, err := n.operand.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments := make([]interface{}, len(n.arguments))
+	for i, argument := range n.arguments {
+		if arguments[i], err = argument.evaluate(s); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := filters[n.name](operand, arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply filter %s: %w", n.name, err)
+	}
+
+	return value, nil
+}
+
+// callNode is a function, like "range(3)".
+type callNode struct {
+	name      string
+	arguments []node
+}
+
+func (n *callNode) evaluate(s *scope) (interface{}, error) {
+	arguments := make([]interface{}, len(n.arguments))
+	for i, argument := range n.arguments {
+		value, err := argument.evaluate(s)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = value
+	}
+
+	value, err := functions[n.name](arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", n.name, err)
+	}
+
+	return value, nil
+}
+
+//#endregion
+//#region filters and functions
+
+// filters are the filters of Jinja supported, indexed by their names.
+var filters = map[string]func(value interface{}, arguments []interface{}) (interface{}, error){
+	"default": defaultFilter,
+	"d":       defaultFilter,
+	"float":   floatFilter,
+	"int":     intFilter,
+	"round":   roundFilter,
+	"abs": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case int:
+			if v < 0 {
+				return -v, nil
+			}
+			return v, nil
+		case float64:
+			return math.Abs(v), nil
+		}
+		return nil, fmt.Errorf("unsupported operand %s", describe(value))
+	},
+	"string": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		return FormatValue(value), nil
+	},
+	"upper": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		return strings.ToUpper(FormatValue(value)), nil
+	},
+	"lower": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		return strings.ToLower(FormatValue(value)), nil
+	},
+	"length": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case string:
+			return len(v), nil
+		case []interface{}:
+			return len(v), nil
+		case map[string]interface{}:
+			return len(v), nil
+		}
+		return nil, fmt.Errorf("%s hasn't length", describe(value))
+	},
+	"min": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		return extreme(value, "<")
+	},
+	"max": func(value interface{}, arguments []interface{}) (interface{}, error) {
+		return extreme(value, ">")
+	},
+}
+
+// functions are the global functions of Jinja supported, indexed by their names.
+var functions = map[string]func(arguments []interface{}) (interface{}, error){
+	"range": rangeFunction,
+}
+
+// defaultFilter returns the first argument if the value is undefined, or if it is false and the second argument is true.
+func defaultFilter(value interface{}, arguments []interface{}) (interface{}, error) {
+	var fallback interface{} = ""
+	if len(arguments) > 0 {
+		fallback = arguments[0]
+	}
+
+	if _, ok := value.(undefined); ok {
+		return fallback, nil
+	}
+
+	if len(arguments) > 1 && truthy(arguments[1]) && !truthy(value) {
+		return fallback, nil
+	}
+
+	return value, nil
+}
+
+// floatFilter converts a value to a float, or returns the first argument, 0.0 by default, if it can't be converted.
+func floatFilter(value interface{}, arguments []interface{}) (interface{}, error) {
+	var fallback interface{} = 0.0
+	if len(arguments) > 0 {
+		fallback = arguments[0]
+	}
+
+	if n, ok := number(value); ok {
+		return n, nil
+	}
+
+	if s, ok := value.(string); ok {
+		if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return n, nil
+		}
+	}
+
+	return fallback, nil
+}
+
+// intFilter converts a value to an int, truncating the floats, or returns the first argument, 0 by default, if it can't be converted.
+func intFilter(value interface{}, arguments []interface{}) (interface{}, error) {
+	var fallback interface{} = 0
+	if len(arguments) > 0 {
+		fallback = arguments[0]
+	}
+
+	if s, ok := value.(string); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return n, nil
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return int(n), nil
+		}
+		return fallback, nil
+	}
+
+	if n, ok := number(value); ok {
+		return int(n), nil
+	}
+
+	return fallback, nil
+}
+
+// roundFilter rounds a number to the precision of the first argument, 0 by default, with the method of the second argument:
+// "common", the default, "ceil" or "floor". The result is always a float, like Jinja.
+func roundFilter(value interface{}, arguments []interface{}) (interface{}, error) {
+	n, ok := number(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported operand %s", describe(value))
+	}
+
+	precision := 0
+	if len(arguments) > 0 {
+		if precision, ok = arguments[0].(int); !ok {
+			return nil, fmt.Errorf("the precision must be an int: %s", FormatValue(arguments[0]))
+		}
+	}
+
+	method := "common"
+	if len(arguments) > 1 {
+		method = FormatValue(arguments[1])
+	}
+
+	factor := math.Pow(10, float64(precision))
+
+	switch method {
+	case "common":
+		return math.Round(n*factor) / factor, nil
+	case "ceil":
+		return math.Ceil(n*factor) / factor, nil
+	case "floor":
+		return math.Floor(n*factor) / factor, nil
+	}
+
+	return nil, fmt.Errorf("unknown method %s", method)
+}
+
+// extreme returns the lowest or the highest number of a list.
+func extreme(value interface{}, operator string) (interface{}, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("the operand must be a list not empty")
+	}
+
+	result := items[0]
+	for _, item := range items[1:] {
+		better, err := compare(operator, item, result)
+		if err != nil {
+			return nil, err
+		}
+		if better {
+			result = item
+		}
+	}
+
+	return result, nil
+}
+
+// rangeFunction returns the ints from the start, 0 by default, to the stop, excluded, with a step, 1 by default, like Python.
+func rangeFunction(arguments []interface{}) (interface{}, error) {
+	if len(arguments) == 0 || len(arguments) > 3 {
+		return nil, fmt.Errorf("it requires between 1 and 3 arguments, got %d", len(arguments))
+	}
+
+	bounds := make([]int, len(arguments))
+	for i, argument := range arguments {
+		n, ok := argument.(int)
+		if !ok {
+			return nil, fmt.Errorf("the arguments must be ints: %s", FormatValue(argument))
+		}
+		bounds[i] = n
+	}
+
+	start, stop, step := 0, bounds[0], 1
+	if len(bounds) > 1 {
+		start, stop = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+
+	if step == 0 {
+		return nil, fmt.Errorf("the step can't be zero")
+	}
+
+	var values []interface{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		if len(values) == MAX_RANGE {
+			return nil, fmt.Errorf("it exceeds %d values", MAX_RANGE)
+		}
+		values = append(values, i)
+	}
+
+	return values, nil
+}
+
+//#endregion
@@ -0,0 +1,203 @@
+// klipper package expands the macros of Klipper, the commands defined by the user with a name and a template of gcode,
+// like "START_PRINT BED=60 EXTRUDER=210", so their gcode can be previewed on the host or sent to a firmware without macros.
+//
+// The macros are defined in code, with the defaults of their parameters, or read from the sections gcode_macro of the
+// configuration of Klipper, and an Expander replaces their calls in a document by the gcode rendered with the arguments
+// of each call:
+//
+//	macros, err := klipper.ParseConfig(config)
+//	expander, err := klipper.NewExpander(func(config klipper.ExpanderConfigurer) error {
+//		return config.SetMacros(macros...)
+//	})
+//	expanded, err := expander.Expand(doc)
+//
+// The templates are written with the subset of Jinja that the macros use, see ParseTemplate.
+package klipper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MACRO_SECTION is the prefix of the sections of the configuration of Klipper that define a macro.
+const MACRO_SECTION = "gcode_macro"
+
+//#region macro struct
+
+// Macro is a command of Klipper defined by the user, like "START_PRINT".
+type Macro struct {
+	// Name is the name of the command, in upper case, like "START_PRINT" or "M600".
+	Name string
+
+	// Description is the description of the command, optional.
+	Description string
+
+	// Parameters are the defaults of the parameters, indexed by their names in upper case, like {"BED": "60"}.
+	// They are read as "params.BED" when the call doesn't write them.
+	Parameters map[string]string
+
+	// Variables are the variables of the macro, indexed by their names, like {"offset": 0.1}, read as "offset".
+	Variables map[string]interface{}
+
+	// Gcode is the template of the gcode of the command, like "M140 S{params.BED|float}".
+	Gcode string
+}
+
+//#endregion
+//#region config
+
+// ParseConfig reads the macros of the sections gcode_macro of a configuration of Klipper, like printer.cfg, in their order.
+// The other sections are ignored.
+//
+// The options description, gcode and the ones with the prefix "variable_" are read, the values of the variables are literals,
+// like "0.1", "'PLA'" or "[1, 2]". The lines of the values are dedented and the lines that start with "#" or ";" are ignored.
+//
+// It returns an error if the source can't be read, the configuration is malformed or some variable isn't a literal.
+func ParseConfig(source io.Reader) ([]Macro, error) {
+	reader := bufio.NewReader(source)
+
+	var macros []Macro
+	var current *Macro
+	var option string
+	var value []string
+
+	flush := func() error {
+		if current == nil || option == "" {
+			return nil
+		}
+
+		if err := current.set(option, strings.Join(value, "\n")); err != nil {
+			return fmt.Errorf("failed to parse macro %s: %w", current.Name, err)
+		}
+
+		option, value = "", nil
+
+		return nil
+	}
+
+	// the lines are read without a limit of length
+	for number := 1; ; number++ {
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		if text == "" && err == io.EOF {
+			break
+		}
+
+		line := strings.TrimRight(text, " \t\r\n")
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';' {
+			continue
+		}
+
+		// the indented lines continue the value of the last option
+		if line[0] == ' ' || line[0] == '\t' {
+			if option == "" {
+				if current == nil {
+					continue
+				}
+				return nil, fmt.Errorf("failed to parse config, line %d is indented outside an option: %q", number, line)
+			}
+			value = append(value, trimmed)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			if !strings.HasSuffix(trimmed, "]") {
+				return nil, fmt.Errorf("failed to parse config, invalid section at line %d: %q", number, line)
+			}
+
+			current = nil
+			kind, name, _ := strings.Cut(strings.TrimSpace(trimmed[1:len(trimmed)-1]), " ")
+			if kind != MACRO_SECTION {
+				continue
+			}
+
+			name = strings.ToUpper(strings.TrimSpace(name))
+			if name == "" {
+				return nil, fmt.Errorf("failed to parse config, the macro at line %d hasn't name", number)
+			}
+
+			macros = append(macros, Macro{Name: name})
+			current = &macros[len(macros)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		separator := strings.IndexAny(trimmed, ":=")
+		if separator < 0 {
+			return nil, fmt.Errorf("failed to parse config, invalid option at line %d: %q", number, line)
+		}
+
+		option = strings.ToLower(strings.TrimSpace(trimmed[:separator]))
+		value = nil
+		if first := strings.TrimSpace(trimmed[separator+1:]); first != "" {
+			value = append(value, first)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return macros, nil
+}
+
+// set assigns an option of the section of the macro.
+func (m *Macro) set(option string, value string) error {
+	switch {
+	case option == "description":
+		m.Description = value
+	case option == "gcode":
+		m.Gcode = value
+	case strings.HasPrefix(option, "variable_"):
+		name := strings.TrimPrefix(option, "variable_")
+		if !validName(name) {
+			return fmt.Errorf("invalid variable %q", name)
+		}
+
+		literal, err := parseLiteral(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of variable %s: %w", name, err)
+		}
+
+		if m.Variables == nil {
+			m.Variables = map[string]interface{}{}
+		}
+		m.Variables[name] = literal
+	}
+
+	return nil
+}
+
+// parseLiteral returns the value of a literal, like "0.1", "'PLA'", "True" or "[1, 2]".
+func parseLiteral(text string) (interface{}, error) {
+	expression, err := parseExpression(text)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := expression.evaluate(&scope{})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := value.(undefined); ok {
+		return nil, fmt.Errorf("%q isn't a literal", text)
+	}
+
+	return value, nil
+}
+
+//#endregion
@@ -0,0 +1,86 @@
+package klipper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	config := `# printer.cfg
+[printer]
+kinematics: cartesian
+
+[gcode_macro start_print]
+description: Heat and home
+variable_offset: 0.1
+variable_material = 'PLA'
+variable_park: [10, 20]
+gcode:
+    # heat the bed first
+    M190 S{params.BED|default(60)}
+    {% if params.HOME|default(1)|int %}
+    G28
+    {% endif %}
+
+[gcode_macro END_PRINT]
+gcode: M104 S0
+
+[extruder]
+nozzle_diameter: 0.4
+`
+
+	macros, err := ParseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	want := []Macro{
+		{
+			Name:        "START_PRINT",
+			Description: "Heat and home",
+			Variables:   map[string]interface{}{"offset": 0.1, "material": "PLA", "park": []interface{}{10, 20}},
+			Gcode:       "M190 S{params.BED|default(60)}\n{% if params.HOME|default(1)|int %}\nG28\n{% endif %}",
+		},
+		{
+			Name:  "END_PRINT",
+			Gcode: "M104 S0",
+		},
+	}
+
+	if !reflect.DeepEqual(macros, want) {
+		t.Errorf("got macros %#v, want %#v", macros, want)
+	}
+}
+
+func TestParseConfig_longLine(t *testing.T) {
+	gcode := "M117 " + strings.Repeat("x", 70000)
+
+	macros, err := ParseConfig(strings.NewReader("[gcode_macro MESSAGE]\ngcode:\n    " + gcode + "\n"))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if len(macros) != 1 || macros[0].Gcode != gcode {
+		t.Errorf("got %d macros, want the macro MESSAGE with the long line", len(macros))
+	}
+}
+
+func TestParseConfig_invalid(t *testing.T) {
+
+	cases := map[string]string{
+		"invalid section":  "[gcode_macro START\ngcode: G28\n",
+		"without name":     "[gcode_macro]\ngcode: G28\n",
+		"invalid option":   "[gcode_macro START]\ngcode\n",
+		"invalid variable": "[gcode_macro START]\nvariable_material: PLA\n",
+		"indented":         "[gcode_macro START]\n  G28\n",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseConfig(strings.NewReader(source)); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package klipper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region scope
+
+// scope stores the variables of a template, the loops create a child scope, so the variables set inside them are forgotten at their end.
+type scope struct {
+	variables map[string]interface{}
+	parent    *scope
+}
+
+// lookup returns the value of a variable, searching from the innermost scope.
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for current := s; current != nil; current = current.parent {
+		if value, ok := current.variables[name]; ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+//#endregion
+//#region template struct
+
+// Template is the gcode of a macro of Klipper, written with the subset of Jinja that the macros use, like
+// "M140 S{params.BED|default(60)|float}".
+type Template struct {
+	// source text of the template
+	source string
+
+	// nodes of the first level
+	nodes []templateNode
+}
+
+// String returns the source of the template.
+func (t *Template) String() string {
+	return t.source
+}
+
+// ParseTemplate parses a template of Klipper.
+//
+// The expressions are written between braces, like "{params.BED}", and the statements between "{%" and "%}": "set", to assign
+// a variable, "if", "elif", "else" and "endif", and "for" and "endfor", to iterate a list, like "{% for i in range(3) %}".
+// The comments are written between "{#" and "#}".
+//
+// The expressions accept the operators of Jinja, from the lowest precedence to the highest: "or"; "and"; "not"; the comparisons
+// "==", "!=", "<", "<=", ">" and ">="; "+" and "-"; "~", that joins the values as strings; "*", "/", "//" and "%"; "**";
+// the signs; the filters, like "|float"; and the attributes and the subscripts, like "params.BED" or "position[0]".
+// The operands are the ints, the floats, the strings between quotes, True, False, None, the lists between brackets,
+// the variables and the function range. The filters are default or d, float, int, round, abs, string, upper, lower, length, min and max.
+//
+// It returns an error if some expression or statement is invalid, or the blocks aren't closed.
+func ParseTemplate(text string) (*Template, error) {
+	p := &templateParser{text: text}
+
+	nodes, end, err := p.parse()
+	if err == nil && end != "" {
+		err = fmt.Errorf("unexpected %s", end)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return &Template{source: text, nodes: nodes}, nil
+}
+
+// Render returns the text of the template, with the variables received, like "params".
+// The values of the variables are ints, floats, strings, booleans, nil, and slices and maps of them.
+//
+// It returns an error if some expression can't be evaluated, like an undefined variable operated.
+func (t *Template) Render(variables map[string]interface{}) (string, error) {
+	s := &scope{variables: map[string]interface{}{}}
+	for name, value := range variables {
+		s.variables[name] = normalize(value)
+	}
+
+	var out strings.Builder
+	if err := renderNodes(t.nodes, s, &out); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+//#endregion
+//#region nodes
+
+// templateNode is a node of the tree of a template.
+type templateNode interface {
+	// render writes the output of the node
+	render(s *scope, out *strings.Builder) error
+}
+
+// renderNodes renders the nodes in order.
+func renderNodes(nodes []templateNode, s *scope, out *strings.Builder) error {
+	for _, n := range nodes {
+		if err := n.render(s, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// textNode is a text written as it is.
+type textNode struct {
+	text string
+}
+
+func (n *textNode) render(s *scope, out *strings.Builder) error {
+	out.WriteString(n.text)
+
+	return nil
+}
+
+// outputNode is an expression between braces, written with its value.
+type outputNode struct {
+	source     string
+	expression node
+}
+
+func (n *outputNode) render(s *scope, out *strings.Builder) error {
+	value, err := n.expression.evaluate(s)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate {%s}: %w", n.source, err)
+	}
+
+	out.WriteString(FormatValue(value))
+
+	return nil
+}
+
+// setNode assigns a variable.
+type setNode struct {
+	name       string
+	source     string
+	expression node
+}
+
+func (n *setNode) render(s *scope, out *strings.Builder) error {
+	value, err := n.expression.evaluate(s)
+	if err != nil {
+		return fmt.Errorf("failed to set %s = %s: %w", n.name, n.source, err)
+	}
+
+	s.variables[n.name] = value
+
+	return nil
+}
+
+// branch is a condition of an if or an elif, with its body.
+type branch struct {
+	source    string
+	condition node
+	body      []templateNode
+}
+
+// ifNode renders the body of the first branch whose condition is true, or the body of else.
+type ifNode struct {
+	branches  []branch
+	otherwise []templateNode
+}
+
+func (n *ifNode) render(s *scope, out *strings.Builder) error {
+	for _, b := range n.branches {
+		value, err := b.condition.evaluate(s)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate condition %s: %w", b.source, err)
+		}
+
+		if truthy(value) {
+			return renderNodes(b.body, s, out)
+		}
+	}
+
+	return renderNodes(n.otherwise, s, out)
+}
+
+// forNode renders its body for each item of a list, with the item assigned to a variable.
+type forNode struct {
+	name   string
+	source string
+	items  node
+	body   []templateNode
+}
+
+func (n *forNode) render(s *scope, out *strings.Builder) error {
+	value, err := n.items.evaluate(s)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate %s: %w", n.source, err)
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		for _, c := range v {
+			items = append(items, string(c))
+		}
+	case undefined:
+	default:
+		return fmt.Errorf("failed to iterate %s, it is %s", n.source, describe(value))
+	}
+
+	for _, item := range items {
+		child := &scope{variables: map[string]interface{}{n.name: item}, parent: s}
+		if err := renderNodes(n.body, child, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region parser
+
+// templateParser splits the text of a template in nodes.
+type templateParser struct {
+	text     string
+	position int
+}
+
+// parse parses the nodes until the end of the text or a statement that closes a block, like "endif", returned with its expression.
+func (p *templateParser) parse() ([]templateNode, string, error) {
+	var nodes []templateNode
+
+	for p.position < len(p.text) {
+		start := strings.IndexByte(p.text[p.position:], '{')
+		if start < 0 {
+			nodes = append(nodes, &textNode{text: p.text[p.position:]})
+			p.position = len(p.text)
+			break
+		}
+
+		if start > 0 {
+			nodes = append(nodes, &textNode{text: p.text[p.position : p.position+start]})
+		}
+		p.position += start
+
+		switch {
+		case strings.HasPrefix(p.text[p.position:], "{#"):
+			end := strings.Index(p.text[p.position:], "#}")
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated comment")
+			}
+			p.position += end + 2
+		case strings.HasPrefix(p.text[p.position:], "{%"):
+			statement, err := p.tag("%}")
+			if err != nil {
+				return nil, "", err
+			}
+			p.trimLine()
+
+			keyword, rest := splitKeyword(statement)
+			switch keyword {
+			case "endif", "endfor", "elif", "else":
+				return nodes, statement, nil
+			}
+
+			n, err := p.statement(keyword, rest)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, n)
+		default:
+			source, err := p.tag("}")
+			if err != nil {
+				return nil, "", err
+			}
+
+			expression, err := parseExpression(source)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, &outputNode{source: source, expression: expression})
+		}
+	}
+
+	return nodes, "", nil
+}
+
+// tag returns the content of the tag that starts at the position, trimmed, and moves the position after its end.
+// The delimiters inside the strings are ignored.
+func (p *templateParser) tag(end string) (string, error) {
+	open := 1
+	if end == "%}" {
+		open = 2
+	}
+
+	var quote byte
+	for i := p.position + open; i < len(p.text); i++ {
+		c := p.text[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(p.text[i:], end):
+			content := strings.TrimSpace(p.text[p.position+open : i])
+			if end == "%}" {
+				// the signs of the whitespace control, like "{%- if x -%}", are ignored because the lines are trimmed
+				content = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(content, "-"), "-"))
+			}
+			p.position = i + len(end)
+			return content, nil
+		}
+	}
+
+	return "", fmt.Errorf("unterminated tag %s", strings.SplitN(p.text[p.position:], "\n", 2)[0])
+}
+
+// trimLine skips the line ending after a statement, like Klipper does with trim_blocks.
+func (p *templateParser) trimLine() {
+	if strings.HasPrefix(p.text[p.position:], "\r\n") {
+		p.position += 2
+	} else if strings.HasPrefix(p.text[p.position:], "\n") {
+		p.position++
+	}
+}
+
+// statement parses a statement that isn't the end of a block.
+func (p *templateParser) statement(keyword string, rest string) (templateNode, error) {
+	switch keyword {
+	case "set":
+		name, source, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validName(name) {
+			return nil, fmt.Errorf("invalid statement set %s", rest)
+		}
+
+		expression, err := parseExpression(source)
+		if err != nil {
+			return nil, err
+		}
+		return &setNode{name: name, source: strings.TrimSpace(source), expression: expression}, nil
+	case "if":
+		return p.conditional(rest)
+	case "for":
+		name, source, ok := strings.Cut(rest, " in ")
+		name = strings.TrimSpace(name)
+		if !ok || !validName(name) {
+			return nil, fmt.Errorf("invalid statement for %s", rest)
+		}
+
+		items, err := parseExpression(source)
+		if err != nil {
+			return nil, err
+		}
+
+		body, end, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if end != "endfor" {
+			return nil, fmt.Errorf("expected endfor of for %s", rest)
+		}
+		return &forNode{name: name, source: strings.TrimSpace(source), items: items, body: body}, nil
+	}
+
+	return nil, fmt.Errorf("unknown statement %q", keyword)
+}
+
+// conditional parses the branches of an if until its endif.
+func (p *templateParser) conditional(source string) (templateNode, error) {
+	n := &ifNode{}
+
+	for {
+		condition, err := parseExpression(source)
+		if err != nil {
+			return nil, err
+		}
+
+		body, end, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		n.branches = append(n.branches, branch{source: source, condition: condition, body: body})
+
+		keyword, rest := splitKeyword(end)
+		switch keyword {
+		case "elif":
+			source = rest
+			continue
+		case "else":
+			if n.otherwise, end, err = p.parse(); err != nil {
+				return nil, err
+			}
+			if end != "endif" {
+				return nil, fmt.Errorf("expected endif after else")
+			}
+			return n, nil
+		case "endif":
+			return n, nil
+		}
+
+		return nil, fmt.Errorf("expected endif of if %s", n.branches[0].source)
+	}
+}
+
+// splitKeyword returns the first word of a statement and the rest of it.
+func splitKeyword(statement string) (string, string) {
+	keyword, rest, _ := strings.Cut(statement, " ")
+
+	return keyword, strings.TrimSpace(rest)
+}
+
+// validName returns true if the text is a valid name of a variable.
+func validName(name string) bool {
+	if name == "" || !isLetter(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		if !isLetter(name[i]) && !numeric.IsDigit(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//#endregion
@@ -0,0 +1,114 @@
+package klipper
+
+import (
+	"testing"
+)
+
+func TestTemplate_Render(t *testing.T) {
+
+	params := map[string]interface{}{"BED": "60", "EXTRUDER": "210.5", "MATERIAL": "PETG"}
+
+	cases := map[string]struct {
+		source string
+		want   string
+	}{
+		"text":                {"G28\nG1 Z10\n", "G28\nG1 Z10\n"},
+		"parameter":           {"M140 S{params.BED}", "M140 S60"},
+		"subscript":           {"M140 S{params['BED']}", "M140 S60"},
+		"float":               {"M104 S{params.EXTRUDER|float}", "M104 S210.5"},
+		"float of int":        {"M140 S{params.BED|float}", "M140 S60.0"},
+		"int":                 {"M104 S{params.EXTRUDER|int}", "M104 S210"},
+		"default":             {"M106 S{params.FAN|default(255)}", "M106 S255"},
+		"default not used":    {"M140 S{params.BED|default(0)}", "M140 S60"},
+		"undefined empty":     {"M117 {params.MESSAGE}", "M117 "},
+		"arithmetic":          {"{1 + 2 * 3} {(1 + 2) * 3} {7 / 2} {7 // 2} {7 % 3} {2 ** 3}", "7 9 3.5 3 1 8"},
+		"mixed numbers":       {"{params.BED|int + 0.5}", "60.5"},
+		"concatenation":       {"{'T' ~ 1 ~ params.MATERIAL|lower}", "T1petg"},
+		"comparison":          {"{params.BED|int > 50} {1 == 1.0} {'a' < 'b'} {not True}", "True True True False"},
+		"logical":             {"{0 or 'x'} {1 and 0}", "x 0"},
+		"round":               {"{3.14159|round(2)} {2.5|round} {1.21|round(1, 'ceil')}", "3.14 3.0 1.3"},
+		"sign and filter":     {"{-3|abs} {-2 ** 2}", "3 4"},
+		"list":                {"{[1, 2][1]} {[3, 1, 2]|min} {[3, 1, 2]|max} {[1, 2]|length}", "2 1 3 2"},
+		"set":                 {"{% set bed = params.BED|float %}\nM140 S{bed * 2}", "M140 S120.0"},
+		"if":                  {"{% if params.MATERIAL == 'PETG' %}\nM221 S95\n{% endif %}\nG28", "M221 S95\nG28"},
+		"elif":                {"{% if params.BED|int > 100 %}\nA\n{% elif params.BED|int > 50 %}\nB\n{% else %}\nC\n{% endif %}\n", "B\n"},
+		"else":                {"{% if params.FAN %}A{% else %}B{% endif %}", "B"},
+		"for":                 {"{% for i in range(3) %}\nT{i}\n{% endfor %}\n", "T0\nT1\nT2\n"},
+		"for range step":      {"{% for i in range(10, 0, -4) %}{i} {% endfor %}", "10 6 2 "},
+		"for scope":           {"{% set x = 1 %}{% for i in range(2) %}{% set x = 5 %}{% endfor %}{x}", "1"},
+		"comment":             {"G28 {# home #}", "G28 "},
+		"whitespace control":  {"{%- if True -%}\nA\n{%- endif -%}\n", "A\n"},
+		"string with brace":   {"M117 {'}'}", "M117 }"},
+		"length of params":    {"{params|length}", "3"},
+		"string filter upper": {"{params.MATERIAL|upper} {1.5|string}", "PETG 1.5"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			template, err := ParseTemplate(tc.source)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got, err := template.Render(map[string]interface{}{"params": params})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_invalid(t *testing.T) {
+
+	cases := map[string]string{
+		"unterminated expression": "M140 S{params.BED",
+		"unterminated statement":  "{% if True",
+		"unterminated comment":    "{# home",
+		"if without endif":        "{% if True %}A",
+		"endif without if":        "A{% endif %}",
+		"for without endfor":      "{% for i in range(3) %}A{% endif %}",
+		"unknown statement":       "{% macro x %}",
+		"invalid set":             "{% set 1x = 2 %}",
+		"unknown filter":          "{params.BED|reverse}",
+		"unknown function":        "{len(params)}",
+		"invalid expression":      "{1 +}",
+		"unterminated string":     "{'abc}",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseTemplate(source); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestTemplate_Render_errors(t *testing.T) {
+
+	cases := map[string]string{
+		"undefined operated":     "{params.BED + 1}",
+		"string and number":      "{'60' + 1}",
+		"division by zero":       "{1 / 0}",
+		"attribute of undefined": "{printer.toolhead.position}",
+		"index out of range":     "{[1, 2][2]}",
+		"range too long":         "{% for i in range(100000) %}{% endfor %}",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			template, err := ParseTemplate(source)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if _, err := template.Render(map[string]interface{}{"params": map[string]interface{}{}}); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,647 @@
+package macrob
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region value
+
+// decimals is the number of decimals at most of the numbers written, the resolution of the addresses in inches.
+const decimals = 4
+
+// Value is the value of a variable or an expression, a number or vacant, like the variable #0 or a variable never assigned.
+type Value struct {
+	// Number is the number of the value, zero if it is vacant.
+	Number float64
+
+	// Vacant is true if the value is vacant.
+	Vacant bool
+}
+
+// String returns the number formatted, or "vacant".
+func (v Value) String() string {
+	if v.Vacant {
+		return "vacant"
+	}
+
+	return numeric.FormatDecimals(v.Number, decimals)
+}
+
+// number returns the number of the value, a vacant value is zero in the arithmetic.
+func (v Value) number() float64 {
+	if v.Vacant {
+		return 0
+	}
+
+	return v.Number
+}
+
+//#endregion
+//#region expression struct
+
+// Expression is an expression of the custom macros B, like "#1 + SIN[#2]" or "#1 GT 10".
+type Expression struct {
+	// source text of the expression, trimmed
+	source string
+
+	// root of the tree of the expression
+	root node
+}
+
+// String returns the source of the expression.
+func (x *Expression) String() string {
+	return x.source
+}
+
+// ParseExpression parses an expression of the custom macros B. The keywords are case-insensitive.
+//
+// The operators are, from the highest precedence to the lowest: the functions, like "SIN[#1]"; "*", "/", "AND" and "MOD";
+// "+", "-", "OR" and "XOR"; and the comparisons "EQ", "NE", "GT", "GE", "LT" and "LE", like Fanuc, so the comparisons
+// combined by AND and OR must be enclosed in brackets, like "[#1 GT 0] AND [#2 LT 5]". The operands are numbers,
+// the variables, like "#100" or "#[#1 + 1]", and the expressions between brackets.
+//
+// The functions are SIN, COS, TAN, ASIN, ACOS and ATAN in degrees, with ATAN[y]/[x] and ATAN[y,x] of two arguments,
+// SQRT, ABS, ROUND, FIX and FUP, that round to the nearest, towards zero and away from zero, LN, EXP, POW[x,y],
+// BIN and BCD.
+//
+// It returns an error if the expression is invalid.
+func ParseExpression(text string) (*Expression, error) {
+	p := &parser{}
+	if err := p.tokenize(text); err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	root, err := p.comparison()
+	if err == nil && p.position < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.position].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	return &Expression{source: strings.TrimSpace(text), root: root}, nil
+}
+
+//#endregion
+//#region tokens
+
+// tokenKind identifies the kind of a token of an expression.
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenName
+	tokenOperator
+)
+
+// token is a token of an expression.
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+}
+
+// tokenize splits the text in tokens, the names in upper case.
+func (p *parser) tokenize(text string) error {
+	s := strings.ToUpper(text)
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case numeric.IsDigit(c) || c == '.':
+			start := i
+			for i < len(s) && (numeric.IsDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+
+			number, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", s[start:i])
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: s[start:i], number: number})
+		case c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(s) && s[i] >= 'A' && s[i] <= 'Z' {
+				i++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenName, text: s[start:i]})
+		case strings.IndexByte("#[]+-*/,", c) >= 0:
+			p.tokens = append(p.tokens, token{kind: tokenOperator, text: string(c)})
+			i++
+		default:
+			return fmt.Errorf("unexpected character %q", c)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region parser
+
+// parser is a recursive descent parser of the expressions.
+type parser struct {
+	tokens   []token
+	position int
+}
+
+// peek returns the next token if it is one of the operators or names received.
+func (p *parser) peek(texts ...string) (string, bool) {
+	if p.position >= len(p.tokens) || p.tokens[p.position].kind == tokenNumber {
+		return "", false
+	}
+
+	for _, text := range texts {
+		if p.tokens[p.position].text == text {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+// expect consumes the operator required or returns an error.
+func (p *parser) expect(operator string) error {
+	if _, ok := p.peek(operator); !ok {
+		if p.position >= len(p.tokens) {
+			return fmt.Errorf("expected %q at the end", operator)
+		}
+		return fmt.Errorf("expected %q instead of %q", operator, p.tokens[p.position].text)
+	}
+
+	p.position++
+
+	return nil
+}
+
+// comparison parses the comparisons, the lowest precedence.
+func (p *parser) comparison() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("EQ", "NE", "GT", "GE", "LT", "LE")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// additive parses "+", "-", "OR" and "XOR".
+func (p *parser) additive() (node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("+", "-", "OR", "XOR")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// multiplicative parses "*", "/", "AND" and "MOD".
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek("*", "/", "AND", "MOD")
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// unary parses the signs.
+func (p *parser) unary() (node, error) {
+	if operator, ok := p.peek("-", "+"); ok {
+		p.position++
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		if operator == "+" {
+			return operand, nil
+		}
+
+		return &negateNode{operand: operand}, nil
+	}
+
+	return p.primary()
+}
+
+// primary parses the numbers, the variables, the functions and the expressions between brackets.
+func (p *parser) primary() (node, error) {
+	if p.position >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+
+	t := p.tokens[p.position]
+	p.position++
+
+	switch {
+	case t.kind == tokenNumber:
+		return &numberNode{value: t.number}, nil
+	case t.kind == tokenName:
+		return p.call(t.text)
+	case t.text == "#":
+		index, err := p.variableIndex()
+		if err != nil {
+			return nil, err
+		}
+		return &variableNode{index: index}, nil
+	case t.text == "[":
+		return p.bracket()
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// variableIndex parses the number of a variable after "#", a number or an expression between brackets.
+func (p *parser) variableIndex() (node, error) {
+	if p.position < len(p.tokens) && p.tokens[p.position].kind == tokenNumber {
+		t := p.tokens[p.position]
+		p.position++
+
+		if t.number != math.Trunc(t.number) {
+			return nil, fmt.Errorf("invalid variable #%s", t.text)
+		}
+		return &numberNode{value: t.number}, nil
+	}
+
+	if err := p.expect("["); err != nil {
+		return nil, fmt.Errorf("expected the number of a variable after #")
+	}
+
+	return p.bracket()
+}
+
+// bracket parses an expression after "[" and its closing bracket.
+func (p *parser) bracket() (node, error) {
+	inner, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+
+	return inner, nil
+}
+
+// call parses the arguments of a function between brackets.
+func (p *parser) call(name string) (node, error) {
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+
+	var arguments []node
+	for {
+		argument, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+
+		if _, ok := p.peek(","); !ok {
+			break
+		}
+		p.position++
+	}
+
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+
+	// the arc tangent of two arguments is also written as ATAN[y]/[x]
+	if name == "ATAN" && len(arguments) == 1 && p.position+1 < len(p.tokens) && p.tokens[p.position].text == "/" && p.tokens[p.position+1].text == "[" {
+		p.position += 2
+
+		x, err := p.bracket()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, x)
+	}
+
+	if len(arguments) < f.min || len(arguments) > f.max {
+		return nil, fmt.Errorf("invalid number of arguments of %s: %d", name, len(arguments))
+	}
+
+	return &callNode{name: name, arguments: arguments}, nil
+}
+
+//#endregion
+//#region nodes
+
+// node is a node of the tree of an expression.
+type node interface {
+	// evaluate returns the value of the node, reading the variables from the interpreter
+	evaluate(r variableReader) (Value, error)
+}
+
+// variableReader reads the variables of an interpreter.
+type variableReader interface {
+	read(number int) (Value, error)
+}
+
+// numberNode is a number.
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) evaluate(r variableReader) (Value, error) {
+	return Value{Number: n.value}, nil
+}
+
+// variableNode is a variable, its index is the number of the variable.
+type variableNode struct {
+	index node
+}
+
+func (n *variableNode) evaluate(r variableReader) (Value, error) {
+	number, err := variableNumber(n.index, r)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return r.read(number)
+}
+
+// negateNode changes the sign of its operand.
+type negateNode struct {
+	operand node
+}
+
+func (n *negateNode) evaluate(r variableReader) (Value, error) {
+	value, err := n.operand.evaluate(r)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// the sign of a vacant value is vacant, like "X-#1" is omitted
+	if value.Vacant {
+		return value, nil
+	}
+
+	return Value{Number: -value.Number}, nil
+}
+
+// binaryNode is a binary operator applied to two operands.
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+func (n *binaryNode) evaluate(r variableReader) (Value, error) {
+	left, err := n.left.evaluate(r)
+	if err != nil {
+		return Value{}, err
+	}
+
+	right, err := n.right.evaluate(r)
+	if err != nil {
+		return Value{}, err
+	}
+
+	l, rr := left.number(), right.number()
+
+	switch n.operator {
+	case "EQ", "NE":
+		// a vacant value only equals another vacant value
+		equal := left.Vacant == right.Vacant && l == rr
+		return boolean(equal == (n.operator == "EQ")), nil
+	case "GT":
+		return boolean(l > rr), nil
+	case "GE":
+		return boolean(l >= rr), nil
+	case "LT":
+		return boolean(l < rr), nil
+	case "LE":
+		return boolean(l <= rr), nil
+	case "+":
+		return Value{Number: l + rr}, nil
+	case "-":
+		return Value{Number: l - rr}, nil
+	case "*":
+		return Value{Number: l * rr}, nil
+	case "/":
+		if rr == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return Value{Number: l / rr}, nil
+	case "MOD":
+		if rr == 0 {
+			return Value{}, fmt.Errorf("modulo by zero")
+		}
+		return Value{Number: math.Mod(l, rr)}, nil
+	}
+
+	// the logical operators are bitwise on the integers, so they also combine the comparisons, that are 1 or 0
+	a, b := int64(math.Round(l)), int64(math.Round(rr))
+	switch n.operator {
+	case "AND":
+		return Value{Number: float64(a & b)}, nil
+	case "OR":
+		return Value{Number: float64(a | b)}, nil
+	}
+
+	return Value{Number: float64(a ^ b)}, nil
+}
+
+// callNode is a call of a function.
+type callNode struct {
+	name      string
+	arguments []node
+}
+
+func (n *callNode) evaluate(r variableReader) (Value, error) {
+	arguments := make([]float64, 0, len(n.arguments))
+	for _, argument := range n.arguments {
+		value, err := argument.evaluate(r)
+		if err != nil {
+			return Value{}, err
+		}
+		arguments = append(arguments, value.number())
+	}
+
+	result, err := functions[n.name].call(arguments)
+	if err != nil {
+		return Value{}, fmt.Errorf("failed to call %s: %w", n.name, err)
+	}
+
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return Value{}, fmt.Errorf("failed to call %s, the result isn't a number", n.name)
+	}
+
+	return Value{Number: result}, nil
+}
+
+//#endregion
+//#region functions
+
+// function is a function of the expressions with its number of arguments.
+type function struct {
+	min, max int
+	call     func(arguments []float64) (float64, error)
+}
+
+// unaryFunction returns a function of one argument.
+func unaryFunction(f func(float64) float64) function {
+	return function{min: 1, max: 1, call: func(arguments []float64) (float64, error) {
+		return f(arguments[0]), nil
+	}}
+}
+
+// degrees converts radians to degrees.
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// radians converts degrees to radians.
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// functions are the functions of the expressions indexed by name.
+var functions = map[string]function{
+	"SIN":  unaryFunction(func(x float64) float64 { return math.Sin(radians(x)) }),
+	"COS":  unaryFunction(func(x float64) float64 { return math.Cos(radians(x)) }),
+	"TAN":  unaryFunction(func(x float64) float64 { return math.Tan(radians(x)) }),
+	"ASIN": unaryFunction(func(x float64) float64 { return degrees(math.Asin(x)) }),
+	"ACOS": unaryFunction(func(x float64) float64 { return degrees(math.Acos(x)) }),
+	"ATAN": {min: 1, max: 2, call: func(arguments []float64) (float64, error) {
+		if len(arguments) == 1 {
+			return degrees(math.Atan(arguments[0])), nil
+		}
+
+		// Fanuc returns the angle from 0 to 360
+		angle := degrees(math.Atan2(arguments[0], arguments[1]))
+		if angle < 0 {
+			angle += 360
+		}
+		return angle, nil
+	}},
+	"SQRT":  unaryFunction(math.Sqrt),
+	"ABS":   unaryFunction(math.Abs),
+	"ROUND": unaryFunction(math.Round),
+	"FIX":   unaryFunction(math.Trunc),
+	"FUP": unaryFunction(func(x float64) float64 {
+		if x < 0 {
+			return math.Floor(x)
+		}
+		return math.Ceil(x)
+	}),
+	"LN":  unaryFunction(math.Log),
+	"EXP": unaryFunction(math.Exp),
+	"POW": {min: 2, max: 2, call: func(arguments []float64) (float64, error) {
+		return math.Pow(arguments[0], arguments[1]), nil
+	}},
+	"BIN": {min: 1, max: 1, call: func(arguments []float64) (float64, error) {
+		bcd := int64(arguments[0])
+		if bcd < 0 || float64(bcd) != arguments[0] {
+			return 0, fmt.Errorf("invalid BCD %v", arguments[0])
+		}
+
+		result, scale := int64(0), int64(1)
+		for ; bcd > 0; bcd >>= 4 {
+			digit := bcd & 0xF
+			if digit > 9 {
+				return 0, fmt.Errorf("invalid BCD %v", arguments[0])
+			}
+			result += digit * scale
+			scale *= 10
+		}
+		return float64(result), nil
+	}},
+	"BCD": {min: 1, max: 1, call: func(arguments []float64) (float64, error) {
+		binary := int64(arguments[0])
+		if binary < 0 || float64(binary) != arguments[0] {
+			return 0, fmt.Errorf("invalid binary %v", arguments[0])
+		}
+
+		result, shift := int64(0), uint(0)
+		for ; binary > 0; binary /= 10 {
+			result |= (binary % 10) << shift
+			shift += 4
+		}
+		return float64(result), nil
+	}},
+}
+
+//#endregion
+//#region private functions
+
+// boolean returns 1 for true and 0 for false, the values of the comparisons.
+func boolean(b bool) Value {
+	if b {
+		return Value{Number: 1}
+	}
+
+	return Value{Number: 0}
+}
+
+// variableNumber evaluates the index of a variable, it must be a positive integer or zero.
+func variableNumber(index node, r variableReader) (int, error) {
+	value, err := index.evaluate(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// the indexes computed are rounded, like #[#1 / 2]
+	number := math.Round(value.number())
+	if value.Vacant || number < 0 || number > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid variable #%s", value)
+	}
+
+	return int(number), nil
+}
+
+//#endregion
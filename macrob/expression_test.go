@@ -0,0 +1,106 @@
+package macrob
+
+import (
+	"testing"
+)
+
+// variables reads the variables of a map, the rest are vacant.
+type variables map[int]float64
+
+func (v variables) read(number int) (Value, error) {
+	value, ok := v[number]
+	return Value{Number: value, Vacant: !ok}, nil
+}
+
+func TestParseExpression(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		want Value
+	}{
+		"precedence":      {"1 + 2 * 3 - 4 / 2", Value{Number: 5}},
+		"brackets":        {"[1 + 2] * 3", Value{Number: 9}},
+		"variable":        {"#1 * 2", Value{Number: 20}},
+		"indirect":        {"#[#2 - 1]", Value{Number: 10}},
+		"vacant":          {"#3", Value{Vacant: true}},
+		"vacant negated":  {"-#3", Value{Vacant: true}},
+		"vacant in sum":   {"#3 + 1", Value{Number: 1}},
+		"vacant equal":    {"#3 EQ #0", Value{Number: 1}},
+		"vacant not zero": {"#3 EQ 0", Value{Number: 0}},
+		"comparison":      {"#1 gt 5", Value{Number: 1}},
+		"logical":         {"[#1 GT 5] AND [#2 LT 1]", Value{Number: 0}},
+		"or":              {"[#1 GT 5] OR [#2 LT 1]", Value{Number: 1}},
+		"xor":             {"6 XOR 3", Value{Number: 5}},
+		"mod":             {"7 MOD 4", Value{Number: 3}},
+		"sin":             {"SIN[90]", Value{Number: 1}},
+		"atan brackets":   {"ATAN[-1]/[-1]", Value{Number: 225}},
+		"atan comma":      {"ATAN[1, 0]", Value{Number: 90}},
+		"fix":             {"FIX[-1.7]", Value{Number: -1}},
+		"fup":             {"FUP[-1.2]", Value{Number: -2}},
+		"round":           {"ROUND[2.5]", Value{Number: 3}},
+		"pow":             {"POW[2, 10]", Value{Number: 1024}},
+		"bcd":             {"BCD[25]", Value{Number: 0x25}},
+		"bin":             {"BIN[37]", Value{Number: 25}},
+	}
+
+	v := variables{1: 10, 2: 2}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x, err := ParseExpression(tc.text)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got, err := x.root.evaluate(v)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseExpression_errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown function": "FOO[1]",
+		"arguments":        "POW[2]",
+		"unclosed":         "[1 + 2",
+		"variable":         "#",
+		"decimal variable": "#1.5",
+		"trailing":         "1 2",
+		"empty":            "",
+		"character":        "1 @ 2",
+		"parentheses":      "(1 + 2)",
+	}
+
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseExpression(text); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestValue_String(t *testing.T) {
+	cases := map[string]struct {
+		value Value
+		want  string
+	}{
+		"integer":  {Value{Number: 2}, "2"},
+		"decimals": {Value{Number: 1.0 / 3}, "0.3333"},
+		"zero":     {Value{Number: -0.00001}, "0"},
+		"vacant":   {Value{Vacant: true}, "vacant"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.value.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
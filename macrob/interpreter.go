@@ -0,0 +1,446 @@
+package macrob
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+// DEFAULT_MAX_STEPS is the maximum number of statements executed by a program, if it isn't configured.
+const DEFAULT_MAX_STEPS = 100000
+
+const (
+	// VARIABLE_ALARM is the system variable that stops the program with an alarm, like "#3000 = 1 (TOOL NOT FOUND)".
+	VARIABLE_ALARM = 3000
+
+	// FIRST_SYSTEM_VARIABLE is the first system variable, they are read and written through the Hook.
+	FIRST_SYSTEM_VARIABLE = 1000
+)
+
+// arguments are the letters of the arguments of a macro call, G65, indexed by the local variable that receives them, like A in #1.
+const arguments = "ABCIJKDEF H M   QRSTUVWXYZ"
+
+//#region hook
+
+// Hook connects an interpreter with the machine that executes the program.
+type Hook interface {
+	// ReadSystem returns the value of a system variable, from FIRST_SYSTEM_VARIABLE, like #5021, the position of the X axis
+	// in the coordinates of the machine. It returns an error if the variable doesn't exist.
+	ReadSystem(number int) (Value, error)
+
+	// WriteSystem writes a system variable, like #2001, the length offset of the first tool.
+	// It returns an error if the variable doesn't exist or it is read-only.
+	WriteSystem(number int, value Value) error
+
+	// Block receives each block executed, with its variables and expressions replaced by their values, before the next statement.
+	// It returns an error to stop the program.
+	Block(line string) error
+}
+
+//#endregion
+//#region interpreter configuration
+
+// InterpreterConfigurer defines the options of an interpreter.
+type InterpreterConfigurer interface {
+	// Set the hook of the system variables and the blocks executed
+	SetHook(hook Hook) error
+
+	// Set the arguments of the macro call
+	SetArguments(arguments map[byte]float64) error
+
+	// Set the initial values of the common variables
+	SetCommonVariables(variables map[int]float64) error
+
+	// Set the maximum number of statements executed by a program
+	SetMaxSteps(steps int) error
+}
+
+// InterpreterConfigurationCallbackable is the signature of the callbacks used to configure an interpreter.
+type InterpreterConfigurationCallbackable func(config InterpreterConfigurer) error
+
+// interpreterConfigurator implements InterpreterConfigurer.
+type interpreterConfigurator struct {
+	hook     Hook
+	locals   map[int]float64
+	common   map[int]Value
+	maxSteps int
+}
+
+// SetHook defines the hook that reads and writes the system variables and receives the blocks executed. Doesn't accept nil.
+// If this method isn't called, by default the system variables don't exist, except VARIABLE_ALARM.
+func (ic *interpreterConfigurator) SetHook(hook Hook) error {
+	if hook == nil {
+		return fmt.Errorf("failed to set hook, it mustn't be nil")
+	}
+
+	ic.hook = hook
+
+	return nil
+}
+
+// SetArguments defines the arguments of the macro call, G65, that are the initial values of the local variables, indexed by their letter,
+// like 'A' for #1 or 'X' for #24, the first specification of arguments of Fanuc. The letters G, L, N, O and P aren't arguments.
+// If this method isn't called, by default the local variables are vacant.
+func (ic *interpreterConfigurator) SetArguments(arguments map[byte]float64) error {
+	locals := map[int]float64{}
+
+	for letter, value := range arguments {
+		number := argumentVariable(letter)
+		if number == 0 {
+			return fmt.Errorf("failed to set arguments, invalid letter %q", letter)
+		}
+		locals[number] = value
+	}
+
+	ic.locals = locals
+
+	return nil
+}
+
+// SetCommonVariables defines the initial values of the common variables, from #100 to #199 and from #500 to #999.
+// If this method isn't called, by default the common variables are vacant.
+func (ic *interpreterConfigurator) SetCommonVariables(variables map[int]float64) error {
+	common := map[int]Value{}
+
+	for number, value := range variables {
+		if !isCommon(number) {
+			return fmt.Errorf("failed to set common variables, #%d isn't a common variable", number)
+		}
+		common[number] = Value{Number: value}
+	}
+
+	ic.common = common
+
+	return nil
+}
+
+// SetMaxSteps defines the maximum number of statements executed by a program, so a program that never ends fails. It must be positive.
+// If this method isn't called, by default it is DEFAULT_MAX_STEPS.
+func (ic *interpreterConfigurator) SetMaxSteps(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("failed to set max steps, it must be positive: %d", steps)
+	}
+
+	ic.maxSteps = steps
+
+	return nil
+}
+
+//#endregion
+//#region interpreter
+
+// Result is the output of a program executed.
+type Result struct {
+	// Lines are the blocks executed, in order, with their variables and expressions replaced by their values.
+	// The words whose address is vacant are omitted, like Fanuc does.
+	Lines []string
+
+	// Alarm is the number assigned to VARIABLE_ALARM, or zero if the program didn't stop with an alarm.
+	Alarm int
+
+	// Message is the comment of the line that raised the alarm.
+	Message string
+}
+
+// Interpreter executes programs of the custom macros B.
+// The common variables persist between the programs executed by the same interpreter, like in the controller.
+type Interpreter struct {
+	configurator *interpreterConfigurator
+}
+
+// NewInterpreter returns a new interpreter with the options received.
+//
+// It returns an error if some option is invalid.
+func NewInterpreter(options ...InterpreterConfigurationCallbackable) (*Interpreter, error) {
+
+	configurator := &interpreterConfigurator{
+		locals:   map[int]float64{},
+		common:   map[int]Value{},
+		maxSteps: DEFAULT_MAX_STEPS,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Interpreter{configurator: configurator}, nil
+}
+
+// CommonVariables returns a copy of the common variables that aren't vacant.
+func (in *Interpreter) CommonVariables() map[int]float64 {
+	common := make(map[int]float64, len(in.configurator.common))
+	for number, value := range in.configurator.common {
+		if !value.Vacant {
+			common[number] = value.Number
+		}
+	}
+
+	return common
+}
+
+// Evaluate parses and evaluates an expression with the common variables, the arguments and the system variables of the hook.
+//
+// It returns an error if the expression is invalid or it can't be evaluated.
+func (in *Interpreter) Evaluate(text string) (Value, error) {
+	x, err := ParseExpression(text)
+	if err != nil {
+		return Value{}, err
+	}
+
+	value, err := x.root.evaluate(in.newExecution(nil))
+	if err != nil {
+		return Value{}, fmt.Errorf("failed to evaluate %s: %w", x, err)
+	}
+
+	return value, nil
+}
+
+// Run executes a program from its first statement until its end, an M30, M2 or M99 block, or an alarm.
+// The local variables start with the arguments of the macro call.
+//
+// It returns an error if some expression can't be evaluated, a GOTO doesn't find its sequence number, a variable can't be written,
+// the hook fails, or the program exceeds the maximum number of steps.
+func (in *Interpreter) Run(p *Program) (*Result, error) {
+	e := in.newExecution(p)
+
+	if err := e.run(); err != nil {
+		return nil, err
+	}
+
+	return e.result, nil
+}
+
+// newExecution returns the state of the execution of a program.
+func (in *Interpreter) newExecution(p *Program) *execution {
+	locals := map[int]float64{}
+	for number, value := range in.configurator.locals {
+		locals[number] = value
+	}
+
+	return &execution{interpreter: in, program: p, locals: locals, result: &Result{}}
+}
+
+//#endregion
+//#region execution
+
+// execution is the state of the execution of a program.
+type execution struct {
+	interpreter *Interpreter
+	program     *Program
+	result      *Result
+
+	// local variables assigned, from #1 to #33
+	locals map[int]float64
+
+	// true if the program ended
+	ended bool
+}
+
+// read returns the value of a variable.
+func (e *execution) read(number int) (Value, error) {
+	switch {
+	case number == 0:
+		return Value{Vacant: true}, nil
+	case number <= 33:
+		value, ok := e.locals[number]
+		return Value{Number: value, Vacant: !ok}, nil
+	case isCommon(number):
+		value, ok := e.interpreter.configurator.common[number]
+		if !ok {
+			return Value{Vacant: true}, nil
+		}
+		return value, nil
+	case number >= FIRST_SYSTEM_VARIABLE && e.interpreter.configurator.hook != nil:
+		return e.interpreter.configurator.hook.ReadSystem(number)
+	}
+
+	return Value{}, fmt.Errorf("unknown variable #%d", number)
+}
+
+// write assigns a value to a variable.
+func (e *execution) write(number int, value Value, st *Statement) error {
+	switch {
+	case number == 0:
+		return fmt.Errorf("the variable #0 is read-only")
+	case number <= 33:
+		if value.Vacant {
+			delete(e.locals, number)
+		} else {
+			e.locals[number] = value.Number
+		}
+		return nil
+	case isCommon(number):
+		e.interpreter.configurator.common[number] = value
+		return nil
+	case number == VARIABLE_ALARM:
+		e.result.Alarm, e.result.Message = int(math.Round(value.number())), st.Comment
+		e.ended = true
+		return nil
+	case number >= FIRST_SYSTEM_VARIABLE && e.interpreter.configurator.hook != nil:
+		return e.interpreter.configurator.hook.WriteSystem(number, value)
+	}
+
+	return fmt.Errorf("unknown variable #%d", number)
+}
+
+// run executes the statements of the program.
+func (e *execution) run() error {
+	statements := e.program.Statements
+
+	steps := 0
+	for pc := 0; pc < len(statements) && !e.ended; {
+		steps++
+		if steps > e.interpreter.configurator.maxSteps {
+			return fmt.Errorf("failed to execute program, it exceeds %d steps", e.interpreter.configurator.maxSteps)
+		}
+
+		st := statements[pc]
+
+		next, err := e.execute(st, pc)
+		if err != nil {
+			return fmt.Errorf("failed to execute line %d %s: %w", st.Line, st.Text, err)
+		}
+		pc = next
+	}
+
+	return nil
+}
+
+// execute executes the statement at an index and returns the index of the next one.
+func (e *execution) execute(st *Statement, pc int) (int, error) {
+	switch st.Kind {
+	case StatementAssign:
+		number, err := variableNumber(st.Variable.root.(*variableNode).index, e)
+		if err != nil {
+			return 0, err
+		}
+
+		value, err := st.Expression.root.evaluate(e)
+		if err != nil {
+			return 0, err
+		}
+
+		// a value computed is a number, only a variable assigned directly keeps it vacant
+		if _, ok := st.Expression.root.(*variableNode); !ok {
+			value = Value{Number: value.number()}
+		}
+
+		return pc + 1, e.write(number, value, st)
+	case StatementIf:
+		condition, err := e.condition(st)
+		if err != nil || !condition {
+			return pc + 1, err
+		}
+
+		then := *st.Then
+		then.Comment = st.Comment
+		return e.execute(&then, pc)
+	case StatementGoto:
+		value, err := st.Expression.root.evaluate(e)
+		if err != nil {
+			return 0, err
+		}
+
+		sequence := int(math.Round(value.number()))
+		next, ok := e.program.jump(sequence, pc)
+		if !ok || value.Vacant {
+			return 0, fmt.Errorf("sequence number N%d not found", sequence)
+		}
+		return next, nil
+	case StatementWhile:
+		if st.Expression == nil {
+			return pc + 1, nil
+		}
+
+		condition, err := e.condition(st)
+		if err != nil {
+			return 0, err
+		}
+		if !condition {
+			return e.program.pairs[pc] + 1, nil
+		}
+		return pc + 1, nil
+	case StatementEnd:
+		return e.program.pairs[pc], nil
+	}
+
+	return pc + 1, e.block(st)
+}
+
+// condition returns true if the condition of a statement isn't zero.
+func (e *execution) condition(st *Statement) (bool, error) {
+	value, err := st.Expression.root.evaluate(e)
+	if err != nil {
+		return false, err
+	}
+
+	return value.number() != 0, nil
+}
+
+// block expands the variables and the expressions of a block and sends it to the hook.
+func (e *execution) block(st *Statement) error {
+	var sb strings.Builder
+	for _, part := range st.template {
+		if part.expression == nil {
+			sb.WriteString(part.text)
+			continue
+		}
+
+		value, err := part.expression.root.evaluate(e)
+		if err != nil {
+			return err
+		}
+
+		if !value.Vacant {
+			sb.WriteByte(part.word)
+			sb.WriteString(numeric.FormatDecimals(value.Number, decimals))
+		}
+	}
+
+	line := strings.Join(strings.Fields(sb.String()), " ")
+	if line == "" {
+		return nil
+	}
+
+	e.result.Lines = append(e.result.Lines, line)
+
+	if hook := e.interpreter.configurator.hook; hook != nil {
+		if err := hook.Block(line); err != nil {
+			return err
+		}
+	}
+
+	switch strings.Fields(line)[0] {
+	case "M2", "M02", "M30", "M99":
+		e.ended = true
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// isCommon returns true if the variable is a common variable.
+func isCommon(number int) bool {
+	return (number >= 100 && number <= 199) || (number >= 500 && number <= 999)
+}
+
+// argumentVariable returns the local variable of the argument of a letter, or zero if the letter isn't an argument.
+func argumentVariable(letter byte) int {
+	if letter == ' ' {
+		return 0
+	}
+
+	if i := strings.IndexByte(arguments, letter); i >= 0 {
+		return i + 1
+	}
+
+	return 0
+}
+
+//#endregion
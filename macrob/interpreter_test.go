@@ -0,0 +1,209 @@
+package macrob
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// machine is a hook with the system variables of a map that records the blocks.
+type machine struct {
+	system map[int]float64
+	blocks []string
+}
+
+func (m *machine) ReadSystem(number int) (Value, error) {
+	value, ok := m.system[number]
+	if !ok {
+		return Value{}, fmt.Errorf("unknown system variable #%d", number)
+	}
+
+	return Value{Number: value}, nil
+}
+
+func (m *machine) WriteSystem(number int, value Value) error {
+	if number >= 5000 {
+		return fmt.Errorf("the system variable #%d is read-only", number)
+	}
+
+	m.system[number] = value.Number
+
+	return nil
+}
+
+func (m *machine) Block(line string) error {
+	m.blocks = append(m.blocks, line)
+
+	return nil
+}
+
+func TestNewInterpreter(t *testing.T) {
+	cases := map[string]struct {
+		option InterpreterConfigurationCallbackable
+		valid  bool
+	}{
+		"default":         {func(config InterpreterConfigurer) error { return nil }, true},
+		"nil hook":        {func(config InterpreterConfigurer) error { return config.SetHook(nil) }, false},
+		"zero steps":      {func(config InterpreterConfigurer) error { return config.SetMaxSteps(0) }, false},
+		"argument letter": {func(config InterpreterConfigurer) error { return config.SetArguments(map[byte]float64{'G': 1}) }, false},
+		"common variable": {func(config InterpreterConfigurer) error { return config.SetCommonVariables(map[int]float64{300: 1}) }, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewInterpreter(tc.option)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestInterpreter_Run(t *testing.T) {
+	cases := map[string]struct {
+		source  string
+		lines   []string
+		alarm   int
+		message string
+	}{
+		"arguments": {
+			source: "G90 G0 X#24 Y#25 Z#26\nG1 Z-[#7 / 2] F#9",
+			lines:  []string{"G90 G0 X10 Y20", "G1 Z-1.5 F300"},
+		},
+		"loop": {
+			source: "#1 = 0\nWHILE [#1 LT 3] DO1\nG1 X[#1 * 10]\n#1 = #1 + 1\nEND1",
+			lines:  []string{"G1 X0", "G1 X10", "G1 X20"},
+		},
+		"nested loops": {
+			source: "#1 = 0\nWHILE [#1 LT 2] DO1\n#2 = 0\nWHILE [#2 LT 2] DO2\nG1 X#1 Y#2\n#2 = #2 + 1\nEND2\n#1 = #1 + 1\nEND1",
+			lines:  []string{"G1 X0 Y0", "G1 X0 Y1", "G1 X1 Y0", "G1 X1 Y1"},
+		},
+		"goto": {
+			source: "#1 = 0\nN10 #1 = #1 + 1\nIF [#1 LT 3] GOTO 10\nG0 X#1\nGOTO 30\nG0 X99\nN30 M30\nG0 X100",
+			lines:  []string{"G0 X3", "M30"},
+		},
+		"then": {
+			source: "IF [#1 EQ #0] THEN #1 = 5\nG0 Z#1",
+			lines:  []string{"G0 Z5"},
+		},
+		"system variables": {
+			source: "#2001 = #5021 + 1\nG0 X#2001",
+			lines:  []string{"G0 X13.5"},
+		},
+		"alarm": {
+			source:  "G28\nIF [#26 EQ #0] THEN #3000 = 1 (Z IS REQUIRED)\nG1 Z#26",
+			lines:   []string{"G28"},
+			alarm:   1,
+			message: "Z IS REQUIRED",
+		},
+		"common variables": {
+			source: "#100 = #100 + 1\n#500 = #1\nG0 X#100",
+			lines:  []string{"G0 X8"},
+		},
+		"return": {
+			source: "G0 X1\nM99\nG0 X2",
+			lines:  []string{"G0 X1", "M99"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProgram(strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			hook := &machine{system: map[int]float64{5021: 12.5}}
+			in, err := NewInterpreter(func(config InterpreterConfigurer) error {
+				if err := config.SetHook(hook); err != nil {
+					return err
+				}
+				if err := config.SetCommonVariables(map[int]float64{100: 7}); err != nil {
+					return err
+				}
+				return config.SetArguments(map[byte]float64{'X': 10, 'Y': 20, 'D': 3, 'F': 300})
+			})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got, err := in.Run(p)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got.Lines, tc.lines) {
+				t.Errorf("got lines %q, want %q", got.Lines, tc.lines)
+			}
+
+			if !reflect.DeepEqual(hook.blocks, tc.lines) {
+				t.Errorf("got blocks %q in the hook, want %q", hook.blocks, tc.lines)
+			}
+
+			if got.Alarm != tc.alarm || got.Message != tc.message {
+				t.Errorf("got alarm %d %q, want %d %q", got.Alarm, got.Message, tc.alarm, tc.message)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Run_errors(t *testing.T) {
+	cases := map[string]string{
+		"infinite loop":   "DO1\nG0 X0\nEND1",
+		"goto":            "GOTO 99",
+		"system variable": "#1 = #5001",
+		"read-only":       "#0 = 1",
+		"unknown":         "#40 = 1",
+		"division":        "#1 = 1 / 0",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProgram(strings.NewReader(source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			in, err := NewInterpreter(func(config InterpreterConfigurer) error {
+				return config.SetMaxSteps(100)
+			})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if _, err := in.Run(p); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestInterpreter_CommonVariables(t *testing.T) {
+	in, err := NewInterpreter()
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	p, err := ParseProgram(strings.NewReader("#500 = 2\n#501 = #500 * 3\n#502 = #0"))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := in.Run(p); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+	}
+
+	if got, want := in.CommonVariables(), map[int]float64{500: 2, 501: 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, err := in.Evaluate("#501 + 1"); err != nil || got != (Value{Number: 7}) {
+		t.Errorf("got %v %v, want 7", got, err)
+	}
+}
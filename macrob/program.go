@@ -0,0 +1,507 @@
+// macrob package parses and interprets the custom macros B of Fanuc: the variables, like "#100 = #100 + 1", the conditionals
+// "IF [#1 GT 10] GOTO 20" and "IF [#1 EQ #0] THEN #1 = 5", the jumps "GOTO 20" to the sequence numbers, the loops "WHILE [#1 LT 5] DO1"
+// closed by "END1", and the blocks with variables and expressions between brackets, like "G1 X#24 Y[#25 + 2]".
+//
+// ParseProgram builds the statements of a program, and an Interpreter executes it, producing the blocks with their values.
+// The system variables, from #1000, like the inputs, the positions or the offsets of the machine, are read and written through a Hook
+// supplied by the user, that also receives each block executed, so an industrial program can be analyzed against a model
+// of the machine, like a state.Machine.
+package macrob
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+// MAX_LOOPS is the number of the identifiers of the loops, DO1 to DO3.
+const MAX_LOOPS = 3
+
+//#region statement struct
+
+// StatementKind identifies the kind of a statement of a program.
+type StatementKind int
+
+const (
+	// StatementBlock is a block of gcode, with or without variables and expressions, like "G1 X#24 Y[#25 + 2]".
+	StatementBlock StatementKind = iota
+
+	// StatementAssign assigns a value to a variable, like "#100 = #100 + 1".
+	StatementAssign
+
+	// StatementIf executes its statement, a GOTO or an assignment after THEN, if its condition is true.
+	StatementIf
+
+	// StatementGoto jumps to a sequence number, like "GOTO 20" or "GOTO #10".
+	StatementGoto
+
+	// StatementWhile executes the statements until its END while its condition is true, or forever if it hasn't condition, like "DO1".
+	StatementWhile
+
+	// StatementEnd closes the loop with the same identifier, like "END1".
+	StatementEnd
+)
+
+// String returns the keyword of the kind, like "IF", or "block" and "assign".
+func (k StatementKind) String() string {
+	switch k {
+	case StatementBlock:
+		return "block"
+	case StatementAssign:
+		return "assign"
+	case StatementIf:
+		return "IF"
+	case StatementGoto:
+		return "GOTO"
+	case StatementWhile:
+		return "WHILE"
+	case StatementEnd:
+		return "END"
+	}
+
+	return fmt.Sprintf("StatementKind(%d)", int(k))
+}
+
+// Statement is a line of a program.
+type Statement struct {
+	// Kind is the kind of the statement.
+	Kind StatementKind
+
+	// Line is the number of the line of the statement in the source, from one.
+	Line int
+
+	// Text is the text of the line without its surrounding spaces.
+	Text string
+
+	// Sequence is the sequence number of the line, like 10 for "N10 G1 X0", or zero if it hasn't one.
+	Sequence int
+
+	// Comment is the text of the comments between parentheses of the line, like the message of an alarm.
+	Comment string
+
+	// Variable is the variable assigned by StatementAssign, like "#100" or "#[#1 + 1]".
+	Variable *Expression
+
+	// Expression is the value of StatementAssign, the condition of StatementIf and StatementWhile, and the sequence number of StatementGoto.
+	// It is nil for the rest, and for a loop without condition.
+	Expression *Expression
+
+	// Then is the statement of StatementIf, a StatementGoto or a StatementAssign.
+	Then *Statement
+
+	// Loop is the identifier of StatementWhile and StatementEnd, from 1 to MAX_LOOPS.
+	Loop int
+
+	// template of a block, its text split by the addresses with variables or expressions
+	template []templatePart
+}
+
+// templatePart is a text of a block, or a word whose address is an expression if the expression isn't nil.
+type templatePart struct {
+	text       string
+	word       byte
+	expression *Expression
+}
+
+//#endregion
+//#region program struct
+
+// Program is a program of the custom macros B, its statements in order.
+type Program struct {
+	// Number is the number of the program, like 1000 for "O1000", or zero if it hasn't one.
+	Number int
+
+	// Statements are the statements of the program. The lines without statements, like "%", "O1000" or the comments, aren't included.
+	Statements []*Statement
+
+	// indexes of the statements of each sequence number, in order
+	labels map[int][]int
+
+	// index of the END of each WHILE and of the WHILE of each END
+	pairs map[int]int
+}
+
+// ParseProgram parses the lines of a program of the custom macros B. The keywords and the words are case-insensitive.
+//
+// It returns an error if some statement or expression is invalid, or a loop isn't closed by an END with its identifier.
+func ParseProgram(source io.Reader) (*Program, error) {
+	reader := bufio.NewReader(source)
+
+	// the lines are read without a limit of length
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read program: %w", err)
+		}
+	}
+
+	return parseLines(lines)
+}
+
+// FromDocument returns the program of the lines of a document, parsed with the macro statements enabled
+// by ParseConfigurer.SetMacroStatements.
+//
+// It returns an error like ParseProgram.
+func FromDocument(d *document.Document) (*Program, error) {
+	lines := make([]string, 0, d.LineCount())
+	for _, l := range d.Lines() {
+		lines = append(lines, l.String())
+	}
+
+	return parseLines(lines)
+}
+
+// jump returns the index of the statement of a sequence number, the first one after the current statement,
+// or the first one of the program if there isn't any after it, like Fanuc searches them.
+func (p *Program) jump(sequence int, current int) (int, bool) {
+	indexes, ok := p.labels[sequence]
+	if !ok {
+		return 0, false
+	}
+
+	for _, index := range indexes {
+		if index > current {
+			return index, true
+		}
+	}
+
+	return indexes[0], true
+}
+
+//#endregion
+//#region private functions
+
+// parseLines parses the lines of a program.
+func parseLines(lines []string) (*Program, error) {
+	program := &Program{labels: map[int][]int{}, pairs: map[int]int{}}
+
+	// the WHILE opened, the innermost last
+	var open []int
+
+	for i, line := range lines {
+		text := strings.TrimSpace(line)
+		code, comment := splitComments(text)
+		code = strings.ToUpper(strings.TrimSpace(code))
+
+		if code == "" || code == "%" {
+			continue
+		}
+
+		if code[0] == 'O' {
+			number, err := strconv.Atoi(strings.TrimSpace(code[1:]))
+			if err != nil || number < 0 {
+				return nil, fmt.Errorf("failed to parse line %d, invalid program number: %s", i+1, text)
+			}
+			program.Number = number
+			continue
+		}
+
+		sequence, code, err := splitSequence(code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", i+1, err)
+		}
+
+		statement, err := parseStatement(code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", i+1, err)
+		}
+		statement.Line, statement.Text, statement.Sequence, statement.Comment = i+1, text, sequence, comment
+
+		index := len(program.Statements)
+		program.Statements = append(program.Statements, statement)
+
+		if sequence > 0 {
+			program.labels[sequence] = append(program.labels[sequence], index)
+		}
+
+		switch statement.Kind {
+		case StatementWhile:
+			for _, o := range open {
+				if program.Statements[o].Loop == statement.Loop {
+					return nil, fmt.Errorf("failed to parse line %d, the loop DO%d is already open", i+1, statement.Loop)
+				}
+			}
+			open = append(open, index)
+		case StatementEnd:
+			if len(open) == 0 || program.Statements[open[len(open)-1]].Loop != statement.Loop {
+				return nil, fmt.Errorf("failed to parse line %d, END%d doesn't close the innermost loop", i+1, statement.Loop)
+			}
+
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			program.pairs[start], program.pairs[index] = index, start
+		}
+	}
+
+	if len(open) > 0 {
+		last := program.Statements[open[len(open)-1]]
+		return nil, fmt.Errorf("failed to parse program, the loop DO%d of line %d isn't closed", last.Loop, last.Line)
+	}
+
+	return program, nil
+}
+
+// parseStatement parses the code of a line in upper case, without its sequence number and its comments.
+func parseStatement(code string) (*Statement, error) {
+	switch {
+	case hasKeyword(code, "IF"):
+		rest := strings.TrimSpace(code[2:])
+		if !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("the condition of IF must be between brackets: %s", code)
+		}
+
+		end, err := closingBracket(rest, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		condition, err := ParseExpression(rest[:end+1])
+		if err != nil {
+			return nil, err
+		}
+
+		rest = strings.TrimSpace(rest[end+1:])
+		if hasKeyword(rest, "THEN") {
+			rest = strings.TrimSpace(rest[4:])
+			if !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("THEN must be followed by an assignment: %s", code)
+			}
+		} else if !hasKeyword(rest, "GOTO") {
+			return nil, fmt.Errorf("IF must be followed by GOTO or THEN: %s", code)
+		}
+
+		then, err := parseStatement(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementIf, Expression: condition, Then: then}, nil
+	case hasKeyword(code, "GOTO"):
+		target, err := ParseExpression(code[4:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementGoto, Expression: target}, nil
+	case hasKeyword(code, "WHILE"):
+		rest := strings.TrimSpace(code[5:])
+		if !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("the condition of WHILE must be between brackets: %s", code)
+		}
+
+		end, err := closingBracket(rest, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		condition, err := ParseExpression(rest[:end+1])
+		if err != nil {
+			return nil, err
+		}
+
+		rest = strings.TrimSpace(rest[end+1:])
+		if !hasKeyword(rest, "DO") {
+			return nil, fmt.Errorf("WHILE must be followed by DO: %s", code)
+		}
+
+		loop, err := loopIdentifier(rest[2:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementWhile, Expression: condition, Loop: loop}, nil
+	case hasKeyword(code, "DO"):
+		loop, err := loopIdentifier(code[2:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementWhile, Loop: loop}, nil
+	case hasKeyword(code, "END"):
+		loop, err := loopIdentifier(code[3:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementEnd, Loop: loop}, nil
+	case strings.HasPrefix(code, "#"):
+		name, value, ok := strings.Cut(code, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected an assignment: %s", code)
+		}
+
+		variable, err := ParseExpression(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := variable.root.(*variableNode); !ok {
+			return nil, fmt.Errorf("expected a variable before the equal sign: %s", name)
+		}
+
+		expression, err := ParseExpression(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Statement{Kind: StatementAssign, Variable: variable, Expression: expression}, nil
+	}
+
+	template, err := parseTemplate(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{Kind: StatementBlock, template: template}, nil
+}
+
+// parseTemplate splits a block by the words whose address is a variable or an expression, like "X#24", "Y-#25" or "Z[#26 / 2]".
+func parseTemplate(code string) ([]templatePart, error) {
+	var parts []templatePart
+	start := 0
+
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' || i+1 >= len(code) {
+			continue
+		}
+
+		j := i + 1
+		if code[j] == '-' {
+			j++
+		}
+
+		if j >= len(code) || (code[j] != '#' && code[j] != '[') {
+			continue
+		}
+
+		// the address is a variable, #24 or #[#1 + 1], or an expression between brackets
+		if code[j] == '#' {
+			j++
+		}
+		end := j
+		switch {
+		case end < len(code) && code[end] == '[':
+			closing, err := closingBracket(code, end)
+			if err != nil {
+				return nil, err
+			}
+			end = closing + 1
+		default:
+			for end < len(code) && (numeric.IsDigit(code[end]) || code[end] == '.') {
+				end++
+			}
+		}
+
+		expression, err := ParseExpression(code[i+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, templatePart{text: code[start:i]}, templatePart{word: code[i], expression: expression})
+		start = end
+		i = end - 1
+	}
+
+	return append(parts, templatePart{text: code[start:]}), nil
+}
+
+// splitComments returns the text without the comments between parentheses, and the text of the comments.
+func splitComments(text string) (string, string) {
+	var code, comment strings.Builder
+	depth := 0
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '(':
+			depth++
+			if depth > 1 {
+				comment.WriteByte(c)
+			}
+		case c == ')' && depth > 0:
+			depth--
+			if depth > 0 {
+				comment.WriteByte(c)
+			}
+		case depth > 0:
+			comment.WriteByte(c)
+		default:
+			code.WriteByte(c)
+		}
+	}
+
+	return code.String(), strings.TrimSpace(comment.String())
+}
+
+// splitSequence returns the sequence number of a line, like 10 for "N10 G1 X0", and the rest of the code.
+func splitSequence(code string) (int, string, error) {
+	if !strings.HasPrefix(code, "N") || len(code) < 2 || !numeric.IsDigit(code[1]) {
+		return 0, code, nil
+	}
+
+	end := 1
+	for end < len(code) && numeric.IsDigit(code[end]) {
+		end++
+	}
+
+	sequence, err := strconv.Atoi(code[1:end])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid sequence number %s", code[:end])
+	}
+
+	return sequence, strings.TrimSpace(code[end:]), nil
+}
+
+// hasKeyword returns true if the code starts with the keyword followed by a space, a bracket, a variable, a digit or the end.
+func hasKeyword(code string, keyword string) bool {
+	if !strings.HasPrefix(code, keyword) {
+		return false
+	}
+
+	rest := code[len(keyword):]
+
+	return rest == "" || strings.IndexByte(" \t[#", rest[0]) >= 0 || numeric.IsDigit(rest[0])
+}
+
+// loopIdentifier parses the identifier of a loop after DO or END.
+func loopIdentifier(text string) (int, error) {
+	loop, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || loop < 1 || loop > MAX_LOOPS {
+		return 0, fmt.Errorf("invalid identifier of loop %q, it must be from 1 to %d", strings.TrimSpace(text), MAX_LOOPS)
+	}
+
+	return loop, nil
+}
+
+// closingBracket returns the position of the bracket that closes the one at the position received.
+func closingBracket(text string, open int) (int, error) {
+	depth := 0
+
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("unclosed bracket in %s", text)
+}
+
+//#endregion
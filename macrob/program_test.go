@@ -0,0 +1,108 @@
+package macrob
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestParseProgram(t *testing.T) {
+	source := strings.Join([]string{
+		"%",
+		"O1000 (POCKET)",
+		"#1 = 0",
+		"N10 WHILE [#1 LT 3] DO1",
+		"G1 X[#1 * 10] Y-#2 F#9",
+		"#1 = #1 + 1",
+		"END1",
+		"IF [#1 EQ 3] GOTO 20",
+		"if [#1 ne 3] then #3000 = 1 (NOT REACHED)",
+		"N20 M30",
+		"%",
+	}, "\n")
+
+	p, err := ParseProgram(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if p.Number != 1000 {
+		t.Errorf("got program number %d, want 1000", p.Number)
+	}
+
+	var kinds []string
+	for _, st := range p.Statements {
+		kinds = append(kinds, st.Kind.String())
+	}
+
+	if got, want := strings.Join(kinds, " "), "assign WHILE block assign END IF IF block"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if st := p.Statements[1]; st.Sequence != 10 || st.Loop != 1 || st.Line != 4 {
+		t.Errorf("got sequence %d, loop %d and line %d, want 10, 1 and 4", st.Sequence, st.Loop, st.Line)
+	}
+
+	if st := p.Statements[6]; st.Then.Kind != StatementAssign || st.Comment != "NOT REACHED" {
+		t.Errorf("got %v with comment %q, want an assignment with comment NOT REACHED", st.Then.Kind, st.Comment)
+	}
+
+	if index, ok := p.jump(20, 0); !ok || index != 7 {
+		t.Errorf("got jump to %d %v, want 7", index, ok)
+	}
+}
+
+func TestParseProgram_longLine(t *testing.T) {
+	p, err := ParseProgram(strings.NewReader("G1 X1 (" + strings.Repeat("x", 70000) + ")\nM30\n"))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if len(p.Statements) != 2 || len(p.Statements[0].Comment) != 70000 {
+		t.Errorf("got %d statements, want 2 with the long comment", len(p.Statements))
+	}
+}
+
+func TestParseProgram_errors(t *testing.T) {
+	cases := map[string]string{
+		"END without DO":     "END1",
+		"DO without END":     "WHILE [#1 LT 3] DO1\nG1 X0",
+		"crossed loops":      "DO1\nDO2\nEND1\nEND2",
+		"loop reopened":      "DO1\nDO1\nEND1\nEND1",
+		"loop identifier":    "WHILE [1] DO4\nEND4",
+		"IF without action":  "IF [#1 GT 0]",
+		"IF without bracket": "IF #1 GT 0 GOTO 10",
+		"THEN without #":     "IF [1] THEN G0 X0",
+		"assignment":         "#1 + 2",
+		"assign constant":    "#1 + 1 = 2",
+		"program number":     "OABC",
+		"template":           "G1 X[1 +]",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseProgram(strings.NewReader(source)); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestFromDocument(t *testing.T) {
+	d, err := document.Parse(strings.NewReader("O2000\n#100 = 5\nG0 Z#100\nM99\n"), func(config document.ParseConfigurer) error {
+		return config.SetMacroStatements(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	p, err := FromDocument(d)
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if p.Number != 2000 || len(p.Statements) != 3 {
+		t.Errorf("got program %d with %d statements, want 2000 with 3", p.Number, len(p.Statements))
+	}
+}
@@ -0,0 +1,461 @@
+// meta package parses and evaluates the meta commands of RepRapFirmware: the conditionals "if", "elif" and "else", the loops
+// "while" with "break" and "continue", the variables "var", "global" and "set", "echo", "abort", and the expressions
+// of the object model between braces in the lines of gcode, like "G1 X{move.axes[0].max - 10}".
+//
+// ParseProgram builds the tree of statements of a source, nested by their indentation, and an Evaluator executes it
+// with the values of the object model returned by a Resolver supplied by the user, producing the lines of gcode with
+// their expressions replaced by their values:
+//
+//	program, err := meta.ParseProgram(source)
+//	evaluator, err := meta.NewEvaluator(func(config meta.EvaluatorConfigurer) error {
+//		return config.SetResolver(meta.ResolverFunc(func(path string) (interface{}, bool) {
+//			return model[path], true
+//		}))
+//	})
+//	result, err := evaluator.Run(program)
+package meta
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DEFAULT_MAX_ITERATIONS is the maximum number of iterations of each loop, if it isn't configured.
+const DEFAULT_MAX_ITERATIONS = 10000
+
+//#region resolver
+
+// Resolver returns the values of the object model of the firmware.
+type Resolver interface {
+	// Resolve returns the value of a path of the object model, like "move.axes[0].homed", or false if it doesn't exist.
+	// The values are numbers, booleans, strings, nil or arrays of them.
+	Resolve(path string) (interface{}, bool)
+}
+
+// ResolverFunc is a function that implements Resolver.
+type ResolverFunc func(path string) (interface{}, bool)
+
+// Resolve calls the function.
+func (f ResolverFunc) Resolve(path string) (interface{}, bool) {
+	return f(path)
+}
+
+//#endregion
+//#region evaluator configuration
+
+// EvaluatorConfigurer defines the options of an evaluator.
+type EvaluatorConfigurer interface {
+	// Set the resolver of the object model
+	SetResolver(resolver Resolver) error
+
+	// Set the parameters of the macro
+	SetParameters(parameters map[string]interface{}) error
+
+	// Set the initial global variables
+	SetGlobals(globals map[string]interface{}) error
+
+	// Set the maximum number of iterations of each loop
+	SetMaxIterations(iterations int) error
+}
+
+// EvaluatorConfigurationCallbackable is the signature of the callbacks used to configure an evaluator.
+type EvaluatorConfigurationCallbackable func(config EvaluatorConfigurer) error
+
+// evaluatorConfigurator implements EvaluatorConfigurer.
+type evaluatorConfigurator struct {
+	resolver      Resolver
+	parameters    map[string]interface{}
+	globals       map[string]interface{}
+	maxIterations int
+}
+
+// SetResolver defines the resolver of the paths of the object model, like "move.axes[0].homed". Doesn't accept nil.
+// If this method isn't called, by default the object model is empty and its paths are unknown values.
+func (ec *evaluatorConfigurator) SetResolver(resolver Resolver) error {
+	if resolver == nil {
+		return fmt.Errorf("failed to set resolver, it mustn't be nil")
+	}
+
+	ec.resolver = resolver
+
+	return nil
+}
+
+// SetParameters defines the parameters of the macro evaluated, read as "param.S", indexed by their letter.
+// If this method isn't called, by default the macro hasn't parameters.
+func (ec *evaluatorConfigurator) SetParameters(parameters map[string]interface{}) error {
+	ec.parameters = map[string]interface{}{}
+	for name, value := range parameters {
+		ec.parameters[name] = normalize(value)
+	}
+
+	return nil
+}
+
+// SetGlobals defines the global variables that exist before the first program, read as "global.name".
+// If this method isn't called, by default there aren't global variables.
+func (ec *evaluatorConfigurator) SetGlobals(globals map[string]interface{}) error {
+	for name := range globals {
+		if !validName(name, false) {
+			return fmt.Errorf("failed to set globals, invalid name %q", name)
+		}
+	}
+
+	ec.globals = map[string]interface{}{}
+	for name, value := range globals {
+		ec.globals[name] = normalize(value)
+	}
+
+	return nil
+}
+
+// SetMaxIterations defines the maximum number of iterations of each loop, so a loop that never ends fails. It must be positive.
+// If this method isn't called, by default it is DEFAULT_MAX_ITERATIONS.
+func (ec *evaluatorConfigurator) SetMaxIterations(iterations int) error {
+	if iterations <= 0 {
+		return fmt.Errorf("failed to set max iterations, it must be positive: %d", iterations)
+	}
+
+	ec.maxIterations = iterations
+
+	return nil
+}
+
+//#endregion
+//#region evaluator
+
+// Result is the output of a program evaluated.
+type Result struct {
+	// Lines are the lines of gcode executed, in order, with their expressions replaced by their values.
+	Lines []string
+
+	// Echo are the messages written by echo, in order.
+	Echo []string
+
+	// Aborted is true if the program ended with abort.
+	Aborted bool
+
+	// Message is the message of abort.
+	Message string
+}
+
+// Evaluator executes programs and evaluates expressions of RepRapFirmware.
+// The global variables persist between the programs executed by the same evaluator, like in the firmware.
+type Evaluator struct {
+	configurator *evaluatorConfigurator
+}
+
+// NewEvaluator returns a new evaluator with the options received.
+//
+// It returns an error if some option is invalid.
+func NewEvaluator(options ...EvaluatorConfigurationCallbackable) (*Evaluator, error) {
+
+	configurator := &evaluatorConfigurator{
+		resolver:      ResolverFunc(func(string) (interface{}, bool) { return nil, false }),
+		parameters:    map[string]interface{}{},
+		globals:       map[string]interface{}{},
+		maxIterations: DEFAULT_MAX_ITERATIONS,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Evaluator{configurator: configurator}, nil
+}
+
+// Globals returns a copy of the global variables.
+func (e *Evaluator) Globals() map[string]interface{} {
+	globals := make(map[string]interface{}, len(e.configurator.globals))
+	for name, value := range e.configurator.globals {
+		globals[name] = value
+	}
+
+	return globals
+}
+
+// Evaluate parses and evaluates an expression, like "move.axes[0].max - 10".
+// The numbers are float64, and the values of the object model are converted like them.
+//
+// It returns an error if the expression is invalid or it can't be evaluated, like a path unknown or a division by zero.
+func (e *Evaluator) Evaluate(text string) (interface{}, error) {
+	x, err := ParseExpression(text)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scope{evaluator: e, variables: []map[string]interface{}{{}}}
+
+	value, err := x.root.evaluate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", x, err)
+	}
+
+	return value, nil
+}
+
+// Run executes a program and returns the lines of gcode executed and the messages written.
+// The variables declared by var exist until the end of the body that declares them.
+//
+// It returns an error if some expression can't be evaluated, a condition isn't a boolean, a variable is declared twice
+// or assigned without being declared, or a loop exceeds the maximum number of iterations.
+func (e *Evaluator) Run(p *Program) (*Result, error) {
+	s := &scope{evaluator: e, result: &Result{}}
+
+	if _, err := s.execute(p.Statements); err != nil {
+		return nil, err
+	}
+
+	return s.result, nil
+}
+
+//#endregion
+//#region scope
+
+// signal is the control flow after executing a body.
+type signal int
+
+const (
+	signalNone signal = iota
+	signalBreak
+	signalContinue
+	signalAbort
+)
+
+// scope is the state of the execution of a program.
+type scope struct {
+	evaluator *Evaluator
+	result    *Result
+
+	// variables declared by var, one map per body, the innermost last
+	variables []map[string]interface{}
+
+	// iterations of the loops, the innermost last
+	iterations []int
+}
+
+// lookup returns the value of a path of the variables, the parameters or the object model.
+func (s *scope) lookup(path string) (interface{}, bool) {
+	first, rest, _ := strings.Cut(path, ".")
+
+	switch first {
+	case "var":
+		for i := len(s.variables) - 1; i >= 0; i-- {
+			if value, ok := s.variables[i][rest]; ok {
+				return value, true
+			}
+		}
+		return nil, false
+	case "global":
+		value, ok := s.evaluator.configurator.globals[rest]
+		return value, ok
+	case "param":
+		value, ok := s.evaluator.configurator.parameters[rest]
+		return value, ok
+	case "iterations":
+		if rest != "" || len(s.iterations) == 0 {
+			return nil, false
+		}
+		return float64(s.iterations[len(s.iterations)-1]), true
+	}
+
+	return s.evaluator.configurator.resolver.Resolve(path)
+}
+
+// evaluate returns the value of an expression at a line.
+func (s *scope) evaluate(x *Expression, line int) (interface{}, error) {
+	value, err := x.root.evaluate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s at line %d: %w", x, line, err)
+	}
+
+	return value, nil
+}
+
+// condition returns the value of a condition at a line, it must be a boolean.
+func (s *scope) condition(st *Statement) (bool, error) {
+	value, err := s.evaluate(st.Expression, st.Line)
+	if err != nil {
+		return false, err
+	}
+
+	condition, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("failed to evaluate %s at line %d, the condition must be a boolean: %s", st.Expression, st.Line, FormatValue(value))
+	}
+
+	return condition, nil
+}
+
+// execute executes a body in a new scope of variables.
+func (s *scope) execute(body []*Statement) (signal, error) {
+	s.variables = append(s.variables, map[string]interface{}{})
+	defer func() { s.variables = s.variables[:len(s.variables)-1] }()
+
+	// true if a condition of the current chain of if, elif and else was true
+	handled := false
+
+	for _, st := range body {
+		var sig signal
+		var err error
+
+		switch st.Kind {
+		case StatementIf, StatementElif:
+			if st.Kind == StatementIf {
+				handled = false
+			}
+			if handled {
+				continue
+			}
+
+			if handled, err = s.condition(st); err == nil && handled {
+				sig, err = s.execute(st.Body)
+			}
+		case StatementElse:
+			if !handled {
+				sig, err = s.execute(st.Body)
+			}
+		case StatementWhile:
+			sig, err = s.loop(st)
+		case StatementBreak:
+			sig = signalBreak
+		case StatementContinue:
+			sig = signalContinue
+		case StatementAbort:
+			s.result.Aborted = true
+			if st.Expression != nil {
+				var value interface{}
+				if value, err = s.evaluate(st.Expression, st.Line); err == nil {
+					s.result.Message = FormatValue(value)
+				}
+			}
+			sig = signalAbort
+		default:
+			err = s.statement(st)
+		}
+
+		if err != nil {
+			return signalNone, err
+		}
+
+		if sig != signalNone {
+			return sig, nil
+		}
+	}
+
+	return signalNone, nil
+}
+
+// loop executes a while until its condition is false, a break or an abort.
+func (s *scope) loop(st *Statement) (signal, error) {
+	s.iterations = append(s.iterations, 0)
+	defer func() { s.iterations = s.iterations[:len(s.iterations)-1] }()
+
+	for {
+		condition, err := s.condition(st)
+		if err != nil || !condition {
+			return signalNone, err
+		}
+
+		if s.iterations[len(s.iterations)-1] >= s.evaluator.configurator.maxIterations {
+			return signalNone, fmt.Errorf("failed to execute the loop at line %d, it exceeds %d iterations", st.Line, s.evaluator.configurator.maxIterations)
+		}
+
+		sig, err := s.execute(st.Body)
+		if err != nil {
+			return signalNone, err
+		}
+
+		switch sig {
+		case signalBreak:
+			return signalNone, nil
+		case signalAbort:
+			return signalAbort, nil
+		}
+
+		s.iterations[len(s.iterations)-1]++
+	}
+}
+
+// statement executes the statements that don't change the control flow.
+func (s *scope) statement(st *Statement) error {
+	switch st.Kind {
+	case StatementVar, StatementGlobal, StatementSet:
+		value, err := s.evaluate(st.Expression, st.Line)
+		if err != nil {
+			return err
+		}
+		return s.assign(st, value)
+	case StatementEcho:
+		values := make([]string, 0, len(st.Arguments))
+		for _, argument := range st.Arguments {
+			value, err := s.evaluate(argument, st.Line)
+			if err != nil {
+				return err
+			}
+			values = append(values, FormatValue(value))
+		}
+		s.result.Echo = append(s.result.Echo, strings.Join(values, " "))
+		return nil
+	}
+
+	var sb strings.Builder
+	for _, part := range st.template {
+		if part.expression == nil {
+			sb.WriteString(part.text)
+			continue
+		}
+
+		value, err := s.evaluate(part.expression, st.Line)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(FormatValue(value))
+	}
+	s.result.Lines = append(s.result.Lines, sb.String())
+
+	return nil
+}
+
+// assign declares or sets a variable.
+func (s *scope) assign(st *Statement, value interface{}) error {
+	globals := s.evaluator.configurator.globals
+
+	switch st.Kind {
+	case StatementVar:
+		current := s.variables[len(s.variables)-1]
+		if _, ok := current[st.Name]; ok {
+			return fmt.Errorf("failed to declare var.%s at line %d, it already exists", st.Name, st.Line)
+		}
+		current[st.Name] = value
+	case StatementGlobal:
+		if _, ok := globals[st.Name]; ok {
+			return fmt.Errorf("failed to declare global.%s at line %d, it already exists", st.Name, st.Line)
+		}
+		globals[st.Name] = value
+	case StatementSet:
+		kind, name, _ := strings.Cut(st.Name, ".")
+
+		if kind == "global" {
+			if _, ok := globals[name]; !ok {
+				return fmt.Errorf("failed to set %s at line %d, it isn't declared", st.Name, st.Line)
+			}
+			globals[name] = value
+			return nil
+		}
+
+		for i := len(s.variables) - 1; i >= 0; i-- {
+			if _, ok := s.variables[i][name]; ok {
+				s.variables[i][name] = value
+				return nil
+			}
+		}
+		return fmt.Errorf("failed to set %s at line %d, it isn't declared", st.Name, st.Line)
+	}
+
+	return nil
+}
+
+//#endregion
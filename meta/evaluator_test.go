@@ -0,0 +1,260 @@
+package meta
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// model is an object model for the tests.
+var model = map[string]interface{}{
+	"move.axes[0].homed":       true,
+	"move.axes[0].max":         235,
+	"move.axes[1].max":         float32(220.5),
+	"heat.heaters[0].current":  24.25,
+	"tools[0].heaters[0]":      1,
+	"heat.heaters[1].current":  210.0,
+	"state.status":             "idle",
+	"sensors.probes[0].offset": []float64{-1.5, 2},
+}
+
+// newEvaluator returns an evaluator of the model with the parameter S and the global count.
+func newEvaluator(t *testing.T) *Evaluator {
+	t.Helper()
+
+	e, err := NewEvaluator(func(config EvaluatorConfigurer) error {
+		if err := config.SetResolver(ResolverFunc(func(path string) (interface{}, bool) {
+			value, ok := model[path]
+			return value, ok
+		})); err != nil {
+			return err
+		}
+
+		if err := config.SetParameters(map[string]interface{}{"S": 3}); err != nil {
+			return err
+		}
+
+		return config.SetGlobals(map[string]interface{}{"count": 1})
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	return e
+}
+
+func TestNewEvaluator(t *testing.T) {
+	cases := map[string]struct {
+		option EvaluatorConfigurationCallbackable
+		valid  bool
+	}{
+		"default":         {func(config EvaluatorConfigurer) error { return nil }, true},
+		"nil resolver":    {func(config EvaluatorConfigurer) error { return config.SetResolver(nil) }, false},
+		"zero iterations": {func(config EvaluatorConfigurer) error { return config.SetMaxIterations(0) }, false},
+		"invalid global": {func(config EvaluatorConfigurer) error {
+			return config.SetGlobals(map[string]interface{}{"a.b": 1})
+		}, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEvaluator(tc.option)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		want interface{}
+	}{
+		"arithmetic":    {"1 + 2 * 3 - 4 / 2", 5.0},
+		"unary":         {"-(2 + 1)", -3.0},
+		"comparison":    {"2 >= 2 && 1 != 2", true},
+		"or":            {"false || 1 < 2", true},
+		"short circuit": {"false && move.unknown", false},
+		"concatenation": {"\"T\" ^ 1", "T1"},
+		"conditional":   {"state.status = \"idle\" ? 1 : 2", 1.0},
+		"path":          {"move.axes[0].max - 10", 225.0},
+		"float32":       {"move.axes[1].max", 220.5},
+		"computed":      {"heat.heaters[tools[0].heaters[0]].current", 210.0},
+		"length":        {"#sensors.probes[0].offset", 2.0},
+		"array":         {"sensors.probes[0].offset", []interface{}{-1.5, 2.0}},
+		"parameter":     {"param.S * 2", 6.0},
+		"global":        {"global.count", 1.0},
+		"functions":     {"max(abs(-4), sqrt(9), mod(7, 4))", 4.0},
+		"exists":        {"exists(move.axes[0].homed) && !exists(param.T)", true},
+		"pi":            {"floor(pi * 100)", 314.0},
+		"null":          {"null == null", true},
+	}
+
+	e := newEvaluator(t)
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := e.Evaluate(tc.text)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Evaluate_errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown path":     "move.axes[5].max",
+		"division by zero": "1 / 0",
+		"types":            "1 + \"a\"",
+		"not boolean":      "!1",
+		"invalid index":    "move.axes[-1].max",
+		"condition":        "1 ? 2 : 3",
+		"iterations":       "iterations",
+	}
+
+	e := newEvaluator(t)
+
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got, err := e.Evaluate(text); err == nil {
+				t.Errorf("got %#v, want error not nil", got)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Run(t *testing.T) {
+	cases := map[string]struct {
+		source  string
+		lines   []string
+		echo    []string
+		aborted bool
+		message string
+	}{
+		"template": {
+			source: "G1 X{move.axes[0].max - 10} Y{move.axes[1].max / 2} ; {not evaluated}",
+			lines:  []string{"G1 X225 Y110.25 ; {not evaluated}"},
+		},
+		"conditional": {
+			source: "if heat.heaters[0].current > 100\n  M104 S0\nelif param.S == 3\n  M104 S{param.S * 50}\nelse\n  M104 S210",
+			lines:  []string{"M104 S150"},
+		},
+		"loop": {
+			source: "var total = 0\nwhile iterations < 5\n  if iterations == 1\n    continue\n  if iterations == 3\n    break\n  set var.total = var.total + iterations\n  G1 Z{iterations}\necho \"total\", var.total",
+			lines:  []string{"G1 Z0", "G1 Z2"},
+			echo:   []string{"total 2"},
+		},
+		"nested loops": {
+			source: "while iterations < 2\n  var i = iterations\n  while iterations < 2\n    G1 X{var.i} Y{iterations}",
+			lines:  []string{"G1 X0 Y0", "G1 X0 Y1", "G1 X1 Y0", "G1 X1 Y1"},
+		},
+		"shadowing": {
+			source: "var x = 1\nif true\n  var x = 2\n  echo var.x\necho var.x",
+			echo:   []string{"2", "1"},
+		},
+		"globals": {
+			source: "set global.count = global.count + 1\nglobal name = \"a\" ^ global.count\necho global.name",
+			echo:   []string{"a2"},
+		},
+		"abort": {
+			source:  "G28\nif !move.axes[0].homed || true\n  abort \"not homed \" ^ state.status\nG1 X0",
+			lines:   []string{"G28"},
+			aborted: true,
+			message: "not homed idle",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProgram(strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got, err := newEvaluator(t).Run(p)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if !reflect.DeepEqual(got.Lines, tc.lines) {
+				t.Errorf("got lines %q, want %q", got.Lines, tc.lines)
+			}
+
+			if !reflect.DeepEqual(got.Echo, tc.echo) {
+				t.Errorf("got echo %q, want %q", got.Echo, tc.echo)
+			}
+
+			if got.Aborted != tc.aborted || got.Message != tc.message {
+				t.Errorf("got aborted %v %q, want %v %q", got.Aborted, got.Message, tc.aborted, tc.message)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Run_errors(t *testing.T) {
+	cases := map[string]string{
+		"infinite loop":    "while true\n  G4 P0",
+		"condition":        "if 1\n  G28",
+		"var twice":        "var x = 1\nvar x = 2",
+		"global twice":     "global count = 2",
+		"set undeclared":   "set var.x = 1",
+		"var out of scope": "if true\n  var x = 1\nset var.x = 2",
+		"unknown path":     "G1 X{move.axes[9].max}",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProgram(strings.NewReader(source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			e, err := NewEvaluator(func(config EvaluatorConfigurer) error {
+				if err := config.SetGlobals(map[string]interface{}{"count": 1}); err != nil {
+					return err
+				}
+				return config.SetMaxIterations(100)
+			})
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if _, err := e.Run(p); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestEvaluator_Globals(t *testing.T) {
+	e := newEvaluator(t)
+
+	p, err := ParseProgram(strings.NewReader("global total = global.count * 10"))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if _, err := e.Run(p); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	globals := e.Globals()
+	if want := map[string]interface{}{"count": 1.0, "total": 10.0}; !reflect.DeepEqual(globals, want) {
+		t.Errorf("got %v, want %v", globals, want)
+	}
+
+	// the copy doesn't change the globals of the evaluator
+	globals["total"] = 0.0
+	if got, _ := e.Evaluate("global.total"); got != 10.0 {
+		t.Errorf("got %v, want 10", got)
+	}
+}
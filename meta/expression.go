@@ -0,0 +1,444 @@
+package meta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region expression struct
+
+// Expression is an expression of RepRapFirmware parsed, like "move.axes[0].max - 10" or "var.count > 2 && !global.paused".
+// It is evaluated by an Evaluator.
+type Expression struct {
+	// source text of the expression, trimmed
+	source string
+
+	// root of the tree of the expression
+	root node
+}
+
+// String returns the source of the expression.
+func (x *Expression) String() string {
+	return x.source
+}
+
+// ParseExpression parses an expression of RepRapFirmware.
+//
+// The operators are, from the highest precedence to the lowest: the unary operators "!", "-", "+" and "#", the length of an array
+// or a string; "*" and "/"; "+", "-" and "^", the concatenation of strings; "<", "<=", ">" and ">="; "=", "==" and "!=";
+// "&" and "&&"; "|" and "||"; and the conditional "?" ":". The operands are numbers, strings between double quotes, the constants
+// true, false, null and pi, the paths of the object model, like "move.axes[0].homed", the variables "var.name", "global.name"
+// and "param.S", "iterations", the expressions between parentheses or braces, and the functions abs, acos, asin, atan, atan2,
+// ceil, cos, degrees, exists, floor, isnan, max, min, mod, radians, sin, sqrt, square and tan.
+//
+// It returns an error if the expression is invalid.
+func ParseExpression(text string) (*Expression, error) {
+	p := &parser{source: text}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	root, err := p.conditional()
+	if err == nil && p.position < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.position].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", text, err)
+	}
+
+	return &Expression{source: strings.TrimSpace(text), root: root}, nil
+}
+
+// FormatValue returns a value of an expression as RepRapFirmware writes it: the numbers with six decimals at most,
+// like "2.5", the booleans as "true" or "false", null as "null" and the arrays between braces, like "{1,2}".
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, FormatValue(normalize(item)))
+		}
+		return "{" + strings.Join(items, ",") + "}"
+	}
+
+	return fmt.Sprint(value)
+}
+
+//#endregion
+//#region tokens
+
+// tokenKind identifies the kind of a token of an expression.
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdentifier
+	tokenOperator
+)
+
+// token is a token of an expression.
+type token struct {
+	kind tokenKind
+	text string
+
+	// value of the numbers and the strings
+	number float64
+	value  string
+}
+
+// operators are the operators and the punctuation of the expressions, the longest first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "^", "!", "#", "<", ">", "=", "&", "|", "?", ":", "(", ")", "[", "]", "{", "}", ",", "."}
+
+// tokenize splits the source in tokens.
+func (p *parser) tokenize() error {
+	s := p.source
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case numeric.IsDigit(c) || (c == '.' && i+1 < len(s) && numeric.IsDigit(s[i+1])):
+			start := i
+			for i < len(s) && (numeric.IsDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+
+			// exponent, like 1e-3
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				j := i + 1
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				if j < len(s) && numeric.IsDigit(s[j]) {
+					i = j
+					for i < len(s) && numeric.IsDigit(s[i]) {
+						i++
+					}
+				}
+			}
+
+			number, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", s[start:i])
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: s[start:i], number: number})
+		case c == '"':
+			// the quotes inside a string are written twice
+			var sb strings.Builder
+			start := i
+			i++
+			closed := false
+			for i < len(s) {
+				if s[i] == '"' {
+					if i+1 < len(s) && s[i+1] == '"' {
+						sb.WriteByte('"')
+						i += 2
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			if !closed {
+				return fmt.Errorf("unclosed string %s", s[start:])
+			}
+			p.tokens = append(p.tokens, token{kind: tokenString, text: s[start:i], value: sb.String()})
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(s) && (s[i] == '_' || (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdentifier, text: s[start:i]})
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(s[i:], operator) {
+					p.tokens = append(p.tokens, token{kind: tokenOperator, text: operator})
+					i += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region parser
+
+// parser is a recursive descent parser of the expressions.
+type parser struct {
+	source   string
+	tokens   []token
+	position int
+}
+
+// peek returns true if the next token is one of the operators.
+func (p *parser) peek(operators ...string) (string, bool) {
+	if p.position >= len(p.tokens) || p.tokens[p.position].kind != tokenOperator {
+		return "", false
+	}
+
+	for _, operator := range operators {
+		if p.tokens[p.position].text == operator {
+			return operator, true
+		}
+	}
+
+	return "", false
+}
+
+// expect consumes the operator required or returns an error.
+func (p *parser) expect(operator string) error {
+	if _, ok := p.peek(operator); !ok {
+		if p.position >= len(p.tokens) {
+			return fmt.Errorf("expected %q at the end", operator)
+		}
+		return fmt.Errorf("expected %q instead of %q", operator, p.tokens[p.position].text)
+	}
+
+	p.position++
+
+	return nil
+}
+
+// conditional parses "condition ? then : else".
+func (p *parser) conditional() (node, error) {
+	condition, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.peek("?"); !ok {
+		return condition, nil
+	}
+	p.position++
+
+	then, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	otherwise, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conditionalNode{condition: condition, then: then, otherwise: otherwise}, nil
+}
+
+// precedences are the binary operators by precedence, from the lowest to the highest.
+var precedences = [][]string{
+	{"|", "||"},
+	{"&", "&&"},
+	{"=", "==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-", "^"},
+	{"*", "/"},
+}
+
+// binary parses the binary operators of a level of precedence and the higher ones, associated to the left.
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedences) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.peek(precedences[level]...)
+		if !ok {
+			return left, nil
+		}
+		p.position++
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{operator: operator, left: left, right: right}
+	}
+}
+
+// unary parses the unary operators.
+func (p *parser) unary() (node, error) {
+	if operator, ok := p.peek("!", "-", "+", "#"); ok {
+		p.position++
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryNode{operator: operator, operand: operand}, nil
+	}
+
+	return p.primary()
+}
+
+// primary parses the operands.
+func (p *parser) primary() (node, error) {
+	if p.position >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+
+	t := p.tokens[p.position]
+	p.position++
+
+	switch t.kind {
+	case tokenNumber:
+		return &literalNode{value: t.number}, nil
+	case tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdentifier:
+		return p.identifier(t.text)
+	}
+
+	// the braces group like the parentheses
+	closing := map[string]string{"(": ")", "{": "}"}[t.text]
+	if closing == "" {
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+
+	inner, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+
+	return inner, nil
+}
+
+// identifier parses a constant, a call of a function or a path that starts with the name.
+func (p *parser) identifier(name string) (node, error) {
+	switch name {
+	case "true":
+		return &literalNode{value: true}, nil
+	case "false":
+		return &literalNode{value: false}, nil
+	case "null":
+		return &literalNode{value: nil}, nil
+	case "pi":
+		return &literalNode{value: math.Pi}, nil
+	}
+
+	if _, ok := p.peek("("); ok {
+		return p.call(name)
+	}
+
+	path := &pathNode{parts: []pathPart{{name: name}}}
+
+	for {
+		if _, ok := p.peek("["); ok {
+			p.position++
+
+			index, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+			last := &path.parts[len(path.parts)-1]
+			last.indexes = append(last.indexes, index)
+			continue
+		}
+
+		if _, ok := p.peek("."); ok {
+			p.position++
+
+			if p.position >= len(p.tokens) || p.tokens[p.position].kind != tokenIdentifier {
+				return nil, fmt.Errorf("expected a name after %q", path.String())
+			}
+
+			path.parts = append(path.parts, pathPart{name: p.tokens[p.position].text})
+			p.position++
+			continue
+		}
+
+		return path, nil
+	}
+}
+
+// call parses the arguments of a function.
+func (p *parser) call(name string) (node, error) {
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var arguments []node
+
+	if _, ok := p.peek(")"); !ok {
+		for {
+			argument, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+
+			if _, ok := p.peek(","); !ok {
+				break
+			}
+			p.position++
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(arguments) < f.min || (f.max >= 0 && len(arguments) > f.max) {
+		return nil, fmt.Errorf("invalid number of arguments of %s: %d", name, len(arguments))
+	}
+
+	if name == "exists" {
+		if _, ok := arguments[0].(*pathNode); !ok {
+			return nil, fmt.Errorf("the argument of exists must be a path")
+		}
+	}
+
+	return &callNode{name: name, arguments: arguments}, nil
+}
+
+//#endregion
@@ -0,0 +1,63 @@
+package meta
+
+import (
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	cases := map[string]struct {
+		text  string
+		valid bool
+	}{
+		"number":            {"1.5e2", true},
+		"path":              {"move.axes[0].homed", true},
+		"nested indexes":    {"heat.heaters[tools[0].heaters[0]].current", true},
+		"conditional":       {"var.x > 2 ? \"big\" : \"small\"", true},
+		"function":          {"max(1, 2, 3)", true},
+		"exists":            {"exists(global.count)", true},
+		"braces":            {"{1 + 2} * 3", true},
+		"unknown function":  {"foo(1)", false},
+		"arguments":         {"atan2(1)", false},
+		"exists of number":  {"exists(1)", false},
+		"unclosed string":   {"\"abc", false},
+		"unclosed paren":    {"(1 + 2", false},
+		"trailing operator": {"1 +", false},
+		"trailing token":    {"1 2", false},
+		"empty":             {"", false},
+		"invalid character": {"1 @ 2", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseExpression(tc.text)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	cases := map[string]struct {
+		value interface{}
+		want  string
+	}{
+		"integer":  {float64(2), "2"},
+		"decimals": {1.0 / 3, "0.333333"},
+		"bool":     {true, "true"},
+		"null":     {nil, "null"},
+		"string":   {"abc", "abc"},
+		"array":    {[]interface{}{1, 2.5, "a"}, "{1,2.5,a}"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := FormatValue(tc.value); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
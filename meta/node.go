@@ -0,0 +1,386 @@
+package meta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//#region nodes
+
+// node is a node of the tree of an expression.
+type node interface {
+	// evaluate returns the value of the node in a scope
+	evaluate(s *scope) (interface{}, error)
+}
+
+// literalNode is a number, a string or a constant.
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) evaluate(s *scope) (interface{}, error) {
+	return n.value, nil
+}
+
+// pathPart is a name of a path with the indexes that follow it, like axes[0].
+type pathPart struct {
+	name    string
+	indexes []node
+}
+
+// pathNode is a path of the object model or a variable, like move.axes[0].homed or var.count.
+type pathNode struct {
+	parts []pathPart
+}
+
+// String returns the path without evaluating the indexes.
+func (n *pathNode) String() string {
+	names := make([]string, 0, len(n.parts))
+	for _, part := range n.parts {
+		names = append(names, part.name+strings.Repeat("[]", len(part.indexes)))
+	}
+
+	return strings.Join(names, ".")
+}
+
+func (n *pathNode) evaluate(s *scope) (interface{}, error) {
+	path, err := n.resolve(s)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := s.lookup(path)
+	if !ok {
+		return nil, fmt.Errorf("unknown value %s", path)
+	}
+
+	return normalize(value), nil
+}
+
+// resolve returns the path with the indexes evaluated, like move.axes[0].homed.
+func (n *pathNode) resolve(s *scope) (string, error) {
+	var sb strings.Builder
+
+	for i, part := range n.parts {
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(part.name)
+
+		for _, index := range part.indexes {
+			value, err := index.evaluate(s)
+			if err != nil {
+				return "", err
+			}
+
+			number, ok := value.(float64)
+			if !ok || number < 0 || number != math.Trunc(number) {
+				return "", fmt.Errorf("invalid index of %s: %s", part.name, FormatValue(value))
+			}
+
+			sb.WriteString("[" + strconv.Itoa(int(number)) + "]")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// unaryNode is an unary operator applied to an operand.
+type unaryNode struct {
+	operator string
+	operand  node
+}
+
+func (n *unaryNode) evaluate(s *scope) (interface{}, error) {
+	value, err := n.operand.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "!":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("the operand of ! must be a boolean: %s", FormatValue(value))
+		}
+		return !b, nil
+	case "#":
+		switch v := value.(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("the operand of # must be an array or a string: %s", FormatValue(value))
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("the operand of %s must be a number: %s", n.operator, FormatValue(value))
+	}
+
+	if n.operator == "-" {
+		return -number, nil
+	}
+
+	return number, nil
+}
+
+// binaryNode is a binary operator applied to two operands.
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+func (n *binaryNode) evaluate(s *scope) (interface{}, error) {
+	left, err := n.left.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// the logical operators are short-circuited
+	switch n.operator {
+	case "&", "&&", "|", "||":
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("the operands of %s must be booleans: %s", n.operator, FormatValue(left))
+		}
+
+		and := n.operator[0] == '&'
+		if l != and {
+			return l, nil
+		}
+
+		right, err := n.right.evaluate(s)
+		if err != nil {
+			return nil, err
+		}
+
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("the operands of %s must be booleans: %s", n.operator, FormatValue(right))
+		}
+		return r, nil
+	}
+
+	right, err := n.right.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "^":
+		return FormatValue(left) + FormatValue(right), nil
+	case "=", "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("the operands of %s must be numbers: %s and %s", n.operator, FormatValue(left), FormatValue(right))
+	}
+
+	switch n.operator {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	}
+
+	return l >= r, nil
+}
+
+// conditionalNode is the conditional operator, condition ? then : otherwise.
+type conditionalNode struct {
+	condition, then, otherwise node
+}
+
+func (n *conditionalNode) evaluate(s *scope) (interface{}, error) {
+	value, err := n.condition.evaluate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("the condition of ? must be a boolean: %s", FormatValue(value))
+	}
+
+	if condition {
+		return n.then.evaluate(s)
+	}
+
+	return n.otherwise.evaluate(s)
+}
+
+// callNode is a call of a function.
+type callNode struct {
+	name      string
+	arguments []node
+}
+
+func (n *callNode) evaluate(s *scope) (interface{}, error) {
+	// exists doesn't evaluate its path, it verifies that it can be resolved
+	if n.name == "exists" {
+		path, err := n.arguments[0].(*pathNode).resolve(s)
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := s.lookup(path)
+		return ok, nil
+	}
+
+	arguments := make([]float64, 0, len(n.arguments))
+	for _, argument := range n.arguments {
+		value, err := argument.evaluate(s)
+		if err != nil {
+			return nil, err
+		}
+
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("the arguments of %s must be numbers: %s", n.name, FormatValue(value))
+		}
+		arguments = append(arguments, number)
+	}
+
+	return functions[n.name].call(arguments)
+}
+
+//#endregion
+//#region functions
+
+// function is a function of the expressions with its number of arguments, max is -1 if it is unlimited.
+type function struct {
+	min, max int
+	call     func(arguments []float64) (interface{}, error)
+}
+
+// unaryFunction returns a function of one argument.
+func unaryFunction(f func(float64) float64) function {
+	return function{min: 1, max: 1, call: func(arguments []float64) (interface{}, error) {
+		return f(arguments[0]), nil
+	}}
+}
+
+// functions are the functions of the expressions indexed by name.
+var functions = map[string]function{
+	"abs":     unaryFunction(math.Abs),
+	"acos":    unaryFunction(math.Acos),
+	"asin":    unaryFunction(math.Asin),
+	"atan":    unaryFunction(math.Atan),
+	"ceil":    unaryFunction(math.Ceil),
+	"cos":     unaryFunction(math.Cos),
+	"degrees": unaryFunction(func(r float64) float64 { return r * 180 / math.Pi }),
+	"floor":   unaryFunction(math.Floor),
+	"radians": unaryFunction(func(d float64) float64 { return d * math.Pi / 180 }),
+	"sin":     unaryFunction(math.Sin),
+	"sqrt":    unaryFunction(math.Sqrt),
+	"square":  unaryFunction(func(x float64) float64 { return x * x }),
+	"tan":     unaryFunction(math.Tan),
+	"atan2": {min: 2, max: 2, call: func(arguments []float64) (interface{}, error) {
+		return math.Atan2(arguments[0], arguments[1]), nil
+	}},
+	"isnan": {min: 1, max: 1, call: func(arguments []float64) (interface{}, error) {
+		return math.IsNaN(arguments[0]), nil
+	}},
+	"mod": {min: 2, max: 2, call: func(arguments []float64) (interface{}, error) {
+		if arguments[1] == 0 {
+			return nil, fmt.Errorf("modulo by zero")
+		}
+		return math.Mod(arguments[0], arguments[1]), nil
+	}},
+	"max": {min: 1, max: -1, call: func(arguments []float64) (interface{}, error) {
+		result := arguments[0]
+		for _, a := range arguments[1:] {
+			result = math.Max(result, a)
+		}
+		return result, nil
+	}},
+	"min": {min: 1, max: -1, call: func(arguments []float64) (interface{}, error) {
+		result := arguments[0]
+		for _, a := range arguments[1:] {
+			result = math.Min(result, a)
+		}
+		return result, nil
+	}},
+	// exists is evaluated by its node, it only declares its arguments
+	"exists": {min: 1, max: 1},
+}
+
+//#endregion
+//#region private functions
+
+// normalize converts the numbers of the object model to float64 and the arrays to []interface{}.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []float64:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return items
+	case []string:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return items
+	}
+
+	return value
+}
+
+// equal returns true if two values are equal, the values of different types aren't equal.
+func equal(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case float64, bool, string, nil:
+		return a == b
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(normalize(x[i]), normalize(y[i])) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+//#endregion
@@ -0,0 +1,445 @@
+package meta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region statement struct
+
+// StatementKind identifies the kind of a statement of a program.
+type StatementKind int
+
+const (
+	// StatementGcode is a line of gcode, with or without expressions between braces, like "G1 X{var.x}".
+	StatementGcode StatementKind = iota
+
+	// StatementIf executes its body if its condition is true.
+	StatementIf
+
+	// StatementElif executes its body if its condition is true and the previous conditions of the chain were false.
+	StatementElif
+
+	// StatementElse executes its body if the previous conditions of the chain were false.
+	StatementElse
+
+	// StatementWhile executes its body while its condition is true.
+	StatementWhile
+
+	// StatementBreak leaves the innermost loop.
+	StatementBreak
+
+	// StatementContinue starts the next iteration of the innermost loop.
+	StatementContinue
+
+	// StatementVar declares a variable in the current body.
+	StatementVar
+
+	// StatementGlobal declares a global variable.
+	StatementGlobal
+
+	// StatementSet assigns a value to a variable declared.
+	StatementSet
+
+	// StatementEcho writes the values of its expressions.
+	StatementEcho
+
+	// StatementAbort ends the program with an optional message.
+	StatementAbort
+)
+
+// String returns the keyword of the kind, like "if", or "gcode".
+func (k StatementKind) String() string {
+	switch k {
+	case StatementGcode:
+		return "gcode"
+	case StatementIf:
+		return "if"
+	case StatementElif:
+		return "elif"
+	case StatementElse:
+		return "else"
+	case StatementWhile:
+		return "while"
+	case StatementBreak:
+		return "break"
+	case StatementContinue:
+		return "continue"
+	case StatementVar:
+		return "var"
+	case StatementGlobal:
+		return "global"
+	case StatementSet:
+		return "set"
+	case StatementEcho:
+		return "echo"
+	case StatementAbort:
+		return "abort"
+	}
+
+	return fmt.Sprintf("StatementKind(%d)", int(k))
+}
+
+// Statement is a line of a program, a meta command or a line of gcode.
+type Statement struct {
+	// Kind is the kind of the statement.
+	Kind StatementKind
+
+	// Line is the number of the line of the statement in the source, from one.
+	Line int
+
+	// Text is the text of the line without its indentation.
+	Text string
+
+	// Name is the name of the variable of var, global and set, like "count" for var and "var.count" for set.
+	Name string
+
+	// Expression is the condition of if, elif and while, the value of var, global and set, and the message of abort.
+	// It is nil for the rest, and for abort without message.
+	Expression *Expression
+
+	// Arguments are the expressions of echo.
+	Arguments []*Expression
+
+	// Body are the statements of if, elif, else and while, indented below them.
+	Body []*Statement
+
+	// template of a line of gcode, its text split by the expressions between braces
+	template []templatePart
+}
+
+// templatePart is a text of a line of gcode, or an expression between braces if the expression isn't nil.
+type templatePart struct {
+	text       string
+	expression *Expression
+}
+
+//#endregion
+//#region program struct
+
+// Program is the tree of statements of a source of RepRapFirmware, nested by their indentation.
+type Program struct {
+	// Statements are the statements of the first level.
+	Statements []*Statement
+}
+
+// ParseProgram parses the lines of a source of RepRapFirmware, like a macro.
+// The blank lines and the comments are ignored, and the bodies of if, elif, else and while are the lines indented below them.
+//
+// It returns an error if some meta command or expression is invalid, or the indentation is inconsistent.
+func ParseProgram(source io.Reader) (*Program, error) {
+	reader := bufio.NewReader(source)
+
+	// the lines are read without a limit of length
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read program: %w", err)
+		}
+	}
+
+	return parseLines(lines)
+}
+
+// FromDocument returns the program of the lines of a document, parsed with the meta commands enabled
+// by ParseConfigurer.SetMetaCommands.
+//
+// It returns an error like ParseProgram.
+func FromDocument(d *document.Document) (*Program, error) {
+	lines := make([]string, 0, d.LineCount())
+	for _, l := range d.Lines() {
+		lines = append(lines, l.String())
+	}
+
+	return parseLines(lines)
+}
+
+//#endregion
+//#region private functions
+
+// frame is a body that receives the statements of a level of indentation.
+type frame struct {
+	body *[]*Statement
+
+	// indentation of the statements of the body, -1 until the first one, and the indentation of the statement that opened it
+	indentation int
+	parent      int
+}
+
+// parseLines parses the lines of a program.
+func parseLines(lines []string) (*Program, error) {
+	program := &Program{}
+	stack := []*frame{{body: &program.Statements, parent: -1}}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		indentation := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		// the lines less indented close the bodies
+		top := stack[len(stack)-1]
+		for top.indentation >= 0 && indentation < top.indentation {
+			stack = stack[:len(stack)-1]
+			top = stack[len(stack)-1]
+		}
+
+		if top.indentation < 0 {
+			if indentation <= top.parent {
+				return nil, fmt.Errorf("failed to parse line %d, expected an indented body", i+1)
+			}
+			top.indentation = indentation
+		}
+
+		if indentation != top.indentation {
+			return nil, fmt.Errorf("failed to parse line %d, unexpected indentation", i+1)
+		}
+
+		statement, err := parseStatement(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", i+1, err)
+		}
+		statement.Line = i + 1
+
+		if statement.Kind == StatementElif || statement.Kind == StatementElse {
+			body := *top.body
+			if len(body) == 0 || (body[len(body)-1].Kind != StatementIf && body[len(body)-1].Kind != StatementElif) {
+				return nil, fmt.Errorf("failed to parse line %d, %s without if", i+1, statement.Kind)
+			}
+		}
+
+		*top.body = append(*top.body, statement)
+
+		switch statement.Kind {
+		case StatementIf, StatementElif, StatementElse, StatementWhile:
+			stack = append(stack, &frame{body: &statement.Body, indentation: -1, parent: indentation})
+		}
+	}
+
+	if top := stack[len(stack)-1]; top.indentation < 0 && len(stack) > 1 {
+		return nil, fmt.Errorf("failed to parse program, expected an indented body at the end")
+	}
+
+	return program, nil
+}
+
+// parseStatement parses a line without indentation.
+func parseStatement(text string) (*Statement, error) {
+	if !document.IsMetaCommand(text) {
+		template, err := parseTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{Kind: StatementGcode, Text: text, template: template}, nil
+	}
+
+	code := stripComment(text)
+
+	keyword := code
+	rest := ""
+	if i := strings.IndexAny(code, " \t{"); i >= 0 {
+		keyword, rest = code[:i], strings.TrimSpace(code[i:])
+	}
+
+	statement := &Statement{Text: text}
+
+	switch keyword {
+	case "if", "elif", "while":
+		statement.Kind = map[string]StatementKind{"if": StatementIf, "elif": StatementElif, "while": StatementWhile}[keyword]
+
+		expression, err := ParseExpression(rest)
+		if err != nil {
+			return nil, err
+		}
+		statement.Expression = expression
+	case "else", "break", "continue":
+		statement.Kind = map[string]StatementKind{"else": StatementElse, "break": StatementBreak, "continue": StatementContinue}[keyword]
+
+		if rest != "" {
+			return nil, fmt.Errorf("%s doesn't accept arguments: %s", keyword, rest)
+		}
+	case "var", "global", "set":
+		statement.Kind = map[string]StatementKind{"var": StatementVar, "global": StatementGlobal, "set": StatementSet}[keyword]
+
+		name, value, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validName(name, keyword == "set") {
+			return nil, fmt.Errorf("invalid %s, expected a name, an equal sign and an expression: %s", keyword, rest)
+		}
+
+		expression, err := ParseExpression(value)
+		if err != nil {
+			return nil, err
+		}
+		statement.Name, statement.Expression = name, expression
+	case "echo":
+		statement.Kind = StatementEcho
+
+		if strings.HasPrefix(rest, ">") {
+			return nil, fmt.Errorf("echo to files isn't supported: %s", rest)
+		}
+
+		for _, argument := range splitArguments(rest) {
+			expression, err := ParseExpression(argument)
+			if err != nil {
+				return nil, err
+			}
+			statement.Arguments = append(statement.Arguments, expression)
+		}
+	case "abort":
+		statement.Kind = StatementAbort
+
+		if rest != "" {
+			expression, err := ParseExpression(rest)
+			if err != nil {
+				return nil, err
+			}
+			statement.Expression = expression
+		}
+	}
+
+	return statement, nil
+}
+
+// parseTemplate splits a line of gcode by its expressions between braces, before its comment.
+func parseTemplate(text string) ([]templatePart, error) {
+	var parts []templatePart
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ';' {
+			break
+		}
+
+		if text[i] != '{' {
+			continue
+		}
+
+		end, err := closingBrace(text, i)
+		if err != nil {
+			return nil, err
+		}
+
+		expression, err := ParseExpression(text[i+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, templatePart{text: text[start:i]}, templatePart{expression: expression})
+		start = end + 1
+		i = end
+	}
+
+	return append(parts, templatePart{text: text[start:]}), nil
+}
+
+// closingBrace returns the position of the brace that closes the one at the position received, skipping the strings.
+func closingBrace(text string, open int) (int, error) {
+	depth := 0
+	quoted := false
+
+	for i := open; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("unclosed brace in %s", text)
+}
+
+// stripComment removes the comment after a semicolon outside of the strings.
+func stripComment(text string) string {
+	quoted := false
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return strings.TrimSpace(text[:i])
+			}
+		}
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// splitArguments splits the arguments of echo by the commas outside of the strings, the parentheses, the brackets and the braces.
+func splitArguments(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	var arguments []string
+	depth := 0
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			arguments = append(arguments, text[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(arguments, text[start:])
+}
+
+// validName returns true if the name is an identifier, or a qualified variable like var.count if it is qualified.
+func validName(name string, qualified bool) bool {
+	if qualified {
+		scope, rest, ok := strings.Cut(name, ".")
+		if !ok || (scope != "var" && scope != "global") {
+			return false
+		}
+		name = rest
+	}
+
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && numeric.IsDigit(c))) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//#endregion
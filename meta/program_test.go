@@ -0,0 +1,95 @@
+package meta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+// kinds returns the kinds of the statements and their bodies, in order, with the bodies between parentheses.
+func kinds(statements []*Statement) string {
+	var sb strings.Builder
+	for i, st := range statements {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(st.Kind.String())
+		if len(st.Body) > 0 {
+			sb.WriteString("(" + kinds(st.Body) + ")")
+		}
+	}
+
+	return sb.String()
+}
+
+func TestParseProgram(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		want   string
+	}{
+		"gcode":       {"G28\nG1 X{move.axes[0].max - 10} ; comment\n", "gcode gcode"},
+		"conditional": {"if move.axes[0].homed\n  G1 X0\nelif true\n  G28 X\nelse\n  M118 S\"x\"\nG4 P0", "if(gcode) elif(gcode) else(gcode) gcode"},
+		"nested":      {"var i = 0\nwhile true\n  if iterations > 2\n    break\n  set var.i = var.i + 1\necho var.i", "var while(if(break) set) echo"},
+		"comments":    {"; header\n\nglobal n = 1\n  ; indented comment\nabort \"done\"", "global abort"},
+		"long line":   {"; " + strings.Repeat("x", 70000) + "\nG28\n", "gcode"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParseProgram(strings.NewReader(tc.source))
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if got := kinds(p.Statements); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseProgram_errors(t *testing.T) {
+	cases := map[string]string{
+		"else without if":   "G28\nelse\n  G1 X0",
+		"empty body":        "if true\nG28",
+		"empty body at end": "while true",
+		"indentation":       "if true\n    G28\n  G29",
+		"unexpected indent": "G28\n  G29",
+		"invalid var":       "var 1x = 2",
+		"set without scope": "set count = 2",
+		"break arguments":   "while true\n  break 2",
+		"echo to file":      "echo >\"file.txt\" 1",
+		"invalid template":  "G1 X{1 +}",
+	}
+
+	for name, source := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseProgram(strings.NewReader(source)); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestFromDocument(t *testing.T) {
+	d, err := document.Parse(strings.NewReader("if heat.heaters[0].current > 200\n  M104 S{heat.heaters[0].current - 10}\nG28\n"), func(config document.ParseConfigurer) error {
+		return config.SetMetaCommands(true)
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	p, err := FromDocument(d)
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if got, want := kinds(p.Statements), "if(gcode) gcode"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, want := p.Statements[0].Body[0].Line, 2; got != want {
+		t.Errorf("got line %d, want %d", got, want)
+	}
+}
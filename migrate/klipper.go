@@ -0,0 +1,243 @@
+package migrate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/commands"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region marlin to klipper
+
+// marlinToKlipperRules are the rules that translate the commands of Marlin that Klipper doesn't support, or supports in other way.
+var marlinToKlipperRules = map[string]rule{
+	"M0":   pause,
+	"M1":   pause,
+	"M125": pause,
+	"M601": pause,
+	"M600": changeFilament,
+	"M602": resume,
+	"M900": pressureAdvance,
+	"M201": maxAcceleration,
+	"M203": maxFeedrate,
+	"M204": acceleration,
+	"M205": jerk,
+	"M290": babystep,
+	"M303": autotune,
+	"G29":  bedLeveling,
+	"M420": bedLevelingState,
+	"M106": peripheral,
+	"M107": peripheral,
+	"M150": peripheral,
+}
+
+// marlinToKlipperReasons explain why some commands of Marlin can't be translated to Klipper.
+var marlinToKlipperReasons = map[string]string{
+	"M92":  "the steps per unit are defined in the configuration of Klipper",
+	"M301": "the PID of the hotend is defined in the configuration of Klipper",
+	"M304": "the PID of the bed is defined in the configuration of Klipper",
+	"M500": "the settings are saved with SAVE_CONFIG, which restarts Klipper",
+	"M501": "the settings are loaded from the configuration of Klipper at its start",
+	"M502": "the settings are loaded from the configuration of Klipper at its start",
+	"M503": "the settings are defined in the configuration of Klipper",
+	"M851": "the offset of the probe is defined in the configuration of Klipper",
+}
+
+// NewMarlinToKlipper returns a new translator of the documents of Marlin to Klipper, configured with the options received.
+//
+// It replaces the pauses M0, M1, M125, M601 and M600 by PAUSE, M602 by RESUME, M900 by SET_PRESSURE_ADVANCE,
+// the limits M201, M203, M204 and M205 by SET_VELOCITY_LIMIT, the babystepping M290 by SET_GCODE_OFFSET, M303 by PID_CALIBRATE,
+// the bed leveling G29 and M420 by BED_MESH_CALIBRATE, BED_MESH_PROFILE and BED_MESH_CLEAR, and M150 by SET_LED.
+// The commands that Klipper doesn't support and have no equivalent, like M500, are flagged.
+//
+// It returns an error if some option fails.
+func NewMarlinToKlipper(options ...TranslatorConfigurationCallbackable) (*Translator, error) {
+	return newTranslator("klipper", marlinToKlipperRules, marlinToKlipperReasons, options...)
+}
+
+//#endregion
+//#region rules
+
+// pause translates the commands that stop the print until the user resumes it.
+func pause(t *Translator, b block.Blocker) translation {
+	return translation{text: "PAUSE", notes: ignored(b, "")}
+}
+
+// changeFilament translates M600 to PAUSE, the filament is changed by the user.
+func changeFilament(t *Translator, b block.Blocker) translation {
+	notes := append([]string{"the filament isn't unloaded and loaded by PAUSE"}, ignored(b, "")...)
+
+	return translation{text: "PAUSE", notes: notes}
+}
+
+// resume translates M602.
+func resume(t *Translator, b block.Blocker) translation {
+	return translation{text: "RESUME", notes: ignored(b, "")}
+}
+
+// pressureAdvance translates the factor K of the linear advance, M900, with the extruder selected by T.
+func pressureAdvance(t *Translator, b block.Blocker) translation {
+	k, ok := transform.Parameter(b, 'K')
+	if !ok {
+		return translation{notes: []string{"M900 without K doesn't set the pressure advance"}}
+	}
+
+	text := "SET_PRESSURE_ADVANCE ADVANCE=" + format(k)
+	if tool, ok := transform.Parameter(b, 'T'); ok {
+		text += " EXTRUDER=" + extruder(tool)
+	}
+
+	return translation{text: text, notes: ignored(b, "KT")}
+}
+
+// maxAcceleration translates the maximum acceleration of the axes X and Y, M201, to the maximum acceleration of the printer.
+func maxAcceleration(t *Translator, b block.Blocker) translation {
+	return velocityLimit(b, "ACCEL", "XY")
+}
+
+// maxFeedrate translates the maximum feedrate of the axes X and Y, M203, to the maximum velocity of the printer.
+func maxFeedrate(t *Translator, b block.Blocker) translation {
+	return velocityLimit(b, "VELOCITY", "XY")
+}
+
+// acceleration translates the acceleration of the moves, M204, with S or with the lowest of the printing and travel accelerations.
+func acceleration(t *Translator, b block.Blocker) translation {
+	if _, ok := transform.Parameter(b, 'S'); ok {
+		return velocityLimit(b, "ACCEL", "S")
+	}
+
+	return velocityLimit(b, "ACCEL", "PT")
+}
+
+// jerk translates the jerk of the axes X and Y, M205, to the square corner velocity.
+func jerk(t *Translator, b block.Blocker) translation {
+	return velocityLimit(b, "SQUARE_CORNER_VELOCITY", "XY")
+}
+
+// babystep translates the babystepping of the axis Z, M290.
+func babystep(t *Translator, b block.Blocker) translation {
+	z, ok := transform.Parameter(b, 'Z')
+	if !ok {
+		return translation{notes: []string{"M290 without Z can't be translated"}}
+	}
+
+	return translation{text: "SET_GCODE_OFFSET Z_ADJUST=" + format(z) + " MOVE=1", notes: ignored(b, "Z")}
+}
+
+// autotune translates the autotune of the PID of a heater, M303, the hotend selected by E or the bed with E-1.
+func autotune(t *Translator, b block.Blocker) translation {
+	target, ok := transform.Parameter(b, 'S')
+	if !ok {
+		return translation{notes: []string{"M303 without S hasn't the target temperature"}}
+	}
+
+	heater := "extruder"
+	if index, ok := transform.Parameter(b, 'E'); ok {
+		heater = extruder(index)
+		if index < 0 {
+			heater = "heater_bed"
+		}
+	}
+
+	return translation{text: "PID_CALIBRATE HEATER=" + heater + " TARGET=" + format(target), notes: ignored(b, "ES")}
+}
+
+// bedLeveling translates the probing of the bed, G29.
+func bedLeveling(t *Translator, b block.Blocker) translation {
+	return translation{text: "BED_MESH_CALIBRATE", notes: ignored(b, "")}
+}
+
+// bedLevelingState translates the bed leveling enabled, M420 S1, or disabled, M420 S0.
+func bedLevelingState(t *Translator, b block.Blocker) translation {
+	enabled, ok := transform.Parameter(b, 'S')
+	if !ok {
+		return translation{notes: []string{"M420 without S doesn't enable or disable the bed leveling"}}
+	}
+
+	if enabled == 0 {
+		return translation{text: "BED_MESH_CLEAR", notes: ignored(b, "S")}
+	}
+
+	return translation{text: "BED_MESH_PROFILE LOAD=default", notes: ignored(b, "S")}
+}
+
+// peripheral translates the commands whose text differs between the firmwares, like the fans and the LEDs.
+func peripheral(t *Translator, b block.Blocker) translation {
+	c, err := commands.Interpret(b)
+	if err != nil {
+		return translation{notes: []string{err.Error()}}
+	}
+
+	command, ok := c.(commands.DialectCommander)
+	if !ok {
+		return translation{notes: []string{fmt.Sprintf("%s isn't a command of a peripheral", b.Command())}}
+	}
+
+	if led, ok := command.(*commands.SetLED); ok {
+		led.Name = t.ledName
+	}
+
+	text, err := command.Line(t.target)
+	if err != nil {
+		return translation{notes: []string{err.Error()}}
+	}
+
+	return translation{text: text}
+}
+
+//#endregion
+//#region private functions
+
+// velocityLimit returns SET_VELOCITY_LIMIT with an argument that is the lowest value of the words accepted.
+// The block can't be translated if it hasn't any of them.
+func velocityLimit(b block.Blocker, argument string, accepted string) translation {
+	value, found := 0.0, false
+
+	for i := 0; i < len(accepted); i++ {
+		if v, ok := transform.Parameter(b, accepted[i]); ok && (!found || v < value) {
+			value, found = v, true
+		}
+	}
+
+	if !found {
+		return translation{notes: []string{fmt.Sprintf("%s without %s can't be translated", b.Command(), strings.Join(strings.Split(accepted, ""), " or "))}}
+	}
+
+	return translation{text: "SET_VELOCITY_LIMIT " + argument + "=" + format(value), notes: ignored(b, accepted)}
+}
+
+// ignored returns a note with the words of the parameters of a block that aren't accepted, or nil if all are accepted.
+func ignored(b block.Blocker, accepted string) []string {
+	var words []string
+
+	for _, p := range b.Parameters() {
+		if !strings.ContainsRune(accepted, rune(p.Word())) {
+			words = append(words, string(p.Word()))
+		}
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	return []string{"ignored " + strings.Join(words, " ")}
+}
+
+// extruder returns the name of an extruder of Klipper by its index, like "extruder" or "extruder1".
+func extruder(index float64) string {
+	if index <= 0 {
+		return "extruder"
+	}
+
+	return "extruder" + format(index)
+}
+
+// format returns the shortest text of a value.
+func format(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+//#endregion
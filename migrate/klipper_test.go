@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestMarlinToKlipper(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		want   string
+		flags  int
+	}{
+		"supported":              {"G28\nG1 X10 Y10 F3000\nM104 S200\n", "G28\nG1 X10 Y10 F3000\nM104 S200\n", 0},
+		"comments kept":          {";LAYER:1\n\nM83\n", ";LAYER:1\n\nM83\n", 0},
+		"pressure advance":       {"M900 K0.05\n", "SET_PRESSURE_ADVANCE ADVANCE=0.05\n", 0},
+		"pressure advance tool":  {"M900 K0.1 T1\n", "SET_PRESSURE_ADVANCE ADVANCE=0.1 EXTRUDER=extruder1\n", 0},
+		"pressure advance query": {"M900\n", "M900\n", 1},
+		"jerk":                   {"M205 X8 Y10\n", "SET_VELOCITY_LIMIT SQUARE_CORNER_VELOCITY=8\n", 0},
+		"jerk ignored":           {"M205 X8 Y8 Z0.4 E5\n", "SET_VELOCITY_LIMIT SQUARE_CORNER_VELOCITY=8\n", 1},
+		"junction deviation":     {"M205 J0.013\n", "M205 J0.013\n", 1},
+		"acceleration":           {"M204 S1500\n", "SET_VELOCITY_LIMIT ACCEL=1500\n", 0},
+		"acceleration travel":    {"M204 P1000 T3000\n", "SET_VELOCITY_LIMIT ACCEL=1000\n", 0},
+		"max feedrate":           {"M203 X500 Y400 Z12\n", "SET_VELOCITY_LIMIT VELOCITY=400\n", 1},
+		"max acceleration":       {"M201 X3000 Y2000\n", "SET_VELOCITY_LIMIT ACCEL=2000\n", 0},
+		"babystep":               {"M290 Z-0.02\n", "SET_GCODE_OFFSET Z_ADJUST=-0.02 MOVE=1\n", 0},
+		"pauses":                 {"M0\nM125\nM601\nM602\n", "PAUSE\nPAUSE\nPAUSE\nRESUME\n", 0},
+		"filament change":        {"M600\n", "PAUSE\n", 1},
+		"autotune":               {"M303 E0 S210 C8\nM303 E-1 S60\n", "PID_CALIBRATE HEATER=extruder TARGET=210\nPID_CALIBRATE HEATER=heater_bed TARGET=60\n", 1},
+		"bed leveling":           {"G29\nM420 S1\nM420 S0\n", "BED_MESH_CALIBRATE\nBED_MESH_PROFILE LOAD=default\nBED_MESH_CLEAR\n", 0},
+		"fan":                    {"M106 S128\nM107\n", "M106 S128\nM107\n", 0},
+		"fan selected":           {"M106 P1 S255\n", "M106 P1 S255\n", 1},
+		"led without name":       {"M150 R255\n", "M150 R255\n", 1},
+		"untranslatable":         {"M500\nM851 Z-1.2\nG34\n", "M500\nM851 Z-1.2\nG34\n", 3},
+		"comment":                {"M900 K0.05 ;linear advance\n", "SET_PRESSURE_ADVANCE ADVANCE=0.05 ;linear advance\n", 0},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			translator, err := NewMarlinToKlipper()
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			got := translate(t, translator, tc.source)
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			if flags := translator.Flags(); len(flags) != tc.flags {
+				t.Errorf("got flags %v, want %d flags", flags, tc.flags)
+			}
+		})
+	}
+}
+
+func TestTranslator_options(t *testing.T) {
+
+	t.Run("led name", func(t *testing.T) {
+		translator, err := NewMarlinToKlipper(func(config TranslatorConfigurer) error {
+			return config.SetLEDName("my_neopixel")
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		want := "SET_LED LED=my_neopixel RED=1 GREEN=0 BLUE=0 WHITE=0\n"
+		if got := translate(t, translator, "M150 R255\n"); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("invalid led name", func(t *testing.T) {
+		_, err := NewMarlinToKlipper(func(config TranslatorConfigurer) error {
+			return config.SetLEDName("my neopixel")
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("comment out", func(t *testing.T) {
+		translator, err := NewMarlinToKlipper(func(config TranslatorConfigurer) error {
+			return config.SetCommentOut(true)
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		want := "G28\n; M500\n"
+		if got := translate(t, translator, "G28\nM500\n"); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		flags := translator.Flags()
+		if len(flags) != 1 || flags[0].Index != 1 || flags[0].Translated {
+			t.Errorf("got flags %v, want M500 flagged at line 1", flags)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		translator, err := NewMarlinToKlipper(func(config TranslatorConfigurer) error {
+			return config.SetStrict(true)
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		if _, err := translator.Translate(parse(t, "M900 K0.05\nM500\n")); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+// parse parses a source with the words of Marlin.
+func parse(t *testing.T, source string) *document.Document {
+	t.Helper()
+
+	marlin, err := dialect.Get("marlin")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	options, err := marlin.ParseOptions()
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	doc, err := document.Parse(strings.NewReader(source), options...)
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	return doc
+}
+
+// translate parses a source, translates it and returns the document exported.
+func translate(t *testing.T, translator *Translator, source string) string {
+	t.Helper()
+
+	translated, err := translator.Translate(parse(t, source))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	return translated.String()
+}
@@ -0,0 +1,246 @@
+// migrate package rewrites the documents written for a firmware into the commands of another one, easing the
+// migration of the gcode libraries when a printer changes its firmware.
+//
+// A Translator replaces each block that the target firmware doesn't support by its nearest equivalent, like
+// "M900 K0.05" of Marlin by "SET_PRESSURE_ADVANCE ADVANCE=0.05" of Klipper, and flags the lines that can't be
+// translated or lose some word, so they can be reviewed before printing:
+//
+//	translator, err := migrate.NewMarlinToKlipper()
+//	translated, err := translator.Translate(doc)
+//	for _, flag := range translator.Flags() {
+//		fmt.Println(flag)
+//	}
+//
+// The lines without gcode and the blocks that the target supports are copied without changes.
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+//#region flag
+
+// Flag describes a line that couldn't be translated, or was translated losing some of its words.
+type Flag struct {
+	// Index is the position of the line in the source document.
+	Index int
+
+	// Line is the line as it was in the source document.
+	Line string
+
+	// Message explains the problem.
+	Message string
+
+	// Translated is true if the line was replaced by its translation, false if it was kept or commented out.
+	Translated bool
+}
+
+// String returns the flag formatted.
+func (f Flag) String() string {
+	return fmt.Sprintf("line %d (%s): %s", f.Index, f.Line, f.Message)
+}
+
+//#endregion
+//#region translator configuration
+
+// TranslatorConfigurer defines the options of a translator.
+type TranslatorConfigurer interface {
+	// Set if an untranslatable line fails the translation
+	SetStrict(enabled bool) error
+
+	// Set if the untranslatable lines are commented out
+	SetCommentOut(enabled bool) error
+
+	// Set the name of the LED of the target firmware
+	SetLEDName(name string) error
+}
+
+// TranslatorConfigurationCallbackable is the signature of the callbacks used to configure a translator.
+type TranslatorConfigurationCallbackable func(config TranslatorConfigurer) error
+
+// translatorConfigurator implements TranslatorConfigurer.
+type translatorConfigurator struct {
+	strict     bool
+	commentOut bool
+	ledName    string
+}
+
+// SetStrict defines if Translate returns an error at the first line that can't be translated, instead of flagging it.
+// If this method isn't called, by default the untranslatable lines are flagged.
+func (tc *translatorConfigurator) SetStrict(enabled bool) error {
+	tc.strict = enabled
+
+	return nil
+}
+
+// SetCommentOut defines if the lines that can't be translated are written as comments, so the target firmware ignores them.
+// If this method isn't called, by default they are kept without changes.
+func (tc *translatorConfigurator) SetCommentOut(enabled bool) error {
+	tc.commentOut = enabled
+
+	return nil
+}
+
+// SetLEDName defines the name of the LED in the configuration of the target firmware, like "my_neopixel",
+// required by the firmwares that address the LEDs by their names. It can't contain spaces.
+// If this method isn't called, by default the colors of the LEDs can't be translated to those firmwares.
+func (tc *translatorConfigurator) SetLEDName(name string) error {
+	if strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("failed to set LED name, it can't contain spaces: %q", name)
+	}
+
+	tc.ledName = name
+
+	return nil
+}
+
+//#endregion
+//#region translator
+
+// translation is the result of a rule applied to a block.
+type translation struct {
+	// text of the line that replaces the block
+	text string
+
+	// notes about the words lost in the translation, or the reason why the block can't be translated if text is empty
+	notes []string
+}
+
+// rule translates a block of a command.
+type rule func(t *Translator, b block.Blocker) translation
+
+// Translator rewrites the blocks of a firmware into the commands of another one.
+type Translator struct {
+	translatorConfigurator
+
+	// dialect that defines the commands supported by the target firmware
+	target *dialect.Dialect
+
+	// rules indexed by the command translated, like "M900"
+	rules map[string]rule
+
+	// reasons why the commands without rules can't be translated, indexed by the command
+	reasons map[string]string
+
+	// flags of the last document translated
+	flags []Flag
+}
+
+// newTranslator returns a new translator from a dialect to another one, configured with the options received.
+func newTranslator(target string, rules map[string]rule, reasons map[string]string, options ...TranslatorConfigurationCallbackable) (*Translator, error) {
+	d, err := dialect.Get(target)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Translator{
+		target:  d,
+		rules:   rules,
+		reasons: reasons,
+	}
+
+	for _, option := range options {
+		if err := option(&t.translatorConfigurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// Flags returns a copy of the flags of the last document translated, in the order of their lines.
+func (t *Translator) Flags() []Flag {
+	flags := make([]Flag, len(t.flags))
+	copy(flags, t.flags)
+
+	return flags
+}
+
+// Translate returns a new document with the blocks of the document received translated to the target firmware.
+// The source document isn't modified.
+//
+// It returns an error if the translator is strict and some line can't be translated.
+func (t *Translator) Translate(d *document.Document) (*document.Document, error) {
+	t.flags = nil
+
+	lines := d.Lines()
+	translated := make([]document.Line, 0, len(lines))
+
+	for index, line := range lines {
+		if !line.IsBlock() || line.Block.Command() == nil {
+			translated = append(translated, line)
+			continue
+		}
+
+		command := line.Block.Command().String()
+		result := t.translate(line.Block, command)
+
+		if result.text == "" {
+			if t.strict {
+				return nil, fmt.Errorf("failed to translate line %d (%s): %s", index, line, strings.Join(result.notes, ", "))
+			}
+
+			t.flags = append(t.flags, Flag{Index: index, Line: line.String(), Message: strings.Join(result.notes, ", ")})
+
+			if t.commentOut {
+				line = document.Line{Text: "; " + line.String()}
+			}
+			translated = append(translated, line)
+			continue
+		}
+
+		if len(result.notes) > 0 {
+			t.flags = append(t.flags, Flag{Index: index, Line: line.String(), Message: strings.Join(result.notes, ", "), Translated: true})
+		}
+
+		if result.text == command {
+			translated = append(translated, line)
+			continue
+		}
+
+		translated = append(translated, document.Line{Text: result.text + comment(line.Block)})
+	}
+
+	return document.NewFromLines(translated...), nil
+}
+
+// translate applies the rule of a command, or returns the command if the target supports it.
+func (t *Translator) translate(b block.Blocker, command string) translation {
+	if r, ok := t.rules[command]; ok {
+		return r(t, b)
+	}
+
+	if t.target.Supports(command) {
+		return translation{text: command}
+	}
+
+	if reason, ok := t.reasons[command]; ok {
+		return translation{notes: []string{reason}}
+	}
+
+	return translation{notes: []string{fmt.Sprintf("%s doesn't support %s", t.target.Name, command)}}
+}
+
+//#endregion
+//#region private functions
+
+// comment returns the comment of a block delimited by a semicolon and preceded by a space, or an empty string if it hasn't one.
+func comment(b block.Blocker) string {
+	text := strings.TrimSpace(b.Comment())
+	if text == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(text, "(") {
+		text = ";" + strings.TrimSuffix(strings.TrimPrefix(text, "("), ")")
+	}
+
+	return " " + text
+}
+
+//#endregion
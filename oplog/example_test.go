@@ -0,0 +1,29 @@
+package oplog_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/oplog"
+)
+
+func ExampleExport() {
+	const source = `M190 S60
+G28
+;LAYER:1
+G1 Z0.2 F1200
+`
+
+	err := oplog.Export(os.Stdout, strings.NewReader(source), oplog.Text)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	// Output:
+	// 1: heat bed to 60 and wait
+	// 2: home all axes
+	// 3: print layer 1 of 1 at 0.2mm
+	// 4: move to Z=0.2 at 1200 mm/min
+}
@@ -0,0 +1,440 @@
+// oplog package converts gcode sources into a chronological log of human-readable operations.
+//
+// Each block of the source is translated into a short sentence, for example "heat bed to 60",
+// "home all axes" or "print layer 1 of 200 at 0.2mm". The resulting log can be exported as plain text,
+// JSON or Markdown and is useful to document a job or audit what a file will do before run it.
+//
+// The translation relies on a registry of describers indexed by the command of each block,
+// which read the values of the blocks through the typed commands of the commands package.
+// Commands without a describer are reported as a generic execution of the command.
+//
+// Layers are detected by the layer markers of the document package, the ";LAYER:" and ";LAYER_CHANGE" comments that most slicers insert at each layer change.
+// The total of layers is taken from the ";LAYER_COUNT:" comment if it exists, otherwise it is the number of layer markers found.
+package oplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/commands"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+// LAYER_COUNT_MARKER is the comment inserted by Cura and other slicers with the total of layers of the job.
+const LAYER_COUNT_MARKER = ";LAYER_COUNT:"
+
+//#region format
+
+// Format defines the output representation used by Export.
+type Format int
+
+const (
+	// Text exports each operation in a single line prefixed with his source line number.
+	Text Format = iota
+
+	// JSON exports the operations as a JSON array.
+	JSON
+
+	// Markdown exports the operations as a Markdown table.
+	Markdown
+)
+
+//#endregion
+//#region operation
+
+// Operation represents a single entry of the log.
+type Operation struct {
+	// Line is the line number, starting at one, of the source that produced the operation.
+	Line int `json:"line"`
+
+	// Layer is the layer number active when the operation is executed. It is zero before the first layer.
+	Layer int `json:"layer"`
+
+	// Source is the gcode expression that produced the operation, without comments.
+	Source string `json:"source"`
+
+	// Description is the human-readable sentence that explains the operation.
+	Description string `json:"description"`
+}
+
+//#endregion
+//#region describers
+
+// Describer is the signature of the functions that translate a block into a human-readable sentence.
+type Describer func(b block.Blocker) string
+
+// describers stores the describer of each command known by the package indexed by its expression, like "G1" or "M104".
+//
+// It is never modified, the describers registered are stored in the registries.
+var describers = map[string]Describer{
+	"G0":   describeMove("travel"),
+	"G1":   describeMove("move"),
+	"G2":   describeMove("clockwise arc"),
+	"G3":   describeMove("counterclockwise arc"),
+	"G4":   describeDwell,
+	"G20":  fixed("use inches as units"),
+	"G21":  fixed("use millimeters as units"),
+	"G28":  describeHome,
+	"G90":  fixed("use absolute positioning"),
+	"G91":  fixed("use relative positioning"),
+	"G92":  describeSetPosition,
+	"M82":  fixed("use absolute extrusion"),
+	"M83":  fixed("use relative extrusion"),
+	"M84":  fixed("disable motors"),
+	"M104": describeTemperature("set hotend temperature to %s"),
+	"M106": describeFan,
+	"M107": fixed("turn fan off"),
+	"M109": describeTemperature("heat hotend to %s and wait"),
+	"M140": describeTemperature("set bed temperature to %s"),
+	"M190": describeTemperature("heat bed to %s and wait"),
+}
+
+// fixed returns a describer that always returns the same sentence.
+func fixed(sentence string) Describer {
+	return func(b block.Blocker) string {
+		return sentence
+	}
+}
+
+// describeMove returns a describer for motion commands that lists the target of each axis.
+func describeMove(verb string) Describer {
+	return func(b block.Blocker) string {
+		var x, y, z, e, f *float64
+
+		switch c := interpret(b).(type) {
+		case *commands.LinearMove:
+			x, y, z, e, f = c.X, c.Y, c.Z, c.E, c.F
+		case *commands.ArcMove:
+			x, y, z, e, f = c.X, c.Y, c.Z, c.E, c.F
+		default:
+			return describeUnknown(b)
+		}
+
+		var axes []string
+		for i, value := range []*float64{x, y, z} {
+			if value != nil {
+				axes = append(axes, fmt.Sprintf("%c=%s", "XYZ"[i], numeric.Format(*value)))
+			}
+		}
+
+		sentence := verb
+		if e != nil {
+			sentence = fmt.Sprintf("%s extruding", sentence)
+		}
+
+		if len(axes) > 0 {
+			sentence = fmt.Sprintf("%s to %s", sentence, strings.Join(axes, " "))
+		}
+
+		if f != nil {
+			sentence = fmt.Sprintf("%s at %s mm/min", sentence, numeric.Format(*f))
+		}
+
+		return sentence
+	}
+}
+
+// describeDwell explains a G4 command, in seconds if the pause is a whole number of seconds or in milliseconds otherwise.
+func describeDwell(b block.Blocker) string {
+	dwell, ok := interpret(b).(*commands.Dwell)
+	if !ok {
+		return describeUnknown(b)
+	}
+
+	switch {
+	case dwell.Duration == 0:
+		return "wait for moves to finish"
+	case dwell.Duration%time.Second == 0:
+		return fmt.Sprintf("wait %s s", numeric.Format(dwell.Duration.Seconds()))
+	}
+
+	return fmt.Sprintf("wait %s ms", numeric.Format(float64(dwell.Duration)/float64(time.Millisecond)))
+}
+
+// describeHome explains a G28 command listing the axes to home.
+func describeHome(b block.Blocker) string {
+	var axes []string
+	for _, p := range b.Parameters() {
+		axes = append(axes, string(p.Word()))
+	}
+
+	if len(axes) == 0 {
+		return "home all axes"
+	}
+
+	return fmt.Sprintf("home %s", strings.Join(axes, " "))
+}
+
+// describeSetPosition explains a G92 command listing the new position of each axis.
+func describeSetPosition(b block.Blocker) string {
+	var axes []string
+	for _, p := range b.Parameters() {
+		if value, ok := transform.Parameter(b, p.Word()); ok {
+			axes = append(axes, fmt.Sprintf("%s=%s", string(p.Word()), numeric.Format(value)))
+		}
+	}
+
+	return fmt.Sprintf("set position %s", strings.Join(axes, " "))
+}
+
+// describeTemperature returns a describer that formats the target of a temperature command.
+func describeTemperature(format string) Describer {
+	return func(b block.Blocker) string {
+		c, ok := interpret(b).(commands.TemperatureCommander)
+		if !ok {
+			return describeUnknown(b)
+		}
+
+		return fmt.Sprintf(format, numeric.Format(c.Target()))
+	}
+}
+
+// describeFan explains a M106 command converting the speed in a percent.
+func describeFan(b block.Blocker) string {
+	c, ok := interpret(b).(*commands.SetFanSpeed)
+	if !ok {
+		return describeUnknown(b)
+	}
+
+	return fmt.Sprintf("set fan speed to %s%%", strconv.FormatFloat(c.Speed*100, 'f', 0, 64))
+}
+
+// describeUnknown explains a block without describer as a generic execution of its command.
+func describeUnknown(b block.Blocker) string {
+	if tool, ok := interpret(b).(*commands.ToolChange); ok {
+		return fmt.Sprintf("select tool %d", tool.Tool)
+	}
+
+	return fmt.Sprintf("execute %s", b.ToLine("%c %p"))
+}
+
+// interpret returns the typed command of a block, or nil if some parameter of the command is invalid.
+func interpret(b block.Blocker) commands.Commander {
+	c, err := commands.Interpret(b)
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+//#endregion
+//#region registry
+
+// Registry stores the describers registered for the commands. It is safe for concurrent use.
+//
+// The commands without a describer registered use the describers of the package.
+// The zero value is a registry without describers registered ready to use.
+type Registry struct {
+	mu         sync.RWMutex
+	describers map[string]Describer
+}
+
+// Register stores a describer for a command expression, replacing the previous one if it exists.
+//
+// command is the expression of the command, like "G1" or "M104".
+func (r *Registry) Register(command string, describer Describer) error {
+	if command == "" {
+		return fmt.Errorf("failed to register describer, command mustn't be empty")
+	}
+
+	if describer == nil {
+		return fmt.Errorf("failed to register describer for %s, it mustn't be nil", command)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.describers == nil {
+		r.describers = map[string]Describer{}
+	}
+	r.describers[command] = describer
+
+	return nil
+}
+
+// Lookup returns the describer of a command expression, the one registered or else the one of the package.
+// It returns false if the command hasn't a describer.
+func (r *Registry) Lookup(command string) (Describer, bool) {
+	r.mu.RLock()
+	describer, ok := r.describers[command]
+	r.mu.RUnlock()
+
+	if ok {
+		return describer, true
+	}
+
+	describer, ok = describers[command]
+
+	return describer, ok
+}
+
+// Describe returns the human-readable sentence of a single block.
+func (r *Registry) Describe(b block.Blocker) string {
+	if describer, ok := r.Lookup(b.Command().String()); ok {
+		return describer(b)
+	}
+
+	return describeUnknown(b)
+}
+
+// defaultRegistry is the registry used by the package functions.
+var defaultRegistry = &Registry{}
+
+// DefaultRegistry returns the registry shared by the whole program, used by Register, Describe, Build and Export.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register stores a describer for a command expression in the default registry, replacing the previous one if it exists.
+//
+// command is the expression of the command, like "G1" or "M104".
+func Register(command string, describer Describer) error {
+	return defaultRegistry.Register(command, describer)
+}
+
+// Describe returns the human-readable sentence of a single block using the default registry.
+func Describe(b block.Blocker) string {
+	return defaultRegistry.Describe(b)
+}
+
+//#endregion
+//#region package functions
+
+// Build reads a gcode source and returns the chronological list of operations that it executes.
+//
+// Blank lines are ignored. Comment lines are only used to detect layer changes.
+// If some line can't be parsed it returns an error that includes its line number.
+func Build(source io.Reader) ([]Operation, error) {
+	return defaultRegistry.Build(source)
+}
+
+// Build reads a gcode source and returns the chronological list of operations that it executes, using the describers of the registry.
+//
+// Blank lines are ignored. Comment lines are only used to detect layer changes.
+// If some line can't be parsed it returns an error that includes its line number.
+func (r *Registry) Build(source io.Reader) ([]Operation, error) {
+
+	d, err := document.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+
+	layers, err := d.Layers(func(config document.LayersConfigurer) error {
+		return config.SetDetection(document.LayerMarkers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect layers: %w", err)
+	}
+
+	layerCount := layerCount(d)
+	if layerCount == 0 {
+		layerCount = len(layers)
+	}
+
+	var operations []Operation
+	layer := 0
+	for i, l := range d.Lines() {
+		for layer < len(layers) && layers[layer].StartLine <= i {
+			layer++
+		}
+
+		if l.Block == nil {
+			if layer == 0 || layers[layer-1].StartLine != i {
+				continue
+			}
+
+			operations = append(operations, Operation{
+				Line:        i + 1,
+				Layer:       layer,
+				Source:      strings.TrimSpace(l.Text),
+				Description: fmt.Sprintf("print layer %d of %d at %smm", layer, layerCount, numeric.Format(layers[layer-1].Z)),
+			})
+			continue
+		}
+
+		operations = append(operations, Operation{
+			Line:        i + 1,
+			Layer:       layer,
+			Source:      l.Block.String(),
+			Description: r.Describe(l.Block),
+		})
+	}
+
+	return operations, nil
+}
+
+// Export writes the operation log of a gcode source in the format required.
+func Export(w io.Writer, source io.Reader, format Format) error {
+	return defaultRegistry.Export(w, source, format)
+}
+
+// Export writes the operation log of a gcode source in the format required, using the describers of the registry.
+func (r *Registry) Export(w io.Writer, source io.Reader, format Format) error {
+
+	operations, err := r.Build(source)
+	if err != nil {
+		return fmt.Errorf("failed to build operation log: %w", err)
+	}
+
+	switch format {
+	case Text:
+		for _, op := range operations {
+			if _, err := fmt.Fprintf(w, "%d: %s\n", op.Line, op.Description); err != nil {
+				return fmt.Errorf("failed to write operation of the line %d: %w", op.Line, err)
+			}
+		}
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if operations == nil {
+			operations = []Operation{}
+		}
+		if err := encoder.Encode(operations); err != nil {
+			return fmt.Errorf("failed to encode operations: %w", err)
+		}
+	case Markdown:
+		if _, err := fmt.Fprint(w, "| Line | Layer | Operation | Source |\n| ---: | ---: | --- | --- |\n"); err != nil {
+			return fmt.Errorf("failed to write table header: %w", err)
+		}
+		for _, op := range operations {
+			source := strings.ReplaceAll(op.Source, "|", "\\|")
+			if _, err := fmt.Fprintf(w, "| %d | %d | %s | `%s` |\n", op.Line, op.Layer, op.Description, source); err != nil {
+				return fmt.Errorf("failed to write operation of the line %d: %w", op.Line, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown export format %d", format)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// layerCount returns the total of layers written in the ";LAYER_COUNT:" comment, or zero if the document hasn't it.
+func layerCount(d *document.Document) int {
+	for _, l := range d.Lines() {
+		text := strings.TrimSpace(l.Text)
+		if l.Block != nil || !strings.HasPrefix(text, LAYER_COUNT_MARKER) {
+			continue
+		}
+
+		if value, err := strconv.Atoi(strings.TrimSpace(text[len(LAYER_COUNT_MARKER):])); err == nil {
+			return value
+		}
+	}
+
+	return 0
+}
+
+//#endregion
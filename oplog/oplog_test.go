@@ -0,0 +1,262 @@
+package oplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+)
+
+const mockSource = `;LAYER_COUNT:200
+M190 S60
+G28
+M109 S210
+;LAYER:1
+G1 Z0.2 F3000
+G1 X10 Y10 E1.5
+M106 S255
+;LAYER:2
+G1 Z0.4
+T1
+M84
+`
+
+func TestDescribe(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		output string
+	}{
+		"bed":          {"M140 S60", "set bed temperature to 60"},
+		"bed wait":     {"M190 S60", "heat bed to 60 and wait"},
+		"home all":     {"G28", "home all axes"},
+		"home axes":    {"G28 X0 Y0", "home X Y"},
+		"travel":       {"G0 X10.5 Y2 F6000", "travel to X=10.5 Y=2 at 6000 mm/min"},
+		"extrude":      {"G1 X1 E0.5", "move extruding to X=1"},
+		"set position": {"G92 E0", "set position E=0"},
+		"dwell":        {"G4 P500", "wait 500 ms"},
+		"fan":          {"M106 S127.5", "set fan speed to 50%"},
+		"tool":         {"T2", "select tool 2"},
+		"unknown":      {"M117 S1", "execute M117 S1"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := Describe(b); got != tc.output {
+				t.Errorf("got %s, want %s", got, tc.output)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	operations, err := Build(strings.NewReader(mockSource))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	want := []Operation{
+		{Line: 2, Layer: 0, Source: "M190 S60", Description: "heat bed to 60 and wait"},
+		{Line: 3, Layer: 0, Source: "G28", Description: "home all axes"},
+		{Line: 4, Layer: 0, Source: "M109 S210", Description: "heat hotend to 210 and wait"},
+		{Line: 5, Layer: 1, Source: ";LAYER:1", Description: "print layer 1 of 200 at 0.2mm"},
+		{Line: 6, Layer: 1, Source: "G1 Z0.2 F3000", Description: "move to Z=0.2 at 3000 mm/min"},
+		{Line: 7, Layer: 1, Source: "G1 X10 Y10 E1.5", Description: "move extruding to X=10 Y=10"},
+		{Line: 8, Layer: 1, Source: "M106 S255", Description: "set fan speed to 100%"},
+		{Line: 9, Layer: 2, Source: ";LAYER:2", Description: "print layer 2 of 200 at 0.4mm"},
+		{Line: 10, Layer: 2, Source: "G1 Z0.4", Description: "move to Z=0.4"},
+		{Line: 11, Layer: 2, Source: "T1", Description: "select tool 1"},
+		{Line: 12, Layer: 2, Source: "M84", Description: "disable motors"},
+	}
+
+	if len(operations) != len(want) {
+		t.Errorf("got %d operations, want %d operations", len(operations), len(want))
+		return
+	}
+
+	for i := range want {
+		if operations[i] != want[i] {
+			t.Errorf("got operation %+v, want operation %+v", operations[i], want[i])
+		}
+	}
+}
+
+func TestBuild_layers(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		want   []string
+	}{
+		"layer change": {
+			";LAYER_CHANGE\nG1 Z0.3\n;LAYER_CHANGE\nG1 Z0.6\n",
+			[]string{"print layer 1 of 2 at 0.3mm", "move to Z=0.3", "print layer 2 of 2 at 0.6mm", "move to Z=0.6"},
+		},
+		"long line": {
+			";LAYER:0\nG1 Z0.2 ; " + strings.Repeat("x", 70000) + "\n",
+			[]string{"print layer 1 of 1 at 0.2mm", "move to Z=0.2"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			operations, err := Build(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var got []string
+			for _, op := range operations {
+				got = append(got, op.Description)
+			}
+
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuild_ParseError(t *testing.T) {
+	_, err := Build(strings.NewReader("G28\nG 1 X0\n"))
+	if err == nil {
+		t.Errorf("got error nil, want error not nil")
+		return
+	}
+
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got error %v, want error with the line number", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	const source = "G28\nM140 S60\n"
+
+	cases := map[string]struct {
+		format Format
+		valid  bool
+		output string
+	}{
+		"text":     {Text, true, "1: home all axes\n2: set bed temperature to 60\n"},
+		"markdown": {Markdown, true, "| Line | Layer | Operation | Source |\n| ---: | ---: | --- | --- |\n| 1 | 0 | home all axes | `G28` |\n| 2 | 0 | set bed temperature to 60 | `M140 S60` |\n"},
+		"unknown":  {Format(99), false, ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			err := Export(&buffer, strings.NewReader(source), tc.format)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if buffer.String() != tc.output {
+				t.Errorf("got %q, want %q", buffer.String(), tc.output)
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := Export(&buffer, strings.NewReader(source), JSON); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		var operations []Operation
+		if err := json.Unmarshal(buffer.Bytes(), &operations); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if len(operations) != 2 || operations[1].Description != "set bed temperature to 60" {
+			t.Errorf("got %+v, want two operations", operations)
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register("", fixed("nothing")); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+
+	if err := Register("M118", nil); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+
+	r := &Registry{}
+	err := r.Register("M117", func(b block.Blocker) string { return "display a message" })
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	cases := map[string]struct {
+		registry *Registry
+		source   string
+		output   string
+	}{
+		"registered":      {r, "M117", "display a message"},
+		"package":         {r, "G28", "home all axes"},
+		"default":         {DefaultRegistry(), "M117", "execute M117"},
+		"registered only": {&Registry{}, "M117", "execute M117"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := tc.registry.Describe(b); got != tc.output {
+				t.Errorf("got %s, want %s", got, tc.output)
+			}
+		})
+	}
+}
+
+func TestRegistry_concurrent(t *testing.T) {
+	r := &Registry{}
+
+	b, err := gcodeblock.Parse("M117")
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Register("M117", fixed("display a message")); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			r.Describe(b)
+		}()
+	}
+	wg.Wait()
+
+	if got := r.Describe(b); got != "display a message" {
+		t.Errorf("got %s, want display a message", got)
+	}
+}
@@ -0,0 +1,32 @@
+package preflight_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/preflight"
+)
+
+func ExamplePreflight() {
+	const source = `G28
+M104 S300
+G1 X10 Y10 E1.0
+`
+
+	doc, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	report := preflight.Preflight(doc, preflight.Profile{
+		MaxHotendTemperature: 260,
+	})
+
+	fmt.Println(report)
+
+	// Output:
+	// preflight failed (executed: parse, dialect, parameters, checksum, numbering, temperatures; skipped: bounds, start)
+	// [temperatures] line 2: hotend temperature 300 exceeds the maximum 260
+}
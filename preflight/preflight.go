@@ -0,0 +1,523 @@
+// preflight package bundles the most important structural validations of a gcode document in a single call.
+//
+// It is intended to gate the files before they are queued to a machine.
+// The validations are executed in order and the process is aborted as soon as one of them fails,
+// so a file with syntax errors is never evaluated against the machine limits.
+//
+// The checks reuse the validations of the rest of the library. They are executed, in order:
+//
+// - parse: every line must be a valid block, a comment, a blank line or a statement recognized by the document package.
+//
+// - dialect: the commands must be supported by the dialect of the machine, if the profile defines it.
+//
+// - parameters: the parameters of the commands described by the spec package must be accepted by them and have the type described, verified by spec.Validate.
+//
+// - checksum: each block that includes a checksum must be verified, and the policy of checksums of the dialect must be respected.
+//
+// - numbering: the line numbers, when are present, must follow the sequence verified by Document.VerifyLineNumbers.
+//
+// - temperatures: the temperatures commanded, interpreted by the commands package, can't exceed the limits of the profile.
+//
+// - bounds: the toolpath simulated, with the arcs interpolated, the homing, the moves of G53 and the inches converted to millimeters,
+// can't exceed the working area of the profile. It is checked by analysis.Bounds in the coordinates of the machine.
+//
+// - start: the commands required by the profile must be executed before the first extrusion, tracked by the state package.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/analysis"
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/commands"
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/spec"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+const (
+	// CHECK_PARSE identifies the validation of the syntax of each line.
+	CHECK_PARSE = "parse"
+
+	// CHECK_DIALECT identifies the validation of the commands supported by the machine.
+	CHECK_DIALECT = "dialect"
+
+	// CHECK_PARAMETERS identifies the validation of the parameters of the commands.
+	CHECK_PARAMETERS = "parameters"
+
+	// CHECK_CHECKSUM identifies the verification of the checksum of each block.
+	CHECK_CHECKSUM = "checksum"
+
+	// CHECK_NUMBERING identifies the validation of the sequence of line numbers.
+	CHECK_NUMBERING = "numbering"
+
+	// CHECK_TEMPERATURES identifies the validation of the temperatures commanded.
+	CHECK_TEMPERATURES = "temperatures"
+
+	// CHECK_BOUNDS identifies the validation of the working area.
+	CHECK_BOUNDS = "bounds"
+
+	// CHECK_START identifies the validation of the required start commands.
+	CHECK_START = "start"
+)
+
+//#region profile
+
+// Profile describes the machine that will execute the source.
+//
+// The zero value of each field disables the check that depends on it.
+type Profile struct {
+	// Dialect is the firmware of the machine, it defines the commands supported and the policy of checksums.
+	// If it is nil then the dialect check accepts any command and the checksums are optional.
+	Dialect *dialect.Dialect
+
+	// MaxHotendTemperature is the maximum target allowed for M104 and M109 commands.
+	MaxHotendTemperature float64
+
+	// MaxBedTemperature is the maximum target allowed for M140 and M190 commands.
+	MaxBedTemperature float64
+
+	// Min is the lower corner of the working area in millimeters, indexed as X, Y and Z.
+	Min [3]float64
+
+	// Max is the upper corner of the working area in millimeters, indexed as X, Y and Z.
+	// If all its values are zero then the bounds check is disabled.
+	Max [3]float64
+
+	// RequiredStartCommands is the list of commands, like "G28", that must be executed before the first extrusion.
+	// The extended commands of Klipper, like "PRINT_START", are accepted too.
+	RequiredStartCommands []string
+}
+
+// NewProfile returns the profile of a machine that runs a dialect, with the limits and the start commands of the dialect.
+func NewProfile(d *dialect.Dialect) Profile {
+	return Profile{
+		Dialect:               d,
+		MaxHotendTemperature:  d.MaxHotendTemperature,
+		MaxBedTemperature:     d.MaxBedTemperature,
+		Min:                   d.Min,
+		Max:                   d.Max,
+		RequiredStartCommands: append([]string(nil), d.StartCommands...),
+	}
+}
+
+//#endregion
+//#region report
+
+// Issue describes a single failure found by a check.
+type Issue struct {
+	// Check is the name of the check that found the issue.
+	Check string
+
+	// Line is the source line number, starting at one. It is zero when the issue isn't related to a single line.
+	Line int
+
+	// Message describes the failure.
+	Message string
+}
+
+// String returns the issue formatted.
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("[%s] %s", i.Check, i.Message)
+	}
+
+	return fmt.Sprintf("[%s] line %d: %s", i.Check, i.Line, i.Message)
+}
+
+// Report contains the verdict of the preflight and the issues found.
+type Report struct {
+	// Passed is true if all checks were executed without issues.
+	Passed bool
+
+	// Executed lists the checks executed, in order.
+	Executed []string
+
+	// Skipped lists the checks that weren't executed because a previous check failed.
+	Skipped []string
+
+	// Issues lists the failures found by the last check executed.
+	Issues []Issue
+}
+
+// String returns a summary of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+
+	if r.Passed {
+		sb.WriteString("preflight passed")
+	} else {
+		sb.WriteString("preflight failed")
+	}
+
+	sb.WriteString(fmt.Sprintf(" (executed: %s", strings.Join(r.Executed, ", ")))
+	if len(r.Skipped) > 0 {
+		sb.WriteString(fmt.Sprintf("; skipped: %s", strings.Join(r.Skipped, ", ")))
+	}
+	sb.WriteString(")")
+
+	for _, issue := range r.Issues {
+		sb.WriteString("\n")
+		sb.WriteString(issue.String())
+	}
+
+	return sb.String()
+}
+
+//#endregion
+//#region package functions
+
+// Preflight validates a document against a machine profile. The result of the validation is described by the report.
+func Preflight(doc *document.Document, profile Profile) *Report {
+
+	t := newTarget(doc)
+
+	checks := []struct {
+		name string
+		run  func(*target, Profile) []Issue
+	}{
+		{CHECK_PARSE, checkParse},
+		{CHECK_DIALECT, checkDialect},
+		{CHECK_PARAMETERS, checkParameters},
+		{CHECK_CHECKSUM, checkChecksum},
+		{CHECK_NUMBERING, checkNumbering},
+		{CHECK_TEMPERATURES, checkTemperatures},
+		{CHECK_BOUNDS, checkBounds},
+		{CHECK_START, checkStart},
+	}
+
+	report := &Report{Passed: true}
+
+	for i, check := range checks {
+		report.Executed = append(report.Executed, check.name)
+
+		issues := check.run(t, profile)
+		if len(issues) == 0 {
+			continue
+		}
+
+		report.Passed = false
+		report.Issues = issues
+		for _, skipped := range checks[i+1:] {
+			report.Skipped = append(report.Skipped, skipped.name)
+		}
+		break
+	}
+
+	return report
+}
+
+// PreflightSource parses a gcode source, with the parse options of the dialect of the profile if it has one,
+// and validates the document against the profile.
+//
+// A line that can't be parsed fails the parse check and the rest of the checks are skipped.
+// It returns an error only if the source can't be read or the dialect is invalid. The result of the validation is described by the report.
+func PreflightSource(source io.Reader, profile Profile) (*Report, error) {
+
+	var options []document.ParseConfigurationCallbackable
+	if profile.Dialect != nil {
+		var err error
+		if options, err = profile.Dialect.ParseOptions(); err != nil {
+			return nil, fmt.Errorf("failed to load dialect: %w", err)
+		}
+	}
+
+	doc, err := document.Parse(source, options...)
+
+	var parseErr *document.ParseError
+	if errors.As(err, &parseErr) {
+		report := &Report{
+			Executed: []string{CHECK_PARSE},
+			Skipped:  []string{CHECK_DIALECT, CHECK_PARAMETERS, CHECK_CHECKSUM, CHECK_NUMBERING, CHECK_TEMPERATURES, CHECK_BOUNDS, CHECK_START},
+			Issues:   []Issue{{Check: CHECK_PARSE, Line: parseErr.Line, Message: parseErr.Err.Error()}},
+		}
+		return report, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+
+	return Preflight(doc, profile), nil
+}
+
+//#endregion
+//#region checks
+
+// target is the document validated, with the line number of each block.
+type target struct {
+	doc    *document.Document
+	blocks []block.Blocker
+
+	// lines are the line numbers of the blocks, starting at one
+	lines []int
+}
+
+// newTarget returns the target of a document.
+func newTarget(doc *document.Document) *target {
+	t := &target{doc: doc, blocks: doc.Blocks()}
+
+	for i, l := range doc.Lines() {
+		if l.Block != nil {
+			t.lines = append(t.lines, i+1)
+		}
+	}
+
+	return t
+}
+
+// checkParse reports the lines that aren't blocks, comments, extended commands nor macro statements.
+func checkParse(t *target, profile Profile) []Issue {
+	var issues []Issue
+
+	for i, l := range t.doc.Lines() {
+		if l.Block != nil || isComment(l.Text) || isStatement(l.Text) {
+			continue
+		}
+
+		issues = append(issues, Issue{Check: CHECK_PARSE, Line: i + 1, Message: fmt.Sprintf("%q isn't a block nor a comment", strings.TrimSpace(l.Text))})
+	}
+
+	return issues
+}
+
+// checkDialect reports the commands, extended commands and macro statements that the dialect of the machine doesn't support.
+func checkDialect(t *target, profile Profile) []Issue {
+	d := profile.Dialect
+	if d == nil {
+		return nil
+	}
+
+	var issues []Issue
+	for i, l := range t.doc.Lines() {
+		if l.Block != nil {
+			if command := l.Block.Command().String(); !d.Supports(command) {
+				issues = append(issues, Issue{Check: CHECK_DIALECT, Line: i + 1, Message: fmt.Sprintf("command %s isn't supported by the machine", command)})
+			}
+			continue
+		}
+
+		text := strings.TrimSpace(l.Text)
+		if isComment(text) {
+			continue
+		}
+
+		switch {
+		case document.IsExtendedCommand(text) && !d.ExtendedCommands:
+			issues = append(issues, Issue{Check: CHECK_DIALECT, Line: i + 1, Message: fmt.Sprintf("extended command %s isn't supported by the machine", text)})
+		case document.IsMacroStatement(text) && !d.MacroStatements:
+			issues = append(issues, Issue{Check: CHECK_DIALECT, Line: i + 1, Message: fmt.Sprintf("macro statement %s isn't supported by the machine", text)})
+		}
+	}
+
+	return issues
+}
+
+// checkParameters reports the parameters that the specification of their command doesn't accept or whose values aren't valid.
+func checkParameters(t *target, profile Profile) []Issue {
+	var issues []Issue
+
+	for i, b := range t.blocks {
+		if err := spec.Validate(b); err != nil {
+			issues = append(issues, Issue{Check: CHECK_PARAMETERS, Line: t.lines[i], Message: err.Error()})
+		}
+	}
+
+	return issues
+}
+
+// checkChecksum reports the checksums that don't match, are missing when the machine requires them or are present when it rejects them.
+func checkChecksum(t *target, profile Profile) []Issue {
+	policy := dialect.CHECKSUM_OPTIONAL
+	if profile.Dialect != nil && profile.Dialect.Checksum != "" {
+		policy = profile.Dialect.Checksum
+	}
+
+	var issues []Issue
+
+	for i, b := range t.blocks {
+		line := t.lines[i]
+
+		if b.Checksum() == nil {
+			if policy == dialect.CHECKSUM_REQUIRED {
+				issues = append(issues, Issue{Check: CHECK_CHECKSUM, Line: line, Message: "checksum is required by the machine"})
+			}
+			continue
+		}
+
+		if policy == dialect.CHECKSUM_NONE {
+			issues = append(issues, Issue{Check: CHECK_CHECKSUM, Line: line, Message: "checksums aren't accepted by the machine"})
+			continue
+		}
+
+		ok, err := b.VerifyChecksum()
+		if err != nil {
+			issues = append(issues, Issue{Check: CHECK_CHECKSUM, Line: line, Message: err.Error()})
+			continue
+		}
+
+		if !ok {
+			expected, _ := b.CalculateChecksum()
+			issues = append(issues, Issue{Check: CHECK_CHECKSUM, Line: line, Message: fmt.Sprintf("checksum %s doesn't match, expected %s", b.Checksum(), expected)})
+		}
+	}
+
+	return issues
+}
+
+// checkNumbering reports the blocks without line number and the line numbers that leave a gap, are repeated or go back without M110.
+func checkNumbering(t *target, profile Profile) []Issue {
+	report, err := t.doc.VerifyLineNumbers()
+	if err != nil {
+		return []Issue{{Check: CHECK_NUMBERING, Message: err.Error()}}
+	}
+
+	var issues []Issue
+	for _, issue := range report.Issues {
+		message := "line number is missing"
+		if issue.Kind != document.LineNumberMissing {
+			message = fmt.Sprintf("line number N%d is a %s, expected N%d", issue.LineNumber, issue.Kind, issue.Expected)
+		}
+
+		issues = append(issues, Issue{Check: CHECK_NUMBERING, Line: t.lines[issue.Index], Message: message})
+	}
+
+	return issues
+}
+
+// checkTemperatures reports the hotend and bed temperatures that exceed the maximums of the profile.
+func checkTemperatures(t *target, profile Profile) []Issue {
+	var issues []Issue
+
+	for i, b := range t.blocks {
+		c, err := commands.Interpret(b)
+		if err != nil {
+			continue
+		}
+
+		temperature, ok := c.(commands.TemperatureCommander)
+		if !ok {
+			continue
+		}
+
+		var limit float64
+		var heater string
+
+		switch temperature.Kind() {
+		case state.HeaterHotend:
+			limit, heater = profile.MaxHotendTemperature, "hotend"
+		case state.HeaterBed:
+			limit, heater = profile.MaxBedTemperature, "bed"
+		}
+
+		if limit == 0 {
+			continue
+		}
+
+		if target := temperature.Target(); target > limit {
+			issues = append(issues, Issue{Check: CHECK_TEMPERATURES, Line: t.lines[i], Message: fmt.Sprintf("%s temperature %v exceeds the maximum %v", heater, target, limit)})
+		}
+	}
+
+	return issues
+}
+
+// checkBounds reports the first point of the toolpath out of the working area of the profile.
+func checkBounds(t *target, profile Profile) []Issue {
+	if profile.Max == [3]float64{} {
+		return nil
+	}
+
+	limits := analysis.Box{
+		Min: analysis.Point{X: profile.Min[0], Y: profile.Min[1], Z: profile.Min[2]},
+		Max: analysis.Point{X: profile.Max[0], Y: profile.Max[1], Z: profile.Max[2]},
+	}
+
+	report, err := analysis.Bounds(t.blocks,
+		func(config analysis.BoundsConfigurer) error {
+			return config.SetLimits(limits)
+		},
+		func(config analysis.BoundsConfigurer) error {
+			return config.SetSimulatorOptions(func(config simulator.SimulatorConfigurer) error {
+				return config.SetMachineCoordinates(true)
+			})
+		},
+	)
+	if err != nil {
+		return []Issue{{Check: CHECK_BOUNDS, Message: err.Error()}}
+	}
+
+	if v := report.Violation; v != nil {
+		return []Issue{{Check: CHECK_BOUNDS, Line: t.lines[v.Index], Message: fmt.Sprintf("%s is out of the working area %s", v.Point, limits)}}
+	}
+
+	return nil
+}
+
+// checkStart reports the required start commands that aren't executed before the first extrusion.
+func checkStart(t *target, profile Profile) []Issue {
+	if len(profile.RequiredStartCommands) == 0 {
+		return nil
+	}
+
+	found := make(map[string]bool, len(profile.RequiredStartCommands))
+	firstExtrusion := 0
+	machine := &state.Machine{}
+
+	for i, l := range t.doc.Lines() {
+		if l.Block == nil {
+			if name, _, ok := document.ParseExtendedCommand(l.Text); ok {
+				found[name] = true
+			}
+			continue
+		}
+
+		command := l.Block.Command().String()
+		before := machine.Apply(l.Block)
+
+		if (command == "G1" || command == "G2" || command == "G3") && machine.Snapshot().Position.E > before.Position.E {
+			firstExtrusion = i + 1
+			break
+		}
+
+		found[command] = true
+	}
+
+	var issues []Issue
+	for _, required := range profile.RequiredStartCommands {
+		if found[required] {
+			continue
+		}
+
+		if firstExtrusion == 0 {
+			issues = append(issues, Issue{Check: CHECK_START, Message: fmt.Sprintf("required command %s isn't executed", required)})
+		} else {
+			issues = append(issues, Issue{Check: CHECK_START, Line: firstExtrusion, Message: fmt.Sprintf("required command %s isn't executed before the first extrusion", required)})
+		}
+	}
+
+	return issues
+}
+
+//#endregion
+//#region private functions
+
+// isComment returns true if the text is blank or only contains a comment, with a semicolon or in parentheses.
+func isComment(text string) bool {
+	text = strings.TrimSpace(text)
+
+	return text == "" || strings.HasPrefix(text, ";") || (strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")"))
+}
+
+// isStatement returns true if the text is a statement that the document package keeps as text when it is enabled,
+// an extended command of Klipper, a meta command of RepRapFirmware or a macro statement of Fanuc.
+func isStatement(text string) bool {
+	return document.IsExtendedCommand(text) || document.IsMetaCommand(text) || document.HasExpression(text) || document.IsMacroStatement(text)
+}
+
+//#endregion
@@ -0,0 +1,264 @@
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/dialect"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestPreflight(t *testing.T) {
+
+	profile := Profile{
+		MaxHotendTemperature:  260,
+		MaxBedTemperature:     110,
+		Max:                   [3]float64{200, 200, 180},
+		RequiredStartCommands: []string{"G28"},
+	}
+
+	cases := map[string]struct {
+		source   string
+		profile  Profile
+		passed   bool
+		check    string
+		line     int
+		executed int
+	}{
+		"valid": {
+			source:   "; start\nG28\nM104 S210\nG1 X10 Y10 Z0.2\nG1 X20 E1.0\n",
+			profile:  profile,
+			passed:   true,
+			executed: 8,
+		},
+		"empty profile": {
+			source:   "G1 X1000 E1.0\nM104 S900\n",
+			passed:   true,
+			executed: 8,
+		},
+		"parse error": {
+			source:   "G28\nG 1 X10\n",
+			profile:  profile,
+			check:    CHECK_PARSE,
+			line:     2,
+			executed: 1,
+		},
+		"unsupported command": {
+			source:   "G28\nM600\n",
+			profile:  Profile{Dialect: &dialect.Dialect{Commands: []string{"G28", "G1"}}},
+			check:    CHECK_DIALECT,
+			line:     2,
+			executed: 2,
+		},
+		"parameter not accepted": {
+			source:   "G28\nG1 X10 S200\n",
+			profile:  profile,
+			check:    CHECK_PARAMETERS,
+			line:     2,
+			executed: 3,
+		},
+		"parameter not integer": {
+			source:   "G28\nM106 P1.5 S255\n",
+			profile:  profile,
+			check:    CHECK_PARAMETERS,
+			line:     2,
+			executed: 3,
+		},
+		"checksum mismatch": {
+			source:   "N4 G92 E0*67\nN5 G28*10\n",
+			profile:  profile,
+			check:    CHECK_CHECKSUM,
+			line:     2,
+			executed: 4,
+		},
+		"numbering gap": {
+			source:   "N4 G92 E0*67\nN6 G28\n",
+			profile:  profile,
+			check:    CHECK_NUMBERING,
+			line:     2,
+			executed: 5,
+		},
+		"numbering reset": {
+			source:   "N4 G92 E0*67\nN0 M110\nN1 G28\n",
+			profile:  profile,
+			passed:   true,
+			executed: 8,
+		},
+		"hotend too hot": {
+			source:   "G28\nM109 S300\n",
+			profile:  profile,
+			check:    CHECK_TEMPERATURES,
+			line:     2,
+			executed: 6,
+		},
+		"bed too hot": {
+			source:   "G28\nM140 S120\n",
+			profile:  profile,
+			check:    CHECK_TEMPERATURES,
+			line:     2,
+			executed: 6,
+		},
+		"out of bounds": {
+			source:   "G28\nG1 X10 Y10\nG91\nG1 X195\n",
+			profile:  profile,
+			check:    CHECK_BOUNDS,
+			line:     4,
+			executed: 7,
+		},
+		"negative bounds": {
+			source:   "G28\nG1 X-1\n",
+			profile:  profile,
+			check:    CHECK_BOUNDS,
+			line:     2,
+			executed: 7,
+		},
+		"checksum required": {
+			source:   "N1 G28*18\nN2 G1 X10\n",
+			profile:  Profile{Dialect: &dialect.Dialect{Checksum: dialect.CHECKSUM_REQUIRED}},
+			check:    CHECK_CHECKSUM,
+			line:     2,
+			executed: 4,
+		},
+		"checksum not accepted": {
+			source:   "N1 G28*18\n",
+			profile:  Profile{Dialect: &dialect.Dialect{Checksum: dialect.CHECKSUM_NONE}},
+			check:    CHECK_CHECKSUM,
+			line:     1,
+			executed: 4,
+		},
+		"numbering missing": {
+			source:   "N4 G92 E0*67\nG28\n",
+			profile:  profile,
+			check:    CHECK_NUMBERING,
+			line:     2,
+			executed: 5,
+		},
+		"out of bounds in inches": {
+			source:   "G28\nG20\nG1 X7.5\nG1 X8\n",
+			profile:  profile,
+			check:    CHECK_BOUNDS,
+			line:     4,
+			executed: 7,
+		},
+		"out of bounds by an arc": {
+			source:   "G28\nG1 X10 Y190\nG2 X40 Y190 I15 J0\n",
+			profile:  profile,
+			check:    CHECK_BOUNDS,
+			line:     3,
+			executed: 7,
+		},
+		"out of bounds by G53": {
+			source:   "G28\nG92 X100\nG1 X150\nG53 G0 X-5\n",
+			profile:  profile,
+			check:    CHECK_BOUNDS,
+			line:     4,
+			executed: 7,
+		},
+		"inside bounds with an offset": {
+			source:   "G28\nG1 X150\nG92 X0\nG1 X40\n",
+			profile:  profile,
+			passed:   true,
+			executed: 8,
+		},
+		"relative extrusion": {
+			source:   "M83\nG1 X10 E-1\nG28\nG1 X20 E1\n",
+			profile:  profile,
+			passed:   true,
+			executed: 8,
+		},
+		"extended start command": {
+			source:   "PRINT_START\nG1 X10 E1\n",
+			profile:  Profile{Dialect: &dialect.Dialect{ExtendedCommands: true}, RequiredStartCommands: []string{"PRINT_START"}},
+			passed:   true,
+			executed: 8,
+		},
+		"missing start command": {
+			source:   "M104 S200\nG1 X10 E2.0\nG28\n",
+			profile:  profile,
+			check:    CHECK_START,
+			line:     2,
+			executed: 8,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report, err := PreflightSource(strings.NewReader(tc.source), tc.profile)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if report.Passed != tc.passed {
+				t.Errorf("got passed %v, want passed %v: %s", report.Passed, tc.passed, report)
+				return
+			}
+
+			if len(report.Executed) != tc.executed {
+				t.Errorf("got %d checks executed, want %d: %s", len(report.Executed), tc.executed, report)
+			}
+
+			if len(report.Executed)+len(report.Skipped) != 8 {
+				t.Errorf("got %d checks in total, want 8", len(report.Executed)+len(report.Skipped))
+			}
+
+			if tc.passed {
+				if len(report.Issues) != 0 {
+					t.Errorf("got issues %v, want none", report.Issues)
+				}
+				return
+			}
+
+			if len(report.Issues) == 0 {
+				t.Errorf("got no issues, want issues")
+				return
+			}
+
+			if report.Issues[0].Check != tc.check || report.Issues[0].Line != tc.line {
+				t.Errorf("got issue %s, want issue of check %s at line %d", report.Issues[0], tc.check, tc.line)
+			}
+		})
+	}
+}
+
+func TestPreflight_document(t *testing.T) {
+	b, err := gcodeblock.Parse("G28")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	doc := document.NewFromLines(document.Line{Text: "; start"}, document.Line{Block: b}, document.Line{Text: "G 1 X10"})
+
+	report := Preflight(doc, Profile{})
+	if report.Passed || len(report.Issues) != 1 || report.Issues[0].Check != CHECK_PARSE || report.Issues[0].Line != 3 {
+		t.Errorf("got report %v, want a parse issue at line 3", report)
+	}
+}
+
+func TestPreflightSource_longLine(t *testing.T) {
+	report, err := PreflightSource(strings.NewReader("G28\nG1 X10 ;"+strings.Repeat("x", 70000)+"\nM104 S300\n"), Profile{MaxHotendTemperature: 260})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if report.Passed || len(report.Issues) != 1 || report.Issues[0].Line != 3 {
+		t.Errorf("got report %v, want the temperature of the line 3 exceeded", report)
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	d, err := dialect.Get("grbl")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	report, err := PreflightSource(strings.NewReader("G0 X10\nM104 S200\n"), NewProfile(d))
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	if report.Passed || len(report.Issues) != 1 || report.Issues[0].Check != CHECK_DIALECT || report.Issues[0].Line != 2 {
+		t.Errorf("got report %v, want M104 unsupported", report)
+	}
+}
@@ -0,0 +1,219 @@
+// preview package renders the toolpath of a sequence of blocks layer by layer, as polylines that separate the travel moves
+// from the extrusions, so a web interface or a command line tool can draw a preview without a geometry engine.
+//
+// The toolpath is simulated with the simulator package and the layers are detected like analysis.Layers does.
+// A preview is exported as JSON, with all layers, or as SVG, one layer per image.
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/analysis"
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/simulator"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region preview struct
+
+// Point is a point of the XY plane, in millimeters.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Polyline is a sequence of connected moves of the same kind.
+type Polyline struct {
+	// Extrusion is true if the moves extrude, false if they are travel moves.
+	Extrusion bool `json:"extrusion"`
+
+	// Points are the points visited by the moves, in order, starting at the position before the first move.
+	Points []Point `json:"points"`
+}
+
+// Layer contains the polylines of a layer.
+type Layer struct {
+	// Index is the index of the layer, starting at zero.
+	Index int `json:"index"`
+
+	// Z is the height at which the layer is extruded, it is zero if the blocks don't extrude.
+	Z float64 `json:"z"`
+
+	// Polylines are the moves of the layer in order. A layer starts at its first extrusion, so the travel moves to it
+	// belong to the previous layer, and the moves before the first extrusion belong to the first layer.
+	Polylines []Polyline `json:"polylines"`
+}
+
+// Preview contains the polylines of all layers.
+type Preview struct {
+	// Layers are the layers in order.
+	Layers []Layer `json:"layers"`
+
+	// Min and Max are the corners of the box that contains all points, they are zero if there aren't moves.
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+// WriteJSON writes the preview as a JSON object with its layers and its box.
+func (p *Preview) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		return fmt.Errorf("failed to write preview: %w", err)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region preview configuration
+
+// PreviewConfigurer defines the options of a preview.
+type PreviewConfigurer interface {
+	// Set if the travel moves are included
+	SetTravel(travel bool) error
+
+	// Set the options of the simulation of the toolpath
+	SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error
+}
+
+// PreviewConfigurationCallbackable is the signature of the callbacks used to configure a preview.
+type PreviewConfigurationCallbackable func(config PreviewConfigurer) error
+
+// previewConfigurator implements PreviewConfigurer.
+type previewConfigurator struct {
+	travel    bool
+	simulator []simulator.SimulatorConfigurationCallbackable
+}
+
+// SetTravel defines if the travel moves are included in the polylines, otherwise only the extrusions are.
+// If this method isn't called, by default the travel moves are included.
+func (pc *previewConfigurator) SetTravel(travel bool) error {
+	pc.travel = travel
+
+	return nil
+}
+
+// SetSimulatorOptions defines the options of the simulation of the toolpath, like the tolerance of the arcs or the initial state.
+// If this method isn't called, by default the simulation uses its defaults.
+func (pc *previewConfigurator) SetSimulatorOptions(options ...simulator.SimulatorConfigurationCallbackable) error {
+	pc.simulator = options
+
+	return nil
+}
+
+//#endregion
+//#region constructor
+
+// New simulates the blocks and returns the polylines of each layer.
+//
+// The moves are projected on the XY plane, so the moves along Z alone aren't included.
+// A move extrudes if the extruder advances while it moves. The positions in inches are converted to millimeters.
+//
+// It returns an error if some option is invalid or the toolpath can't be simulated.
+func New(blocks []block.Blocker, options ...PreviewConfigurationCallbackable) (*Preview, error) {
+
+	configurator := &previewConfigurator{
+		travel: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	layers, err := analysis.Layers(blocks, func(config analysis.LayersConfigurer) error {
+		return config.SetSimulatorOptions(configurator.simulator...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect layers: %w", err)
+	}
+
+	preview := &Preview{}
+	for _, l := range layers.Layers {
+		preview.Layers = append(preview.Layers, Layer{Index: l.Index, Z: l.Z})
+	}
+	if len(preview.Layers) == 0 {
+		preview.Layers = append(preview.Layers, Layer{})
+	}
+
+	sim, err := simulator.New(blocks, configurator.simulator...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulator: %w", err)
+	}
+
+	empty := true
+
+	for sim.Next() {
+		segment := sim.Segment()
+
+		if segment.Start.X == segment.End.X && segment.Start.Y == segment.End.Y {
+			continue
+		}
+
+		extrusion := segment.Extrusion > 0
+		if !extrusion && !configurator.travel {
+			continue
+		}
+
+		scale := 1.0
+		if sim.State().Units == state.UnitsInches {
+			scale = gcode.MILLIMETERS_PER_INCH
+		}
+
+		start := Point{X: segment.Start.X * scale, Y: segment.Start.Y * scale}
+		end := Point{X: segment.End.X * scale, Y: segment.End.Y * scale}
+
+		// the layer of the segment is the last one started by a block before it or by itself
+		index := sort.Search(len(layers.Layers), func(i int) bool { return layers.Layers[i].Block > segment.Index }) - 1
+		if index < 0 {
+			index = 0
+		}
+		layer := &preview.Layers[index]
+
+		if n := len(layer.Polylines); n > 0 {
+			last := &layer.Polylines[n-1]
+			if last.Extrusion == extrusion && last.Points[len(last.Points)-1] == start {
+				last.Points = append(last.Points, end)
+				preview.extend(end)
+				continue
+			}
+		}
+
+		layer.Polylines = append(layer.Polylines, Polyline{Extrusion: extrusion, Points: []Point{start, end}})
+
+		if empty {
+			preview.Min, preview.Max = start, start
+			empty = false
+		}
+		preview.extend(start)
+		preview.extend(end)
+	}
+
+	if err := sim.Err(); err != nil {
+		return nil, fmt.Errorf("failed to render preview: %w", err)
+	}
+
+	return preview, nil
+}
+
+//#endregion
+//#region private functions
+
+// extend grows the box of the preview to contain the point.
+func (p *Preview) extend(point Point) {
+	p.Min = Point{X: math.Min(p.Min.X, point.X), Y: math.Min(p.Min.Y, point.Y)}
+	p.Max = Point{X: math.Max(p.Max.X, point.X), Y: math.Max(p.Max.Y, point.Y)}
+}
+
+// formatCoordinate returns a coordinate rounded to the micron, with the shortest representation.
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64)
+}
+
+//#endregion
@@ -0,0 +1,190 @@
+package preview
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestNew(t *testing.T) {
+	cases := map[string]struct {
+		lines  []string
+		travel bool
+		want   []Layer
+		min    Point
+		max    Point
+	}{
+		"empty": {nil, true, []Layer{{}}, Point{}, Point{}},
+		"travel only": {
+			[]string{"G0 X10 Y5", "G0 Z5", "G0 X20"},
+			true,
+			[]Layer{{Polylines: []Polyline{{Points: []Point{{0, 0}, {10, 5}, {20, 5}}}}}},
+			Point{0, 0}, Point{20, 5},
+		},
+		"layers": {
+			[]string{"G1 Z0.2", "G1 X10 E1", "G1 Y10 E2", "G0 Z0.4", "G0 X0 Y0", "G1 X10 E3"},
+			true,
+			[]Layer{
+				{Index: 0, Z: 0.2, Polylines: []Polyline{
+					{Extrusion: true, Points: []Point{{0, 0}, {10, 0}, {10, 10}}},
+					{Points: []Point{{10, 10}, {0, 0}}},
+				}},
+				{Index: 1, Z: 0.4, Polylines: []Polyline{
+					{Extrusion: true, Points: []Point{{0, 0}, {10, 0}}},
+				}},
+			},
+			Point{0, 0}, Point{10, 10},
+		},
+		"without travel": {
+			[]string{"G0 X50 Y50", "G1 X60 E1", "G0 X0", "G1 Y10 E2"},
+			false,
+			[]Layer{{Polylines: []Polyline{
+				{Extrusion: true, Points: []Point{{50, 50}, {60, 50}}},
+				{Extrusion: true, Points: []Point{{0, 50}, {0, 10}}},
+			}}},
+			Point{0, 10}, Point{60, 50},
+		},
+		"inches": {
+			[]string{"G20", "G1 X1 E0.1"},
+			true,
+			[]Layer{{Polylines: []Polyline{{Extrusion: true, Points: []Point{{0, 0}, {25.4, 0}}}}}},
+			Point{0, 0}, Point{25.4, 0},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			preview, err := New(blocktest.Parse(t, tc.lines...), func(config PreviewConfigurer) error {
+				return config.SetTravel(tc.travel)
+			})
+			if err != nil {
+				t.Fatalf("failed to create preview: %v", err)
+			}
+
+			if !reflect.DeepEqual(preview.Layers, tc.want) {
+				t.Errorf("got layers %+v, want %+v", preview.Layers, tc.want)
+			}
+
+			if preview.Min != tc.min || preview.Max != tc.max {
+				t.Errorf("got box %v %v, want %v %v", preview.Min, preview.Max, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	if _, err := New(blocktest.Parse(t, "G2 X10 R1")); err == nil {
+		t.Errorf("got nil error, want an error for an invalid arc")
+	}
+}
+
+func TestPreview_WriteJSON(t *testing.T) {
+	preview, err := New(blocktest.Parse(t, "G1 X10 Y5 E1"))
+	if err != nil {
+		t.Fatalf("failed to create preview: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := preview.WriteJSON(&buffer); err != nil {
+		t.Fatalf("failed to write json: %v", err)
+	}
+
+	want := `{"layers":[{"index":0,"z":0,"polylines":[{"extrusion":true,"points":[{"x":0,"y":0},{"x":10,"y":5}]}]}],"min":{"x":0,"y":0},"max":{"x":10,"y":5}}` + "\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPreview_WriteSVG(t *testing.T) {
+	preview, err := New(blocktest.Parse(t, "G0 X10 Y10", "G1 X20 E1", "G1 Y15 E2"))
+	if err != nil {
+		t.Fatalf("failed to create preview: %v", err)
+	}
+
+	cases := map[string]struct {
+		options []SVGConfigurationCallbackable
+		want    string
+	}{
+		"default": {nil, `<svg xmlns="http://www.w3.org/2000/svg" width="30mm" height="25mm" viewBox="0 0 30 25">
+<g fill="none" stroke-linecap="round" stroke-linejoin="round">
+<polyline class="travel" stroke="#d62728" stroke-width="0.2" points="5,20 15,10"/>
+<polyline class="extrusion" stroke="#1f77b4" stroke-width="0.4" points="15,10 25,10 25,5"/>
+</g>
+</svg>
+`},
+		"configured": {
+			[]SVGConfigurationCallbackable{func(config SVGConfigurer) error {
+				if err := config.SetColors("black", "gray"); err != nil {
+					return err
+				}
+				if err := config.SetStrokeWidth(0.5); err != nil {
+					return err
+				}
+				return config.SetMargin(0)
+			}},
+			`<svg xmlns="http://www.w3.org/2000/svg" width="20mm" height="15mm" viewBox="0 0 20 15">
+<g fill="none" stroke-linecap="round" stroke-linejoin="round">
+<polyline class="travel" stroke="gray" stroke-width="0.25" points="0,15 10,5"/>
+<polyline class="extrusion" stroke="black" stroke-width="0.5" points="10,5 20,5 20,0"/>
+</g>
+</svg>
+`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := preview.WriteSVG(&buffer, 0, tc.options...); err != nil {
+				t.Fatalf("failed to write svg: %v", err)
+			}
+
+			if got := buffer.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPreview_WriteSVG_errors(t *testing.T) {
+	preview, err := New(blocktest.Parse(t, "G1 X10 E1"))
+	if err != nil {
+		t.Fatalf("failed to create preview: %v", err)
+	}
+
+	cases := map[string]struct {
+		layer  int
+		option SVGConfigurationCallbackable
+	}{
+		"missing layer":  {1, nil},
+		"negative layer": {-1, nil},
+		"empty color": {0, func(config SVGConfigurer) error {
+			return config.SetColors("", "gray")
+		}},
+		"markup in color": {0, func(config SVGConfigurer) error {
+			return config.SetColors(`red"/><script>`, "gray")
+		}},
+		"width zero": {0, func(config SVGConfigurer) error {
+			return config.SetStrokeWidth(0)
+		}},
+		"negative margin": {0, func(config SVGConfigurer) error {
+			return config.SetMargin(-1)
+		}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options []SVGConfigurationCallbackable
+			if tc.option != nil {
+				options = append(options, tc.option)
+			}
+
+			var buffer bytes.Buffer
+			if err := preview.WriteSVG(&buffer, tc.layer, options...); err == nil {
+				t.Errorf("got nil error, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package preview
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// DEFAULT_EXTRUSION_COLOR is the color of the extrusions in SVG, if it isn't configured.
+	DEFAULT_EXTRUSION_COLOR = "#1f77b4"
+
+	// DEFAULT_TRAVEL_COLOR is the color of the travel moves in SVG, if it isn't configured.
+	DEFAULT_TRAVEL_COLOR = "#d62728"
+
+	// DEFAULT_STROKE_WIDTH is the width in millimeters of the extrusions in SVG, if it isn't configured.
+	DEFAULT_STROKE_WIDTH = 0.4
+
+	// DEFAULT_SVG_MARGIN is the margin in millimeters around the toolpath in SVG, if it isn't configured.
+	DEFAULT_SVG_MARGIN = 5.0
+)
+
+//#region svg configuration
+
+// SVGConfigurer defines the options of the SVG of a layer.
+type SVGConfigurer interface {
+	// Set the colors of the extrusions and the travel moves
+	SetColors(extrusion string, travel string) error
+
+	// Set the width of the extrusions
+	SetStrokeWidth(width float64) error
+
+	// Set the margin around the toolpath
+	SetMargin(margin float64) error
+}
+
+// SVGConfigurationCallbackable is the signature of the callbacks used to configure the SVG of a layer.
+type SVGConfigurationCallbackable func(config SVGConfigurer) error
+
+// svgConfigurator implements SVGConfigurer.
+type svgConfigurator struct {
+	extrusion string
+	travel    string
+	width     float64
+	margin    float64
+}
+
+// SetColors defines the colors of the extrusions and the travel moves, any color of SVG like "#000000" or "black".
+// If this method isn't called, by default they are DEFAULT_EXTRUSION_COLOR and DEFAULT_TRAVEL_COLOR.
+func (sc *svgConfigurator) SetColors(extrusion string, travel string) error {
+	if extrusion == "" || travel == "" {
+		return fmt.Errorf("failed to set colors, they can't be empty")
+	}
+
+	if strings.ContainsAny(extrusion+travel, "\"<>&") {
+		return fmt.Errorf("failed to set colors, they can't contain quotes nor markup: %s, %s", extrusion, travel)
+	}
+
+	sc.extrusion = extrusion
+	sc.travel = travel
+
+	return nil
+}
+
+// SetStrokeWidth defines the width in millimeters of the extrusions, usually the width of the extrusion lines.
+// The travel moves are drawn with the half of it.
+// If this method isn't called, by default it is DEFAULT_STROKE_WIDTH.
+func (sc *svgConfigurator) SetStrokeWidth(width float64) error {
+	if !(width > 0) || width > 1e6 {
+		return fmt.Errorf("failed to set stroke width, it must be positive: %v", width)
+	}
+
+	sc.width = width
+
+	return nil
+}
+
+// SetMargin defines the margin in millimeters around the box of the toolpath.
+// If this method isn't called, by default it is DEFAULT_SVG_MARGIN.
+func (sc *svgConfigurator) SetMargin(margin float64) error {
+	if !(margin >= 0) || margin > 1e6 {
+		return fmt.Errorf("failed to set margin, it can't be negative: %v", margin)
+	}
+
+	sc.margin = margin
+
+	return nil
+}
+
+//#endregion
+//#region svg
+
+// WriteSVG writes the polylines of a layer as an SVG image, whose units are millimeters.
+//
+// The image covers the box of all layers, so the images of the layers are aligned and can be animated.
+// The Y axis points up like in the machine, and each polyline has the class "extrusion" or "travel" to style it with CSS.
+//
+// It returns an error if the preview doesn't have the layer, some option is invalid or the image can't be written.
+func (p *Preview) WriteSVG(w io.Writer, layer int, options ...SVGConfigurationCallbackable) error {
+
+	configurator := &svgConfigurator{
+		extrusion: DEFAULT_EXTRUSION_COLOR,
+		travel:    DEFAULT_TRAVEL_COLOR,
+		width:     DEFAULT_STROKE_WIDTH,
+		margin:    DEFAULT_SVG_MARGIN,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	if layer < 0 || layer >= len(p.Layers) {
+		return fmt.Errorf("failed to write svg, the preview doesn't have the layer %d", layer)
+	}
+
+	width := p.Max.X - p.Min.X + 2*configurator.margin
+	height := p.Max.Y - p.Min.Y + 2*configurator.margin
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%smm" height="%smm" viewBox="0 0 %s %s">`,
+		formatCoordinate(width), formatCoordinate(height), formatCoordinate(width), formatCoordinate(height)))
+	sb.WriteString("\n")
+	sb.WriteString(`<g fill="none" stroke-linecap="round" stroke-linejoin="round">`)
+	sb.WriteString("\n")
+
+	for _, polyline := range p.Layers[layer].Polylines {
+		class, color, stroke := "travel", configurator.travel, configurator.width/2
+		if polyline.Extrusion {
+			class, color, stroke = "extrusion", configurator.extrusion, configurator.width
+		}
+
+		points := make([]string, 0, len(polyline.Points))
+		for _, point := range polyline.Points {
+			x := point.X - p.Min.X + configurator.margin
+			y := p.Max.Y - point.Y + configurator.margin
+			points = append(points, formatCoordinate(x)+","+formatCoordinate(y))
+		}
+
+		sb.WriteString(fmt.Sprintf(`<polyline class="%s" stroke="%s" stroke-width="%s" points="%s"/>`,
+			class, color, formatCoordinate(stroke), strings.Join(points, " ")))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("</g>\n</svg>\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write svg: %w", err)
+	}
+
+	return nil
+}
+
+//#endregion
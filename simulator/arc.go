@@ -0,0 +1,114 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+//#region plane axes
+
+// axes describes the axes of a plane: the first and second axes of the plane, with the words of their center offsets,
+// and the axis perpendicular to it, that moves linearly in the helical arcs.
+type axes struct {
+	first, second, linear func(p *state.Position) *float64
+
+	// words of the center offsets of the first and second axes
+	offsets [2]byte
+}
+
+// planeAxes returns the axes of the plane, like X and Y with the offsets I and J for the plane XY.
+func planeAxes(plane state.Plane) axes {
+	x := func(p *state.Position) *float64 { return &p.X }
+	y := func(p *state.Position) *float64 { return &p.Y }
+	z := func(p *state.Position) *float64 { return &p.Z }
+
+	switch plane {
+	case state.PlaneZX:
+		return axes{first: z, second: x, linear: y, offsets: [2]byte{'K', 'I'}}
+	case state.PlaneYZ:
+		return axes{first: y, second: z, linear: x, offsets: [2]byte{'J', 'K'}}
+	}
+
+	return axes{first: x, second: y, linear: z, offsets: [2]byte{'I', 'J'}}
+}
+
+//#endregion
+//#region geometry
+
+// geometry describes an arc in a plane.
+type geometry struct {
+	// center of the arc, in the first and second axes of the plane
+	c0, c1 float64
+
+	// radius of the arc
+	radius float64
+
+	// angle of the start point and angle swept, positive counterclockwise
+	start, sweep float64
+}
+
+// arcGeometry calculates the geometry of an arc from its start and end points and the parameters of the block.
+// An arc whose end point is its start point is a full circle.
+func arcGeometry(b block.Blocker, a axes, from state.Position, to state.Position) (geometry, error) {
+	clockwise := b.Command().String() == "G2"
+
+	var g geometry
+
+	p0, q0 := *a.first(&from), *a.second(&from)
+	p1, q1 := *a.first(&to), *a.second(&to)
+
+	i, hasI := blockparam.Number(b, a.offsets[0])
+	j, hasJ := blockparam.Number(b, a.offsets[1])
+	r, hasR := blockparam.Number(b, 'R')
+
+	switch {
+	case hasI || hasJ:
+		g.c0, g.c1 = p0+i, q0+j
+		g.radius = math.Hypot(i, j)
+	case hasR:
+		// the center is on the perpendicular bisector of the chord, a negative radius selects the major arc
+		dp, dq := p1-p0, q1-q0
+		chord := math.Hypot(dp, dq)
+		if chord == 0 || math.Abs(r) < chord/2 {
+			return g, fmt.Errorf("the radius %v can't join the start and end points", r)
+		}
+
+		h := math.Sqrt(r*r - chord*chord/4)
+		if clockwise != (r < 0) {
+			h = -h
+		}
+
+		g.c0 = p0 + dp/2 - h*dq/chord
+		g.c1 = q0 + dq/2 + h*dp/chord
+		g.radius = math.Abs(r)
+	default:
+		return g, fmt.Errorf("the arc hasn't center offsets nor radius")
+	}
+
+	if g.radius == 0 {
+		return g, fmt.Errorf("the radius of the arc is zero")
+	}
+
+	g.start = math.Atan2(q0-g.c1, p0-g.c0)
+	end := math.Atan2(q1-g.c1, p1-g.c0)
+
+	g.sweep = end - g.start
+	if clockwise {
+		if g.sweep >= -1e-9 {
+			g.sweep -= 2 * math.Pi
+		}
+	} else if g.sweep <= 1e-9 {
+		g.sweep += 2 * math.Pi
+	}
+
+	return g, nil
+}
+
+//#endregion
+//#region private functions
+
+//#endregion
@@ -0,0 +1,328 @@
+// simulator package walks the toolpath of a sequence of blocks, producing the segments that the machine moves through.
+//
+// It is built on the state package: each block is applied to a state.Machine, and each move is converted into segments
+// with its start and end points, its feedrate and the amount extruded. The arcs, G2 and G3, are interpolated by chords
+// in the plane selected, so the segments always describe straight lines, ready for the geometry analysis,
+// like the bounding box or the length of the print.
+//
+// The positions are expressed in the units and the work coordinate system of the blocks, like the state tracks them,
+// or in the coordinates of the machine, so the work offsets selected by G54 to G59.3, set by G10 L2 and L20 and shifted by G92
+// are applied and the toolpath is continuous when they change.
+package simulator
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// DEFAULT_TOLERANCE is the maximum distance between an arc and the segments that interpolate it, if it isn't configured.
+const DEFAULT_TOLERANCE = 0.01
+
+//#region segment
+
+// Segment is a straight move of the toolpath.
+type Segment struct {
+	// Index is the position of the block that commanded the move in the sequence simulated.
+	Index int
+
+	// Block is the block that commanded the move.
+	Block block.Blocker
+
+	// Start and End are the positions of the axes at the start and at the end of the segment.
+	Start, End state.Position
+
+	// Feedrate is the feedrate of the move, the last one commanded.
+	Feedrate float64
+
+	// Extrusion is the length of filament extruded by the segment, it is negative for a retraction.
+	Extrusion float64
+
+	// Rapid is true if the move was commanded by G0.
+	Rapid bool
+
+	// Arc is true if the segment is a chord of an arc.
+	Arc bool
+}
+
+// Length returns the distance travelled by the XYZ axes.
+func (s Segment) Length() float64 {
+	dx, dy, dz := s.End.X-s.Start.X, s.End.Y-s.Start.Y, s.End.Z-s.Start.Z
+
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+//#endregion
+//#region simulator configuration
+
+// SimulatorConfigurer defines the options of the simulation.
+type SimulatorConfigurer interface {
+	// Set the maximum distance between an arc and its segments
+	SetTolerance(tolerance float64) error
+
+	// Set the state of the machine before the first block
+	SetInitialState(initial state.State) error
+
+	// Set if the positions are expressed in the coordinates of the machine
+	SetMachineCoordinates(enabled bool) error
+}
+
+// SimulatorConfigurationCallbackable is the signature of the callbacks used to configure the simulation.
+type SimulatorConfigurationCallbackable func(config SimulatorConfigurer) error
+
+// simulatorConfigurator implements SimulatorConfigurer.
+type simulatorConfigurator struct {
+	tolerance float64
+	initial   state.State
+	machine   bool
+}
+
+// SetTolerance defines the maximum distance between an arc and the segments that interpolate it. It must be positive.
+// A lower tolerance generates more segments.
+// If this method isn't called, by default it is DEFAULT_TOLERANCE.
+func (sc *simulatorConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it must be positive and finite: %v", tolerance)
+	}
+
+	sc.tolerance = tolerance
+
+	return nil
+}
+
+// SetInitialState defines the state of the machine before the first block, like the state at the end of a previous file.
+// If this method isn't called, by default it is the zero state, a machine after a reset.
+func (sc *simulatorConfigurator) SetInitialState(initial state.State) error {
+	sc.initial = initial.Clone()
+
+	return nil
+}
+
+// SetMachineCoordinates defines if the positions of the segments are expressed in the coordinates of the machine,
+// like state.State.MachinePosition, instead of the work coordinates of the blocks.
+// If this method isn't called, by default the positions are expressed in the work coordinates.
+func (sc *simulatorConfigurator) SetMachineCoordinates(enabled bool) error {
+	sc.machine = enabled
+
+	return nil
+}
+
+//#endregion
+//#region simulator struct
+
+// Simulator walks the segments of the toolpath of a sequence of blocks.
+//
+// It is used like a bufio.Scanner:
+//
+//	sim, err := simulator.New(d.Blocks())
+//	...
+//	for sim.Next() {
+//		fmt.Println(sim.Segment().Start, sim.Segment().End)
+//	}
+//	if err := sim.Err(); err != nil {
+//		...
+//	}
+//
+// Only the moves, G0, G1, G2 and G3, also in the coordinates of the machine with G53, produce segments. A linear move that doesn't change any axis doesn't produce segments.
+type Simulator struct {
+	// blocks simulated
+	blocks []block.Blocker
+
+	// maximum distance between an arc and its segments
+	tolerance float64
+
+	// true if the positions are expressed in the coordinates of the machine
+	machineCoordinates bool
+
+	// state of the machine after the last block applied
+	machine *state.Machine
+
+	// position of the next block to apply
+	next int
+
+	// segments of the last block applied that weren't returned yet
+	pending []Segment
+
+	// current segment and state of the machine after its block
+	segment Segment
+	state   state.State
+
+	// error that stopped the simulation
+	err error
+}
+
+// Next advances to the next segment, it returns false when there aren't more segments or the simulation failed.
+func (s *Simulator) Next() bool {
+	for len(s.pending) == 0 {
+		if s.err != nil || s.next >= len(s.blocks) {
+			return false
+		}
+
+		index, b := s.next, s.blocks[s.next]
+		s.next++
+
+		if b == nil {
+			continue
+		}
+
+		before := s.machine.Apply(b)
+		s.state = s.machine.Snapshot()
+
+		segments, err := s.segments(index, b, before, s.state)
+		if err != nil {
+			s.err = fmt.Errorf("failed to simulate block %d %s: %w", index, b, err)
+			return false
+		}
+		s.pending = segments
+	}
+
+	s.segment = s.pending[0]
+	s.pending = s.pending[1:]
+
+	return true
+}
+
+// Segment returns the current segment.
+func (s *Simulator) Segment() Segment {
+	return s.segment
+}
+
+// State returns the state of the machine after executing the block of the current segment.
+func (s *Simulator) State() state.State {
+	return s.state
+}
+
+// Err returns the error that stopped the simulation, like an arc that can't be interpolated. It is nil if all blocks were simulated.
+func (s *Simulator) Err() error {
+	return s.err
+}
+
+// segments returns the segments of the block executed from the state before to the state after.
+func (s *Simulator) segments(index int, b block.Blocker, before state.State, after state.State) ([]Segment, error) {
+	command := motion(b)
+	if command == "" {
+		return nil, nil
+	}
+
+	start, end := before.Position, after.Position
+	if s.machineCoordinates {
+		start, end = before.MachinePosition(), after.MachinePosition()
+	}
+
+	// an arc that ends at its start point is a full circle
+	linear := command == "G0" || command == "G1"
+	if linear && start == end {
+		return nil, nil
+	}
+
+	segment := Segment{
+		Index:     index,
+		Block:     b,
+		Start:     start,
+		End:       end,
+		Feedrate:  after.Feedrate,
+		Extrusion: end.E - start.E,
+		Rapid:     command == "G0",
+	}
+
+	if linear {
+		return []Segment{segment}, nil
+	}
+
+	return s.interpolate(segment, after.Plane)
+}
+
+// interpolate divides an arc into chords whose distance to the arc doesn't exceed the tolerance.
+// The extrusion and the move of the axis perpendicular to the plane, in the helical arcs, are distributed along the chords.
+func (s *Simulator) interpolate(arc Segment, plane state.Plane) ([]Segment, error) {
+	a := planeAxes(plane)
+
+	g, err := arcGeometry(arc.Block, a, arc.Start, arc.End)
+	if err != nil {
+		return nil, err
+	}
+
+	// the chord of an angle a is at r*(1-cos(a/2)) of the arc
+	step := math.Pi
+	if s.tolerance < g.radius {
+		step = 2 * math.Acos(1-s.tolerance/g.radius)
+	}
+	count := int(math.Ceil(math.Abs(g.sweep) / step))
+	if count < 1 {
+		count = 1
+	}
+
+	segments := make([]Segment, 0, count)
+	previous := arc.Start
+	for i := 1; i <= count; i++ {
+		t := float64(i) / float64(count)
+
+		point := arc.End
+		if i < count {
+			angle := g.start + g.sweep*t
+			*a.first(&point) = g.c0 + g.radius*math.Cos(angle)
+			*a.second(&point) = g.c1 + g.radius*math.Sin(angle)
+			*a.linear(&point) = *a.linear(&arc.Start) + (*a.linear(&arc.End)-*a.linear(&arc.Start))*t
+			point.E = arc.Start.E + (arc.End.E-arc.Start.E)*t
+		}
+
+		chord := arc
+		chord.Start, chord.End = previous, point
+		chord.Extrusion = point.E - previous.E
+		chord.Arc = true
+
+		segments = append(segments, chord)
+		previous = point
+	}
+
+	return segments, nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a simulator of the blocks received. The nil blocks are ignored.
+func New(blocks []block.Blocker, options ...SimulatorConfigurationCallbackable) (*Simulator, error) {
+
+	configurator := &simulatorConfigurator{
+		tolerance: DEFAULT_TOLERANCE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Simulator{
+		blocks:             blocks,
+		tolerance:          configurator.tolerance,
+		machineCoordinates: configurator.machine,
+		machine:            state.New(configurator.initial),
+		state:              configurator.initial.Clone(),
+	}, nil
+}
+
+//#endregion
+//#region private functions
+
+// motion returns the move commanded by the block, G0, G1, G2 or G3, or empty if it isn't a move.
+// The moves in the coordinates of the machine are written as "G53 G0 X0", so their move is a parameter.
+func motion(b block.Blocker) string {
+	switch command := b.Command().String(); command {
+	case "G0", "G1", "G2", "G3":
+		return command
+	case "G53":
+		for _, p := range b.Parameters() {
+			if name := p.String(); name == "G0" || name == "G1" {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+//#endregion
@@ -0,0 +1,221 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/state"
+)
+
+// simulate returns all segments of the blocks, it fails the test if the simulation fails.
+func simulate(t *testing.T, blocks []block.Blocker, options ...SimulatorConfigurationCallbackable) []Segment {
+	t.Helper()
+
+	sim, err := New(blocks, options...)
+	if err != nil {
+		t.Fatalf("failed to create simulator: %v", err)
+	}
+
+	var segments []Segment
+	for sim.Next() {
+		segments = append(segments, sim.Segment())
+	}
+
+	if err := sim.Err(); err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+
+	return segments
+}
+
+func TestSimulator_linear(t *testing.T) {
+	blocks := blocktest.Parse(t, "G28", "G0 X10 Y0 F6000", "M104 S200", "G1 X10 Y10 E0.5 F1200", "G1 X10 Y10", "G91", "G1 E-1")
+
+	got := simulate(t, blocks)
+
+	want := []Segment{
+		{Index: 1, Start: state.Position{}, End: state.Position{X: 10}, Feedrate: 6000, Rapid: true},
+		{Index: 3, Start: state.Position{X: 10}, End: state.Position{X: 10, Y: 10, E: 0.5}, Feedrate: 1200, Extrusion: 0.5},
+		{Index: 6, Start: state.Position{X: 10, Y: 10, E: 0.5}, End: state.Position{X: 10, Y: 10, E: -0.5}, Feedrate: 1200, Extrusion: -1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments %+v, want %d", len(got), got, len(want))
+	}
+
+	for i := range want {
+		want[i].Block = blocks[want[i].Index]
+		if got[i] != want[i] {
+			t.Errorf("got segment %d %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if length := got[1].Length(); length != 10 {
+		t.Errorf("got length %v, want 10", length)
+	}
+}
+
+func TestSimulator_arcs(t *testing.T) {
+
+	cases := map[string]struct {
+		lines []string
+		// plane axes of the arc, the center and the radius expected for all vertexes
+		first, second func(p state.Position) float64
+		c0, c1        float64
+		radius        float64
+		end           state.Position
+		extrusion     float64
+	}{
+		"clockwise semicircle": {
+			[]string{"G1 X0 Y0", "G2 X10 Y0 I5 J0 E2"},
+			px, py, 5, 0, 5,
+			state.Position{X: 10, E: 2}, 2,
+		},
+		"counterclockwise by radius": {
+			[]string{"G1 X0 Y0", "G3 X10 Y10 R10"},
+			px, py, 0, 10, 10,
+			state.Position{X: 10, Y: 10}, 0,
+		},
+		"full circle": {
+			[]string{"G1 X10 Y0", "G3 X10 Y0 I-10 J0"},
+			px, py, 0, 0, 10,
+			state.Position{X: 10}, 0,
+		},
+		"helix in plane ZX": {
+			[]string{"G18", "G1 X0 Z0", "G2 X0 Z10 K5 I0 Y3"},
+			pz, px, 5, 0, 5,
+			state.Position{Y: 3, Z: 10}, 0,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			segments := simulate(t, blocktest.ParseCNC(t, tc.lines...), func(config SimulatorConfigurer) error {
+				return config.SetTolerance(0.001)
+			})
+
+			arc := segments[1:]
+			if len(segments) > 0 && segments[0].Arc {
+				arc = segments
+			}
+
+			if len(arc) < 10 {
+				t.Fatalf("got %d chords, want the arc divided", len(arc))
+			}
+
+			extrusion := 0.0
+			for i, s := range arc {
+				if !s.Arc {
+					t.Errorf("got segment %d not marked as arc", i)
+				}
+
+				if i > 0 && s.Start != arc[i-1].End {
+					t.Errorf("got segment %d starting at %+v, want the end of the previous %+v", i, s.Start, arc[i-1].End)
+				}
+
+				if r := math.Hypot(tc.first(s.End)-tc.c0, tc.second(s.End)-tc.c1); math.Abs(r-tc.radius) > 1e-9 {
+					t.Errorf("got vertex %+v at %v from the center, want %v", s.End, r, tc.radius)
+				}
+
+				extrusion += s.Extrusion
+			}
+
+			if end := arc[len(arc)-1].End; end != tc.end {
+				t.Errorf("got end %+v, want %+v", end, tc.end)
+			}
+
+			if math.Abs(extrusion-tc.extrusion) > 1e-9 {
+				t.Errorf("got extrusion %v, want %v", extrusion, tc.extrusion)
+			}
+		})
+	}
+}
+
+func TestSimulator_errors(t *testing.T) {
+	sim, err := New(blocktest.Parse(t, "G1 X1", "G2 X10 Y0 R1", "G1 X20"))
+	if err != nil {
+		t.Fatalf("failed to create simulator: %v", err)
+	}
+
+	count := 0
+	for sim.Next() {
+		count++
+	}
+
+	if count != 1 || sim.Err() == nil {
+		t.Errorf("got %d segments and error %v, want 1 segment and an error", count, sim.Err())
+	}
+
+	if sim.Next() {
+		t.Errorf("got Next true after the error, want false")
+	}
+
+	if _, err := New(nil, func(config SimulatorConfigurer) error { return config.SetTolerance(0) }); err == nil {
+		t.Errorf("got nil error with tolerance 0, want an error")
+	}
+}
+
+func TestSimulator_initialState(t *testing.T) {
+	blocks := blocktest.Parse(t, "G1 X1 E1")
+
+	segments := simulate(t, blocks, func(config SimulatorConfigurer) error {
+		return config.SetInitialState(state.State{Relative: true, RelativeExtrusion: true, Position: state.Position{X: 5, E: 10}})
+	})
+
+	if len(segments) != 1 || segments[0].End != (state.Position{X: 6, E: 11}) {
+		t.Errorf("got %+v, want a segment to X6 E11", segments)
+	}
+}
+
+func px(p state.Position) float64 { return p.X }
+func py(p state.Position) float64 { return p.Y }
+func pz(p state.Position) float64 { return p.Z }
+
+func TestSimulator_machineCoordinates(t *testing.T) {
+	blocks := blocktest.ParseCNC(t, "G10 L2 P2 X100 Y50", "G0 X10 Y10", "G55", "G0 X10 Y10", "G92 X0", "G1 X5", "G53 G0 X0 Y0")
+
+	cases := map[string]struct {
+		machine bool
+		want    []Segment
+	}{
+		"work coordinates": {
+			false,
+			[]Segment{
+				{Index: 1, End: state.Position{X: 10, Y: 10}, Rapid: true},
+				{Index: 3, Start: state.Position{X: -90, Y: -40}, End: state.Position{X: 10, Y: 10}, Rapid: true},
+				{Index: 5, Start: state.Position{Y: 10}, End: state.Position{X: 5, Y: 10}},
+				{Index: 6, Start: state.Position{X: 5, Y: 10}, End: state.Position{X: -110, Y: -50}, Rapid: true},
+			},
+		},
+		"machine coordinates": {
+			true,
+			[]Segment{
+				{Index: 1, End: state.Position{X: 10, Y: 10}, Rapid: true},
+				{Index: 3, Start: state.Position{X: 10, Y: 10}, End: state.Position{X: 110, Y: 60}, Rapid: true},
+				{Index: 5, Start: state.Position{X: 110, Y: 60}, End: state.Position{X: 115, Y: 60}},
+				{Index: 6, Start: state.Position{X: 115, Y: 60}, End: state.Position{}, Rapid: true},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := simulate(t, blocks, func(config SimulatorConfigurer) error {
+				return config.SetMachineCoordinates(tc.machine)
+			})
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d segments %+v, want %d", len(got), got, len(tc.want))
+			}
+
+			for i, want := range tc.want {
+				want.Block = blocks[want.Index]
+				if got[i] != want {
+					t.Errorf("got segment %d %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
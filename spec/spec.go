@@ -0,0 +1,319 @@
+// spec package is a database of the standard G and M codes: their meaning, the parameters that they accept,
+// the type of each parameter and the modal group of the command.
+//
+// The database is embedded in the package as a JSON document. The preflight package validates with it the parameters of the blocks;
+// the commands that aren't described, like most of the commands specific of a firmware, aren't validated.
+//
+//	command, ok := spec.Lookup('G', 1)
+//	fmt.Println(command.Description) // linear move
+package spec
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+const (
+	// TYPE_NUMBER is the type of the parameters with a numeric value, like X10.5.
+	TYPE_NUMBER = "number"
+
+	// TYPE_INTEGER is the type of the parameters with an integer value, like T1.
+	TYPE_INTEGER = "integer"
+
+	// TYPE_STRING is the type of the parameters with a text value, like the name of a file.
+	TYPE_STRING = "string"
+)
+
+const (
+	// GROUP_MOTION is the modal group of the motion commands, like G0, G1 and the canned cycles.
+	GROUP_MOTION = "motion"
+
+	// GROUP_PLANE is the modal group of the selection of the plane, G17, G18 and G19.
+	GROUP_PLANE = "plane"
+
+	// GROUP_UNITS is the modal group of the units, G20 and G21.
+	GROUP_UNITS = "units"
+
+	// GROUP_DISTANCE is the modal group of the distance mode, G90 and G91.
+	GROUP_DISTANCE = "distance"
+
+	// GROUP_EXTRUSION is the modal group of the extrusion mode, M82 and M83.
+	GROUP_EXTRUSION = "extrusion"
+
+	// GROUP_FEEDRATE_MODE is the modal group of the feedrate mode, G93 and G94.
+	GROUP_FEEDRATE_MODE = "feedrate mode"
+
+	// GROUP_CUTTER_COMPENSATION is the modal group of the cutter radius compensation, G40, G41 and G42.
+	GROUP_CUTTER_COMPENSATION = "cutter compensation"
+
+	// GROUP_TOOL_LENGTH_OFFSET is the modal group of the tool length offset, G43 and G49.
+	GROUP_TOOL_LENGTH_OFFSET = "tool length offset"
+
+	// GROUP_COORDINATE_SYSTEM is the modal group of the work coordinate systems, from G54 to G59.3.
+	GROUP_COORDINATE_SYSTEM = "coordinate system"
+
+	// GROUP_CANNED_CYCLE_RETURN is the modal group of the return mode of the canned cycles, G98 and G99.
+	GROUP_CANNED_CYCLE_RETURN = "canned cycle return"
+
+	// GROUP_STOPPING is the modal group of the stops, like M0 and M2.
+	GROUP_STOPPING = "stopping"
+
+	// GROUP_TOOL_CHANGE is the modal group of the tool change, M6.
+	GROUP_TOOL_CHANGE = "tool change"
+
+	// GROUP_SPINDLE is the modal group of the spindle, M3, M4 and M5.
+	GROUP_SPINDLE = "spindle"
+
+	// GROUP_COOLANT is the modal group of the coolant, M7, M8 and M9.
+	GROUP_COOLANT = "coolant"
+)
+
+// source is the database embedded, a JSON array of commands.
+//
+//go:embed commands.json
+var source []byte
+
+// database are the commands of the database indexed by name, and names are their names sorted.
+var database, names = load()
+
+//#region spec structs
+
+// Parameter describes a parameter accepted by a command.
+type Parameter struct {
+	// Word is the letter of the parameter, like 'X'.
+	Word byte `json:"-"`
+
+	// Type is the type of the value, TYPE_NUMBER, TYPE_INTEGER or TYPE_STRING.
+	Type string `json:"type"`
+
+	// Description describes the meaning of the parameter.
+	Description string `json:"description"`
+}
+
+// Command describes a G or M code.
+type Command struct {
+	// Name is the word and the number of the command, like "G1" or "G38.2".
+	Name string `json:"name"`
+
+	// Description describes the meaning of the command.
+	Description string `json:"description"`
+
+	// Group is the modal group of the command, like GROUP_MOTION. It is empty if the command isn't modal.
+	Group string `json:"group"`
+
+	// Parameters are the parameters accepted, in the usual order.
+	Parameters []Parameter `json:"parameters"`
+}
+
+// UnmarshalJSON reads a parameter with its word as a string, like "X".
+func (p *Parameter) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Word        string `json:"word"`
+		Type        string `json:"type"`
+		Description string `json:"description"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if len(raw.Word) != 1 {
+		return fmt.Errorf("failed to read parameter, the word must be a letter: %q", raw.Word)
+	}
+
+	p.Word = raw.Word[0]
+	p.Type = raw.Type
+	p.Description = raw.Description
+
+	return nil
+}
+
+// Word returns the letter of the command, 'G' or 'M'.
+func (c Command) Word() byte {
+	return c.Name[0]
+}
+
+// Number returns the number of the command, like 38.2 for G38.2.
+func (c Command) Number() float64 {
+	number, _ := strconv.ParseFloat(c.Name[1:], 64)
+	return number
+}
+
+// Modal returns true if the command belongs to a modal group, so it remains active in the following blocks.
+func (c Command) Modal() bool {
+	return c.Group != ""
+}
+
+// Parameter returns the description of a parameter accepted by the command, or false if it doesn't accept the word.
+func (c Command) Parameter(word byte) (Parameter, bool) {
+	for _, p := range c.Parameters {
+		if p.Word == word {
+			return p, true
+		}
+	}
+
+	return Parameter{}, false
+}
+
+//#endregion
+//#region lookup
+
+// Lookup returns the description of the command with the word and the number, like Lookup('G', 1) for G1,
+// or false if the command isn't in the database.
+func Lookup(word byte, number float64) (Command, bool) {
+	return LookupName(string(word) + strconv.FormatFloat(number, 'f', -1, 64))
+}
+
+// LookupName returns the description of the command with the name, like "G1" or "m104", or false if the command isn't in the database.
+func LookupName(name string) (Command, bool) {
+	c, ok := database[strings.ToUpper(name)]
+	if !ok {
+		return Command{}, false
+	}
+
+	c.Parameters = append([]Parameter(nil), c.Parameters...)
+
+	return c, true
+}
+
+// Commands returns the description of all the commands of the database, sorted by word and number.
+func Commands() []Command {
+	commands := make([]Command, 0, len(names))
+	for _, name := range names {
+		c, _ := LookupName(name)
+		commands = append(commands, c)
+	}
+
+	return commands
+}
+
+// Validate verifies that the parameters of a block are accepted by its command and that their values have the type described.
+// The blocks without a command and the commands that aren't in the database aren't validated.
+// The G and M words that follow the command, like G0 in G53 G0 X10, are commands of the same block,
+// so a parameter is accepted if any of the commands accepts it.
+//
+// It returns an error with the first parameter invalid.
+func Validate(b block.Blocker) error {
+	if b == nil || b.Command() == nil {
+		return nil
+	}
+
+	c, ok := LookupName(b.Command().String())
+	if !ok {
+		return nil
+	}
+
+	commands := []Command{c}
+	for _, p := range b.Parameters() {
+		if p.Word() != 'G' && p.Word() != 'M' {
+			continue
+		}
+
+		other, ok := LookupName(p.String())
+		if !ok {
+			return nil
+		}
+		commands = append(commands, other)
+	}
+
+	for _, p := range b.Parameters() {
+		if p.Word() == 'G' || p.Word() == 'M' {
+			continue
+		}
+
+		parameter, ok := accepted(commands, p.Word())
+		if !ok {
+			return fmt.Errorf("failed to validate %s, it doesn't accept the parameter %c", c.Name, p.Word())
+		}
+
+		if parameter.Type == TYPE_STRING || !p.HasAddress() {
+			continue
+		}
+
+		value, ok := gcode.NumericAddress(p)
+		if !ok {
+			return fmt.Errorf("failed to validate %s, the parameter %s must be a %s", c.Name, p, parameter.Type)
+		}
+
+		if parameter.Type == TYPE_INTEGER && value != float64(int64(value)) {
+			return fmt.Errorf("failed to validate %s, the parameter %s must be an integer", c.Name, p)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
+//#region private functions
+
+// load reads the database embedded and indexes it by name.
+// It panics if the database is invalid, the tests of the package verify it.
+func load() (map[string]Command, []string) {
+	var commands []Command
+	if err := json.Unmarshal(source, &commands); err != nil {
+		panic(fmt.Sprintf("failed to load the database of commands: %v", err))
+	}
+
+	database := make(map[string]Command, len(commands))
+	names := make([]string, 0, len(commands))
+
+	for _, c := range commands {
+		if err := c.validate(); err != nil {
+			panic(fmt.Sprintf("failed to load the database of commands: %v", err))
+		}
+
+		database[c.Name] = c
+		names = append(names, c.Name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, b := database[names[i]], database[names[j]]
+		if a.Word() != b.Word() {
+			return a.Word() < b.Word()
+		}
+		return a.Number() < b.Number()
+	})
+
+	return database, names
+}
+
+// accepted returns the description of the parameter of the first command that accepts the word.
+func accepted(commands []Command, word byte) (Parameter, bool) {
+	for _, c := range commands {
+		if p, ok := c.Parameter(word); ok {
+			return p, true
+		}
+	}
+
+	return Parameter{}, false
+}
+
+// validate verifies the name and the types of the parameters of a command.
+func (c Command) validate() error {
+	if len(c.Name) < 2 || (c.Name[0] != 'G' && c.Name[0] != 'M') {
+		return fmt.Errorf("invalid name %q", c.Name)
+	}
+
+	if _, err := strconv.ParseFloat(c.Name[1:], 64); err != nil {
+		return fmt.Errorf("invalid number of %s: %w", c.Name, err)
+	}
+
+	for _, p := range c.Parameters {
+		switch p.Type {
+		case TYPE_NUMBER, TYPE_INTEGER, TYPE_STRING:
+		default:
+			return fmt.Errorf("invalid type %q of the parameter %c of %s", p.Type, p.Word, c.Name)
+		}
+	}
+
+	return nil
+}
+
+//#endregion
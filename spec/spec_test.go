@@ -0,0 +1,126 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+)
+
+func TestLookup(t *testing.T) {
+	cases := map[string]struct {
+		word        byte
+		number      float64
+		found       bool
+		description string
+		group       string
+	}{
+		"linear move": {'G', 1, true, "linear move", GROUP_MOTION},
+		"subcode":     {'G', 38.2, true, "probe toward workpiece, stop on contact, signal error if failure", GROUP_MOTION},
+		"not modal":   {'M', 104, true, "set hotend temperature", ""},
+		"extrusion":   {'M', 83, true, "relative extrusion", GROUP_EXTRUSION},
+		"unknown":     {'G', 7, false, "", ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, ok := Lookup(tc.word, tc.number)
+			if ok != tc.found {
+				t.Fatalf("got found %v, want %v", ok, tc.found)
+			}
+
+			if c.Description != tc.description {
+				t.Errorf("got description %q, want %q", c.Description, tc.description)
+			}
+
+			if c.Group != tc.group {
+				t.Errorf("got group %q, want %q", c.Group, tc.group)
+			}
+
+			if ok && (c.Word() != tc.word || c.Number() != tc.number) {
+				t.Errorf("got %c%v, want %c%v", c.Word(), c.Number(), tc.word, tc.number)
+			}
+		})
+	}
+}
+
+func TestCommand_Parameter(t *testing.T) {
+	c, ok := LookupName("m106")
+	if !ok {
+		t.Fatalf("got not found, want M106")
+	}
+
+	if p, ok := c.Parameter('S'); !ok || p.Type != TYPE_NUMBER {
+		t.Errorf("got %v %v, want a number S", p, ok)
+	}
+
+	if p, ok := c.Parameter('P'); !ok || p.Type != TYPE_INTEGER {
+		t.Errorf("got %v %v, want an integer P", p, ok)
+	}
+
+	if _, ok := c.Parameter('X'); ok {
+		t.Errorf("got X accepted, want not accepted")
+	}
+
+	c.Parameters[0].Description = "modified"
+	if again, _ := LookupName("M106"); again.Parameters[0].Description == "modified" {
+		t.Errorf("got the database modified, want a copy")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	commands := Commands()
+	if len(commands) == 0 {
+		t.Fatalf("got no commands, want the database")
+	}
+
+	for i := 1; i < len(commands); i++ {
+		a, b := commands[i-1], commands[i]
+		if a.Word() > b.Word() || (a.Word() == b.Word() && a.Number() >= b.Number()) {
+			t.Errorf("got %s before %s, want sorted by word and number", a.Name, b.Name)
+		}
+	}
+
+	for _, c := range commands {
+		seen := map[byte]bool{}
+		for _, p := range c.Parameters {
+			if seen[p.Word] {
+				t.Errorf("got parameter %c repeated in %s", p.Word, c.Name)
+			}
+			seen[p.Word] = true
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		valid  bool
+	}{
+		"valid move":                        {"G1 X10 Y20.5 E1 F1200", true},
+		"unknown command":                   {"G7 Q1", true},
+		"without command":                   {"X10", true},
+		"not accepted":                      {"G1 X10 S200", false},
+		"integer":                           {"M106 P1 S128", true},
+		"integer with dots":                 {"M106 P1.5 S128", false},
+		"several commands":                  {"G53 G0 X10 F3000", true},
+		"not accepted by several commands":  {"G53 G0 X10 S1", false},
+		"unknown command after the command": {"G53 G7 Q1", true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			err = Validate(b)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
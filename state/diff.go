@@ -0,0 +1,146 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+)
+
+//#region change
+
+// Change is a setting of the state that has different values in two snapshots.
+type Change struct {
+	// Field is the name of the setting, like "feedrate", "position X" or "hotend 0".
+	Field string
+
+	// Before and After are the values of the setting formatted, they are "unknown" if a fan or a heater wasn't commanded.
+	Before string
+	After  string
+}
+
+// String returns the change formatted like "feedrate: 1200 -> 3000".
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Before, c.After)
+}
+
+// unknown is the value of the fans and the heaters that weren't commanded.
+const unknown = "unknown"
+
+//#endregion
+//#region diff
+
+// Diff returns the settings that changed from a snapshot to another one, like the state before and after a block.
+//
+// The changes are sorted in the order of the fields of State, the fans by index and the heaters by kind and index.
+// It returns nil if the snapshots are equal.
+func Diff(before, after State) []Change {
+	var changes []Change
+
+	add := func(field string, b, a string) {
+		if b != a {
+			changes = append(changes, Change{Field: field, Before: b, After: a})
+		}
+	}
+
+	add("units", before.Units.String(), after.Units.String())
+	add("positioning", positioning(before.Relative), positioning(after.Relative))
+	add("extrusion", positioning(before.RelativeExtrusion), positioning(after.RelativeExtrusion))
+	add("plane", before.Plane.String(), after.Plane.String())
+	add("tool", strconv.Itoa(before.Tool), strconv.Itoa(after.Tool))
+	add("feedrate", numeric.Format(before.Feedrate), numeric.Format(after.Feedrate))
+	add("acceleration", numeric.Format(before.Limits.Acceleration), numeric.Format(after.Limits.Acceleration))
+	add("travel acceleration", numeric.Format(before.Limits.TravelAcceleration), numeric.Format(after.Limits.TravelAcceleration))
+	add("retract acceleration", numeric.Format(before.Limits.RetractAcceleration), numeric.Format(after.Limits.RetractAcceleration))
+	for i, axis := range []string{"X", "Y", "Z", "E"} {
+		add("jerk "+axis, numeric.Format(before.Limits.Jerk[i]), numeric.Format(after.Limits.Jerk[i]))
+	}
+	add("junction deviation", numeric.Format(before.Limits.JunctionDeviation), numeric.Format(after.Limits.JunctionDeviation))
+	add("velocity", numeric.Format(before.Limits.Velocity), numeric.Format(after.Limits.Velocity))
+	add("square corner velocity", numeric.Format(before.Limits.SquareCornerVelocity), numeric.Format(after.Limits.SquareCornerVelocity))
+	add("position X", numeric.Format(before.Position.X), numeric.Format(after.Position.X))
+	add("position Y", numeric.Format(before.Position.Y), numeric.Format(after.Position.Y))
+	add("position Z", numeric.Format(before.Position.Z), numeric.Format(after.Position.Z))
+	add("position E", numeric.Format(before.Position.E), numeric.Format(after.Position.E))
+	add("work offset", before.WorkOffset.String(), after.WorkOffset.String())
+
+	for w := range before.Offsets {
+		field := "offset " + WorkOffset(w).String()
+		add(field+" X", numeric.Format(before.Offsets[w].X), numeric.Format(after.Offsets[w].X))
+		add(field+" Y", numeric.Format(before.Offsets[w].Y), numeric.Format(after.Offsets[w].Y))
+		add(field+" Z", numeric.Format(before.Offsets[w].Z), numeric.Format(after.Offsets[w].Z))
+	}
+
+	add("shift X", numeric.Format(before.Shift.X), numeric.Format(after.Shift.X))
+	add("shift Y", numeric.Format(before.Shift.Y), numeric.Format(after.Shift.Y))
+	add("shift Z", numeric.Format(before.Shift.Z), numeric.Format(after.Shift.Z))
+	add("shift suspended", strconv.FormatBool(before.ShiftSuspended), strconv.FormatBool(after.ShiftSuspended))
+
+	add("spindle", before.SpindleDirection.String(), after.SpindleDirection.String())
+	add("spindle speed", numeric.Format(before.SpindleSpeed), numeric.Format(after.SpindleSpeed))
+
+	fans := map[int]bool{}
+	for fan := range before.Fans {
+		fans[fan] = true
+	}
+	for fan := range after.Fans {
+		fans[fan] = true
+	}
+
+	sortedFans := make([]int, 0, len(fans))
+	for fan := range fans {
+		sortedFans = append(sortedFans, fan)
+	}
+	sort.Ints(sortedFans)
+
+	for _, fan := range sortedFans {
+		add("fan "+strconv.Itoa(fan), lookup(before.Fans, fan), lookup(after.Fans, fan))
+	}
+
+	heaters := map[Heater]bool{}
+	for heater := range before.Heaters {
+		heaters[heater] = true
+	}
+	for heater := range after.Heaters {
+		heaters[heater] = true
+	}
+
+	sortedHeaters := make([]Heater, 0, len(heaters))
+	for heater := range heaters {
+		sortedHeaters = append(sortedHeaters, heater)
+	}
+	sort.Slice(sortedHeaters, func(i, j int) bool {
+		return sortedHeaters[i].less(sortedHeaters[j])
+	})
+
+	for _, heater := range sortedHeaters {
+		add(heater.String(), lookup(before.Heaters, heater), lookup(after.Heaters, heater))
+	}
+
+	return changes
+}
+
+//#endregion
+//#region private functions
+
+// positioning returns the name of a positioning mode.
+func positioning(relative bool) string {
+	if relative {
+		return "relative"
+	}
+
+	return "absolute"
+}
+
+// lookup returns the value of a key formatted, or unknown if the map doesn't contain it.
+func lookup[K comparable](values map[K]float64, key K) string {
+	value, ok := values[key]
+	if !ok {
+		return unknown
+	}
+
+	return numeric.Format(value)
+}
+
+//#endregion
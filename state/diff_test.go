@@ -0,0 +1,67 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestDiff(t *testing.T) {
+
+	cases := map[string]struct {
+		lines []string
+		want  []string
+	}{
+		"empty":      {nil, nil},
+		"unmodified": {[]string{"G90", "M115"}, nil},
+		"move": {
+			[]string{"G1 X10 E0.5 F1200"},
+			[]string{"feedrate: 0 -> 1200", "position X: 0 -> 10", "position E: 0 -> 0.5"},
+		},
+		"modes": {
+			[]string{"G20", "M83", "G19", "T1"},
+			[]string{"units: millimeters -> inches", "extrusion: absolute -> relative", "plane: XY -> YZ", "tool: 0 -> 1"},
+		},
+		"work offset": {
+			[]string{"G10 L2 P3 X1", "G56"},
+			[]string{"position X: 0 -> -1", "work offset: G54 -> G56", "offset G56 X: 0 -> 1"},
+		},
+		"shift": {
+			[]string{"G1 X10", "G92 X0", "G92.2"},
+			[]string{"position X: 0 -> 10", "shift X: 0 -> 10", "shift suspended: false -> true"},
+		},
+		"limits": {
+			[]string{"M204 P1500 T3000", "M205 Y10 J0.05"},
+			[]string{"acceleration: 0 -> 1500", "travel acceleration: 0 -> 3000", "jerk Y: 0 -> 10", "junction deviation: 0 -> 0.05"},
+		},
+		"spindle, fans and heaters": {
+			[]string{"M3 S500", "M106 P2 S10", "M106", "M140 S60", "M104 T1 S200"},
+			[]string{
+				"spindle: stopped -> clockwise", "spindle speed: 0 -> 500",
+				"fan 0: unknown -> 255", "fan 2: unknown -> 10",
+				"hotend 1: unknown -> 200", "bed: unknown -> 60",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var m Machine
+			before := m.Snapshot()
+
+			for _, b := range blocktest.ParseCNC(t, tc.lines...) {
+				m.Apply(b)
+			}
+
+			var got []string
+			for _, c := range Diff(before, m.Snapshot()) {
+				got = append(got, c.String())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
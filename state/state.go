@@ -0,0 +1,774 @@
+// state package contains a state machine that consumes blocks and tracks the state of the machine that executes them.
+//
+// The state includes the modal settings, like the units, the positioning, the plane or the work offset selected,
+// the position of the axes, the active tool and the feedrate, and the targets of the spindle, the fans and the heaters.
+// A Machine applies the blocks one by one, and its snapshots can be compared with Diff to know what each block changed.
+//
+// It is the only model of the machine of the library, intended for the analysis of the programs of any kind of machine,
+// from 3D printers to CNC mills. The modal state of the documents, document.ModalState, is an alias of State.
+package state
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+)
+
+//#region modes
+
+// Units identifies the units of the lengths commanded.
+type Units int
+
+const (
+	// UnitsMillimeters is selected by G21, it is the default of the firmwares.
+	UnitsMillimeters Units = iota
+
+	// UnitsInches is selected by G20.
+	UnitsInches
+)
+
+// String returns the name of the units.
+func (u Units) String() string {
+	switch u {
+	case UnitsMillimeters:
+		return "millimeters"
+	case UnitsInches:
+		return "inches"
+	}
+
+	return fmt.Sprintf("units(%d)", int(u))
+}
+
+// Plane identifies the plane of the arcs, selected by G17, G18 and G19.
+type Plane int
+
+const (
+	// PlaneXY is selected by G17, it is the default of the firmwares.
+	PlaneXY Plane = iota
+
+	// PlaneZX is selected by G18.
+	PlaneZX
+
+	// PlaneYZ is selected by G19.
+	PlaneYZ
+)
+
+// String returns the name of the plane.
+func (p Plane) String() string {
+	switch p {
+	case PlaneXY:
+		return "XY"
+	case PlaneZX:
+		return "ZX"
+	case PlaneYZ:
+		return "YZ"
+	}
+
+	return fmt.Sprintf("plane(%d)", int(p))
+}
+
+// WorkOffset identifies a work coordinate system, selected by the commands from G54 to G59.3.
+type WorkOffset int
+
+const (
+	// G54 is the first work coordinate system, it is the default of the controllers.
+	G54 WorkOffset = iota
+	G55
+	G56
+	G57
+	G58
+	G59
+	G59_1
+	G59_2
+	G59_3
+
+	// WORK_OFFSETS is the number of work coordinate systems.
+	WORK_OFFSETS = 9
+)
+
+// workOffsetCommands are the commands that select each work coordinate system.
+var workOffsetCommands = [WORK_OFFSETS]string{"G54", "G55", "G56", "G57", "G58", "G59", "G59.1", "G59.2", "G59.3"}
+
+// String returns the command that selects the work coordinate system.
+func (w WorkOffset) String() string {
+	if w < 0 || w >= WORK_OFFSETS {
+		return fmt.Sprintf("workoffset(%d)", int(w))
+	}
+
+	return workOffsetCommands[w]
+}
+
+// SpindleDirection identifies the rotation of the spindle, selected by M3, M4 and M5.
+type SpindleDirection int
+
+const (
+	// SpindleStopped is selected by M5, it is the default of the controllers.
+	SpindleStopped SpindleDirection = iota
+
+	// SpindleClockwise is selected by M3.
+	SpindleClockwise
+
+	// SpindleCounterClockwise is selected by M4.
+	SpindleCounterClockwise
+)
+
+// String returns the name of the direction.
+func (d SpindleDirection) String() string {
+	switch d {
+	case SpindleStopped:
+		return "stopped"
+	case SpindleClockwise:
+		return "clockwise"
+	case SpindleCounterClockwise:
+		return "counterclockwise"
+	}
+
+	return fmt.Sprintf("spindle(%d)", int(d))
+}
+
+// HeaterKind identifies the kind of a heater.
+type HeaterKind int
+
+const (
+	// HeaterHotend is the heater of the hotend of a tool, set by M104 and M109.
+	HeaterHotend HeaterKind = iota
+
+	// HeaterBed is the heater of the bed, set by M140 and M190.
+	HeaterBed
+
+	// HeaterChamber is the heater of the chamber, set by M141 and M191.
+	HeaterChamber
+)
+
+// Heater identifies a heater of the machine.
+type Heater struct {
+	// Kind is the kind of the heater.
+	Kind HeaterKind
+
+	// Index is the tool of a hotend, it is always zero for the bed and the chamber.
+	Index int
+}
+
+// String returns the name of the heater, like "hotend 1" or "bed".
+func (h Heater) String() string {
+	switch h.Kind {
+	case HeaterHotend:
+		return "hotend " + strconv.Itoa(h.Index)
+	case HeaterBed:
+		return "bed"
+	case HeaterChamber:
+		return "chamber"
+	}
+
+	return fmt.Sprintf("heater(%d) %d", int(h.Kind), h.Index)
+}
+
+// less returns true if the heater is sorted before another one, by kind and index.
+func (h Heater) less(other Heater) bool {
+	if h.Kind != other.Kind {
+		return h.Kind < other.Kind
+	}
+
+	return h.Index < other.Index
+}
+
+//#endregion
+//#region state
+
+// Limits are the limits of the motion commanded to the firmware, in millimeters and seconds.
+// A zero value wasn't commanded, so the firmware uses its configuration.
+type Limits struct {
+	// Acceleration is the acceleration of the moves that print in mm/s², set by M204 P or S, or ACCEL of SET_VELOCITY_LIMIT.
+	Acceleration float64
+
+	// TravelAcceleration is the acceleration of the travel moves in mm/s², set by M204 T or S, or ACCEL of SET_VELOCITY_LIMIT.
+	TravelAcceleration float64
+
+	// RetractAcceleration is the acceleration of the moves of the extruder alone in mm/s², set by M204 R.
+	RetractAcceleration float64
+
+	// Jerk is the maximum instantaneous change of speed of each axis in mm/s, indexed as X, Y, Z and E, set by M205 X, Y, Z and E.
+	Jerk [4]float64
+
+	// JunctionDeviation is the junction deviation in mm, set by M205 J.
+	JunctionDeviation float64
+
+	// Velocity is the maximum speed of the moves in mm/s, set by VELOCITY of SET_VELOCITY_LIMIT.
+	Velocity float64
+
+	// SquareCornerVelocity is the maximum speed in mm/s at a corner of 90 degrees, set by SQUARE_CORNER_VELOCITY of SET_VELOCITY_LIMIT.
+	SquareCornerVelocity float64
+}
+
+// Position is the position of the axes of the machine.
+type Position struct {
+	X, Y, Z, E float64
+}
+
+// Offset is the offset of the X, Y and Z axes of a work coordinate system from the origin of the machine.
+type Offset struct {
+	X, Y, Z float64
+}
+
+// State is the state of the machine after executing a sequence of blocks.
+//
+// The zero value is the state of a machine after a reset, with all targets unknown.
+// The maps are shared by the copies of a state, use Clone to get an independent one.
+type State struct {
+	// Units are the units of the lengths, selected by G20 and G21.
+	Units Units
+
+	// Relative is true if the XYZ axes are positioned relative to the current position, selected by G90 and G91.
+	Relative bool
+
+	// RelativeExtrusion is true if the extruder is positioned relative to the current position.
+	// It is selected by M82 and M83, and also by G90 and G91 like Marlin does.
+	RelativeExtrusion bool
+
+	// Plane is the plane of the arcs.
+	Plane Plane
+
+	// Tool is the active tool, selected by T commands.
+	Tool int
+
+	// Feedrate is the last feedrate commanded by a move.
+	Feedrate float64
+
+	// Limits are the accelerations, jerks and velocities commanded by M204, M205 and SET_VELOCITY_LIMIT.
+	Limits Limits
+
+	// Position is the position of the axes after the last move, in the work coordinate system selected and shifted by G92.
+	// G92 sets it and G28 moves the axes homed to the origin of the machine. MachinePosition returns it from the origin of the machine.
+	Position Position
+
+	// WorkOffset is the work coordinate system selected.
+	WorkOffset WorkOffset
+
+	// Offsets are the offsets of each work coordinate system, set by G10 L2 and G10 L20.
+	Offsets [WORK_OFFSETS]Offset
+
+	// Shift is the offset of the XYZ axes set by G92, added to the offset of every work coordinate system.
+	// G92.1 clears it, G92.2 suspends it, G92.3 restores it, and G28 clears it in the axes homed like Marlin does.
+	Shift Offset
+
+	// ShiftSuspended is true if the shift was suspended by G92.2, so it doesn't apply until G92.3.
+	ShiftSuspended bool
+
+	// SpindleDirection is the rotation of the spindle.
+	SpindleDirection SpindleDirection
+
+	// SpindleSpeed is the last speed commanded with S by M3 or M4. It is kept after M5, so a M3 without S starts again at the same speed.
+	SpindleSpeed float64
+
+	// Fans are the speeds of the fans commanded by M106 and M107, indexed by the P of the commands, from 0 to 255.
+	// The fans that weren't commanded aren't included.
+	Fans map[int]float64
+
+	// Heaters are the targets of the heaters commanded. The heaters that weren't commanded aren't included.
+	Heaters map[Heater]float64
+}
+
+// Clone returns a copy of the state that doesn't share the maps.
+func (s State) Clone() State {
+	clone := s
+
+	clone.Fans = nil
+	if s.Fans != nil {
+		clone.Fans = make(map[int]float64, len(s.Fans))
+		for fan, speed := range s.Fans {
+			clone.Fans[fan] = speed
+		}
+	}
+
+	clone.Heaters = nil
+	if s.Heaters != nil {
+		clone.Heaters = make(map[Heater]float64, len(s.Heaters))
+		for heater, target := range s.Heaters {
+			clone.Heaters[heater] = target
+		}
+	}
+
+	return clone
+}
+
+// Apply updates the state with a block, so the state is the one after executing it.
+// The commands that don't modify the state are ignored.
+func (s *State) Apply(b block.Blocker) {
+	command := b.Command()
+
+	if command.Word() == 'T' {
+		if tool, ok := gcode.NumericAddress(command); ok {
+			s.Tool = int(tool)
+		}
+		return
+	}
+
+	switch name := command.String(); name {
+	case "G20":
+		s.Units = UnitsInches
+	case "G21":
+		s.Units = UnitsMillimeters
+	case "G90":
+		s.Relative, s.RelativeExtrusion = false, false
+	case "G91":
+		s.Relative, s.RelativeExtrusion = true, true
+	case "M82":
+		s.RelativeExtrusion = false
+	case "M83":
+		s.RelativeExtrusion = true
+	case "G17":
+		s.Plane = PlaneXY
+	case "G18":
+		s.Plane = PlaneZX
+	case "G19":
+		s.Plane = PlaneYZ
+	case "G54", "G55", "G56", "G57", "G58", "G59", "G59.1", "G59.2", "G59.3":
+		for w, c := range workOffsetCommands {
+			if c == name {
+				s.selectWorkOffset(WorkOffset(w))
+				break
+			}
+		}
+	case "G10":
+		s.setOffset(b)
+	case "G92", "G92.1", "G92.2", "G92.3":
+		s.shift(b)
+	case "G28":
+		s.home(b)
+	case "G53":
+		if hasCommand(b, "G0") || hasCommand(b, "G1") {
+			s.moveMachine(b)
+		}
+	case "G0", "G1", "G2", "G3":
+		if f, ok := blockparam.Number(b, 'F'); ok {
+			s.Feedrate = f
+		}
+
+		if hasCommand(b, "G53") {
+			s.moveMachine(b)
+			break
+		}
+
+		for _, word := range []byte{'X', 'Y', 'Z', 'E'} {
+			value, ok := blockparam.Number(b, word)
+			if !ok {
+				continue
+			}
+
+			relative := s.Relative
+			if word == 'E' {
+				relative = s.RelativeExtrusion
+			}
+
+			if relative {
+				*s.axis(word) += value
+			} else {
+				*s.axis(word) = value
+			}
+		}
+	case "M3", "M4":
+		s.SpindleDirection = SpindleClockwise
+		if name == "M4" {
+			s.SpindleDirection = SpindleCounterClockwise
+		}
+
+		if speed, ok := blockparam.Number(b, 'S'); ok {
+			s.SpindleSpeed = speed
+		}
+	case "M5":
+		s.SpindleDirection = SpindleStopped
+	case "M106", "M107":
+		fan := 0
+		if p, ok := blockparam.Number(b, 'P'); ok {
+			fan = int(p)
+		}
+
+		speed := 0.0
+		if name == "M106" {
+			speed = 255
+			if value, ok := blockparam.Number(b, 'S'); ok {
+				speed = value
+			}
+		}
+
+		if s.Fans == nil {
+			s.Fans = map[int]float64{}
+		}
+		s.Fans[fan] = speed
+	case "M104", "M109", "M140", "M190", "M141", "M191":
+		s.setHeater(b)
+	case "M204":
+		if value, ok := blockparam.Number(b, 'S'); ok {
+			s.Limits.Acceleration, s.Limits.TravelAcceleration = value, value
+		}
+		if value, ok := blockparam.Number(b, 'P'); ok {
+			s.Limits.Acceleration = value
+		}
+		if value, ok := blockparam.Number(b, 'T'); ok {
+			s.Limits.TravelAcceleration = value
+		}
+		if value, ok := blockparam.Number(b, 'R'); ok {
+			s.Limits.RetractAcceleration = value
+		}
+	case "M205":
+		for i, word := range []byte{'X', 'Y', 'Z', 'E'} {
+			if value, ok := blockparam.Number(b, word); ok {
+				s.Limits.Jerk[i] = value
+			}
+		}
+		if value, ok := blockparam.Number(b, 'J'); ok {
+			s.Limits.JunctionDeviation = value
+		}
+	}
+}
+
+// ApplyExtendedCommand updates the state with an extended command of Klipper, like the ones returned by document.ParseExtendedCommand:
+// the name in upper case and the arguments indexed by their names in upper case.
+// SET_VELOCITY_LIMIT sets the limits, its ACCEL sets the accelerations of the moves that print and the travel moves.
+// The other commands and the arguments that aren't numbers are ignored.
+func (s *State) ApplyExtendedCommand(name string, arguments map[string]string) {
+	if name != "SET_VELOCITY_LIMIT" {
+		return
+	}
+
+	for argument, value := range arguments {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+
+		switch argument {
+		case "VELOCITY":
+			s.Limits.Velocity = number
+		case "ACCEL":
+			s.Limits.Acceleration, s.Limits.TravelAcceleration = number, number
+		case "SQUARE_CORNER_VELOCITY":
+			s.Limits.SquareCornerVelocity = number
+		}
+	}
+}
+
+// ActiveOffset returns the offset of the origin of the work coordinates from the origin of the machine:
+// the offset of the work coordinate system selected plus the shift of G92, if it isn't suspended.
+func (s State) ActiveOffset() Offset {
+	offset := s.Offsets[s.WorkOffset]
+	if s.ShiftSuspended {
+		return offset
+	}
+
+	return Offset{X: offset.X + s.Shift.X, Y: offset.Y + s.Shift.Y, Z: offset.Z + s.Shift.Z}
+}
+
+// MachinePosition returns the position of the axes from the origin of the machine, the position in the coordinates of G53.
+// The extruder isn't affected by the offsets.
+func (s State) MachinePosition() Position {
+	offset := s.ActiveOffset()
+
+	return Position{X: s.Position.X + offset.X, Y: s.Position.Y + offset.Y, Z: s.Position.Z + offset.Z, E: s.Position.E}
+}
+
+// setMachinePosition sets the position of the XYZ axes from a position in the coordinates of the machine.
+func (s *State) setMachinePosition(machine Offset) {
+	offset := s.ActiveOffset()
+
+	s.Position.X = machine.X - offset.X
+	s.Position.Y = machine.Y - offset.Y
+	s.Position.Z = machine.Z - offset.Z
+}
+
+// selectWorkOffset selects a work coordinate system, so the position is expressed from its origin.
+func (s *State) selectWorkOffset(w WorkOffset) {
+	previous, next := s.Offsets[s.WorkOffset], s.Offsets[w]
+
+	s.Position.X += previous.X - next.X
+	s.Position.Y += previous.Y - next.Y
+	s.Position.Z += previous.Z - next.Z
+	s.WorkOffset = w
+}
+
+// setOffset sets the offset of a work coordinate system with G10 L2, the offset from the origin of the machine,
+// or G10 L20, the offset that makes the current position the value commanded.
+// The work coordinate system is selected by P from 1, or the active one with P0 or without P.
+// The other uses of G10, like the retraction of Marlin, are ignored.
+func (s *State) setOffset(b block.Blocker) {
+	l, ok := blockparam.Number(b, 'L')
+	if !ok || (l != 2 && l != 20) {
+		return
+	}
+
+	w := s.WorkOffset
+	if p, ok := blockparam.Number(b, 'P'); ok && p != 0 {
+		if p < 1 || p > WORK_OFFSETS {
+			return
+		}
+		w = WorkOffset(p - 1)
+	}
+
+	current := s.MachinePosition()
+	machine := Offset{X: current.X, Y: current.Y, Z: current.Z}
+
+	// G10 L20 makes the current position the value commanded with the shift of G92 applied
+	shift := Offset{}
+	if !s.ShiftSuspended {
+		shift = s.Shift
+	}
+
+	offset := &s.Offsets[w]
+	for _, axis := range []struct {
+		word    byte
+		offset  *float64
+		machine float64
+		shift   float64
+	}{
+		{'X', &offset.X, machine.X, shift.X},
+		{'Y', &offset.Y, machine.Y, shift.Y},
+		{'Z', &offset.Z, machine.Z, shift.Z},
+	} {
+		value, ok := blockparam.Number(b, axis.word)
+		if !ok {
+			continue
+		}
+
+		if l == 2 {
+			*axis.offset = value
+		} else {
+			*axis.offset = axis.machine - axis.shift - value
+		}
+	}
+
+	// the machine doesn't move, so the position changes if the offset of the active system changed
+	s.setMachinePosition(machine)
+}
+
+// shift applies G92, that shifts the work coordinates so the current position is the value commanded, or the extruder
+// without shifting it, and G92.1, G92.2 and G92.3, that clear, suspend and restore the shift.
+func (s *State) shift(b block.Blocker) {
+	current := s.MachinePosition()
+	machine := Offset{X: current.X, Y: current.Y, Z: current.Z}
+
+	switch b.Command().String() {
+	case "G92.1":
+		s.Shift, s.ShiftSuspended = Offset{}, false
+	case "G92.2":
+		s.ShiftSuspended = true
+	case "G92.3":
+		s.ShiftSuspended = false
+	default:
+		if value, ok := blockparam.Number(b, 'E'); ok {
+			s.Position.E = value
+		}
+
+		// a new shift replaces the suspended one
+		if s.ShiftSuspended && (hasParameter(b, 'X') || hasParameter(b, 'Y') || hasParameter(b, 'Z')) {
+			s.Shift, s.ShiftSuspended = Offset{}, false
+		}
+
+		for _, axis := range []struct {
+			word     byte
+			shift    *float64
+			position float64
+		}{
+			{'X', &s.Shift.X, s.Position.X},
+			{'Y', &s.Shift.Y, s.Position.Y},
+			{'Z', &s.Shift.Z, s.Position.Z},
+		} {
+			if value, ok := blockparam.Number(b, axis.word); ok {
+				*axis.shift += axis.position - value
+			}
+		}
+	}
+
+	// the machine doesn't move, so the position changes with the shift
+	s.setMachinePosition(machine)
+}
+
+// moveMachine moves the axes to the positions of the block in the coordinates of the machine, like G53 G0 does.
+// The positions are absolute even in relative positioning, and the extruder moves like in any other move.
+func (s *State) moveMachine(b block.Blocker) {
+	if f, ok := blockparam.Number(b, 'F'); ok {
+		s.Feedrate = f
+	}
+
+	current := s.MachinePosition()
+	machine := Offset{X: current.X, Y: current.Y, Z: current.Z}
+
+	for _, axis := range []struct {
+		word     byte
+		position *float64
+	}{
+		{'X', &machine.X},
+		{'Y', &machine.Y},
+		{'Z', &machine.Z},
+	} {
+		if value, ok := blockparam.Number(b, axis.word); ok {
+			*axis.position = value
+		}
+	}
+
+	if value, ok := blockparam.Number(b, 'E'); ok {
+		if s.RelativeExtrusion {
+			s.Position.E += value
+		} else {
+			s.Position.E = value
+		}
+	}
+
+	s.setMachinePosition(machine)
+}
+
+// home moves the axes homed by G28 to the origin of the machine, all of them if the block doesn't select any,
+// and clears the shift of G92 of the axes homed.
+func (s *State) home(b block.Blocker) {
+	offset := s.Offsets[s.WorkOffset]
+
+	all := !hasParameter(b, 'X') && !hasParameter(b, 'Y') && !hasParameter(b, 'Z')
+
+	// the subtraction from zero avoids the negative zero of the offsets unset
+	for _, axis := range []struct {
+		word     byte
+		offset   float64
+		position *float64
+		shift    *float64
+	}{
+		{'X', offset.X, &s.Position.X, &s.Shift.X},
+		{'Y', offset.Y, &s.Position.Y, &s.Shift.Y},
+		{'Z', offset.Z, &s.Position.Z, &s.Shift.Z},
+	} {
+		if all || hasParameter(b, axis.word) {
+			*axis.position = 0 - axis.offset
+			*axis.shift = 0
+		}
+	}
+
+	// a suspended shift cleared in all axes isn't suspended anymore
+	if s.ShiftSuspended && s.Shift == (Offset{}) {
+		s.ShiftSuspended = false
+	}
+}
+
+// setHeater stores the target of a temperature command.
+func (s *State) setHeater(b block.Blocker) {
+	heater, target, ok := s.HeaterTarget(b)
+	if !ok {
+		return
+	}
+
+	if s.Heaters == nil {
+		s.Heaters = map[Heater]float64{}
+	}
+	s.Heaters[heater] = target
+}
+
+// HeaterTarget returns the heater and the target of a temperature command executed in the state, commanded by S or by R like Marlin does.
+// The hotend is the one of the tool selected by T, or the active tool.
+// It returns false if the block isn't a temperature command with a target, like M104, M109, M140, M190, M141 or M191.
+func (s State) HeaterTarget(b block.Blocker) (Heater, float64, bool) {
+	heater := Heater{Kind: HeaterHotend, Index: s.Tool}
+
+	switch b.Command().String() {
+	case "M104", "M109":
+		if t, ok := blockparam.Number(b, 'T'); ok {
+			heater.Index = int(t)
+		}
+	case "M140", "M190":
+		heater = Heater{Kind: HeaterBed}
+	case "M141", "M191":
+		heater = Heater{Kind: HeaterChamber}
+	default:
+		return Heater{}, 0, false
+	}
+
+	target, ok := blockparam.Number(b, 'S')
+	if !ok {
+		if target, ok = blockparam.Number(b, 'R'); !ok {
+			return Heater{}, 0, false
+		}
+	}
+
+	return heater, target, true
+}
+
+// axis returns the coordinate of the axis required.
+func (s *State) axis(word byte) *float64 {
+	switch word {
+	case 'X':
+		return &s.Position.X
+	case 'Y':
+		return &s.Position.Y
+	case 'Z':
+		return &s.Position.Z
+	}
+
+	return &s.Position.E
+}
+
+//#endregion
+//#region machine
+
+// Machine consumes blocks and tracks the state of the machine that executes them.
+//
+// The zero value is a machine after a reset, ready to use.
+type Machine struct {
+	state State
+}
+
+// Apply updates the state of the machine with a block and returns the state before executing it.
+func (m *Machine) Apply(b block.Blocker) State {
+	before := m.state.Clone()
+	m.state.Apply(b)
+
+	return before
+}
+
+// ApplyExtendedCommand updates the state of the machine with an extended command of Klipper and returns the state before executing it.
+func (m *Machine) ApplyExtendedCommand(name string, arguments map[string]string) State {
+	before := m.state.Clone()
+	m.state.ApplyExtendedCommand(name, arguments)
+
+	return before
+}
+
+// Snapshot returns a copy of the current state, it isn't modified by the following blocks.
+func (m *Machine) Snapshot() State {
+	return m.state.Clone()
+}
+
+// Reset restores the state of a machine after a reset.
+func (m *Machine) Reset() {
+	m.state = State{}
+}
+
+// New returns a machine that starts from the state received, like the state at the end of a previous file.
+func New(initial State) *Machine {
+	return &Machine{state: initial.Clone()}
+}
+
+//#endregion
+//#region private functions
+
+// hasCommand returns true if the block has a parameter that is the command required, like the G53 of "G0 G53 X0".
+func hasCommand(b block.Blocker, command string) bool {
+	for _, p := range b.Parameters() {
+		if p.String() == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasParameter returns true if the block has a parameter with the word required.
+func hasParameter(b block.Blocker, word byte) bool {
+	for _, p := range b.Parameters() {
+		if p.Word() == word {
+			return true
+		}
+	}
+
+	return false
+}
+
+//#endregion
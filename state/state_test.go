@@ -0,0 +1,227 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+)
+
+func TestState_Apply(t *testing.T) {
+
+	cases := map[string]struct {
+		lines []string
+		want  func(s *State)
+	}{
+		"empty": {nil, func(s *State) {}},
+		"modes": {
+			[]string{"G20", "G91", "M82", "G18", "T2"},
+			func(s *State) {
+				s.Units, s.Relative, s.Plane, s.Tool = UnitsInches, true, PlaneZX, 2
+			},
+		},
+		"moves": {
+			[]string{"G1 X10 Y5 F1200", "G91", "G1 X1 E2", "G90", "G0 Z0.3 F3000"},
+			func(s *State) {
+				s.Position, s.Feedrate = Position{X: 11, Y: 5, Z: 0.3, E: 2}, 3000
+			},
+		},
+		"set and home": {
+			[]string{"G1 X10 Y10 Z10", "G92 E5 X2", "G28 Y0"},
+			func(s *State) {
+				s.Position = Position{X: 2, Y: 0, Z: 10, E: 5}
+				s.Shift = Offset{X: 8}
+			},
+		},
+		"shift with work offset": {
+			[]string{"G10 L2 P2 X100", "G55", "G1 X10 Y10", "G92 X0", "G54", "G1 X5"},
+			func(s *State) {
+				s.Offsets[G55] = Offset{X: 100}
+				s.Shift = Offset{X: 10}
+				s.Position = Position{X: 5, Y: 10}
+			},
+		},
+		"shift suspended and restored": {
+			[]string{"G1 X10 Z5", "G92 X0 Z0", "G92.2", "G1 X20", "G92.3"},
+			func(s *State) {
+				s.Shift = Offset{X: 10, Z: 5}
+				s.Position = Position{X: 10, Z: 0}
+			},
+		},
+		"shift cleared": {
+			[]string{"G1 X10", "G92 X0", "G1 X5", "G92.1"},
+			func(s *State) {
+				s.Position = Position{X: 15}
+			},
+		},
+		"work offset with shift": {
+			[]string{"G1 X10", "G92 X0", "G10 L20 P1 X1"},
+			func(s *State) {
+				s.Offsets[G54] = Offset{X: -1}
+				s.Shift = Offset{X: 10}
+				s.Position = Position{X: 1}
+			},
+		},
+		"machine coordinates": {
+			[]string{"G10 L2 P1 X50 Y50", "G91", "G53 G0 X10 Y0", "G1 G53 Z-5 F300"},
+			func(s *State) {
+				s.Relative, s.RelativeExtrusion = true, true
+				s.Offsets[G54] = Offset{X: 50, Y: 50}
+				s.Position = Position{X: -40, Y: -50, Z: -5}
+				s.Feedrate = 300
+			},
+		},
+		"machine coordinates without move": {
+			[]string{"G53 X10"},
+			func(s *State) {},
+		},
+		"spindle": {
+			[]string{"M3 S12000", "M5", "M4"},
+			func(s *State) {
+				s.SpindleDirection, s.SpindleSpeed = SpindleCounterClockwise, 12000
+			},
+		},
+		"fans": {
+			[]string{"M106 S128", "M106 P1", "M107 P1"},
+			func(s *State) {
+				s.Fans = map[int]float64{0: 128, 1: 0}
+			},
+		},
+		"heaters": {
+			[]string{"T1", "M104 S210", "M109 T0 R200", "M190 S60", "M141 S40", "M104"},
+			func(s *State) {
+				s.Tool = 1
+				s.Heaters = map[Heater]float64{
+					{Kind: HeaterHotend, Index: 1}: 210,
+					{Kind: HeaterHotend, Index: 0}: 200,
+					{Kind: HeaterBed}:              60,
+					{Kind: HeaterChamber}:          40,
+				}
+			},
+		},
+		"work offsets": {
+			[]string{"G10 L2 P2 X100 Y50", "G1 X10 Y10", "G55"},
+			func(s *State) {
+				s.WorkOffset = G55
+				s.Offsets[G55] = Offset{X: 100, Y: 50}
+				s.Position = Position{X: -90, Y: -40}
+			},
+		},
+		"work offset of the active system": {
+			[]string{"G59.1", "G1 X10 Y10 Z5", "G10 L20 P0 X0 Z1"},
+			func(s *State) {
+				s.WorkOffset = G59_1
+				s.Offsets[G59_1] = Offset{X: 10, Z: 4}
+				s.Position = Position{X: 0, Y: 10, Z: 1}
+			},
+		},
+		"home with work offset": {
+			[]string{"G10 L2 P1 X5 Y5 Z5", "G28 X0"},
+			func(s *State) {
+				s.Offsets[G54] = Offset{X: 5, Y: 5, Z: 5}
+				s.Position = Position{X: -5, Y: -5, Z: -5}
+			},
+		},
+		"limits": {
+			[]string{"M204 S1000 R500", "M204 P1500", "M205 X10 Y8 E5 J0.02"},
+			func(s *State) {
+				s.Limits = Limits{Acceleration: 1500, TravelAcceleration: 1000, RetractAcceleration: 500, Jerk: [4]float64{10, 8, 0, 5}, JunctionDeviation: 0.02}
+			},
+		},
+		"retraction ignored": {
+			[]string{"G10", "G10 P10 X1"},
+			func(s *State) {},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got State
+			for _, b := range blocktest.ParseCNC(t, tc.lines...) {
+				got.Apply(b)
+			}
+
+			var want State
+			tc.want(&want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMachine(t *testing.T) {
+	blocks := blocktest.Parse(t, "M106 S255", "M107", "G1 X3")
+
+	m := New(State{Tool: 1})
+
+	before := m.Apply(blocks[0])
+	if before.Fans != nil || before.Tool != 1 {
+		t.Errorf("got state before %+v, want the initial state", before)
+	}
+
+	snapshot := m.Snapshot()
+	m.Apply(blocks[1])
+	m.Apply(blocks[2])
+
+	if snapshot.Fans[0] != 255 || snapshot.Position.X != 0 {
+		t.Errorf("got snapshot %+v, want it unmodified by the following blocks", snapshot)
+	}
+
+	if got := m.Snapshot(); got.Fans[0] != 0 || got.Position.X != 3 {
+		t.Errorf("got state %+v, want fan 0 off at X3", got)
+	}
+
+	m.Reset()
+	if got := m.Snapshot(); !reflect.DeepEqual(got, State{}) {
+		t.Errorf("got state %+v after reset, want the zero state", got)
+	}
+}
+
+func TestState_ApplyExtendedCommand(t *testing.T) {
+	s := State{Limits: Limits{Acceleration: 500, RetractAcceleration: 800}}
+
+	s.ApplyExtendedCommand("SET_VELOCITY_LIMIT", map[string]string{"VELOCITY": "300", "ACCEL": "3000", "SQUARE_CORNER_VELOCITY": "5", "MINIMUM_CRUISE_RATIO": "0.5"})
+	s.ApplyExtendedCommand("SET_VELOCITY_LIMIT", map[string]string{"VELOCITY": "fast"})
+	s.ApplyExtendedCommand("SET_FAN_SPEED", map[string]string{"SPEED": "1"})
+
+	want := Limits{Acceleration: 3000, TravelAcceleration: 3000, RetractAcceleration: 800, Velocity: 300, SquareCornerVelocity: 5}
+	if !reflect.DeepEqual(s, State{Limits: want}) {
+		t.Errorf("got %+v, want limits %+v", s, want)
+	}
+
+	m := New(s)
+	before := m.ApplyExtendedCommand("SET_VELOCITY_LIMIT", map[string]string{"ACCEL": "1000"})
+	if before.Limits.Acceleration != 3000 || m.Snapshot().Limits.TravelAcceleration != 1000 {
+		t.Errorf("got state before %+v and after %+v, want acceleration 3000 then 1000", before.Limits, m.Snapshot().Limits)
+	}
+}
+
+func TestState_HeaterTarget(t *testing.T) {
+
+	cases := map[string]struct {
+		line   string
+		heater Heater
+		target float64
+		ok     bool
+	}{
+		"active tool":    {"M104 S210", Heater{Kind: HeaterHotend, Index: 2}, 210, true},
+		"tool selected":  {"M109 T1 R180", Heater{Kind: HeaterHotend, Index: 1}, 180, true},
+		"bed":            {"M190 S60", Heater{Kind: HeaterBed}, 60, true},
+		"chamber":        {"M141 S0", Heater{Kind: HeaterChamber}, 0, true},
+		"without target": {"M104 T1", Heater{}, 0, false},
+		"other command":  {"M106 S200", Heater{}, 0, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := State{Tool: 2}
+
+			heater, target, ok := s.HeaterTarget(blocktest.Parse(t, tc.line)[0])
+			if heater != tc.heater || target != tc.target || ok != tc.ok {
+				t.Errorf("got %v %v %v, want %v %v %v", heater, target, ok, tc.heater, tc.target, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// localstorage package implements storage.Storage interface using a directory of the local filesystem.
+//
+// Each key is mapped to a file path relative to the root directory, creating the intermediate directories when it is required.
+package localstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+//#region local storage struct
+
+// LocalStorage stores the documents as files inside a root directory.
+type LocalStorage struct {
+	// root is the directory that contains all documents
+	root string
+}
+
+// Open returns the file of the document stored with the key.
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to open document %s: %w", key, storage.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to open document %s: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// Create returns a new file to write the document with the key, creating the directories required.
+func (s *LocalStorage) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	path, err := s.path(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of the document %s: %w", key, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document %s: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// Delete removes the file of the document stored with the key.
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete document %s: %w", key, storage.ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete document %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// List walks the root directory and returns the keys of all files that begin with the prefix.
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents with prefix %s: %w", prefix, err)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// path validates the key and returns the file path of the document.
+func (s *LocalStorage) path(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if err := storage.ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new LocalStorage that stores the documents inside the root directory.
+//
+// The root directory must exist.
+func New(root string) (*LocalStorage, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a local storage at %s: %w", root, err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to create a local storage at %s: it isn't a directory", root)
+	}
+
+	return &LocalStorage{root: root}, nil
+}
+
+//#endregion
@@ -0,0 +1,98 @@
+package localstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+func TestNew(t *testing.T) {
+	root := t.TempDir()
+
+	if _, err := New(root); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+	}
+
+	if _, err := New(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	if _, err := New(file); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	s, err := New(root)
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	for _, key := range []string{"jobs/b.gcode", "jobs/a.gcode", "other.gcode"} {
+		w, err := s.Create(ctx, key)
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		if _, err := io.WriteString(w, "G28\n"+key); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "jobs", "a.gcode")); err != nil {
+		t.Errorf("got error %v, want document stored as file", err)
+	}
+
+	r, err := s.Open(ctx, "jobs/a.gcode")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "G28\njobs/a.gcode" {
+		t.Errorf("got %q, want %q", content, "G28\njobs/a.gcode")
+	}
+
+	keys, err := s.List(ctx, "jobs/")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	if strings.Join(keys, ",") != "jobs/a.gcode,jobs/b.gcode" {
+		t.Errorf("got keys %v, want keys [jobs/a.gcode jobs/b.gcode]", keys)
+	}
+
+	if err := s.Delete(ctx, "jobs/a.gcode"); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+	}
+	if err := s.Delete(ctx, "jobs/a.gcode"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v, want error not found", err)
+	}
+	if _, err := s.Open(ctx, "jobs/a.gcode"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v, want error not found", err)
+	}
+
+	if _, err := s.Create(ctx, "../escape.gcode"); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.Open(cancelled, "jobs/b.gcode"); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
@@ -0,0 +1,148 @@
+// memorystorage package implements storage.Storage interface keeping the documents in memory.
+//
+// It is safe for concurrent use. The documents are lost when the MemoryStorage instance is released.
+package memorystorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+//#region memory storage struct
+
+// MemoryStorage stores the content of each document in a map indexed by key.
+type MemoryStorage struct {
+	mutex     sync.RWMutex
+	documents map[string][]byte
+}
+
+// Open returns a reader of the content of the document stored with the key.
+//
+// The reader is a snapshot of the document, it isn't affected by later writes.
+func (s *MemoryStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validate(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	content, ok := s.documents[key]
+	if !ok {
+		return nil, fmt.Errorf("failed to open document %s: %w", key, storage.ErrNotFound)
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Create returns a writer that stores the document when it is closed.
+func (s *MemoryStorage) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	if err := validate(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return &writer{storage: s, key: key}, nil
+}
+
+// Delete removes the document stored with the key.
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	if err := validate(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.documents[key]; !ok {
+		return fmt.Errorf("failed to delete document %s: %w", key, storage.ErrNotFound)
+	}
+
+	delete(s.documents, key)
+
+	return nil
+}
+
+// List returns the keys of all documents that begin with the prefix.
+func (s *MemoryStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list documents with prefix %s: %w", prefix, err)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var keys []string
+	for key := range s.documents {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+//#endregion
+//#region writer
+
+// writer buffers the content of a document until it is closed.
+type writer struct {
+	storage *MemoryStorage
+	key     string
+	buffer  bytes.Buffer
+	closed  bool
+}
+
+// Write appends data to the document.
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("failed to write document %s: writer is closed", w.key)
+	}
+
+	return w.buffer.Write(p)
+}
+
+// Close stores the document in the storage.
+func (w *writer) Close() error {
+	if w.closed {
+		return fmt.Errorf("failed to close document %s: writer is already closed", w.key)
+	}
+	w.closed = true
+
+	w.storage.mutex.Lock()
+	defer w.storage.mutex.Unlock()
+
+	w.storage.documents[w.key] = w.buffer.Bytes()
+
+	return nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new empty MemoryStorage.
+func New() *MemoryStorage {
+	return &MemoryStorage{documents: make(map[string][]byte)}
+}
+
+//#endregion
+//#region private functions
+
+// validate checks the context and the key before execute any operation.
+func validate(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return storage.ValidateKey(key)
+}
+
+//#endregion
@@ -0,0 +1,70 @@
+package memorystorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	for _, key := range []string{"jobs/b.gcode", "jobs/a.gcode", "other.gcode"} {
+		w, err := s.Create(ctx, key)
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		if _, err := io.WriteString(w, "G28\n"+key); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		// the document isn't visible until the writer is closed
+		if _, err := s.Open(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("got error %v, want error not found", err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+		if err := w.Close(); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+		if _, err := w.Write([]byte("G1")); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	}
+
+	r, err := s.Open(ctx, "jobs/a.gcode")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "G28\njobs/a.gcode" {
+		t.Errorf("got %q, want %q", content, "G28\njobs/a.gcode")
+	}
+
+	keys, err := s.List(ctx, "jobs/")
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+	if strings.Join(keys, ",") != "jobs/a.gcode,jobs/b.gcode" {
+		t.Errorf("got keys %v, want keys [jobs/a.gcode jobs/b.gcode]", keys)
+	}
+
+	if err := s.Delete(ctx, "jobs/a.gcode"); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+	}
+	if err := s.Delete(ctx, "jobs/a.gcode"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v, want error not found", err)
+	}
+
+	if _, err := s.Create(ctx, ""); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
@@ -0,0 +1,437 @@
+// s3storage package implements storage.Storage interface using an S3-compatible object store, like AWS S3 or MinIO.
+//
+// The documents are streamed directly between the caller and the object store.
+// The reads use the body of the response without buffering.
+// The writes are buffered only until they reach the part size, then each part is uploaded using a multipart upload,
+// so the memory used by a writer is bounded and never requires temporary files.
+//
+// The requests are authenticated with the AWS Signature Version 4 using only the standard library.
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+const (
+	// MIN_PART_SIZE is the minimum size of each part of a multipart upload allowed by S3, except the last one.
+	MIN_PART_SIZE = 5 * 1024 * 1024
+)
+
+//#region config
+
+// Config contains the parameters to connect to the object store.
+type Config struct {
+	// Endpoint is the base URL of the object store, like "https://s3.us-east-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+
+	// Region is the region used to sign the requests, like "us-east-1".
+	Region string
+
+	// Bucket is the name of the bucket that stores the documents.
+	Bucket string
+
+	// AccessKeyID and SecretAccessKey are the credentials used to sign the requests.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is optional, it is required only with temporary credentials.
+	SessionToken string
+
+	// PathStyle uses URLs like "endpoint/bucket/key" instead of "bucket.endpoint/key". Most S3-compatible stores require it.
+	PathStyle bool
+
+	// PartSize is the size of each part of the multipart uploads, it mustn't be less than MIN_PART_SIZE. If it is zero then MIN_PART_SIZE is used.
+	PartSize int
+
+	// Client is the HTTP client used to send the requests. If it is nil then http.DefaultClient is used.
+	Client *http.Client
+}
+
+//#endregion
+//#region s3 storage struct
+
+// S3Storage stores each document as an object of a bucket.
+type S3Storage struct {
+	endpoint    *url.URL
+	region      string
+	bucket      string
+	credentials credentials
+	pathStyle   bool
+	client      *http.Client
+
+	// partSize is the size of each part of the multipart uploads, it is reduced in tests.
+	partSize int
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+}
+
+// Open returns the body of the object stored with the key.
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := storage.ValidateKey(key); err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document %s: %w", key, err)
+	}
+
+	return resp.Body, nil
+}
+
+// Create returns a writer that uploads the object with the key.
+//
+// If the document is smaller than the part size it is uploaded with a single request when the writer is closed.
+// Otherwise, a multipart upload is started and completed when the writer is closed.
+func (s *S3Storage) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	if err := storage.ValidateKey(key); err != nil {
+		return nil, fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return &writer{ctx: ctx, storage: s, key: key}, nil
+}
+
+// Delete removes the object stored with the key.
+//
+// S3 doesn't report if an object exists when it is deleted, so a HEAD request is sent first.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := storage.ValidateKey(key); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	resp, err = s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// List returns the keys of all objects that begin with the prefix, following the continuation tokens.
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents with prefix %s: %w", prefix, err)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the list of documents with prefix %s: %w", prefix, err)
+		}
+
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return keys, nil
+}
+
+// do sends a signed request and returns the response if the status is successful.
+//
+// key is the object key, or empty to send the request to the bucket.
+// A response with status 404 is reported as an error that wraps storage.ErrNotFound.
+func (s *S3Storage) do(ctx context.Context, method string, key string, query url.Values, body []byte) (*http.Response, error) {
+
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		path += "/" + s.bucket
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	path += "/" + key
+
+	u.Path = path
+	u.RawPath = escape(path, true)
+	u.RawQuery = canonicalQuery(query)
+
+	var reader io.Reader
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		payloadHash = hashHex(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s request: %w", method, err)
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+	}
+
+	sign(req, payloadHash, s.credentials, s.region, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, storage.ErrNotFound
+	}
+
+	var result struct {
+		Code    string
+		Message string
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.Code != "" {
+		return nil, fmt.Errorf("%s request failed with status %d: %s: %s", method, resp.StatusCode, result.Code, result.Message)
+	}
+
+	return nil, fmt.Errorf("%s request failed with status %d", method, resp.StatusCode)
+}
+
+//#endregion
+//#region writer
+
+// writer buffers the content of a document and uploads it in parts.
+type writer struct {
+	ctx     context.Context
+	storage *S3Storage
+	key     string
+	buffer  bytes.Buffer
+
+	// uploadID identifies the multipart upload, it is empty until the first part is uploaded
+	uploadID string
+
+	// etags stores the ETag of each part uploaded, in order
+	etags []string
+
+	closed bool
+	err    error
+}
+
+// Write appends data to the document, uploading a new part each time the buffer reaches the part size.
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("failed to write document %s: writer is closed", w.key)
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n, _ := w.buffer.Write(p)
+
+	for w.buffer.Len() >= w.storage.partSize {
+		if err := w.uploadPart(w.buffer.Next(w.storage.partSize)); err != nil {
+			w.err = err
+			w.abort()
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close uploads the content that remains in the buffer and completes the upload.
+func (w *writer) Close() error {
+	if w.closed {
+		return fmt.Errorf("failed to close document %s: writer is already closed", w.key)
+	}
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	// small documents are uploaded with a single request
+	if w.uploadID == "" {
+		resp, err := w.storage.do(w.ctx, http.MethodPut, w.key, nil, w.buffer.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to upload document %s: %w", w.key, err)
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if w.buffer.Len() > 0 {
+		if err := w.uploadPart(w.buffer.Bytes()); err != nil {
+			w.abort()
+			return err
+		}
+	}
+
+	var complete struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	for i, etag := range w.etags {
+		complete.Parts = append(complete.Parts, struct {
+			PartNumber int
+			ETag       string
+		}{i + 1, etag})
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		w.abort()
+		return fmt.Errorf("failed to encode the completion of the document %s: %w", w.key, err)
+	}
+
+	resp, err := w.storage.do(w.ctx, http.MethodPost, w.key, url.Values{"uploadId": {w.uploadID}}, body)
+	if err != nil {
+		w.abort()
+		return fmt.Errorf("failed to complete the upload of the document %s: %w", w.key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// uploadPart uploads a single part, starting the multipart upload if it is required.
+func (w *writer) uploadPart(part []byte) error {
+	if w.uploadID == "" {
+		resp, err := w.storage.do(w.ctx, http.MethodPost, w.key, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start the upload of the document %s: %w", w.key, err)
+		}
+
+		var result struct {
+			UploadId string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil || result.UploadId == "" {
+			return fmt.Errorf("failed to start the upload of the document %s: invalid response: %v", w.key, err)
+		}
+
+		w.uploadID = result.UploadId
+	}
+
+	number := len(w.etags) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {w.uploadID}}
+
+	resp, err := w.storage.do(w.ctx, http.MethodPut, w.key, query, part)
+	if err != nil {
+		return fmt.Errorf("failed to upload the part %d of the document %s: %w", number, w.key, err)
+	}
+	resp.Body.Close()
+
+	w.etags = append(w.etags, resp.Header.Get("ETag"))
+
+	return nil
+}
+
+// abort cancels the multipart upload, if it was started, to release the parts stored.
+func (w *writer) abort() {
+	if w.uploadID == "" {
+		return
+	}
+
+	resp, err := w.storage.do(w.ctx, http.MethodDelete, w.key, url.Values{"uploadId": {w.uploadID}}, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new S3Storage configured to use the bucket and credentials of the config.
+func New(config Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a s3 storage, invalid endpoint %s: %w", config.Endpoint, err)
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("failed to create a s3 storage, endpoint %s must include scheme and host", config.Endpoint)
+	}
+
+	if config.Region == "" {
+		return nil, fmt.Errorf("failed to create a s3 storage, region mustn't be empty")
+	}
+
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("failed to create a s3 storage, bucket mustn't be empty")
+	}
+
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("failed to create a s3 storage, credentials mustn't be empty")
+	}
+
+	partSize := config.PartSize
+	if partSize == 0 {
+		partSize = MIN_PART_SIZE
+	}
+	if partSize < MIN_PART_SIZE {
+		return nil, fmt.Errorf("failed to create a s3 storage, part size %d mustn't be less than %d", partSize, MIN_PART_SIZE)
+	}
+
+	client := config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &S3Storage{
+		endpoint: endpoint,
+		region:   config.Region,
+		bucket:   config.Bucket,
+		credentials: credentials{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			sessionToken:    config.SessionToken,
+		},
+		pathStyle: config.PathStyle,
+		partSize:  partSize,
+		client:    client,
+		now:       time.Now,
+	}, nil
+}
+
+//#endregion
@@ -0,0 +1,303 @@
+package s3storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/storage"
+)
+
+// mockObjectStore is a minimal in-memory S3 server that supports the requests used by S3Storage.
+type mockObjectStore struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	aborted int
+}
+
+func (m *mockObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>AccessDenied</Code><Message>unsigned request</Message></Error>")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if hashHex(body) != r.Header.Get("X-Amz-Content-Sha256") {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "<Error><Code>XAmzContentSHA256Mismatch</Code><Message>bad hash</Message></Error>")
+		return
+	}
+
+	prefix := "/" + m.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+	query := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		var keys []string
+		for k := range m.objects {
+			if strings.HasPrefix(k, query.Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		// paginate one key per page to exercise the continuation tokens
+		start := 0
+		if token := query.Get("continuation-token"); token != "" {
+			fmt.Sscanf(token, "%d", &start)
+		}
+		fmt.Fprint(w, "<ListBucketResult>")
+		if start < len(keys) {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", keys[start])
+		}
+		if start+1 < len(keys) {
+			fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", start+1)
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(m.uploads)+1)
+		m.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		var number int
+		fmt.Sscanf(query.Get("partNumber"), "%d", &number)
+		m.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"etag-%d\"", number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var content []byte
+		for _, part := range complete.Parts {
+			content = append(content, m.uploads[query.Get("uploadId")][part.PartNumber]...)
+		}
+		m.objects[key] = content
+		delete(m.uploads, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		m.aborted++
+		delete(m.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		m.objects[key] = body
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		content, ok := m.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	case r.Method == http.MethodDelete:
+		delete(m.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newMockStorage(t *testing.T, partSize int) (*S3Storage, *mockObjectStore) {
+	t.Helper()
+
+	mock := &mockObjectStore{bucket: "gcodes", objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	s, err := New(Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "gcodes",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	// S3 rejects the parts smaller than MIN_PART_SIZE, the mock accepts them to test the multipart uploads with small documents
+	if partSize > 0 {
+		s.partSize = partSize
+	}
+
+	return s, mock
+}
+
+func TestNew(t *testing.T) {
+	valid := Config{Endpoint: "https://s3.amazonaws.com", Region: "us-east-1", Bucket: "b", AccessKeyID: "a", SecretAccessKey: "s"}
+
+	cases := map[string]struct {
+		modify func(c *Config)
+		valid  bool
+	}{
+		"valid":             {func(c *Config) {}, true},
+		"without scheme":    {func(c *Config) { c.Endpoint = "s3.amazonaws.com" }, false},
+		"without region":    {func(c *Config) { c.Region = "" }, false},
+		"without bucket":    {func(c *Config) { c.Bucket = "" }, false},
+		"without secret":    {func(c *Config) { c.SecretAccessKey = "" }, false},
+		"negative partsize": {func(c *Config) { c.PartSize = -1 }, false},
+		"small partsize":    {func(c *Config) { c.PartSize = MIN_PART_SIZE - 1 }, false},
+		"minimum partsize":  {func(c *Config) { c.PartSize = MIN_PART_SIZE }, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := valid
+			tc.modify(&config)
+
+			_, err := New(config)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		content string
+		parts   int
+	}{
+		"single request": {"G28\nG1 X1", 0},
+		"multipart":      {"G28\nG1 X10\nG1 X20\nG1 X30\n", 3},
+		"exact parts":    {"0123456789abcdefghij", 2},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, mock := newMockStorage(t, 10)
+
+			w, err := s.Create(ctx, "jobs/part a.gcode")
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			// write byte by byte to exercise the buffering
+			for _, c := range []byte(tc.content) {
+				if _, err := w.Write([]byte{c}); err != nil {
+					t.Errorf("got error %v, want error nil", err)
+					return
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got := len(w.(*writer).etags); got != tc.parts {
+				t.Errorf("got %d parts, want %d parts", got, tc.parts)
+			}
+
+			if string(mock.objects["jobs/part a.gcode"]) != tc.content {
+				t.Errorf("got stored %q, want %q", mock.objects["jobs/part a.gcode"], tc.content)
+			}
+
+			r, err := s.Open(ctx, "jobs/part a.gcode")
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+			content, _ := io.ReadAll(r)
+			r.Close()
+
+			if string(content) != tc.content {
+				t.Errorf("got %q, want %q", content, tc.content)
+			}
+		})
+	}
+}
+
+func TestS3Storage_ListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	s, mock := newMockStorage(t, 0)
+
+	mock.objects["jobs/b.gcode"] = []byte("G28")
+	mock.objects["jobs/a.gcode"] = []byte("G28")
+	mock.objects["other.gcode"] = []byte("G28")
+
+	keys, err := s.List(ctx, "jobs/")
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+	if strings.Join(keys, ",") != "jobs/a.gcode,jobs/b.gcode" {
+		t.Errorf("got keys %v, want keys [jobs/a.gcode jobs/b.gcode]", keys)
+	}
+
+	if err := s.Delete(ctx, "jobs/a.gcode"); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+	}
+
+	if err := s.Delete(ctx, "jobs/a.gcode"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v, want error not found", err)
+	}
+
+	if _, err := s.Open(ctx, "jobs/a.gcode"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v, want error not found", err)
+	}
+
+	if _, err := s.Open(ctx, "../a.gcode"); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+func TestS3Storage_Errors(t *testing.T) {
+	ctx := context.Background()
+	s, mock := newMockStorage(t, 10)
+
+	// requests signed with other credentials are rejected
+	s.credentials.accessKeyID = "OTHER"
+
+	_, err := s.Open(ctx, "a.gcode")
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("got error %v, want error access denied", err)
+	}
+
+	w, err := s.Create(ctx, "a.gcode")
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+	if _, err := w.Write([]byte("0123456789")); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+	if _, ok := mock.objects["a.gcode"]; ok {
+		t.Errorf("got document stored, want document not stored")
+	}
+}
@@ -0,0 +1,64 @@
+// storage package defines the abstraction used to persist and recover gcode documents.
+//
+// A Storage stores documents identified by a key, similar to a path, like "jobs/part_a.gcode".
+// The documents are always handled as streams, so a backend never needs to load a complete document in memory
+// or stage it in temporary files.
+//
+// storage package provides three packages that implement the Storage interface ready to use:
+//
+// - localstorage stores the documents in a directory of the local filesystem.
+//
+// - memorystorage stores the documents in memory, useful for tests and caches.
+//
+// - s3storage stores the documents in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned, wrapped, when a document with the key required doesn't exist.
+var ErrNotFound = errors.New("document not found")
+
+// Storage defines the methods to persist and recover documents using a key.
+type Storage interface {
+	// Open returns a reader to stream the content of the document stored with the key.
+	// The caller must close the reader. Returns an error that wraps ErrNotFound if the document doesn't exist.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Create returns a writer to stream the content of a new document with the key, replacing the previous one if it exists.
+	// The document is only guaranteed to be stored when Close returns a nil error.
+	Create(ctx context.Context, key string) (io.WriteCloser, error)
+
+	// Delete removes the document stored with the key. Returns an error that wraps ErrNotFound if the document doesn't exist.
+	Delete(ctx context.Context, key string) error
+
+	// List returns the keys of all documents that begin with the prefix, sorted in ascending order.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ValidateKey returns an error if the key can't be used to identify a document.
+//
+// A valid key is a non-empty sequence of names separated by slashes.
+// It can't begin or end with a slash and can't contain empty, "." or ".." names.
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("storage key mustn't be empty")
+	}
+
+	if strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("storage key contains invalid chars: %q", key)
+	}
+
+	for _, name := range strings.Split(key, "/") {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("storage key has an invalid name: %q", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import "testing"
+
+func TestValidateKey(t *testing.T) {
+	cases := map[string]struct {
+		key   string
+		valid bool
+	}{
+		"simple":       {"part.gcode", true},
+		"nested":       {"jobs/2022/part.gcode", true},
+		"empty":        {"", false},
+		"absolute":     {"/part.gcode", false},
+		"trailing":     {"jobs/", false},
+		"double slash": {"jobs//part.gcode", false},
+		"dot":          {"jobs/./part.gcode", false},
+		"parent":       {"../part.gcode", false},
+		"backslash":    {"jobs\\part.gcode", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateKey(tc.key)
+			if tc.valid && err != nil {
+				t.Errorf("got error %v, want error nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+// arc package contains the transformers that convert the arcs, G2 and G3, into linear segments and vice versa.
+//
+// Only the arcs in the XY plane are supported, with the center defined by the offsets I and J or by the radius R.
+// The helical arcs, that move Z while they turn, are supported too.
+package arc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// DEFAULT_TOLERANCE is the maximum distance between an arc and the segments that replace it, if it isn't configured.
+	DEFAULT_TOLERANCE = 0.01
+
+	// COORDINATE_DECIMALS is the number of decimals of the coordinates generated.
+	COORDINATE_DECIMALS = 3
+
+	// EXTRUSION_DECIMALS is the number of decimals of the extrusion generated.
+	EXTRUSION_DECIMALS = 5
+)
+
+//#region geometry
+
+// geometry describes an arc in the XY plane.
+type geometry struct {
+	// center of the arc
+	cx, cy float64
+
+	// radius of the arc
+	radius float64
+
+	// angle of the start point and angle swept, positive counterclockwise
+	start, sweep float64
+}
+
+// arcGeometry calculates the geometry of an arc from its start and end points and the parameters of the block.
+func arcGeometry(b block.Blocker, x0 float64, y0 float64, x1 float64, y1 float64) (geometry, error) {
+	clockwise := b.Command().String() == "G2"
+
+	var g geometry
+
+	i, hasI := transform.Parameter(b, 'I')
+	j, hasJ := transform.Parameter(b, 'J')
+	r, hasR := transform.Parameter(b, 'R')
+
+	switch {
+	case hasI || hasJ:
+		g.cx, g.cy = x0+i, y0+j
+		g.radius = math.Hypot(i, j)
+	case hasR:
+		// the center is on the perpendicular bisector of the chord, a negative radius selects the major arc
+		dx, dy := x1-x0, y1-y0
+		chord := math.Hypot(dx, dy)
+		if chord == 0 || math.Abs(r) < chord/2 {
+			return g, fmt.Errorf("the radius %v can't join the start and end points", r)
+		}
+
+		h := math.Sqrt(r*r - chord*chord/4)
+		if clockwise != (r < 0) {
+			h = -h
+		}
+
+		g.cx = x0 + dx/2 - h*dy/chord
+		g.cy = y0 + dy/2 + h*dx/chord
+		g.radius = math.Abs(r)
+	default:
+		return g, fmt.Errorf("the arc hasn't center offsets nor radius")
+	}
+
+	if g.radius == 0 {
+		return g, fmt.Errorf("the radius of the arc is zero")
+	}
+
+	g.start = math.Atan2(y0-g.cy, x0-g.cx)
+	end := math.Atan2(y1-g.cy, x1-g.cx)
+
+	g.sweep = end - g.start
+	if clockwise {
+		if g.sweep >= -1e-9 {
+			g.sweep -= 2 * math.Pi
+		}
+	} else if g.sweep <= 1e-9 {
+		g.sweep += 2 * math.Pi
+	}
+
+	return g, nil
+}
+
+//#endregion
+//#region flatten configuration
+
+// FlattenConfigurer defines the options of the flattening of arcs.
+type FlattenConfigurer interface {
+	// Set the maximum distance between an arc and its segments
+	SetTolerance(tolerance float64) error
+}
+
+// FlattenConfigurationCallbackable is the signature of the callbacks used to configure the flattening.
+type FlattenConfigurationCallbackable func(config FlattenConfigurer) error
+
+// flattenConfigurator implements FlattenConfigurer.
+type flattenConfigurator struct {
+	tolerance float64
+}
+
+// SetTolerance defines the maximum distance between an arc and the segments that replace it. It must be positive.
+// A lower tolerance generates more segments.
+// If this method isn't called, by default it is DEFAULT_TOLERANCE.
+func (fc *flattenConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it must be positive and finite: %v", tolerance)
+	}
+
+	fc.tolerance = tolerance
+
+	return nil
+}
+
+//#endregion
+//#region flatten struct
+
+// Flatten is a transformer that replaces the arcs by linear moves whose vertexes are on the arc, so each segment is a chord.
+//
+// The extrusion of the arc is distributed in proportion to the length of each segment, and the height of the helical arcs too.
+// The feedrate and the comment of the arc are kept in the first segment. The line number and the checksum aren't kept.
+type Flatten struct {
+	// maximum distance between an arc and its segments
+	tolerance float64
+}
+
+// Apply replaces an arc by linear moves, the rest of blocks are returned without changes.
+func (f *Flatten) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	command := b.Command().String()
+	if command != "G2" && command != "G3" {
+		return []block.Blocker{b}, nil
+	}
+
+	from, to := state.Position, state.After.Position
+
+	g, err := arcGeometry(b, from.X, from.Y, to.X, to.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten arc %s: %w", b, err)
+	}
+
+	// the chord of an angle a is at r*(1-cos(a/2)) of the arc
+	step := math.Pi
+	if f.tolerance < g.radius {
+		step = 2 * math.Acos(1-f.tolerance/g.radius)
+	}
+	segments := int(math.Ceil(math.Abs(g.sweep) / step))
+	if segments < 1 {
+		segments = 1
+	}
+
+	_, hasE := transform.Parameter(b, 'E')
+	feedrate, hasF := transform.Parameter(b, 'F')
+
+	blocks := make([]block.Blocker, 0, segments)
+	previous := from
+	for s := 1; s <= segments; s++ {
+		t := float64(s) / float64(segments)
+
+		point := to
+		if s < segments {
+			angle := g.start + g.sweep*t
+			point.X = g.cx + g.radius*math.Cos(angle)
+			point.Y = g.cy + g.radius*math.Sin(angle)
+			point.Z = from.Z + (to.Z-from.Z)*t
+			point.E = from.E + (to.E-from.E)*t
+		}
+
+		var sb strings.Builder
+		sb.WriteString("G1")
+
+		x, y, z, e := point.X, point.Y, point.Z, point.E
+		if state.Relative {
+			x, y, z = point.X-previous.X, point.Y-previous.Y, point.Z-previous.Z
+		}
+		if state.RelativeExtrusion {
+			e = point.E - previous.E
+		}
+
+		sb.WriteString(" X" + numeric.FormatDecimals(x, COORDINATE_DECIMALS))
+		sb.WriteString(" Y" + numeric.FormatDecimals(y, COORDINATE_DECIMALS))
+		if to.Z != from.Z {
+			sb.WriteString(" Z" + numeric.FormatDecimals(z, COORDINATE_DECIMALS))
+		}
+		if hasE {
+			sb.WriteString(" E" + numeric.FormatDecimals(e, EXTRUSION_DECIMALS))
+		}
+		if hasF && s == 1 {
+			sb.WriteString(" F" + numeric.FormatDecimals(feedrate, COORDINATE_DECIMALS))
+		}
+
+		segment, err := gcodeblock.Parse(sb.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create segment %d of arc %s: %w", s, b, err)
+		}
+
+		if s == 1 && b.Comment() != "" {
+			segment.SetComment(b.Comment())
+		}
+
+		blocks = append(blocks, segment)
+		previous = point
+	}
+
+	return blocks, nil
+}
+
+//#endregion
+//#region constructors
+
+// NewFlatten returns a new transformer that replaces the arcs by linear moves.
+func NewFlatten(options ...FlattenConfigurationCallbackable) (*Flatten, error) {
+
+	configurator := &flattenConfigurator{
+		tolerance: DEFAULT_TOLERANCE,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Flatten{tolerance: configurator.tolerance}, nil
+}
+
+//#endregion
+//#region private functions
+
+//#endregion
@@ -0,0 +1,196 @@
+package arc
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+// run transforms a source with a single transformer.
+func run(t *testing.T, source string, transformer transform.Transformer) (*document.Document, error) {
+	t.Helper()
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+
+	p, err := transform.NewPipeline([]transform.Transformer{transformer})
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+
+	return p.Run(d)
+}
+
+func TestFlatten(t *testing.T) {
+
+	cases := map[string]struct {
+		source    string
+		tolerance float64
+		want      string
+		valid     bool
+	}{
+		"half circle counterclockwise": {
+			source:    "G1 X10 Y0\nG3 X-10 Y0 I-10 J0 E4 F1200 ;arc\n",
+			tolerance: 0.8,
+			want:      "G1 X10 Y0\nG1 X7.071 Y7.071 E1 F1200 ;arc\nG1 X0 Y10 E2\nG1 X-7.071 Y7.071 E3\nG1 X-10 Y0 E4\n",
+			valid:     true,
+		},
+		"quarter clockwise with radius": {
+			source:    "G1 X0 Y0\nG2 X10 Y10 R10\n",
+			tolerance: 0.8,
+			want:      "G1 X0 Y0\nG1 X2.929 Y7.071\nG1 X10 Y10\n",
+			valid:     true,
+		},
+		"major arc with negative radius": {
+			source:    "G1 X0 Y0\nG2 X10 Y10 R-10\n",
+			tolerance: 6,
+			want:      "G1 X0 Y0\nG1 X-10 Y10\nG1 X0 Y20\nG1 X10 Y10\n",
+			valid:     true,
+		},
+		"full circle relative helical": {
+			source:    "G1 X10 Y0\nG91\nM83\nG2 X0 Y0 Z1 I-10 J0 E4\n",
+			tolerance: 3,
+			want:      "G1 X10 Y0\nG91\nM83\nG1 X-10 Y-10 Z0.25 E1\nG1 X-10 Y10 Z0.25 E1\nG1 X10 Y10 Z0.25 E1\nG1 X10 Y-10 Z0.25 E1\n",
+			valid:     true,
+		},
+		"other blocks": {
+			source:    "G28\nG1 X1\n",
+			tolerance: 1,
+			want:      "G28\nG1 X1\n",
+			valid:     true,
+		},
+		"without center": {
+			source:    "G2 X10 Y10\n",
+			tolerance: 1,
+		},
+		"radius too short": {
+			source:    "G2 X10 Y10 R1\n",
+			tolerance: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			flatten, err := NewFlatten(func(config FlattenConfigurer) error {
+				return config.SetTolerance(tc.tolerance)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := run(t, tc.source, flatten)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid tolerance", func(t *testing.T) {
+		for _, tolerance := range []float64{0, -1, math.NaN()} {
+			_, err := NewFlatten(func(config FlattenConfigurer) error {
+				return config.SetTolerance(tolerance)
+			})
+			if err == nil {
+				t.Errorf("got error nil with tolerance %v, want error not nil", tolerance)
+			}
+		}
+	})
+}
+
+func TestFit(t *testing.T) {
+
+	// quarter of a circle of radius 10 centered at the origin, sampled each 15 degrees
+	quarter := "G1 X9.659 Y2.588 E1 F1200\nG1 X8.66 Y5 E2\nG1 X7.071 Y7.071 E3\nG1 X5 Y8.66 E4\nG1 X2.588 Y9.659 E5\nG1 X0 Y10 E6\n"
+
+	cases := map[string]struct {
+		source string
+		want   string
+	}{
+		"quarter counterclockwise": {
+			source: "G0 X10 Y0\n" + quarter + "M107\n",
+			want:   "G0 X10 Y0\nG3 X0 Y10 I-10 J0 E6 F1200\nM107\n",
+		},
+		"quarter clockwise at the end of the document": {
+			source: "G0 X0 Y10\nG1 X2.588 Y9.659\nG1 X5 Y8.66\nG1 X7.071 Y7.071\nG1 X8.66 Y5\nG1 X9.659 Y2.588\nG1 X10 Y0\n",
+			want:   "G0 X0 Y10\nG2 X10 Y0 I0 J-10\n",
+		},
+		"previous line isn't part of the arc": {
+			source: "G0 X0 Y0\nG1 X10 Y0 F1200\n" + quarter,
+			want:   "G0 X0 Y0\nG1 X10 Y0 F1200\nG3 X0 Y10 I-10 J0 E6 F1200\n",
+		},
+		"relative": {
+			source: "G0 X10 Y0\nG91\nM83\nG1 X-0.341 Y2.588 E1\nG1 X-0.999 Y2.412 E1\nG1 X-1.589 Y2.071 E1\nG1 X-2.071 Y1.589 E1\n",
+			want:   "G0 X10 Y0\nG91\nM83\nG3 X-5 Y8.66 I-10.001 J-0.001 E4\n",
+		},
+		"comment breaks the sequence": {
+			source: "G0 X10 Y0\nG1 X9.659 Y2.588\nG1 X8.66 Y5\n;comment\nG1 X7.071 Y7.071\nG1 X5 Y8.66\n",
+			want:   "G0 X10 Y0\nG1 X9.659 Y2.588\nG1 X8.66 Y5\n;comment\nG1 X7.071 Y7.071\nG1 X5 Y8.66\n",
+		},
+		"feedrate breaks the sequence": {
+			source: "G0 X10 Y0\nG1 X9.659 Y2.588\nG1 X8.66 Y5\nG1 X7.071 Y7.071 F600\nG1 X5 Y8.66\n",
+			want:   "G0 X10 Y0\nG1 X9.659 Y2.588\nG1 X8.66 Y5\nG1 X7.071 Y7.071 F600\nG1 X5 Y8.66\n",
+		},
+		"straight lines": {
+			source: "G1 X10 Y0 E1\nG1 X20 Y0 E2\nG1 X30 Y0 E3\nG1 X40 Y0 E4\n",
+			want:   "G1 X10 Y0 E1\nG1 X20 Y0 E2\nG1 X30 Y0 E3\nG1 X40 Y0 E4\n",
+		},
+		"zigzag": {
+			source: "G1 X10 Y1\nG1 X20 Y0\nG1 X30 Y1\nG1 X40 Y0\n",
+			want:   "G1 X10 Y1\nG1 X20 Y0\nG1 X30 Y1\nG1 X40 Y0\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fit, err := NewFit(func(config FitConfigurer) error {
+				return config.SetTolerance(0.1)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := run(t, tc.source, fit)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		options := map[string]FitConfigurationCallbackable{
+			"tolerance":    func(config FitConfigurer) error { return config.SetTolerance(0) },
+			"min segments": func(config FitConfigurer) error { return config.SetMinSegments(1) },
+			"max radius":   func(config FitConfigurer) error { return config.SetMaxRadius(-1) },
+		}
+
+		for name, option := range options {
+			if _, err := NewFit(option); err == nil {
+				t.Errorf("got error nil with invalid %s, want error not nil", name)
+			}
+		}
+	})
+}
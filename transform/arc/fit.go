@@ -0,0 +1,380 @@
+package arc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// DEFAULT_MIN_SEGMENTS is the minimum number of moves replaced by an arc, if it isn't configured.
+	DEFAULT_MIN_SEGMENTS = 3
+
+	// DEFAULT_MAX_RADIUS is the maximum radius of the arcs generated, if it isn't configured.
+	// The sequences that fit larger circles are almost straight, so they are kept as linear moves.
+	DEFAULT_MAX_RADIUS = 1000
+
+	// extrusionRateTolerance is the maximum relative difference between the extrusion per millimeter of the moves of an arc.
+	extrusionRateTolerance = 0.05
+)
+
+//#region fit configuration
+
+// FitConfigurer defines the options of the fitting of arcs.
+type FitConfigurer interface {
+	// Set the maximum distance between the moves and the arc that replaces them
+	SetTolerance(tolerance float64) error
+
+	// Set the minimum number of moves replaced by an arc
+	SetMinSegments(segments int) error
+
+	// Set the maximum radius of the arcs
+	SetMaxRadius(radius float64) error
+}
+
+// FitConfigurationCallbackable is the signature of the callbacks used to configure the fitting.
+type FitConfigurationCallbackable func(config FitConfigurer) error
+
+// fitConfigurator implements FitConfigurer.
+type fitConfigurator struct {
+	tolerance   float64
+	minSegments int
+	maxRadius   float64
+}
+
+// SetTolerance defines the maximum distance between the moves and the arc that replaces them. It must be positive.
+// If this method isn't called, by default it is DEFAULT_TOLERANCE.
+func (fc *fitConfigurator) SetTolerance(tolerance float64) error {
+	if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+		return fmt.Errorf("failed to set tolerance, it must be positive and finite: %v", tolerance)
+	}
+
+	fc.tolerance = tolerance
+
+	return nil
+}
+
+// SetMinSegments defines the minimum number of consecutive moves replaced by an arc. It must be at least 2.
+// If this method isn't called, by default it is DEFAULT_MIN_SEGMENTS.
+func (fc *fitConfigurator) SetMinSegments(segments int) error {
+	if segments < 2 {
+		return fmt.Errorf("failed to set minimum segments, it must be at least 2: %d", segments)
+	}
+
+	fc.minSegments = segments
+
+	return nil
+}
+
+// SetMaxRadius defines the maximum radius of the arcs generated. It must be positive.
+// If this method isn't called, by default it is DEFAULT_MAX_RADIUS.
+func (fc *fitConfigurator) SetMaxRadius(radius float64) error {
+	if !(radius > 0) || math.IsInf(radius, 0) {
+		return fmt.Errorf("failed to set maximum radius, it must be positive and finite: %v", radius)
+	}
+
+	fc.maxRadius = radius
+
+	return nil
+}
+
+//#endregion
+//#region fit struct
+
+// Fit is a transformer that replaces sequences of linear moves that approximate an arc by a single G2 or G3 move.
+//
+// The moves of a sequence must be in the XY plane at the same height, with the same feedrate and the same extrusion per millimeter,
+// and each vertex and each segment must be within the tolerance of the arc.
+// The sequences with fewer moves than the minimum are kept without changes.
+//
+// The moves are retained until the sequence ends, so the transformer implements transform.Flusher.
+type Fit struct {
+	// options of the fitting
+	tolerance   float64
+	minSegments int
+	maxRadius   float64
+
+	// moves retained, the first point is the start of the first move
+	moves  []block.Blocker
+	points []point
+
+	// extrusion of each move retained
+	extrusions []float64
+
+	// state of the last move retained
+	last transform.State
+}
+
+// point is a vertex of a sequence of moves.
+type point struct {
+	x, y float64
+}
+
+// Apply retains the linear moves that can be part of an arc, and releases the sequence retained when a block breaks it.
+func (f *Fit) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	if !f.candidate(b, state) {
+		released, err := f.Flush()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(released, b), nil
+	}
+
+	start := point{state.Position.X, state.Position.Y}
+	end := point{state.After.Position.X, state.After.Position.Y}
+	extrusion := state.After.Position.E - state.Position.E
+
+	if len(f.moves) > 0 && f.accepts(state, end, extrusion) {
+		f.retain(b, state, end, extrusion)
+		return nil, nil
+	}
+
+	var released []block.Blocker
+	if len(f.moves) >= f.minSegments {
+		arc, err := f.Flush()
+		if err != nil {
+			return nil, err
+		}
+		released = arc
+	}
+
+	// the first moves retained are released until the rest can start an arc with the move received
+	for len(f.moves) > 0 && !f.accepts(state, end, extrusion) {
+		released = append(released, f.shift())
+	}
+
+	if len(f.moves) == 0 {
+		f.points = append(f.points[:0], start)
+	}
+	f.retain(b, state, end, extrusion)
+
+	return released, nil
+}
+
+// Flush releases the moves retained, replaced by an arc if they are enough.
+func (f *Fit) Flush() ([]block.Blocker, error) {
+	if len(f.moves) == 0 {
+		return nil, nil
+	}
+
+	defer f.reset()
+
+	if len(f.moves) < f.minSegments {
+		return append([]block.Blocker(nil), f.moves...), nil
+	}
+
+	arc, err := f.arc()
+	if err != nil {
+		return nil, err
+	}
+
+	return []block.Blocker{arc}, nil
+}
+
+// candidate returns true if the block is a linear move in the XY plane that can be part of an arc.
+func (f *Fit) candidate(b block.Blocker, state transform.State) bool {
+	if b.Command().String() != "G1" || state.Position.Z != state.After.Position.Z {
+		return false
+	}
+
+	for _, p := range b.Parameters() {
+		switch p.Word() {
+		case 'X', 'Y', 'Z', 'E', 'F':
+		default:
+			return false
+		}
+	}
+
+	dx := state.After.Position.X - state.Position.X
+	dy := state.After.Position.Y - state.Position.Y
+
+	return dx != 0 || dy != 0
+}
+
+// accepts returns true if the sequence retained plus the move received still fits an arc.
+func (f *Fit) accepts(state transform.State, end point, extrusion float64) bool {
+
+	// the moves of an arc share the modes and the feedrate
+	if state.Relative != f.last.Relative || state.RelativeExtrusion != f.last.RelativeExtrusion || state.After.Feedrate != f.last.After.Feedrate {
+		return false
+	}
+
+	// the extrusion per millimeter must be uniform
+	length := distance(f.points[len(f.points)-1], end)
+	firstLength := distance(f.points[0], f.points[1])
+	rate, firstRate := extrusion/length, f.extrusions[0]/firstLength
+	if (extrusion > 0) != (f.extrusions[0] > 0) || math.Abs(rate-firstRate) > extrusionRateTolerance*math.Abs(firstRate) {
+		return false
+	}
+
+	points := append(append([]point(nil), f.points...), end)
+	_, _, _, ok := f.fitCircle(points)
+
+	return ok
+}
+
+// retain stores a move in the sequence.
+func (f *Fit) retain(b block.Blocker, state transform.State, end point, extrusion float64) {
+	f.moves = append(f.moves, b)
+	f.points = append(f.points, end)
+	f.extrusions = append(f.extrusions, extrusion)
+	f.last = state
+}
+
+// shift removes the first move retained and returns it.
+func (f *Fit) shift() block.Blocker {
+	b := f.moves[0]
+
+	f.moves = f.moves[1:]
+	f.points = f.points[1:]
+	f.extrusions = f.extrusions[1:]
+
+	return b
+}
+
+// reset forgets the sequence retained.
+func (f *Fit) reset() {
+	f.moves = f.moves[:0]
+	f.points = f.points[:0]
+	f.extrusions = f.extrusions[:0]
+}
+
+// fitCircle returns the center and the radius of the circle that passes through the first, middle and last points,
+// and true if all points and segments are within the tolerance of it, turning always in the same direction.
+func (f *Fit) fitCircle(points []point) (float64, float64, float64, bool) {
+	a, b, c := points[0], points[len(points)/2], points[len(points)-1]
+
+	d := 2 * (a.x*(b.y-c.y) + b.x*(c.y-a.y) + c.x*(a.y-b.y))
+	if d == 0 {
+		return 0, 0, 0, false
+	}
+
+	sa, sb, sc := a.x*a.x+a.y*a.y, b.x*b.x+b.y*b.y, c.x*c.x+c.y*c.y
+	cx := (sa*(b.y-c.y) + sb*(c.y-a.y) + sc*(a.y-b.y)) / d
+	cy := (sa*(c.x-b.x) + sb*(a.x-c.x) + sc*(b.x-a.x)) / d
+	radius := math.Hypot(a.x-cx, a.y-cy)
+
+	if radius > f.maxRadius {
+		return 0, 0, 0, false
+	}
+
+	sweep := 0.0
+	for i, p := range points {
+		if math.Abs(math.Hypot(p.x-cx, p.y-cy)-radius) > f.tolerance {
+			return 0, 0, 0, false
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		// the middle of each segment is inside the circle by its sagitta
+		q := points[i-1]
+		half := distance(p, q) / 2
+		if half > radius || radius-math.Sqrt(radius*radius-half*half) > f.tolerance {
+			return 0, 0, 0, false
+		}
+
+		angle := math.Atan2((q.x-cx)*(p.y-cy)-(q.y-cy)*(p.x-cx), (q.x-cx)*(p.x-cx)+(q.y-cy)*(p.y-cy))
+		if i > 1 && (angle > 0) != (sweep > 0) {
+			return 0, 0, 0, false
+		}
+		sweep += angle
+	}
+
+	// a complete circle is ambiguous
+	if math.Abs(sweep) >= 2*math.Pi-1e-6 {
+		return 0, 0, 0, false
+	}
+
+	return cx, cy, radius, true
+}
+
+// arc returns the block that replaces the sequence retained.
+func (f *Fit) arc() (block.Blocker, error) {
+	cx, cy, _, _ := f.fitCircle(f.points)
+
+	start, end := f.points[0], f.points[len(f.points)-1]
+	second := f.points[1]
+
+	// the direction is the sign of the cross product of the first segment around the center
+	command := "G3"
+	if (start.x-cx)*(second.y-cy)-(start.y-cy)*(second.x-cx) < 0 {
+		command = "G2"
+	}
+
+	x, y := end.x, end.y
+	if f.last.Relative {
+		x, y = end.x-start.x, end.y-start.y
+	}
+
+	var sb strings.Builder
+	sb.WriteString(command)
+	sb.WriteString(" X" + numeric.FormatDecimals(x, COORDINATE_DECIMALS))
+	sb.WriteString(" Y" + numeric.FormatDecimals(y, COORDINATE_DECIMALS))
+	sb.WriteString(" I" + numeric.FormatDecimals(cx-start.x, COORDINATE_DECIMALS))
+	sb.WriteString(" J" + numeric.FormatDecimals(cy-start.y, COORDINATE_DECIMALS))
+
+	if _, ok := transform.Parameter(f.moves[len(f.moves)-1], 'E'); ok {
+		e := f.last.After.Position.E
+		if f.last.RelativeExtrusion {
+			e = 0
+			for _, extrusion := range f.extrusions {
+				e += extrusion
+			}
+		}
+		sb.WriteString(" E" + numeric.FormatDecimals(e, EXTRUSION_DECIMALS))
+	}
+
+	if feedrate, ok := transform.Parameter(f.moves[0], 'F'); ok {
+		sb.WriteString(" F" + numeric.FormatDecimals(feedrate, COORDINATE_DECIMALS))
+	}
+
+	arc, err := gcodeblock.Parse(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create arc %s: %w", sb.String(), err)
+	}
+
+	return arc, nil
+}
+
+//#endregion
+//#region constructors
+
+// NewFit returns a new transformer that replaces sequences of linear moves by arcs.
+func NewFit(options ...FitConfigurationCallbackable) (*Fit, error) {
+
+	configurator := &fitConfigurator{
+		tolerance:   DEFAULT_TOLERANCE,
+		minSegments: DEFAULT_MIN_SEGMENTS,
+		maxRadius:   DEFAULT_MAX_RADIUS,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Fit{
+		tolerance:   configurator.tolerance,
+		minSegments: configurator.minSegments,
+		maxRadius:   configurator.maxRadius,
+	}, nil
+}
+
+//#endregion
+//#region private functions
+
+// distance returns the distance between two points.
+func distance(a point, b point) float64 {
+	return math.Hypot(b.x-a.x, b.y-a.y)
+}
+
+//#endregion
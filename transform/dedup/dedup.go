@@ -0,0 +1,345 @@
+// dedup package contains a transformer that removes the commands that don't change the state of the machine.
+//
+// The slicers and the post-processors often repeat modal commands, like G90 before each section, the same feedrate on every move,
+// or the same temperature again. They don't do anything, but they grow the file and the traffic of a serial link.
+// The transformer only removes a command after the state was commanded in the same file, because the state before it isn't known.
+// The moves that don't move any axis, like a move to the current position, are removed too.
+package dedup
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region rules
+
+// Rule identifies a kind of redundant command removed. The rules can be combined with the | operator.
+type Rule int
+
+const (
+	// RuleModes removes the unit, positioning, extrusion mode and plane commands that select the current mode, like a repeated G90.
+	RuleModes Rule = 1 << iota
+
+	// RuleFeedrates removes the F of the moves that command the current feedrate, and the moves that only command it.
+	RuleFeedrates
+
+	// RuleTemperatures removes the M104 and M140 that set the current target of the heater. M109 and M190 are kept, because they wait.
+	RuleTemperatures
+
+	// RuleFans removes the M106 and M107 that set the current speed of the fan.
+	RuleFans
+
+	// RuleMoves removes the linear moves that don't move any axis nor change the feedrate, like a G1 to the current position.
+	// An absolute coordinate is only considered the current position after the axis was positioned by a move, G92 or G28.
+	RuleMoves
+
+	// RuleAll combines all rules.
+	RuleAll = RuleModes | RuleFeedrates | RuleTemperatures | RuleFans | RuleMoves
+)
+
+//#endregion
+//#region dedup configuration
+
+// DedupConfigurer defines the options of the deduplication.
+type DedupConfigurer interface {
+	// Set the kinds of redundant commands removed
+	SetRules(rules Rule) error
+}
+
+// DedupConfigurationCallbackable is the signature of the callbacks used to configure the deduplication.
+type DedupConfigurationCallbackable func(config DedupConfigurer) error
+
+// dedupConfigurator implements DedupConfigurer.
+type dedupConfigurator struct {
+	rules Rule
+}
+
+// SetRules defines the kinds of redundant commands removed, like RuleModes|RuleFeedrates. It requires at least one rule.
+// If this method isn't called, by default it is RuleAll.
+func (dc *dedupConfigurator) SetRules(rules Rule) error {
+	if rules == 0 || rules&^RuleAll != 0 {
+		return fmt.Errorf("failed to set rules, unknown value %d", rules)
+	}
+
+	dc.rules = rules
+
+	return nil
+}
+
+//#endregion
+//#region dedup struct
+
+// Dedup is a transformer that removes the redundant commands, tracking the state commanded by the blocks received.
+type Dedup struct {
+	rules Rule
+
+	// true if the units, the positioning, the extrusion mode and the plane were commanded
+	units, positioning, extrusion bool
+
+	// plane selected, it is empty if it wasn't commanded
+	plane string
+
+	// true if the feedrate was commanded since the last change of units
+	feedrate bool
+
+	// last value commanded by each setting, like the target of the hotend of a tool or the speed of a fan
+	settings map[string]float64
+
+	// axes whose position was commanded, by an absolute move, G92 or G28
+	positioned map[byte]bool
+}
+
+// Apply removes the block if it doesn't change the state, or returns a copy without the redundant feedrate.
+func (d *Dedup) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	command := b.Command().String()
+
+	switch command {
+	case "G20", "G21":
+		// the feedrate is expressed in the units, so it isn't known after they change
+		if !d.units || state.Units != state.After.Units {
+			d.feedrate = false
+		}
+		return d.mode(b, &d.units, state.Units == state.After.Units)
+
+	case "G90", "G91":
+		redundant := d.extrusion && state.Relative == state.After.Relative && state.RelativeExtrusion == state.After.RelativeExtrusion
+		d.extrusion = true
+		return d.mode(b, &d.positioning, redundant)
+
+	case "M82", "M83":
+		return d.mode(b, &d.extrusion, state.RelativeExtrusion == state.After.RelativeExtrusion)
+
+	case "G17", "G18", "G19":
+		known := d.plane != ""
+		redundant := d.plane == command
+		d.plane = command
+		return d.mode(b, &known, redundant)
+
+	case "G0", "G1", "G2", "G3":
+		redundant := d.redundantMove(b, state)
+		d.position(b, state.Relative, state.RelativeExtrusion)
+
+		if d.rules&RuleMoves != 0 && redundant {
+			// the feedrate is known, because a redundant move only commands the current one
+			return nil, nil
+		}
+		return d.move(b, state)
+
+	case "G92":
+		if len(b.Parameters()) == 0 {
+			d.positioned['X'], d.positioned['Y'], d.positioned['Z'], d.positioned['E'] = true, true, true, true
+		}
+		d.position(b, false, false)
+		return []block.Blocker{b}, nil
+
+	case "G28":
+		if countWords(b, 'X', 'Y', 'Z') == 0 {
+			d.positioned['X'], d.positioned['Y'], d.positioned['Z'] = true, true, true
+		}
+		d.position(b, false, true)
+		return []block.Blocker{b}, nil
+
+	case "M104", "M140":
+		if d.rules&RuleTemperatures == 0 || len(b.Parameters()) != countWords(b, 'S', 'T') {
+			d.forget(b, state)
+			return []block.Blocker{b}, nil
+		}
+
+		target, ok := transform.Parameter(b, 'S')
+		if !ok {
+			d.forget(b, state)
+			return []block.Blocker{b}, nil
+		}
+		return d.setting(b, heaterKey(b, state), target)
+
+	case "M109", "M190":
+		// they wait, but the target is stored, so a following M104 with the same value is redundant
+		d.forget(b, state)
+		if value, ok := transform.Parameter(b, 'S'); ok && countWords(b, 'S', 'T') == len(b.Parameters()) {
+			d.settings[heaterKey(b, state)] = value
+		}
+		return []block.Blocker{b}, nil
+
+	case "M106", "M107":
+		if d.rules&RuleFans == 0 || len(b.Parameters()) != countWords(b, 'S', 'P') {
+			delete(d.settings, fanKey(b))
+			return []block.Blocker{b}, nil
+		}
+
+		speed := 0.0
+		if command == "M106" {
+			speed = 255
+			if s, ok := transform.Parameter(b, 'S'); ok {
+				speed = s
+			}
+		}
+		return d.setting(b, fanKey(b), speed)
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// mode removes a modal command if it is redundant and the mode was known, then the mode is known.
+func (d *Dedup) mode(b block.Blocker, known *bool, redundant bool) ([]block.Blocker, error) {
+	wasKnown := *known
+	*known = true
+
+	if d.rules&RuleModes != 0 && wasKnown && redundant && len(b.Parameters()) == 0 {
+		return nil, nil
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// move removes the feedrate of a move if it is the current one, and the move if it only commanded the feedrate.
+func (d *Dedup) move(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	f, ok := transform.Parameter(b, 'F')
+	if !ok {
+		return []block.Blocker{b}, nil
+	}
+
+	known := d.feedrate
+	d.feedrate = true
+
+	if d.rules&RuleFeedrates == 0 || !known || f != state.Feedrate {
+		return []block.Blocker{b}, nil
+	}
+
+	if len(b.Parameters()) == countWords(b, 'F') {
+		return nil, nil
+	}
+
+	removed, _, err := transform.RemoveParameter(b, 'F')
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove redundant feedrate: %w", err)
+	}
+
+	return []block.Blocker{removed}, nil
+}
+
+// redundantMove returns true if the block is a linear move that doesn't move any axis nor change the feedrate.
+// Its absolute coordinates must be positions commanded before, and its feedrate, if any, the one commanded before.
+func (d *Dedup) redundantMove(b block.Blocker, state transform.State) bool {
+	if command := b.Command().String(); command != "G0" && command != "G1" {
+		return false
+	}
+
+	if state.Position != state.After.Position || len(b.Parameters()) != countWords(b, 'X', 'Y', 'Z', 'E', 'F') {
+		return false
+	}
+
+	for _, word := range []byte{'X', 'Y', 'Z', 'E'} {
+		relative := state.Relative
+		if word == 'E' {
+			relative = state.RelativeExtrusion
+		}
+
+		if countWords(b, word) > 0 && !relative && !d.positioned[word] {
+			return false
+		}
+	}
+
+	if f, ok := transform.Parameter(b, 'F'); ok && (!d.feedrate || f != state.Feedrate) {
+		return false
+	}
+
+	return true
+}
+
+// position marks the axes of the block as positioned, except the relative ones.
+func (d *Dedup) position(b block.Blocker, relative bool, relativeExtrusion bool) {
+	for _, word := range []byte{'X', 'Y', 'Z', 'E'} {
+		r := relative
+		if word == 'E' {
+			r = relativeExtrusion
+		}
+
+		if !r && countWords(b, word) > 0 {
+			d.positioned[word] = true
+		}
+	}
+}
+
+// setting removes the block if the value is the last value of the setting, then the value is stored.
+func (d *Dedup) setting(b block.Blocker, key string, value float64) ([]block.Blocker, error) {
+	last, known := d.settings[key]
+	d.settings[key] = value
+
+	if known && last == value {
+		return nil, nil
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// forget removes the target stored of the heater of the block, because it isn't known after it.
+func (d *Dedup) forget(b block.Blocker, state transform.State) {
+	delete(d.settings, heaterKey(b, state))
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that removes the redundant commands of the rules configured.
+func New(options ...DedupConfigurationCallbackable) (*Dedup, error) {
+
+	configurator := &dedupConfigurator{
+		rules: RuleAll,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Dedup{rules: configurator.rules, settings: map[string]float64{}, positioned: map[byte]bool{}}, nil
+}
+
+//#endregion
+//#region private functions
+
+// heaterKey returns the key of the heater of a temperature command, the bed or the hotend of a tool.
+func heaterKey(b block.Blocker, state transform.State) string {
+	switch b.Command().String() {
+	case "M140", "M190":
+		return "bed"
+	}
+
+	tool := state.Tool
+	if t, ok := transform.Parameter(b, 'T'); ok {
+		tool = int(t)
+	}
+
+	return "hotend" + strconv.Itoa(tool)
+}
+
+// fanKey returns the key of the fan of a fan command, selected by P.
+func fanKey(b block.Blocker) string {
+	fan := 0
+	if p, ok := transform.Parameter(b, 'P'); ok {
+		fan = int(p)
+	}
+
+	return "fan" + strconv.Itoa(fan)
+}
+
+// countWords returns the number of parameters of the block with some of the words received.
+func countWords(b block.Blocker, words ...byte) int {
+	count := 0
+	for _, p := range b.Parameters() {
+		for _, word := range words {
+			if p.Word() == word {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+//#endregion
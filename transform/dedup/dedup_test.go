@@ -0,0 +1,95 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestDedup(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		rules  Rule
+		want   string
+	}{
+		"modes": {
+			source: "G21\nG90\nM83\nG21\nG90\nM83\nG91\nG90\nM83\nG17\nG17\nG18\n",
+			want:   "G21\nG90\nM83\nG90\nM83\nG91\nG90\nM83\nG17\nG18\n",
+		},
+		"feedrates": {
+			source: "G1 X10 F1200\nG1 X20 F1200\nG1 F1200\nG0 X0 F6000\nG1 X5 F6000 E1\nG20\nG1 X1 F6000\n",
+			want:   "G1 X10 F1200\nG1 X20\nG0 X0 F6000\nG1 X5 E1\nG20\nG1 X1 F6000\n",
+		},
+		"temperatures": {
+			source: "M104 S200\nM104 S200\nM109 S210\nM104 S210\nM104 T1 S210\nM104 T1 S210\nM140 S60\nM190 S60\nM140 S60\n",
+			want:   "M104 S200\nM109 S210\nM104 T1 S210\nM140 S60\nM190 S60\n",
+		},
+		"fans": {
+			source: "M106\nM106 S255\nM106 P1 S128\nM107\nM106 S0\nM107 P1\n",
+			want:   "M106\nM106 P1 S128\nM107\nM107 P1\n",
+		},
+		"moves": {
+			source: "G1 X0 Y0\nG1 X10 F1200\nG1 X10\nG1 X10 F1200\nG1 X10 F600\nG1 Z0\nG28 Z0\nG1 Z0\nG91\nG1 X0 E0\nG1\nG90\nM83\nG1 E0\nM82\nG1 E0\nG92 E0\nG1 E0\n",
+			rules:  RuleMoves,
+			want:   "G1 X0 Y0\nG1 X10 F1200\nG1 X10 F600\nG1 Z0\nG28 Z0\nG91\nG90\nM83\nM82\nG1 E0\nG92 E0\n",
+		},
+		"only modes": {
+			source: "G90\nG90\nG1 X10 F1200\nG1 X20 F1200\nM104 S200\nM104 S200\n",
+			rules:  RuleModes,
+			want:   "G90\nG1 X10 F1200\nG1 X20 F1200\nM104 S200\nM104 S200\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var options []DedupConfigurationCallbackable
+			if tc.rules != 0 {
+				options = append(options, func(config DedupConfigurer) error {
+					return config.SetRules(tc.rules)
+				})
+			}
+
+			dedup, err := New(options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{dedup})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, rules := range []Rule{0, RuleAll + 1} {
+			_, err := New(func(config DedupConfigurer) error {
+				return config.SetRules(rules)
+			})
+			if err == nil {
+				t.Errorf("got error nil with rules %d, want error not nil", rules)
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package transform_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+// fanRemover removes the commands that turn on the fan.
+type fanRemover struct{}
+
+func (fanRemover) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	if b.Command().String() == "M106" {
+		return nil, nil
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+func ExampleNewPipeline() {
+	const source = `G28
+M106 S255
+G1 X10 Y10 E1
+`
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	p, err := transform.NewPipeline([]transform.Transformer{fanRemover{}})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	result, err := p.Run(d)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print(result)
+
+	// Output:
+	// G28
+	// G1 X10 Y10 E1
+}
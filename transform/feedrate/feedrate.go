@@ -0,0 +1,209 @@
+// feedrate package contains a transformer that adjusts the speed of the moves, scaling and limiting their feedrates.
+//
+// The travel moves and the extrusion moves are scaled by independent factors, so each one can be tuned without affecting the other.
+// The feedrates can be limited to the maximum speed of each axis, like the ones of a machine profile,
+// considering the direction of each move, so a diagonal move can be faster than a move along the slowest axis.
+//
+// The feedrate is modal, so the blocks without F receive one when the feedrate in effect must change.
+// The moves executed before any feedrate is commanded aren't modified, because their feedrate is unknown.
+package feedrate
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// FEEDRATE_DECIMALS is the number of decimals of the feedrates generated.
+	FEEDRATE_DECIMALS = 3
+)
+
+//#region feedrate configuration
+
+// FeedrateConfigurer defines the options of the feedrate adjustment.
+type FeedrateConfigurer interface {
+	// Set the factor applied to the feedrate of the travel moves
+	SetTravelFactor(factor float64) error
+
+	// Set the factor applied to the feedrate of the extrusion moves
+	SetExtrusionFactor(factor float64) error
+
+	// Set the maximum speed of each axis
+	SetLimits(limits [4]float64) error
+}
+
+// FeedrateConfigurationCallbackable is the signature of the callbacks used to configure the feedrate adjustment.
+type FeedrateConfigurationCallbackable func(config FeedrateConfigurer) error
+
+// feedrateConfigurator implements FeedrateConfigurer.
+type feedrateConfigurator struct {
+	travel    float64
+	extrusion float64
+	limits    [4]float64
+}
+
+// SetTravelFactor defines the factor applied to the feedrate of the moves without extrusion. It must be positive and finite.
+// If this method isn't called, by default it is 1.
+func (fc *feedrateConfigurator) SetTravelFactor(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return fmt.Errorf("failed to set travel factor, it must be positive and finite: %v", factor)
+	}
+
+	fc.travel = factor
+
+	return nil
+}
+
+// SetExtrusionFactor defines the factor applied to the feedrate of the moves that extrude while they move. It must be positive and finite.
+// The retractions and the primes aren't scaled.
+// If this method isn't called, by default it is 1.
+func (fc *feedrateConfigurator) SetExtrusionFactor(factor float64) error {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return fmt.Errorf("failed to set extrusion factor, it must be positive and finite: %v", factor)
+	}
+
+	fc.extrusion = factor
+
+	return nil
+}
+
+// SetLimits defines the maximum speed of each axis in units per second, indexed as X, Y, Z and E,
+// like the M203 command of Marlin. A zero value doesn't limit the axis.
+// The limits can't be negative or infinite.
+// If this method isn't called, by default the feedrates aren't limited.
+func (fc *feedrateConfigurator) SetLimits(limits [4]float64) error {
+	for _, limit := range limits {
+		if !(limit >= 0) || math.IsInf(limit, 0) {
+			return fmt.Errorf("failed to set limits, they must be positive and finite: %v", limit)
+		}
+	}
+
+	fc.limits = limits
+
+	return nil
+}
+
+//#endregion
+//#region feedrate struct
+
+// Feedrate is a transformer that scales and limits the feedrate of the moves.
+type Feedrate struct {
+	// factors of the travel and extrusion moves
+	travel    float64
+	extrusion float64
+
+	// maximum speed of each axis in units per second, zero if it isn't limited
+	limits [4]float64
+
+	// feedrate in effect in the output, zero if it wasn't commanded yet
+	output float64
+}
+
+// Apply adjusts the feedrate of the block, it returns the block received or a copy of it with an F parameter added.
+func (f *Feedrate) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	switch b.Command().String() {
+	case "G0", "G1", "G2", "G3":
+	default:
+		return []block.Blocker{b}, nil
+	}
+
+	if state.After.Feedrate <= 0 {
+		return []block.Blocker{b}, nil
+	}
+
+	before, after := state.Position, state.After.Position
+	deltas := [4]float64{after.X - before.X, after.Y - before.Y, after.Z - before.Z, after.E - before.E}
+	moved := deltas[0] != 0 || deltas[1] != 0 || deltas[2] != 0
+
+	target := state.After.Feedrate
+	switch {
+	case moved && deltas[3] > 0:
+		target *= f.extrusion
+	case moved && deltas[3] == 0:
+		target *= f.travel
+	}
+
+	target = math.Min(target, f.limit(b, deltas))
+	target = math.Round(target*math.Pow10(FEEDRATE_DECIMALS)) / math.Pow10(FEEDRATE_DECIMALS)
+
+	found, err := transform.SetParameter(b, 'F', target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust feedrate of block %s: %w", b, err)
+	}
+
+	if found || target == f.output {
+		f.output = target
+		return []block.Blocker{b}, nil
+	}
+
+	f.output = target
+
+	adjusted, err := transform.AppendParameter(b, 'F', target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust feedrate of block %s: %w", b, err)
+	}
+
+	return []block.Blocker{adjusted}, nil
+}
+
+// limit returns the maximum feedrate of a move, in units per minute, so no axis exceeds its limit.
+//
+// The arcs change their direction along the path, so their X and Y limits are applied to the whole feedrate.
+func (f *Feedrate) limit(b block.Blocker, deltas [4]float64) float64 {
+	limit := math.Inf(1)
+
+	length := math.Sqrt(deltas[0]*deltas[0] + deltas[1]*deltas[1] + deltas[2]*deltas[2])
+	if length == 0 {
+		length = math.Abs(deltas[3])
+	}
+
+	arc := b.Command().String() == "G2" || b.Command().String() == "G3"
+
+	for axis, maximum := range f.limits {
+		ratio := 0.0
+		switch {
+		case arc && axis < 2:
+			ratio = 1
+		case length > 0:
+			ratio = math.Abs(deltas[axis]) / length
+		}
+
+		if maximum == 0 || ratio == 0 {
+			continue
+		}
+
+		limit = math.Min(limit, maximum*60/ratio)
+	}
+
+	return limit
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that adjusts the feedrates as configured.
+// Without options the feedrates aren't modified.
+func New(options ...FeedrateConfigurationCallbackable) (*Feedrate, error) {
+
+	configurator := &feedrateConfigurator{
+		travel:    1,
+		extrusion: 1,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Feedrate{
+		travel:    configurator.travel,
+		extrusion: configurator.extrusion,
+		limits:    configurator.limits,
+	}, nil
+}
+
+//#endregion
@@ -0,0 +1,106 @@
+package feedrate
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestFeedrate(t *testing.T) {
+
+	cases := map[string]struct {
+		source  string
+		options []FeedrateConfigurationCallbackable
+		want    string
+	}{
+		"without options": {
+			source: "G1 X10 E1 F1200\nG0 X20\n",
+			want:   "G1 X10 E1 F1200\nG0 X20\n",
+		},
+		"travel and extrusion factors": {
+			source: "G0 X10 F6000\nG1 X20 E1 F1200\nG1 X30 E2\nG0 X40\nG0 X50\n",
+			options: []FeedrateConfigurationCallbackable{
+				func(config FeedrateConfigurer) error { return config.SetTravelFactor(1.5) },
+				func(config FeedrateConfigurer) error { return config.SetExtrusionFactor(0.8) },
+			},
+			want: "G0 X10 F9000\nG1 X20 E1 F960\nG1 X30 E2\nG0 X40 F1800\nG0 X50\n",
+		},
+		"retractions aren't scaled": {
+			source: "G1 X10 E1 F1200\nG1 E0.2 F2400\nG1 E1\n",
+			options: []FeedrateConfigurationCallbackable{
+				func(config FeedrateConfigurer) error { return config.SetExtrusionFactor(0.5) },
+			},
+			want: "G1 X10 E1 F600\nG1 E0.2 F2400\nG1 E1\n",
+		},
+		"limits": {
+			source: "G1 X100 F12000\nG1 X200 Y100\nG1 Z10\nG1 X210 Z11\nG1 E5\n",
+			options: []FeedrateConfigurationCallbackable{
+				func(config FeedrateConfigurer) error { return config.SetLimits([4]float64{100, 150, 5, 25}) },
+			},
+			want: "G1 X100 F6000\nG1 X200 Y100 F8485.281\nG1 Z10 F300\nG1 X210 Z11 F3014.963\nG1 E5 F1500\n",
+		},
+		"limits of arcs": {
+			source: "G1 X10 F12000\nG2 X10 Y0 I5 J0\n",
+			options: []FeedrateConfigurationCallbackable{
+				func(config FeedrateConfigurer) error { return config.SetLimits([4]float64{200, 100, 0, 0}) },
+			},
+			want: "G1 X10 F12000\nG2 X10 Y0 I5 J0 F6000\n",
+		},
+		"unknown feedrate": {
+			source: "G1 X10\nG1 X20 F600\n",
+			options: []FeedrateConfigurationCallbackable{
+				func(config FeedrateConfigurer) error { return config.SetTravelFactor(2) },
+			},
+			want: "G1 X10\nG1 X20 F1200\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			feedrate, err := New(tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{feedrate})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		options := map[string]FeedrateConfigurationCallbackable{
+			"travel factor":    func(config FeedrateConfigurer) error { return config.SetTravelFactor(0) },
+			"extrusion factor": func(config FeedrateConfigurer) error { return config.SetExtrusionFactor(math.NaN()) },
+			"limits":           func(config FeedrateConfigurer) error { return config.SetLimits([4]float64{-1, 0, 0, 0}) },
+		}
+
+		for name, option := range options {
+			if _, err := New(option); err == nil {
+				t.Errorf("got error nil with invalid %s, want error not nil", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// flow package contains a transformer that multiplies the extrusion, to correct the over or under extrusion of a file already sliced.
+//
+// The amount of material extruded by each move is multiplied, not the E coordinates,
+// so the absolute extrusion mode (M82) stays coherent along the file and the G92 E resets are respected.
+// By default, the retractions and the primes after them aren't modified, because they don't deposit material.
+package flow
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region flow configuration
+
+// FlowConfigurer defines the options of the flow adjustment.
+type FlowConfigurer interface {
+	// Set if the retractions are multiplied too
+	SetRetractions(enabled bool) error
+}
+
+// FlowConfigurationCallbackable is the signature of the callbacks used to configure the flow adjustment.
+type FlowConfigurationCallbackable func(config FlowConfigurer) error
+
+// flowConfigurator implements FlowConfigurer.
+type flowConfigurator struct {
+	retractions bool
+}
+
+// SetRetractions defines if the extrusion of the moves without displacement in the XY plane, like the retractions and the primes, is multiplied too.
+// If this method isn't called, by default only the moves in the XY plane are multiplied.
+func (fc *flowConfigurator) SetRetractions(enabled bool) error {
+	fc.retractions = enabled
+
+	return nil
+}
+
+//#endregion
+//#region flow struct
+
+// Flow is a transformer that multiplies the extrusion of the moves.
+type Flow struct {
+	// factor applied to the extrusion
+	multiplier float64
+
+	// true if the moves without displacement in the XY plane are multiplied too
+	retractions bool
+
+	// position of the extruder in the output, only tracked in absolute extrusion mode
+	e float64
+}
+
+// Apply multiplies the extrusion of the block, it always returns the block received.
+func (f *Flow) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	switch b.Command().String() {
+	case "G0", "G1", "G2", "G3":
+	case "G92":
+		if e, ok := transform.Parameter(b, 'E'); ok {
+			f.e = e
+		}
+		return []block.Blocker{b}, nil
+	default:
+		return []block.Blocker{b}, nil
+	}
+
+	if _, ok := transform.Parameter(b, 'E'); !ok {
+		return []block.Blocker{b}, nil
+	}
+
+	delta := state.After.Position.E - state.Position.E
+
+	moved := state.After.Position.X != state.Position.X || state.After.Position.Y != state.Position.Y
+	if moved || f.retractions {
+		delta *= f.multiplier
+	}
+
+	value := delta
+	if !state.RelativeExtrusion {
+		f.e += delta
+		value = f.e
+	}
+
+	if _, err := transform.SetParameter(b, 'E', value); err != nil {
+		return nil, fmt.Errorf("failed to adjust extrusion of block %s: %w", b, err)
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that multiplies the extrusion by the factor received, like 0.95 to extrude 5% less.
+// The multiplier must be positive and finite.
+func New(multiplier float64, options ...FlowConfigurationCallbackable) (*Flow, error) {
+	if !(multiplier > 0) || math.IsInf(multiplier, 0) {
+		return nil, fmt.Errorf("failed to create flow, the multiplier must be positive and finite: %v", multiplier)
+	}
+
+	configurator := &flowConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Flow{multiplier: multiplier, retractions: configurator.retractions}, nil
+}
+
+//#endregion
@@ -0,0 +1,89 @@
+package flow
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestFlow(t *testing.T) {
+
+	cases := map[string]struct {
+		source      string
+		multiplier  float64
+		retractions bool
+		want        string
+	}{
+		"absolute extrusion": {
+			source:     "M82\nG1 X10 E1\nG1 X20 E3\nG1 E2\nG0 X30\nG1 E3\nG1 X40 E5\n",
+			multiplier: 0.5,
+			want:       "M82\nG1 X10 E0.5\nG1 X20 E1.5\nG1 E0.5\nG0 X30\nG1 E1.5\nG1 X40 E2.5\n",
+		},
+		"relative extrusion": {
+			source:     "M83\nG1 X10 E1\nG1 E-0.8\nG1 E0.8\nG1 X20 E2\n",
+			multiplier: 1.5,
+			want:       "M83\nG1 X10 E1.5\nG1 E-0.8\nG1 E0.8\nG1 X20 E3\n",
+		},
+		"retractions": {
+			source:      "M83\nG1 X10 E1\nG1 E-0.8\nG1 E0.8\n",
+			multiplier:  0.5,
+			retractions: true,
+			want:        "M83\nG1 X10 E0.5\nG1 E-0.4\nG1 E0.4\n",
+		},
+		"extrusion reset": {
+			source:     "G1 X10 E10\nG92 E0\nG1 X20 E4\nG1 X30 E8\n",
+			multiplier: 0.5,
+			want:       "G1 X10 E5\nG92 E0\nG1 X20 E2\nG1 X30 E4\n",
+		},
+		"arcs": {
+			source:     "M83\nG2 X10 Y0 I5 J0 E2\n",
+			multiplier: 2,
+			want:       "M83\nG2 X10 Y0 I5 J0 E4\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			flow, err := New(tc.multiplier, func(config FlowConfigurer) error {
+				return config.SetRetractions(tc.retractions)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{flow})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid multiplier", func(t *testing.T) {
+		for _, multiplier := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+			if _, err := New(multiplier); err == nil {
+				t.Errorf("got error nil with multiplier %v, want error not nil", multiplier)
+			}
+		}
+	})
+}
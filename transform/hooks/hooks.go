@@ -0,0 +1,239 @@
+// hooks package contains a transformer that injects gcode snippets at the events detected along a print,
+// like a timelapse trigger at each layer change, a purge routine after each tool change or a LED command at the end.
+//
+// The snippets can contain placeholders that are replaced by the values of the event:
+// {layer} is the index of the layer, {tool} is the active tool and {z} is the height of the nozzle after the block where the event is detected.
+package hooks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region event
+
+// Event identifies a moment of the print where the snippets are injected.
+type Event int
+
+const (
+	// EventLayerChange is the start of each layer, the snippets are injected before its first block.
+	EventLayerChange Event = iota
+
+	// EventToolChange is each tool change, the snippets are injected after the T command.
+	EventToolChange
+
+	// EventFirstExtrusion is the first extrusion move, the snippets are injected before it.
+	EventFirstExtrusion
+
+	// EventPrintEnd is the end of the print, the snippets are injected after the last extrusion move, before the end gcode.
+	EventPrintEnd
+)
+
+// String returns the name of the event.
+func (e Event) String() string {
+	switch e {
+	case EventLayerChange:
+		return "layer change"
+	case EventToolChange:
+		return "tool change"
+	case EventFirstExtrusion:
+		return "first extrusion"
+	case EventPrintEnd:
+		return "print end"
+	}
+
+	return fmt.Sprintf("event(%d)", int(e))
+}
+
+//#endregion
+//#region hooks configuration
+
+// HooksConfigurer defines the snippets injected at each event.
+type HooksConfigurer interface {
+	// Register a snippet injected at an event
+	Register(event Event, expressions ...string) error
+}
+
+// HooksConfigurationCallbackable is the signature of the callbacks used to configure the hooks.
+type HooksConfigurationCallbackable func(config HooksConfigurer) error
+
+// hooksConfigurator implements HooksConfigurer.
+type hooksConfigurator struct {
+	snippets map[Event][]string
+}
+
+// Register adds a snippet injected at an event, each expression is a block that can contain placeholders.
+// The snippets of the same event are injected in the order registered. It requires at least one expression.
+// It returns an error if the event is unknown or some expression isn't a valid block.
+func (hc *hooksConfigurator) Register(event Event, expressions ...string) error {
+	switch event {
+	case EventLayerChange, EventToolChange, EventFirstExtrusion, EventPrintEnd:
+	default:
+		return fmt.Errorf("failed to register snippet, unknown event %d", event)
+	}
+
+	if len(expressions) == 0 {
+		return fmt.Errorf("failed to register snippet at %s, it requires at least one expression", event)
+	}
+
+	for _, expression := range expressions {
+		if _, err := gcodeblock.Parse(replacePlaceholders(expression, 0, 0, 0)); err != nil {
+			return fmt.Errorf("failed to register snippet at %s, invalid expression %s: %w", event, expression, err)
+		}
+	}
+
+	hc.snippets[event] = append(hc.snippets[event], expressions...)
+
+	return nil
+}
+
+//#endregion
+//#region hooks struct
+
+// Hooks is a transformer that injects the snippets registered at the events detected.
+//
+// The end of the print is the last extrusion move of the source document, so the transformer must be applied to the document received by New.
+type Hooks struct {
+	// snippets of each event
+	snippets map[Event][]string
+
+	// position of the first block after the last extrusion move of the source document, and its number of blocks
+	footer int
+	blocks int
+
+	// progress of the events detected
+	layer    int
+	extruded bool
+	ended    bool
+	last     int
+	z        float64
+	tool     int
+}
+
+// Apply returns the block received with the snippets of the events detected around it.
+func (h *Hooks) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	var blocks []block.Blocker
+
+	h.last, h.z, h.tool = state.Index, state.After.Position.Z, state.After.Tool
+
+	inject := func(event Event, position []block.Blocker) ([]block.Blocker, error) {
+		snippet, err := h.snippet(event, state.Layer, state.Tool, state.After.Position.Z)
+		if err != nil {
+			return nil, err
+		}
+		return append(position, snippet...), nil
+	}
+
+	var err error
+
+	if !h.ended && state.Index >= h.footer {
+		h.ended = true
+		if blocks, err = inject(EventPrintEnd, blocks); err != nil {
+			return nil, err
+		}
+	}
+
+	if state.Layer > h.layer {
+		h.layer = state.Layer
+		if blocks, err = inject(EventLayerChange, blocks); err != nil {
+			return nil, err
+		}
+	}
+
+	moved := state.After.Position.X != state.Position.X || state.After.Position.Y != state.Position.Y
+	if !h.extruded && moved && state.After.Position.E > state.Position.E {
+		h.extruded = true
+		if blocks, err = inject(EventFirstExtrusion, blocks); err != nil {
+			return nil, err
+		}
+	}
+
+	blocks = append(blocks, b)
+
+	if b.Command().Word() == 'T' {
+		snippet, err := h.snippet(EventToolChange, state.Layer, state.After.Tool, state.After.Position.Z)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, snippet...)
+	}
+
+	return blocks, nil
+}
+
+// Flush injects the snippets of the end of the print, if the print ends with the last block of the document.
+func (h *Hooks) Flush() ([]block.Blocker, error) {
+	if h.ended || h.last < h.blocks-1 {
+		return nil, nil
+	}
+
+	h.ended = true
+
+	return h.snippet(EventPrintEnd, h.layer, h.tool, h.z)
+}
+
+// snippet returns the blocks of the snippets of an event, with the placeholders replaced.
+func (h *Hooks) snippet(event Event, layer int, tool int, z float64) ([]block.Blocker, error) {
+	var blocks []block.Blocker
+
+	for _, expression := range h.snippets[event] {
+		source := replacePlaceholders(expression, layer, tool, z)
+
+		b, err := gcodeblock.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inject snippet at %s, invalid expression %s: %w", event, source, err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that injects the snippets registered by the options in the document received, it mustn't be nil.
+// The document is only used to find the end of the print, it isn't modified.
+func New(d *document.Document, options ...HooksConfigurationCallbackable) (*Hooks, error) {
+	if d == nil {
+		return nil, fmt.Errorf("failed to create hooks, the document mustn't be nil")
+	}
+
+	configurator := &hooksConfigurator{
+		snippets: map[Event][]string{},
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Hooks{
+		snippets: configurator.snippets,
+		footer:   d.Sections().Footer.Start,
+		blocks:   d.Len(),
+		layer:    -1,
+		last:     -1,
+	}, nil
+}
+
+//#endregion
+//#region private functions
+
+// replacePlaceholders returns the expression with the placeholders replaced by the values received.
+func replacePlaceholders(expression string, layer int, tool int, z float64) string {
+	return strings.NewReplacer(
+		"{layer}", strconv.Itoa(layer),
+		"{tool}", strconv.Itoa(tool),
+		"{z}", strconv.FormatFloat(z, 'f', -1, 64),
+	).Replace(expression)
+}
+
+//#endregion
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestHooks(t *testing.T) {
+
+	source := "G28\nT0\n;LAYER:0\nG1 Z0.2\nG1 X10 E1\n;LAYER:1\nG1 Z0.4\nT1\nG1 X20 E2\nG1 Z10\nM104 S0\n"
+
+	cases := map[string]struct {
+		source  string
+		options []HooksConfigurationCallbackable
+		want    string
+	}{
+		"without snippets": {
+			source: source,
+			want:   source,
+		},
+		"layer change": {
+			source: source,
+			options: []HooksConfigurationCallbackable{
+				func(config HooksConfigurer) error {
+					return config.Register(EventLayerChange, "M240", "G4 P{layer} S{z}")
+				},
+			},
+			want: "G28\nT0\n;LAYER:0\nM240\nG4 P0 S0.2\nG1 Z0.2\nG1 X10 E1\n;LAYER:1\nM240\nG4 P1 S0.4\nG1 Z0.4\nT1\nG1 X20 E2\nG1 Z10\nM104 S0\n",
+		},
+		"tool change": {
+			source: source,
+			options: []HooksConfigurationCallbackable{
+				func(config HooksConfigurer) error { return config.Register(EventToolChange, "G1 X{tool}") },
+			},
+			want: "G28\nT0\nG1 X0\n;LAYER:0\nG1 Z0.2\nG1 X10 E1\n;LAYER:1\nG1 Z0.4\nT1\nG1 X1\nG1 X20 E2\nG1 Z10\nM104 S0\n",
+		},
+		"first extrusion and end": {
+			source: source,
+			options: []HooksConfigurationCallbackable{
+				func(config HooksConfigurer) error { return config.Register(EventFirstExtrusion, "M150 U255") },
+				func(config HooksConfigurer) error { return config.Register(EventPrintEnd, "M150 R255") },
+			},
+			want: "G28\nT0\n;LAYER:0\nG1 Z0.2\nM150 U255\nG1 X10 E1\n;LAYER:1\nG1 Z0.4\nT1\nG1 X20 E2\nM150 R255\nG1 Z10\nM104 S0\n",
+		},
+		"end at the last block": {
+			source: "G1 X10 E1\n; end\n",
+			options: []HooksConfigurationCallbackable{
+				func(config HooksConfigurer) error { return config.Register(EventPrintEnd, "M150 R255") },
+			},
+			want: "G1 X10 E1\nM150 R255\n; end\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			hooks, err := New(d, tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{hooks})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		d := document.NewFromLines()
+
+		if _, err := New(nil); err == nil {
+			t.Errorf("got error nil with a nil document, want error not nil")
+		}
+
+		options := []HooksConfigurationCallbackable{
+			func(config HooksConfigurer) error { return config.Register(Event(9), "M240") },
+			func(config HooksConfigurer) error { return config.Register(EventLayerChange) },
+			func(config HooksConfigurer) error { return config.Register(EventLayerChange, "G1 X{y}") },
+		}
+
+		for _, option := range options {
+			if _, err := New(d, option); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		}
+	})
+}
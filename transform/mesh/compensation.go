@@ -0,0 +1,275 @@
+package mesh
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// COORDINATE_DECIMALS is the number of decimals of the coordinates generated.
+	COORDINATE_DECIMALS = 3
+
+	// EXTRUSION_DECIMALS is the number of decimals of the extrusion generated.
+	EXTRUSION_DECIMALS = 5
+)
+
+//#region compensation configuration
+
+// CompensationConfigurer defines the options of the compensation.
+type CompensationConfigurer interface {
+	// Set the maximum length of the segments
+	SetSegmentLength(length float64) error
+
+	// Set the height at which the compensation ends
+	SetFadeHeight(height float64) error
+}
+
+// CompensationConfigurationCallbackable is the signature of the callbacks used to configure the compensation.
+type CompensationConfigurationCallbackable func(config CompensationConfigurer) error
+
+// compensationConfigurator implements CompensationConfigurer.
+type compensationConfigurator struct {
+	segment float64
+	fade    float64
+}
+
+// SetSegmentLength defines the maximum length in the XY plane of the segments in which the moves are divided. It must be positive.
+// If this method isn't called, by default it is half the spacing of the grid.
+func (cc *compensationConfigurator) SetSegmentLength(length float64) error {
+	if !(length > 0) || math.IsInf(length, 0) {
+		return fmt.Errorf("failed to set segment length, it must be positive and finite: %v", length)
+	}
+
+	cc.segment = length
+
+	return nil
+}
+
+// SetFadeHeight defines the height at which the compensation ends, it is reduced gradually from the bed to that height. It must be positive.
+// If this method isn't called, by default the compensation is applied at all heights.
+func (cc *compensationConfigurator) SetFadeHeight(height float64) error {
+	if !(height > 0) || math.IsInf(height, 0) {
+		return fmt.Errorf("failed to set fade height, it must be positive and finite: %v", height)
+	}
+
+	cc.fade = height
+
+	return nil
+}
+
+//#endregion
+//#region compensation struct
+
+// Compensation is a transformer that adds the height errors of a mesh to the moves.
+//
+// The moves G0 and G1 are divided in segments no longer than the segment length, each one with the height compensated.
+// The arcs can't follow the mesh, so they must be flattened before, like with the arc package.
+// The heights set by G92 are compensated too, so the coordinates stay coherent with the moves that follow.
+type Compensation struct {
+	// mesh of height errors
+	mesh *Mesh
+
+	// maximum length of the segments
+	segment float64
+
+	// height at which the compensation ends, zero if it doesn't fade
+	fade float64
+
+	// compensation included in the current height of the nozzle
+	applied float64
+}
+
+// Apply compensates the block, it returns the block received or the segments that replace it.
+func (c *Compensation) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	switch b.Command().String() {
+	case "G0", "G1":
+		return c.move(b, state)
+	case "G2", "G3":
+		return nil, fmt.Errorf("failed to compensate block %s, the arcs must be flattened before", b)
+	case "G92":
+		z, ok := transform.Parameter(b, 'Z')
+		if !ok {
+			break
+		}
+
+		c.applied = c.compensation(state.After.Position.X, state.After.Position.Y, z)
+		if _, err := transform.SetParameter(b, 'Z', numeric.Round(z+c.applied, COORDINATE_DECIMALS)); err != nil {
+			return nil, fmt.Errorf("failed to compensate block %s: %w", b, err)
+		}
+	case "G28":
+		// after homing the height is the physical one
+		c.applied = 0
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// move divides a move in segments and compensates their heights.
+func (c *Compensation) move(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	start, end := state.Position, state.After.Position
+
+	length := math.Hypot(end.X-start.X, end.Y-start.Y)
+	segments := int(math.Ceil(length / c.segment))
+	if segments < 1 || !segmentable(b) {
+		segments = 1
+	}
+
+	// a move without displacement in XYZ keeps the compensation, like a retraction
+	if length == 0 && end.Z == start.Z {
+		return []block.Blocker{b}, nil
+	}
+
+	blocks := make([]block.Blocker, 0, segments)
+	previous := start.Z + c.applied
+	previousX, previousY := numeric.Round(start.X, COORDINATE_DECIMALS), numeric.Round(start.Y, COORDINATE_DECIMALS)
+	extruded := 0.0
+
+	for i := 1; i <= segments; i++ {
+		t := float64(i) / float64(segments)
+		x, y := start.X+(end.X-start.X)*t, start.Y+(end.Y-start.Y)*t
+		z := start.Z + (end.Z-start.Z)*t
+
+		compensation := c.compensation(x, y, z)
+		height := numeric.Round(z+compensation, COORDINATE_DECIMALS)
+
+		zValue := height
+		if state.Relative {
+			zValue = numeric.Round(height-previous, COORDINATE_DECIMALS)
+		}
+		unchanged := height == previous
+		previous = height
+		c.applied = compensation
+
+		if segments == 1 {
+			// the moves that don't command a height and don't need one are kept
+			if _, ok := transform.Parameter(b, 'Z'); !ok && unchanged {
+				return []block.Blocker{b}, nil
+			}
+
+			if err := setHeight(&b, zValue); err != nil {
+				return nil, err
+			}
+			return []block.Blocker{b}, nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(b.Command().String())
+
+		x, y = numeric.Round(x, COORDINATE_DECIMALS), numeric.Round(y, COORDINATE_DECIMALS)
+		xValue, yValue := x, y
+		if state.Relative {
+			xValue, yValue = x-previousX, y-previousY
+		}
+		previousX, previousY = x, y
+		sb.WriteString(" X" + numeric.FormatDecimals(xValue, COORDINATE_DECIMALS))
+		sb.WriteString(" Y" + numeric.FormatDecimals(yValue, COORDINATE_DECIMALS))
+		sb.WriteString(" Z" + numeric.FormatDecimals(zValue, COORDINATE_DECIMALS))
+
+		if _, ok := transform.Parameter(b, 'E'); ok {
+			e := start.E + (end.E-start.E)*t
+			if state.RelativeExtrusion {
+				// the last segment extrudes the remainder, so the total isn't altered by the rounding
+				e = numeric.Round((end.E-start.E)/float64(segments), EXTRUSION_DECIMALS)
+				if i == segments {
+					e = end.E - start.E - extruded
+				}
+				extruded += e
+			}
+			sb.WriteString(" E" + numeric.FormatDecimals(e, EXTRUSION_DECIMALS))
+		}
+
+		if feedrate, ok := transform.Parameter(b, 'F'); ok && i == 1 {
+			sb.WriteString(" F" + numeric.FormatDecimals(feedrate, COORDINATE_DECIMALS))
+		}
+
+		if i == 1 && b.Comment() != "" {
+			sb.WriteString(" " + b.Comment())
+		}
+
+		segment, err := gcodeblock.Parse(sb.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create segment %s of block %s: %w", sb.String(), b, err)
+		}
+
+		blocks = append(blocks, segment)
+	}
+
+	return blocks, nil
+}
+
+// compensation returns the height error at a point, reduced by the fade.
+func (c *Compensation) compensation(x float64, y float64, z float64) float64 {
+	factor := 1.0
+	if c.fade > 0 {
+		factor = math.Max(0, 1-z/c.fade)
+	}
+
+	return c.mesh.Height(x, y) * factor
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that compensates the moves with the mesh received, it mustn't be nil.
+func New(mesh *Mesh, options ...CompensationConfigurationCallbackable) (*Compensation, error) {
+	if mesh == nil {
+		return nil, fmt.Errorf("failed to create compensation, the mesh mustn't be nil")
+	}
+
+	spacingX, spacingY := mesh.Spacing()
+	configurator := &compensationConfigurator{
+		segment: math.Min(spacingX, spacingY) / 2,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Compensation{mesh: mesh, segment: configurator.segment, fade: configurator.fade}, nil
+}
+
+//#endregion
+//#region private functions
+
+// segmentable returns true if the block only has parameters that the segments can keep.
+func segmentable(b block.Blocker) bool {
+	for _, p := range b.Parameters() {
+		switch p.Word() {
+		case 'X', 'Y', 'Z', 'E', 'F':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// setHeight sets the Z parameter of a block, adding it if the block hasn't one.
+func setHeight(b *block.Blocker, z float64) error {
+	found, err := transform.SetParameter(*b, 'Z', z)
+	if err != nil {
+		return fmt.Errorf("failed to compensate block %s: %w", *b, err)
+	}
+
+	if found {
+		return nil
+	}
+
+	appended, err := transform.AppendParameter(*b, 'Z', z)
+	if err != nil {
+		return fmt.Errorf("failed to compensate block %s: %w", *b, err)
+	}
+	*b = appended
+
+	return nil
+}
+
+//#endregion
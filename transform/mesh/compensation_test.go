@@ -0,0 +1,115 @@
+package mesh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestCompensation(t *testing.T) {
+
+	m, err := NewMesh(0, 0, 100, 100, [][]float64{{0, 0.1}, {0.2, 0.3}})
+	if err != nil {
+		t.Fatalf("failed to create mesh: %v", err)
+	}
+
+	cases := map[string]struct {
+		source  string
+		options []CompensationConfigurationCallbackable
+		want    string
+		valid   bool
+	}{
+		"absolute": {
+			source: "G28\nG1 Z0.2 F1200\nG1 X100 Y0 E5 ; perimeter\nG1 E4\n",
+			want:   "G28\nG1 Z0.2 F1200\nG1 X50 Y0 Z0.25 E2.5 ; perimeter\nG1 X100 Y0 Z0.3 E5\nG1 E4\n",
+			valid:  true,
+		},
+		"fade": {
+			source: "G28\nG1 Z0.2 F1200\nG1 X100 Y0 E5\n",
+			options: []CompensationConfigurationCallbackable{
+				func(config CompensationConfigurer) error { return config.SetFadeHeight(0.4) },
+			},
+			want:  "G28\nG1 Z0.2 F1200\nG1 X50 Y0 Z0.225 E2.5\nG1 X100 Y0 Z0.25 E5\n",
+			valid: true,
+		},
+		"relative": {
+			source: "G28\nG91\nG1 X100 Z0.2 E5\n",
+			want:   "G28\nG91\nG1 X50 Y0 Z0.15 E2.5\nG1 X50 Y0 Z0.15 E2.5\n",
+			valid:  true,
+		},
+		"coordinates set": {
+			source: "G28\nG1 X100 Y100 Z10\nG92 Z0\nG1 X100 Y100 Z0.2\n",
+			options: []CompensationConfigurationCallbackable{
+				func(config CompensationConfigurer) error { return config.SetSegmentLength(200) },
+			},
+			want:  "G28\nG1 X100 Y100 Z10.3\nG92 Z0.3\nG1 X100 Y100 Z0.5\n",
+			valid: true,
+		},
+		"other parameters": {
+			source: "G28\nG1 X100 S1\n",
+			want:   "G28\nG1 X100 S1 Z0.1\n",
+			valid:  true,
+		},
+		"arcs": {
+			source: "G28\nG2 X10 Y10 I5 J5\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			compensation, err := New(m, tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{compensation})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := New(nil); err == nil {
+			t.Errorf("got error nil with a nil mesh, want error not nil")
+		}
+
+		options := []CompensationConfigurationCallbackable{
+			func(config CompensationConfigurer) error { return config.SetSegmentLength(0) },
+			func(config CompensationConfigurer) error { return config.SetFadeHeight(-1) },
+		}
+
+		for _, option := range options {
+			if _, err := New(m, option); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// mesh package contains a transformer that compensates the irregularities of the bed in the gcode itself,
+// for the machines whose firmware doesn't apply a bed mesh.
+//
+// The mesh is a grid of height errors probed over the bed. The moves are divided in segments,
+// so the height of the nozzle follows the surface interpolated between the points of the grid.
+// The compensation can fade out with the height, so the top of the print is flat.
+package mesh
+
+import (
+	"fmt"
+	"math"
+)
+
+//#region mesh struct
+
+// Mesh is a grid of height errors probed at points equally spaced over a rectangle of the bed.
+type Mesh struct {
+	// rectangle covered by the grid
+	minX, minY, maxX, maxY float64
+
+	// height errors, indexed by row along Y and column along X
+	heights [][]float64
+}
+
+// Rows returns the number of points of the grid along the Y axis.
+func (m *Mesh) Rows() int {
+	return len(m.heights)
+}
+
+// Columns returns the number of points of the grid along the X axis.
+func (m *Mesh) Columns() int {
+	return len(m.heights[0])
+}
+
+// Spacing returns the distance between two points of the grid along the X and Y axes.
+func (m *Mesh) Spacing() (float64, float64) {
+	return (m.maxX - m.minX) / float64(m.Columns()-1), (m.maxY - m.minY) / float64(m.Rows()-1)
+}
+
+// Height returns the height error at a point of the bed, interpolated between the four points of the grid around it.
+// The points outside the grid take the error of the nearest border.
+func (m *Mesh) Height(x float64, y float64) float64 {
+	column, u := m.cell(x, m.minX, m.maxX, m.Columns())
+	row, v := m.cell(y, m.minY, m.maxY, m.Rows())
+
+	h00, h01 := m.heights[row][column], m.heights[row][column+1]
+	h10, h11 := m.heights[row+1][column], m.heights[row+1][column+1]
+
+	return (h00*(1-u)+h01*u)*(1-v) + (h10*(1-u)+h11*u)*v
+}
+
+// cell returns the index of the first point of the cell that contains a coordinate, and the position of the coordinate inside the cell, from 0 to 1.
+func (m *Mesh) cell(value float64, low float64, high float64, points int) (int, float64) {
+	position := (value - low) / (high - low) * float64(points-1)
+	position = math.Max(0, math.Min(position, float64(points-1)))
+
+	index := int(position)
+	if index == points-1 {
+		index--
+	}
+
+	return index, position - float64(index)
+}
+
+//#endregion
+//#region constructor
+
+// NewMesh returns a new mesh that covers the rectangle received with the grid of height errors, indexed by row along Y and column along X.
+//
+// The grid must have at least two rows and two columns, all rows with the same length, and finite values.
+// The rectangle must have a positive area.
+func NewMesh(minX float64, minY float64, maxX float64, maxY float64, heights [][]float64) (*Mesh, error) {
+	for _, v := range []float64{minX, minY, maxX, maxY} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("failed to create mesh, the limits must be finite: %v", v)
+		}
+	}
+
+	if !(maxX > minX) || !(maxY > minY) {
+		return nil, fmt.Errorf("failed to create mesh, the rectangle [%v, %v]-[%v, %v] hasn't area", minX, minY, maxX, maxY)
+	}
+
+	if len(heights) < 2 || len(heights[0]) < 2 {
+		return nil, fmt.Errorf("failed to create mesh, the grid must have at least two rows and two columns")
+	}
+
+	grid := make([][]float64, len(heights))
+	for i, row := range heights {
+		if len(row) != len(heights[0]) {
+			return nil, fmt.Errorf("failed to create mesh, the row %d has %d points, want %d", i, len(row), len(heights[0]))
+		}
+
+		for j, h := range row {
+			if math.IsNaN(h) || math.IsInf(h, 0) {
+				return nil, fmt.Errorf("failed to create mesh, the height of the point [%d][%d] isn't finite: %v", i, j, h)
+			}
+		}
+
+		grid[i] = append([]float64(nil), row...)
+	}
+
+	return &Mesh{minX: minX, minY: minY, maxX: maxX, maxY: maxY, heights: grid}, nil
+}
+
+//#endregion
@@ -0,0 +1,72 @@
+package mesh
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMesh_Height(t *testing.T) {
+
+	m, err := NewMesh(0, 0, 100, 100, [][]float64{{0, 0.1}, {0.2, 0.3}})
+	if err != nil {
+		t.Fatalf("failed to create mesh: %v", err)
+	}
+
+	cases := map[string]struct {
+		x, y float64
+		want float64
+	}{
+		"corner":       {0, 0, 0},
+		"opposite":     {100, 100, 0.3},
+		"center":       {50, 50, 0.15},
+		"border":       {50, 0, 0.05},
+		"outside":      {-10, 150, 0.2},
+		"outside both": {200, -50, 0.1},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := m.Height(tc.x, tc.y); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewMesh(t *testing.T) {
+
+	cases := map[string]struct {
+		minX, minY, maxX, maxY float64
+		heights                [][]float64
+		valid                  bool
+	}{
+		"valid":          {0, 0, 200, 100, [][]float64{{0, 0, 0}, {0.1, 0.1, 0.1}}, true},
+		"without area":   {0, 0, 0, 100, [][]float64{{0, 0}, {0, 0}}, false},
+		"single row":     {0, 0, 100, 100, [][]float64{{0, 0}}, false},
+		"irregular rows": {0, 0, 100, 100, [][]float64{{0, 0}, {0}}, false},
+		"height NaN":     {0, 0, 100, 100, [][]float64{{0, 0}, {0, math.NaN()}}, false},
+		"infinite limit": {0, 0, math.Inf(1), 100, [][]float64{{0, 0}, {0, 0}}, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMesh(tc.minX, tc.minY, tc.maxX, tc.maxY, tc.heights)
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, want valid %v", err, tc.valid)
+				return
+			}
+
+			if !tc.valid {
+				return
+			}
+
+			if m.Rows() != 2 || m.Columns() != 3 {
+				t.Errorf("got %d rows and %d columns, want 2 and 3", m.Rows(), m.Columns())
+			}
+
+			if x, y := m.Spacing(); x != 100 || y != 100 {
+				t.Errorf("got spacing %v, %v, want 100, 100", x, y)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// minify package contains a transformer that reduces the size of a file, to stream it faster over slow serial links.
+//
+// It removes the comments, the comment lines and the blank lines, and exports the addresses with the minimum number of characters,
+// optionally rounded to fewer decimals. The redundant whitespace of the blocks disappears when they are exported again.
+// The transformer counts the bytes removed, so the saving can be reported.
+package minify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region stats
+
+// Stats counts the bytes of the lines received and returned by a minifier.
+//
+// The blocks are measured exported with document.LINE_FORMAT and each line counts one byte for its line ending.
+type Stats struct {
+	// Lines is the number of lines received.
+	Lines int
+
+	// RemovedLines is the number of lines without gcode removed.
+	RemovedLines int
+
+	// InputBytes is the size of the lines received.
+	InputBytes int64
+
+	// OutputBytes is the size of the lines returned.
+	OutputBytes int64
+}
+
+// Saved returns the number of bytes removed.
+func (s Stats) Saved() int64 {
+	return s.InputBytes - s.OutputBytes
+}
+
+// Ratio returns the size of the output relative to the size of the input, or 1 if nothing was received.
+func (s Stats) Ratio() float64 {
+	if s.InputBytes == 0 {
+		return 1
+	}
+
+	return float64(s.OutputBytes) / float64(s.InputBytes)
+}
+
+// String returns the stats formatted.
+func (s Stats) String() string {
+	return fmt.Sprintf("%d bytes saved of %d (%.1f%%), %d of %d lines removed", s.Saved(), s.InputBytes, (1-s.Ratio())*100, s.RemovedLines, s.Lines)
+}
+
+//#endregion
+//#region minify configuration
+
+// MinifyConfigurer defines the options of the minification.
+type MinifyConfigurer interface {
+	// Set the number of decimals of the addresses
+	SetPrecision(decimals int) error
+}
+
+// MinifyConfigurationCallbackable is the signature of the callbacks used to configure the minification.
+type MinifyConfigurationCallbackable func(config MinifyConfigurer) error
+
+// minifyConfigurator implements MinifyConfigurer.
+type minifyConfigurator struct {
+	precision int
+}
+
+// SetPrecision defines the maximum number of decimals of the fractional addresses, the trailing zeros are removed.
+// The addresses are rounded when they are exported, so the coordinates of consecutive moves can accumulate the rounding error.
+// It must be between zero and gcode.MAX_FLOAT_PRECISION.
+// If this method isn't called, by default the addresses keep all their decimals.
+func (mc *minifyConfigurator) SetPrecision(decimals int) error {
+	if decimals < 0 || decimals > gcode.MAX_FLOAT_PRECISION {
+		return fmt.Errorf("failed to set precision, it must be between 0 and %d: %d", gcode.MAX_FLOAT_PRECISION, decimals)
+	}
+
+	mc.precision = decimals
+
+	return nil
+}
+
+//#endregion
+//#region minify struct
+
+// Minify is a transformer that removes everything that the machine doesn't need from the blocks and the lines without gcode.
+//
+// The line numbers and the checksums are kept, because the streaming protocol could require them,
+// and the pipeline recalculates the checksums after the addresses are exported again.
+// The blocks parsed in round-trip mode and not modified keep their original bytes, so they should be parsed without it.
+type Minify struct {
+	// format of the addresses of the blocks returned
+	format gcode.FloatFormat
+
+	// bytes counted since the transformer was created
+	stats Stats
+}
+
+// Apply removes the comment of the block and exports its addresses with the minimum number of characters.
+//
+// It returns an error if the block doesn't implement block.Editor and block.Formatter.
+func (m *Minify) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	e, editable := b.(block.Editor)
+	f, formattable := b.(block.Formatter)
+	if !editable || !formattable {
+		return nil, fmt.Errorf("failed to minify block %s, it doesn't implement block.Editor and block.Formatter", b)
+	}
+
+	input := len(document.Line{Block: b}.String()) + 1
+
+	e.SetComment("")
+	f.SetPreserveLiterals(false)
+
+	if err := f.SetFloatFormat(m.format); err != nil {
+		return nil, fmt.Errorf("failed to minify block %s: %w", b, err)
+	}
+
+	m.stats.Lines++
+	m.stats.InputBytes += int64(input)
+	m.stats.OutputBytes += int64(len(document.Line{Block: b}.String()) + 1)
+
+	return []block.Blocker{b}, nil
+}
+
+// KeepLine removes the comment lines and the blank lines. The other lines without gcode are kept.
+func (m *Minify) KeepLine(text string) bool {
+	m.stats.Lines++
+	m.stats.InputBytes += int64(len(text) + 1)
+
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+		m.stats.RemovedLines++
+		return false
+	}
+
+	m.stats.OutputBytes += int64(len(text) + 1)
+
+	return true
+}
+
+// Stats returns the bytes counted since the transformer was created.
+func (m *Minify) Stats() Stats {
+	return m.stats
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that minifies the blocks and removes the comment and blank lines.
+func New(options ...MinifyConfigurationCallbackable) (*Minify, error) {
+
+	configurator := &minifyConfigurator{
+		precision: -1,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	format := gcode.FloatFormat{Precision: configurator.precision}
+	if configurator.precision >= 0 {
+		format.TrimZeros = true
+	}
+
+	return &Minify{format: format}, nil
+}
+
+//#endregion
@@ -0,0 +1,112 @@
+package minify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestMinify(t *testing.T) {
+
+	cases := map[string]struct {
+		source    string
+		precision int
+		want      string
+		stats     Stats
+	}{
+		"comments and blank lines": {
+			source:    "; generated by a slicer\nG28 ; home\n\n   \nG1 X10.0 Y20.5 F3000\nM84\n",
+			precision: -1,
+			want:      "G28\nG1 X10 Y20.5 F3000\nM84\n",
+			stats:     Stats{Lines: 6, RemovedLines: 3, InputBytes: 65, OutputBytes: 27},
+		},
+		"precision": {
+			source:    "G1 X10.12345 Y0.5 E0.123456\n",
+			precision: 2,
+			want:      "G1 X10.12 Y0.5 E0.12\n",
+			stats:     Stats{Lines: 1, InputBytes: 28, OutputBytes: 21},
+		},
+		"checksums": {
+			source:    "N1 G1 X10.0*0 ; move\n",
+			precision: -1,
+			want:      "N1 G1 X10*80\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var options []MinifyConfigurationCallbackable
+			if tc.precision >= 0 {
+				options = append(options, func(config MinifyConfigurer) error {
+					return config.SetPrecision(tc.precision)
+				})
+			}
+
+			minify, err := New(options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{minify})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+
+			if tc.stats != (Stats{}) && minify.Stats() != tc.stats {
+				t.Errorf("got stats %+v, want %+v", minify.Stats(), tc.stats)
+			}
+		})
+	}
+
+	t.Run("invalid precision", func(t *testing.T) {
+		for _, decimals := range []int{-1, 17} {
+			_, err := New(func(config MinifyConfigurer) error {
+				return config.SetPrecision(decimals)
+			})
+			if err == nil {
+				t.Errorf("got error nil with precision %d, want error not nil", decimals)
+			}
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	stats := Stats{Lines: 4, RemovedLines: 1, InputBytes: 200, OutputBytes: 150}
+
+	if stats.Saved() != 50 {
+		t.Errorf("got saved %d, want 50", stats.Saved())
+	}
+
+	if stats.Ratio() != 0.75 {
+		t.Errorf("got ratio %v, want 0.75", stats.Ratio())
+	}
+
+	want := "50 bytes saved of 200 (25.0%), 1 of 4 lines removed"
+	if stats.String() != want {
+		t.Errorf("got %q, want %q", stats.String(), want)
+	}
+
+	if (Stats{}).Ratio() != 1 {
+		t.Errorf("got ratio %v without input, want 1", (Stats{}).Ratio())
+	}
+}
@@ -0,0 +1,228 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/gcode/addressablegcode"
+	"github.com/mauroalderete/gcode-core/internal/blockparam"
+)
+
+//#region parameters
+
+// Parameter returns the numeric address of the first parameter of the block with the word required.
+// It returns false if the block hasn't that parameter or its address isn't numeric.
+//
+// The float32 addresses are converted using their shortest representation, so Z0.2 is 0.2 instead of 0.20000000298.
+func Parameter(b block.Blocker, word byte) (float64, bool) {
+	return blockparam.Number(b, word)
+}
+
+// SetParameter modifies in place the numeric address of the first parameter of the block with the word required.
+// It returns false if the block hasn't that parameter.
+//
+// The integer addresses are replaced by a float64 address if the value isn't an integer, like X10 moved to X10.5.
+// A gcodeblock.GcodeBlock creates the new address with its gcode factory, so it accepts the same words.
+// It returns an error if the address isn't numeric, the gcode is frozen or the value isn't finite.
+func SetParameter(b block.Blocker, word byte, value float64) (bool, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return false, fmt.Errorf("failed to set parameter %c, the value must be finite: %v", word, value)
+	}
+
+	parameters := b.Parameters()
+	for i, p := range parameters {
+		if p.Word() != word {
+			continue
+		}
+
+		var err error
+		switch v := p.(type) {
+		case gcode.AddressableGcoder[float32]:
+			err = v.SetAddress(float32(value))
+		case gcode.AddressableGcoder[float64]:
+			err = v.SetAddress(value)
+		case gcode.AddressableGcoder[int32]:
+			if value == math.Trunc(value) && value >= math.MinInt32 && value <= math.MaxInt32 {
+				err = v.SetAddress(int32(value))
+				break
+			}
+			err = replaceParameter(b, parameters, i, value)
+		case gcode.AddressableGcoder[uint32]:
+			if value == math.Trunc(value) && value >= 0 && value <= math.MaxUint32 {
+				err = v.SetAddress(uint32(value))
+				break
+			}
+			err = replaceParameter(b, parameters, i, value)
+		default:
+			return true, fmt.Errorf("failed to set parameter %s, it hasn't a numeric address", p)
+		}
+
+		if err != nil {
+			return true, fmt.Errorf("failed to set parameter %c: %w", word, err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// AppendParameter returns a new block like the block received with a parameter added at the end of its parameters.
+//
+// The blocks can't grow in place, so the new block keeps the line number, the comment, the tags, the float format, the literal policy,
+// the checksum input, the hash and the words accepted of the original, and its checksum is recalculated if the original had one.
+// It returns an error if the value isn't finite or the new block can't be created.
+func AppendParameter(b block.Blocker, word byte, value float64) (block.Blocker, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("failed to append parameter %c, the value must be finite: %v", word, value)
+	}
+
+	source := fmt.Sprintf("%s %c%s", b.ToLine("%l %c %p"), word, strconv.FormatFloat(value, 'f', -1, 64))
+
+	appended, err := rebuild(b, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append parameter %c to block %s: %w", word, b, err)
+	}
+
+	return appended, nil
+}
+
+// RemoveParameter returns a new block like the block received without the parameters with the word required.
+//
+// The new block keeps the same properties that AppendParameter keeps, and the other parameters are written
+// with the float format and the literal policy of the original, so their text doesn't change.
+// It returns false, and the block received, if the block hasn't that parameter.
+// It returns an error if the new block can't be created.
+func RemoveParameter(b block.Blocker, word byte) (block.Blocker, bool, error) {
+	parts := []string{b.ToLine("%l %c")}
+	found := false
+
+	for _, p := range b.Parameters() {
+		if p.Word() == word {
+			found = true
+			continue
+		}
+		parts = append(parts, formatParameter(b, p))
+	}
+
+	if !found {
+		return b, false, nil
+	}
+
+	removed, err := rebuild(b, strings.Join(parts, " "))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to remove parameter %c from block %s: %w", word, b, err)
+	}
+
+	return removed, true, nil
+}
+
+//#endregion
+//#region private functions
+
+// rebuild parses the expression of a new block that replaces the block received, without its comment,
+// and copies the comment of the original. The checksum is recalculated if the original had one.
+//
+// A gcodeblock.GcodeBlock derives the new block, so it keeps its gcode factory, hash, checksum input, float format,
+// literal policy and tags. Other implementations keep the checksum input, the float format, the literal policy and the tags
+// if they implement block.ChecksumInputer, block.Formatter and block.Tagger.
+func rebuild(b block.Blocker, expression string) (block.Blocker, error) {
+	source := strings.TrimSpace(expression)
+
+	var rebuilt *gcodeblock.GcodeBlock
+	if gb, ok := b.(*gcodeblock.GcodeBlock); ok {
+		derived, err := gb.Derive(source)
+		if err != nil {
+			return nil, err
+		}
+		rebuilt = derived
+	} else {
+		parsed, err := gcodeblock.Parse(source, func(config block.BlockParserConfigurer) error {
+			if ci, ok := b.(block.ChecksumInputer); ok {
+				if err := config.SetChecksumInput(ci.ChecksumInput()); err != nil {
+					return err
+				}
+			}
+			if f, ok := b.(block.Formatter); ok {
+				if err := config.SetFloatFormat(f.FloatFormat()); err != nil {
+					return err
+				}
+				return config.SetPreserveLiterals(f.PreserveLiterals())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		rebuilt = parsed
+
+		if t, ok := b.(block.Tagger); ok {
+			for key, value := range t.Tags() {
+				if err := rebuilt.SetTag(key, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	rebuilt.SetComment(b.Comment())
+
+	if b.Checksum() != nil {
+		if err := rebuilt.UpdateChecksum(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rebuilt, nil
+}
+
+// formatParameter returns a parameter exported like the block exports it,
+// with the original text of its address if the block preserves the literals, or with the float format of the block.
+// The parameters of a block that doesn't implement block.Formatter are exported with their default format.
+func formatParameter(b block.Blocker, p gcode.Gcoder) string {
+	f, ok := b.(block.Formatter)
+	if !ok {
+		return p.String()
+	}
+
+	if f.PreserveLiterals() {
+		if lg, ok := p.(gcode.LiteralGcoder); ok && lg.Literal() != "" {
+			return string(p.Word()) + lg.Literal()
+		}
+	}
+
+	if fg, ok := p.(gcode.FormattableGcoder); ok {
+		return fg.Format(f.FloatFormat())
+	}
+
+	return p.String()
+}
+
+// replaceParameter replaces a parameter by a float64 gcode with the same word.
+// The parameters are the slice stored by the block, so the gcode is replaced in place.
+//
+// A gcodeblock.GcodeBlock creates the gcode with its gcode factory, other implementations with the default words.
+func replaceParameter(b block.Blocker, parameters []gcode.Gcoder, index int, value float64) error {
+	var g gcode.Gcoder
+	var err error
+
+	if gb, ok := b.(*gcodeblock.GcodeBlock); ok {
+		g, err = gb.NewParameter(parameters[index].Word(), value)
+	} else {
+		g, err = addressablegcode.New(parameters[index].Word(), value)
+	}
+	if err != nil {
+		return err
+	}
+
+	parameters[index] = g
+
+	return nil
+}
+
+//#endregion
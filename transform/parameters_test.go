@@ -0,0 +1,278 @@
+package transform
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/checksum"
+	"github.com/mauroalderete/gcode-core/gcode"
+)
+
+func TestSetParameter(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		word   byte
+		value  float64
+		found  bool
+		valid  bool
+		want   string
+	}{
+		"float":             {"G1 X10.5 Y2", 'X', 12.25, true, true, "G1 X12.25 Y2"},
+		"integer":           {"G1 X10 Y2", 'Y', 5, true, true, "G1 X10 Y5"},
+		"integer to float":  {"G1 X10 Y2", 'X', 10.5, true, true, "G1 X10.5 Y2"},
+		"negative":          {"G1 X10 Y2", 'X', -3, true, true, "G1 X-3 Y2"},
+		"missing":           {"G1 X10", 'Z', 1, false, true, "G1 X10"},
+		"string address":    {"M32 P\"file.gcode\"", 'P', 1, true, false, "M32 P\"file.gcode\""},
+		"not finite":        {"G1 X10", 'X', math.Inf(1), false, false, "G1 X10"},
+		"first of repeated": {"G1 X1 X2", 'X', 3, true, true, "G1 X3 X2"},
+		"large coordinate":  {"G1 X10 Y2", 'X', 123456.789, true, true, "G1 X123456.789 Y2"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			found, err := SetParameter(b, tc.word, tc.value)
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, want valid %v", err, tc.valid)
+			}
+
+			if found != tc.found {
+				t.Errorf("got found %v, want %v", found, tc.found)
+			}
+
+			if b.ToLine("%c %p") != tc.want {
+				t.Errorf("got %s, want %s", b.ToLine("%c %p"), tc.want)
+			}
+
+			if !tc.found || !tc.valid {
+				return
+			}
+
+			if v, ok := Parameter(b, tc.word); !ok || v != tc.value {
+				t.Errorf("got parameter %v %v, want %v", v, ok, tc.value)
+			}
+		})
+	}
+}
+
+func TestSetParameter_wordRegistry(t *testing.T) {
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K'); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	b, err := gcodeblock.Parse("G2 X10 I5 K2", func(config block.BlockParserConfigurer) error {
+		return config.SetWordRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if _, err := SetParameter(b, 'K', 2.5); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+	}
+
+	if got := b.ToLine("%c %p"); got != "G2 X10 I5 K2.5" {
+		t.Errorf("got %s, want G2 X10 I5 K2.5", got)
+	}
+}
+
+func TestAppendParameter(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		word   byte
+		value  float64
+		valid  bool
+		want   string
+	}{
+		"integer":       {"G1 X10", 'F', 1200, true, "G1 X10 F1200"},
+		"float":         {"G1 X10", 'E', 0.25, true, "G1 X10 E0.25"},
+		"comment":       {"G1 X10 ; move", 'F', 600, true, "G1 X10 F600 ; move"},
+		"line number":   {"N3 G1 X10*81", 'F', 600, true, "N3 G1 X10 F600*2"},
+		"not finite":    {"G1 X10", 'F', math.NaN(), false, ""},
+		"no parameters": {"G28", 'X', 0, true, "G28 X0"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			got, err := AppendParameter(b, tc.word, tc.value)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if line := got.ToLine("%l %c %p%k %m"); line != tc.want {
+				t.Errorf("got %s, want %s", line, tc.want)
+			}
+
+			if line := b.ToLine("%l %c %p%k %m"); line != tc.source {
+				t.Errorf("got original %s, want %s unchanged", line, tc.source)
+			}
+		})
+	}
+}
+
+func TestAppendParameter_configuration(t *testing.T) {
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K'); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	cases := map[string]struct {
+		source  string
+		options []block.BlockParserConfigurationCallbackable
+		want    string
+	}{
+		"crc16": {"N10 G1 X10 Y5*18494", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetHash(checksum.NewCRC16())
+			},
+		}, "N10 G1 X10 Y5 F1200*36783"},
+		"word registry": {"G2 X10 I5 K2", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetWordRegistry(registry)
+			},
+		}, "G2 X10 I5 K2 F1200"},
+		"preserve literals": {"G1 X0010.50", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetPreserveLiterals(true)
+			},
+		}, "G1 X0010.50 F1200"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source, tc.options...)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			got, err := AppendParameter(b, 'F', 1200)
+			if err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			if line := got.ToLine("%l %c %p%k"); line != tc.want {
+				t.Errorf("got %s, want %s", line, tc.want)
+			}
+
+			if preserve := got.(block.Formatter).PreserveLiterals(); preserve != b.PreserveLiterals() {
+				t.Errorf("got preserve literals %v, want %v", preserve, b.PreserveLiterals())
+			}
+		})
+	}
+}
+
+func TestRemoveParameter(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		word   byte
+		found  bool
+		want   string
+	}{
+		"middle":      {"G1 X10 F1200 E0.5", 'F', true, "G1 X10 E0.5"},
+		"repeated":    {"G1 X1 X2 Y3", 'X', true, "G1 Y3"},
+		"comment":     {"G1 X10 F600 ; move", 'F', true, "G1 X10 ; move"},
+		"line number": {"N3 G1 X10 F600*2", 'F', true, "N3 G1 X10*82"},
+		"missing":     {"G1 X10", 'F', false, "G1 X10"},
+		"last":        {"G1 F600", 'F', true, "G1"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			got, found, err := RemoveParameter(b, tc.word)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if found != tc.found {
+				t.Errorf("got found %v, want %v", found, tc.found)
+			}
+
+			if line := got.ToLine("%l %c %p%k %m"); line != tc.want {
+				t.Errorf("got %s, want %s", line, tc.want)
+			}
+
+			if line := b.ToLine("%l %c %p%k %m"); line != tc.source {
+				t.Errorf("got original %s, want %s unchanged", line, tc.source)
+			}
+		})
+	}
+}
+
+func TestRemoveParameter_configuration(t *testing.T) {
+
+	registry := gcode.DefaultWordRegistry()
+	if err := registry.Allow('K'); err != nil {
+		t.Fatalf("got error %v, want error nil", err)
+	}
+
+	cases := map[string]struct {
+		source  string
+		options []block.BlockParserConfigurationCallbackable
+		want    string
+	}{
+		"crc16": {"N10 G1 X10 Y5 F1200*36783", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetHash(checksum.NewCRC16())
+			},
+		}, "N10 G1 X10 Y5*18494"},
+		"word registry": {"G2 X10 I5 K2 F1200", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetWordRegistry(registry)
+			},
+		}, "G2 X10 I5 K2"},
+		"preserve literals": {"G1 X0010.50 F1200", []block.BlockParserConfigurationCallbackable{
+			func(config block.BlockParserConfigurer) error {
+				return config.SetPreserveLiterals(true)
+			},
+		}, "G1 X0010.50"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := gcodeblock.Parse(tc.source, tc.options...)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.source, err)
+			}
+
+			got, found, err := RemoveParameter(b, 'F')
+			if err != nil || !found {
+				t.Fatalf("got error %v found %v, want error nil found true", err, found)
+			}
+
+			if line := got.ToLine("%l %c %p%k"); line != tc.want {
+				t.Errorf("got %s, want %s", line, tc.want)
+			}
+		})
+	}
+}
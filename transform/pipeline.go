@@ -0,0 +1,421 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+// checksumSnapshotFormat exports the part of a block covered by its checksum, to detect if it was modified.
+const checksumSnapshotFormat = "%l %c %p"
+
+//#region checksum policy
+
+// ChecksumPolicy defines which blocks returned by a pipeline get their checksum recalculated.
+type ChecksumPolicy int
+
+const (
+	// ChecksumModified recalculates the checksum of the blocks modified or inserted by the transformers, if they have one.
+	// It is the default policy.
+	ChecksumModified ChecksumPolicy = iota
+
+	// ChecksumKeep never recalculates the checksums, the transformers are responsible of them.
+	ChecksumKeep
+
+	// ChecksumAll recalculates the checksum of every block returned, and adds it to the blocks without one.
+	ChecksumAll
+)
+
+// String returns the name of the policy.
+func (c ChecksumPolicy) String() string {
+	switch c {
+	case ChecksumModified:
+		return "modified"
+	case ChecksumKeep:
+		return "keep"
+	case ChecksumAll:
+		return "all"
+	}
+
+	return fmt.Sprintf("checksum policy(%d)", int(c))
+}
+
+//#endregion
+//#region pipeline configuration
+
+// PipelineConfigurer defines the options of a pipeline.
+type PipelineConfigurer interface {
+	// Set if the checksums of the blocks are recalculated
+	SetChecksums(update bool) error
+
+	// Set which blocks get their checksum recalculated
+	SetChecksumPolicy(policy ChecksumPolicy) error
+
+	// Set the callback that receives the progress of the pipeline
+	SetProgress(progress document.ProgressCallbackable) error
+}
+
+// PipelineConfigurationCallbackable is the signature of the callbacks used to configure a pipeline.
+type PipelineConfigurationCallbackable func(config PipelineConfigurer) error
+
+// pipelineConfigurator implements PipelineConfigurer.
+type pipelineConfigurator struct {
+	checksums ChecksumPolicy
+	progress  document.ProgressCallbackable
+}
+
+// SetChecksums defines if the checksum of each block modified by the transformers is recalculated, if the block has one.
+// It is a shortcut of SetChecksumPolicy with ChecksumModified or ChecksumKeep.
+// If this method isn't called, by default the checksums are recalculated.
+func (pc *pipelineConfigurator) SetChecksums(update bool) error {
+	pc.checksums = ChecksumKeep
+	if update {
+		pc.checksums = ChecksumModified
+	}
+
+	return nil
+}
+
+// SetChecksumPolicy defines which blocks returned by the last transformer get their checksum recalculated.
+// A block is modified if it was inserted by a transformer or its line number, command or parameters changed,
+// so the transformers don't need to update the checksums themselves.
+// If this method isn't called, by default it is ChecksumModified.
+func (pc *pipelineConfigurator) SetChecksumPolicy(policy ChecksumPolicy) error {
+	switch policy {
+	case ChecksumModified, ChecksumKeep, ChecksumAll:
+	default:
+		return fmt.Errorf("failed to set checksum policy, unknown value %d", policy)
+	}
+
+	pc.checksums = policy
+
+	return nil
+}
+
+// SetProgress defines the callback that receives the progress of the pipeline. Doesn't accept nil.
+// The lines reported are the lines of the source document processed.
+// If this method isn't called, by default the progress isn't reported.
+func (pc *pipelineConfigurator) SetProgress(progress document.ProgressCallbackable) error {
+	if progress == nil {
+		return fmt.Errorf("failed to set progress, the callback mustn't be nil")
+	}
+
+	pc.progress = progress
+
+	return nil
+}
+
+//#endregion
+//#region pipeline struct
+
+// Pipeline applies a chain of transformers to each block of a document, in order.
+//
+// The blocks returned by a transformer are received by the next one, and the blocks returned by the last one are the result.
+// A pipeline can be run many times, but not concurrently.
+type Pipeline struct {
+	// transformers applied in order
+	transformers []Transformer
+
+	// blocks whose checksum is recalculated
+	checksums ChecksumPolicy
+
+	// callback that receives the progress, it is nil if the progress isn't reported
+	progress document.ProgressCallbackable
+}
+
+// Run transforms the document and returns a new document with the result.
+//
+// The source document shares its blocks with the result, so it mustn't be used after the transformation.
+// The lines that aren't modified keep their original bytes, if they were parsed in round-trip mode.
+func (p *Pipeline) Run(d *document.Document) (*document.Document, error) {
+	lines := make([]document.Line, 0, d.LineCount())
+
+	err := p.run(d, func(l document.Line) error {
+		lines = append(lines, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return document.NewFromLines(lines...), nil
+}
+
+// RunTo transforms the document and exports the result to a writer as each block is transformed, without storing it.
+//
+// The writer isn't flushed. The source document shares its blocks with the result, so it mustn't be used after the transformation.
+func (p *Pipeline) RunTo(d *document.Document, w *document.Writer) error {
+	if w == nil {
+		return fmt.Errorf("failed to run pipeline, the writer mustn't be nil")
+	}
+
+	return p.run(d, w.WriteLine)
+}
+
+// run transforms the document and sends each line of the result to emit.
+func (p *Pipeline) run(d *document.Document, emit func(l document.Line) error) error {
+
+	// the default options are always valid
+	layers, _ := d.Layers()
+
+	// input state of each transformer
+	states := make([]document.ModalState, len(p.transformers))
+
+	// position of the block in the source document, shared by all transformers
+	context := State{Index: -1, Layer: -1, Object: -1}
+
+	emitBlocks := func(blocks []block.Blocker, source block.Blocker, snapshot string) error {
+		if err := p.updateChecksums(blocks, source, snapshot, context.Index); err != nil {
+			return err
+		}
+
+		for _, b := range blocks {
+			if err := emit(document.Line{Block: b}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := d.Walk(func(i int, l document.Line) error {
+		if p.progress != nil && i > 0 && i%document.PROGRESS_INTERVAL == 0 {
+			p.progress(document.Progress{Lines: i, TotalLines: d.LineCount()})
+		}
+
+		if !l.IsBlock() {
+			// the blocks retained by the transformers keep their position relative to the lines without gcode
+			blocks, err := p.flush(states, context)
+			if err != nil {
+				return err
+			}
+
+			if err := emitBlocks(blocks, nil, ""); err != nil {
+				return err
+			}
+
+			if !p.keepLine(l.Text) {
+				return nil
+			}
+
+			return emit(l)
+		}
+
+		context.Index++
+		for context.Layer+1 < len(layers) && layers[context.Layer+1].Start <= context.Index {
+			context.Layer++
+		}
+
+		if object, ok := objectStarted(l.Block); ok {
+			context.Object = object
+		}
+
+		// the export of the source block detects if it was modified, the blocks retained are considered modified when they are released
+		snapshot := ""
+		if p.checksums == ChecksumModified {
+			snapshot = l.Block.ToLine(checksumSnapshotFormat)
+		}
+
+		blocks, err := p.transform([]block.Blocker{l.Block}, 0, states, context)
+		if err != nil {
+			return err
+		}
+
+		// the line is kept if its block wasn't replaced, so it keeps its original bytes
+		if len(blocks) == 1 && blocks[0] == l.Block {
+			if err := p.updateChecksums(blocks, l.Block, snapshot, context.Index); err != nil {
+				return err
+			}
+
+			return emit(l)
+		}
+
+		return emitBlocks(blocks, l.Block, snapshot)
+	})
+	if err != nil {
+		return err
+	}
+
+	blocks, err := p.flush(states, context)
+	if err != nil {
+		return err
+	}
+
+	if err := emitBlocks(blocks, nil, ""); err != nil {
+		return err
+	}
+
+	if p.progress != nil {
+		p.progress(document.Progress{Lines: d.LineCount(), TotalLines: d.LineCount()})
+	}
+
+	return nil
+}
+
+// transform applies the transformers from the position first to the blocks received and returns the result.
+// The context contains the position of the block in the source document.
+func (p *Pipeline) transform(blocks []block.Blocker, first int, states []document.ModalState, context State) ([]block.Blocker, error) {
+	index := context.Index
+
+	for t := first; t < len(p.transformers); t++ {
+		var transformed []block.Blocker
+
+		for _, b := range blocks {
+			// the state is updated before applying the transformer, because it can modify the block received
+			state := context
+			state.ModalState = states[t].Clone()
+			states[t].Apply(b)
+			state.After = states[t].Clone()
+
+			result, err := p.transformers[t].Apply(b, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply transformer %d to block %d: %w", t, index, err)
+			}
+
+			if err := checkBlocks(result); err != nil {
+				return nil, fmt.Errorf("failed to apply transformer %d to block %d: %w", t, index, err)
+			}
+
+			transformed = append(transformed, result...)
+		}
+
+		blocks = transformed
+	}
+
+	return blocks, nil
+}
+
+// updateChecksums recalculates the checksums of the blocks returned by the last transformer, according to the policy.
+// The source is the block received by the first transformer and the snapshot its export, the other blocks are considered modified.
+func (p *Pipeline) updateChecksums(blocks []block.Blocker, source block.Blocker, snapshot string, index int) error {
+	for _, b := range blocks {
+		switch p.checksums {
+		case ChecksumKeep:
+			continue
+		case ChecksumModified:
+			if b.Checksum() == nil || (b == source && b.ToLine(checksumSnapshotFormat) == snapshot) {
+				continue
+			}
+		}
+
+		if err := b.UpdateChecksum(); err != nil {
+			return fmt.Errorf("failed to update checksum of block %d: %w", index, err)
+		}
+	}
+
+	return nil
+}
+
+// flush releases the blocks retained by the transformers that implement Flusher, in order,
+// and applies the transformers that follow each one to the blocks released.
+func (p *Pipeline) flush(states []document.ModalState, context State) ([]block.Blocker, error) {
+	var flushed []block.Blocker
+
+	for t, transformer := range p.transformers {
+		flusher, ok := transformer.(Flusher)
+		if !ok {
+			continue
+		}
+
+		released, err := flusher.Flush()
+		if err != nil {
+			return nil, fmt.Errorf("failed to flush transformer %d: %w", t, err)
+		}
+
+		if err := checkBlocks(released); err != nil {
+			return nil, fmt.Errorf("failed to flush transformer %d: %w", t, err)
+		}
+
+		// the following transformers can retain the blocks released, until they are flushed too
+		if len(released) > 0 {
+			transformed, err := p.transform(released, t+1, states, context)
+			if err != nil {
+				return nil, err
+			}
+			flushed = append(flushed, transformed...)
+		}
+	}
+
+	return flushed, nil
+}
+
+// keepLine returns false if some transformer that implements LineFilter removes the line without gcode.
+func (p *Pipeline) keepLine(text string) bool {
+	for _, transformer := range p.transformers {
+		if filter, ok := transformer.(LineFilter); ok && !filter.KeepLine(text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//#endregion
+//#region constructor
+
+// NewPipeline returns a new pipeline that applies the transformers in the order received.
+//
+// It returns an error if some transformer is nil or some option is invalid.
+func NewPipeline(transformers []Transformer, options ...PipelineConfigurationCallbackable) (*Pipeline, error) {
+
+	for i, transformer := range transformers {
+		if transformer == nil {
+			return nil, fmt.Errorf("failed to create pipeline, the transformer %d is nil", i)
+		}
+	}
+
+	configurator := &pipelineConfigurator{
+		checksums: ChecksumModified,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	p := &Pipeline{
+		transformers: make([]Transformer, len(transformers)),
+		checksums:    configurator.checksums,
+		progress:     configurator.progress,
+	}
+	copy(p.transformers, transformers)
+
+	return p, nil
+}
+
+//#endregion
+//#region private functions
+
+// checkBlocks returns an error if some block returned by a transformer is nil.
+func checkBlocks(blocks []block.Blocker) error {
+	for _, b := range blocks {
+		if b == nil {
+			return fmt.Errorf("it returned a nil block")
+		}
+	}
+
+	return nil
+}
+
+// objectStarted returns the object started by an M486 S block, or -1 if the block ends the current object.
+// It returns false if the block doesn't start or end an object.
+func objectStarted(b block.Blocker) (int, bool) {
+	if b.Command().String() != "M486" {
+		return 0, false
+	}
+
+	object, ok := Parameter(b, 'S')
+	if !ok {
+		return 0, false
+	}
+
+	if object < 0 {
+		return -1, true
+	}
+
+	return int(object), true
+}
+
+//#endregion
@@ -0,0 +1,405 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+// transformerFunc adapts a function to the Transformer interface.
+type transformerFunc func(b block.Blocker, state State) ([]block.Blocker, error)
+
+func (f transformerFunc) Apply(b block.Blocker, state State) ([]block.Blocker, error) {
+	return f(b, state)
+}
+
+// remove removes the blocks with the command required.
+func remove(command string) Transformer {
+	return transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		if b.Command().String() == command {
+			return nil, nil
+		}
+		return []block.Blocker{b}, nil
+	})
+}
+
+// insertAfter inserts a block after each block with the command required.
+func insertAfter(command string, expression string) Transformer {
+	return transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		if b.Command().String() != command {
+			return []block.Blocker{b}, nil
+		}
+
+		inserted, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return []block.Blocker{b, inserted}, nil
+	})
+}
+
+// insertBefore inserts a block before each block.
+func insertBefore(expression string) Transformer {
+	return transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		inserted, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return []block.Blocker{inserted, b}, nil
+	})
+}
+
+// replace replaces the blocks with the command required by a new block.
+func replace(command string, expression string) Transformer {
+	return transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		if b.Command().String() != command {
+			return []block.Blocker{b}, nil
+		}
+
+		replaced, err := gcodeblock.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		return []block.Blocker{replaced}, nil
+	})
+}
+
+func TestPipeline_Run(t *testing.T) {
+
+	source := "N1 G28*18\n; comment\nN2 G91*19\nN3 M107*38\nN4 G1 X10 E1*1\n"
+
+	cases := map[string]struct {
+		transformers []Transformer
+		options      []PipelineConfigurationCallbackable
+		want         string
+		valid        bool
+	}{
+		"without transformers": {
+			want:  source,
+			valid: true,
+		},
+		"remove": {
+			transformers: []Transformer{remove("M107")},
+			want:         "N1 G28*18\n; comment\nN2 G91*19\nN4 G1 X10 E1*1\n",
+			valid:        true,
+		},
+		"insert and replace chained": {
+			transformers: []Transformer{insertAfter("G28", "N9 G4 P100"), replace("G4", "N9 G4 P200")},
+			want:         "N1 G28*18\nN9 G4 P200\n; comment\nN2 G91*19\nN3 M107*38\nN4 G1 X10 E1*1\n",
+			valid:        true,
+		},
+		"without checksums": {
+			transformers: []Transformer{insertAfter("G28", "N9 G4 P100*0")},
+			options: []PipelineConfigurationCallbackable{
+				func(config PipelineConfigurer) error {
+					return config.SetChecksums(false)
+				},
+			},
+			want:  "N1 G28*18\nN9 G4 P100*0\n; comment\nN2 G91*19\nN3 M107*38\nN4 G1 X10 E1*1\n",
+			valid: true,
+		},
+		"checksums updated": {
+			transformers: []Transformer{insertAfter("G28", "N9 G4 P100*0")},
+			want:         "N1 G28*18\nN9 G4 P100*101\n; comment\nN2 G91*19\nN3 M107*38\nN4 G1 X10 E1*1\n",
+			valid:        true,
+		},
+		"failing transformer": {
+			transformers: []Transformer{transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+				return nil, errors.New("failed")
+			})},
+		},
+		"nil block": {
+			transformers: []Transformer{transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+				return []block.Blocker{nil}, nil
+			})},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := NewPipeline(tc.transformers, tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_State(t *testing.T) {
+
+	source := "G28\n;LAYER:0\nG1 X10 Z0.2 F1200\nG91\n;LAYER:1\nG1 X5 Z0.2\n"
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	var seen []State
+	record := transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		seen = append(seen, state)
+		return []block.Blocker{b}, nil
+	})
+
+	// the second transformer receives the block inserted by the first one
+	p, err := NewPipeline([]Transformer{insertAfter("G91", "G1 X1"), record})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if _, err := p.Run(d); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	want := []struct {
+		index, layer int
+		beforeX      float64
+		afterX       float64
+		relative     bool
+	}{
+		{0, -1, 0, 0, false},
+		{1, 0, 0, 10, false},
+		{2, 0, 10, 10, false},
+		{2, 0, 10, 11, true},
+		{3, 1, 11, 16, true},
+	}
+
+	if len(seen) != len(want) {
+		t.Errorf("got %d blocks, want %d", len(seen), len(want))
+		return
+	}
+
+	for i, w := range want {
+		s := seen[i]
+		if s.Index != w.index || s.Layer != w.layer || s.Position.X != w.beforeX || s.After.Position.X != w.afterX || s.Relative != w.relative {
+			t.Errorf("block %d: got index %d, layer %d, X from %v to %v and relative %v, want %+v", i, s.Index, s.Layer, s.Position.X, s.After.Position.X, s.Relative, w)
+		}
+	}
+}
+
+// delay retains the blocks with the command required until they are flushed.
+type delay struct {
+	command  string
+	retained []block.Blocker
+}
+
+func (d *delay) Apply(b block.Blocker, state State) ([]block.Blocker, error) {
+	if b.Command().String() != d.command {
+		return []block.Blocker{b}, nil
+	}
+
+	d.retained = append(d.retained, b)
+	return nil, nil
+}
+
+func (d *delay) Flush() ([]block.Blocker, error) {
+	released := d.retained
+	d.retained = nil
+	return released, nil
+}
+
+func TestPipeline_Flush(t *testing.T) {
+
+	source := "G1 X1\nG1 X2\nM400\n; comment\nG1 X3\nM400\n"
+
+	d, err := document.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	// the blocks released by the first transformer pass through the second one
+	p, err := NewPipeline([]Transformer{&delay{command: "G1"}, insertAfter("G1", "M117")})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	got, err := p.Run(d)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	want := "M400\nG1 X1\nM117\nG1 X2\nM117\n; comment\nM400\nG1 X3\nM117\n"
+	if got.String() != want {
+		t.Errorf("got %q, want %q", got.String(), want)
+	}
+}
+
+func TestPipeline_Checksums(t *testing.T) {
+
+	// the checksum of the first block is wrong, so it shows if it was recalculated
+	source := "N1 G28*0\nN2 G1 X10*113\nG4 P100\n"
+
+	// move doubles the X of the G1 blocks
+	move := transformerFunc(func(b block.Blocker, state State) ([]block.Blocker, error) {
+		if x, ok := Parameter(b, 'X'); ok {
+			if _, err := SetParameter(b, 'X', x*2); err != nil {
+				return nil, err
+			}
+		}
+		return []block.Blocker{b}, nil
+	})
+
+	cases := map[string]struct {
+		transformers []Transformer
+		policy       ChecksumPolicy
+		want         string
+	}{
+		"modified": {
+			transformers: []Transformer{move},
+			policy:       ChecksumModified,
+			want:         "N1 G28*0\nN2 G1 X20*80\nG4 P100\n",
+		},
+		"retained blocks are modified": {
+			transformers: []Transformer{&delay{command: "G28"}},
+			policy:       ChecksumModified,
+			want:         "N2 G1 X10*113\nG4 P100\nN1 G28*18\n",
+		},
+		"keep": {
+			transformers: []Transformer{move},
+			policy:       ChecksumKeep,
+			want:         "N1 G28*0\nN2 G1 X20*113\nG4 P100\n",
+		},
+		"all": {
+			transformers: []Transformer{move},
+			policy:       ChecksumAll,
+			want:         "N1 G28*18\nN2 G1 X20*80\nG4 P100*50\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := NewPipeline(tc.transformers, func(config PipelineConfigurer) error {
+				return config.SetChecksumPolicy(tc.policy)
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("unknown policy", func(t *testing.T) {
+		_, err := NewPipeline(nil, func(config PipelineConfigurer) error {
+			return config.SetChecksumPolicy(ChecksumPolicy(9))
+		})
+		if err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
+
+func TestPipeline_RunTo(t *testing.T) {
+
+	source := "G28 ; home\r\n  G1 X1\r\nM107\r\n"
+
+	d, err := document.Parse(strings.NewReader(source), func(config document.ParseConfigurer) error {
+		return config.SetRoundTrip(true)
+	})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	var progress []document.Progress
+	p, err := NewPipeline([]Transformer{remove("M107")}, func(config PipelineConfigurer) error {
+		return config.SetProgress(func(p document.Progress) {
+			progress = append(progress, p)
+		})
+	})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	var output bytes.Buffer
+	w, err := document.NewWriter(&output)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if err := p.RunTo(d, w); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+	w.Flush()
+
+	if output.String() != "G28 ; home\r\n  G1 X1\r\n" {
+		t.Errorf("got %q, want the lines kept with their original bytes", output.String())
+	}
+
+	if len(progress) != 1 || progress[0].Percent() != 100 {
+		t.Errorf("got progress %+v, want a single call at 100%%", progress)
+	}
+
+	if err := p.RunTo(d, nil); err == nil {
+		t.Errorf("got error nil with a nil writer, want error not nil")
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+
+	if _, err := NewPipeline([]Transformer{nil}); err == nil {
+		t.Errorf("got error nil with a nil transformer, want error not nil")
+	}
+
+	_, err := NewPipeline(nil, func(config PipelineConfigurer) error {
+		return config.SetProgress(nil)
+	})
+	if err == nil {
+		t.Errorf("got error nil with a nil progress, want error not nil")
+	}
+}
@@ -0,0 +1,182 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region transformer func
+
+// TransformerFunc adapts a function that modifies a block in place to the Transformer interface.
+//
+// It is the simplest way to write a custom post-processing step: the function receives each block with its state,
+// and the block is returned as the only result, modified or not. The blocks can't be removed or inserted with it.
+type TransformerFunc func(b block.Blocker, state State) error
+
+// Apply calls the function and returns the block received.
+func (f TransformerFunc) Apply(b block.Blocker, state State) ([]block.Blocker, error) {
+	if err := f(b, state); err != nil {
+		return nil, err
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+//#endregion
+//#region plugin
+
+// Plugin defines a custom transformer that can be registered in a catalog and created by its name,
+// like the post-processing steps selected by the user of a command line tool.
+type Plugin interface {
+	// Name returns the identifier of the plugin in a catalog, like "pressure-advance".
+	Name() string
+
+	// Description returns a short explanation of the plugin, for the help of the tools.
+	Description() string
+
+	// New returns a new transformer configured with the options received, like the flags of a command line tool.
+	// It returns an error if some option is unknown or invalid.
+	New(options map[string]string) (Transformer, error)
+}
+
+// PluginFactory is the signature of the functions that create the transformers of a plugin.
+type PluginFactory func(options map[string]string) (Transformer, error)
+
+// plugin implements Plugin with a factory function.
+type plugin struct {
+	name        string
+	description string
+	factory     PluginFactory
+}
+
+// Name returns the identifier of the plugin.
+func (p *plugin) Name() string {
+	return p.name
+}
+
+// Description returns the explanation of the plugin.
+func (p *plugin) Description() string {
+	return p.description
+}
+
+// New calls the factory of the plugin.
+func (p *plugin) New(options map[string]string) (Transformer, error) {
+	return p.factory(options)
+}
+
+// NewPlugin returns a plugin that creates its transformers with the factory received.
+//
+// It returns an error if the name is empty or the factory is nil.
+func NewPlugin(name string, description string, factory PluginFactory) (Plugin, error) {
+	if name == "" {
+		return nil, fmt.Errorf("failed to create plugin, the name mustn't be empty")
+	}
+
+	if factory == nil {
+		return nil, fmt.Errorf("failed to create plugin %s, the factory mustn't be nil", name)
+	}
+
+	return &plugin{name: name, description: description, factory: factory}, nil
+}
+
+//#endregion
+//#region catalog
+
+// Catalog stores plugins indexed by their names. It is safe for concurrent use.
+//
+// The zero value is an empty catalog ready to use.
+type Catalog struct {
+	mu      sync.RWMutex
+	plugins map[string]Plugin
+}
+
+// Register adds a plugin to the catalog.
+//
+// It returns an error if the plugin is nil, its name is empty or there is another plugin with the same name.
+func (c *Catalog) Register(p Plugin) error {
+	if p == nil {
+		return fmt.Errorf("failed to register plugin, it mustn't be nil")
+	}
+
+	name := p.Name()
+	if name == "" {
+		return fmt.Errorf("failed to register plugin, the name mustn't be empty")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.plugins[name]; ok {
+		return fmt.Errorf("failed to register plugin %s, the name is already registered", name)
+	}
+
+	if c.plugins == nil {
+		c.plugins = map[string]Plugin{}
+	}
+	c.plugins[name] = p
+
+	return nil
+}
+
+// Lookup returns the plugin registered with the name required. It returns false if it doesn't exist.
+func (c *Catalog) Lookup(name string) (Plugin, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.plugins[name]
+
+	return p, ok
+}
+
+// Names returns the names of the plugins registered in ascending order.
+func (c *Catalog) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.plugins))
+	for name := range c.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New returns a new transformer created by the plugin registered with the name required.
+//
+// It returns an error if the plugin doesn't exist or it can't create the transformer.
+func (c *Catalog) New(name string, options map[string]string) (Transformer, error) {
+	p, ok := c.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("failed to create transformer, the plugin %s isn't registered", name)
+	}
+
+	t, err := p.New(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transformer of the plugin %s: %w", name, err)
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("failed to create transformer of the plugin %s, it returned nil", name)
+	}
+
+	return t, nil
+}
+
+// defaultCatalog is the catalog used by the package functions.
+var defaultCatalog = &Catalog{}
+
+// DefaultCatalog returns the catalog shared by the whole program, where the plugins usually register themselves from an init function.
+func DefaultCatalog() *Catalog {
+	return defaultCatalog
+}
+
+// Register adds a plugin to the default catalog.
+func Register(p Plugin) error {
+	return defaultCatalog.Register(p)
+}
+
+//#endregion
@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+// offsetPlugin creates a transformer that adds the option "x" to the X of every block.
+func offsetPlugin(name string) Plugin {
+	p, _ := NewPlugin(name, "offset X", func(options map[string]string) (Transformer, error) {
+		offset, err := strconv.ParseFloat(options["x"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid option x: %w", err)
+		}
+
+		return TransformerFunc(func(b block.Blocker, state State) error {
+			if x, ok := Parameter(b, 'X'); ok {
+				_, err := SetParameter(b, 'X', x+offset)
+				return err
+			}
+			return nil
+		}), nil
+	})
+
+	return p
+}
+
+func TestTransformerFunc(t *testing.T) {
+	d, err := document.Parse(strings.NewReader("G28\nG1 X10\n"))
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	transformer, err := offsetPlugin("offset").New(map[string]string{"x": "5"})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	p, err := NewPipeline([]Transformer{transformer})
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	got, err := p.Run(d)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if got.String() != "G28\nG1 X15\n" {
+		t.Errorf("got %q, want %q", got.String(), "G28\nG1 X15\n")
+	}
+
+	failing := TransformerFunc(func(b block.Blocker, state State) error {
+		return errors.New("failed")
+	})
+
+	if _, err := failing.Apply(d.Blocks()[0], State{}); err == nil {
+		t.Errorf("got error nil, want error not nil")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := &Catalog{}
+
+	for _, name := range []string{"offset", "another"} {
+		if err := catalog.Register(offsetPlugin(name)); err != nil {
+			t.Errorf("got error %v, want error nil", err)
+		}
+	}
+
+	if err := catalog.Register(offsetPlugin("offset")); err == nil {
+		t.Errorf("got error nil registering a duplicated name, want error not nil")
+	}
+
+	if err := catalog.Register(nil); err == nil {
+		t.Errorf("got error nil registering a nil plugin, want error not nil")
+	}
+
+	if got := catalog.Names(); !reflect.DeepEqual(got, []string{"another", "offset"}) {
+		t.Errorf("got names %v, want [another offset]", got)
+	}
+
+	p, ok := catalog.Lookup("offset")
+	if !ok || p.Description() != "offset X" {
+		t.Errorf("got plugin %v, %v, want the offset plugin", p, ok)
+	}
+
+	cases := map[string]struct {
+		name    string
+		options map[string]string
+		valid   bool
+	}{
+		"valid":          {name: "offset", options: map[string]string{"x": "1"}, valid: true},
+		"unknown plugin": {name: "unknown"},
+		"invalid option": {name: "offset", options: map[string]string{"x": "a"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			transformer, err := catalog.New(tc.name, tc.options)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil || transformer == nil {
+				t.Errorf("got transformer %v and error %v, want a transformer and error nil", transformer, err)
+			}
+		})
+	}
+}
+
+func TestNewPlugin(t *testing.T) {
+	factory := func(options map[string]string) (Transformer, error) { return nil, nil }
+
+	if _, err := NewPlugin("", "", factory); err == nil {
+		t.Errorf("got error nil with an empty name, want error not nil")
+	}
+
+	if _, err := NewPlugin("nil", "", nil); err == nil {
+		t.Errorf("got error nil with a nil factory, want error not nil")
+	}
+
+	p, err := NewPlugin("nil", "", factory)
+	if err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	catalog := &Catalog{}
+	if err := catalog.Register(p); err != nil {
+		t.Errorf("got error %v, want error nil", err)
+		return
+	}
+
+	if _, err := catalog.New("nil", nil); err == nil {
+		t.Errorf("got error nil with a factory that returns nil, want error not nil")
+	}
+}
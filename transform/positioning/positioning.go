@@ -0,0 +1,243 @@
+// positioning package contains a transformer that converts a file between absolute and relative positioning.
+//
+// The XYZ axes can be converted between G90 and G91, and the extruder between M82 and M83, independently.
+// The coordinates of each move are calculated again from the position simulated along the file,
+// so the machine follows the same path. The files normalized to absolute positioning are simpler to transform.
+//
+// The mode commands of the file are replaced by the ones of the modes required, and the modes are commanded before the first move.
+// G90 and G91 also select the mode of the extruder, like Marlin does, so an M82 or M83 is added after them when it is required.
+package positioning
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// COORDINATE_DECIMALS is the number of decimals of the coordinates calculated.
+	COORDINATE_DECIMALS = 6
+)
+
+//#region mode
+
+// Mode is the positioning required for some axes.
+type Mode int
+
+const (
+	// ModeKeep keeps the positioning of the file.
+	ModeKeep Mode = iota
+
+	// ModeAbsolute converts the coordinates to absolute positions.
+	ModeAbsolute
+
+	// ModeRelative converts the coordinates to displacements from the current position.
+	ModeRelative
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeKeep:
+		return "keep"
+	case ModeAbsolute:
+		return "absolute"
+	case ModeRelative:
+		return "relative"
+	}
+
+	return fmt.Sprintf("mode(%d)", int(m))
+}
+
+// relative returns true if the mode is relative, or the current mode if it keeps it.
+func (m Mode) relative(current bool) bool {
+	if m == ModeKeep {
+		return current
+	}
+
+	return m == ModeRelative
+}
+
+//#endregion
+//#region positioning struct
+
+// Positioning is a transformer that converts the coordinates of the moves between absolute and relative positioning.
+type Positioning struct {
+	// modes required for the XYZ axes and the extruder
+	positioning Mode
+	extrusion   Mode
+
+	// modes in effect in the output, only valid if they were commanded
+	relative, relativeExtrusion bool
+	known, knownExtrusion       bool
+}
+
+// Apply converts the coordinates of the block and replaces the mode commands.
+// It can return mode commands before or after the block.
+func (p *Positioning) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	var blocks []block.Blocker
+	var err error
+
+	switch command := b.Command().String(); command {
+	case "G90", "G91":
+		relative := p.positioning.relative(state.After.Relative)
+		if blocks, err = replaceMode(b, command == "G91", relative, "G90", "G91"); err != nil {
+			return nil, err
+		}
+		p.setPositioning(relative)
+
+		blocks, err = p.ensureExtrusion(blocks, p.extrusion.relative(state.After.RelativeExtrusion), true)
+	case "M82", "M83":
+		relative := p.extrusion.relative(state.After.RelativeExtrusion)
+
+		// a command replaced is redundant if the output is already in the mode required
+		if relative != (command == "M83") && p.knownExtrusion && p.relativeExtrusion == relative {
+			return nil, nil
+		}
+
+		if blocks, err = replaceMode(b, command == "M83", relative, "M82", "M83"); err != nil {
+			return nil, err
+		}
+		p.relativeExtrusion, p.knownExtrusion = relative, true
+	case "G0", "G1", "G2", "G3":
+		blocks, err = p.move(b, state)
+	default:
+		blocks = []block.Blocker{b}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// move commands the modes required before a move and converts its coordinates.
+func (p *Positioning) move(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	var blocks []block.Blocker
+
+	relative := p.positioning.relative(state.Relative)
+	if p.positioning != ModeKeep && (!p.known || p.relative != relative) {
+		command, err := modeBlock(relative, "G90", "G91")
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, command)
+		p.setPositioning(relative)
+	}
+
+	relativeExtrusion := p.extrusion.relative(state.RelativeExtrusion)
+	blocks, err := p.ensureExtrusion(blocks, relativeExtrusion, p.extrusion != ModeKeep)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after := state.Position, state.After.Position
+	axes := []struct {
+		word          byte
+		before, after float64
+		convert       bool
+		relative      bool
+	}{
+		{'X', before.X, after.X, relative != state.Relative, relative},
+		{'Y', before.Y, after.Y, relative != state.Relative, relative},
+		{'Z', before.Z, after.Z, relative != state.Relative, relative},
+		{'E', before.E, after.E, relativeExtrusion != state.RelativeExtrusion, relativeExtrusion},
+	}
+
+	for _, axis := range axes {
+		if !axis.convert {
+			continue
+		}
+
+		if _, ok := transform.Parameter(b, axis.word); !ok {
+			continue
+		}
+
+		value := axis.after
+		if axis.relative {
+			value = axis.after - axis.before
+		}
+
+		if _, err := transform.SetParameter(b, axis.word, numeric.Round(value, COORDINATE_DECIMALS)); err != nil {
+			return nil, fmt.Errorf("failed to convert block %s: %w", b, err)
+		}
+	}
+
+	return append(blocks, b), nil
+}
+
+// ensureExtrusion appends the command of the extrusion mode required if the output is in another mode.
+// If the mode of the output is unknown, the command is only appended if force is true.
+func (p *Positioning) ensureExtrusion(blocks []block.Blocker, required bool, force bool) ([]block.Blocker, error) {
+	if p.knownExtrusion && p.relativeExtrusion == required || !p.knownExtrusion && !force {
+		return blocks, nil
+	}
+
+	command, err := modeBlock(required, "M82", "M83")
+	if err != nil {
+		return nil, err
+	}
+	p.relativeExtrusion, p.knownExtrusion = required, true
+
+	return append(blocks, command), nil
+}
+
+// setPositioning records the positioning of the output, G90 and G91 select the extrusion mode too.
+func (p *Positioning) setPositioning(relative bool) {
+	p.relative, p.known = relative, true
+	p.relativeExtrusion, p.knownExtrusion = relative, true
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that converts the XYZ axes and the extruder to the modes received.
+func New(positioning Mode, extrusion Mode) (*Positioning, error) {
+	for _, mode := range []Mode{positioning, extrusion} {
+		switch mode {
+		case ModeKeep, ModeAbsolute, ModeRelative:
+		default:
+			return nil, fmt.Errorf("failed to create positioning, unknown mode %d", mode)
+		}
+	}
+
+	return &Positioning{positioning: positioning, extrusion: extrusion}, nil
+}
+
+//#endregion
+//#region private functions
+
+// replaceMode returns the mode command received if it selects the mode required, else a new command that selects it.
+func replaceMode(b block.Blocker, current bool, required bool, absolute string, relative string) ([]block.Blocker, error) {
+	if current == required {
+		return []block.Blocker{b}, nil
+	}
+
+	command, err := modeBlock(required, absolute, relative)
+	if err != nil {
+		return nil, err
+	}
+
+	return []block.Blocker{command}, nil
+}
+
+// modeBlock returns a new block with the command of the mode required.
+func modeBlock(relative bool, absoluteCommand string, relativeCommand string) (block.Blocker, error) {
+	command := absoluteCommand
+	if relative {
+		command = relativeCommand
+	}
+
+	b, err := gcodeblock.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mode command %s: %w", command, err)
+	}
+
+	return b, nil
+}
+
+//#endregion
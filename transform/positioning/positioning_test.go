@@ -0,0 +1,89 @@
+package positioning
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestPositioning(t *testing.T) {
+
+	cases := map[string]struct {
+		source      string
+		positioning Mode
+		extrusion   Mode
+		want        string
+	}{
+		"relative to absolute": {
+			source:      "G28\nG91\nG1 X10 Y5 E1\nG1 X0.1 Z0.2 E0.5\nG92 E0\nG1 X-5 E1\n",
+			positioning: ModeAbsolute,
+			extrusion:   ModeAbsolute,
+			want:        "G28\nG90\nG1 X10 Y5 E1\nG1 X10.1 Z0.2 E1.5\nG92 E0\nG1 X5.1 E1\n",
+		},
+		"absolute to relative": {
+			source:      "G28\nG1 X10 Y5 E1 F1200\nG1 X10.1 Z0.2 E1.5\nG2 X20.1 Y5 I5 J0 E2\n",
+			positioning: ModeRelative,
+			extrusion:   ModeRelative,
+			want:        "G28\nG91\nG1 X10 Y5 E1 F1200\nG1 X0.1 Z0.2 E0.5\nG2 X10.0 Y0 I5 J0 E0.5\n",
+		},
+		"only extrusion": {
+			source:      "G90\nM82\nG1 X10 E1\nG1 X20 E3\nM83\nG1 X30 E1\n",
+			positioning: ModeKeep,
+			extrusion:   ModeRelative,
+			want:        "G90\nM83\nG1 X10 E1\nG1 X20 E2\nM83\nG1 X30 E1\n",
+		},
+		"only positioning": {
+			// G91 selects the relative extrusion too, so it is kept after G90
+			source:      "M82\nG91\nG1 X10 E1\nG90\nG1 X20 E2\n",
+			positioning: ModeAbsolute,
+			extrusion:   ModeKeep,
+			want:        "M82\nG90\nM83\nG1 X10 E1\nG90\nG1 X20 E2\n",
+		},
+		"keep": {
+			source:      "G91\nG1 X10 E1\nG90\nM83\nG1 X20 E2\n",
+			positioning: ModeKeep,
+			extrusion:   ModeKeep,
+			want:        "G91\nG1 X10 E1\nG90\nM83\nG1 X20 E2\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			positioning, err := New(tc.positioning, tc.extrusion)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{positioning})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid mode", func(t *testing.T) {
+		if _, err := New(Mode(5), ModeKeep); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
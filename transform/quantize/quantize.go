@@ -0,0 +1,234 @@
+// quantize package contains a transformer that rounds the coordinates to a resolution, like 0.001 mm,
+// and removes the coordinates that don't move an axis.
+//
+// The rounded positions are tracked, so the error never accumulates: every position of the result is at most
+// half the resolution away from the position of the source, also in relative positioning, where the distances are
+// calculated between the rounded positions. The resolutions are expressed in the units of the file.
+package quantize
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// DEFAULT_RESOLUTION is the resolution of the X, Y and Z coordinates, if it isn't configured.
+	DEFAULT_RESOLUTION = 0.001
+
+	// DEFAULT_EXTRUSION_RESOLUTION is the resolution of the E coordinates, if it isn't configured.
+	DEFAULT_EXTRUSION_RESOLUTION = 0.00001
+)
+
+//#region quantize configuration
+
+// QuantizeConfigurer defines the options of the quantization.
+type QuantizeConfigurer interface {
+	// Set the resolution of the X, Y and Z coordinates
+	SetResolution(resolution float64) error
+
+	// Set the resolution of the E coordinates
+	SetExtrusionResolution(resolution float64) error
+}
+
+// QuantizeConfigurationCallbackable is the signature of the callbacks used to configure the quantization.
+type QuantizeConfigurationCallbackable func(config QuantizeConfigurer) error
+
+// quantizeConfigurator implements QuantizeConfigurer.
+type quantizeConfigurator struct {
+	resolution          float64
+	extrusionResolution float64
+}
+
+// SetResolution defines the resolution of the X, Y and Z coordinates. It must be positive and finite.
+// If this method isn't called, by default it is DEFAULT_RESOLUTION.
+func (qc *quantizeConfigurator) SetResolution(resolution float64) error {
+	if resolution <= 0 || math.IsInf(resolution, 0) || math.IsNaN(resolution) {
+		return fmt.Errorf("failed to set resolution, it must be positive and finite: %v", resolution)
+	}
+
+	qc.resolution = resolution
+
+	return nil
+}
+
+// SetExtrusionResolution defines the resolution of the E coordinates. It must be positive and finite.
+// If this method isn't called, by default it is DEFAULT_EXTRUSION_RESOLUTION.
+func (qc *quantizeConfigurator) SetExtrusionResolution(resolution float64) error {
+	if resolution <= 0 || math.IsInf(resolution, 0) || math.IsNaN(resolution) {
+		return fmt.Errorf("failed to set extrusion resolution, it must be positive and finite: %v", resolution)
+	}
+
+	qc.extrusionResolution = resolution
+
+	return nil
+}
+
+//#endregion
+//#region quantize struct
+
+// Quantize is a transformer that rounds the coordinates of the moves and G92, and removes the ones that don't move an axis.
+//
+// The arcs are rounded, but their coordinates are never removed, because an arc without end point is a full circle.
+// A move is removed if it only commanded coordinates that don't move.
+type Quantize struct {
+	// resolution of the X, Y and Z coordinates, and of the E coordinates
+	resolution          float64
+	extrusionResolution float64
+
+	// rounded position of the axes of the result, in the order X, Y, Z and E
+	position [4]float64
+
+	// true if the position of each axis is known, because it was commanded in absolute positioning, set by G92 or homed
+	known [4]bool
+}
+
+// axes are the words of the coordinates quantized, in the order of the positions tracked.
+var axes = [4]byte{'X', 'Y', 'Z', 'E'}
+
+// Apply rounds the coordinates of the block and removes the ones that don't move an axis.
+func (q *Quantize) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	command := b.Command().String()
+
+	switch command {
+	case "G92":
+		for i, word := range axes {
+			value, ok := transform.Parameter(b, word)
+			if !ok {
+				continue
+			}
+
+			rounded := q.round(i, value)
+			if _, err := transform.SetParameter(b, word, rounded); err != nil {
+				return nil, fmt.Errorf("failed to quantize block %s: %w", b, err)
+			}
+
+			q.position[i], q.known[i] = rounded, true
+		}
+
+		return []block.Blocker{b}, nil
+
+	case "G28":
+		homed := false
+		for i, word := range axes[:3] {
+			if _, ok := transform.Parameter(b, word); ok {
+				q.position[i], q.known[i] = 0, true
+				homed = true
+			}
+		}
+
+		if !homed {
+			for i := range axes[:3] {
+				q.position[i], q.known[i] = 0, true
+			}
+		}
+
+		return []block.Blocker{b}, nil
+
+	case "G0", "G1", "G2", "G3":
+	default:
+		return []block.Blocker{b}, nil
+	}
+
+	arc := command == "G2" || command == "G3"
+	target := [4]float64{state.After.Position.X, state.After.Position.Y, state.After.Position.Z, state.After.Position.E}
+
+	var redundant []byte
+	for i, word := range axes {
+		if _, ok := transform.Parameter(b, word); !ok {
+			continue
+		}
+
+		relative := state.Relative
+		if word == 'E' {
+			relative = state.RelativeExtrusion
+		}
+
+		rounded := q.round(i, target[i])
+
+		value := rounded
+		if relative {
+			value = q.round(i, rounded-q.position[i])
+		}
+
+		// in relative positioning a zero distance doesn't move, even if the position isn't known
+		moves := rounded != q.position[i] || !(q.known[i] || relative)
+
+		q.position[i] = rounded
+		q.known[i] = q.known[i] || !relative
+
+		if !moves && !arc {
+			redundant = append(redundant, word)
+			continue
+		}
+
+		if _, err := transform.SetParameter(b, word, value); err != nil {
+			return nil, fmt.Errorf("failed to quantize block %s: %w", b, err)
+		}
+	}
+
+	if len(redundant) == 0 {
+		return []block.Blocker{b}, nil
+	}
+
+	if len(redundant) == len(b.Parameters()) {
+		return nil, nil
+	}
+
+	for _, word := range redundant {
+		removed, _, err := transform.RemoveParameter(b, word)
+		if err != nil {
+			return nil, fmt.Errorf("failed to quantize block %s: %w", b, err)
+		}
+		b = removed
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// round returns the value rounded to the resolution of the axis, without the noise of the binary representation.
+func (q *Quantize) round(axis int, value float64) float64 {
+	resolution := q.resolution
+	if axes[axis] == 'E' {
+		resolution = q.extrusionResolution
+	}
+
+	rounded := math.Round(value/resolution) * resolution
+
+	// the decimals of the resolution, so 0.1 * 3 is 0.3 instead of 0.30000000000000004
+	decimals := math.Ceil(-math.Log10(resolution)) + 1
+	if decimals < 0 {
+		return rounded
+	}
+
+	factor := math.Pow(10, decimals)
+
+	return math.Round(rounded*factor) / factor
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that rounds the coordinates to the resolutions configured.
+func New(options ...QuantizeConfigurationCallbackable) (*Quantize, error) {
+
+	configurator := &quantizeConfigurator{
+		resolution:          DEFAULT_RESOLUTION,
+		extrusionResolution: DEFAULT_EXTRUSION_RESOLUTION,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Quantize{
+		resolution:          configurator.resolution,
+		extrusionResolution: configurator.extrusionResolution,
+	}, nil
+}
+
+//#endregion
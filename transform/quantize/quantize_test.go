@@ -0,0 +1,96 @@
+package quantize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestQuantize(t *testing.T) {
+
+	cases := map[string]struct {
+		source     string
+		resolution float64
+		want       string
+	}{
+		"absolute": {
+			source: "G1 X10.00012 Y5.4996 F1200\nG1 X10.0004 Y7\nG1 X10.0004 Y5.49999\n",
+			want:   "G1 X10.0 Y5.5 F1200\nG1 Y7\nG1 Y5.5\n",
+		},
+		"unknown position": {
+			source: "G1 X0 Y0\nG1 X0 Y0\n",
+			want:   "G1 X0 Y0\n",
+		},
+		"relative without accumulated error": {
+			source:     "G91\nG1 X0.04\nG1 X0.04\nG1 X0.04\nG1 X0.04\nG1 X0.01 Y1\n",
+			resolution: 0.1,
+			want:       "G91\nG1 X0.1\nG1 X0.1\nG1 Y1\n",
+		},
+		"extrusion": {
+			source: "M83\nG1 X1 E0.0123456\nG1 X2 E0.000001\n",
+			want:   "M83\nG1 X1 E0.01235\nG1 X2\n",
+		},
+		"arcs keep coordinates": {
+			source: "G1 X10 Y0\nG2 X10.0001 Y0 I5 J0\n",
+			want:   "G1 X10 Y0\nG2 X10.0 Y0 I5 J0\n",
+		},
+		"position set and homed": {
+			source: "G28\nG1 Z0\nG92 X5.00049\nG1 X5\n",
+			want:   "G28\nG92 X5.0\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var options []QuantizeConfigurationCallbackable
+			if tc.resolution != 0 {
+				options = append(options, func(config QuantizeConfigurer) error {
+					return config.SetResolution(tc.resolution)
+				})
+			}
+
+			quantize, err := New(options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{quantize})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid resolutions", func(t *testing.T) {
+		options := []QuantizeConfigurationCallbackable{
+			func(config QuantizeConfigurer) error { return config.SetResolution(0) },
+			func(config QuantizeConfigurer) error { return config.SetExtrusionResolution(-1) },
+		}
+
+		for i, option := range options {
+			if _, err := New(option); err == nil {
+				t.Errorf("got error nil with the option %d, want error not nil", i)
+			}
+		}
+	})
+}
@@ -0,0 +1,216 @@
+// renumber package contains a transformer that rewrites the line number of each block with a sequence.
+//
+// It is the streaming counterpart of document.Document.Renumber: it numbers the blocks as they pass through a pipeline,
+// so it can be combined with other transformers and the result exported without storing it.
+// It must be the last transformer of the pipeline, so the blocks inserted by the others are numbered too.
+package renumber
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode/addressablegcode"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region renumber configuration
+
+// RenumberConfigurer defines the options of the renumbering.
+type RenumberConfigurer interface {
+	// Set the line number of the first block
+	SetStart(start uint32) error
+
+	// Set the increment between consecutive line numbers
+	SetStep(step uint32) error
+
+	// Set if the lines without gcode are skipped by the sequence
+	SetSkipComments(skip bool) error
+
+	// Set the maximum line number, after which the sequence starts again
+	SetWrap(limit uint32) error
+
+	// Set if the checksum of each block is recalculated or removed
+	SetChecksum(enabled bool) error
+}
+
+// RenumberConfigurationCallbackable is the signature of the callbacks used to configure the renumbering.
+type RenumberConfigurationCallbackable func(config RenumberConfigurer) error
+
+// renumberConfigurator implements RenumberConfigurer.
+type renumberConfigurator struct {
+	start        uint32
+	step         uint32
+	skipComments bool
+	wrap         uint32
+	wraps        bool
+	checksum     bool
+}
+
+// SetStart defines the line number of the first block.
+// If this method isn't called, by default the first block is N1, the line expected by Marlin after a reset.
+func (rc *renumberConfigurator) SetStart(start uint32) error {
+	rc.start = start
+
+	return nil
+}
+
+// SetStep defines the increment between consecutive line numbers. It must be positive.
+// If this method isn't called, by default the step is 1, required to stream to the firmwares.
+func (rc *renumberConfigurator) SetStep(step uint32) error {
+	if step == 0 {
+		return fmt.Errorf("failed to set step, it must be positive")
+	}
+
+	rc.step = step
+
+	return nil
+}
+
+// SetSkipComments defines if the lines without gcode, like the comments and the blank lines, are skipped by the sequence.
+// If it is false, each one of them consumes a line number, so the numbers follow the lines of the file, like some CNC controllers expect.
+// If this method isn't called, by default they are skipped.
+func (rc *renumberConfigurator) SetSkipComments(skip bool) error {
+	rc.skipComments = skip
+
+	return nil
+}
+
+// SetWrap defines the maximum line number, like 9999 for the controllers that only accept four digits.
+// The block that would exceed it is numbered with the start again. It must be greater than or equal to the start.
+// The firmwares that check the sequence, like Marlin, must be told the new number with M110.
+// If this method isn't called, by default the sequence doesn't wrap and it fails when it overflows.
+func (rc *renumberConfigurator) SetWrap(limit uint32) error {
+	rc.wrap = limit
+	rc.wraps = true
+
+	return nil
+}
+
+// SetChecksum defines if the checksum of each block is recalculated, or removed if it is false.
+// If this method isn't called, by default the checksums are recalculated.
+func (rc *renumberConfigurator) SetChecksum(enabled bool) error {
+	rc.checksum = enabled
+
+	return nil
+}
+
+//#endregion
+//#region renumber struct
+
+// Renumber is a transformer that sets the line number of each block with a sequence.
+type Renumber struct {
+	// first line number of the sequence and the increment between consecutive line numbers
+	start uint32
+	step  uint32
+
+	// true if the lines without gcode don't consume line numbers
+	skipComments bool
+
+	// maximum line number of the sequence, it is only used if wraps is true
+	wrap  uint32
+	wraps bool
+
+	// true if the checksums are recalculated, else they are removed
+	checksum bool
+
+	// line number of the next line, it is greater than the wrap when the sequence must start again
+	next uint64
+}
+
+// Apply sets the next line number of the sequence to the block and recalculates or removes its checksum.
+//
+// It returns an error if the block doesn't implement block.Editor, the sequence overflows without wrap or the checksum can't be calculated.
+func (r *Renumber) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	e, ok := b.(block.Editor)
+	if !ok {
+		return nil, fmt.Errorf("failed to renumber block %s, it doesn't implement block.Editor", b)
+	}
+
+	number, err := r.take()
+	if err != nil {
+		return nil, fmt.Errorf("failed to renumber block %s: %w", b, err)
+	}
+
+	lineNumber, err := addressablegcode.New('N', number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create line number %d of the block %s: %w", number, b, err)
+	}
+
+	e.SetLineNumber(lineNumber)
+
+	if !r.checksum {
+		e.SetChecksum(nil)
+		return []block.Blocker{b}, nil
+	}
+
+	if err := b.UpdateChecksum(); err != nil {
+		return nil, fmt.Errorf("failed to update checksum of the block %s: %w", b, err)
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// KeepLine keeps all lines without gcode, but they consume a line number if they aren't skipped.
+func (r *Renumber) KeepLine(text string) bool {
+	if !r.skipComments {
+		// the overflow is reported by the next block
+		_, _ = r.take()
+	}
+
+	return true
+}
+
+// take returns the next line number of the sequence and advances it.
+func (r *Renumber) take() (uint32, error) {
+	if r.wraps && r.next > uint64(r.wrap) {
+		r.next = uint64(r.start)
+	}
+
+	if r.next > math.MaxUint32 {
+		return 0, fmt.Errorf("the line number %d overflows", r.next)
+	}
+
+	number := uint32(r.next)
+	r.next += uint64(r.step)
+
+	return number, nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that numbers the blocks from N1 with step 1, adding a checksum to each one.
+//
+// It returns an error if some option is invalid, or the wrap is less than the start.
+func New(options ...RenumberConfigurationCallbackable) (*Renumber, error) {
+
+	configurator := &renumberConfigurator{
+		start:        1,
+		step:         1,
+		skipComments: true,
+		checksum:     true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	if configurator.wraps && configurator.wrap < configurator.start {
+		return nil, fmt.Errorf("failed to create renumber, the wrap %d is less than the start %d", configurator.wrap, configurator.start)
+	}
+
+	return &Renumber{
+		start:        configurator.start,
+		step:         configurator.step,
+		skipComments: configurator.skipComments,
+		wrap:         configurator.wrap,
+		wraps:        configurator.wraps,
+		checksum:     configurator.checksum,
+		next:         uint64(configurator.start),
+	}, nil
+}
+
+//#endregion
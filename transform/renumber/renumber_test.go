@@ -0,0 +1,135 @@
+package renumber
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestRenumber(t *testing.T) {
+
+	source := "G28\n; comment\nG1 X10\nG1 X20\nM84\n"
+
+	cases := map[string]struct {
+		source  string
+		options []RenumberConfigurationCallbackable
+		want    string
+	}{
+		"default": {
+			source: source,
+			want:   "N1 G28*18\n; comment\nN2 G1 X10*83\nN3 G1 X20*81\nN4 M84*27\n",
+		},
+		"start and step without checksums": {
+			source: "N7 G28*0\nG1 X10\n",
+			options: []RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetStart(10) },
+				func(config RenumberConfigurer) error { return config.SetStep(10) },
+				func(config RenumberConfigurer) error { return config.SetChecksum(false) },
+			},
+			want: "N10 G28\nN20 G1 X10\n",
+		},
+		"comments consume line numbers": {
+			source: source,
+			options: []RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetSkipComments(false) },
+				func(config RenumberConfigurer) error { return config.SetChecksum(false) },
+			},
+			want: "N1 G28\n; comment\nN3 G1 X10\nN4 G1 X20\nN5 M84\n",
+		},
+		"wrap": {
+			source: source,
+			options: []RenumberConfigurationCallbackable{
+				func(config RenumberConfigurer) error { return config.SetStart(0) },
+				func(config RenumberConfigurer) error { return config.SetWrap(2) },
+				func(config RenumberConfigurer) error { return config.SetChecksum(false) },
+			},
+			want: "N0 G28\n; comment\nN1 G1 X10\nN2 G1 X20\nN0 M84\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			renumber, err := New(tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{renumber})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+
+			for i, b := range got.Blocks() {
+				if b.Checksum() == nil {
+					continue
+				}
+
+				if ok, err := b.VerifyChecksum(); err != nil || !ok {
+					t.Errorf("got invalid checksum in block %d: %s", i, b)
+				}
+			}
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		d, err := document.Parse(strings.NewReader("G28\nG1 X10\n"))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		renumber, err := New(func(config RenumberConfigurer) error { return config.SetStart(4294967295) })
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		p, err := transform.NewPipeline([]transform.Transformer{renumber})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, err := p.Run(d); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		options := []RenumberConfigurationCallbackable{
+			func(config RenumberConfigurer) error { return config.SetStep(0) },
+			func(config RenumberConfigurer) error {
+				if err := config.SetStart(10); err != nil {
+					return err
+				}
+				return config.SetWrap(9)
+			},
+		}
+
+		for i, option := range options {
+			if _, err := New(option); err == nil {
+				t.Errorf("got error nil with the option %d, want error not nil", i)
+			}
+		}
+	})
+}
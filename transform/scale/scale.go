@@ -0,0 +1,341 @@
+// scale package contains a transformer that resizes a print, with a factor for all axes or one per axis.
+//
+// The coordinates of the moves executed in absolute positioning are scaled around an origin,
+// and the displacements of the relative moves are scaled by the factors.
+// Optionally, the extrusion is scaled in proportion to the length of each move, and the arc offsets are scaled with the axes.
+//
+// Some commands can't be scaled safely, like the firmware retractions or the bed mesh commands.
+// They are reported as warnings, or as errors in strict mode.
+package scale
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/state"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region scale configuration
+
+// ScaleConfigurer defines the options of the scaling.
+type ScaleConfigurer interface {
+	// Set the point that keeps its position
+	SetOrigin(x float64, y float64, z float64) error
+
+	// Set if the extrusion is scaled in proportion to the length of the moves
+	SetExtrusion(enabled bool) error
+
+	// Set if the arc offsets are scaled
+	SetArcs(enabled bool) error
+
+	// Set if the commands that can't be scaled safely return an error
+	SetStrict(strict bool) error
+}
+
+// ScaleConfigurationCallbackable is the signature of the callbacks used to configure the scaling.
+type ScaleConfigurationCallbackable func(config ScaleConfigurer) error
+
+// scaleConfigurator implements ScaleConfigurer.
+type scaleConfigurator struct {
+	origin    [3]float64
+	extrusion bool
+	arcs      bool
+	strict    bool
+}
+
+// SetOrigin defines the point that keeps its position, like the center of the print. The coordinates must be finite.
+// If this method isn't called, by default it is the origin of the coordinates.
+func (sc *scaleConfigurator) SetOrigin(x float64, y float64, z float64) error {
+	for _, v := range []float64{x, y, z} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("failed to set origin, the coordinates must be finite: %v", v)
+		}
+	}
+
+	sc.origin = [3]float64{x, y, z}
+
+	return nil
+}
+
+// SetExtrusion defines if the extrusion of each move is scaled in proportion to the length of the move,
+// so the amount of material per millimeter doesn't change. The length is measured in the three axes,
+// and the arcs that don't keep their shape, like a helix whose linear axis has another factor, are reported and their extrusion isn't scaled.
+// If this method isn't called, by default the extrusion isn't modified.
+func (sc *scaleConfigurator) SetExtrusion(enabled bool) error {
+	sc.extrusion = enabled
+
+	return nil
+}
+
+// SetArcs defines if the offsets of the arcs to their center, I, J and K, are scaled with the axes, and their radius R too.
+// An arc scaled with different factors for the axes of its plane isn't an arc anymore, so those arcs are reported.
+// If this method isn't called, by default the arcs are scaled.
+func (sc *scaleConfigurator) SetArcs(enabled bool) error {
+	sc.arcs = enabled
+
+	return nil
+}
+
+// SetStrict defines if the commands that can't be scaled safely return an error instead of a warning.
+// If this method isn't called, by default they are reported as warnings.
+func (sc *scaleConfigurator) SetStrict(strict bool) error {
+	sc.strict = strict
+
+	return nil
+}
+
+//#endregion
+//#region scale struct
+
+// Scale is a transformer that scales the coordinates of the blocks.
+type Scale struct {
+	// factors and origin of each axis, in order X, Y and Z
+	factors [3]float64
+	origin  [3]float64
+
+	// options of the scaling
+	extrusion bool
+	arcs      bool
+	strict    bool
+
+	// position of the extruder in the output, seeded with the position of the input by the first move that extrudes
+	e      float64
+	seeded bool
+
+	// commands that couldn't be scaled safely
+	warnings []transform.Warning
+}
+
+// Warnings returns the commands that couldn't be scaled safely, in the order found.
+func (s *Scale) Warnings() []transform.Warning {
+	warnings := make([]transform.Warning, len(s.warnings))
+	copy(warnings, s.warnings)
+
+	return warnings
+}
+
+// Apply scales the coordinates of the block, it always returns the block received.
+func (s *Scale) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	var err error
+
+	switch command := b.Command().String(); command {
+	case "G0", "G1", "G2", "G3":
+		err = s.scaleMove(b, state)
+	case "G92":
+		err = s.scaleCoordinates(b, false)
+		if e, ok := transform.Parameter(b, 'E'); ok {
+			s.e, s.seeded = e, true
+		}
+	case "G10", "G11":
+		if s.extrusion {
+			err = s.warn(b, state, "the length of the firmware retraction isn't scaled")
+		}
+	case "G29", "M420":
+		err = s.warn(b, state, "the bed mesh refers to the bed, it can't be scaled")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// scaleMove scales the coordinates, the arc offsets and the extrusion of a move.
+func (s *Scale) scaleMove(b block.Blocker, state transform.State) error {
+	if err := s.scaleCoordinates(b, state.Relative); err != nil {
+		return err
+	}
+
+	arc := b.Command().String() == "G2" || b.Command().String() == "G3"
+	if arc && s.arcs {
+		if err := s.scaleArc(b, state); err != nil {
+			return err
+		}
+	}
+
+	if !s.extrusion {
+		return nil
+	}
+
+	if _, ok := transform.Parameter(b, 'E'); !ok {
+		return nil
+	}
+
+	ratio, err := s.extrusionRatio(b, state, arc)
+	if err != nil {
+		return err
+	}
+
+	// the file could start without a G92 E0, so the output starts where the input is
+	if !s.seeded {
+		s.e, s.seeded = state.Position.E, true
+	}
+
+	delta := (state.After.Position.E - state.Position.E) * ratio
+	s.e += delta
+
+	value := delta
+	if !state.RelativeExtrusion {
+		value = s.e
+	}
+
+	if _, err := transform.SetParameter(b, 'E', value); err != nil {
+		return fmt.Errorf("failed to scale extrusion of block %s: %w", b, err)
+	}
+
+	return nil
+}
+
+// extrusionRatio returns the ratio between the length of a move after and before the scaling.
+//
+// The length of a line is measured in the three axes. An arc scaled with the same factor for the axes of its plane
+// keeps its shape, so its length is scaled by that factor, unless it is a helix whose linear axis has another factor.
+// The extrusion of those helices isn't scaled and they are reported.
+func (s *Scale) extrusionRatio(b block.Blocker, state transform.State, arc bool) (float64, error) {
+	if arc {
+		axes := planeAxes(state)
+		factor := s.factors[axes[0]]
+
+		linear := 3 - axes[0] - axes[1]
+		moved := position(state.After.Position, linear) != position(state.Position, linear)
+
+		if factor != s.factors[axes[1]] || (moved && factor != s.factors[linear]) {
+			return 1, s.warn(b, state, "the extrusion of an arc scaled with different factors isn't scaled")
+		}
+
+		return factor, nil
+	}
+
+	var length, scaled float64
+	for i := range s.factors {
+		d := position(state.After.Position, i) - position(state.Position, i)
+		length += d * d
+		scaled += d * d * s.factors[i] * s.factors[i]
+	}
+
+	if length == 0 {
+		return 1, nil
+	}
+
+	return math.Sqrt(scaled / length), nil
+}
+
+// scaleCoordinates scales the X, Y and Z parameters, as displacements if relative is true, else as coordinates around the origin.
+func (s *Scale) scaleCoordinates(b block.Blocker, relative bool) error {
+	for i, word := range []byte{'X', 'Y', 'Z'} {
+		value, ok := transform.Parameter(b, word)
+		if !ok {
+			continue
+		}
+
+		if relative {
+			value *= s.factors[i]
+		} else {
+			value = s.origin[i] + (value-s.origin[i])*s.factors[i]
+		}
+
+		if _, err := transform.SetParameter(b, word, value); err != nil {
+			return fmt.Errorf("failed to scale block %s: %w", b, err)
+		}
+	}
+
+	return nil
+}
+
+// scaleArc scales the offsets and the radius of an arc.
+func (s *Scale) scaleArc(b block.Blocker, state transform.State) error {
+	axes := planeAxes(state)
+	if s.factors[axes[0]] != s.factors[axes[1]] {
+		if err := s.warn(b, state, "an arc scaled with different factors for the axes of its plane isn't an arc"); err != nil {
+			return err
+		}
+	}
+
+	for i, word := range []byte{'I', 'J', 'K'} {
+		value, ok := transform.Parameter(b, word)
+		if !ok {
+			continue
+		}
+
+		if _, err := transform.SetParameter(b, word, value*s.factors[i]); err != nil {
+			return fmt.Errorf("failed to scale arc %s: %w", b, err)
+		}
+	}
+
+	if r, ok := transform.Parameter(b, 'R'); ok {
+		if _, err := transform.SetParameter(b, 'R', r*s.factors[axes[0]]); err != nil {
+			return fmt.Errorf("failed to scale arc %s: %w", b, err)
+		}
+	}
+
+	return nil
+}
+
+// warn records a command that can't be scaled safely, it returns an error in strict mode.
+func (s *Scale) warn(b block.Blocker, state transform.State, message string) error {
+	if s.strict {
+		return fmt.Errorf("failed to scale block %s: %s", b, message)
+	}
+
+	s.warnings = append(s.warnings, transform.Warning{Index: state.Index, Block: b.String(), Message: message})
+
+	return nil
+}
+
+// planeAxes returns the indexes of the axes of the plane of the arcs, in order X, Y and Z.
+func planeAxes(t transform.State) [2]int {
+	switch t.Plane {
+	case state.PlaneZX:
+		return [2]int{2, 0}
+	case state.PlaneYZ:
+		return [2]int{1, 2}
+	}
+
+	return [2]int{0, 1}
+}
+
+// position returns the coordinate of an axis, in order X, Y and Z.
+func position(p state.Position, axis int) float64 {
+	return [3]float64{p.X, p.Y, p.Z}[axis]
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that scales the X, Y and Z axes by the factors received. The factors must be positive and finite.
+func New(x float64, y float64, z float64, options ...ScaleConfigurationCallbackable) (*Scale, error) {
+
+	for _, factor := range []float64{x, y, z} {
+		if !(factor > 0) || math.IsInf(factor, 0) {
+			return nil, fmt.Errorf("failed to create scale, the factor must be positive and finite: %v", factor)
+		}
+	}
+
+	configurator := &scaleConfigurator{
+		arcs: true,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Scale{
+		factors:   [3]float64{x, y, z},
+		origin:    configurator.origin,
+		extrusion: configurator.extrusion,
+		arcs:      configurator.arcs,
+		strict:    configurator.strict,
+	}, nil
+}
+
+// NewUniform returns a new transformer that scales all axes by the same factor.
+func NewUniform(factor float64, options ...ScaleConfigurationCallbackable) (*Scale, error) {
+	return New(factor, factor, factor, options...)
+}
+
+//#endregion
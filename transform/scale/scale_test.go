@@ -0,0 +1,211 @@
+package scale
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/blocktest"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestScale(t *testing.T) {
+
+	cases := map[string]struct {
+		source   string
+		factors  [3]float64
+		options  []ScaleConfigurationCallbackable
+		want     string
+		warnings int
+		valid    bool
+	}{
+		"uniform": {
+			source:  "G90\nG1 X10 Y20 Z0.2 E1\nG2 X30 Y20 I10 J0 E2\n",
+			factors: [3]float64{2, 2, 1},
+			want:    "G90\nG1 X20 Y40 Z0.2 E1\nG2 X60 Y40 I20 J0 E2\n",
+			valid:   true,
+		},
+		"origin": {
+			source:  "G1 X110 Y90\nG92 X100\n",
+			factors: [3]float64{2, 2, 1},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetOrigin(100, 100, 0) },
+			},
+			want:  "G1 X120 Y80\nG92 X100\n",
+			valid: true,
+		},
+		"relative": {
+			source:  "G91\nG1 X10 Y-5\n",
+			factors: [3]float64{1.5, 2, 1},
+			want:    "G91\nG1 X15 Y-10\n",
+			valid:   true,
+		},
+		"absolute extrusion": {
+			source:  "M82\nG1 X10 E1\nG1 X20 E2\nG1 Z1\nG92 E0\nG1 X10 E0.5\n",
+			factors: [3]float64{2, 2, 2},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:  "M82\nG1 X20 E2\nG1 X40 E4\nG1 Z2\nG92 E0\nG1 X20 E1.0\n",
+			valid: true,
+		},
+		"relative extrusion": {
+			source:  "M83\nG1 X10 Y0 E1\nG1 X10 Y10 E1\n",
+			factors: [3]float64{3, 1, 1},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:  "M83\nG1 X30 Y0 E3\nG1 X30 Y10 E1\n",
+			valid: true,
+		},
+		"extrusion modes mixed": {
+			source:  "M83\nG1 X10 E1\nM82\nG1 X20 E3\n",
+			factors: [3]float64{2, 2, 2},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:  "M83\nG1 X20 E2\nM82\nG1 X40 E6\n",
+			valid: true,
+		},
+		"extrusion in Z": {
+			source:  "M83\nG1 Z1 E1\n",
+			factors: [3]float64{2, 2, 3},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:  "M83\nG1 Z3 E3\n",
+			valid: true,
+		},
+		"extrusion of an arc": {
+			source:  "M83\nG2 X20 Y0 I10 J0 E1\n",
+			factors: [3]float64{2, 2, 1},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:  "M83\nG2 X40 Y0 I20 J0 E2\n",
+			valid: true,
+		},
+		"extrusion of a helix": {
+			source:  "M83\nG2 X20 Y0 Z1 I10 J0 E1\n",
+			factors: [3]float64{2, 2, 1},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:     "M83\nG2 X40 Y0 Z1 I20 J0 E1\n",
+			warnings: 1,
+			valid:    true,
+		},
+		"arc in plane YZ": {
+			source:  "G19\nG2 Y20 Z0 R10\n",
+			factors: [3]float64{1, 3, 3},
+			want:    "G19\nG2 Y60 Z0 R30\n",
+			valid:   true,
+		},
+		"arcs untouched": {
+			source:  "G2 X20 Y0 I10 J0\n",
+			factors: [3]float64{2, 2, 2},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetArcs(false) },
+			},
+			want:  "G2 X40 Y0 I10 J0\n",
+			valid: true,
+		},
+		"warnings": {
+			source:  "G29\nG10\nG2 X20 Y0 R10\n",
+			factors: [3]float64{2, 1, 1},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetExtrusion(true) },
+			},
+			want:     "G29\nG10\nG2 X40 Y0 R20\n",
+			warnings: 3,
+			valid:    true,
+		},
+		"strict": {
+			source:  "G28\nG29\n",
+			factors: [3]float64{2, 2, 2},
+			options: []ScaleConfigurationCallbackable{
+				func(config ScaleConfigurer) error { return config.SetStrict(true) },
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			scale, err := New(tc.factors[0], tc.factors[1], tc.factors[2], tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{scale})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if !tc.valid {
+				if err == nil {
+					t.Errorf("got error nil, want error not nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+
+			if len(scale.Warnings()) != tc.warnings {
+				t.Errorf("got warnings %v, want %d", scale.Warnings(), tc.warnings)
+			}
+		})
+	}
+
+	t.Run("extrusion seeded from the state", func(t *testing.T) {
+		scale, err := NewUniform(2, func(config ScaleConfigurer) error { return config.SetExtrusion(true) })
+		if err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		b := blocktest.Parse(t, "G1 X10 E11")[0]
+		s := transform.State{
+			ModalState: document.ModalState{Position: document.Position{E: 10}},
+			After:      document.ModalState{Position: document.Position{X: 10, E: 11}},
+		}
+
+		if _, err := scale.Apply(b, s); err != nil {
+			t.Fatalf("got error %v, want error nil", err)
+		}
+
+		if got := b.String(); got != "G1 X20 E12" {
+			t.Errorf("got %s, want G1 X20 E12", got)
+		}
+	})
+
+	t.Run("invalid factors", func(t *testing.T) {
+		for _, factor := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+			if _, err := NewUniform(factor); err == nil {
+				t.Errorf("got error nil with factor %v, want error not nil", factor)
+			}
+		}
+
+		_, err := NewUniform(2, func(config ScaleConfigurer) error {
+			return config.SetOrigin(math.NaN(), 0, 0)
+		})
+		if err == nil {
+			t.Errorf("got error nil with an invalid origin, want error not nil")
+		}
+	})
+}
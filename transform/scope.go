@@ -0,0 +1,196 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+)
+
+//#region scope configuration
+
+// ScopeConfigurer defines the blocks that a scoped transformer receives.
+type ScopeConfigurer interface {
+	// Set the range of layers in scope
+	SetLayers(first int, last int) error
+
+	// Set the range of heights in scope
+	SetHeights(minZ float64, maxZ float64) error
+
+	// Set the region of the XY plane in scope
+	SetRegion(minX float64, minY float64, maxX float64, maxY float64) error
+
+	// Set the objects in scope
+	SetObjects(objects ...int) error
+}
+
+// ScopeConfigurationCallbackable is the signature of the callbacks used to configure a scope.
+type ScopeConfigurationCallbackable func(config ScopeConfigurer) error
+
+// scopeConfigurator implements ScopeConfigurer.
+type scopeConfigurator struct {
+	scope scope
+}
+
+// SetLayers defines the range of layers in scope, from first to last inclusive, starting at zero.
+// The blocks before the first layer don't belong to any layer, so they are out of scope.
+// If this method isn't called, by default all layers are in scope.
+func (sc *scopeConfigurator) SetLayers(first int, last int) error {
+	if first < 0 || last < first {
+		return fmt.Errorf("failed to set layers, the range [%d, %d] isn't valid", first, last)
+	}
+
+	sc.scope.layers = true
+	sc.scope.firstLayer, sc.scope.lastLayer = first, last
+
+	return nil
+}
+
+// SetHeights defines the range of heights in scope, from minZ to maxZ inclusive.
+// A block is in scope if the height of the nozzle after executing it is inside the range.
+// If this method isn't called, by default all heights are in scope.
+func (sc *scopeConfigurator) SetHeights(minZ float64, maxZ float64) error {
+	if math.IsNaN(minZ) || math.IsNaN(maxZ) || maxZ < minZ {
+		return fmt.Errorf("failed to set heights, the range [%v, %v] isn't valid", minZ, maxZ)
+	}
+
+	sc.scope.heights = true
+	sc.scope.minZ, sc.scope.maxZ = minZ, maxZ
+
+	return nil
+}
+
+// SetRegion defines the rectangle of the XY plane in scope, its limits are inclusive.
+// A block is in scope if the positions of the nozzle before and after executing it are inside the rectangle.
+// If this method isn't called, by default the whole plane is in scope.
+func (sc *scopeConfigurator) SetRegion(minX float64, minY float64, maxX float64, maxY float64) error {
+	if math.IsNaN(minX) || math.IsNaN(minY) || math.IsNaN(maxX) || math.IsNaN(maxY) || maxX < minX || maxY < minY {
+		return fmt.Errorf("failed to set region, the rectangle [%v, %v]-[%v, %v] isn't valid", minX, minY, maxX, maxY)
+	}
+
+	sc.scope.region = true
+	sc.scope.minX, sc.scope.minY, sc.scope.maxX, sc.scope.maxY = minX, minY, maxX, maxY
+
+	return nil
+}
+
+// SetObjects defines the identifiers of the objects in scope, the objects are started by M486 S. It requires at least one object.
+// If this method isn't called, by default the blocks inside and outside the objects are in scope.
+func (sc *scopeConfigurator) SetObjects(objects ...int) error {
+	if len(objects) == 0 {
+		return fmt.Errorf("failed to set objects, it requires at least one object")
+	}
+
+	sc.scope.objects = map[int]bool{}
+	for _, object := range objects {
+		if object < 0 {
+			return fmt.Errorf("failed to set objects, the identifier can't be negative: %d", object)
+		}
+		sc.scope.objects[object] = true
+	}
+
+	return nil
+}
+
+//#endregion
+//#region scoped struct
+
+// scope stores the criteria of a scope, a block is in scope if it meets all of them.
+type scope struct {
+	layers                bool
+	firstLayer, lastLayer int
+
+	heights    bool
+	minZ, maxZ float64
+
+	region                 bool
+	minX, minY, maxX, maxY float64
+
+	// objects in scope, nil if all blocks are in scope
+	objects map[int]bool
+}
+
+// contains returns true if the block received with the state is in scope.
+func (s *scope) contains(state State) bool {
+	if s.layers && (state.Layer < s.firstLayer || state.Layer > s.lastLayer) {
+		return false
+	}
+
+	if s.heights && (state.After.Position.Z < s.minZ || state.After.Position.Z > s.maxZ) {
+		return false
+	}
+
+	if s.region && !(s.inRegion(state.Position.X, state.Position.Y) && s.inRegion(state.After.Position.X, state.After.Position.Y)) {
+		return false
+	}
+
+	if s.objects != nil && !s.objects[state.Object] {
+		return false
+	}
+
+	return true
+}
+
+// inRegion returns true if the point is inside the region.
+func (s *scope) inRegion(x float64, y float64) bool {
+	return x >= s.minX && x <= s.maxX && y >= s.minY && y <= s.maxY
+}
+
+// Scoped is a transformer that restricts another transformer to some layers, heights, region or objects.
+//
+// The blocks in scope are applied to the transformer, and the others pass through without changes.
+// If the transformer retains blocks, they are flushed before the first block out of scope, so the order of the blocks is kept.
+type Scoped struct {
+	// transformer restricted
+	transformer Transformer
+
+	// criteria of the blocks in scope
+	scope scope
+}
+
+// Apply applies the transformer to the block if it is in scope, else it returns the block received.
+func (s *Scoped) Apply(b block.Blocker, state State) ([]block.Blocker, error) {
+	if s.scope.contains(state) {
+		return s.transformer.Apply(b, state)
+	}
+
+	released, err := s.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(released, b), nil
+}
+
+// Flush releases the blocks retained by the transformer, if it implements Flusher.
+func (s *Scoped) Flush() ([]block.Blocker, error) {
+	flusher, ok := s.transformer.(Flusher)
+	if !ok {
+		return nil, nil
+	}
+
+	return flusher.Flush()
+}
+
+//#endregion
+//#region constructor
+
+// NewScoped returns a new transformer that applies the transformer received only to the blocks in scope.
+// Without options all blocks are in scope.
+func NewScoped(transformer Transformer, options ...ScopeConfigurationCallbackable) (*Scoped, error) {
+	if transformer == nil {
+		return nil, fmt.Errorf("failed to create scoped transformer, the transformer mustn't be nil")
+	}
+
+	configurator := &scopeConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Scoped{transformer: transformer, scope: configurator.scope}, nil
+}
+
+//#endregion
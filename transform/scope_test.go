@@ -0,0 +1,139 @@
+package transform
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+func TestScoped(t *testing.T) {
+
+	source := "G1 X0 Y0 Z5\n;LAYER:0\nG1 Z0.2\nM486 S0\nG1 X10 Y10\nM486 S1\nG1 X50 Y50\nM486 S-1\n;LAYER:1\nG1 Z0.4\nM486 S0\nG1 X10 Y10\nM486 S-1\n"
+
+	cases := map[string]struct {
+		options []ScopeConfigurationCallbackable
+		want    string
+	}{
+		"without options": {
+			want: "M400\nG1 X0 Y0 Z5\n;LAYER:0\nM400\nG1 Z0.2\nM400\nM486 S0\nM400\nG1 X10 Y10\nM400\nM486 S1\nM400\nG1 X50 Y50\nM400\nM486 S-1\n;LAYER:1\nM400\nG1 Z0.4\nM400\nM486 S0\nM400\nG1 X10 Y10\nM400\nM486 S-1\n",
+		},
+		"layers": {
+			options: []ScopeConfigurationCallbackable{
+				func(config ScopeConfigurer) error { return config.SetLayers(1, 1) },
+			},
+			want: "G1 X0 Y0 Z5\n;LAYER:0\nG1 Z0.2\nM486 S0\nG1 X10 Y10\nM486 S1\nG1 X50 Y50\nM486 S-1\n;LAYER:1\nM400\nG1 Z0.4\nM400\nM486 S0\nM400\nG1 X10 Y10\nM400\nM486 S-1\n",
+		},
+		"heights": {
+			options: []ScopeConfigurationCallbackable{
+				func(config ScopeConfigurer) error { return config.SetHeights(0, 0.3) },
+			},
+			want: "G1 X0 Y0 Z5\n;LAYER:0\nM400\nG1 Z0.2\nM400\nM486 S0\nM400\nG1 X10 Y10\nM400\nM486 S1\nM400\nG1 X50 Y50\nM400\nM486 S-1\n;LAYER:1\nG1 Z0.4\nM486 S0\nG1 X10 Y10\nM486 S-1\n",
+		},
+		"region": {
+			options: []ScopeConfigurationCallbackable{
+				func(config ScopeConfigurer) error { return config.SetRegion(20, 20, 60, 60) },
+			},
+			want: "G1 X0 Y0 Z5\n;LAYER:0\nG1 Z0.2\nM486 S0\nG1 X10 Y10\nM486 S1\nG1 X50 Y50\nM400\nM486 S-1\n;LAYER:1\nM400\nG1 Z0.4\nM400\nM486 S0\nG1 X10 Y10\nM486 S-1\n",
+		},
+		"objects": {
+			options: []ScopeConfigurationCallbackable{
+				func(config ScopeConfigurer) error { return config.SetObjects(1) },
+			},
+			want: "G1 X0 Y0 Z5\n;LAYER:0\nG1 Z0.2\nM486 S0\nG1 X10 Y10\nM400\nM486 S1\nM400\nG1 X50 Y50\nM486 S-1\n;LAYER:1\nG1 Z0.4\nM486 S0\nG1 X10 Y10\nM486 S-1\n",
+		},
+		"combined": {
+			options: []ScopeConfigurationCallbackable{
+				func(config ScopeConfigurer) error { return config.SetObjects(0) },
+				func(config ScopeConfigurer) error { return config.SetLayers(1, 3) },
+			},
+			want: "G1 X0 Y0 Z5\n;LAYER:0\nG1 Z0.2\nM486 S0\nG1 X10 Y10\nM486 S1\nG1 X50 Y50\nM486 S-1\n;LAYER:1\nG1 Z0.4\nM400\nM486 S0\nM400\nG1 X10 Y10\nM486 S-1\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			scoped, err := NewScoped(insertBefore("M400"), tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := NewPipeline([]Transformer{scoped})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("flush at the end of the scope", func(t *testing.T) {
+		d, err := document.Parse(strings.NewReader("M486 S0\nG1 X1\nG1 X2\nM486 S1\nG1 X3\n"))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		scoped, err := NewScoped(&delay{command: "G1"}, func(config ScopeConfigurer) error {
+			return config.SetObjects(0)
+		})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		p, err := NewPipeline([]Transformer{scoped})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		got, err := p.Run(d)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		want := "M486 S0\nG1 X1\nG1 X2\nM486 S1\nG1 X3\n"
+		if got.String() != want {
+			t.Errorf("got %q, want %q", got.String(), want)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := NewScoped(nil); err == nil {
+			t.Errorf("got error nil with a nil transformer, want error not nil")
+		}
+
+		options := []ScopeConfigurationCallbackable{
+			func(config ScopeConfigurer) error { return config.SetLayers(2, 1) },
+			func(config ScopeConfigurer) error { return config.SetHeights(math.NaN(), 1) },
+			func(config ScopeConfigurer) error { return config.SetRegion(10, 0, 0, 10) },
+			func(config ScopeConfigurer) error { return config.SetObjects() },
+			func(config ScopeConfigurer) error { return config.SetObjects(-1) },
+		}
+
+		for _, option := range options {
+			if _, err := NewScoped(remove("M107"), option); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		}
+	})
+}
@@ -0,0 +1,221 @@
+// temperature package contains a transformer that overrides the temperatures commanded by a file,
+// so a file sliced for a filament can be printed with another one without slicing it again.
+//
+// The targets of the heating commands are replaced by a value or offset by a delta.
+// The targets of zero aren't modified, because they turn off the heater.
+// The override can be limited to the hotend of a tool and to a range of layers, and several transformers can be chained for several rules.
+// Only the commands found are rewritten, no command is inserted.
+package temperature
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region heater
+
+// Heater identifies the heaters whose temperature can be overridden.
+type Heater int
+
+const (
+	// HeaterHotend is the hotend, commanded by M104 and M109.
+	HeaterHotend Heater = iota
+
+	// HeaterBed is the bed, commanded by M140 and M190.
+	HeaterBed
+
+	// HeaterChamber is the chamber, commanded by M141 and M191.
+	HeaterChamber
+)
+
+// String returns the name of the heater.
+func (h Heater) String() string {
+	switch h {
+	case HeaterHotend:
+		return "hotend"
+	case HeaterBed:
+		return "bed"
+	case HeaterChamber:
+		return "chamber"
+	}
+
+	return fmt.Sprintf("heater(%d)", int(h))
+}
+
+// heaterCommands relates each heating command with its heater.
+var heaterCommands = map[string]Heater{
+	"M104": HeaterHotend,
+	"M109": HeaterHotend,
+	"M140": HeaterBed,
+	"M190": HeaterBed,
+	"M141": HeaterChamber,
+	"M191": HeaterChamber,
+}
+
+//#endregion
+//#region temperature configuration
+
+// TemperatureConfigurer defines the options of the temperature override.
+type TemperatureConfigurer interface {
+	// Set the tool whose hotend is overridden
+	SetTool(tool int) error
+
+	// Set the range of layers overridden
+	SetLayers(first int, last int) error
+}
+
+// TemperatureConfigurationCallbackable is the signature of the callbacks used to configure the temperature override.
+type TemperatureConfigurationCallbackable func(config TemperatureConfigurer) error
+
+// temperatureConfigurator implements TemperatureConfigurer.
+type temperatureConfigurator struct {
+	tool        int
+	first, last int
+}
+
+// SetTool defines the tool whose hotend is overridden, it can't be negative.
+// The tool of each command is its T parameter, or the active tool if it hasn't one. It doesn't affect the other heaters.
+// If this method isn't called, by default the hotends of all tools are overridden.
+func (tc *temperatureConfigurator) SetTool(tool int) error {
+	if tool < 0 {
+		return fmt.Errorf("failed to set tool, it can't be negative: %d", tool)
+	}
+
+	tc.tool = tool
+
+	return nil
+}
+
+// SetLayers defines the range of layers overridden, from first to last inclusive, starting at zero.
+// The commands before the first layer, like the ones of the start gcode, don't belong to any layer.
+// If this method isn't called, by default the whole document is overridden.
+func (tc *temperatureConfigurator) SetLayers(first int, last int) error {
+	if first < 0 || last < first {
+		return fmt.Errorf("failed to set layers, the range [%d, %d] isn't valid", first, last)
+	}
+
+	tc.first, tc.last = first, last
+
+	return nil
+}
+
+//#endregion
+//#region temperature struct
+
+// Temperature is a transformer that overrides the targets of a heater.
+type Temperature struct {
+	// heater overridden
+	heater Heater
+
+	// value of the target, or the delta added to it if relative is true
+	value    float64
+	relative bool
+
+	// tool overridden, negative if all of them
+	tool int
+
+	// range of layers overridden, first is negative if all of them
+	first, last int
+}
+
+// Apply overrides the targets of the block, it always returns the block received.
+func (t *Temperature) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	heater, ok := heaterCommands[b.Command().String()]
+	if !ok || heater != t.heater {
+		return []block.Blocker{b}, nil
+	}
+
+	if t.first >= 0 && (state.Layer < t.first || state.Layer > t.last) {
+		return []block.Blocker{b}, nil
+	}
+
+	if heater == HeaterHotend && t.tool >= 0 {
+		tool := state.Tool
+		if value, ok := transform.Parameter(b, 'T'); ok {
+			tool = int(value)
+		}
+
+		if tool != t.tool {
+			return []block.Blocker{b}, nil
+		}
+	}
+
+	// S is the target and R the target that is awaited even while cooling
+	for _, word := range []byte{'S', 'R'} {
+		target, ok := transform.Parameter(b, word)
+		if !ok || target == 0 {
+			continue
+		}
+
+		value := t.value
+		if t.relative {
+			value += target
+		}
+
+		if value < 0 {
+			return nil, fmt.Errorf("failed to override temperature of block %s, the target %v is negative", b, value)
+		}
+
+		if _, err := transform.SetParameter(b, word, value); err != nil {
+			return nil, fmt.Errorf("failed to override temperature of block %s: %w", b, err)
+		}
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+//#endregion
+//#region constructors
+
+// New returns a new transformer that replaces the targets of a heater by the value received, it must be positive and finite.
+func New(heater Heater, value float64, options ...TemperatureConfigurationCallbackable) (*Temperature, error) {
+	if !(value > 0) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("failed to create temperature, the target must be positive and finite: %v", value)
+	}
+
+	return newTemperature(heater, value, false, options)
+}
+
+// NewOffset returns a new transformer that adds a delta to the targets of a heater, like -5 to print five degrees colder.
+// The delta must be finite.
+func NewOffset(heater Heater, delta float64, options ...TemperatureConfigurationCallbackable) (*Temperature, error) {
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return nil, fmt.Errorf("failed to create temperature, the delta must be finite: %v", delta)
+	}
+
+	return newTemperature(heater, delta, true, options)
+}
+
+// newTemperature returns a new transformer configured by the options received.
+func newTemperature(heater Heater, value float64, relative bool, options []TemperatureConfigurationCallbackable) (*Temperature, error) {
+	switch heater {
+	case HeaterHotend, HeaterBed, HeaterChamber:
+	default:
+		return nil, fmt.Errorf("failed to create temperature, unknown heater %d", heater)
+	}
+
+	configurator := &temperatureConfigurator{
+		tool:  -1,
+		first: -1,
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Temperature{
+		heater:   heater,
+		value:    value,
+		relative: relative,
+		tool:     configurator.tool,
+		first:    configurator.first,
+		last:     configurator.last,
+	}, nil
+}
+
+//#endregion
@@ -0,0 +1,138 @@
+package temperature
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestTemperature(t *testing.T) {
+
+	source := "M140 S60\nM104 S210\nM190 S60\nM109 S210\n;LAYER:0\nM104 T1 S200\n;LAYER:1\nM104 S215\nM140 S55\nT1\nM109 R190\n;LAYER:2\nM104 S0\nM140 S0\n"
+
+	cases := map[string]struct {
+		heater   Heater
+		value    float64
+		relative bool
+		options  []TemperatureConfigurationCallbackable
+		want     string
+	}{
+		"hotend": {
+			heater: HeaterHotend,
+			value:  230,
+			want:   "M140 S60\nM104 S230\nM190 S60\nM109 S230\n;LAYER:0\nM104 T1 S230\n;LAYER:1\nM104 S230\nM140 S55\nT1\nM109 R230\n;LAYER:2\nM104 S0\nM140 S0\n",
+		},
+		"bed offset": {
+			heater:   HeaterBed,
+			value:    -5,
+			relative: true,
+			want:     "M140 S55\nM104 S210\nM190 S55\nM109 S210\n;LAYER:0\nM104 T1 S200\n;LAYER:1\nM104 S215\nM140 S50\nT1\nM109 R190\n;LAYER:2\nM104 S0\nM140 S0\n",
+		},
+		"tool": {
+			heater:   HeaterHotend,
+			value:    10,
+			relative: true,
+			options: []TemperatureConfigurationCallbackable{
+				func(config TemperatureConfigurer) error { return config.SetTool(1) },
+			},
+			want: "M140 S60\nM104 S210\nM190 S60\nM109 S210\n;LAYER:0\nM104 T1 S210\n;LAYER:1\nM104 S215\nM140 S55\nT1\nM109 R200\n;LAYER:2\nM104 S0\nM140 S0\n",
+		},
+		"layers": {
+			heater: HeaterHotend,
+			value:  220,
+			options: []TemperatureConfigurationCallbackable{
+				func(config TemperatureConfigurer) error { return config.SetLayers(1, 2) },
+			},
+			want: "M140 S60\nM104 S210\nM190 S60\nM109 S210\n;LAYER:0\nM104 T1 S200\n;LAYER:1\nM104 S220\nM140 S55\nT1\nM109 R220\n;LAYER:2\nM104 S0\nM140 S0\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var temperature *Temperature
+			if tc.relative {
+				temperature, err = NewOffset(tc.heater, tc.value, tc.options...)
+			} else {
+				temperature, err = New(tc.heater, tc.value, tc.options...)
+			}
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{temperature})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("negative target", func(t *testing.T) {
+		d, err := document.Parse(strings.NewReader("M140 S60\n"))
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		temperature, err := NewOffset(HeaterBed, -70)
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		p, err := transform.NewPipeline([]transform.Transformer{temperature})
+		if err != nil {
+			t.Errorf("got error %v, want error nil", err)
+			return
+		}
+
+		if _, err := p.Run(d); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := New(HeaterHotend, 0); err == nil {
+			t.Errorf("got error nil with a zero target, want error not nil")
+		}
+
+		if _, err := NewOffset(HeaterBed, math.NaN()); err == nil {
+			t.Errorf("got error nil with a delta NaN, want error not nil")
+		}
+
+		if _, err := New(Heater(10), 200); err == nil {
+			t.Errorf("got error nil with an unknown heater, want error not nil")
+		}
+
+		options := []TemperatureConfigurationCallbackable{
+			func(config TemperatureConfigurer) error { return config.SetTool(-1) },
+			func(config TemperatureConfigurer) error { return config.SetLayers(3, 2) },
+		}
+
+		for _, option := range options {
+			if _, err := New(HeaterHotend, 200, option); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// transform package contains the pipeline that streams the blocks of a document through chained transformers.
+//
+// A transformer receives each block with the modal state of the machine in which it is executed,
+// and returns the blocks that replace it: the same block, modified or not, several blocks, or none to remove it.
+// The transformers are the base of all post-processing operations, like translate a print or adjust its flow.
+//
+// The pipeline tracks the modal state of the blocks received by each transformer independently,
+// so a transformer always sees the state produced by the transformers that precede it.
+// The lines without gcode, like the comments, pass through the pipeline without changes, unless a transformer that implements LineFilter removes them.
+// A transformer can retain blocks and release them later, if it implements Flusher.
+// The pipeline recalculates the checksums of the blocks modified, so the transformers don't need to update them.
+// Any transformer can be restricted to some layers, heights, region or objects with NewScoped.
+// The custom post-processing steps can be written as a TransformerFunc and published as a Plugin in a Catalog,
+// so the tools create them by name.
+package transform
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+)
+
+//#region transformer
+
+// State is the context in which a transformer receives a block.
+type State struct {
+	// ModalState is the state of the machine before executing the block, so its position is the start of a move.
+	document.ModalState
+
+	// After is the state of the machine after executing the block, as it was received, so its position is the end of a move.
+	After document.ModalState
+
+	// Index is the position in the source document of the block that originated the block received.
+	// The blocks inserted by a transformer share the position of the block that they replace.
+	Index int
+
+	// Layer is the index of the layer of the block in the source document, or -1 if it is before the first layer.
+	// The layers are detected like document.Document.Layers does with document.LayerAuto.
+	Layer int
+
+	// Object is the identifier of the object of the block in the source document, started by M486 S, or -1 if it is outside any object.
+	// The M486 block that starts an object belongs to it.
+	Object int
+}
+
+// Transformer defines the step of a pipeline that modifies the blocks of a document.
+type Transformer interface {
+	// Apply returns the blocks that replace the block received.
+	//
+	// It can modify the block received and return it, return new blocks, or return none to remove it.
+	// The state mustn't be modified.
+	Apply(b block.Blocker, state State) ([]block.Blocker, error)
+}
+
+// Flusher is implemented by the transformers that retain blocks to process them together, like a sequence of moves replaced by an arc.
+//
+// The pipeline calls Flush before each line without gcode and at the end of the document,
+// so the blocks retained keep their position relative to the comments.
+type Flusher interface {
+	// Flush returns the blocks retained, processed, and forgets them.
+	Flush() ([]block.Blocker, error)
+}
+
+// LineFilter is implemented by the transformers that remove lines without gcode, like the comments or the blank lines.
+//
+// The pipeline asks every transformer that implements it, in order, after flushing the blocks retained.
+type LineFilter interface {
+	// KeepLine returns false if the line without gcode must be removed. The text is the line as it was read.
+	KeepLine(text string) bool
+}
+
+//#endregion
+//#region warning
+
+// Warning describes a block that a transformer couldn't process safely, but didn't prevent the transformation.
+type Warning struct {
+	// Index is the position of the block in the source document.
+	Index int
+
+	// Block is the block exported as it was received.
+	Block string
+
+	// Message explains the problem.
+	Message string
+}
+
+// String returns the warning formatted.
+func (w Warning) String() string {
+	return fmt.Sprintf("block %d (%s): %s", w.Index, w.Block, w.Message)
+}
+
+//#endregion
@@ -0,0 +1,84 @@
+// translate package contains a transformer that moves a print to another position, like another place of the bed.
+//
+// The coordinates of the moves executed in absolute positioning are offset by a vector, while the relative moves are untouched.
+// The G92 commands are offset too, so the coordinates set by them stay coherent with the moves that follow.
+// The moves in machine coordinates (G53) and the homing (G28) aren't modified, because they refer to physical positions.
+package translate
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region translate struct
+
+// Translate is a transformer that offsets the X, Y and Z coordinates of the blocks.
+type Translate struct {
+	// offset of each axis
+	x, y, z float64
+}
+
+// Apply offsets the coordinates of the block, it always returns the block received.
+func (t *Translate) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	switch b.Command().String() {
+	case "G0", "G1", "G2", "G3":
+		if state.Relative || machineCoordinates(b) {
+			return []block.Blocker{b}, nil
+		}
+	case "G92":
+		// G92 sets the coordinates in any positioning mode
+	default:
+		return []block.Blocker{b}, nil
+	}
+
+	for _, axis := range []struct {
+		word   byte
+		offset float64
+	}{{'X', t.x}, {'Y', t.y}, {'Z', t.z}} {
+		if axis.offset == 0 {
+			continue
+		}
+
+		value, ok := transform.Parameter(b, axis.word)
+		if !ok {
+			continue
+		}
+
+		if _, err := transform.SetParameter(b, axis.word, value+axis.offset); err != nil {
+			return nil, fmt.Errorf("failed to translate block %s: %w", b, err)
+		}
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// machineCoordinates returns true if the block has a G53 after its command, like "G0 G53 X10", so it moves in the coordinates of the machine.
+func machineCoordinates(b block.Blocker) bool {
+	for _, p := range b.Parameters() {
+		if number, ok := gcode.NumericAddress(p); ok && p.Word() == 'G' && number == 53 {
+			return true
+		}
+	}
+
+	return false
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that offsets the coordinates by the vector received. The offsets must be finite.
+func New(x float64, y float64, z float64) (*Translate, error) {
+	for _, offset := range []float64{x, y, z} {
+		if math.IsNaN(offset) || math.IsInf(offset, 0) {
+			return nil, fmt.Errorf("failed to create translate, the offset must be finite: %v", offset)
+		}
+	}
+
+	return &Translate{x: x, y: y, z: z}, nil
+}
+
+//#endregion
@@ -0,0 +1,83 @@
+package translate
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestTranslate(t *testing.T) {
+
+	cases := map[string]struct {
+		source  string
+		x, y, z float64
+		want    string
+	}{
+		"absolute moves": {
+			source: "G28\nG90\nG0 X10 Y10 Z0.3\nG1 X20.5 Y10 E1\nG2 X30 Y10 I5 J0 E2\n",
+			x:      5, y: -2.5, z: 0,
+			want: "G28\nG90\nG0 X15 Y7.5 Z0.3\nG1 X25.5 Y7.5 E1\nG2 X35 Y7.5 I5 J0 E2\n",
+		},
+		"relative moves": {
+			source: "G91\nG1 X10 Y10 E1\nG90\nG1 X10\n",
+			x:      5, y: 5, z: 1,
+			want: "G91\nG1 X10 Y10 E1\nG90\nG1 X15\n",
+		},
+		"coordinates set": {
+			source: "G91\nG92 X0 Y0 Z0 E0\nG1 Z1\n",
+			x:      1, y: 2, z: 3,
+			want: "G91\nG92 X1 Y2 Z3 E0\nG1 Z1\n",
+		},
+		"physical positions": {
+			source: "G28 X0\nG53 G0 X0 Y0\nG1 X1\n",
+			x:      1, y: 1, z: 1,
+			want: "G28 X0\nG53 G0 X0 Y0\nG1 X2\n",
+		},
+		"machine coordinates after the move": {
+			source: "G0 G53 X10 Y10\nG1 G90 G53 Z5\nG1 X1\n",
+			x:      1, y: 1, z: 1,
+			want: "G0 G53 X10 Y10\nG1 G90 G53 Z5\nG1 X2\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			translate, err := New(tc.x, tc.y, tc.z)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{translate})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid offset", func(t *testing.T) {
+		if _, err := New(math.NaN(), 0, 0); err == nil {
+			t.Errorf("got error nil, want error not nil")
+		}
+	})
+}
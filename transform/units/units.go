@@ -0,0 +1,186 @@
+// units package contains a transformer that converts a file between inches (G20) and millimeters (G21).
+//
+// The addresses of the words that are lengths are converted, like the coordinates, the arc offsets, the extrusion and the feedrates,
+// and the unit commands are replaced by the one of the units required.
+// The words that are lengths depend on the command and on the dialect of the machine, so they can be configured for each command.
+package units
+
+import (
+	"fmt"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/block/gcodeblock"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/internal/numeric"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+const (
+	// MILLIMETERS_PER_INCH is the length of an inch in millimeters.
+	MILLIMETERS_PER_INCH = 25.4
+
+	// MILLIMETER_DECIMALS is the number of decimals of the lengths converted to millimeters.
+	MILLIMETER_DECIMALS = 3
+
+	// INCH_DECIMALS is the number of decimals of the lengths converted to inches.
+	INCH_DECIMALS = 5
+)
+
+// DefaultLengthWords returns the words that are lengths for each command, in the most common dialects, like Marlin and RepRapFirmware.
+func DefaultLengthWords() map[string]string {
+	return map[string]string{
+		"G0":  "XYZEF",
+		"G1":  "XYZEF",
+		"G2":  "XYZIJKREF",
+		"G3":  "XYZIJKREF",
+		"G53": "XYZ",
+		"G92": "XYZE",
+	}
+}
+
+//#region units configuration
+
+// UnitsConfigurer defines the options of the conversion of units.
+type UnitsConfigurer interface {
+	// Set the words that are lengths for a command
+	SetLengthWords(command string, words string) error
+}
+
+// UnitsConfigurationCallbackable is the signature of the callbacks used to configure the conversion of units.
+type UnitsConfigurationCallbackable func(config UnitsConfigurer) error
+
+// unitsConfigurator implements UnitsConfigurer.
+type unitsConfigurator struct {
+	words map[string]string
+}
+
+// SetLengthWords defines the words whose addresses are lengths for a command, like "XYZEF" for "G1",
+// to adapt the conversion to the dialect of the machine. An empty string stops converting the command.
+// The words must be uppercase letters.
+// If this method isn't called, by default the words are the ones returned by DefaultLengthWords.
+func (uc *unitsConfigurator) SetLengthWords(command string, words string) error {
+	if command == "" {
+		return fmt.Errorf("failed to set length words, the command mustn't be empty")
+	}
+
+	for _, word := range []byte(words) {
+		if word < 'A' || word > 'Z' {
+			return fmt.Errorf("failed to set length words of %s, %q isn't an uppercase letter", command, word)
+		}
+	}
+
+	if words == "" {
+		delete(uc.words, command)
+		return nil
+	}
+
+	uc.words[command] = words
+
+	return nil
+}
+
+//#endregion
+//#region units struct
+
+// Units is a transformer that converts the lengths of the blocks to the units required.
+type Units struct {
+	// units required
+	target document.Units
+
+	// words that are lengths for each command
+	words map[string]string
+
+	// true if the units of the output were commanded
+	known bool
+}
+
+// Apply converts the lengths of the block and replaces the unit commands.
+// It can return the unit command required before the first block that needs it.
+func (u *Units) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	command := b.Command().String()
+
+	if command == "G20" || command == "G21" {
+		u.known = true
+
+		if command == u.command() {
+			return []block.Blocker{b}, nil
+		}
+
+		replaced, err := gcodeblock.Parse(u.command())
+		if err != nil {
+			return nil, fmt.Errorf("failed to replace unit command %s: %w", b, err)
+		}
+
+		return []block.Blocker{replaced}, nil
+	}
+
+	words, ok := u.words[command]
+	if !ok || state.Units == u.target {
+		return []block.Blocker{b}, nil
+	}
+
+	factor, decimals := MILLIMETERS_PER_INCH, MILLIMETER_DECIMALS
+	if u.target == document.UnitsInches {
+		factor, decimals = 1/MILLIMETERS_PER_INCH, INCH_DECIMALS
+	}
+
+	for _, word := range []byte(words) {
+		value, ok := transform.Parameter(b, word)
+		if !ok {
+			continue
+		}
+
+		if _, err := transform.SetParameter(b, word, numeric.Round(value*factor, decimals)); err != nil {
+			return nil, fmt.Errorf("failed to convert block %s: %w", b, err)
+		}
+	}
+
+	// the file doesn't select its units before this block, so the machine must be told
+	if !u.known {
+		u.known = true
+
+		selection, err := gcodeblock.Parse(u.command())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create unit command: %w", err)
+		}
+
+		return []block.Blocker{selection, b}, nil
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// command returns the unit command of the units required.
+func (u *Units) command() string {
+	if u.target == document.UnitsInches {
+		return "G20"
+	}
+
+	return "G21"
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that converts the lengths to the units received.
+func New(target document.Units, options ...UnitsConfigurationCallbackable) (*Units, error) {
+	switch target {
+	case document.UnitsMillimeters, document.UnitsInches:
+	default:
+		return nil, fmt.Errorf("failed to create units, unknown units %d", target)
+	}
+
+	configurator := &unitsConfigurator{
+		words: DefaultLengthWords(),
+	}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &Units{target: target, words: configurator.words}, nil
+}
+
+//#endregion
@@ -0,0 +1,111 @@
+package units
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/gcode"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestUnits(t *testing.T) {
+
+	cases := map[string]struct {
+		source  string
+		target  document.Units
+		options []UnitsConfigurationCallbackable
+		words   string
+		want    string
+	}{
+		"inches to millimeters": {
+			source: "G20\nG28\nG1 X1 Y0.5 E0.1 F60\nG2 X2 Y0.5 I0.5 J0\nG92 E0\nG4 P100\n",
+			target: document.UnitsMillimeters,
+			want:   "G21\nG28\nG1 X25.4 Y12.7 E2.54 F1524\nG2 X50.8 Y12.7 I12.7 J0\nG92 E0\nG4 P100\n",
+		},
+		"millimeters to inches": {
+			source: "G28\nG1 X25.4 Y10 F1200\nG21\nG1 X50.8\n",
+			target: document.UnitsInches,
+			want:   "G28\nG20\nG1 X1.0 Y0.3937 F47.24409\nG20\nG1 X2.0\n",
+		},
+		"same units": {
+			source: "G21\nG1 X10\n",
+			target: document.UnitsMillimeters,
+			want:   "G21\nG1 X10\n",
+		},
+		"dialect": {
+			source: "G20\nG1 X1 F10\nM207 S0.1\n",
+			target: document.UnitsMillimeters,
+			options: []UnitsConfigurationCallbackable{
+				func(config UnitsConfigurer) error { return config.SetLengthWords("G1", "XYZE") },
+				func(config UnitsConfigurer) error { return config.SetLengthWords("M207", "SZ") },
+			},
+			want: "G21\nG1 X25.4 F10\nM207 S2.54\n",
+		},
+		"arcs in other planes": {
+			source: "G20\nG18\nG2 X1 Z0 I0.5 K0\n",
+			target: document.UnitsMillimeters,
+			words:  "K",
+			want:   "G21\nG18\nG2 X25.4 Z0 I12.7 K0\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			registry := gcode.DefaultWordRegistry()
+			if err := registry.Allow([]byte(tc.words)...); err != nil {
+				t.Fatalf("got error %v, want error nil", err)
+			}
+
+			d, err := document.Parse(strings.NewReader(tc.source), func(config document.ParseConfigurer) error {
+				return config.SetBlockOptions(func(config block.BlockParserConfigurer) error {
+					return config.SetWordRegistry(registry)
+				})
+			})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			units, err := New(tc.target, tc.options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{units})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := New(document.Units(7)); err == nil {
+			t.Errorf("got error nil with unknown units, want error not nil")
+		}
+
+		options := []UnitsConfigurationCallbackable{
+			func(config UnitsConfigurer) error { return config.SetLengthWords("", "X") },
+			func(config UnitsConfigurer) error { return config.SetLengthWords("G1", "x") },
+		}
+
+		for _, option := range options {
+			if _, err := New(document.UnitsInches, option); err == nil {
+				t.Errorf("got error nil, want error not nil")
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// zoffset package contains a transformer that applies a constant offset to the height of the nozzle,
+// like a babystepping adjustment saved in the file.
+//
+// While the offset is active, the heights of the moves executed in absolute positioning are offset,
+// and the heights set by G92 too, so the coordinates stay coherent with the moves that follow.
+// In relative positioning the offset is added to the first move in Z, and removed from the first move in Z after it ends.
+// The offset can be limited to the first layers, like a first layer adjustment.
+package zoffset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mauroalderete/gcode-core/block"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+//#region z offset configuration
+
+// ZOffsetConfigurer defines the options of the z offset.
+type ZOffsetConfigurer interface {
+	// Set the number of layers offset
+	SetLayers(layers int) error
+}
+
+// ZOffsetConfigurationCallbackable is the signature of the callbacks used to configure the z offset.
+type ZOffsetConfigurationCallbackable func(config ZOffsetConfigurer) error
+
+// zOffsetConfigurator implements ZOffsetConfigurer.
+type zOffsetConfigurator struct {
+	layers int
+}
+
+// SetLayers defines the number of layers offset, starting at the first one. It must be positive.
+// The blocks before the first layer, like the start gcode, are offset too.
+// The offset ends at the first move in Z of the next layer.
+// If this method isn't called, by default the whole document is offset.
+func (zc *zOffsetConfigurator) SetLayers(layers int) error {
+	if layers <= 0 {
+		return fmt.Errorf("failed to set layers, it must be positive: %d", layers)
+	}
+
+	zc.layers = layers
+
+	return nil
+}
+
+//#endregion
+//#region z offset struct
+
+// ZOffset is a transformer that offsets the height of the nozzle.
+type ZOffset struct {
+	// offset of the height
+	offset float64
+
+	// number of layers offset, zero if all of them
+	layers int
+
+	// true if the height of the nozzle includes the offset
+	applied bool
+}
+
+// Apply offsets the height of the block, it always returns the block received.
+func (z *ZOffset) Apply(b block.Blocker, state transform.State) ([]block.Blocker, error) {
+	active := z.layers == 0 || state.Layer < z.layers
+
+	switch b.Command().String() {
+	case "G0", "G1", "G2", "G3":
+		value, ok := transform.Parameter(b, 'Z')
+		if !ok {
+			break
+		}
+
+		offset := 0.0
+		switch {
+		case !state.Relative && active:
+			offset = z.offset
+		case state.Relative && active && !z.applied:
+			offset = z.offset
+		case state.Relative && !active && z.applied:
+			offset = -z.offset
+		}
+		z.applied = active
+
+		if offset == 0 {
+			break
+		}
+
+		if err := z.set(b, value+offset); err != nil {
+			return nil, err
+		}
+	case "G92":
+		value, ok := transform.Parameter(b, 'Z')
+		if !ok || !z.applied {
+			break
+		}
+
+		if err := z.set(b, value+z.offset); err != nil {
+			return nil, err
+		}
+	case "G28":
+		// after homing the height is the physical one
+		_, x := transform.Parameter(b, 'X')
+		_, y := transform.Parameter(b, 'Y')
+		if _, ok := transform.Parameter(b, 'Z'); ok || (!x && !y) {
+			z.applied = false
+		}
+	}
+
+	return []block.Blocker{b}, nil
+}
+
+// set updates the height of a block.
+func (z *ZOffset) set(b block.Blocker, value float64) error {
+	if _, err := transform.SetParameter(b, 'Z', value); err != nil {
+		return fmt.Errorf("failed to offset block %s: %w", b, err)
+	}
+
+	return nil
+}
+
+//#endregion
+//#region constructor
+
+// New returns a new transformer that offsets the height by the value received. The offset must be finite.
+func New(offset float64, options ...ZOffsetConfigurationCallbackable) (*ZOffset, error) {
+	if math.IsNaN(offset) || math.IsInf(offset, 0) {
+		return nil, fmt.Errorf("failed to create z offset, the offset must be finite: %v", offset)
+	}
+
+	configurator := &zOffsetConfigurator{}
+
+	for _, option := range options {
+		if err := option(configurator); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+
+	return &ZOffset{offset: offset, layers: configurator.layers}, nil
+}
+
+//#endregion
@@ -0,0 +1,100 @@
+package zoffset
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mauroalderete/gcode-core/document"
+	"github.com/mauroalderete/gcode-core/transform"
+)
+
+func TestZOffset(t *testing.T) {
+
+	cases := map[string]struct {
+		source string
+		offset float64
+		layers int
+		want   string
+	}{
+		"absolute moves": {
+			source: "G28\nG90\nG0 X10 Z5\nG1 X20 Z0.2 E1\nG1 X30 E2\n",
+			offset: -0.05,
+			want:   "G28\nG90\nG0 X10 Z4.95\nG1 X20 Z0.15 E1\nG1 X30 E2\n",
+		},
+		"relative moves": {
+			source: "G28\nG91\nG1 Z0.2\nG1 Z0.2\n",
+			offset: 0.1,
+			want:   "G28\nG91\nG1 Z0.3\nG1 Z0.2\n",
+		},
+		"coordinates set": {
+			source: "G1 Z0.2\nG92 Z0\nG1 Z0.4\nG28 X0\nG92 Z1\nG28\nG92 Z1\n",
+			offset: 0.1,
+			want:   "G1 Z0.3\nG92 Z0.1\nG1 Z0.5\nG28 X0\nG92 Z1.1\nG28\nG92 Z1\n",
+		},
+		"first layers absolute": {
+			source: "G1 Z5\n;LAYER:0\nG1 Z0.2\n;LAYER:1\nG1 Z0.4\n;LAYER:2\nG1 Z0.6\n",
+			offset: 0.1,
+			layers: 2,
+			want:   "G1 Z5.1\n;LAYER:0\nG1 Z0.3\n;LAYER:1\nG1 Z0.5\n;LAYER:2\nG1 Z0.6\n",
+		},
+		"first layers relative": {
+			source: ";LAYER:0\nG91\nG1 Z0.2\nG1 Z0.2\n;LAYER:1\nG1 Z0.2\nG1 Z0.2\n",
+			offset: 0.1,
+			layers: 1,
+			want:   ";LAYER:0\nG91\nG1 Z0.3\nG1 Z0.2\n;LAYER:1\nG1 Z0.1\nG1 Z0.2\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := document.Parse(strings.NewReader(tc.source))
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			var options []ZOffsetConfigurationCallbackable
+			if tc.layers > 0 {
+				options = append(options, func(config ZOffsetConfigurer) error {
+					return config.SetLayers(tc.layers)
+				})
+			}
+
+			zoffset, err := New(tc.offset, options...)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			p, err := transform.NewPipeline([]transform.Transformer{zoffset})
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			got, err := p.Run(d)
+			if err != nil {
+				t.Errorf("got error %v, want error nil", err)
+				return
+			}
+
+			if got.String() != tc.want {
+				t.Errorf("got %q, want %q", got.String(), tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := New(math.Inf(1)); err == nil {
+			t.Errorf("got error nil with an infinite offset, want error not nil")
+		}
+
+		_, err := New(0.1, func(config ZOffsetConfigurer) error {
+			return config.SetLayers(0)
+		})
+		if err == nil {
+			t.Errorf("got error nil with zero layers, want error not nil")
+		}
+	})
+}